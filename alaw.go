@@ -0,0 +1,63 @@
+package wav2ulaw
+
+import "math/bits"
+
+// alawDecodeTable maps every A-law code to its 16-bit linear value
+var alawDecodeTable [256]int16
+
+func init() {
+	for code := 0; code < 256; code++ {
+		// Even bits are inverted on the wire
+		v := uint8(code) ^ 0x55
+		exponent := (v >> 4) & 0x07
+		magnitude := int(v&0x0F)<<4 + 8
+		if exponent > 0 {
+			magnitude = (magnitude + 0x100) << (exponent - 1)
+		}
+		// Unlike u-law, a set sign bit means positive
+		if v&0x80 != 0 {
+			alawDecodeTable[code] = int16(magnitude)
+		} else {
+			alawDecodeTable[code] = int16(-magnitude)
+		}
+	}
+}
+
+// decodeAlawSamples expands A-law bytes to 16-bit PCM samples
+func decodeAlawSamples(alawBytes []byte) []int16 {
+	samples := make([]int16, len(alawBytes))
+	for i, code := range alawBytes {
+		samples[i] = alawDecodeTable[code]
+	}
+	return samples
+}
+
+// encodeAlawSample compands one 16-bit sample to A-law (ITU-T G.711)
+func encodeAlawSample(sample int16) byte {
+	// Non-negative samples carry the sign bit, negatives use one's complement magnitude
+	sign := byte(0x80)
+	if sample < 0 {
+		sign = 0
+		sample = ^sample
+	}
+
+	// Segment 0 holds the 4 lowest bits of the 12-bit magnitude as is, each
+	// further segment doubles the step
+	magnitude := int(sample) >> 4
+	code := magnitude
+	if magnitude > 0x0F {
+		seg := bits.Len(uint(magnitude)) - 4
+		code = seg<<4 | (magnitude>>(seg-1))&0x0F
+	}
+	// Even bits are inverted on the wire
+	return (sign | byte(code)) ^ 0x55
+}
+
+// encodeAlawSamples compands 16-bit PCM samples to A-law bytes
+func encodeAlawSamples(samples []int16) []byte {
+	alaw := make([]byte, len(samples))
+	for i, sample := range samples {
+		alaw[i] = encodeAlawSample(sample)
+	}
+	return alaw
+}
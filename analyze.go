@@ -0,0 +1,158 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"math"
+	"time"
+)
+
+// Stats contains statistics about a WAV file's audio content.
+// Levels are linear and relative to full scale (0.0 to 1.0).
+type Stats struct {
+	// Duration of the audio
+	Duration time.Duration
+	// Sample rate (Hz)
+	SampleRate int
+	// Number of channels
+	Channels int
+	// Bits per sample
+	BitDepth int
+	// Number of sample frames (samples per channel)
+	Frames int
+	// Highest absolute sample value across all channels
+	Peak float64
+	// Root-mean-square level across all channels
+	RMS float64
+	// Mean sample value; non-zero means the signal is not centered
+	DCOffset float64
+	// Number of samples at the minimum or maximum code value
+	ClippedSamples int
+	// Runs of consecutive full-scale samples, in channel then time order
+	ClipRegions []ClipRegion
+	// Estimated signal-to-noise ratio of speech versus non-speech frames (dB)
+	SNR float64
+	// Total time classified as speech by the voice activity detector
+	SpeechDuration time.Duration
+	// Total time classified as silence or background noise
+	SilenceDuration time.Duration
+	// Share of the audio that is silence (0 to 100)
+	SilencePercent float64
+}
+
+// decodedWav holds interleaved samples normalized to [-1, 1]
+type decodedWav struct {
+	sampleRate int
+	channels   int
+	bitDepth   int
+	data       []float64
+}
+
+// decodeWavFloat parses WAV bytes into normalized interleaved samples
+func decodeWavFloat(wavBytes []byte) (*decodedWav, error) {
+	header, err := inspectWav(bytes.NewReader(wavBytes), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	pcm := decodeWavData(wavBytes, header)
+	data := make([]float64, len(pcm))
+	for i, v := range pcm {
+		data[i] = normalizeSample(v, header.bitDepth)
+	}
+
+	return &decodedWav{
+		sampleRate: header.sampleRate,
+		channels:   header.channels,
+		bitDepth:   header.bitDepth,
+		data:       data,
+	}, nil
+}
+
+// normalizeSample scales a decoded integer sample to [-1, 1].
+// 8-bit WAV samples are unsigned, all other depths are signed.
+func normalizeSample(v, bitDepth int) float64 {
+	if bitDepth == 8 {
+		return float64(v-128) / 128.0
+	}
+	if bitDepth <= 0 {
+		bitDepth = 16
+	}
+	return float64(v) / float64(int64(1)<<(bitDepth-1))
+}
+
+// isClipped reports whether a normalized sample sits at a full-scale code value
+func isClipped(v float64, bitDepth int) bool {
+	if bitDepth <= 0 {
+		bitDepth = 16
+	}
+	step := 1.0 / float64(int64(1)<<(bitDepth-1))
+	return v >= 1.0-step || v <= -1.0
+}
+
+// AnalyzeWav reports duration, format and level statistics for WAV file bytes
+func AnalyzeWav(wavBytes []byte) (*Stats, error) {
+	decoded, err := decodeWavFloat(wavBytes)
+	if err != nil {
+		return nil, err
+	}
+	return analyzeDecoded(decoded), nil
+}
+
+// AnalyzeUlaw reports duration, level and talk-time statistics for 8 kHz u-law bytes
+func AnalyzeUlaw(ulawBytes []byte) (*Stats, error) {
+	samples := decodeUlawSamples(ulawBytes)
+	data := make([]float64, len(samples))
+	for i, sample := range samples {
+		data[i] = float64(sample) / 32768.0
+	}
+	return analyzeDecoded(&decodedWav{sampleRate: 8000, channels: 1, bitDepth: 16, data: data}), nil
+}
+
+// analyzeDecoded computes statistics of normalized decoded audio
+func analyzeDecoded(decoded *decodedWav) *Stats {
+	frames := len(decoded.data) / decoded.channels
+	stats := &Stats{
+		SampleRate: decoded.sampleRate,
+		Channels:   decoded.channels,
+		BitDepth:   decoded.bitDepth,
+		Frames:     frames,
+	}
+	if decoded.sampleRate > 0 {
+		stats.Duration = time.Duration(float64(frames) / float64(decoded.sampleRate) * float64(time.Second))
+	}
+	if len(decoded.data) == 0 {
+		return stats
+	}
+
+	sum, sumSq := 0.0, 0.0
+	for _, v := range decoded.data {
+		sum += v
+		sumSq += v * v
+		if abs := math.Abs(v); abs > stats.Peak {
+			stats.Peak = abs
+		}
+		if isClipped(v, decoded.bitDepth) {
+			stats.ClippedSamples++
+		}
+	}
+
+	n := float64(len(decoded.data))
+	stats.RMS = math.Sqrt(sumSq / n)
+	stats.DCOffset = sum / n
+	stats.ClipRegions = detectClipRegions(decoded.data, decoded.channels, decoded.sampleRate, decoded.bitDepth)
+	mono := decoded.monoMix()
+	stats.SNR = estimateSNR(mono, decoded.sampleRate)
+
+	frameDuration := time.Duration(analysisFrameMs) * time.Millisecond
+	for _, active := range detectVoiceActivity(mono, decoded.sampleRate) {
+		if active {
+			stats.SpeechDuration += frameDuration
+		}
+	}
+	stats.SpeechDuration = min(stats.SpeechDuration, stats.Duration)
+	stats.SilenceDuration = stats.Duration - stats.SpeechDuration
+	if stats.Duration > 0 {
+		stats.SilencePercent = 100 * float64(stats.SilenceDuration) / float64(stats.Duration)
+	}
+	return stats
+}
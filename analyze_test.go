@@ -0,0 +1,87 @@
+package wav2ulaw
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestAnalyzeWav(t *testing.T) {
+	samples := sineWave(16000, 1000, 16000, 0.5)
+	// Two full-scale samples and a small positive bias
+	samples[100] = 32767
+	samples[200] = -32768
+	for i := range samples {
+		if samples[i] < 32000 && samples[i] > -32000 {
+			samples[i] += 328
+		}
+	}
+
+	wavBytes, err := encodeWavPCM16(samples, 16000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := AnalyzeWav(wavBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.SampleRate != 16000 || stats.Channels != 1 || stats.BitDepth != 16 {
+		t.Errorf("unexpected format: %+v", stats)
+	}
+	if stats.Duration != time.Second || stats.Frames != 16000 {
+		t.Errorf("expected 1s / 16000 frames, got %v / %d", stats.Duration, stats.Frames)
+	}
+	if stats.Peak < 0.999 {
+		t.Errorf("expected full-scale peak, got %f", stats.Peak)
+	}
+	if stats.ClippedSamples != 2 {
+		t.Errorf("expected 2 clipped samples, got %d", stats.ClippedSamples)
+	}
+	if math.Abs(stats.DCOffset-0.01) > 0.001 {
+		t.Errorf("expected DC offset 0.01, got %f", stats.DCOffset)
+	}
+	if math.Abs(stats.RMS-0.5/math.Sqrt2) > 0.01 {
+		t.Errorf("expected RMS %.3f, got %f", 0.5/math.Sqrt2, stats.RMS)
+	}
+}
+
+func TestDetectClipRegions(t *testing.T) {
+	data := make([]float64, 2*1000)
+	// Channel 1: five clipped samples starting at frame 500
+	for i := 500; i < 505; i++ {
+		data[i*2+1] = 1.0
+	}
+	// Channel 0: two isolated full-scale samples are below the minimum run
+	data[10*2] = -1.0
+	data[11*2] = -1.0
+
+	regions := detectClipRegions(data, 2, 1000, 16)
+	if len(regions) != 1 {
+		t.Fatalf("expected 1 region, got %d: %+v", len(regions), regions)
+	}
+	r := regions[0]
+	if r.Channel != 1 || r.Samples != 5 || r.Start != 500*time.Millisecond || r.Duration != 5*time.Millisecond {
+		t.Errorf("unexpected region %+v", r)
+	}
+}
+
+func TestEstimateSNR(t *testing.T) {
+	const rate = 8000
+	noise := NewComfortNoiseGenerator(-50, 1).Samples(rate * 2)
+	tone := sineWave(rate, 500, rate, 0.1) // about -23 dBov RMS
+
+	signal := make([]float64, len(noise))
+	for i, n := range noise {
+		signal[i] = float64(n) / 32768.0
+		if i < len(tone) {
+			signal[i] += float64(tone[i]) / 32768.0
+		}
+	}
+
+	// Speech at -23 dBov over a -50 dBov floor
+	if snr := estimateSNR(signal, rate); math.Abs(snr-27) > 2 {
+		t.Errorf("expected SNR near 27 dB, got %.2f", snr)
+	}
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"context"
+	"io"
+)
+
+// StreamChunkSize is the size of the chunks ConvertFile sends and the server
+// replies with in ConvertStream, well below gRPC's default 4 MiB message limit
+const StreamChunkSize = 64 << 10
+
+// ConvertFile converts the file read from r with the ConvertStream RPC and
+// writes the output to w, returning the direction the conversion ran in.
+// settings sets the direction, preset and sample rate; its data is ignored
+// and it may be nil.
+func ConvertFile(ctx context.Context, client ConverterClient, settings *ConvertRequest, r io.Reader, w io.Writer) (Direction, error) {
+	stream, err := client.ConvertStream(ctx)
+	if err != nil {
+		return 0, err
+	}
+	req := &ConvertRequest{}
+	if settings != nil {
+		req = &ConvertRequest{Direction: settings.Direction, Preset: settings.Preset, SampleRate: settings.SampleRate}
+	}
+	buf := make([]byte, StreamChunkSize)
+	for sent := false; ; sent = true {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 || !sent {
+			// The first message carries the settings even for an empty file
+			req.Data = buf[:n]
+			if err := stream.Send(req); err != nil {
+				return 0, err
+			}
+			req = &ConvertRequest{}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		return 0, err
+	}
+
+	direction := Direction_DIRECTION_UNSPECIFIED
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return direction, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		if direction == Direction_DIRECTION_UNSPECIFIED {
+			direction = resp.Direction
+		}
+		if _, err := w.Write(resp.Data); err != nil {
+			return 0, err
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// echoServer replies to ConvertStream with its input, recording the requests
+type echoServer struct {
+	UnimplementedConverterServer
+	requests []*ConvertRequest
+}
+
+func (s *echoServer) ConvertStream(stream Converter_ConvertStreamServer) error {
+	var input []byte
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		s.requests = append(s.requests, req)
+		input = append(input, req.Data...)
+	}
+	for len(input) > 0 {
+		n := min(len(input), 1000)
+		if err := stream.Send(&ConvertResponse{Data: input[:n], Direction: s.requests[0].Direction}); err != nil {
+			return err
+		}
+		input = input[n:]
+	}
+	return nil
+}
+
+func TestConvertFile(t *testing.T) {
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	echo := &echoServer{}
+	RegisterConverterServer(server, echo)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	input := make([]byte, 2*StreamChunkSize+123)
+	for i := range input {
+		input[i] = byte(i * 7)
+	}
+	var output bytes.Buffer
+	settings := &ConvertRequest{Direction: Direction_DIRECTION_ULAW_TO_WAV, Preset: "voicemail", SampleRate: 16000, Data: []byte("ignored")}
+	direction, err := ConvertFile(context.Background(), NewConverterClient(conn), settings, bytes.NewReader(input), &output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if direction != Direction_DIRECTION_ULAW_TO_WAV {
+		t.Errorf("got direction %v, want %v", direction, Direction_DIRECTION_ULAW_TO_WAV)
+	}
+	if !bytes.Equal(output.Bytes(), input) {
+		t.Fatalf("output differs from input: %d bytes, want %d", output.Len(), len(input))
+	}
+
+	if len(echo.requests) != 3 {
+		t.Fatalf("got %d messages, want 3", len(echo.requests))
+	}
+	first := echo.requests[0]
+	if first.Preset != "voicemail" || first.SampleRate != 16000 || len(first.Data) != StreamChunkSize {
+		t.Errorf("first message has preset %q, sample rate %d, %d bytes", first.Preset, first.SampleRate, len(first.Data))
+	}
+	for _, req := range echo.requests[1:] {
+		if req.Preset != "" || req.SampleRate != 0 || req.Direction != Direction_DIRECTION_UNSPECIFIED {
+			t.Errorf("settings repeated after the first message: %v", req)
+		}
+	}
+}
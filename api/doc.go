@@ -0,0 +1,5 @@
+// Package api holds the gRPC service definition of the converter and the
+// code generated from it.
+package api
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative wav2ulaw.proto
@@ -0,0 +1,203 @@
+package wav2ulaw
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	// Output rate of ASROptions that leave SampleRate unset, the rate
+	// Whisper, Vosk and most other recognizers are trained on
+	asrSampleRate = 16000
+	// Highest sample peak loudness normalization raises the signal to
+	asrPeakLimit = 0.98
+	// Corner frequency of the ASROptions high shelf (Hz), where the second
+	// formant and the consonant cues u-law blurs most begin
+	asrShelfFrequency = 2000
+	// Largest boost or cut of the high shelf (dB)
+	maxShelfGainDb = 12
+)
+
+// ASROptions control ConvertForASR
+type ASROptions struct {
+	// Output sample rate (Hz, 0 = 16000)
+	SampleRate int
+	// Pre-emphasis coefficient a of y[n] = x[n] - a*x[n-1], typically 0.97
+	// (0 = disabled)
+	PreEmphasis float64
+	// Integrated loudness to normalize the output to (LUFS, e.g. -23;
+	// 0 = keep the input level). The gain is reduced where it would push
+	// the sample peak above full scale.
+	LoudnessTarget float64
+	// Boost of the band above 2 kHz by a high shelf (dB, negative to cut,
+	// 0 = disabled)
+	HighShelfGainDb float64
+	// Mute 20 ms frames quieter than this, as ReprocessOptions.NoiseGateDbov
+	// (dBov, 0 = disabled)
+	NoiseGateDbov float64
+	// Target speech level of automatic gain control, as
+	// ReprocessOptions.AGCTargetDbov (dBov, 0 = disabled)
+	AGCTargetDbov float64
+	// Largest boost or cut AGC applies (dB, 0 = 20 dB)
+	AGCMaxGainDb float64
+	// Variant of u-law input
+	UlawVariant UlawVariant
+}
+
+// DefaultASROptions returns options producing 16 kHz output without
+// pre-emphasis or loudness normalization
+func DefaultASROptions() *ASROptions {
+	return &ASROptions{SampleRate: asrSampleRate}
+}
+
+// ConvertForASR converts WAV or u-law input to the mono 16-bit PCM that
+// speech recognizers expect, the reverse direction of the telephony
+// conversion. format names the input format; FormatUnknown detects it with
+// DetectFormat. WAV channels are averaged to mono, and the signal is
+// resampled to options.SampleRate without the telephone band-pass, so
+// wideband input keeps its bandwidth. The cleanup stages then run in the
+// order noise gate, high shelf, AGC, pre-emphasis and loudness
+// normalization.
+func ConvertForASR(data []byte, format InputFormat, options *ASROptions) ([]int16, error) {
+	if options == nil {
+		options = DefaultASROptions()
+	}
+	outputRate := options.SampleRate
+	if outputRate == 0 {
+		outputRate = asrSampleRate
+	}
+	if outputRate < 0 {
+		return nil, fmt.Errorf("invalid sample rate %d", options.SampleRate)
+	}
+	if options.PreEmphasis < 0 || options.PreEmphasis >= 1 {
+		return nil, fmt.Errorf("invalid pre-emphasis %g, must be in [0, 1)", options.PreEmphasis)
+	}
+	if options.LoudnessTarget > 0 {
+		return nil, fmt.Errorf("invalid loudness target %g LUFS, must be negative", options.LoudnessTarget)
+	}
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+
+	if format == FormatUnknown {
+		var err error
+		if format, err = DetectFormat(data); err != nil {
+			return nil, err
+		}
+	}
+	var samples []int16
+	var inputRate int
+	switch format {
+	case FormatWAV:
+		decoded, err := decodeWavFloat(data)
+		if err != nil {
+			return nil, err
+		}
+		samples, inputRate = decoded.mono(), decoded.sampleRate
+	case FormatUlaw:
+		samples, inputRate = decodeUlawSamples(ConvertUlawVariant(data, options.UlawVariant, 0)), 8000
+	default:
+		return nil, fmt.Errorf("unsupported input format %v", format)
+	}
+	if len(samples) == 0 {
+		return nil, ErrEmptyInput
+	}
+
+	resampler, err := NewResampler(inputRate, outputRate, ResampleQualityHigh)
+	if err != nil {
+		return nil, err
+	}
+	signal := append(resampler.Push(samples), resampler.Flush()...)
+	if options.NoiseGateDbov != 0 {
+		applyNoiseGate(signal, outputRate, options.NoiseGateDbov)
+	}
+	if options.HighShelfGainDb != 0 {
+		shelf := newHighShelf(asrShelfFrequency, options.HighShelfGainDb, float64(outputRate))
+		for i, s := range signal {
+			signal[i] = int16(math.Max(-32768, math.Min(32767, math.Round(shelf.process(float64(s))))))
+		}
+	}
+	if options.AGCTargetDbov != 0 {
+		maxGain := options.AGCMaxGainDb
+		if maxGain == 0 {
+			maxGain = defaultAGCMaxGainDb
+		}
+		applyAGC(signal, outputRate, options.AGCTargetDbov, maxGain)
+	}
+	if options.PreEmphasis > 0 {
+		preEmphasize(signal, options.PreEmphasis)
+	}
+	if options.LoudnessTarget < 0 {
+		normalizeLoudness(signal, outputRate, options.LoudnessTarget)
+	}
+	return signal, nil
+}
+
+// validate checks the cleanup stages of the options, returning a
+// *ConfigError for the first value out of range
+func (o *ASROptions) validate() error {
+	v := &configValidator{config: &AudioConfig{}}
+	v.check("HighShelfGainDb", &o.HighShelfGainDb, -maxShelfGainDb, maxShelfGainDb)
+	v.check("NoiseGateDbov", &o.NoiseGateDbov, minReprocessDbov, 0)
+	v.check("AGCTargetDbov", &o.AGCTargetDbov, minReprocessDbov, 0)
+	v.check("AGCMaxGainDb", &o.AGCMaxGainDb, 0, math.Inf(1))
+	return v.err
+}
+
+// ConvertForASRWav is ConvertForASR returning a mono 16-bit WAV file
+func ConvertForASRWav(data []byte, format InputFormat, options *ASROptions) ([]byte, error) {
+	samples, err := ConvertForASR(data, format, options)
+	if err != nil {
+		return nil, err
+	}
+	rate := asrSampleRate
+	if options != nil && options.SampleRate != 0 {
+		rate = options.SampleRate
+	}
+	return encodeWavPCM16(samples, rate)
+}
+
+// mono averages the channels of a decoded WAV into 16-bit samples
+func (d *decodedWav) mono() []int16 {
+	frames := len(d.data) / d.channels
+	samples := make([]int16, frames)
+	for i := range samples {
+		var sum float64
+		for _, v := range d.data[i*d.channels : (i+1)*d.channels] {
+			sum += v
+		}
+		samples[i] = int16(math.Max(-32768, math.Min(32767, math.Round(sum/float64(d.channels)*32768))))
+	}
+	return samples
+}
+
+// preEmphasize applies the first-order high-pass y[n] = x[n] - a*x[n-1] in
+// place, boosting the upper formants some recognizers were trained with
+func preEmphasize(samples []int16, a float64) {
+	previous := 0.0
+	for i, s := range samples {
+		x := float64(s)
+		samples[i] = int16(math.Max(-32768, math.Min(32767, math.Round(x-a*previous))))
+		previous = x
+	}
+}
+
+// normalizeLoudness scales samples in place to an integrated loudness of
+// target LUFS, or less if that would raise the peak above asrPeakLimit.
+// Silence is left as is.
+func normalizeLoudness(samples []int16, sampleRate int, target float64) {
+	signal := make([]float64, len(samples))
+	peak := 0.0
+	for i, s := range samples {
+		signal[i] = float64(s) / 32768
+		peak = math.Max(peak, math.Abs(signal[i]))
+	}
+	loudness := measureLoudness([][]float64{signal}, sampleRate)
+	if math.IsInf(loudness.Integrated, -1) || peak == 0 {
+		return
+	}
+	gain := math.Min(math.Pow(10, (target-loudness.Integrated)/20), asrPeakLimit/peak)
+	for i, v := range signal {
+		samples[i] = int16(math.Max(-32768, math.Min(32767, math.Round(v*gain*32768))))
+	}
+}
@@ -0,0 +1,89 @@
+package wav2ulaw
+
+import (
+	"math"
+	"testing"
+)
+
+func TestConvertForASRFromWav(t *testing.T) {
+	// A 10 kHz tone above the output Nyquist frequency must not alias to 6 kHz
+	input := sineWave(44100, 1000, 44100, 0.5)
+	for i, s := range sineWave(44100, 10000, 44100, 0.2) {
+		input[i] += s
+	}
+	wavBytes := encodeWavDepth(t, input, 44100, 24, 2)
+
+	samples, err := ConvertForASR(wavBytes, FormatUnknown, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 16000 {
+		t.Fatalf("expected 16000 samples, got %d", len(samples))
+	}
+	middle := samples[1000:15000]
+	if level := toneLevel(middle, 1000, 16000); math.Abs(level-0.5) > 0.01 {
+		t.Errorf("1 kHz level %.3f, want 0.5", level)
+	}
+	if level := toneLevel(middle, 6000, 16000); level > 0.001 {
+		t.Errorf("6 kHz alias level %.4f, want none", level)
+	}
+}
+
+func TestConvertForASRFromUlaw(t *testing.T) {
+	ulaw, err := ConvertPCM16ToUlaw(sineWave(8000, 1000, 8000, 0.25), 8000, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wavBytes, err := ConvertForASRWav(ulaw, FormatUlaw, DefaultASROptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := decodeWavFloat(wavBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.sampleRate != 16000 || decoded.channels != 1 || len(decoded.data) != 16000 {
+		t.Fatalf("got %d Hz, %d channels, %d samples", decoded.sampleRate, decoded.channels, len(decoded.data))
+	}
+}
+
+func TestConvertForASRPreEmphasisAndLoudness(t *testing.T) {
+	wavBytes, err := encodeWavPCM16(append(sineWave(16000, 200, 16000, 0.3), sineWave(16000, 4000, 16000, 0.3)...), 16000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := ConvertForASR(wavBytes, FormatWAV, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	emphasized, err := ConvertForASR(wavBytes, FormatWAV, &ASROptions{PreEmphasis: 0.97})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The filter attenuates 200 Hz and boosts 4 kHz
+	low := toneLevel(emphasized[1000:15000], 200, 16000) / toneLevel(plain[1000:15000], 200, 16000)
+	high := toneLevel(emphasized[17000:31000], 4000, 16000) / toneLevel(plain[17000:31000], 4000, 16000)
+	if low > 0.2 || high < 1.3 {
+		t.Errorf("pre-emphasis gains %.2f at 200 Hz and %.2f at 4 kHz", low, high)
+	}
+
+	quiet, err := encodeWavPCM16(sineWave(48000, 1000, 16000, 0.01), 16000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	normalized, err := ConvertForASR(quiet, FormatWAV, &ASROptions{LoudnessTarget: -23})
+	if err != nil {
+		t.Fatal(err)
+	}
+	signal := make([]float64, len(normalized))
+	for i, s := range normalized {
+		signal[i] = float64(s) / 32768
+	}
+	if lufs := measureLoudness([][]float64{signal}, 16000).Integrated; math.Abs(lufs+23) > 0.2 {
+		t.Errorf("loudness %.2f LUFS, want -23", lufs)
+	}
+
+	if _, err := ConvertForASR(quiet, FormatWAV, &ASROptions{PreEmphasis: 1}); err == nil {
+		t.Error("expected an error for pre-emphasis 1")
+	}
+}
@@ -0,0 +1,36 @@
+package wav2ulaw
+
+import "math"
+
+const (
+	// Edge of the passband of AAAuto, relative to the output Nyquist
+	// frequency: 3400 Hz at 8 kHz, the top of the telephone band
+	autoPassbandRatio = 0.85
+	// Attenuation of AAAuto from the output Nyquist frequency up (dB), well
+	// below the quantization noise of u-law
+	autoStopbandDb = 70.0
+)
+
+// isFIR reports whether the anti-aliasing filter is a FIR kernel that the
+// sinc resampler folds into its own
+func (t AntiAliasingType) isFIR() bool {
+	return t == AAWindowedSinc || t == AAAuto
+}
+
+// autoAntiAliasingKernel designs the AAAuto filter for decimating from
+// sampleRate to targetRate: a Kaiser-windowed sinc passing up to
+// autoPassbandRatio of the output Nyquist frequency and attenuating
+// everything above it by autoStopbandDb, so nothing folds back into the
+// output band. Kaiser's formulas give the beta for the attenuation and the
+// length for the transition band at this rate, so the kernel grows with the
+// ratio and the filter order, cutoff and window settings play no part.
+func autoAntiAliasingKernel(sampleRate, targetRate float64) []float64 {
+	nyquist := targetRate / 2
+	pass := nyquist * autoPassbandRatio
+	transition := (nyquist - pass) / sampleRate
+	beta := 0.1102 * (autoStopbandDb - 8.7)
+	taps := (autoStopbandDb - 7.95) / (14.36 * transition)
+	halfLength := int(math.Ceil(taps / 2))
+	cutoff := (pass + nyquist) / 2 / sampleRate
+	return designLowpassFIR(halfLength, cutoff, makeWindow(WindowKaiser, 2*halfLength+1, beta))
+}
@@ -0,0 +1,50 @@
+package wav2ulaw
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAutoAntiAliasingKernelMeetsTargets(t *testing.T) {
+	for _, rate := range []float64{11025, 16000, 22050, 32000, 44100, 48000, 96000} {
+		kernel := autoAntiAliasingKernel(rate, 8000)
+		gainDb := func(freq float64) float64 {
+			return 20 * math.Log10(transferGain(kernel, nil, 2*math.Pi*freq/rate))
+		}
+		for _, freq := range []float64{300, 1000, 3000, 3400} {
+			if g := gainDb(freq); math.Abs(g) > 0.1 {
+				t.Errorf("%g Hz input: gain %.2f dB at %g Hz, want the passband flat", rate, g, freq)
+			}
+		}
+		for freq := 4000.0; freq < rate/2; freq += 50 {
+			if g := gainDb(freq); g > -autoStopbandDb+1 {
+				t.Errorf("%g Hz input: gain %.1f dB at %g Hz, want at most -%g", rate, g, freq, autoStopbandDb)
+				break
+			}
+		}
+	}
+}
+
+func TestAutoAntiAliasingConversion(t *testing.T) {
+	for _, rate := range []int{22050, 44100, 48000} {
+		config := DefaultAudioConfig()
+		config.AntiAliasingType = AAAuto
+		config.NormalizePeak = 0
+		// Settings the automatic design ignores
+		config.AntiAliasingCutoffRatio = 1
+		config.FilterOrder = 2
+
+		// 4.5 kHz folds to 3.5 kHz, inside the telephone band
+		wavBytes, err := encodeWavPCM16(sineWave(rate, 4500, float64(rate), 0.5), rate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ulaw, err := ConvertWavBytesToUlaw(wavBytes, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if level := toneLevel(decodeUlawSamples(ulaw[800:7200]), 3500, 8000); level > 0.001 {
+			t.Errorf("%d Hz: alias at 3500 Hz has level %.4f", rate, level)
+		}
+	}
+}
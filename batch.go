@@ -0,0 +1,197 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"slices"
+	"sync"
+	"time"
+)
+
+// BatchJob is one WAV to u-law conversion run by a BatchConverter
+type BatchJob struct {
+	// Name identifies the job in its result and errors, e.g. the input path
+	Name   string
+	Input  io.Reader
+	Output io.Writer
+	// Settings of this job, nil for the converter's Config
+	Config *AudioConfig
+}
+
+// BatchResult reports the outcome of one BatchJob
+type BatchResult struct {
+	Name string
+	// Position of the job in the slice given to Run
+	Index int
+	// Bytes read from Input and written to Output
+	InputBytes  int64
+	OutputBytes int64
+	// Silence appended to the output by AudioConfig.FrameAlign (bytes)
+	PaddingBytes int64
+	// Time spent on the job, reading and writing included
+	Elapsed time.Duration
+	Err     error
+}
+
+// BatchConverter converts many files on a bounded pool of workers. Jobs
+// share the package's cached filter designs and lookup tables, so each
+// distinct setting is designed once however many files use it.
+type BatchConverter struct {
+	// Jobs converted at once; 0 uses one per CPU
+	Workers int
+	// Settings of jobs without their own, nil for DefaultAudioConfig
+	Config *AudioConfig
+	// Called with each result as its job finishes, from the worker that ran
+	// it, e.g. to report progress; may be nil
+	OnResult func(BatchResult)
+}
+
+// Run converts jobs and returns their results in the same order. Once ctx
+// is cancelled, jobs not yet started are skipped with ctx.Err() as their
+// error; running jobs complete. Use BatchError to turn the failures into a
+// single error.
+func (b *BatchConverter) Run(ctx context.Context, jobs []BatchJob) []BatchResult {
+	workers := b.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	workers = min(workers, len(jobs))
+
+	results := make([]BatchResult, len(jobs))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = b.convert(ctx, i, jobs[i])
+				if b.OnResult != nil {
+					b.OnResult(results[i])
+				}
+			}
+		}()
+	}
+	for i := range jobs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
+
+// convert runs job, the index-th of the batch
+func (b *BatchConverter) convert(ctx context.Context, index int, job BatchJob) (result BatchResult) {
+	result = BatchResult{Name: job.Name, Index: index}
+	if err := ctx.Err(); err != nil {
+		result.Err = err
+		return result
+	}
+	start := time.Now()
+	defer func() { result.Elapsed = time.Since(start) }()
+
+	config := job.Config
+	if config == nil {
+		config = b.Config
+	}
+	if config != nil && config.FrameAlign > 1 {
+		config = withPaddingReport(config, &result.PaddingBytes)
+	}
+	wavBytes, err := io.ReadAll(job.Input)
+	result.InputBytes = int64(len(wavBytes))
+	if err != nil {
+		result.Err = fmt.Errorf("error reading input: %v", err)
+		return result
+	}
+	ulawBytes, err := ConvertWavBytesToUlaw(wavBytes, config)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	n, err := job.Output.Write(ulawBytes)
+	result.OutputBytes = int64(n)
+	if err != nil {
+		result.Err = fmt.Errorf("error writing output: %v", err)
+	}
+	return result
+}
+
+// ConvertAll converts WAV files held in memory, keyed by name, to u-law on
+// up to concurrency workers (0 = one per CPU) and returns the output of each
+// key that converted and the error of each that did not, nil when none
+// failed. The resampler tables for the inputs' sample rates are designed
+// once before the workers start, rather than by the first workers to need
+// them. Cancelling ctx skips the files not yet started, with ctx.Err() as
+// their error.
+func ConvertAll(ctx context.Context, inputs map[string][]byte, config *AudioConfig, concurrency int) (map[string][]byte, map[string]error) {
+	if config == nil {
+		config = DefaultAudioConfig()
+	}
+	names := make([]string, 0, len(inputs))
+	for name := range inputs {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var rates []int
+	jobs := make([]BatchJob, len(names))
+	outputs := make([]bytes.Buffer, len(names))
+	for i, name := range names {
+		rate := config.InputSampleRate
+		if header, err := inspectWav(bytes.NewReader(inputs[name]), config.Limits); err == nil && rate == 0 {
+			rate = header.sampleRate
+		}
+		if !slices.Contains(rates, rate) {
+			rates = append(rates, rate)
+		}
+		jobs[i] = BatchJob{Name: name, Input: bytes.NewReader(inputs[name]), Output: &outputs[i]}
+	}
+	PrecomputeResampler(config, rates...)
+
+	converter := &BatchConverter{Workers: concurrency, Config: config}
+	results := make(map[string][]byte, len(names))
+	var errs map[string]error
+	for _, r := range converter.Run(ctx, jobs) {
+		if r.Err != nil {
+			if errs == nil {
+				errs = make(map[string]error)
+			}
+			errs[r.Name] = r.Err
+			continue
+		}
+		results[r.Name] = outputs[r.Index].Bytes()
+	}
+	return results, errs
+}
+
+// withPaddingReport returns config recording the size of the "frame align"
+// stage's padding in padding, besides reporting the stage as before
+func withPaddingReport(config *AudioConfig, padding *int64) *AudioConfig {
+	c := *config
+	c.OnStageMetrics = func(m StageMetrics) {
+		if m.Stage == "frame align" {
+			*padding = int64(m.OutputSamples - m.InputSamples)
+		}
+		if config.OnStageMetrics != nil {
+			config.OnStageMetrics(m)
+		}
+	}
+	return &c
+}
+
+// BatchError joins the errors of the failed jobs in results, each prefixed
+// with its job's name, or returns nil when every job succeeded. The joined
+// error matches the individual ones with errors.Is and errors.As.
+func BatchError(results []BatchResult) error {
+	var errs []error
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", r.Name, r.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,95 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestBatchConverter(t *testing.T) {
+	wavBytes, err := encodeWavPCM16(sineWave(16000, 440, 16000, 0.5), 16000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := ConvertWavBytesToUlaw(wavBytes, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outputs := make([]bytes.Buffer, 6)
+	jobs := make([]BatchJob, len(outputs))
+	for i := range jobs {
+		jobs[i] = BatchJob{Name: string(rune('a' + i)), Input: bytes.NewReader(wavBytes), Output: &outputs[i]}
+	}
+	jobs[3].Input = strings.NewReader("not a wav file")
+	var reported atomic.Int32
+	b := &BatchConverter{Workers: 2, OnResult: func(BatchResult) { reported.Add(1) }}
+	results := b.Run(context.Background(), jobs)
+
+	if int(reported.Load()) != len(jobs) {
+		t.Errorf("OnResult called %d times, want %d", reported.Load(), len(jobs))
+	}
+	for i, r := range results {
+		if r.Index != i || r.Name != jobs[i].Name {
+			t.Errorf("result %d is for job %d (%s)", i, r.Index, r.Name)
+		}
+		if i == 3 {
+			if r.Err == nil {
+				t.Error("expected an error for the invalid input")
+			}
+			continue
+		}
+		if r.Err != nil || !bytes.Equal(outputs[i].Bytes(), want) || r.OutputBytes != int64(len(want)) {
+			t.Errorf("job %d: %v, %d bytes written", i, r.Err, outputs[i].Len())
+		}
+	}
+	err = BatchError(results)
+	if err == nil || !strings.HasPrefix(err.Error(), "d: ") {
+		t.Errorf("got batch error %v, want one naming job d", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results = b.Run(ctx, jobs[:2])
+	if !errors.Is(BatchError(results), context.Canceled) {
+		t.Error("expected jobs of a cancelled batch to fail with context.Canceled")
+	}
+}
+
+func TestConvertAll(t *testing.T) {
+	inputs := map[string][]byte{"broken.wav": []byte("not a wav file")}
+	want := map[string][]byte{}
+	for _, rate := range []int{8000, 16000, 22050, 44100} {
+		wavBytes, err := encodeWavPCM16(sineWave(rate, 440, float64(rate), 0.5), rate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := fmt.Sprintf("%d.wav", rate)
+		inputs[name] = wavBytes
+		if want[name], err = ConvertWavBytesToUlaw(wavBytes, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	outputs, errs := ConvertAll(context.Background(), inputs, nil, 3)
+	if len(outputs) != len(want) {
+		t.Errorf("%d outputs, want %d", len(outputs), len(want))
+	}
+	for name, ulaw := range want {
+		if !bytes.Equal(outputs[name], ulaw) {
+			t.Errorf("%s differs from a single conversion", name)
+		}
+	}
+	var wavErr *WavError
+	if len(errs) != 1 || !errors.As(errs["broken.wav"], &wavErr) {
+		t.Errorf("errors %v, want a WavError for broken.wav", errs)
+	}
+
+	if outputs, errs := ConvertAll(context.Background(), map[string][]byte{"8000.wav": inputs["8000.wav"]}, nil, 0); len(outputs) != 1 || errs != nil {
+		t.Errorf("single file: %d outputs, errors %v", len(outputs), errs)
+	}
+}
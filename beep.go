@@ -0,0 +1,80 @@
+package wav2ulaw
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// Raised-cosine ramp at each end of a beep, so it starts and stops
+	// without a click (ms)
+	beepRampMs = 5
+	// Accepted range of beep frequencies (Hz), kept inside the telephone band
+	minBeepFrequency = 20
+	maxBeepFrequency = 3800
+	// Loudest accepted beep (dBov); the peak of a sine is 3 dB above its RMS
+	maxBeepDbov = -3
+)
+
+// Beep is a tone mixed into the output, e.g. the recording notification
+// tone compliance rules require in archived calls. The tone is added to the
+// audio rather than replacing it, so the timing of the audio is unchanged.
+type Beep struct {
+	// Start of the tone from the beginning of the output, PadStart included
+	At time.Duration
+	// Length of the tone, including its 5 ms ramps
+	Duration time.Duration
+	// Tone frequency (Hz)
+	Frequency float64
+	// RMS level of the tone (dBov)
+	LevelDbov float64
+	// Interval after which the tone repeats until the end of the output
+	// (0 = once)
+	Repeat time.Duration
+}
+
+// mixBeeps adds beeps to the 8 kHz output samples in place
+func mixBeeps(samples []int16, beeps []Beep) {
+	mixBeepsAt(samples, 0, beeps)
+}
+
+// mixBeepsAt adds beeps to the block of 8 kHz output starting at offset, so
+// output written block by block gets exactly the tones mixBeeps would give
+// the whole output
+func mixBeepsAt(block []int16, offset int, beeps []Beep) {
+	end := offset + len(block)
+	for _, beep := range beeps {
+		length := padLength(beep.Duration, 8000)
+		if length == 0 {
+			continue
+		}
+		amplitude := 32767 * math.Sqrt2 * math.Pow(10, beep.LevelDbov/20)
+		ramp := min(beepRampMs*8000/1000, length/2)
+		step := 2 * math.Pi * beep.Frequency / 8000
+
+		first := padLength(beep.At, 8000)
+		interval := padLength(beep.Repeat, 8000)
+		k := 0
+		if interval > 0 && offset > first+length {
+			// Skip the repeats that end before the block
+			k = (offset - first - length) / interval
+		}
+		for ; ; k++ {
+			start := first + k*interval
+			if start >= end || (k > 0 && interval == 0) {
+				break
+			}
+			for i := max(start, offset); i < min(start+length, end); i++ {
+				j := i - start
+				gain := 1.0
+				if j < ramp {
+					gain = fadeGain(FadeCosine, float64(j)/float64(ramp))
+				} else if length-1-j < ramp {
+					gain = fadeGain(FadeCosine, float64(length-1-j)/float64(ramp))
+				}
+				v := float64(block[i-offset]) + amplitude*gain*math.Sin(step*float64(j))
+				block[i-offset] = int16(math.Max(-32768, math.Min(32767, math.Round(v))))
+			}
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestMixBeepsAtMatchesWhole(t *testing.T) {
+	beeps := []Beep{
+		{At: 100 * time.Millisecond, Duration: 300 * time.Millisecond, Frequency: 1400, LevelDbov: -20, Repeat: time.Second},
+		{At: 2500 * time.Millisecond, Duration: 50 * time.Millisecond, Frequency: 440, LevelDbov: -30},
+	}
+	whole := make([]int16, 4*8000)
+	mixBeeps(whole, beeps)
+
+	blocks := make([]int16, len(whole))
+	for offset := 0; offset < len(blocks); offset += 777 {
+		mixBeepsAt(blocks[offset:min(offset+777, len(blocks))], offset, beeps)
+	}
+	if !slices.Equal(whole, blocks) {
+		t.Fatal("beeps mixed block by block differ from the whole output")
+	}
+
+	// Repeats at 100 ms, 1.1 s, 2.1 s and 3.1 s with nothing in between
+	for _, at := range []int{100, 1100, 2100, 3100} {
+		tone := whole[(at+50)*8 : (at+250)*8]
+		if level := rmsDbov(tone); math.Abs(level+20) > 0.5 {
+			t.Errorf("beep at %d ms: level %.2f dBov, want -20", at, level)
+		}
+	}
+	if level := rmsDbov(whole[500*8 : 1000*8]); !math.IsInf(level, -1) {
+		t.Errorf("level %.2f dBov between beeps, want silence", level)
+	}
+	if level := toneLevel(whole[2500*8:2550*8], 440, 8000); level == 0 {
+		t.Error("missing the one-off 440 Hz beep")
+	}
+}
+
+func TestConvertWithBeeps(t *testing.T) {
+	wavBytes, err := encodeWavPCM16(make([]int16, 16000*2), 16000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := DefaultAudioConfig()
+	config.PadStart = 500 * time.Millisecond
+	config.PadEnd = 500 * time.Millisecond
+	// Beeps land in the leading padding, the audio and the trailing padding
+	config.Beeps = []Beep{{At: 200 * time.Millisecond, Duration: 200 * time.Millisecond, Frequency: 1400, LevelDbov: -18, Repeat: time.Second}}
+
+	ulaw, err := ConvertWavBytesToUlaw(wavBytes, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var streamed bytes.Buffer
+	if err := ConvertWavStreamToUlaw(bytes.NewReader(wavBytes), &streamed, config); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(ulaw, streamed.Bytes()) {
+		t.Error("streamed output differs from the in-memory conversion")
+	}
+
+	samples := decodeUlawSamples(ulaw)
+	if len(samples) != 3*8000 {
+		t.Fatalf("got %d samples, want 24000", len(samples))
+	}
+	for _, at := range []int{200, 1200, 2200} {
+		if level := toneLevel(samples[at*8:(at+200)*8], 1400, 8000); level < 0.1 {
+			t.Errorf("beep at %d ms: 1400 Hz level %.3f", at, level)
+		}
+	}
+
+	config.Beeps[0].Frequency = 5000
+	var configErr *ConfigError
+	if _, err := ConvertWavBytesToUlaw(wavBytes, config); !errors.As(err, &configErr) || configErr.Field != "Beeps[0].Frequency" {
+		t.Errorf("expected a ConfigError for a 5000 Hz beep, got %v", err)
+	}
+}
@@ -0,0 +1,44 @@
+package wav2ulaw
+
+import "math"
+
+// biquad is a stateful second-order IIR section in direct form I with
+// normalized coefficients (a0 = 1). It keeps its delay line between calls
+// so signals can be filtered in consecutive blocks.
+type biquad struct {
+	b0, b1, b2 float64
+	a1, a2     float64
+	x1, x2     float64
+	y1, y2     float64
+}
+
+// process filters one sample
+func (f *biquad) process(x float64) float64 {
+	y := f.b0*x + f.b1*f.x1 + f.b2*f.x2 - f.a1*f.y1 - f.a2*f.y2
+	f.x2, f.x1 = f.x1, x
+	f.y2, f.y1 = f.y1, y
+	return y
+}
+
+// reset clears the delay line
+func (f *biquad) reset() {
+	f.x1, f.x2, f.y1, f.y2 = 0, 0, 0, 0
+}
+
+// newHighShelf designs a shelving filter changing the level above frequency
+// by gainDb, with the steepest slope free of overshoot (RBJ cookbook, S = 1)
+func newHighShelf(frequency, gainDb, sampleRate float64) *biquad {
+	a := math.Pow(10, gainDb/40)
+	w0 := 2 * math.Pi * frequency / sampleRate
+	cos := math.Cos(w0)
+	alpha := math.Sin(w0) / 2 * math.Sqrt2
+	beta := 2 * math.Sqrt(a) * alpha
+	a0 := (a + 1) - (a-1)*cos + beta
+	return &biquad{
+		b0: a * ((a + 1) + (a-1)*cos + beta) / a0,
+		b1: -2 * a * ((a - 1) + (a+1)*cos) / a0,
+		b2: a * ((a + 1) + (a-1)*cos - beta) / a0,
+		a1: 2 * ((a - 1) - (a+1)*cos) / a0,
+		a2: ((a + 1) - (a-1)*cos - beta) / a0,
+	}
+}
@@ -0,0 +1,110 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// BlobStore reads and writes the objects of one bucket of a storage service
+// such as S3 or GCS. Keys are the object names within the bucket.
+type BlobStore interface {
+	// Get opens an object for reading
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Put creates or replaces an object with the content of r
+	Put(ctx context.Context, key string, r io.Reader) error
+	// List returns the keys of the objects whose key starts with prefix
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// BlobOpener returns the store of a bucket
+type BlobOpener func(ctx context.Context, bucket string) (BlobStore, error)
+
+var (
+	blobMu      sync.Mutex
+	blobOpeners = map[string]BlobOpener{}
+)
+
+// RegisterBlobStore makes URLs of a scheme (e.g. "s3" for s3://bucket/key)
+// open their bucket with open. The library registers no schemes itself, so
+// it carries no storage SDKs; the CLI registers s3 and gs.
+func RegisterBlobStore(scheme string, open BlobOpener) {
+	blobMu.Lock()
+	defer blobMu.Unlock()
+	blobOpeners[scheme] = open
+}
+
+// BlobSchemes returns the registered URL schemes, sorted
+func BlobSchemes() []string {
+	blobMu.Lock()
+	defer blobMu.Unlock()
+	schemes := make([]string, 0, len(blobOpeners))
+	for scheme := range blobOpeners {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// ParseBlobURL splits a scheme://bucket/key URL whose scheme is registered.
+// ok is false for any other path, such as a local file.
+func ParseBlobURL(url string) (scheme, bucket, key string, ok bool) {
+	scheme, rest, found := strings.Cut(url, "://")
+	if !found {
+		return "", "", "", false
+	}
+	blobMu.Lock()
+	_, registered := blobOpeners[scheme]
+	blobMu.Unlock()
+	if !registered {
+		return "", "", "", false
+	}
+	bucket, key, _ = strings.Cut(rest, "/")
+	if bucket == "" {
+		return "", "", "", false
+	}
+	return scheme, bucket, key, true
+}
+
+// OpenBlobURL opens the store of a blob URL's bucket and returns it with the
+// key the URL names
+func OpenBlobURL(ctx context.Context, url string) (BlobStore, string, error) {
+	scheme, bucket, key, ok := ParseBlobURL(url)
+	if !ok {
+		return nil, "", fmt.Errorf("invalid blob URL '%s', schemes are %s", url, strings.Join(BlobSchemes(), ", "))
+	}
+	blobMu.Lock()
+	open := blobOpeners[scheme]
+	blobMu.Unlock()
+	store, err := open(ctx, bucket)
+	if err != nil {
+		return nil, "", fmt.Errorf("cannot open %s://%s: %v", scheme, bucket, err)
+	}
+	return store, key, nil
+}
+
+// ConvertBlob converts the WAV object inputKey of store to u-law, written to
+// outputKey of the same store
+func ConvertBlob(ctx context.Context, store BlobStore, inputKey, outputKey string, config *AudioConfig) error {
+	if inputKey == outputKey {
+		return fmt.Errorf("output would overwrite the input %s", inputKey)
+	}
+	r, err := store.Get(ctx, inputKey)
+	if err != nil {
+		return err
+	}
+	wavBytes, err := io.ReadAll(r)
+	r.Close()
+	if err != nil {
+		return fmt.Errorf("error reading %s: %v", inputKey, err)
+	}
+	ulawBytes, err := ConvertWavBytesToUlaw(wavBytes, config)
+	if err != nil {
+		return err
+	}
+	return store.Put(ctx, outputKey, bytes.NewReader(ulawBytes))
+}
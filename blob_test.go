@@ -0,0 +1,93 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// memoryBlobStore keeps objects in a map
+type memoryBlobStore map[string][]byte
+
+func (s memoryBlobStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	data, ok := s[key]
+	if !ok {
+		return nil, fmt.Errorf("no object %s", key)
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s memoryBlobStore) Put(ctx context.Context, key string, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	s[key] = data
+	return err
+}
+
+func (s memoryBlobStore) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	for key := range s {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func TestParseBlobURL(t *testing.T) {
+	RegisterBlobStore("mem", func(ctx context.Context, bucket string) (BlobStore, error) {
+		return memoryBlobStore{}, nil
+	})
+
+	tests := []struct {
+		url, bucket, key string
+		ok               bool
+	}{
+		{"mem://prompts/en/welcome.wav", "prompts", "en/welcome.wav", true},
+		{"mem://prompts", "prompts", "", true},
+		{"mem:///welcome.wav", "", "", false},
+		{"other://prompts/welcome.wav", "", "", false},
+		{"prompts/welcome.wav", "", "", false},
+	}
+	for _, tt := range tests {
+		scheme, bucket, key, ok := ParseBlobURL(tt.url)
+		if ok != tt.ok || bucket != tt.bucket || key != tt.key || (ok && scheme != "mem") {
+			t.Errorf("ParseBlobURL(%q) = %q, %q, %q, %v", tt.url, scheme, bucket, key, ok)
+		}
+	}
+	if _, _, err := OpenBlobURL(context.Background(), "other://prompts/welcome.wav"); err == nil {
+		t.Error("opened a URL of an unregistered scheme")
+	}
+}
+
+func TestConvertBlob(t *testing.T) {
+	const rate = 16000
+	wavBytes, err := encodeWavPCM16(sineWave(rate, 440, rate, 0.5), rate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := memoryBlobStore{"in/tone.wav": wavBytes}
+
+	ctx := context.Background()
+	if err := ConvertBlob(ctx, store, "in/tone.wav", "out/tone.ulaw", nil); err != nil {
+		t.Fatal(err)
+	}
+	want, err := ConvertWavBytesToUlaw(wavBytes, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(store["out/tone.ulaw"], want) {
+		t.Errorf("stored %d bytes, want the %d of ConvertWavBytesToUlaw", len(store["out/tone.ulaw"]), len(want))
+	}
+
+	if err := ConvertBlob(ctx, store, "in/tone.wav", "in/tone.wav", nil); err == nil {
+		t.Error("converted an object onto itself")
+	}
+	if err := ConvertBlob(ctx, store, "in/missing.wav", "out/missing.ulaw", nil); err == nil {
+		t.Error("converted a missing object")
+	}
+}
@@ -0,0 +1,100 @@
+package wav2ulaw
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"time"
+)
+
+// Buffer is mono 16-bit audio held in memory for sample-level editing, such
+// as removing a cough from a prompt or inserting a pause, before it is
+// converted. Positions are sample indexes; Index converts from time.
+type Buffer struct {
+	Samples    []int16
+	SampleRate int
+}
+
+// BufferFromWav decodes a WAV file into a Buffer at its own sample rate,
+// averaging the channels of multi-channel files
+func BufferFromWav(wavBytes []byte) (*Buffer, error) {
+	decoded, err := decodeWavFloat(wavBytes)
+	if err != nil {
+		return nil, err
+	}
+	return &Buffer{Samples: decoded.mono(), SampleRate: decoded.sampleRate}, nil
+}
+
+// BufferFromUlaw decodes raw 8 kHz u-law into a Buffer
+func BufferFromUlaw(ulawBytes []byte) *Buffer {
+	return &Buffer{Samples: decodeUlawSamples(ulawBytes), SampleRate: 8000}
+}
+
+// Index returns the sample index at time d from the start, rounded to the
+// nearest sample
+func (b *Buffer) Index(d time.Duration) int {
+	return int(math.Round(d.Seconds() * float64(b.SampleRate)))
+}
+
+// Duration returns the length of the audio
+func (b *Buffer) Duration() time.Duration {
+	return samplesDuration(len(b.Samples), b.SampleRate)
+}
+
+// Cut removes the samples from index from up to, not including, index to and
+// returns them, so they can be inserted elsewhere
+func (b *Buffer) Cut(from, to int) ([]int16, error) {
+	if err := b.checkRange(from, to); err != nil {
+		return nil, err
+	}
+	removed := slices.Clone(b.Samples[from:to])
+	b.Samples = slices.Delete(b.Samples, from, to)
+	return removed, nil
+}
+
+// Insert inserts samples before index at; at may be the length of the
+// buffer to append them
+func (b *Buffer) Insert(at int, samples []int16) error {
+	if err := b.checkRange(at, at); err != nil {
+		return err
+	}
+	b.Samples = slices.Insert(b.Samples, at, samples...)
+	return nil
+}
+
+// InsertSilence inserts d of digital silence before index at
+func (b *Buffer) InsertSilence(at int, d time.Duration) error {
+	if d < 0 {
+		return fmt.Errorf("invalid silence duration %v", d)
+	}
+	return b.Insert(at, make([]int16, padLength(d, b.SampleRate)))
+}
+
+// Replace replaces the samples from index from up to, not including, index
+// to with samples, which may differ in length
+func (b *Buffer) Replace(from, to int, samples []int16) error {
+	if err := b.checkRange(from, to); err != nil {
+		return err
+	}
+	b.Samples = slices.Replace(b.Samples, from, to, samples...)
+	return nil
+}
+
+// Wav encodes the buffer as a 16-bit mono WAV file
+func (b *Buffer) Wav() ([]byte, error) {
+	return encodeWavPCM16(b.Samples, b.SampleRate)
+}
+
+// Ulaw converts the buffer to u-law through the processing chain of config
+// (nil for DefaultAudioConfig), like ConvertPCM16ToUlaw
+func (b *Buffer) Ulaw(config *AudioConfig) ([]byte, error) {
+	return ConvertPCM16ToUlaw(b.Samples, b.SampleRate, config)
+}
+
+// checkRange verifies that [from, to) lies within the buffer
+func (b *Buffer) checkRange(from, to int) error {
+	if from < 0 || to < from || to > len(b.Samples) {
+		return fmt.Errorf("sample range %d-%d is outside the buffer of %d samples", from, to, len(b.Samples))
+	}
+	return nil
+}
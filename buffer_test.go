@@ -0,0 +1,68 @@
+package wav2ulaw
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestBufferEditing(t *testing.T) {
+	b := &Buffer{Samples: []int16{0, 1, 2, 3, 4, 5, 6, 7}, SampleRate: 8000}
+
+	// Move a cough from the middle to the end
+	cough, err := b.Cut(2, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Insert(len(b.Samples), cough); err != nil {
+		t.Fatal(err)
+	}
+	if want := []int16{0, 1, 4, 5, 6, 7, 2, 3}; !slices.Equal(b.Samples, want) {
+		t.Errorf("after cut and insert: %v, want %v", b.Samples, want)
+	}
+
+	if err := b.Replace(1, 5, []int16{9}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []int16{0, 9, 7, 2, 3}; !slices.Equal(b.Samples, want) {
+		t.Errorf("after replace: %v, want %v", b.Samples, want)
+	}
+
+	if err := b.InsertSilence(b.Index(125*time.Microsecond), 500*time.Microsecond); err != nil {
+		t.Fatal(err)
+	}
+	if want := []int16{0, 0, 0, 0, 0, 9, 7, 2, 3}; !slices.Equal(b.Samples, want) {
+		t.Errorf("after inserting silence: %v, want %v", b.Samples, want)
+	}
+
+	for _, r := range [][2]int{{-1, 2}, {3, 2}, {0, 10}} {
+		if _, err := b.Cut(r[0], r[1]); err == nil {
+			t.Errorf("expected an error cutting %d-%d", r[0], r[1])
+		}
+	}
+	if err := b.Insert(10, cough); err == nil {
+		t.Error("expected an error inserting past the end")
+	}
+}
+
+func TestBufferFromWav(t *testing.T) {
+	samples := sineWave(1600, 440, 16000, 0.5)
+	wavBytes, err := encodeWavPCM16(samples, 16000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := BufferFromWav(wavBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.SampleRate != 16000 || !slices.Equal(b.Samples, samples) || b.Duration() != 100*time.Millisecond {
+		t.Fatalf("decoded %d samples at %d Hz, want the input back", len(b.Samples), b.SampleRate)
+	}
+	ulaw, err := b.Ulaw(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ulaw) != 800 {
+		t.Errorf("got %d u-law samples, want 800", len(ulaw))
+	}
+}
@@ -0,0 +1,201 @@
+// Command capi exports the converter as a C shared library, so services in
+// other languages can convert in-process instead of spawning the CLI.
+//
+// Build with make c-shared, or directly:
+//
+//	go build -buildmode=c-shared -o libwav2ulaw.so ./capi
+//
+// The build also writes libwav2ulaw.h declaring:
+//
+//	int wav2ulaw_convert(unsigned char* input, size_t inputLen, char* configJSON,
+//	                     unsigned char** output, size_t* outputLen, char** errOut);
+//	void wav2ulaw_free(void* ptr);
+//
+// wav2ulaw_convert returns 0 and sets output on success. On failure it
+// returns 1 and, when errOut is not NULL, sets it to a message. Both buffers
+// must be released with wav2ulaw_free. The functions are safe to call from
+// several threads at once.
+package main
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"unsafe"
+	"wav2ulaw"
+)
+
+// Library configurations selectable with the "preset" key
+var presets = map[string]func() *wav2ulaw.AudioConfig{
+	"telephony":      wav2ulaw.TelephonyConfig,
+	"voicemail":      wav2ulaw.VoicemailConfig,
+	"tts-narrowband": wav2ulaw.TTSNarrowbandConfig,
+	"tts-fast":       wav2ulaw.TTSFastConfig,
+	"raw":            wav2ulaw.RawPassthroughConfig,
+}
+
+// convertConfig is the JSON configuration of a conversion. Keys are the
+// names of the CLI flags, as in its config files; settings left out keep the
+// value of the preset, or of wav2ulaw.DefaultAudioConfig without one.
+type convertConfig struct {
+	Mode       string `json:"mode"`
+	Preset     string `json:"preset"`
+	SampleRate uint32 `json:"sample-rate"`
+
+	LowPass           *float64 `json:"low-pass"`
+	HighPass          *float64 `json:"high-pass"`
+	Normalize         *float64 `json:"normalize"`
+	Tempo             *float64 `json:"tempo"`
+	CompressRatio     *float64 `json:"compress-ratio"`
+	CompressThreshold *float64 `json:"compress-threshold"`
+	FadeIn            *float64 `json:"fade-in"`
+	FadeOut           *float64 `json:"fade-out"`
+	FadeShape         *int     `json:"fade-shape"`
+	WindowSize        *int     `json:"window-size"`
+	ResampleMethod    *int     `json:"resample-method"`
+	Window            *int     `json:"window"`
+	KaiserBeta        *float64 `json:"kaiser-beta"`
+	AntiAliasingRatio *float64 `json:"anti-aliasing-ratio"`
+	AntiAliasingType  *int     `json:"anti-aliasing-type"`
+	FilterOrder       *int     `json:"filter-order"`
+	ChebyshevRipple   *float64 `json:"chebyshev-ripple"`
+	Concurrency       *int     `json:"concurrency"`
+	Deterministic     *bool    `json:"deterministic"`
+}
+
+// audioConfig builds the library configuration the settings describe
+func (c *convertConfig) audioConfig() (*wav2ulaw.AudioConfig, error) {
+	config := wav2ulaw.DefaultAudioConfig()
+	if c.Preset != "" {
+		preset, ok := presets[c.Preset]
+		if !ok {
+			return nil, fmt.Errorf("invalid preset '%s'. Must be 'telephony', 'voicemail', 'tts-narrowband', 'tts-fast' or 'raw'", c.Preset)
+		}
+		config = preset()
+	}
+	setFloat(&config.LowPassCutoff, c.LowPass)
+	setFloat(&config.HighPassCutoff, c.HighPass)
+	setFloat(&config.NormalizePeak, c.Normalize)
+	setFloat(&config.Tempo, c.Tempo)
+	setFloat(&config.CompressionRatio, c.CompressRatio)
+	setFloat(&config.CompressionThreshold, c.CompressThreshold)
+	setFloat(&config.FadeInMs, c.FadeIn)
+	setFloat(&config.FadeOutMs, c.FadeOut)
+	setFloat(&config.KaiserBeta, c.KaiserBeta)
+	setFloat(&config.AntiAliasingCutoffRatio, c.AntiAliasingRatio)
+	setFloat(&config.ChebyshevRipple, c.ChebyshevRipple)
+	if c.FadeShape != nil {
+		config.FadeShape = wav2ulaw.FadeShape(*c.FadeShape)
+	}
+	if c.WindowSize != nil {
+		config.ResamplingWindowSize = *c.WindowSize
+	}
+	if c.ResampleMethod != nil {
+		config.ResampleMethod = wav2ulaw.ResampleMethod(*c.ResampleMethod)
+	}
+	if c.Window != nil {
+		config.WindowFunction = wav2ulaw.WindowFunction(*c.Window)
+	}
+	if c.AntiAliasingType != nil {
+		config.AntiAliasingType = wav2ulaw.AntiAliasingType(*c.AntiAliasingType)
+	}
+	if c.FilterOrder != nil {
+		if *c.FilterOrder < 2 || *c.FilterOrder > 8 {
+			return nil, fmt.Errorf("invalid filter order %d. Must be between 2 and 8", *c.FilterOrder)
+		}
+		config.FilterOrder = *c.FilterOrder
+	}
+	if c.Concurrency != nil {
+		config.Concurrency = *c.Concurrency
+	}
+	if c.Deterministic != nil {
+		config.Deterministic = *c.Deterministic
+	}
+	return config, nil
+}
+
+// setFloat overrides *field when value is set
+func setFloat(field *float64, value *float64) {
+	if value != nil {
+		*field = *value
+	}
+}
+
+// convert runs one conversion. An empty configJSON converts WAV to u-law
+// with the default settings.
+func convert(input []byte, configJSON string) ([]byte, error) {
+	settings := convertConfig{Mode: "wav2ulaw", SampleRate: 8000}
+	if configJSON != "" {
+		decoder := json.NewDecoder(bytes.NewReader([]byte(configJSON)))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&settings); err != nil {
+			return nil, fmt.Errorf("invalid config: %v", err)
+		}
+	}
+	config, err := settings.audioConfig()
+	if err != nil {
+		return nil, err
+	}
+	switch settings.Mode {
+	case "wav2ulaw":
+		return wav2ulaw.ConvertWavBytesToUlaw(input, config)
+	case "ulaw2wav":
+		return wav2ulaw.ConvertUlawBytesToWav(input, settings.SampleRate, config.ResamplingWindowSize)
+	}
+	return nil, fmt.Errorf("invalid mode '%s'. Must be 'wav2ulaw' or 'ulaw2wav'", settings.Mode)
+}
+
+//export wav2ulaw_convert
+func wav2ulaw_convert(input *C.uchar, inputLen C.size_t, configJSON *C.char, output **C.uchar, outputLen *C.size_t, errOut **C.char) C.int {
+	if output == nil || outputLen == nil {
+		return fail(errOut, fmt.Errorf("output and output_len must not be NULL"))
+	}
+	*output, *outputLen = nil, 0
+
+	var data []byte
+	if input != nil && inputLen > 0 {
+		// Copied, as C.GoBytes is limited to 2 GiB
+		data = bytes.Clone(unsafe.Slice((*byte)(unsafe.Pointer(input)), int(inputLen)))
+	}
+	var config string
+	if configJSON != nil {
+		config = C.GoString(configJSON)
+	}
+	result, err := convert(data, config)
+	if err != nil {
+		return fail(errOut, err)
+	}
+
+	// malloc(0) may return NULL, which callers would take for a failure
+	buf := C.malloc(C.size_t(max(len(result), 1)))
+	if buf == nil {
+		return fail(errOut, fmt.Errorf("out of memory"))
+	}
+	copy(unsafe.Slice((*byte)(buf), len(result)), result)
+	*output, *outputLen = (*C.uchar)(buf), C.size_t(len(result))
+	if errOut != nil {
+		*errOut = nil
+	}
+	return 0
+}
+
+//export wav2ulaw_free
+func wav2ulaw_free(ptr unsafe.Pointer) {
+	C.free(ptr)
+}
+
+// fail reports err through errOut, when given, and returns the failure code
+func fail(errOut **C.char, err error) C.int {
+	if errOut != nil {
+		*errOut = C.CString(err.Error())
+	}
+	return 1
+}
+
+// main is required by -buildmode=c-shared but never runs
+func main() {}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+)
+
+// wavTone returns a mono 16-bit WAV file holding n samples of a 1 kHz tone
+func wavTone(n, rate int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(36+2*n))
+	buf.WriteString("WAVEfmt ")
+	for _, v := range []any{uint32(16), uint16(1), uint16(1), uint32(rate), uint32(2 * rate), uint16(2), uint16(16)} {
+		binary.Write(&buf, binary.LittleEndian, v)
+	}
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(2*n))
+	for i := 0; i < n; i++ {
+		binary.Write(&buf, binary.LittleEndian, int16(8000*math.Sin(2*math.Pi*1000*float64(i)/float64(rate))))
+	}
+	return buf.Bytes()
+}
+
+func TestConvert(t *testing.T) {
+	wav := wavTone(16000, 16000)
+	ulaw, err := convert(wav, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ulaw) != 8000 {
+		t.Fatalf("got %d u-law bytes, want 8000", len(ulaw))
+	}
+
+	back, err := convert(ulaw, `{"mode": "ulaw2wav", "sample-rate": 16000}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 44 + 2*16000; len(back) != want {
+		t.Fatalf("got %d WAV bytes, want %d", len(back), want)
+	}
+
+	if _, err := convert(wav, `{"preset": "voicemail", "normalize": 0.5, "anti-aliasing-type": 1}`); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConvertInvalidConfig(t *testing.T) {
+	wav := wavTone(800, 8000)
+	for config, want := range map[string]string{
+		`{"lowpass": 3000}`:   "unknown field",
+		`{"mode": "wav2mp3"}`: "invalid mode",
+		`{"preset": "hifi"}`:  "invalid preset",
+		`not json`:            "invalid config",
+	} {
+		if _, err := convert(wav, config); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("config %s: got error %v, want %q", config, err, want)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package wav2ulaw
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Input converted between checkpoints when CheckpointOptions.Interval is 0
+const defaultCheckpointInterval = time.Minute
+
+// CheckpointOptions control ConvertWavFileResumable
+type CheckpointOptions struct {
+	// File the progress is saved to (default: the output path with
+	// ".checkpoint" appended)
+	Path string
+	// Input audio converted between checkpoints (0 = 1 minute)
+	Interval time.Duration
+}
+
+// ConvertWavFileResumable converts the WAV file at inputPath to u-law at
+// outputPath, saving a checkpoint after every options.Interval of input so an
+// interrupted conversion of a multi-hour recording resumes where it stopped
+// instead of restarting. A checkpoint holds the input position, the output
+// length and the DSP state of a ChunkEncoder, so stages that need the whole
+// signal are not applied and the channels are mixed to mono; the output is
+// the same whether or not the conversion was interrupted. When ctx is done a
+// checkpoint is saved before ctx's error is returned. The checkpoint is
+// removed once the conversion completes. A checkpoint saved for another input
+// or configuration is not resumed from: it fails with an error wrapping
+// ErrStateMismatch, and removing it starts over. It returns the input position
+// the conversion resumed from, 0 for a fresh start.
+func ConvertWavFileResumable(ctx context.Context, inputPath, outputPath string, config *AudioConfig, options *CheckpointOptions) (time.Duration, error) {
+	if config == nil {
+		config = DefaultAudioConfig()
+	}
+	if options == nil {
+		options = &CheckpointOptions{}
+	}
+	checkpointPath, interval := options.Path, options.Interval
+	if checkpointPath == "" {
+		checkpointPath = outputPath + ".checkpoint"
+	}
+	if interval == 0 {
+		interval = defaultCheckpointInterval
+	}
+	if interval < 0 {
+		return 0, fmt.Errorf("invalid checkpoint interval %v", interval)
+	}
+
+	in, err := os.Open(inputPath)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+	header, err := inspectWav(in, config.Limits)
+	if err != nil {
+		return 0, err
+	}
+	frameSize := int64(header.channels * header.bitDepth / 8)
+	frames := header.dataSize / frameSize
+	if frames == 0 {
+		return 0, ErrEmptyInput
+	}
+	rate := header.sampleRate
+	if config.InputSampleRate != 0 {
+		rate = config.InputSampleRate
+	}
+	encoder, err := NewChunkEncoder(rate, config)
+	if err != nil {
+		return 0, err
+	}
+	fingerprint, err := checkpointFingerprint(config, header)
+	if err != nil {
+		return 0, err
+	}
+
+	cp := &checkpoint{fingerprint: fingerprint}
+	resumed := false
+	if data, err := os.ReadFile(checkpointPath); err == nil {
+		if err := cp.unmarshal(data, encoder); err != nil {
+			return 0, fmt.Errorf("checkpoint %s: %w", checkpointPath, err)
+		}
+		resumed = true
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return 0, err
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if resumed {
+		flags = os.O_WRONLY
+	}
+	out, err := os.OpenFile(outputPath, flags, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	defer out.Close()
+	if resumed {
+		// Output written after the checkpoint is produced again
+		if info, err := out.Stat(); err != nil {
+			return 0, err
+		} else if info.Size() < cp.written {
+			return 0, fmt.Errorf("checkpoint %s: output %s is shorter than checkpointed", checkpointPath, outputPath)
+		}
+		if err := out.Truncate(cp.written); err != nil {
+			return 0, err
+		}
+		if _, err := out.Seek(cp.written, io.SeekStart); err != nil {
+			return 0, err
+		}
+		logDebug(config, "resuming from checkpoint", "checkpoint", checkpointPath, "frame", cp.frame, "output_bytes", cp.written)
+	}
+	resumedFrom := time.Duration(cp.frame) * time.Second / time.Duration(header.sampleRate)
+
+	save := func() error {
+		if err := out.Sync(); err != nil {
+			return err
+		}
+		data, err := cp.marshal(encoder)
+		if err != nil {
+			return err
+		}
+		return writeFileAtomic(checkpointPath, data)
+	}
+	emit := func(ulaw []byte) error {
+		n, err := out.Write(ulaw)
+		cp.written += int64(n)
+		return err
+	}
+
+	perCheckpoint := max(int64(interval.Seconds()*float64(header.sampleRate)), 1)
+	nextSave := cp.frame + perCheckpoint
+	buf := make([]byte, streamReadFrames*frameSize)
+	pcm := make([]int, streamReadFrames*header.channels)
+	samples := make([]int16, streamReadFrames)
+	for cp.frame < frames {
+		if ctx.Err() != nil {
+			if err := save(); err != nil {
+				return resumedFrom, err
+			}
+			return resumedFrom, ctx.Err()
+		}
+		n := min(frames-cp.frame, streamReadFrames)
+		chunk := buf[:n*frameSize]
+		if _, err := in.ReadAt(chunk, header.dataOffset+cp.frame*frameSize); err != nil {
+			return resumedFrom, fmt.Errorf("error reading WAV data: %v", err)
+		}
+		decodePCM(pcm, chunk, header.bitDepth)
+		pcmToInt16(samples[:n], pcm[:n*int64(header.channels)], header.channels, header.bitDepth, true)
+		if err := emit(encoder.Encode(samples[:n])); err != nil {
+			return resumedFrom, err
+		}
+		cp.frame += n
+		if cp.frame >= nextSave && cp.frame < frames {
+			if err := save(); err != nil {
+				return resumedFrom, err
+			}
+			nextSave = cp.frame + perCheckpoint
+		}
+	}
+	if err := emit(encoder.Flush()); err != nil {
+		return resumedFrom, err
+	}
+	if err := out.Close(); err != nil {
+		return resumedFrom, err
+	}
+	if err := os.Remove(checkpointPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return resumedFrom, err
+	}
+	return resumedFrom, nil
+}
+
+// checkpoint is the progress of ConvertWavFileResumable
+type checkpoint struct {
+	// Hash of the input format and the configuration it was saved with
+	fingerprint [sha256.Size]byte
+	// Input frames converted and output bytes written
+	frame   int64
+	written int64
+}
+
+// checkpointFingerprint identifies the input format and configuration a
+// checkpoint applies to
+func checkpointFingerprint(config *AudioConfig, header *wavHeader) ([sha256.Size]byte, error) {
+	settings, err := json.Marshal(config)
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%d/%d/%d/%d/%d;", header.sampleRate, header.channels, header.bitDepth, header.dataOffset, header.dataSize)
+	h.Write(settings)
+	var sum [sha256.Size]byte
+	h.Sum(sum[:0])
+	return sum, nil
+}
+
+// marshal serializes the checkpoint with the encoder's state
+func (cp *checkpoint) marshal(encoder *ChunkEncoder) ([]byte, error) {
+	state, err := encoder.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	w := newStateWriter(stateCheckpoint)
+	w.bytes(cp.fingerprint[:])
+	w.int64(cp.frame)
+	w.int64(cp.written)
+	w.bytes(state)
+	return w.buf, nil
+}
+
+// unmarshal restores a checkpoint saved with the same fingerprint into cp
+// and encoder
+func (cp *checkpoint) unmarshal(data []byte, encoder *ChunkEncoder) error {
+	r := newStateReader(data, stateCheckpoint)
+	fingerprint := r.bytes()
+	frame, written := r.int64(), r.int64()
+	state := r.bytes()
+	if err := r.finish(); err != nil {
+		return err
+	}
+	if string(fingerprint) != string(cp.fingerprint[:]) {
+		return fmt.Errorf("%w: saved for another input or configuration", ErrStateMismatch)
+	}
+	if err := encoder.UnmarshalBinary(state); err != nil {
+		return err
+	}
+	cp.frame, cp.written = frame, written
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it over path, so a crash leaves either the old or the new contents
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
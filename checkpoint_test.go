@@ -0,0 +1,84 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// interruptedContext is cancelled after its Err has been checked calls times
+type interruptedContext struct {
+	context.Context
+	calls int
+}
+
+func (c *interruptedContext) Err() error {
+	if c.calls--; c.calls < 0 {
+		return context.Canceled
+	}
+	return nil
+}
+
+func TestConvertWavFileResumable(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "call.wav")
+	if err := os.WriteFile(input, encodeWavDepth(t, sineWave(10*44100, 440, 44100, 0.3), 44100, 24, 2), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(dir, "call.ulaw")
+	checkpointPath := output + ".checkpoint"
+	options := &CheckpointOptions{Interval: 500 * time.Millisecond}
+
+	if from, err := ConvertWavFileResumable(context.Background(), input, output, nil, options); err != nil || from != 0 {
+		t.Fatalf("uninterrupted: resumed from %v (%v)", from, err)
+	}
+	want, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(want) < 80000 || len(want) > 80001 {
+		t.Errorf("%d bytes of u-law, want 10 s", len(want))
+	}
+	if _, err := os.Stat(checkpointPath); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("checkpoint left behind: %v", err)
+	}
+
+	// Interrupted twice, each time with output written past the
+	// checkpoint, the conversion still produces the same bytes
+	for _, chunks := range []int{20, 50} {
+		ctx := &interruptedContext{Context: context.Background(), calls: chunks}
+		if _, err := ConvertWavFileResumable(ctx, input, output, nil, options); !errors.Is(err, context.Canceled) {
+			t.Fatalf("interrupted after %d chunks: got %v, want context.Canceled", chunks, err)
+		}
+		f, err := os.OpenFile(output, os.O_WRONLY|os.O_APPEND, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte("written before the crash"))
+		f.Close()
+	}
+	from, err := ConvertWavFileResumable(context.Background(), input, output, nil, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from < time.Second {
+		t.Errorf("resumed from %v, want the position of the second interruption", from)
+	}
+	if got, err := os.ReadFile(output); err != nil || !bytes.Equal(got, want) {
+		t.Errorf("resumed conversion differs from the uninterrupted one (%d bytes, want %d)", len(got), len(want))
+	}
+
+	// A checkpoint for other settings is not resumed from
+	if _, err := ConvertWavFileResumable(&interruptedContext{Context: context.Background(), calls: 20}, input, output, nil, options); !errors.Is(err, context.Canceled) {
+		t.Fatal(err)
+	}
+	config := DefaultAudioConfig()
+	config.LowPassCutoff = 3000
+	if _, err := ConvertWavFileResumable(context.Background(), input, output, config, options); !errors.Is(err, ErrStateMismatch) {
+		t.Errorf("other settings: got %v, want ErrStateMismatch", err)
+	}
+}
@@ -0,0 +1,484 @@
+package wav2ulaw
+
+import (
+	"fmt"
+	"math"
+)
+
+// ChunkEncoder converts live 16-bit PCM to u-law one chunk at a time. Filter
+// and resampler state carries over between chunks, so the output does not
+// depend on how the input is split. Stages that need the whole signal (peak
+// normalization, loudness targeting, fades, tempo changes and noise overlay)
+// and silence padding are not applied. With AudioConfig.RealTime set, the
+// encoder guarantees a latency of at most RealTimeLatency for chunks of up
+// to RealTimeBlock.
+type ChunkEncoder struct {
+	config    *AudioConfig
+	inputRate int
+	filters   []chunkFilter
+	resampler *DriftResampler
+	// A chunk longer than RealTimeBlock was reported
+	warnedBlock bool
+	// Factor undoing the gain of the filters and resampler (LevelCompensation)
+	levelScale float64
+}
+
+// NewChunkEncoder creates an encoder for mono samples at inputRate
+func NewChunkEncoder(inputRate int, config *AudioConfig) (*ChunkEncoder, error) {
+	if config == nil {
+		config = DefaultAudioConfig()
+	}
+	if inputRate <= 0 {
+		return nil, fmt.Errorf("invalid sample rate %d", inputRate)
+	}
+	config, err := validateConfig(config, inputRate)
+	if err != nil {
+		return nil, err
+	}
+	if config.RealTime {
+		if config, err = realTimeConfig(config, inputRate); err != nil {
+			return nil, err
+		}
+	}
+
+	// Chunks are not reported to OnStageOutput piecemeal
+	e := &ChunkEncoder{config: withoutStageOutput(config), inputRate: inputRate, levelScale: 1}
+	rate := float64(inputRate)
+	if config.HighPassCutoff > 0 {
+		e.filters = append(e.filters, newHighPassState(rate, config.HighPassCutoff))
+	}
+	if config.LowPassCutoff > 0 {
+		e.filters = append(e.filters, newLowPassState(rate, config.LowPassCutoff))
+	}
+	if inputRate > 8000 {
+		cutoff := 4000 * config.AntiAliasingCutoffRatio
+		switch config.AntiAliasingType {
+		case AAButterworth:
+			e.filters = append(e.filters, newSectionState(butterworthSections(rate, cutoff, config.FilterOrder)))
+		case AABessel:
+			e.filters = append(e.filters, newSectionState(besselSections(rate, cutoff, config.FilterOrder)))
+		case AAChebyshev:
+			e.filters = append(e.filters, newSectionState(chebyshevSections(rate, cutoff, config.ChebyshevRipple, config.FilterOrder)))
+		case AAWindowedSinc, AAAuto:
+			// Run by the resampling stage below
+		default: // AASimple
+			e.filters = append(e.filters, newLowPassState(rate, cutoff))
+		}
+		// The batch decimators band-limit on their own, the streaming
+		// resampler does not, so the windowed-sinc kernel always runs
+		e.filters = append(e.filters, newFIRState(antiAliasingKernel(rate, 8000, config)))
+	}
+	if inputRate != 8000 {
+		e.resampler = NewDriftResampler(inputRate, 8000, config.ResamplingWindowSize)
+	}
+	if config.LevelCompensation {
+		// Measure on an identical encoder, leaving this one's state untouched
+		probe := e.clone()
+		e.levelScale = 1 / chainGain(inputRate, func(samples []int16) []int16 {
+			return append(probe.process(samples), probe.flush()...)
+		})
+	}
+	logResample(config, inputRate, 8000)
+	return e, nil
+}
+
+// Encode processes the next chunk and returns the u-law bytes it completes.
+// Filters and the resampler delay their output slightly, so early chunks
+// may produce fewer bytes than their duration. samples is not modified.
+func (e *ChunkEncoder) Encode(samples []int16) []byte {
+	e.checkBlock(len(samples))
+	return e.encode(e.process(samples))
+}
+
+// Flush returns the output still held back by the filters and resampler.
+// The encoder must not be used afterwards.
+func (e *ChunkEncoder) Flush() []byte {
+	return e.encode(e.flush())
+}
+
+// MarshalBinary serializes the filter delay lines and the resampler phase and
+// history, so a stream can move to another process without a glitch. The
+// state is restored with UnmarshalBinary into an encoder created with the
+// same input rate and configuration.
+func (e *ChunkEncoder) MarshalBinary() ([]byte, error) {
+	w := newStateWriter(stateEncoder)
+	w.int(len(e.filters))
+	for _, f := range e.filters {
+		f.saveState(w)
+	}
+	w.bool(e.resampler != nil)
+	if e.resampler != nil {
+		e.resampler.saveState(w)
+	}
+	return w.buf, nil
+}
+
+// UnmarshalBinary restores state serialized by MarshalBinary. It fails
+// without changing the encoder when the state was saved by an encoder with
+// a different filter chain or resampler.
+func (e *ChunkEncoder) UnmarshalBinary(data []byte) error {
+	// Restore into a copy so a mismatch leaves e untouched
+	restored := e.clone()
+	r := newStateReader(data, stateEncoder)
+	r.expect("filter count", len(restored.filters))
+	for _, f := range restored.filters {
+		f.loadState(r)
+	}
+	if resampled := r.bool(); r.err == nil && resampled != (restored.resampler != nil) {
+		r.err = fmt.Errorf("%w: resampling differs", ErrStateMismatch)
+	}
+	if restored.resampler != nil {
+		restored.resampler.loadState(r)
+	}
+	if err := r.finish(); err != nil {
+		return err
+	}
+	*e = *restored
+	return nil
+}
+
+// clone returns an encoder with the same configuration and state
+func (e *ChunkEncoder) clone() *ChunkEncoder {
+	c := &ChunkEncoder{config: e.config, inputRate: e.inputRate, warnedBlock: e.warnedBlock, levelScale: e.levelScale, filters: make([]chunkFilter, len(e.filters))}
+	for i, f := range e.filters {
+		c.filters[i] = f.clone()
+	}
+	if e.resampler != nil {
+		c.resampler = e.resampler.clone()
+	}
+	return c
+}
+
+// process runs the filters and resampler on the next chunk, returning the
+// 8 kHz samples it completes
+func (e *ChunkEncoder) process(samples []int16) []int16 {
+	buf := append([]int16(nil), samples...)
+	for _, f := range e.filters {
+		buf = f.process(buf)
+	}
+	if e.resampler != nil {
+		buf = e.resampler.Process(buf, 0, 0)
+	}
+	return buf
+}
+
+// flush returns the 8 kHz samples still held back by the filters and resampler
+func (e *ChunkEncoder) flush() []int16 {
+	var buf []int16
+	for _, f := range e.filters {
+		buf = append(f.process(buf), f.flush()...)
+	}
+	if e.resampler != nil {
+		buf = append(e.resampler.Process(buf, 0, 0), e.resampler.Flush()...)
+	}
+	return buf
+}
+
+// encode runs the level stages on 8 kHz samples and encodes them to u-law
+func (e *ChunkEncoder) encode(samples []int16) []byte {
+	if e.levelScale != 1 {
+		scaleSamples(samples, e.levelScale)
+	}
+	if e.config.CompressionRatio > 1.0 {
+		samples = applyCompression(samples, e.config.CompressionRatio, e.config.CompressionThreshold)
+	}
+	return encodeChunk(e.config, samples)
+}
+
+// ChunkDecoder converts live u-law to 16-bit PCM one chunk at a time,
+// keeping the resampler state between chunks
+type ChunkDecoder struct {
+	resampler *DriftResampler
+}
+
+// NewChunkDecoder creates a decoder producing samples at outputRate, using a
+// sinc window of the given half-width when resampling
+func NewChunkDecoder(outputRate, windowSize int) (*ChunkDecoder, error) {
+	if outputRate <= 0 {
+		return nil, fmt.Errorf("invalid sample rate %d", outputRate)
+	}
+	if windowSize <= 0 {
+		return nil, fmt.Errorf("invalid window size %d", windowSize)
+	}
+	d := &ChunkDecoder{}
+	if outputRate != 8000 {
+		d.resampler = NewDriftResampler(8000, outputRate, windowSize)
+	}
+	return d, nil
+}
+
+// Decode expands the next chunk of u-law bytes and returns the samples it
+// completes
+func (d *ChunkDecoder) Decode(ulaw []byte) []int16 {
+	samples := decodeUlawSamples(ulaw)
+	if d.resampler != nil {
+		return d.resampler.Process(samples, 0, 0)
+	}
+	return samples
+}
+
+// Flush returns the samples still held back by the resampler
+func (d *ChunkDecoder) Flush() []int16 {
+	if d.resampler != nil {
+		return d.resampler.Flush()
+	}
+	return nil
+}
+
+// MarshalBinary serializes the resampler phase and history. The state is
+// restored with UnmarshalBinary into a decoder created with the same output
+// rate and window size.
+func (d *ChunkDecoder) MarshalBinary() ([]byte, error) {
+	w := newStateWriter(stateDecoder)
+	w.bool(d.resampler != nil)
+	if d.resampler != nil {
+		d.resampler.saveState(w)
+	}
+	return w.buf, nil
+}
+
+// UnmarshalBinary restores state serialized by MarshalBinary, failing
+// without changing the decoder when it does not match
+func (d *ChunkDecoder) UnmarshalBinary(data []byte) error {
+	var restored *DriftResampler
+	if d.resampler != nil {
+		restored = d.resampler.clone()
+	}
+	r := newStateReader(data, stateDecoder)
+	if resampled := r.bool(); r.err == nil && resampled != (restored != nil) {
+		r.err = fmt.Errorf("%w: resampling differs", ErrStateMismatch)
+	}
+	if restored != nil {
+		restored.loadState(r)
+	}
+	if err := r.finish(); err != nil {
+		return err
+	}
+	d.resampler = restored
+	return nil
+}
+
+// chunkFilter is a filter stage whose state carries over between chunks
+type chunkFilter interface {
+	// process filters the next chunk, possibly in place
+	process(samples []int16) []int16
+	// flush returns output held back at the end of the stream
+	flush() []int16
+	// saveState and loadState serialize the state carried between chunks
+	saveState(w *stateWriter)
+	loadState(r *stateReader)
+	// clone returns an independent copy of the filter and its state
+	clone() chunkFilter
+}
+
+// Tags identifying each chunkFilter in serialized state
+const (
+	highPassTag = iota + 1
+	lowPassTag
+	sectionTag
+	firTag
+)
+
+// highPassState is the streaming form of applyHighPassFilter
+type highPassState struct {
+	alpha      float64
+	prevInput  float64
+	prevOutput float64
+	started    bool
+}
+
+func newHighPassState(sampleRate, cutoffFreq float64) *highPassState {
+	rc := 1.0 / (2.0 * math.Pi * cutoffFreq)
+	dt := 1.0 / sampleRate
+	return &highPassState{alpha: rc / (rc + dt)}
+}
+
+func (s *highPassState) process(samples []int16) []int16 {
+	for i := range samples {
+		// The first sample of the stream passes unchanged
+		if !s.started {
+			s.started = true
+			continue
+		}
+		input := float64(samples[i])
+		output := s.alpha * (s.prevOutput + input - s.prevInput)
+		samples[i] = int16(math.Max(-32768, math.Min(32767, math.Round(output))))
+		s.prevInput = input
+		s.prevOutput = output
+	}
+	return samples
+}
+
+func (s *highPassState) flush() []int16 { return nil }
+
+func (s *highPassState) clone() chunkFilter {
+	c := *s
+	return &c
+}
+
+func (s *highPassState) saveState(w *stateWriter) {
+	w.int(highPassTag)
+	w.bool(s.started)
+	w.float(s.prevInput)
+	w.float(s.prevOutput)
+}
+
+func (s *highPassState) loadState(r *stateReader) {
+	r.expect("filter", highPassTag)
+	s.started = r.bool()
+	s.prevInput = r.float()
+	s.prevOutput = r.float()
+}
+
+// lowPassState is the streaming form of applyLowPassFilter
+type lowPassState struct {
+	alpha   float64
+	prev    int16
+	started bool
+}
+
+func newLowPassState(sampleRate, cutoffFreq float64) *lowPassState {
+	rc := 1.0 / (2.0 * math.Pi * cutoffFreq)
+	dt := 1.0 / sampleRate
+	return &lowPassState{alpha: dt / (rc + dt)}
+}
+
+func (s *lowPassState) process(samples []int16) []int16 {
+	for i := range samples {
+		if s.started {
+			samples[i] = int16(math.Round(float64(s.prev) + s.alpha*(float64(samples[i])-float64(s.prev))))
+		}
+		s.started = true
+		s.prev = samples[i]
+	}
+	return samples
+}
+
+func (s *lowPassState) flush() []int16 { return nil }
+
+func (s *lowPassState) clone() chunkFilter {
+	c := *s
+	return &c
+}
+
+func (s *lowPassState) saveState(w *stateWriter) {
+	w.int(lowPassTag)
+	w.bool(s.started)
+	w.int(int(uint16(s.prev)))
+}
+
+func (s *lowPassState) loadState(r *stateReader) {
+	r.expect("filter", lowPassTag)
+	s.started = r.bool()
+	s.prev = int16(r.int())
+}
+
+// sectionState runs a cascade of IIR sections like applySections, keeping
+// the delay lines between chunks
+type sectionState struct {
+	stages []biquad
+}
+
+func newSectionState(sections []filterCoefficients) *sectionState {
+	return &sectionState{stages: newSectionStages(sections)}
+}
+
+func (s *sectionState) process(samples []int16) []int16 {
+	for i := range samples {
+		y := float64(samples[i]) / 32767.0
+		for j := range s.stages {
+			y = s.stages[j].process(y)
+		}
+		samples[i] = int16(math.Max(-32768, math.Min(32767, y*32767.0)))
+	}
+	return samples
+}
+
+func (s *sectionState) flush() []int16 { return nil }
+
+func (s *sectionState) clone() chunkFilter {
+	return &sectionState{stages: append([]biquad(nil), s.stages...)}
+}
+
+func (s *sectionState) saveState(w *stateWriter) {
+	w.int(sectionTag)
+	w.int(len(s.stages))
+	for _, stage := range s.stages {
+		w.float(stage.x1)
+		w.float(stage.x2)
+		w.float(stage.y1)
+		w.float(stage.y2)
+	}
+}
+
+func (s *sectionState) loadState(r *stateReader) {
+	r.expect("filter", sectionTag)
+	r.expect("filter sections", len(s.stages))
+	for i := range s.stages {
+		stage := &s.stages[i]
+		stage.x1, stage.x2 = r.float(), r.float()
+		stage.y1, stage.y2 = r.float(), r.float()
+	}
+}
+
+// firState convolves a stream with a centered odd-length kernel. Output lags
+// the input by half the kernel length until flush drains it.
+type firState struct {
+	kernel []float64
+	// Input not yet centered under the kernel, preceded by the samples the
+	// kernel still reaches back to
+	history []float64
+}
+
+func newFIRState(kernel []float64) *firState {
+	// Leading zeros stand in for the samples before the stream started
+	return &firState{kernel: kernel, history: make([]float64, len(kernel)/2)}
+}
+
+func (s *firState) process(samples []int16) []int16 {
+	for _, sample := range samples {
+		s.history = append(s.history, float64(sample))
+	}
+	return s.drain()
+}
+
+func (s *firState) flush() []int16 {
+	s.history = append(s.history, make([]float64, len(s.kernel)/2)...)
+	return s.drain()
+}
+
+func (s *firState) clone() chunkFilter {
+	return &firState{kernel: s.kernel, history: append([]float64(nil), s.history...)}
+}
+
+func (s *firState) saveState(w *stateWriter) {
+	w.int(firTag)
+	w.int(len(s.kernel))
+	w.floats(s.history)
+}
+
+func (s *firState) loadState(r *stateReader) {
+	r.expect("filter", firTag)
+	r.expect("filter kernel length", len(s.kernel))
+	if history := r.floats(); r.err == nil {
+		s.history = history
+	}
+}
+
+// drain produces every output whose kernel span is fully buffered
+func (s *firState) drain() []int16 {
+	n := len(s.history) - len(s.kernel) + 1
+	if n <= 0 {
+		return nil
+	}
+	output := make([]int16, n)
+	for i := range output {
+		sum := 0.0
+		for k, w := range s.kernel {
+			sum += s.history[i+k] * w
+		}
+		output[i] = int16(math.Max(-32768, math.Min(32767, math.Round(sum))))
+	}
+	s.history = append(s.history[:0], s.history[n:]...)
+	return output
+}
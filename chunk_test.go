@@ -0,0 +1,95 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestChunkEncoderIndependentOfChunking(t *testing.T) {
+	input := sineWave(48000, 1000, 48000, 0.5)
+	for _, aa := range []AntiAliasingType{AASimple, AAButterworth, AAWindowedSinc} {
+		config := DefaultAudioConfig()
+		config.AntiAliasingType = aa
+
+		whole, err := NewChunkEncoder(48000, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := append(whole.Encode(input), whole.Flush()...)
+
+		chunked, _ := NewChunkEncoder(48000, config)
+		var output []byte
+		for start, size := 0, 1; start < len(input); size = size*3 + 7 {
+			end := min(start+size, len(input))
+			output = append(output, chunked.Encode(input[start:end])...)
+			start = end
+		}
+		output = append(output, chunked.Flush()...)
+
+		if !bytes.Equal(output, expected) {
+			t.Errorf("type %d: chunked output differs from a single chunk", aa)
+		}
+		if len(output) < 7990 || len(output) > 8010 {
+			t.Errorf("type %d: expected about 8000 bytes, got %d", aa, len(output))
+		}
+	}
+}
+
+func TestChunkEncoderMatchesBatchLevel(t *testing.T) {
+	input := sineWave(44100, 1000, 44100, 0.5)
+	config := DefaultAudioConfig()
+	// Normalization needs the whole signal and is skipped when streaming
+	config.NormalizePeak = 0
+
+	encoder, err := NewChunkEncoder(44100, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var streamed []byte
+	for start := 0; start < len(input); start += 882 {
+		streamed = append(streamed, encoder.Encode(input[start:min(start+882, len(input))])...)
+	}
+	streamed = append(streamed, encoder.Flush()...)
+
+	batch, err := ConvertPCM16ToUlaw(input, 44100, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := toneLevel(decodeUlawSamples(streamed[800:7200]), 1000, 8000)
+	want := toneLevel(decodeUlawSamples(batch[800:7200]), 1000, 8000)
+	if got < want*0.9 || got > want*1.1 {
+		t.Errorf("expected tone level near %.3f, got %.3f", want, got)
+	}
+
+	// Aliases of 5000 Hz land on 3000 Hz and must be rejected
+	encoder, _ = NewChunkEncoder(44100, config)
+	streamed = append(encoder.Encode(sineWave(44100, 5000, 44100, 0.5)), encoder.Flush()...)
+	if level := toneLevel(decodeUlawSamples(streamed[800:7200]), 3000, 8000); level > 0.01 {
+		t.Errorf("alias of 5000 Hz not rejected, level %.4f", level)
+	}
+}
+
+func TestChunkDecoder(t *testing.T) {
+	ulaw := encodeUlawSamples(sineWave(8000, 440, 8000, 0.5))
+
+	decoder, err := NewChunkDecoder(16000, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var output []int16
+	for start := 0; start < len(ulaw); start += 160 {
+		output = append(output, decoder.Decode(ulaw[start:start+160])...)
+	}
+	output = append(output, decoder.Flush()...)
+
+	if len(output) != 16000 {
+		t.Fatalf("expected 16000 samples, got %d", len(output))
+	}
+	if level := toneLevel(output[1600:14400], 440, 16000); level < 0.45 || level > 0.55 {
+		t.Errorf("expected tone level near 0.5, got %.3f", level)
+	}
+
+	if _, err := NewChunkDecoder(0, 16); err == nil {
+		t.Error("expected error for zero sample rate")
+	}
+}
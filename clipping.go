@@ -0,0 +1,169 @@
+package wav2ulaw
+
+import "time"
+
+const (
+	// Minimum run of consecutive full-scale samples reported as a clipped region
+	minClipRun = 3
+)
+
+// ClipRegion describes a run of consecutive full-scale samples in one channel
+type ClipRegion struct {
+	// Channel index (0-based)
+	Channel int
+	// Offset of the first clipped sample from the start of the audio
+	Start time.Duration
+	// Length of the clipped run
+	Duration time.Duration
+	// Number of consecutive clipped samples
+	Samples int
+}
+
+// detectClipRegions finds runs of at least minClipRun full-scale samples in
+// normalized interleaved data
+func detectClipRegions(data []float64, channels, sampleRate, bitDepth int) []ClipRegion {
+	if channels < 1 || sampleRate <= 0 {
+		return nil
+	}
+
+	var regions []ClipRegion
+	frames := len(data) / channels
+	toDuration := func(frames int) time.Duration {
+		return time.Duration(float64(frames) / float64(sampleRate) * float64(time.Second))
+	}
+
+	for ch := 0; ch < channels; ch++ {
+		run := 0
+		for i := 0; i <= frames; i++ {
+			if i < frames && isClipped(data[i*channels+ch], bitDepth) {
+				run++
+				continue
+			}
+			if run >= minClipRun {
+				start := i - run
+				regions = append(regions, ClipRegion{
+					Channel:  ch,
+					Start:    toDuration(start),
+					Duration: toDuration(run),
+					Samples:  run,
+				})
+			}
+			run = 0
+		}
+	}
+
+	return regions
+}
+
+// clippedSamples counts the 16-bit samples in runs of at least minClipRun
+// at full scale
+func clippedSamples(samples []int16) int {
+	count, run := 0, 0
+	for i := 0; i <= len(samples); i++ {
+		if i < len(samples) && (samples[i] == 32767 || samples[i] == -32768) {
+			run++
+			continue
+		}
+		if run >= minClipRun {
+			count += run
+		}
+		run = 0
+	}
+	return count
+}
+
+// clipWatch warns about each processing stage that leaves more clipped
+// samples than it was given, so hosts learn which setting (e.g. a normalize
+// peak of 1.0 after a hot filter) drives the signal into clipping. It does
+// nothing when warnings go nowhere.
+type clipWatch struct {
+	config  *AudioConfig
+	clipped int
+}
+
+// newClipWatch counts the clipped samples of the input, warning if there are any
+func newClipWatch(config *AudioConfig, samples []int16) *clipWatch {
+	w := &clipWatch{config: config}
+	if !warns(config) {
+		return w
+	}
+	if w.clipped = clippedSamples(samples); w.clipped > 0 {
+		logWarn(config, WarnInputClipped, "", "input is clipped", "clipped_samples", w.clipped)
+	}
+	return w
+}
+
+// check counts the clipped samples after stage and warns if it added any
+func (w *clipWatch) check(stage string, samples []int16) {
+	if !warns(w.config) {
+		return
+	}
+	clipped := clippedSamples(samples)
+	if clipped > w.clipped {
+		logWarn(w.config, WarnClipping, stage, "clipping detected", "clipped_samples", clipped-w.clipped)
+	}
+	w.clipped = clipped
+}
+
+// clipDetector finds clipped regions incrementally, so streamed input can be
+// checked chunk by chunk without splitting runs at chunk boundaries
+type clipDetector struct {
+	channels   int
+	sampleRate int
+	bitDepth   int
+	runs       []int
+	frame      int
+}
+
+// newClipDetector creates a detector for interleaved normalized data
+func newClipDetector(channels, sampleRate, bitDepth int) *clipDetector {
+	return &clipDetector{
+		channels:   channels,
+		sampleRate: sampleRate,
+		bitDepth:   bitDepth,
+		runs:       make([]int, channels),
+	}
+}
+
+// feed scans the next chunk of whole frames and returns the regions that ended in it
+func (d *clipDetector) feed(data []float64) []ClipRegion {
+	var regions []ClipRegion
+	for i := 0; i+d.channels <= len(data); i += d.channels {
+		for ch := 0; ch < d.channels; ch++ {
+			if isClipped(data[i+ch], d.bitDepth) {
+				d.runs[ch]++
+				continue
+			}
+			regions = d.end(regions, ch)
+		}
+		d.frame++
+	}
+	return regions
+}
+
+// flush returns the regions still open at the end of the input
+func (d *clipDetector) flush() []ClipRegion {
+	var regions []ClipRegion
+	for ch := range d.runs {
+		regions = d.end(regions, ch)
+	}
+	return regions
+}
+
+// end closes the current run of channel ch, recording it if long enough
+func (d *clipDetector) end(regions []ClipRegion, ch int) []ClipRegion {
+	run := d.runs[ch]
+	d.runs[ch] = 0
+	if run < minClipRun {
+		return regions
+	}
+	toDuration := func(frames int) time.Duration {
+		return time.Duration(float64(frames) / float64(d.sampleRate) * float64(time.Second))
+	}
+	return append(regions, ClipRegion{
+		Channel:  ch,
+		Start:    toDuration(d.frame - run),
+		Duration: toDuration(run),
+		Samples:  run,
+	})
+}
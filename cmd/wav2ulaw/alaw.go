@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"wav2ulaw"
+)
+
+// convertAlaw converts data in the A-law modes: wav2alaw and alaw2wav run
+// the processing of their u-law counterparts, while ulaw2alaw and alaw2ulaw
+// transcode code by code. u-law is read and written in -ulaw-variant.
+func (c *conversion) convertAlaw(inputData []byte) ([]byte, error) {
+	if c.mode == "wav2alaw" {
+		if err := checkWavInput(inputData); err != nil {
+			return nil, err
+		}
+		outputData, err := wav2ulaw.ConvertWavBytesToAlaw(inputData, c.config)
+		if err != nil {
+			return nil, withExitCode(conversionExitCode(err), fmt.Errorf("error converting WAV to A-law: %v", err))
+		}
+		return outputData, nil
+	}
+
+	if format, _ := wav2ulaw.DetectFormat(inputData); format == wav2ulaw.FormatWAV {
+		return nil, withExitCode(exitFormat, fmt.Errorf("input is a WAV file, expected raw G.711"))
+	}
+	switch c.mode {
+	case "ulaw2alaw":
+		return wav2ulaw.ConvertUlawToAlaw(c.standardUlaw(inputData)), nil
+	case "alaw2ulaw":
+		return wav2ulaw.ConvertUlawVariant(wav2ulaw.ConvertAlawToUlaw(inputData), 0, c.config.UlawVariant), nil
+	}
+	outputData, err := wav2ulaw.ConvertAlawBytesToWav(inputData, c.sampleRate, c.windowSize)
+	if err != nil {
+		return nil, withExitCode(exitDecode, fmt.Errorf("error converting A-law to WAV: %v", err))
+	}
+	return outputData, nil
+}
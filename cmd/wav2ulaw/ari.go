@@ -0,0 +1,355 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+	"wav2ulaw"
+
+	"github.com/gorilla/websocket"
+)
+
+// ariCommand defines the flags of the "ari" subcommand and returns its implementation
+func ariCommand(fs *flag.FlagSet) func(args []string) {
+	ariURL := fs.String("url", "http://localhost:8088", "Base URL of the Asterisk HTTP server")
+	user := fs.String("user", "", "ARI user")
+	password := fs.String("password", "", "ARI password")
+	app := fs.String("app", "wav2ulaw", "Stasis application name to register")
+	channel := fs.String("channel", "", "ID of a live channel to bridge with the external media channel")
+	externalHost := fs.String("external-host", "", "Address Asterisk sends RTP to, e.g. 10.0.0.7:4000")
+	listen := fs.String("listen", "", "UDP address to receive RTP on (default: the port of -external-host)")
+	play := fs.String("play", "", "WAV or u-law file to play into the call")
+	record := fs.String("record", "", "WAV file to record the call audio to")
+	duration := fs.Duration("duration", 0, "Stop after this long (0 = when playback ends, or until the call hangs up when recording)")
+	gapFill := fs.String("gap-fill", "silence", "Fill for lost packets when recording: silence, noise or conceal")
+	convFlags := registerConversionFlags(fs)
+	logFlags := registerLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: wav2ulaw ari -user u -password p -external-host host:port [-channel id] [-play file] [-record file] [flags]")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Creates an ARI externalMedia channel exchanging u-law RTP with this host and,")
+		fmt.Fprintln(os.Stderr, "with -channel, bridges it with a live channel to play and record on the call.")
+		fs.PrintDefaults()
+	}
+	return func(args []string) {
+		if *externalHost == "" || (*play == "" && *record == "") || len(args) != 0 {
+			fs.Usage()
+			os.Exit(exitUsage)
+		}
+		logger, err := logFlags.logger(os.Stderr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		job, err := convFlags.conversion(logger)
+		if err != nil {
+			logger.Error("invalid settings", "error", err)
+			os.Exit(exitUsage)
+		}
+		fill, ok := gapFills[*gapFill]
+		if !ok {
+			logger.Error(fmt.Sprintf("invalid -gap-fill '%s'. Must be 'silence', 'noise' or 'conceal'", *gapFill))
+			os.Exit(exitUsage)
+		}
+		var packets [][]byte
+		if *play != "" {
+			data, err := readUlawInput(*play, job)
+			if err != nil {
+				logger.Error("error reading input file", "input", *play, "error", err)
+				os.Exit(exitCode(err))
+			}
+			packetizer, _ := wav2ulaw.NewRTPPacketizer(20, rand.Uint32())
+			packets = packetizer.Packetize(data)
+		}
+		if *listen == "" {
+			_, port, err := net.SplitHostPort(*externalHost)
+			if err != nil {
+				logger.Error("invalid -external-host", "error", err)
+				os.Exit(exitUsage)
+			}
+			*listen = ":" + port
+		}
+
+		client := &ariClient{baseURL: strings.TrimSuffix(*ariURL, "/") + "/ari", user: *user, password: *password}
+		session := &ariSession{client: client, logger: logger, done: make(chan struct{})}
+		err = session.run(*app, *channel, *externalHost, *listen, packets, *record != "", *duration)
+		if err != nil {
+			logger.Error("ARI session failed", "error", err)
+			os.Exit(1)
+		}
+
+		if *record != "" {
+			stats := session.depacketizer.Stats()
+			output, err := session.depacketizer.ConvertToWav(job.sampleRate, job.windowSize, fill)
+			if err != nil {
+				logger.Error("error encoding WAV", "error", err)
+				os.Exit(exitDecode)
+			}
+			if err := writeOutput(*record, output); err != nil {
+				logger.Error("error writing output file", "error", err)
+				os.Exit(exitWrite)
+			}
+			logger.Info("recording completed", "output", *record, "packets", stats.Packets, "lost", stats.Lost)
+		}
+	}
+}
+
+// ariClient calls the Asterisk REST Interface
+type ariClient struct {
+	baseURL  string
+	user     string
+	password string
+}
+
+// ariChannel is the part of an ARI channel object used here
+type ariChannel struct {
+	ID          string            `json:"id"`
+	ChannelVars map[string]string `json:"channelvars"`
+}
+
+// ariEvent is the part of an ARI event used here
+type ariEvent struct {
+	Type    string     `json:"type"`
+	Channel ariChannel `json:"channel"`
+}
+
+// call sends a request with query parameters and decodes the JSON response
+// into out unless it is nil
+func (c *ariClient) call(method, path string, params url.Values, out any) error {
+	target := c.baseURL + path
+	if len(params) > 0 {
+		target += "?" + params.Encode()
+	}
+	req, err := http.NewRequest(method, target, nil)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(c.user, c.password)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		var ariErr struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(body, &ariErr) == nil && ariErr.Message != "" {
+			return fmt.Errorf("%s %s: %s", method, path, ariErr.Message)
+		}
+		return fmt.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(body, out)
+}
+
+// events opens the event WebSocket, which registers the Stasis application
+func (c *ariClient) events(app string) (*websocket.Conn, error) {
+	u, err := url.Parse(c.baseURL + "/events")
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "https":
+		u.Scheme = "wss"
+	default:
+		u.Scheme = "ws"
+	}
+	u.RawQuery = url.Values{"app": {app}, "api_key": {c.user + ":" + c.password}}.Encode()
+	conn, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to ARI events: %v", err)
+	}
+	return conn, nil
+}
+
+// ariSession is one externalMedia channel exchanging RTP with this process
+type ariSession struct {
+	client       *ariClient
+	logger       *slog.Logger
+	depacketizer *wav2ulaw.RTPDepacketizer
+	done         chan struct{}
+	stopOnce     sync.Once
+}
+
+// stop ends the session, giving the reason in the log
+func (s *ariSession) stop(reason string) {
+	s.stopOnce.Do(func() {
+		s.logger.Info("stopping", "reason", reason)
+		close(s.done)
+	})
+}
+
+// run sets up the channels, plays packets and receives RTP until the
+// session stops, then removes what it created
+func (s *ariSession) run(app, channel, externalHost, listen string, packets [][]byte, record bool, duration time.Duration) error {
+	events, err := s.client.events(app)
+	if err != nil {
+		return err
+	}
+	defer events.Close()
+
+	conn, err := net.ListenPacket("udp", listen)
+	if err != nil {
+		return fmt.Errorf("cannot listen on %s: %v", listen, err)
+	}
+	defer conn.Close()
+
+	var media ariChannel
+	params := url.Values{"app": {app}, "external_host": {externalHost}, "format": {"ulaw"}}
+	if err := s.client.call(http.MethodPost, "/channels/externalMedia", params, &media); err != nil {
+		return err
+	}
+	defer s.client.call(http.MethodDelete, "/channels/"+media.ID, nil, nil)
+	remote, err := s.mediaAddress(media)
+	if err != nil {
+		return err
+	}
+	s.logger.Info("external media channel created", "channel", media.ID, "rtp", remote.String())
+
+	if channel != "" {
+		var bridge struct {
+			ID string `json:"id"`
+		}
+		if err := s.client.call(http.MethodPost, "/bridges", url.Values{"type": {"mixing"}}, &bridge); err != nil {
+			return err
+		}
+		defer s.client.call(http.MethodDelete, "/bridges/"+bridge.ID, nil, nil)
+		if err := s.client.call(http.MethodPost, "/bridges/"+bridge.ID+"/addChannel", url.Values{"channel": {channel + "," + media.ID}}, nil); err != nil {
+			return err
+		}
+		s.logger.Info("bridged", "bridge", bridge.ID, "channel", channel)
+	}
+
+	go s.watchEvents(events, media.ID, channel)
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	go func() {
+		select {
+		case <-interrupt:
+			s.stop("interrupted")
+		case <-s.done:
+		}
+	}()
+	if duration > 0 {
+		timer := time.AfterFunc(duration, func() { s.stop("duration reached") })
+		defer timer.Stop()
+	}
+
+	// Asterisk sends RTP to the listening socket and expects ours from it
+	var receiving sync.WaitGroup
+	s.depacketizer = wav2ulaw.NewRTPDepacketizer()
+	receiving.Add(1)
+	go func() {
+		defer receiving.Done()
+		s.receive(conn)
+	}()
+	if packets != nil {
+		go func() {
+			if err := sendPaced(&packetWriter{conn, remote}, packets, 20*time.Millisecond); err != nil {
+				s.logger.Error("error sending RTP", "error", err)
+			}
+			if !record && duration == 0 {
+				s.stop("playback finished")
+			}
+		}()
+	}
+
+	<-s.done
+	conn.Close()
+	receiving.Wait()
+	return nil
+}
+
+// mediaAddress returns where Asterisk expects the RTP it plays into the
+// channel, using the ARI host when Asterisk reports a wildcard address
+func (s *ariSession) mediaAddress(media ariChannel) (*net.UDPAddr, error) {
+	host := media.ChannelVars["UNICASTRTP_LOCAL_ADDRESS"]
+	port := media.ChannelVars["UNICASTRTP_LOCAL_PORT"]
+	if port == "" {
+		return nil, fmt.Errorf("external media channel reports no RTP port")
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		u, err := url.Parse(s.client.baseURL)
+		if err != nil {
+			return nil, err
+		}
+		host = u.Hostname()
+	}
+	return net.ResolveUDPAddr("udp", net.JoinHostPort(host, port))
+}
+
+// watchEvents stops the session when a channel of interest goes away
+func (s *ariSession) watchEvents(events *websocket.Conn, mediaID, channel string) {
+	for {
+		_, data, err := events.ReadMessage()
+		if err != nil {
+			s.stop("ARI event connection closed")
+			return
+		}
+		var event ariEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			continue
+		}
+		s.logger.Debug("ARI event", "type", event.Type, "channel", event.Channel.ID)
+		switch {
+		case (event.Type == "StasisEnd" || event.Type == "ChannelDestroyed") && event.Channel.ID == mediaID:
+			s.stop("external media channel ended")
+			return
+		case (event.Type == "ChannelLeftBridge" || event.Type == "ChannelDestroyed") && channel != "" && event.Channel.ID == channel:
+			s.stop("channel left the call")
+			return
+		}
+	}
+}
+
+// receive feeds RTP into the depacketizer until conn is closed
+func (s *ariSession) receive(conn net.PacketConn) {
+	buf := make([]byte, 65536)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			s.logger.Error("error receiving", "error", err)
+			s.stop("receive failed")
+			return
+		}
+		packet, err := wav2ulaw.ParseRTPPacket(buf[:n])
+		if err != nil {
+			continue
+		}
+		s.depacketizer.Push(packet)
+	}
+}
+
+// packetWriter writes datagrams to a fixed address from a PacketConn
+type packetWriter struct {
+	conn net.PacketConn
+	addr net.Addr
+}
+
+func (w *packetWriter) Write(p []byte) (int, error) {
+	return w.conn.WriteTo(p, w.addr)
+}
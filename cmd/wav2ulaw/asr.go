@@ -0,0 +1,38 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"wav2ulaw"
+)
+
+// convertASR converts WAV or u-law input to the mono 16-bit PCM of asr
+// mode: a WAV file, or headerless s16le as for ffmpeg -f s16le with
+// -ffmpeg-compat. Input that is not WAV is taken as u-law, as in ulaw2wav
+// mode.
+func (c *conversion) convertASR(inputData []byte) ([]byte, error) {
+	format := wav2ulaw.FormatUlaw
+	if detected, _ := wav2ulaw.DetectFormat(inputData); detected == wav2ulaw.FormatWAV {
+		format = detected
+		if c.ffmpegCompat {
+			inputData = fixStreamedWavSizes(inputData)
+		}
+	}
+	if !c.ffmpegCompat {
+		output, err := wav2ulaw.ConvertForASRWav(inputData, format, &c.asr)
+		if err != nil {
+			return nil, withExitCode(conversionExitCode(err), fmt.Errorf("error converting to ASR input: %v", err))
+		}
+		return output, nil
+	}
+
+	samples, err := wav2ulaw.ConvertForASR(inputData, format, &c.asr)
+	if err != nil {
+		return nil, withExitCode(conversionExitCode(err), fmt.Errorf("error converting to ASR input: %v", err))
+	}
+	pcm := make([]byte, 2*len(samples))
+	for i, s := range samples {
+		binary.LittleEndian.PutUint16(pcm[2*i:], uint16(s))
+	}
+	return pcm, nil
+}
@@ -0,0 +1,28 @@
+//go:build !audio
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+// playCommand and recordCommand report that this build lacks audio device
+// support, which requires cgo and the platform audio headers on Linux
+func playCommand(fs *flag.FlagSet) func(args []string) {
+	return func(args []string) {
+		exitNoAudio()
+	}
+}
+
+func recordCommand(fs *flag.FlagSet) func(args []string) {
+	return func(args []string) {
+		exitNoAudio()
+	}
+}
+
+func exitNoAudio() {
+	fmt.Fprintln(os.Stderr, "Error: this build has no audio device support; rebuild with: go build -tags audio ./cmd/wav2ulaw")
+	os.Exit(1)
+}
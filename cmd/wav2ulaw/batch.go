@@ -0,0 +1,394 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"wav2ulaw"
+)
+
+// batchFile is one input and the output it converts to
+type batchFile struct {
+	input  string
+	output string
+	// Settings of this file, nil for those of the run
+	job *conversion
+}
+
+// batchOptions controls how a batch is collected and run
+type batchOptions struct {
+	// Walk subdirectories, mirroring their structure under the output directory
+	recursive bool
+	// Files converted concurrently
+	jobs int
+	// Report what would be converted without writing any output
+	dryRun bool
+	// Handling of outputs left by an earlier run
+	overwrite overwritePolicy
+	// Path of a summary of every file, "-" for stdout, empty for none
+	report string
+	// "csv" or "json"
+	reportFormat string
+	// Shows the share of files done
+	progress *progressBar
+}
+
+// batchReport is the summary written to -report
+type batchReport struct {
+	Files     int  `json:"files"`
+	Converted int  `json:"converted"`
+	Skipped   int  `json:"skipped"`
+	Failed    int  `json:"failed"`
+	DryRun    bool `json:"dry_run,omitempty"`
+	// Wall-clock time of the whole batch
+	TotalSeconds float64       `json:"total_seconds"`
+	Results      []batchResult `json:"results"`
+}
+
+// batchResult is the outcome of one file of a batch
+type batchResult struct {
+	Input           string  `json:"input"`
+	Output          string  `json:"output"`
+	Status          string  `json:"status"` // converted, skipped, failed or planned
+	Error           string  `json:"error,omitempty"`
+	ExitCode        int     `json:"exit_code,omitempty"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	// Sizes of local files and the length of the converted audio, 0 when
+	// unknown
+	InputBytes   int64   `json:"input_bytes,omitempty"`
+	OutputBytes  int64   `json:"output_bytes,omitempty"`
+	AudioSeconds float64 `json:"audio_seconds,omitempty"`
+	// Problems found in a file that still converted, such as clipping
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// batchReportHeader is the header row of a CSV report, one row per file
+var batchReportHeader = []string{"input", "output", "status", "error", "exit_code", "duration_seconds", "input_bytes", "output_bytes", "audio_seconds", "warnings"}
+
+// write writes the report to path as JSON or as CSV, which has one row per
+// file and leaves out the totals
+func (r *batchReport) write(path, format string) error {
+	var buf bytes.Buffer
+	if format == "csv" {
+		w := csv.NewWriter(&buf)
+		w.Write(batchReportHeader)
+		for _, result := range r.Results {
+			w.Write([]string{
+				result.Input,
+				result.Output,
+				result.Status,
+				result.Error,
+				strconv.Itoa(result.ExitCode),
+				strconv.FormatFloat(result.DurationSeconds, 'f', 3, 64),
+				strconv.FormatInt(result.InputBytes, 10),
+				strconv.FormatInt(result.OutputBytes, 10),
+				strconv.FormatFloat(result.AudioSeconds, 'f', 3, 64),
+				strings.Join(result.Warnings, "; "),
+			})
+		}
+		w.Flush()
+	} else {
+		out, _ := json.MarshalIndent(r, "", "  ")
+		buf.Write(append(out, '\n'))
+	}
+	return writeOutput(path, buf.Bytes())
+}
+
+// measure records the sizes of a converted file and the length of its audio.
+// Remote files are not fetched again.
+func (r *batchResult) measure(job *conversion) {
+	if info, err := os.Stat(r.Input); err == nil && !isBlobURL(r.Input) {
+		r.InputBytes = info.Size()
+	}
+	if isBlobURL(r.Output) {
+		return
+	}
+	info, err := os.Stat(r.Output)
+	if err != nil {
+		return
+	}
+	r.OutputBytes = info.Size()
+	// Raw G.711 holds one byte per 8 kHz sample
+	if ext := job.outputExt(); ext == ".ulaw" || ext == ".alaw" {
+		r.AudioSeconds = wav2ulaw.UlawDuration(int(info.Size())).Seconds()
+		return
+	}
+	file, err := os.Open(r.Output)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	if estimate, err := wav2ulaw.EstimateWav(file, nil); err == nil {
+		r.AudioSeconds = estimate.InputDuration.Seconds()
+	}
+}
+
+// withWarnings returns job also collecting the warnings of the conversion,
+// with the clipping it finds summed up, and a function adding them to result
+// once the conversion is done
+func withWarnings(job *conversion, result *batchResult) (*conversion, func()) {
+	var regions, samples int
+	var first time.Duration
+	var warnings []string
+	recorded := *job
+	config := *job.config
+	if onClipping := config.OnClipping; onClipping != nil {
+		config.OnClipping = func(region wav2ulaw.ClipRegion) {
+			onClipping(region)
+			if regions == 0 || region.Start < first {
+				first = region.Start
+			}
+			regions++
+			samples += region.Samples
+		}
+	}
+	onWarning := config.OnWarning
+	config.OnWarning = func(warning wav2ulaw.Warning) {
+		if onWarning != nil {
+			onWarning(warning)
+		}
+		// The clipping summary covers clipped input
+		if warning.Code == wav2ulaw.WarnInputClipped && config.OnClipping != nil {
+			return
+		}
+		message := warning.Message
+		if warning.Stage != "" {
+			message += " (" + warning.Stage + ")"
+		}
+		if !slices.Contains(warnings, message) {
+			warnings = append(warnings, message)
+		}
+	}
+	recorded.config = &config
+	return &recorded, func() {
+		if regions > 0 {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("clipping in %d regions (%d samples), the first at %v", regions, samples, first))
+		}
+		result.Warnings = append(result.Warnings, warnings...)
+	}
+}
+
+// runBatch converts every file matching pattern into outputDir like runFiles
+func runBatch(pattern, outputDir string, job *conversion, opts batchOptions) (int, error) {
+	var files []batchFile
+	var err error
+	if isBlobURL(pattern) {
+		files, err = collectBlobs(pattern, outputDir, opts.recursive, job)
+	} else if opts.recursive {
+		files, err = collectRecursive(pattern, outputDir, job)
+	} else {
+		files, err = collectGlob(pattern, outputDir, job)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if len(files) == 0 {
+		return 0, withExitCode(exitInput, fmt.Errorf("no files match '%s'", pattern))
+	}
+	if err := checkBatchOutputs(files); err != nil {
+		return 0, err
+	}
+	if !opts.dryRun && !isBlobURL(outputDir) {
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			return 0, withExitCode(exitWrite, fmt.Errorf("error creating output directory: %v", err))
+		}
+	}
+	return runFiles(files, job, opts)
+}
+
+// checkBatchOutputs refuses batches writing two inputs to one output, as an
+// output template without {name} or a glob matching one file name in
+// several directories can
+func checkBatchOutputs(files []batchFile) error {
+	inputs := make(map[string]string, len(files))
+	for _, f := range files {
+		if other, ok := inputs[f.output]; ok {
+			return withExitCode(exitUsage, fmt.Errorf("'%s' and '%s' would both be written to '%s'", other, f.input, f.output))
+		}
+		inputs[f.output] = f.input
+	}
+	return nil
+}
+
+// runFiles converts files with a pool of workers, logging the outcome of
+// each file and writing the report if one is requested. It returns the exit
+// code of the failed files: their shared code, exitFailure when they failed
+// differently, or 0 if none did.
+func runFiles(files []batchFile, job *conversion, opts batchOptions) (int, error) {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	report := batchReport{Files: len(files), DryRun: opts.dryRun, Results: make([]batchResult, len(files))}
+	batchStart := time.Now()
+	finished, code := 0, 0
+	queue := make(chan int)
+	for w := 0; w < max(opts.jobs, 1); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				f := files[i]
+				fileJob := job
+				if f.job != nil {
+					fileJob = f.job
+				}
+				result := batchResult{Input: f.input, Output: f.output}
+
+				start := time.Now()
+				skip, err := opts.overwrite.check(f.output)
+				switch {
+				case skip || err != nil:
+				case opts.dryRun:
+					err = fileJob.dryRun(f.input, f.output)
+				default:
+					warnedJob, summarize := withWarnings(fileJob, &result)
+					err = convertBatchFile(warnedJob, f.input, f.output)
+					summarize()
+				}
+				result.DurationSeconds = time.Since(start).Seconds()
+				if opts.report != "" && !skip && err == nil && !opts.dryRun {
+					result.measure(fileJob)
+				}
+
+				mu.Lock()
+				switch {
+				case skip:
+					job.logger.Info("skipped", "input", f.input, "output", f.output, "reason", "output exists")
+					result.Status = "skipped"
+					report.Skipped++
+				case err != nil:
+					if opts.dryRun {
+						job.logger.Error("dry run failed", "input", f.input, "error", err)
+					} else {
+						job.logger.Error("conversion failed", "input", f.input, "error", err)
+					}
+					result.Status, result.Error, result.ExitCode = "failed", err.Error(), exitCode(err)
+					report.Failed++
+					code = mergeExitCode(code, exitCode(err))
+				case opts.dryRun:
+					result.Status = "planned"
+				default:
+					job.logger.Info("converted", "input", f.input, "output", f.output, "duration", time.Since(start))
+					result.Status = "converted"
+					report.Converted++
+				}
+				report.Results[i] = result
+				finished++
+				if !opts.dryRun {
+					opts.progress.set(fmt.Sprintf("%d/%d files", finished, len(files)), float64(finished)/float64(len(files)))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := range files {
+		queue <- i
+	}
+	close(queue)
+	wg.Wait()
+	opts.progress.finish()
+
+	if opts.dryRun {
+		job.logger.Info("dry run finished", "files", len(files), "skipped", report.Skipped, "failed", report.Failed)
+	} else {
+		job.logger.Info("batch finished", "converted", report.Converted, "skipped", report.Skipped, "failed", report.Failed)
+	}
+	if opts.report != "" {
+		report.TotalSeconds = time.Since(batchStart).Seconds()
+		if err := report.write(opts.report, opts.reportFormat); err != nil {
+			return code, withExitCode(exitWrite, fmt.Errorf("error writing report: %v", err))
+		}
+	}
+	return code, nil
+}
+
+// collectGlob lists the files matching a glob pattern, all written directly into outputDir
+func collectGlob(pattern, outputDir string, job *conversion) ([]batchFile, error) {
+	inputs, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid input pattern '%s': %v", pattern, err)
+	}
+
+	files := make([]batchFile, 0, len(inputs))
+	for _, input := range inputs {
+		files = append(files, batchFile{input: input, output: outputPath(outputDir, filepath.Base(input), job)})
+	}
+	return files, nil
+}
+
+// collectRecursive walks a directory tree. pattern is either a directory,
+// which selects every file with the mode's input extension, or a directory
+// followed by a file name pattern (e.g. "voicemail/*.wav") applied at every
+// level. Outputs keep their path relative to the root.
+func collectRecursive(pattern, outputDir string, job *conversion) ([]batchFile, error) {
+	root, namePattern := pattern, "*"+job.inputExt()
+	if info, err := os.Stat(pattern); err != nil || !info.IsDir() {
+		root, namePattern = filepath.Split(pattern)
+		if root == "" {
+			root = "."
+		}
+	}
+	if _, err := filepath.Match(namePattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid input pattern '%s': %v", pattern, err)
+	}
+
+	var files []batchFile
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if ok, _ := filepath.Match(namePattern, d.Name()); !ok {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, batchFile{input: path, output: outputPath(outputDir, rel, job)})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error walking '%s': %v", root, err)
+	}
+	return files, nil
+}
+
+// convertBatchFile converts a single batch entry, refusing to overwrite its input
+func convertBatchFile(job *conversion, input, output string) error {
+	if isBlobURL(input) || isBlobURL(output) {
+		if input == output {
+			return withExitCode(exitUsage, fmt.Errorf("output would overwrite the input"))
+		}
+		if !isBlobURL(output) {
+			if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+				return withExitCode(exitWrite, fmt.Errorf("error creating output directory: %v", err))
+			}
+		}
+		return job.convert(input, output)
+	}
+	if info, err := os.Stat(input); err != nil {
+		return withExitCode(exitInput, err)
+	} else if info.IsDir() {
+		return withExitCode(exitInput, fmt.Errorf("is a directory"))
+	}
+	if absIn, err := filepath.Abs(input); err == nil {
+		if absOut, err := filepath.Abs(output); err == nil && absIn == absOut {
+			return withExitCode(exitUsage, fmt.Errorf("output would overwrite the input"))
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+		return withExitCode(exitWrite, fmt.Errorf("error creating output directory: %v", err))
+	}
+	return job.convert(input, output)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestBatchOutputPaths(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"a.wav", "notes.txt", "sub/c.wav", "sub/c.ulaw", "sub/deep/d.wav", "other/c.wav"} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	in := func(name string) string { return filepath.Join(root, filepath.FromSlash(name)) }
+	out := func(name string) string { return filepath.Join("out", filepath.FromSlash(name)) }
+
+	tests := []struct {
+		name      string
+		pattern   string
+		recursive bool
+		job       *conversion
+		// Input and output of each file, in walk order
+		want      [][2]string
+		duplicate bool
+	}{
+		{
+			name: "directory", pattern: root, recursive: true, job: &conversion{mode: "wav2ulaw"},
+			want: [][2]string{{in("a.wav"), out("a.ulaw")}, {in("other/c.wav"), out("other/c.ulaw")}, {in("sub/c.wav"), out("sub/c.ulaw")}, {in("sub/deep/d.wav"), out("sub/deep/d.ulaw")}},
+		},
+		{
+			name: "name pattern at every level", pattern: in("c.wav"), recursive: true, job: &conversion{mode: "wav2ulaw"},
+			want: [][2]string{{in("other/c.wav"), out("other/c.ulaw")}, {in("sub/c.wav"), out("sub/c.ulaw")}},
+		},
+		{
+			name: "input extension of the mode", pattern: root, recursive: true, job: &conversion{mode: "ulaw2wav", sampleRate: 16000},
+			want: [][2]string{{in("sub/c.ulaw"), out("sub/c.wav")}},
+		},
+		{
+			name: "template", pattern: root, recursive: true, job: &conversion{mode: "wav2alaw", outputTemplate: "{dir}/{rate}/{name}.{ext}"},
+			want: [][2]string{{in("a.wav"), out("8000/a.alaw")}, {in("other/c.wav"), out("other/8000/c.alaw")}, {in("sub/c.wav"), out("sub/8000/c.alaw")}, {in("sub/deep/d.wav"), out("sub/deep/8000/d.alaw")}},
+		},
+		{
+			name: "template without name", pattern: in("sub"), recursive: true, job: &conversion{mode: "wav2ulaw", outputTemplate: "all.{ext}"},
+			want:      [][2]string{{in("sub/c.wav"), out("all.ulaw")}, {in("sub/deep/d.wav"), out("all.ulaw")}},
+			duplicate: true,
+		},
+		{
+			name: "glob flattens directories", pattern: in("*/c.wav"), job: &conversion{mode: "wav2ulaw"},
+			want:      [][2]string{{in("other/c.wav"), out("c.ulaw")}, {in("sub/c.wav"), out("c.ulaw")}},
+			duplicate: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collect := collectGlob
+			if tt.recursive {
+				collect = collectRecursive
+			}
+			files, err := collect(tt.pattern, "out", tt.job)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got [][2]string
+			for _, f := range files {
+				got = append(got, [2]string{f.input, f.output})
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			err = checkBatchOutputs(files)
+			if tt.duplicate && exitCode(err) != exitUsage {
+				t.Errorf("duplicate outputs: got %v, want a usage error", err)
+			} else if !tt.duplicate && err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
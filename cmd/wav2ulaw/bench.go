@@ -0,0 +1,117 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"text/tabwriter"
+	"time"
+	"wav2ulaw"
+)
+
+// benchCase is one configuration measured by the bench subcommand
+type benchCase struct {
+	name   string
+	config *wav2ulaw.AudioConfig
+}
+
+var (
+	benchFilterNames = []string{"simple", "butterworth", "bessel", "chebyshev", "windowed-sinc", "auto"}
+	benchWindowNames = []string{"blackman", "kaiser", "hann", "hamming", "blackman-harris"}
+)
+
+// benchConfig returns the default configuration with level processing
+// disabled, so quality metrics reflect filtering and resampling only
+func benchConfig(windowSize int) *wav2ulaw.AudioConfig {
+	config := wav2ulaw.DefaultAudioConfig()
+	config.ResamplingWindowSize = windowSize
+	config.NormalizePeak = 0
+	config.CompressionRatio = 1.0
+	return config
+}
+
+// benchCases returns every anti-aliasing filter and window combination for
+// the sinc resampler, plus the FFT resampler with each filter
+func benchCases(windowSize int) []benchCase {
+	var cases []benchCase
+	for aa, aaName := range benchFilterNames {
+		for win, winName := range benchWindowNames {
+			config := benchConfig(windowSize)
+			config.AntiAliasingType = wav2ulaw.AntiAliasingType(aa)
+			config.WindowFunction = wav2ulaw.WindowFunction(win)
+			cases = append(cases, benchCase{name: "sinc/" + aaName + "/" + winName, config: config})
+		}
+	}
+	for aa, aaName := range benchFilterNames {
+		config := benchConfig(windowSize)
+		config.AntiAliasingType = wav2ulaw.AntiAliasingType(aa)
+		config.ResampleMethod = wav2ulaw.ResampleFFT
+		cases = append(cases, benchCase{name: "fft/" + aaName, config: config})
+	}
+	return cases
+}
+
+// benchCommand defines the flags of the "bench" subcommand and returns its implementation
+func benchCommand(fs *flag.FlagSet) func(args []string) {
+	inputFile := fs.String("input", "", "Input WAV file path")
+	iterations := fs.Int("n", 5, "Conversions per configuration")
+	windowSize := fs.Int("window-size", 16, "Resampling window size used by every configuration")
+	return func(args []string) {
+		if *inputFile == "" {
+			fmt.Println("Error: Input file path is required")
+			fs.Usage()
+			os.Exit(exitUsage)
+		}
+		if *iterations < 1 {
+			fmt.Println("Error: -n must be at least 1")
+			os.Exit(exitUsage)
+		}
+
+		inputData, err := os.ReadFile(*inputFile)
+		if err != nil {
+			fmt.Printf("Error reading input file: %v\n", err)
+			os.Exit(exitInput)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(w, "configuration\tms/op\tx realtime\tallocs/op\tKB/op\tPSNR dB\tseg SNR dB\tspectral dist dB\t")
+
+		for _, c := range benchCases(*windowSize) {
+			var before, after runtime.MemStats
+			var output []byte
+
+			runtime.GC()
+			runtime.ReadMemStats(&before)
+			start := time.Now()
+			for i := 0; i < *iterations; i++ {
+				output, err = wav2ulaw.ConvertWavBytesToUlaw(inputData, c.config)
+				if err != nil {
+					fmt.Printf("Error converting WAV to u-law (%s): %v\n", c.name, err)
+					os.Exit(exitDecode)
+				}
+			}
+			elapsed := time.Since(start) / time.Duration(*iterations)
+			runtime.ReadMemStats(&after)
+
+			metrics, err := wav2ulaw.CompareWavToUlaw(inputData, output)
+			if err != nil {
+				fmt.Printf("Error measuring quality (%s): %v\n", c.name, err)
+				os.Exit(1)
+			}
+
+			audioSeconds := float64(len(output)) / 8000
+			fmt.Fprintf(w, "%s\t%.1f\t%.1f\t%d\t%d\t%.1f\t%.1f\t%.2f\t\n",
+				c.name,
+				float64(elapsed)/float64(time.Millisecond),
+				audioSeconds/elapsed.Seconds(),
+				(after.Mallocs-before.Mallocs)/uint64(*iterations),
+				(after.TotalAlloc-before.TotalAlloc)/uint64(*iterations)/1024,
+				metrics.PSNR,
+				metrics.SegmentalSNR,
+				metrics.SpectralDistortion,
+			)
+		}
+		w.Flush()
+	}
+}
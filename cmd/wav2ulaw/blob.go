@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"sync"
+	"wav2ulaw"
+)
+
+func init() {
+	wav2ulaw.RegisterBlobStore("s3", openS3Store)
+	wav2ulaw.RegisterBlobStore("gs", openGCSStore)
+}
+
+// blobStores caches the open store of each bucket, keyed by scheme://bucket,
+// so a batch loads credentials once
+var blobStores sync.Map
+
+// isBlobURL reports whether path names an object of a storage service
+// rather than a local file
+func isBlobURL(path string) bool {
+	_, _, _, ok := wav2ulaw.ParseBlobURL(path)
+	return ok
+}
+
+// openBlob returns the store holding the object a blob URL names and its key
+func openBlob(url string) (wav2ulaw.BlobStore, string, error) {
+	scheme, bucket, key, _ := wav2ulaw.ParseBlobURL(url)
+	id := scheme + "://" + bucket
+	if store, ok := blobStores.Load(id); ok {
+		return store.(wav2ulaw.BlobStore), key, nil
+	}
+	store, key, err := wav2ulaw.OpenBlobURL(context.Background(), url)
+	if err != nil {
+		return nil, "", err
+	}
+	actual, _ := blobStores.LoadOrStore(id, store)
+	return actual.(wav2ulaw.BlobStore), key, nil
+}
+
+// readBlob reads a whole object
+func readBlob(url string) ([]byte, error) {
+	store, key, err := openBlob(url)
+	if err != nil {
+		return nil, err
+	}
+	r, err := store.Get(context.Background(), key)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// writeBlob creates or replaces an object
+func writeBlob(url string, data []byte) error {
+	store, key, err := openBlob(url)
+	if err != nil {
+		return err
+	}
+	return store.Put(context.Background(), key, bytes.NewReader(data))
+}
+
+// blobExists reports whether an object exists
+func blobExists(url string) (bool, error) {
+	store, key, err := openBlob(url)
+	if err != nil {
+		return false, err
+	}
+	keys, err := store.List(context.Background(), key)
+	if err != nil {
+		return false, err
+	}
+	for _, k := range keys {
+		if k == key {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// collectBlobs lists the objects matching a URL whose key may end in a glob
+// pattern, such as s3://bucket/prompts/*.wav. With recursive set, the
+// pattern is matched against the object names at every level below its
+// directory, and a URL without a pattern selects every object with the
+// mode's input extension. Outputs keep their key relative to the directory.
+func collectBlobs(pattern, outputDir string, recursive bool, job *conversion) ([]batchFile, error) {
+	store, key, err := openBlob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	root := strings.TrimSuffix(pattern, key)
+	if !strings.HasSuffix(root, "/") {
+		root += "/"
+	}
+	dir, namePattern := path.Split(key)
+	if !strings.ContainsAny(namePattern, "*?[") {
+		if recursive {
+			dir, namePattern = strings.TrimSuffix(key, "/")+"/", "*"+job.inputExt()
+			if key == "" {
+				dir = ""
+			}
+		} else {
+			namePattern = path.Base(key)
+		}
+	}
+	if _, err := path.Match(namePattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid input pattern '%s': %v", pattern, err)
+	}
+
+	keys, err := store.List(context.Background(), dir)
+	if err != nil {
+		return nil, fmt.Errorf("error listing '%s': %v", root+dir, err)
+	}
+	var files []batchFile
+	for _, k := range keys {
+		rel := strings.TrimPrefix(k, dir)
+		if !recursive && strings.Contains(rel, "/") {
+			continue
+		}
+		if ok, _ := path.Match(namePattern, path.Base(rel)); !ok {
+			continue
+		}
+		files = append(files, batchFile{input: root + k, output: outputPath(outputDir, rel, job)})
+	}
+	return files, nil
+}
+
+// joinBlobURL joins a blob URL and a relative key
+func joinBlobURL(dir, rel string) string {
+	return strings.TrimSuffix(dir, "/") + "/" + path.Clean(strings.ReplaceAll(rel, "\\", "/"))
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"wav2ulaw"
+
+	"golang.org/x/oauth2/google"
+)
+
+// gcsStore is a Google Cloud Storage bucket, accessed through the JSON API
+type gcsStore struct {
+	client *http.Client
+	// https://storage.googleapis.com, or the emulator's address
+	endpoint string
+	bucket   string
+}
+
+// openGCSStore opens a bucket with Application Default Credentials
+// (GOOGLE_APPLICATION_CREDENTIALS, gcloud's login or the metadata server).
+// STORAGE_EMULATOR_HOST points it at an emulator, without credentials.
+func openGCSStore(ctx context.Context, bucket string) (wav2ulaw.BlobStore, error) {
+	if host := os.Getenv("STORAGE_EMULATOR_HOST"); host != "" {
+		endpoint := host
+		if u, err := url.Parse(host); err != nil || u.Scheme == "" || u.Host == "" {
+			endpoint = "http://" + host
+		}
+		return &gcsStore{client: http.DefaultClient, endpoint: endpoint, bucket: bucket}, nil
+	}
+	client, err := google.DefaultClient(ctx, "https://www.googleapis.com/auth/devstorage.read_write")
+	if err != nil {
+		return nil, err
+	}
+	return &gcsStore{client: client, endpoint: "https://storage.googleapis.com", bucket: bucket}, nil
+}
+
+func (s *gcsStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := s.do(ctx, http.MethodGet, "/storage/v1/b/"+url.PathEscape(s.bucket)+"/o/"+url.PathEscape(key)+"?alt=media", key, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *gcsStore) Put(ctx context.Context, key string, r io.Reader) error {
+	query := url.Values{"uploadType": {"media"}, "name": {key}}
+	resp, err := s.do(ctx, http.MethodPost, "/upload/storage/v1/b/"+url.PathEscape(s.bucket)+"/o?"+query.Encode(), key, r)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *gcsStore) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	query := url.Values{"prefix": {prefix}, "fields": {"items(name),nextPageToken"}}
+	for {
+		resp, err := s.do(ctx, http.MethodGet, "/storage/v1/b/"+url.PathEscape(s.bucket)+"/o?"+query.Encode(), prefix, nil)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Items []struct {
+				Name string `json:"name"`
+			} `json:"items"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("invalid object listing: %v", err)
+		}
+		for _, item := range page.Items {
+			keys = append(keys, item.Name)
+		}
+		if page.NextPageToken == "" {
+			return keys, nil
+		}
+		query.Set("pageToken", page.NextPageToken)
+	}
+}
+
+// do sends a JSON API request about the object (or prefix) key, turning
+// error statuses into errors
+func (s *gcsStore) do(ctx context.Context, method, path, key string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.endpoint+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s gs://%s/%s: %s: %s", method, s.bucket, key, resp.Status, bytes.TrimSpace(message))
+	}
+	return resp, nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"time"
+	"wav2ulaw"
+)
+
+// Values of -gap-fill
+var gapFills = map[string]wav2ulaw.GapFill{
+	"silence": wav2ulaw.GapFillSilence,
+	"noise":   wav2ulaw.GapFillComfortNoise,
+	"conceal": wav2ulaw.GapFillConceal,
+}
+
+// captureCommand defines the flags of the "capture" subcommand and returns its implementation
+func captureCommand(fs *flag.FlagSet) func(args []string) {
+	listen := fs.String("listen", "", "UDP address to receive RTP on, e.g. :4000")
+	outputFile := fs.String("output", "", "Output WAV file path (- for stdout)")
+	duration := fs.Duration("duration", 0, "Stop this long after the first packet (0 = until interrupted)")
+	timeout := fs.Duration("timeout", 5*time.Second, "Stop when no packet arrives for this long after the first (0 = never)")
+	gapFill := fs.String("gap-fill", "silence", "Fill for lost packets: silence, noise or conceal")
+	sampleRate := fs.Uint("sample-rate", 8000, "Sample rate for the output WAV file")
+	windowSize := fs.Int("window-size", 16, "Resampling window size used when upsampling")
+	logFlags := registerLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: wav2ulaw capture -listen :4000 -output call.wav [flags]")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Records a PCMU or PCMA (payload type 0 or 8) RTP stream to WAV. The first")
+		fmt.Fprintln(os.Stderr, "packet selects the stream, packets are reordered by timestamp and losses filled.")
+		fs.PrintDefaults()
+	}
+	return func(args []string) {
+		if *listen == "" || *outputFile == "" || len(args) != 0 {
+			fs.Usage()
+			os.Exit(exitUsage)
+		}
+		logger, err := logFlags.logger(os.Stderr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		fill, ok := gapFills[*gapFill]
+		if !ok {
+			logger.Error(fmt.Sprintf("invalid -gap-fill '%s'. Must be 'silence', 'noise' or 'conceal'", *gapFill))
+			os.Exit(exitUsage)
+		}
+
+		conn, err := net.ListenPacket("udp", *listen)
+		if err != nil {
+			logger.Error("cannot listen", "addr", *listen, "error", err)
+			os.Exit(1)
+		}
+		// Ctrl-C ends the capture and still writes the file
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt)
+		go func() {
+			<-interrupt
+			conn.Close()
+		}()
+
+		logger.Info("listening", "addr", conn.LocalAddr().String())
+		depacketizer := wav2ulaw.NewRTPDepacketizer()
+		buf := make([]byte, 65536)
+		started := false
+		for {
+			if started && *timeout > 0 {
+				conn.SetReadDeadline(time.Now().Add(*timeout))
+			}
+			n, addr, err := conn.ReadFrom(buf)
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				logger.Info("stream idle", "timeout", *timeout)
+				break
+			}
+			if errors.Is(err, net.ErrClosed) {
+				break
+			}
+			if err != nil {
+				logger.Error("error receiving", "error", err)
+				os.Exit(1)
+			}
+
+			packet, err := wav2ulaw.ParseRTPPacket(buf[:n])
+			if err != nil {
+				logger.Debug("ignoring datagram", "from", addr.String(), "error", err)
+				continue
+			}
+			// Push decodes the payload, so buf can be reused
+			depacketizer.Push(packet)
+			if !started && depacketizer.Stats().Packets == 1 {
+				started = true
+				logger.Info("receiving", "from", addr.String(), "ssrc", fmt.Sprintf("0x%08x", packet.SSRC), "payload-type", packet.PayloadType)
+				if *duration > 0 {
+					time.AfterFunc(*duration, func() { conn.Close() })
+				}
+			}
+		}
+		conn.Close()
+
+		stats := depacketizer.Stats()
+		if stats.Packets == 0 {
+			logger.Error("no RTP audio received", "ignored", stats.Ignored)
+			os.Exit(1)
+		}
+		output, err := depacketizer.ConvertToWav(uint32(*sampleRate), *windowSize, fill)
+		if err != nil {
+			logger.Error("error encoding WAV", "error", err)
+			os.Exit(exitDecode)
+		}
+		if err := writeOutput(*outputFile, output); err != nil {
+			logger.Error("error writing output file", "error", err)
+			os.Exit(exitWrite)
+		}
+		logger.Info("capture completed", "output", *outputFile, "packets", stats.Packets, "lost", stats.Lost, "duplicates", stats.Duplicates, "ignored", stats.Ignored)
+	}
+}
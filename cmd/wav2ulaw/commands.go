@@ -0,0 +1,109 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+)
+
+// command is a subcommand of the CLI. setup defines the command's flags on
+// a FlagSet and returns the function run with the remaining arguments, so
+// flags can be listed without running the command, as completion does.
+type command struct {
+	name    string
+	summary string
+	setup   func(fs *flag.FlagSet) func(args []string)
+}
+
+// commands lists every subcommand, the default conversion first
+var commands []*command
+
+func init() {
+	// Assigned here because the completion command refers to this list
+	commands = []*command{
+		{"convert", "Convert between WAV and u-law (the default)", convertCommand},
+		{"watch", "Convert files as they appear in a directory", watchCommand},
+		{"follow", "Convert a WAV file while it is still being written", followCommand},
+		{"serve", "Serve conversions over gRPC and HTTP", serveCommand},
+		{"rtp", "Send a file as a real-time RTP stream", rtpCommand},
+		{"capture", "Record an RTP stream to WAV", captureCommand},
+		{"ivr-test", "Play a prompt into an RTP endpoint and record its response", ivrTestCommand},
+		{"ari", "Play and record on Asterisk channels via ARI external media", ariCommand},
+		{"kvs", "Extract call audio from Kinesis Video Streams fragments", kvsCommand},
+		{"concat", "Join files into one u-law stream", concatCommand},
+		{"mix", "Overlay one file on another into one u-law stream", mixCommand},
+		{"merge-legs", "Combine the RX and TX u-law legs of a call into a stereo WAV", mergeLegsCommand},
+		{"split-legs", "Convert each channel of a stereo call WAV to its own u-law file", splitLegsCommand},
+		{"segment", "Cut a WAV file into fixed-length WAV segments", segmentCommand},
+		{"info", "Report format, levels and problems of files", infoCommand},
+		{"compare", "Compare two files with objective quality metrics", compareCommand},
+		{"meta", "Show or edit INFO and bext metadata of WAV files", metaCommand},
+		{"config", "Print the effective conversion settings", configCommand},
+		{"spectrogram", "Render a spectrogram PNG", spectrogramCommand},
+		{"bench", "Compare speed and quality of filter configurations", benchCommand},
+		{"selftest", "Verify filters and resampling with synthesized signals", selftestCommand},
+		{"gen-corpus", "Write WAV fixtures with their expected u-law for regression tests", genCorpusCommand},
+		{"play", "Play a file on the default audio device", playCommand},
+		{"record", "Record from the default input device to u-law", recordCommand},
+		{"completion", "Print a shell completion script", completionCommand},
+		{"help", "List commands, or show the flags of one", helpCommand},
+	}
+	commandArgs["help"] = commandNames()
+}
+
+// findCommand returns the command called name, or nil
+func findCommand(name string) *command {
+	for _, c := range commands {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// flagSet returns the command's flags along with its implementation
+func (c *command) flagSet() (*flag.FlagSet, func(args []string)) {
+	fs := flag.NewFlagSet(c.name, flag.ExitOnError)
+	run := c.setup(fs)
+	return fs, run
+}
+
+// run sets flags from the environment, parses args and runs the command
+func (c *command) run(args []string) {
+	fs, run := c.flagSet()
+	if err := applyEnv(fs, os.LookupEnv); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	fs.Parse(args)
+	run(fs.Args())
+}
+
+// helpCommand defines the flags of the "help" subcommand and returns its implementation
+func helpCommand(fs *flag.FlagSet) func(args []string) {
+	return func(args []string) {
+		if len(args) == 0 {
+			printCommands(os.Stdout)
+			return
+		}
+		c := findCommand(args[0])
+		if c == nil {
+			fmt.Fprintf(os.Stderr, "Error: Unknown command '%s'\n", args[0])
+			os.Exit(exitUsage)
+		}
+		cfs, _ := c.flagSet()
+		cfs.Usage()
+	}
+}
+
+// printCommands writes the command list with summaries
+func printCommands(w io.Writer) {
+	fmt.Fprintln(w, "Commands:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, c := range commands {
+		fmt.Fprintf(tw, "  %s\t%s\n", c.name, c.summary)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"text/tabwriter"
+	"wav2ulaw"
+)
+
+// compareReport is the report printed by the compare subcommand. Levels
+// that are infinite, such as the PSNR of identical files, are null in JSON.
+type compareReport struct {
+	A                    string   `json:"a"`
+	B                    string   `json:"b"`
+	SampleRate           int      `json:"sample_rate"`
+	LagSamples           int      `json:"lag_samples"`
+	PSNR                 *float64 `json:"psnr_db"`
+	SegmentalSNR         float64  `json:"segmental_snr_db"`
+	SpectralDistortion   float64  `json:"spectral_distortion_db"`
+	LoudnessDelta        *float64 `json:"loudness_delta_lu"`
+	DurationDeltaSeconds float64  `json:"duration_delta_seconds"`
+	Pass                 bool     `json:"pass"`
+	Failures             []string `json:"failures"`
+}
+
+// compareCommand defines the flags of the "compare" subcommand and returns its implementation
+func compareCommand(fs *flag.FlagSet) func(args []string) {
+	asJSON := fs.Bool("json", false, "Print the report as JSON")
+	minPSNR := fs.Float64("min-psnr", math.Inf(-1), "Fail when the PSNR is below this (dB)")
+	minSegSNR := fs.Float64("min-segsnr", math.Inf(-1), "Fail when the segmental SNR is below this (dB)")
+	maxLoudness := fs.Float64("max-loudness-delta", math.Inf(1), "Fail when the loudness differs by more than this (LU)")
+	maxDuration := fs.Duration("max-duration-delta", -1, "Fail when the durations differ by more than this, e.g. 10ms (negative = no limit)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: wav2ulaw compare [flags] <reference> <file>")
+		fs.PrintDefaults()
+	}
+	return func(args []string) {
+		if len(args) != 2 {
+			fs.Usage()
+			os.Exit(exitUsage)
+		}
+		var data [2][]byte
+		for i, path := range args {
+			var err error
+			if data[i], err = readInput(path); err != nil {
+				fmt.Fprintf(os.Stderr, "Error reading '%s': %v\n", path, err)
+				os.Exit(exitInput)
+			}
+		}
+		comparison, err := wav2ulaw.CompareFiles(data[0], data[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error comparing: %v\n", err)
+			os.Exit(exitDecode)
+		}
+
+		report := &compareReport{
+			A:                    args[0],
+			B:                    args[1],
+			SampleRate:           comparison.SampleRate,
+			LagSamples:           comparison.Lag,
+			PSNR:                 finite(comparison.PSNR),
+			SegmentalSNR:         comparison.SegmentalSNR,
+			SpectralDistortion:   comparison.SpectralDistortion,
+			LoudnessDelta:        finite(comparison.LoudnessDelta),
+			DurationDeltaSeconds: comparison.DurationDelta.Seconds(),
+			Failures:             []string{},
+		}
+		fail := func(format string, args ...any) {
+			report.Failures = append(report.Failures, fmt.Sprintf(format, args...))
+		}
+		if comparison.PSNR < *minPSNR {
+			fail("PSNR %.2f dB below %.2f dB", comparison.PSNR, *minPSNR)
+		}
+		if comparison.SegmentalSNR < *minSegSNR {
+			fail("segmental SNR %.2f dB below %.2f dB", comparison.SegmentalSNR, *minSegSNR)
+		}
+		// A silent file against a non-silent one differs by an infinite amount
+		if delta := comparison.LoudnessDelta; math.Abs(delta) > *maxLoudness || (math.IsNaN(delta) && !math.IsInf(*maxLoudness, 1)) {
+			fail("loudness differs by %.2f LU, more than %.2f LU", delta, *maxLoudness)
+		}
+		if delta := comparison.DurationDelta; *maxDuration >= 0 && (delta > *maxDuration || -delta > *maxDuration) {
+			fail("durations differ by %v, more than %v", delta, *maxDuration)
+		}
+		report.Pass = len(report.Failures) == 0
+
+		if *asJSON {
+			out, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error encoding report: %v\n", err)
+				os.Exit(exitFailure)
+			}
+			os.Stdout.Write(append(out, '\n'))
+		} else {
+			printCompareReport(comparison, report)
+		}
+		if !report.Pass {
+			os.Exit(exitFailure)
+		}
+	}
+}
+
+// finite returns a pointer to v, or nil when v is infinite or NaN
+func finite(v float64) *float64 {
+	if math.IsInf(v, 0) || math.IsNaN(v) {
+		return nil
+	}
+	return &v
+}
+
+// printCompareReport prints a human-readable comparison
+func printCompareReport(c *wav2ulaw.Comparison, report *compareReport) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(w, "Reference:\t%s (%v, %.1f LUFS)\n", report.A, c.DurationA, c.LoudnessA)
+	fmt.Fprintf(w, "File:\t%s (%v, %.1f LUFS)\n", report.B, c.DurationB, c.LoudnessB)
+	fmt.Fprintf(w, "Compared at:\t%d Hz, lag %d samples\n", c.SampleRate, c.Lag)
+	fmt.Fprintf(w, "PSNR:\t%.2f dB\n", c.PSNR)
+	fmt.Fprintf(w, "Segmental SNR:\t%.2f dB\n", c.SegmentalSNR)
+	fmt.Fprintf(w, "Spectral distortion:\t%.2f dB\n", c.SpectralDistortion)
+	fmt.Fprintf(w, "Loudness delta:\t%+.2f LU\n", c.LoudnessDelta)
+	fmt.Fprintf(w, "Duration delta:\t%v\n", c.DurationDelta)
+	if report.Pass {
+		fmt.Fprintf(w, "Result:\tpass\n")
+	} else {
+		for _, failure := range report.Failures {
+			fmt.Fprintf(w, "Result:\tFAIL, %s\n", failure)
+		}
+	}
+	w.Flush()
+}
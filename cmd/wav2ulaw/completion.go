@@ -0,0 +1,303 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Values offered for flags that take one of a fixed set, keyed by flag name
+// or by "command.flag" where commands give the flag different meanings
+var flagChoices = map[string][]string{
+	"mode":               {"wav2ulaw", "ulaw2wav", "ulaw2ulaw", "asr", "wav2alaw", "alaw2wav", "ulaw2alaw", "alaw2ulaw"},
+	"log-format":         {"text", "json"},
+	"levels":             {"csv", "json"},
+	"report-format":      {"csv", "json"},
+	"after":              {"none", "move", "delete"},
+	"gap-fill":           {"silence", "noise", "conceal"},
+	"spectrogram.format": {"wav", "ulaw"},
+	"output-format":      {"wav", "ulaw"},
+	"config.format":      {"yaml", "json"},
+	"kvs.track":          {"AUDIO_FROM_CUSTOMER", "AUDIO_TO_CUSTOMER", "mix"},
+	"rtp.marker":         {"first", "talkspurt", "never"},
+	"resample-tradeoff":  {"fast", "balanced", "best"},
+	"bit-depth":          {"8", "16"},
+}
+
+// Flags completed with file or directory names
+var (
+	fileFlags = map[string]bool{"input": true, "output": true, "config": true, "play": true, "record": true, "manifest": true, "report": true, "noise": true, "log-file": true, "left": true, "right": true, "rx": true, "tx": true}
+	dirFlags  = map[string]bool{"output-dir": true, "move-dir": true}
+)
+
+// Positional arguments: "file" and "dir" complete paths, anything else is
+// offered as a word
+var commandArgs = map[string][]string{
+	"watch":      {"dir"},
+	"follow":     {"file"},
+	"gen-corpus": {"dir"},
+	"concat":     {"file"},
+	"mix":        {"file"},
+	"merge-legs": {"file"},
+	"split-legs": {"file"},
+	"segment":    {"file"},
+	"info":       {"file"},
+	"compare":    {"file"},
+	"play":       {"file"},
+	"rtp":        {"file"},
+	"ivr-test":   {"file"},
+	"kvs":        {"file"},
+	"config":     {"dump"},
+	"meta":       {"show", "set"},
+	"completion": {"bash", "zsh", "fish"},
+}
+
+// completionFlag describes one flag for the completion scripts
+type completionFlag struct {
+	name   string
+	usage  string
+	isBool bool
+	values []string
+	file   bool
+	dir    bool
+}
+
+// presetNames returns every value accepted by -preset
+func presetNames() []string {
+	names := make([]string, 0, len(configPresets)+len(asrPresets)+1)
+	for name := range configPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	names = append(names, "telephone-fx")
+	for name := range asrPresets {
+		names = append(names, name)
+	}
+	return names
+}
+
+// commandFlags lists the flags of c in name order
+func commandFlags(c *command) []completionFlag {
+	fs, _ := c.flagSet()
+	var flags []completionFlag
+	fs.VisitAll(func(fl *flag.Flag) {
+		f := completionFlag{name: fl.Name, usage: fl.Usage, file: fileFlags[fl.Name], dir: dirFlags[fl.Name]}
+		if b, ok := fl.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+			f.isBool = true
+		}
+		if values, ok := flagChoices[c.name+"."+fl.Name]; ok {
+			f.values = values
+		} else if values, ok := flagChoices[fl.Name]; ok {
+			f.values = values
+		} else if fl.Name == "preset" {
+			f.values = presetNames()
+		}
+		flags = append(flags, f)
+	})
+	return flags
+}
+
+// completionCommand defines the flags of the "completion" subcommand and returns its implementation
+func completionCommand(fs *flag.FlagSet) func(args []string) {
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: wav2ulaw completion bash|zsh|fish")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Prints a completion script for the shell, for example:")
+		fmt.Fprintln(os.Stderr, "  source <(wav2ulaw completion bash)")
+	}
+	return func(args []string) {
+		if len(args) != 1 {
+			fs.Usage()
+			os.Exit(exitUsage)
+		}
+		switch args[0] {
+		case "bash":
+			fmt.Print(bashCompletion())
+		case "zsh":
+			fmt.Print(zshCompletion())
+		case "fish":
+			fmt.Print(fishCompletion())
+		default:
+			fmt.Fprintf(os.Stderr, "Error: Invalid shell '%s'. Must be 'bash', 'zsh' or 'fish'\n", args[0])
+			os.Exit(exitUsage)
+		}
+	}
+}
+
+// commandNames returns the names of all commands
+func commandNames() []string {
+	names := make([]string, len(commands))
+	for i, c := range commands {
+		names[i] = c.name
+	}
+	return names
+}
+
+// bashCompletion returns a bash completion script
+func bashCompletion() string {
+	var b strings.Builder
+	b.WriteString("# bash completion for wav2ulaw\n")
+	b.WriteString("_wav2ulaw() {\n")
+	b.WriteString("    local cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\" cmd=convert\n")
+	b.WriteString("    if [[ ${COMP_CWORD} -gt 1 && ${COMP_WORDS[1]} != -* ]]; then\n")
+	b.WriteString("        cmd=\"${COMP_WORDS[1]}\"\n")
+	b.WriteString("    fi\n")
+	fmt.Fprintf(&b, "    if [[ ${COMP_CWORD} -eq 1 && ${cur} != -* ]]; then\n")
+	fmt.Fprintf(&b, "        COMPREPLY=($(compgen -W %q -- \"${cur}\"))\n", strings.Join(commandNames(), " "))
+	b.WriteString("        return\n")
+	b.WriteString("    fi\n")
+
+	// Values of the flag just typed
+	b.WriteString("    case \"${cmd}:${prev#-}\" in\n")
+	for _, c := range commands {
+		for _, f := range commandFlags(c) {
+			pattern := fmt.Sprintf("%s:%s|%s:-%s", c.name, f.name, c.name, f.name)
+			switch {
+			case f.values != nil:
+				fmt.Fprintf(&b, "        %s) COMPREPLY=($(compgen -W %q -- \"${cur}\")); return ;;\n", pattern, strings.Join(f.values, " "))
+			case f.dir:
+				fmt.Fprintf(&b, "        %s) COMPREPLY=($(compgen -d -- \"${cur}\")); return ;;\n", pattern)
+			case f.file:
+				fmt.Fprintf(&b, "        %s) COMPREPLY=($(compgen -f -- \"${cur}\")); return ;;\n", pattern)
+			case !f.isBool:
+				fmt.Fprintf(&b, "        %s) return ;;\n", pattern)
+			}
+		}
+	}
+	b.WriteString("    esac\n")
+
+	// Flag names, then positional arguments
+	b.WriteString("    if [[ ${cur} == -* ]]; then\n")
+	b.WriteString("        case \"${cmd}\" in\n")
+	for _, c := range commands {
+		var names []string
+		for _, f := range commandFlags(c) {
+			names = append(names, "-"+f.name)
+		}
+		if names != nil {
+			fmt.Fprintf(&b, "            %s) COMPREPLY=($(compgen -W %q -- \"${cur}\")) ;;\n", c.name, strings.Join(names, " "))
+		}
+	}
+	b.WriteString("        esac\n")
+	b.WriteString("        return\n")
+	b.WriteString("    fi\n")
+	b.WriteString("    case \"${cmd}\" in\n")
+	for _, c := range commands {
+		switch args := commandArgs[c.name]; {
+		case args == nil:
+		case args[0] == "dir":
+			fmt.Fprintf(&b, "        %s) COMPREPLY=($(compgen -d -- \"${cur}\")) ;;\n", c.name)
+		case args[0] == "file":
+			fmt.Fprintf(&b, "        %s) COMPREPLY=($(compgen -f -- \"${cur}\")) ;;\n", c.name)
+		default:
+			fmt.Fprintf(&b, "        %s) COMPREPLY=($(compgen -W %q -- \"${cur}\")) ;;\n", c.name, strings.Join(args, " "))
+		}
+	}
+	b.WriteString("    esac\n")
+	b.WriteString("}\n")
+	b.WriteString("complete -o filenames -F _wav2ulaw wav2ulaw\n")
+	return b.String()
+}
+
+// zshCompletion returns a zsh completion script
+func zshCompletion() string {
+	// Brackets delimit descriptions in _arguments specs
+	escape := strings.NewReplacer("'", `'\''`, "[", `\[`, "]", `\]`)
+
+	var b strings.Builder
+	b.WriteString("#compdef wav2ulaw\n\n")
+	b.WriteString("_wav2ulaw() {\n")
+	b.WriteString("    local -a commands\n")
+	b.WriteString("    commands=(\n")
+	for _, c := range commands {
+		fmt.Fprintf(&b, "        '%s:%s'\n", c.name, escape.Replace(c.summary))
+	}
+	b.WriteString("    )\n")
+	b.WriteString("    local cmd=convert\n")
+	b.WriteString("    if (( CURRENT == 2 )) && [[ $words[2] != -* ]]; then\n")
+	b.WriteString("        _describe -t commands 'wav2ulaw command' commands\n")
+	b.WriteString("        return\n")
+	b.WriteString("    elif [[ $words[2] != -* ]]; then\n")
+	b.WriteString("        cmd=$words[2]\n")
+	b.WriteString("        shift words\n")
+	b.WriteString("        (( CURRENT-- ))\n")
+	b.WriteString("    fi\n")
+	b.WriteString("    case $cmd in\n")
+	for _, c := range commands {
+		fmt.Fprintf(&b, "        %s)\n", c.name)
+		b.WriteString("            _arguments")
+		for _, f := range commandFlags(c) {
+			spec := fmt.Sprintf("-%s[%s]", f.name, escape.Replace(f.usage))
+			switch {
+			case f.values != nil:
+				spec += fmt.Sprintf(":%s:(%s)", f.name, strings.Join(f.values, " "))
+			case f.dir:
+				spec += ":directory:_files -/"
+			case f.file:
+				spec += ":file:_files"
+			case !f.isBool:
+				spec += ":" + f.name + ": "
+			}
+			fmt.Fprintf(&b, " \\\n                '%s'", spec)
+		}
+		switch args := commandArgs[c.name]; {
+		case args == nil:
+		case args[0] == "dir":
+			b.WriteString(" \\\n                '*:directory:_files -/'")
+		case args[0] == "file":
+			b.WriteString(" \\\n                '*:file:_files'")
+		default:
+			fmt.Fprintf(&b, " \\\n                '1:argument:(%s)'", strings.Join(args, " "))
+		}
+		b.WriteString("\n            ;;\n")
+	}
+	b.WriteString("    esac\n")
+	b.WriteString("}\n\n")
+	b.WriteString("_wav2ulaw \"$@\"\n")
+	return b.String()
+}
+
+// fishCompletion returns a fish completion script
+func fishCompletion() string {
+	quote := func(s string) string {
+		return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+	}
+	names := commandNames()
+
+	var b strings.Builder
+	b.WriteString("# fish completion for wav2ulaw\n")
+	fmt.Fprintf(&b, "set -l wav2ulaw_commands %s\n", strings.Join(names, " "))
+	for _, c := range commands {
+		fmt.Fprintf(&b, "complete -c wav2ulaw -n \"not __fish_seen_subcommand_from $wav2ulaw_commands\" -a %s -d %s\n", c.name, quote(c.summary))
+	}
+	for _, c := range commands {
+		condition := "__fish_seen_subcommand_from " + c.name
+		if c == commands[0] {
+			// The default command's flags also apply when no command is named
+			condition = "not __fish_seen_subcommand_from " + strings.Join(names[1:], " ")
+		}
+		for _, f := range commandFlags(c) {
+			line := fmt.Sprintf("complete -c wav2ulaw -n %s -o %s -d %s", quote(condition), f.name, quote(f.usage))
+			switch {
+			case f.values != nil:
+				line += " -x -a " + quote(strings.Join(f.values, " "))
+			case f.dir:
+				line += " -x -a '(__fish_complete_directories)'"
+			case f.file:
+				line += " -r -F"
+			case !f.isBool:
+				line += " -x"
+			}
+			b.WriteString(line + "\n")
+		}
+		switch args := commandArgs[c.name]; {
+		case args == nil, args[0] == "file", args[0] == "dir":
+			// Paths are completed by default
+		default:
+			fmt.Fprintf(&b, "complete -c wav2ulaw -n %s -f -a %s\n", quote(condition), quote(strings.Join(args, " ")))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"wav2ulaw"
+)
+
+// concatCommand defines the flags of the "concat" subcommand and returns its implementation
+func concatCommand(fs *flag.FlagSet) func(args []string) {
+	outputFile := fs.String("output", "", "Output u-law file path (- for stdout)")
+	crossfade := fs.Float64("crossfade", 0, "Crossfade between segments in milliseconds (0 = hard cuts)")
+	convFlags := registerConversionFlags(fs)
+	logFlags := registerLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: wav2ulaw concat [flags] -output out.ulaw <file>...")
+		fs.PrintDefaults()
+	}
+	return func(args []string) {
+		if *outputFile == "" || len(args) == 0 {
+			fs.Usage()
+			os.Exit(exitUsage)
+		}
+		logger, err := logFlags.logger(os.Stderr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		job, err := convFlags.conversion(logger)
+		if err != nil {
+			logger.Error("invalid settings", "error", err)
+			os.Exit(exitUsage)
+		}
+
+		// WAV inputs go through the processing chain, u-law inputs are joined as is
+		segments := make([][]byte, 0, len(args))
+		for _, path := range args {
+			data, err := readInput(path)
+			if err != nil {
+				logger.Error("error reading input file", "input", path, "error", err)
+				os.Exit(exitInput)
+			}
+			format, err := wav2ulaw.DetectFormat(data)
+			if err != nil {
+				logger.Error("unsupported input", "input", path, "error", err)
+				os.Exit(exitFormat)
+			}
+			if format == wav2ulaw.FormatWAV {
+				if data, err = wav2ulaw.ConvertWavBytesToUlaw(data, job.config); err != nil {
+					logger.Error("error converting WAV to u-law", "input", path, "error", err)
+					os.Exit(exitDecode)
+				}
+			}
+			logger.Debug("segment", "input", path, "format", format.String(), "samples", len(data))
+			segments = append(segments, data)
+		}
+
+		output := wav2ulaw.ConcatenateUlaw(segments, *crossfade, job.config.FadeShape)
+		if err := writeOutput(*outputFile, output); err != nil {
+			logger.Error("error writing output file", "error", err)
+			os.Exit(exitWrite)
+		}
+		logger.Info("concatenation completed", "segments", len(segments), "output", *outputFile, "samples", len(output))
+	}
+}
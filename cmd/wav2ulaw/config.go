@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configCommand defines the flags of the "config" subcommand and returns its implementation
+func configCommand(fs *flag.FlagSet) func(args []string) {
+	format := fs.String("format", "yaml", "Output format: yaml or json")
+	convFlags := registerConversionFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: wav2ulaw config dump [-format yaml|json] [flags]")
+		fs.PrintDefaults()
+	}
+	return func(args []string) {
+		if len(args) == 0 || args[0] != "dump" {
+			fs.Usage()
+			os.Exit(exitUsage)
+		}
+		// Flags may also follow the action
+		fs.Parse(args[1:])
+
+		// Validates the settings and merges in -config
+		if _, err := convFlags.conversion(slog.Default()); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+
+		var out []byte
+		var err error
+		switch *format {
+		case "yaml":
+			out, err = yaml.Marshal(convFlags.settings())
+		case "json":
+			out, err = json.MarshalIndent(convFlags.settings(), "", "  ")
+			out = append(out, '\n')
+		default:
+			fmt.Fprintf(os.Stderr, "Error: Invalid format '%s'. Must be 'yaml' or 'json'\n", *format)
+			os.Exit(exitUsage)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error encoding configuration: %v\n", err)
+			os.Exit(1)
+		}
+		os.Stdout.Write(out)
+	}
+}
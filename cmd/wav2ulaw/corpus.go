@@ -0,0 +1,105 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"wav2ulaw"
+)
+
+// corpusIndexEntry describes one fixture in the corpus.json index
+type corpusIndexEntry struct {
+	Name           string `json:"name"`
+	Description    string `json:"description"`
+	SampleRate     int    `json:"sample_rate"`
+	BitDepth       int    `json:"bit_depth"`
+	Channels       int    `json:"channels"`
+	Input          string `json:"input"`
+	Expected       string `json:"expected"`
+	ExpectedSHA256 string `json:"expected_sha256"`
+}
+
+// genCorpusCommand defines the flags of the "gen-corpus" subcommand and returns its implementation
+func genCorpusCommand(fs *flag.FlagSet) func(args []string) {
+	convFlags := registerConversionFlags(fs)
+	logFlags := registerLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: wav2ulaw gen-corpus [flags] <dir>")
+		fmt.Fprintln(os.Stderr, "Writes WAV fixtures, their conversions with the given flags and a corpus.json index to <dir>")
+		fs.PrintDefaults()
+	}
+	return func(args []string) {
+		if len(args) != 1 {
+			fs.Usage()
+			os.Exit(exitUsage)
+		}
+		dir := args[0]
+		logger, err := logFlags.logger(os.Stderr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		job, err := convFlags.conversion(logger)
+		if err != nil {
+			logger.Error("invalid settings", "error", err)
+			os.Exit(exitUsage)
+		}
+		if job.preset == "" && job.mode != "wav2ulaw" {
+			logger.Error("invalid settings", "error", fmt.Sprintf("the corpus inputs are WAV files, which mode '%s' does not convert", job.mode))
+			os.Exit(exitUsage)
+		}
+
+		fixtures, err := wav2ulaw.CorpusInputs()
+		if err != nil {
+			logger.Error("error generating corpus", "error", err)
+			os.Exit(exitFailure)
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			logger.Error("error creating corpus directory", "dir", dir, "error", err)
+			os.Exit(exitWrite)
+		}
+
+		// The expected outputs are converted like the convert command would,
+		// so "wav2ulaw [flags] <input> <expected>" reproduces each of them
+		index := make([]corpusIndexEntry, 0, len(fixtures))
+		for _, f := range fixtures {
+			output, err := job.convertData(f.Wav)
+			if err != nil {
+				logger.Error("error converting fixture", "fixture", f.Name, "error", err)
+				os.Exit(exitCode(err))
+			}
+			hash := sha256.Sum256(output)
+			entry := corpusIndexEntry{
+				Name:           f.Name,
+				Description:    f.Description,
+				SampleRate:     f.SampleRate,
+				BitDepth:       f.BitDepth,
+				Channels:       f.Channels,
+				Input:          f.Name + ".wav",
+				Expected:       f.Name + ".expected" + job.outputExt(),
+				ExpectedSHA256: hex.EncodeToString(hash[:]),
+			}
+			for name, data := range map[string][]byte{entry.Input: f.Wav, entry.Expected: output} {
+				if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+					logger.Error("error writing corpus", "error", err)
+					os.Exit(exitWrite)
+				}
+			}
+			index = append(index, entry)
+		}
+
+		data, err := json.MarshalIndent(index, "", "  ")
+		if err == nil {
+			err = os.WriteFile(filepath.Join(dir, "corpus.json"), append(data, '\n'), 0644)
+		}
+		if err != nil {
+			logger.Error("error writing corpus index", "error", err)
+			os.Exit(exitWrite)
+		}
+		logger.Info("corpus written", "dir", dir, "fixtures", len(index))
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"wav2ulaw"
+)
+
+// withStageDump returns a copy of the conversion that writes the audio after
+// each processing stage of inputPath to the -debug-dump directory, as
+// <name>.<step>-<stage>.wav numbered in processing order
+func (c *conversion) withStageDump(inputPath string) *conversion {
+	name := strings.TrimSuffix(filepath.Base(inputPath), filepath.Ext(inputPath))
+	if inputPath == "-" {
+		name = "stdin"
+	}
+	step := 0
+	config := *c.config
+	config.OnStageOutput = func(stage string, samples []int16, sampleRate int) {
+		step++
+		path := filepath.Join(c.debugDump, fmt.Sprintf("%s.%02d-%s.wav", name, step, strings.ReplaceAll(stage, " ", "-")))
+		data, err := wav2ulaw.EncodeWavPCM16(samples, sampleRate)
+		if err == nil {
+			err = os.WriteFile(path, data, 0644)
+		}
+		if err != nil {
+			c.logger.Warn("error writing stage dump", "stage", stage, "path", path, "error", err)
+			return
+		}
+		c.logger.Debug("stage dumped", "stage", stage, "path", path)
+	}
+	dumped := *c
+	dumped.config = &config
+	dumped.debugDump = ""
+	return &dumped
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// downloadOptions control how HTTP(S) inputs are fetched
+type downloadOptions struct {
+	// Largest body accepted, in bytes (0 = unlimited)
+	maxSize int64
+	// Time allowed for each attempt, including reading the body
+	timeout time.Duration
+	// Attempts made after the first one fails
+	retries int
+	// Receives retry warnings, slog.Default() when nil
+	logger *slog.Logger
+}
+
+// download holds the settings of every HTTP(S) input of the run. Commands
+// without the flags keep the defaults.
+var download = downloadOptions{maxSize: 256 << 20, timeout: time.Minute, retries: 2}
+
+// registerDownloadFlags defines the flags of HTTP(S) inputs
+func registerDownloadFlags(fs *flag.FlagSet) {
+	fs.Int64Var(&download.maxSize, "http-max-size", download.maxSize, "Largest HTTP(S) input accepted, in bytes (0 = unlimited)")
+	fs.DurationVar(&download.timeout, "http-timeout", download.timeout, "Time allowed for each HTTP(S) download attempt")
+	fs.IntVar(&download.retries, "http-retries", download.retries, "Retries of HTTP(S) downloads that fail with a network error or a 5xx or 429 status")
+}
+
+// isHTTPURL reports whether path is an http:// or https:// URL
+func isHTTPURL(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// urlExt returns the extension of the file a URL names, ignoring its query
+func urlExt(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return path.Ext(u.Path)
+}
+
+// permanentError marks download failures that retrying cannot fix
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+
+// fetch downloads url, streaming the body into the writer open returns.
+// open is called again before each retry, which must discard what the failed
+// attempt wrote.
+func (o downloadOptions) fetch(url string, open func() (io.Writer, error)) error {
+	backoff := 500 * time.Millisecond
+	for attempt := 0; ; attempt++ {
+		w, err := open()
+		if err != nil {
+			return err
+		}
+		err = o.get(url, w)
+		var permanent permanentError
+		if err == nil || errors.As(err, &permanent) || attempt >= o.retries {
+			return err
+		}
+		logger := o.logger
+		if logger == nil {
+			logger = slog.Default()
+		}
+		logger.Warn("download failed, retrying", "url", url, "error", err, "retry_in", backoff)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// get makes one download attempt
+func (o downloadOptions) get(url string, w io.Writer) error {
+	ctx := context.Background()
+	if o.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return permanentError{err}
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("GET %s: %s", url, resp.Status)
+		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+			return err
+		}
+		return permanentError{err}
+	}
+	if o.maxSize > 0 && resp.ContentLength > o.maxSize {
+		return permanentError{fmt.Errorf("GET %s: %d bytes exceed -http-max-size %d", url, resp.ContentLength, o.maxSize)}
+	}
+
+	body := io.Reader(resp.Body)
+	if o.maxSize > 0 {
+		body = io.LimitReader(resp.Body, o.maxSize+1)
+	}
+	n, err := io.Copy(w, body)
+	if err != nil {
+		return fmt.Errorf("GET %s: %v", url, err)
+	}
+	if o.maxSize > 0 && n > o.maxSize {
+		return permanentError{fmt.Errorf("GET %s: body exceeds -http-max-size %d", url, o.maxSize)}
+	}
+	return nil
+}
+
+// readURL downloads a whole HTTP(S) input into memory
+func readURL(url string) ([]byte, error) {
+	var buf bytes.Buffer
+	err := download.fetch(url, func() (io.Writer, error) {
+		buf.Reset()
+		return &buf, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// downloadFile streams an HTTP(S) input into a temporary file, which the
+// streaming converter can seek in while memory use stays constant. The
+// caller removes the file.
+func downloadFile(url string) (*os.File, error) {
+	file, err := os.CreateTemp("", "wav2ulaw-*"+urlExt(url))
+	if err != nil {
+		return nil, err
+	}
+	err = download.fetch(url, func() (io.Writer, error) {
+		if err := file.Truncate(0); err != nil {
+			return nil, err
+		}
+		_, err := file.Seek(0, io.SeekStart)
+		return file, err
+	})
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+	return file, nil
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"wav2ulaw"
+)
+
+// wavHeaderSize is the size of the header written by the library's WAV encoder
+const wavHeaderSize = 44
+
+// conversionPlan describes what converting one file would do
+type conversionPlan struct {
+	inputFormat    string
+	stats          *wav2ulaw.Stats
+	stages         []string
+	outputSamples  int
+	outputBytes    int
+	outputDuration time.Duration
+}
+
+// plan decodes and analyzes an input and predicts the conversion of it
+// without writing anything
+func (c *conversion) plan(inputPath string) (*conversionPlan, error) {
+	data, err := readInput(inputPath)
+	if err != nil {
+		return nil, withExitCode(exitInput, fmt.Errorf("error reading input file: %v", err))
+	}
+
+	if c.preset == "" && c.mode == "asr" {
+		return c.planASR(data)
+	}
+	p := &conversionPlan{}
+	if c.preset == "" && (c.mode == "ulaw2wav" || c.mode == "ulaw2ulaw" || c.mode == "alaw2wav" || c.mode == "ulaw2alaw" || c.mode == "alaw2ulaw") {
+		if format, _ := wav2ulaw.DetectFormat(data); format == wav2ulaw.FormatWAV {
+			return nil, withExitCode(exitFormat, fmt.Errorf("input is a WAV file, expected raw G.711"))
+		}
+		p.inputFormat = "u-law"
+		if strings.HasPrefix(c.mode, "alaw") {
+			// Both hold one 8 kHz sample per byte, so u-law stands in for the analysis
+			p.inputFormat = "A-law"
+			data = wav2ulaw.ConvertAlawToUlaw(data)
+		}
+		if p.stats, err = wav2ulaw.AnalyzeUlaw(data); err != nil {
+			return nil, withExitCode(exitDecode, err)
+		}
+		if c.mode == "ulaw2alaw" || c.mode == "alaw2ulaw" {
+			p.stages = append(p.stages, "transcode "+c.mode)
+			p.outputSamples = p.stats.Frames
+			p.outputBytes = p.outputSamples
+			p.outputDuration = samplesDuration(p.outputSamples, 8000)
+			return p, nil
+		}
+		p.stages = append(p.stages, p.inputFormat+" decode")
+		if c.mode == "ulaw2ulaw" {
+			c.reprocessStages(p)
+			p.outputSamples = c.loopStage(p, p.stats.Frames)
+			p.outputBytes = p.outputSamples
+			p.outputDuration = samplesDuration(p.outputSamples, 8000)
+			return p, nil
+		}
+		p.outputSamples = c.loopStage(p, p.stats.Frames)
+		if c.sampleRate != 8000 {
+			p.stages = append(p.stages, fmt.Sprintf("resample 8000->%d Hz", c.sampleRate))
+			p.outputSamples = int(math.Ceil(float64(p.outputSamples) * float64(c.sampleRate) / 8000))
+		}
+		p.outputBytes = wavHeaderSize + 2*p.outputSamples
+		p.outputDuration = samplesDuration(p.outputSamples, int(c.sampleRate))
+		return p, nil
+	}
+
+	if err := checkWavInput(data); err != nil {
+		return nil, err
+	}
+	if p.stats, err = wav2ulaw.AnalyzeWav(data); err != nil {
+		return nil, withExitCode(exitDecode, err)
+	}
+	p.inputFormat = fmt.Sprintf("WAV %d Hz, %d-bit, %d channel(s)", p.stats.SampleRate, p.stats.BitDepth, p.stats.Channels)
+
+	if c.preset == "telephone-fx" {
+		p.stages = []string{"telephone effect (300-3400 Hz band, line distortion, compression)"}
+		p.outputSamples = p.stats.Frames
+		p.outputBytes = wavHeaderSize + 2*p.outputSamples
+		p.outputDuration = samplesDuration(p.outputSamples, p.stats.SampleRate)
+		return p, nil
+	}
+
+	config := c.config
+	inputRate := config.InputSampleRate
+	if inputRate == 0 {
+		inputRate = p.stats.SampleRate
+	}
+	// Channels are processed interleaved unless they are mixed down first
+	samples := p.stats.Frames
+	if config.ForceMono && p.stats.Channels > 1 {
+		p.stages = append(p.stages, fmt.Sprintf("mix %d channels to mono", p.stats.Channels))
+	} else {
+		samples *= p.stats.Channels
+	}
+
+	if config.Noise != nil {
+		source := "generated"
+		if config.Noise.Data != nil {
+			source = "file"
+		}
+		p.stages = append(p.stages, fmt.Sprintf("add %s noise at %.1f dB SNR", source, config.Noise.SNR))
+	}
+	if config.Speed > 0 && config.Speed != 1.0 {
+		// The input is resampled as if recorded at a different rate
+		p.stages = append(p.stages, fmt.Sprintf("speed x%.2f, pitch included", config.Speed))
+		inputRate = int(math.Round(float64(inputRate) * config.Speed))
+	}
+	if config.HighPassCutoff > 0 {
+		p.stages = append(p.stages, fmt.Sprintf("high-pass %.0f Hz", config.HighPassCutoff))
+	}
+	if config.LowPassCutoff > 0 {
+		p.stages = append(p.stages, fmt.Sprintf("low-pass %.0f Hz", config.LowPassCutoff))
+	}
+	if inputRate != 8000 {
+		p.stages = append(p.stages,
+			fmt.Sprintf("anti-aliasing type %d, cutoff ratio %.2f", config.AntiAliasingType, config.AntiAliasingCutoffRatio),
+			fmt.Sprintf("resample %d->8000 Hz (method %d, window size %d)", inputRate, config.ResampleMethod, config.ResamplingWindowSize),
+		)
+		samples = (samples*8000 + inputRate - 1) / inputRate
+	}
+	if config.AutoTrim != nil {
+		// The length depends on the speech found, so the estimate stays an upper bound
+		p.stages = append(p.stages, fmt.Sprintf("trim to detected speech with %v pre-roll, %v post-roll", config.AutoTrim.PreRoll, config.AutoTrim.PostRoll))
+	}
+	if config.Tempo > 0 && config.Tempo != 1.0 {
+		p.stages = append(p.stages, fmt.Sprintf("tempo x%.2f", config.Tempo))
+		samples = int(math.Round(float64(samples) / config.Tempo))
+	}
+	if config.Reverse {
+		p.stages = append(p.stages, "reverse")
+	}
+	if config.CompressionRatio > 1.0 {
+		p.stages = append(p.stages, fmt.Sprintf("compression %.1f:1 above %.2f", config.CompressionRatio, config.CompressionThreshold))
+	}
+	if config.TargetLoudness != 0 {
+		p.stages = append(p.stages, fmt.Sprintf("loudness %.1f LUFS, peaks limited to -1 dBFS", config.TargetLoudness))
+	} else if config.NormalizePeak > 0 {
+		p.stages = append(p.stages, fmt.Sprintf("normalize to %.2f", config.NormalizePeak))
+	}
+	if config.FadeInMs > 0 || config.FadeOutMs > 0 {
+		p.stages = append(p.stages, fmt.Sprintf("fade in %.0f ms, out %.0f ms", config.FadeInMs, config.FadeOutMs))
+	}
+	if config.PadStart > 0 || config.PadEnd > 0 {
+		p.stages = append(p.stages, fmt.Sprintf("pad %v of silence before, %v after", config.PadStart, config.PadEnd))
+		samples += int(math.Round(config.PadStart.Seconds()*8000)) + int(math.Round(config.PadEnd.Seconds()*8000))
+	}
+	for _, beep := range config.Beeps {
+		stage := fmt.Sprintf("beep %.0f Hz at %.1f dBov for %v at %v", beep.Frequency, beep.LevelDbov, beep.Duration, beep.At)
+		if beep.Repeat > 0 {
+			stage += fmt.Sprintf(", every %v", beep.Repeat)
+		}
+		p.stages = append(p.stages, stage)
+	}
+	if config.FrameAlign > 1 {
+		p.stages = append(p.stages, fmt.Sprintf("pad to a multiple of %d bytes", config.FrameAlign))
+		if rest := samples % config.FrameAlign; rest > 0 {
+			samples += config.FrameAlign - rest
+		}
+	}
+	if c.mode == "wav2alaw" {
+		p.stages = append(p.stages, "A-law encode")
+	} else {
+		p.stages = append(p.stages, "u-law encode")
+	}
+	samples = c.loopStage(p, samples)
+
+	// One byte per u-law sample
+	p.outputSamples = samples
+	p.outputBytes = samples
+	p.outputDuration = samplesDuration(samples, 8000)
+	return p, nil
+}
+
+// planASR predicts an asr mode conversion
+func (c *conversion) planASR(data []byte) (*conversionPlan, error) {
+	p := &conversionPlan{}
+	var err error
+	if format, _ := wav2ulaw.DetectFormat(data); format == wav2ulaw.FormatWAV {
+		if p.stats, err = wav2ulaw.AnalyzeWav(data); err != nil {
+			return nil, withExitCode(exitDecode, err)
+		}
+		p.inputFormat = fmt.Sprintf("WAV %d Hz, %d-bit, %d channel(s)", p.stats.SampleRate, p.stats.BitDepth, p.stats.Channels)
+		if p.stats.Channels > 1 {
+			p.stages = append(p.stages, fmt.Sprintf("mix %d channels to mono", p.stats.Channels))
+		}
+	} else {
+		if p.stats, err = wav2ulaw.AnalyzeUlaw(data); err != nil {
+			return nil, withExitCode(exitDecode, err)
+		}
+		p.inputFormat = "u-law"
+		p.stages = append(p.stages, "u-law decode")
+	}
+
+	inputRate, outputRate := p.stats.SampleRate, c.asr.SampleRate
+	p.outputSamples = p.stats.Frames
+	if inputRate != outputRate {
+		p.stages = append(p.stages, fmt.Sprintf("resample %d->%d Hz", inputRate, outputRate))
+		p.outputSamples = (p.outputSamples*outputRate + inputRate - 1) / inputRate
+	}
+	if c.asr.NoiseGateDbov != 0 {
+		p.stages = append(p.stages, fmt.Sprintf("noise gate below %.1f dBov", c.asr.NoiseGateDbov))
+	}
+	if c.asr.HighShelfGainDb != 0 {
+		p.stages = append(p.stages, fmt.Sprintf("high shelf %+.1f dB above 2000 Hz", c.asr.HighShelfGainDb))
+	}
+	if c.asr.AGCTargetDbov != 0 {
+		p.stages = append(p.stages, fmt.Sprintf("agc to %.1f dBov, at most %.1f dB", c.asr.AGCTargetDbov, c.asr.AGCMaxGainDb))
+	}
+	if c.asr.PreEmphasis > 0 {
+		p.stages = append(p.stages, fmt.Sprintf("pre-emphasis %.2f", c.asr.PreEmphasis))
+	}
+	if c.asr.LoudnessTarget < 0 {
+		p.stages = append(p.stages, fmt.Sprintf("loudness %.1f LUFS", c.asr.LoudnessTarget))
+	}
+	p.outputBytes = wavHeaderSize + 2*p.outputSamples
+	p.outputDuration = samplesDuration(p.outputSamples, outputRate)
+	return p, nil
+}
+
+// reprocessStages adds the level stages of ulaw2ulaw reprocessing to the plan
+func (c *conversion) reprocessStages(p *conversionPlan) {
+	options := c.reprocess
+	if options.NoiseGateDbov != 0 {
+		p.stages = append(p.stages, fmt.Sprintf("noise gate below %.1f dBov", options.NoiseGateDbov))
+	}
+	if options.AGCTargetDbov != 0 {
+		p.stages = append(p.stages, fmt.Sprintf("agc to %.1f dBov, at most %.1f dB", options.AGCTargetDbov, options.AGCMaxGainDb))
+	}
+	if options.NormalizePeak > 0 {
+		p.stages = append(p.stages, fmt.Sprintf("normalize to %.2f", options.NormalizePeak))
+	}
+	p.stages = append(p.stages, "u-law encode")
+}
+
+// loopStage adds the loop stage to the plan when the audio is repeated and
+// returns the number of u-law samples after it
+func (c *conversion) loopStage(p *conversionPlan, samples int) int {
+	if !c.loop.enabled() {
+		return samples
+	}
+	looped := wav2ulaw.LoopedLength(samples, c.loop.count, c.loop.minDuration, c.loop.crossfadeMs)
+	p.stages = append(p.stages, fmt.Sprintf("loop to %v, crossfade %.0f ms", samplesDuration(looped, 8000), c.loop.crossfadeMs))
+	return looped
+}
+
+// outputSize predicts the size in bytes of converting data from its header
+// alone, without decoding the audio, so servers can refuse a conversion
+// whose output would exceed their limit before building it. The result is
+// exact or, with settings depending on the audio, an upper bound.
+func (c *conversion) outputSize(data []byte) (int64, error) {
+	wav := len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WAVE"
+	if c.preset == "" && c.mode == "asr" {
+		seconds := wav2ulaw.UlawDuration(len(data)).Seconds()
+		if wav {
+			estimate, err := wav2ulaw.EstimateWav(bytes.NewReader(data), nil)
+			if err != nil {
+				return 0, err
+			}
+			seconds = estimate.InputDuration.Seconds()
+		}
+		return wavHeaderSize + 2*int64(math.Ceil(seconds*float64(c.asr.SampleRate))), nil
+	}
+	if c.preset == "" && (c.mode == "ulaw2wav" || c.mode == "ulaw2ulaw" || c.mode == "alaw2wav" || c.mode == "ulaw2alaw" || c.mode == "alaw2ulaw") {
+		samples := int64(len(data))
+		if c.mode == "ulaw2wav" || c.mode == "ulaw2ulaw" {
+			samples = c.loopedLength(samples)
+		}
+		if !strings.HasSuffix(c.mode, "2wav") {
+			return samples, nil
+		}
+		if c.samples > 0 {
+			samples = int64(c.samples)
+		} else {
+			samples = (samples*int64(c.sampleRate) + 7999) / 8000
+		}
+		if c.bitDepth == 8 {
+			return wavHeaderSize + samples, nil
+		}
+		return wavHeaderSize + 2*samples, nil
+	}
+
+	if c.preset == "telephone-fx" {
+		// 16-bit output of at most the input's samples
+		return wavHeaderSize + 2*int64(len(data)), nil
+	}
+	if !wav && c.ffmpegCompat {
+		// Raw 16-bit PCM at the -sample-rate
+		data = append(streamWavHeader(int(c.sampleRate)), data...)
+	}
+	estimate, err := wav2ulaw.EstimateWav(bytes.NewReader(data), c.config)
+	if err != nil {
+		return 0, err
+	}
+	if c.mode == "wav2alaw" {
+		return estimate.OutputBytes, nil
+	}
+	return c.loopedLength(estimate.OutputBytes), nil
+}
+
+// loopedLength returns the length of n u-law samples after the loop stage
+func (c *conversion) loopedLength(n int64) int64 {
+	if !c.loop.enabled() {
+		return n
+	}
+	return int64(wav2ulaw.LoopedLength(int(n), c.loop.count, c.loop.minDuration, c.loop.crossfadeMs))
+}
+
+// dryRun logs the plan for converting inputPath to outputPath
+func (c *conversion) dryRun(inputPath, outputPath string) error {
+	p, err := c.plan(inputPath)
+	if err != nil {
+		return err
+	}
+	args := []any{
+		"input", inputPath,
+		"output", outputPath,
+		"input_format", p.inputFormat,
+		"input_duration", p.stats.Duration,
+		"stages", p.stages,
+		"output_bytes", p.outputBytes,
+		"output_duration", p.outputDuration,
+	}
+	if p.stats.ClippedSamples > 0 {
+		args = append(args, "clipped_samples", p.stats.ClippedSamples)
+	}
+	c.logger.Info("dry run", args...)
+	return nil
+}
+
+// samplesDuration returns the playing time of n samples at sampleRate
+func samplesDuration(n, sampleRate int) time.Duration {
+	if sampleRate <= 0 {
+		return 0
+	}
+	return time.Duration(float64(n) / float64(sampleRate) * float64(time.Second))
+}
@@ -0,0 +1,418 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"wav2ulaw"
+)
+
+// soxEffect maps a sox effect onto the processing flags
+type soxEffect struct {
+	// Position of the effect's stage in the fixed processing chain
+	stage int
+	apply func(e *effectChain, args []string) error
+}
+
+// Stages of the processing chain in the order they run
+const (
+	stageMono = iota
+	stageSpeed
+	stageHighPass
+	stageLowPass
+	stageRate
+	stageTempo
+	stageReverse
+	stageCompress
+	stageNormalize
+	stageFade
+	stageNone
+)
+
+// soxEffects lists the sox effects accepted after the input and output files
+var soxEffects = map[string]soxEffect{
+	"channels": {stageMono, applyChannels},
+	"remix":    {stageMono, applyRemix},
+	"highpass": {stageHighPass, func(e *effectChain, args []string) error { return e.setFrequency("high-pass", args) }},
+	"lowpass":  {stageLowPass, func(e *effectChain, args []string) error { return e.setFrequency("low-pass", args) }},
+	"sinc":     {stageHighPass, applySinc},
+	"speed":    {stageSpeed, applySpeed},
+	"rate":     {stageRate, applyRate},
+	"tempo":    {stageTempo, applyTempo},
+	"reverse":  {stageReverse, applyReverse},
+	"compand":  {stageCompress, applyCompand},
+	"norm":     {stageNormalize, applyNorm},
+	"fade":     {stageFade, applyFade},
+	"dither":   {stageNone, func(e *effectChain, args []string) error { return nil }},
+}
+
+// Flags that an effect chain turns off unless an effect or flag sets them,
+// since sox only runs the effects it is given
+var effectStageFlags = map[string]string{
+	"high-pass":      "0",
+	"low-pass":       "0",
+	"normalize":      "0",
+	"compress-ratio": "1",
+}
+
+// effectChain applies sox effects to the processing flags
+type effectChain struct {
+	flags  *conversionFlags
+	logger *slog.Logger
+	// Flags set by the chain, to catch effects given twice
+	set  map[string]string
+	mono bool
+}
+
+// applyEffects sets the processing flags described by a sox effect chain
+// such as "highpass 300 lowpass 3400 norm -1". The effects run in the fixed
+// order of the processing chain whatever their order on the command line.
+// It reports whether the chain mixes the input down to mono.
+func applyEffects(f *conversionFlags, tokens []string, logger *slog.Logger) (bool, error) {
+	if len(tokens) == 0 {
+		return false, nil
+	}
+	e := &effectChain{flags: f, logger: logger, set: map[string]string{}}
+	lastStage := -1
+	for len(tokens) > 0 {
+		name := tokens[0]
+		effect, ok := soxEffects[name]
+		if !ok {
+			return false, fmt.Errorf("unsupported effect '%s', supported effects are %s", name, strings.Join(soxEffectNames(), ", "))
+		}
+		end := 1
+		for end < len(tokens) {
+			if _, ok := soxEffects[tokens[end]]; ok {
+				break
+			}
+			end++
+		}
+		if err := effect.apply(e, tokens[1:end]); err != nil {
+			return false, fmt.Errorf("%s: %v", name, err)
+		}
+		if effect.stage != stageNone {
+			if effect.stage < lastStage {
+				logger.Warn("effect runs in processing chain order", "effect", name)
+			}
+			lastStage = max(lastStage, effect.stage)
+		}
+		tokens = tokens[end:]
+	}
+
+	if *f.preset == "" {
+		for name, off := range effectStageFlags {
+			if _, ok := e.set[name]; !ok && !f.isSet(name) {
+				f.fs.Set(name, off)
+			}
+		}
+	}
+	return e.mono, nil
+}
+
+// setFlag sets a processing flag for an effect, refusing to override a flag
+// given on the command line or set by an earlier effect
+func (e *effectChain) setFlag(name, value string) error {
+	if previous, ok := e.set[name]; ok {
+		return fmt.Errorf("-%s is already set to %s by an earlier effect", name, previous)
+	}
+	if e.flags.isSet(name) {
+		return fmt.Errorf("-%s is also given as a flag", name)
+	}
+	if err := e.flags.fs.Set(name, value); err != nil {
+		return err
+	}
+	e.set[name] = value
+	return nil
+}
+
+// setFrequency handles highpass and lowpass: [-1|-2] frequency [width]
+func (e *effectChain) setFrequency(flagName string, args []string) error {
+	if len(args) > 0 && (args[0] == "-1" || args[0] == "-2") {
+		args = args[1:]
+	}
+	if len(args) == 0 || len(args) > 2 {
+		return fmt.Errorf("expected [-1|-2] frequency [width]")
+	}
+	freq, err := parseSoxFrequency(args[0])
+	if err != nil {
+		return err
+	}
+	if len(args) == 2 {
+		e.logger.Warn("filter width is not supported and ignored", "flag", flagName, "width", args[1])
+	}
+	return e.setFlag(flagName, strconv.FormatFloat(freq, 'f', -1, 64))
+}
+
+// applySinc handles sinc [-a att] [-n taps] low-high, low, or -high
+func applySinc(e *effectChain, args []string) error {
+	for len(args) > 1 && strings.HasPrefix(args[0], "-") && len(args[0]) == 2 {
+		e.logger.Warn("sinc option is not supported and ignored", "option", args[0])
+		args = args[2:]
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("expected a frequency range such as 300-3400")
+	}
+	low, high, _ := strings.Cut(args[0], "-")
+	if low != "" {
+		freq, err := parseSoxFrequency(low)
+		if err != nil {
+			return err
+		}
+		if err := e.setFlag("high-pass", strconv.FormatFloat(freq, 'f', -1, 64)); err != nil {
+			return err
+		}
+	}
+	if high != "" {
+		freq, err := parseSoxFrequency(high)
+		if err != nil {
+			return err
+		}
+		if err := e.setFlag("low-pass", strconv.FormatFloat(freq, 'f', -1, 64)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyChannels handles channels 1
+func applyChannels(e *effectChain, args []string) error {
+	if len(args) != 1 || args[0] != "1" {
+		return fmt.Errorf("only mixing down to one channel is supported")
+	}
+	e.mono = true
+	return nil
+}
+
+// applyRemix handles remix -, which mixes every channel into one
+func applyRemix(e *effectChain, args []string) error {
+	if len(args) != 1 || args[0] != "-" {
+		return fmt.Errorf("only remix - (mix down to one channel) is supported")
+	}
+	e.mono = true
+	return nil
+}
+
+// applyRate handles rate [options] 8000; the output is always 8 kHz
+func applyRate(e *effectChain, args []string) error {
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	rate, err := parseSoxFrequency(args[0])
+	if err != nil || rate != 8000 {
+		return fmt.Errorf("u-law output is always 8000 Hz, got %s", args[0])
+	}
+	return nil
+}
+
+// applyTempo handles tempo [-q|-m|-s|-l] factor
+func applyTempo(e *effectChain, args []string) error {
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("expected a tempo factor")
+	}
+	if len(args) > 1 {
+		e.logger.Warn("tempo segment, search and overlap are not supported and ignored")
+	}
+	if _, err := strconv.ParseFloat(args[0], 64); err != nil {
+		return fmt.Errorf("invalid factor '%s'", args[0])
+	}
+	return e.setFlag("tempo", args[0])
+}
+
+// applySpeed handles speed factor[c], the factor being in cents with the c
+func applySpeed(e *effectChain, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected a speed factor")
+	}
+	cents, inCents := strings.CutSuffix(args[0], "c")
+	factor, err := strconv.ParseFloat(cents, 64)
+	if err != nil {
+		return fmt.Errorf("invalid factor '%s'", args[0])
+	}
+	if inCents {
+		factor = math.Pow(2, factor/1200)
+	}
+	return e.setFlag("speed", strconv.FormatFloat(factor, 'f', -1, 64))
+}
+
+// applyReverse handles reverse, which takes no arguments
+func applyReverse(e *effectChain, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("expected no arguments")
+	}
+	return e.setFlag("reverse", "true")
+}
+
+// applyNorm handles norm [dB-level]
+func applyNorm(e *effectChain, args []string) error {
+	level := 0.0
+	if len(args) > 0 && strings.HasPrefix(args[0], "-") && len(args[0]) == 2 && !isNumber(args[0]) {
+		// -b, -i and -h balance channels or guard against clipping
+		e.logger.Warn("norm option is not supported and ignored", "option", args[0])
+		args = args[1:]
+	}
+	if len(args) > 1 {
+		return fmt.Errorf("expected [dB-level]")
+	}
+	if len(args) == 1 {
+		var err error
+		if level, err = strconv.ParseFloat(args[0], 64); err != nil || level > 0 {
+			return fmt.Errorf("invalid level '%s', must be 0 dB or below", args[0])
+		}
+	}
+	return e.setFlag("normalize", strconv.FormatFloat(math.Pow(10, level/20), 'f', 4, 64))
+}
+
+// applyFade handles fade [type] fade-in [stop [fade-out]]. The fade-out
+// must end at the end of the audio (stop 0 or -0).
+func applyFade(e *effectChain, args []string) error {
+	shape := ""
+	if len(args) > 0 && len(args[0]) == 1 && strings.Contains("qhtlp", args[0]) {
+		shape = args[0]
+		args = args[1:]
+	}
+	if len(args) == 0 || len(args) > 3 {
+		return fmt.Errorf("expected [type] fade-in-length [stop-position [fade-out-length]]")
+	}
+	fadeIn, err := parseSoxTime(args[0])
+	if err != nil {
+		return err
+	}
+	fadeOut := 0.0
+	if len(args) >= 2 {
+		if args[1] != "0" && args[1] != "-0" {
+			return fmt.Errorf("stop position %s is not supported, fades end at the end of the audio (use -0)", args[1])
+		}
+		// sox fades out as long as it fades in unless told otherwise
+		fadeOut = fadeIn
+		if len(args) == 3 {
+			if fadeOut, err = parseSoxTime(args[2]); err != nil {
+				return err
+			}
+		}
+	}
+	if err := e.setFlag("fade-in", strconv.FormatFloat(fadeIn*1000, 'f', -1, 64)); err != nil {
+		return err
+	}
+	if err := e.setFlag("fade-out", strconv.FormatFloat(fadeOut*1000, 'f', -1, 64)); err != nil {
+		return err
+	}
+	switch shape {
+	case "":
+	case "t", "l":
+		return e.setFlag("fade-shape", strconv.Itoa(int(wav2ulaw.FadeLinear)))
+	default:
+		// Quarter and half sine, and parabola, are closest to the cosine ramp
+		return e.setFlag("fade-shape", strconv.Itoa(int(wav2ulaw.FadeCosine)))
+	}
+	return nil
+}
+
+// applyCompand handles compand attack,decay [soft-knee:]in-dB1[,out-dB1]{,in-dB,out-dB} [gain ...].
+// The compressor works on instantaneous levels with one threshold and
+// ratio, so the transfer function is approximated by the point where it
+// leaves unity gain and its average slope above it.
+func applyCompand(e *effectChain, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("expected attack,decay and a transfer function")
+	}
+	if len(args) > 2 {
+		e.logger.Warn("compand gain, initial volume and delay are not supported and ignored")
+	}
+	e.logger.Debug("compand attack and decay times are ignored", "times", args[0])
+
+	transfer := args[1]
+	if _, rest, ok := strings.Cut(transfer, ":"); ok {
+		transfer = rest
+	}
+	var values []float64
+	for _, field := range strings.Split(transfer, ",") {
+		v, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return fmt.Errorf("invalid transfer function '%s'", args[1])
+		}
+		values = append(values, v)
+	}
+	// A lone first value is a point on the unity line
+	if len(values)%2 == 1 {
+		values = append([]float64{values[0]}, values...)
+	}
+	type point struct{ in, out float64 }
+	points := make([]point, 0, len(values)/2)
+	for i := 0; i < len(values); i += 2 {
+		points = append(points, point{values[i], values[i+1]})
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].in < points[j].in })
+
+	knee := -1
+	for i, p := range points {
+		if math.Abs(p.out-p.in) > 0.5 {
+			break
+		}
+		knee = i
+	}
+	if knee < 0 || knee == len(points)-1 {
+		return fmt.Errorf("transfer function must start at unity gain and compress above a threshold")
+	}
+	threshold, last := points[knee], points[len(points)-1]
+	slope := (last.out - threshold.out) / (last.in - threshold.in)
+	if slope <= 0 || slope >= 1 {
+		return fmt.Errorf("only downward compression (a slope between 0 and 1 above the threshold) is supported")
+	}
+	ratio := 1 / slope
+	e.logger.Info("compand approximated", "threshold_db", threshold.in, "ratio", math.Round(ratio*100)/100)
+	if err := e.setFlag("compress-threshold", strconv.FormatFloat(math.Pow(10, threshold.in/20), 'f', 4, 64)); err != nil {
+		return err
+	}
+	return e.setFlag("compress-ratio", strconv.FormatFloat(ratio, 'f', 4, 64))
+}
+
+// parseSoxFrequency parses a frequency in Hz, with an optional k suffix
+func parseSoxFrequency(s string) (float64, error) {
+	scale := 1.0
+	if trimmed, ok := strings.CutSuffix(s, "k"); ok {
+		s, scale = trimmed, 1000
+	}
+	freq, err := strconv.ParseFloat(s, 64)
+	if err != nil || freq <= 0 {
+		return 0, fmt.Errorf("invalid frequency '%s'", s)
+	}
+	return freq * scale, nil
+}
+
+// parseSoxTime parses a time in seconds, optionally as [[hh:]mm:]ss[.frac]
+func parseSoxTime(s string) (float64, error) {
+	seconds := 0.0
+	for _, field := range strings.Split(s, ":") {
+		v, err := strconv.ParseFloat(field, 64)
+		if err != nil || v < 0 {
+			return 0, fmt.Errorf("invalid time '%s' (sample counts are not supported)", s)
+		}
+		seconds = seconds*60 + v
+	}
+	return seconds, nil
+}
+
+// isNumber reports whether s parses as a number
+func isNumber(s string) bool {
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
+
+// soxEffectNames returns the supported effect names, sorted
+func soxEffectNames() []string {
+	names := make([]string, 0, len(soxEffects))
+	for name := range soxEffects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// envPrefix starts the environment variable behind every flag
+const envPrefix = "WAV2ULAW_"
+
+// envName returns the environment variable setting a flag, e.g.
+// WAV2ULAW_LOW_PASS for -low-pass
+func envName(flag string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+// applyEnv sets each flag of fs that has a non-empty environment variable,
+// read through lookup. It runs before the command line is parsed, so flags
+// on the command line override the environment, while flags set from it
+// count as given and take precedence over -config files and presets.
+func applyEnv(fs *flag.FlagSet, lookup func(string) (string, bool)) error {
+	var err error
+	fs.VisitAll(func(fl *flag.Flag) {
+		name := envName(fl.Name)
+		if value, ok := lookup(name); ok && value != "" && err == nil {
+			if setErr := fs.Set(fl.Name, value); setErr != nil {
+				err = fmt.Errorf("invalid value for %s: %v", name, setErr)
+			}
+		}
+	})
+	return err
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"wav2ulaw"
+)
+
+// Exit codes, distinct per failure class so wrappers can branch on them
+const (
+	exitFailure = 1 // Any failure not covered below
+	exitUsage   = 2 // Invalid arguments or settings, as the flag package uses
+	exitInput   = 3 // Input could not be read
+	exitFormat  = 4 // Input is not in a supported format
+	exitDecode  = 5 // Input is malformed or could not be converted
+	exitWrite   = 6 // Output could not be written
+	exitVerify  = 7 // Output failed -verify-telephony
+)
+
+// exitError is an error classified by the exit code it should produce
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+
+func (e *exitError) Unwrap() error { return e.err }
+
+// withExitCode classifies err, keeping a classification it already has
+func withExitCode(code int, err error) error {
+	var classified *exitError
+	if err == nil || errors.As(err, &classified) {
+		return err
+	}
+	return &exitError{code: code, err: err}
+}
+
+// exitCode returns the exit code for err, exitFailure when unclassified
+func exitCode(err error) int {
+	var classified *exitError
+	if errors.As(err, &classified) {
+		return classified.code
+	}
+	return exitFailure
+}
+
+// mergeExitCode combines the codes of several failures: a shared class is
+// kept, mixed classes become exitFailure
+func mergeExitCode(code, next int) int {
+	if code == 0 || code == next {
+		return next
+	}
+	return exitFailure
+}
+
+// conversionExitCode is the exit code of a failed conversion: exitUsage for
+// settings the library rejected, exitDecode otherwise
+func conversionExitCode(err error) int {
+	var configErr *wav2ulaw.ConfigError
+	if errors.As(err, &configErr) {
+		return exitUsage
+	}
+	return exitDecode
+}
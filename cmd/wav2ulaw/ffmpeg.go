@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"wav2ulaw"
+)
+
+// convertFFmpeg converts data with the raw formats of ffmpeg pipes: u-law
+// output is headerless as for -f mulaw, and the PCM side is either WAV,
+// possibly with the unknown sizes ffmpeg writes to a pipe, or headerless
+// 16-bit little-endian mono PCM as for -f s16le at -sample-rate
+func (c *conversion) convertFFmpeg(inputData []byte) ([]byte, error) {
+	if c.mode == "ulaw2wav" {
+		wav, err := wav2ulaw.ConvertUlawBytesToWavLength(c.standardUlaw(c.repeat(inputData)), c.sampleRate, c.windowSize, c.samples)
+		if err != nil {
+			return nil, withExitCode(exitDecode, fmt.Errorf("error converting u-law to PCM: %v", err))
+		}
+		_, _, pcm, err := parseStreamHeader(wav)
+		if err != nil {
+			return nil, withExitCode(exitDecode, err)
+		}
+		return pcm, nil
+	}
+
+	var output []byte
+	var err error
+	if len(inputData) >= 12 && string(inputData[0:4]) == "RIFF" && string(inputData[8:12]) == "WAVE" {
+		output, err = wav2ulaw.ConvertWavBytesToUlaw(fixStreamedWavSizes(inputData), c.config)
+	} else {
+		if len(inputData)%2 != 0 {
+			return nil, withExitCode(exitFormat, fmt.Errorf("unsupported input: odd number of bytes for 16-bit PCM"))
+		}
+		samples := make([]int16, len(inputData)/2)
+		for i := range samples {
+			samples[i] = int16(binary.LittleEndian.Uint16(inputData[2*i:]))
+		}
+		output, err = wav2ulaw.ConvertPCM16ToUlaw(samples, int(c.sampleRate), c.config)
+	}
+	if err != nil {
+		return nil, withExitCode(exitDecode, fmt.Errorf("error converting PCM to u-law: %v", err))
+	}
+	return c.repeat(output), nil
+}
+
+// fixStreamedWavSizes sets the RIFF and data chunk sizes of a WAV file
+// written to a pipe, which its writer could not seek back to fill in, to
+// the data actually present. Other files are returned unchanged.
+func fixStreamedWavSizes(data []byte) []byte {
+	for pos := 12; pos+8 <= len(data); {
+		size := int64(binary.LittleEndian.Uint32(data[pos+4:]))
+		remaining := int64(len(data) - pos - 8)
+		if string(data[pos:pos+4]) == "data" {
+			if size == 0 || size > remaining {
+				binary.LittleEndian.PutUint32(data[pos+4:], uint32(remaining))
+				binary.LittleEndian.PutUint32(data[4:], uint32(len(data)-8))
+			}
+			break
+		}
+		pos += 8 + int(size+size%2)
+	}
+	return data
+}
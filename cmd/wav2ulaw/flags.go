@@ -0,0 +1,542 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"wav2ulaw"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Library configurations selectable with -preset. Flags given on the
+// command line or in a config file override individual settings.
+var configPresets = map[string]func() *wav2ulaw.AudioConfig{
+	"telephony":      wav2ulaw.TelephonyConfig,
+	"voicemail":      wav2ulaw.VoicemailConfig,
+	"tts-narrowband": wav2ulaw.TTSNarrowbandConfig,
+	"tts-fast":       wav2ulaw.TTSFastConfig,
+	"raw":            wav2ulaw.RawPassthroughConfig,
+	"prompt-library": wav2ulaw.ConfigForPromptLibrary,
+}
+
+// asrPresets are the -preset values that select asr mode with their options
+var asrPresets = map[string]func() *wav2ulaw.ASROptions{
+	"asr-cleanup": wav2ulaw.ASRCleanupOptions,
+}
+
+// asrPresetFields reads the ASROptions field behind each asr mode flag
+var asrPresetFields = map[string]func(o *wav2ulaw.ASROptions) any{
+	"sample-rate":  func(o *wav2ulaw.ASROptions) any { return o.SampleRate },
+	"pre-emphasis": func(o *wav2ulaw.ASROptions) any { return o.PreEmphasis },
+	"loudness":     func(o *wav2ulaw.ASROptions) any { return o.LoudnessTarget },
+	"high-shelf":   func(o *wav2ulaw.ASROptions) any { return o.HighShelfGainDb },
+	"gate":         func(o *wav2ulaw.ASROptions) any { return o.NoiseGateDbov },
+	"agc":          func(o *wav2ulaw.ASROptions) any { return o.AGCTargetDbov },
+	"agc-max-gain": func(o *wav2ulaw.ASROptions) any { return o.AGCMaxGainDb },
+}
+
+// presetFields reads the AudioConfig field behind each processing flag
+var presetFields = map[string]func(c *wav2ulaw.AudioConfig) any{
+	"low-pass":            func(c *wav2ulaw.AudioConfig) any { return c.LowPassCutoff },
+	"high-pass":           func(c *wav2ulaw.AudioConfig) any { return c.HighPassCutoff },
+	"normalize":           func(c *wav2ulaw.AudioConfig) any { return c.NormalizePeak },
+	"target-loudness":     func(c *wav2ulaw.AudioConfig) any { return c.TargetLoudness },
+	"tempo":               func(c *wav2ulaw.AudioConfig) any { return c.Tempo },
+	"compress-ratio":      func(c *wav2ulaw.AudioConfig) any { return c.CompressionRatio },
+	"compress-threshold":  func(c *wav2ulaw.AudioConfig) any { return c.CompressionThreshold },
+	"fade-in":             func(c *wav2ulaw.AudioConfig) any { return c.FadeInMs },
+	"fade-out":            func(c *wav2ulaw.AudioConfig) any { return c.FadeOutMs },
+	"fade-shape":          func(c *wav2ulaw.AudioConfig) any { return int(c.FadeShape) },
+	"window-size":         func(c *wav2ulaw.AudioConfig) any { return c.ResamplingWindowSize },
+	"resample-method":     func(c *wav2ulaw.AudioConfig) any { return int(c.ResampleMethod) },
+	"window":              func(c *wav2ulaw.AudioConfig) any { return int(c.WindowFunction) },
+	"kaiser-beta":         func(c *wav2ulaw.AudioConfig) any { return c.KaiserBeta },
+	"anti-aliasing-ratio": func(c *wav2ulaw.AudioConfig) any { return c.AntiAliasingCutoffRatio },
+	"anti-aliasing-type":  func(c *wav2ulaw.AudioConfig) any { return int(c.AntiAliasingType) },
+	"filter-order":        func(c *wav2ulaw.AudioConfig) any { return c.FilterOrder },
+	"chebyshev-ripple":    func(c *wav2ulaw.AudioConfig) any { return c.ChebyshevRipple },
+}
+
+// conversionFlags are the processing flags shared by every command that
+// converts files
+type conversionFlags struct {
+	fs *flag.FlagSet
+	// Names of the processing flags, which are also the config file keys
+	names      []string
+	configFile *string
+
+	mode              *string
+	preset            *string
+	sampleRate        *uint
+	samples           *int
+	bitDepth          *int
+	dither            *bool
+	lowPass           *float64
+	highPass          *float64
+	normalize         *float64
+	targetLoudness    *float64
+	agc               *float64
+	agcMaxGain        *float64
+	gate              *float64
+	tempo             *float64
+	speed             *float64
+	reverse           *bool
+	compressRatio     *float64
+	compressThreshold *float64
+	levelCompensation *bool
+	fadeIn            *float64
+	fadeOut           *float64
+	fadeShape         *int
+	autoTrim          *bool
+	trimPreRoll       *time.Duration
+	trimPostRoll      *time.Duration
+	padStart          *time.Duration
+	padEnd            *time.Duration
+	frameAlign        *int
+	loop              *int
+	minDuration       *time.Duration
+	loopCrossfade     *float64
+	windowSize        *int
+	resampleMethod    *int
+	resampleTradeoff  *string
+	windowFunction    *int
+	kaiserBeta        *float64
+	antiAliasingRatio *float64
+	antiAliasingType  *int
+	filterOrder       *int
+	concurrency       *int
+	deterministic     *bool
+	warnClipping      *bool
+	lenient           *bool
+	sanityCheck       *bool
+	ulawVariant       *string
+	noise             *string
+	noiseSNR          *float64
+	noiseSeed         *int64
+	chebyshevRipple   *float64
+	preEmphasis       *float64
+	loudness          *float64
+	highShelf         *float64
+	beepAt            *string
+	beepEvery         *time.Duration
+	beepFrequency     *float64
+	beepLevel         *float64
+	beepDuration      *time.Duration
+}
+
+// registerConversionFlags defines the processing flags on fs, plus -config
+// to read them from a file
+func registerConversionFlags(fs *flag.FlagSet) *conversionFlags {
+	existing := make(map[string]bool)
+	fs.VisitAll(func(fl *flag.Flag) { existing[fl.Name] = true })
+
+	f := &conversionFlags{
+		mode:              fs.String("mode", "wav2ulaw", "Conversion mode: wav2ulaw, ulaw2wav, ulaw2ulaw to fix the levels of u-law files, asr for 16 kHz mono PCM for speech recognition, wav2alaw and alaw2wav for G.711 A-law, or ulaw2alaw and alaw2ulaw to transcode (default: inferred from file extensions)"),
+		preset:            fs.String("preset", "", "Processing preset: telephony, voicemail, tts-narrowband, tts-fast, raw, prompt-library (every prompt at -14 LUFS), telephone-fx (WAV to WAV telephone effect), or asr-cleanup (asr mode tuned for recognition of call audio)"),
+		sampleRate:        fs.Uint("sample-rate", 8000, "Sample rate for output WAV file (only for ulaw2wav and alaw2wav modes, and asr mode where it defaults to 16000), and of raw PCM with -ffmpeg-compat"),
+		samples:           fs.Int("samples", 0, "Exact number of samples of the output WAV (only for ulaw2wav mode), e.g. the frame count of the original WAV to restore its duration (0 = as long as the u-law)"),
+		bitDepth:          fs.Int("bit-depth", 16, "Bits per sample of the output WAV (only for ulaw2wav mode): 16, or 8 for the unsigned 8-bit PCM of legacy paging hardware"),
+		dither:            fs.Bool("dither", false, "Add triangular dither when reducing ulaw2wav output to -bit-depth 8, so quiet passages become low hiss rather than distortion"),
+		lowPass:           fs.Float64("low-pass", 3400, "Low-pass filter cutoff frequency in Hz"),
+		highPass:          fs.Float64("high-pass", 300, "High-pass filter cutoff frequency in Hz"),
+		normalize:         fs.Float64("normalize", 0.9, "Normalize audio to this peak level (0.0 to 1.0); in ulaw2ulaw mode only when given"),
+		targetLoudness:    fs.Float64("target-loudness", 0, "Integrated loudness of the output in LUFS, e.g. -16, reached with a gain and a peak limiter in place of -normalize (-40 to -8, 0 = off)"),
+		agc:               fs.Float64("agc", 0, "Target speech level of automatic gain control in dBov, e.g. -20 (only for ulaw2ulaw and asr modes, 0 = off)"),
+		agcMaxGain:        fs.Float64("agc-max-gain", 20, "Largest boost or cut of -agc in dB"),
+		gate:              fs.Float64("gate", 0, "Noise gate threshold in dBov, e.g. -50: quieter stretches become digital silence (only for ulaw2ulaw and asr modes, 0 = off)"),
+		tempo:             fs.Float64("tempo", 1.0, "Tempo change without pitch shift (e.g. 1.05 = 5% faster)"),
+		reverse:           fs.Bool("reverse", false, "Play the audio backwards; fades, padding and beeps apply to the reversed audio"),
+		speed:             fs.Float64("speed", 1.0, "Playback speed change by resampling, with the pitch following (0.5 to 2.0); cheaper than -tempo"),
+		compressRatio:     fs.Float64("compress-ratio", 2.0, "Compression ratio (1.0 means no compression)"),
+		compressThreshold: fs.Float64("compress-threshold", 0.5, "Compression threshold (0.0 to 1.0)"),
+		levelCompensation: fs.Bool("level-compensation", false, "Undo the gain of the filters and resampler at 1 kHz so the output level matches the input (use with -normalize 0)"),
+		fadeIn:            fs.Float64("fade-in", 0, "Fade-in duration in milliseconds"),
+		fadeOut:           fs.Float64("fade-out", 0, "Fade-out duration in milliseconds"),
+		fadeShape:         fs.Int("fade-shape", int(wav2ulaw.FadeCosine), "Fade curve (0=Linear, 1=Cosine)"),
+		autoTrim:          fs.Bool("auto-trim", false, "Trim the output to the speech found by voice activity detection, dropping leading and trailing dead air"),
+		trimPreRoll:       fs.Duration("trim-pre-roll", 200*time.Millisecond, "Audio -auto-trim keeps before the first speech"),
+		trimPostRoll:      fs.Duration("trim-post-roll", 300*time.Millisecond, "Audio -auto-trim keeps after the last speech"),
+		padStart:          fs.Duration("pad-start", 0, "Digital silence inserted before the output, e.g. 250ms"),
+		padEnd:            fs.Duration("pad-end", 0, "Digital silence appended to the output, e.g. 500ms"),
+		frameAlign:        fs.Int("frame-align", 0, "Pad the end of the output with silence to a multiple of this many bytes, e.g. 160 for 20 ms packets (0 = off)"),
+		loop:              fs.Int("loop", 1, "Repeat the u-law audio this many times, e.g. for music on hold"),
+		minDuration:       fs.Duration("min-duration", 0, "Repeat the u-law audio until it lasts at least this long, e.g. 30s"),
+		loopCrossfade:     fs.Float64("loop-crossfade", 0, "Crossfade at each loop point in milliseconds (0 = hard cuts)"),
+		windowSize:        fs.Int("window-size", 16, "Resampling window size (larger = better quality but slower)"),
+		resampleMethod:    fs.Int("resample-method", int(wav2ulaw.ResampleSinc), "Resampling method (0=Sinc, 1=FFT)"),
+		resampleTradeoff:  fs.String("resample-tradeoff", "balanced", "Speed against accuracy of sinc resampling: fast (half the window), balanced, or best (exact sinc, many times slower)"),
+		windowFunction:    fs.Int("window", int(wav2ulaw.WindowBlackman), "Resampling window function (0=Blackman, 1=Kaiser, 2=Hann, 3=Hamming, 4=Blackman-Harris)"),
+		kaiserBeta:        fs.Float64("kaiser-beta", 8.6, "Kaiser window beta (higher = more stopband attenuation, wider transition)"),
+		antiAliasingRatio: fs.Float64("anti-aliasing-ratio", 0.9, "Anti-aliasing filter cutoff ratio (0.0 to 1.0)"),
+		antiAliasingType:  fs.Int("anti-aliasing-type", int(wav2ulaw.AAButterworth), "Anti-aliasing filter type (0=Simple, 1=Butterworth, 2=Bessel, 3=Chebyshev, 4=Windowed sinc, 5=Auto: FIR designed from the rate pair, ignoring -anti-aliasing-ratio and -filter-order)"),
+		filterOrder:       fs.Int("filter-order", 4, "Filter order for Butterworth/Bessel/Chebyshev, each order adding 6 dB/octave of rolloff (2-8)"),
+		concurrency:       fs.Int("concurrency", 1, "Workers used to process a single file (-1 = all CPUs)"),
+		deterministic:     fs.Bool("deterministic", false, "Guarantee byte-identical output for identical input and settings: convert twice, fail if the runs differ, and log the SHA-256 of the output"),
+		warnClipping:      fs.Bool("warn-clipping", false, "Print a warning for each clipped region in the input"),
+		ulawVariant:       fs.String("ulaw-variant", "standard", "u-law bit layout for legacy switches: standard, or a comma-separated list of zero-trap, invert and invert-even"),
+		lenient:           fs.Bool("lenient", false, "Clamp out-of-range processing settings with a warning instead of failing"),
+		sanityCheck:       fs.Bool("sanity-check", false, "Fail conversions whose output is silent, stuck at one level, mostly DC or far from the expected length"),
+		noise:             fs.String("noise", "", "Mix background noise into the input before filtering: a WAV or u-law file, or white or pink for generated noise"),
+		noiseSNR:          fs.Float64("noise-snr", 20, "Speech-to-noise ratio of -noise in dB"),
+		noiseSeed:         fs.Int64("noise-seed", 1, "Seed of generated -noise; the same seed gives the same noise"),
+		chebyshevRipple:   fs.Float64("chebyshev-ripple", 0.5, "Ripple in dB for Chebyshev filter (0.1-3.0)"),
+		preEmphasis:       fs.Float64("pre-emphasis", 0, "Pre-emphasis coefficient of asr mode output, e.g. 0.97 (0 = off)"),
+		loudness:          fs.Float64("loudness", 0, "Integrated loudness of asr mode output in LUFS, e.g. -23 (0 = unchanged)"),
+		highShelf:         fs.Float64("high-shelf", 0, "Boost above 2 kHz of asr mode output in dB, negative to cut (0 = off)"),
+		beepAt:            fs.String("beep-at", "", "Comma-separated positions in the u-law output to mix a beep in at, e.g. 0s,15s (padding included)"),
+		beepEvery:         fs.Duration("beep-every", 0, "Repeat each beep at this interval until the end of the output, e.g. 15s; without -beep-at the first beep is at 0s (0 = once)"),
+		beepFrequency:     fs.Float64("beep-frequency", 1400, "Frequency of the beeps in Hz"),
+		beepLevel:         fs.Float64("beep-level", -20, "RMS level of the beeps in dBov"),
+		beepDuration:      fs.Duration("beep-duration", 500*time.Millisecond, "Length of each beep"),
+	}
+	fs.VisitAll(func(fl *flag.Flag) {
+		if !existing[fl.Name] {
+			f.names = append(f.names, fl.Name)
+		}
+	})
+	f.fs = fs
+	f.configFile = fs.String("config", "", "JSON or YAML file of processing flags (keys are flag names); flags given on the command line or as WAV2ULAW_* variables take precedence")
+	return f
+}
+
+// applyConfigFile sets each flag named in the -config file that was not
+// given on the command line
+func (f *conversionFlags) applyConfigFile() error {
+	if *f.configFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(*f.configFile)
+	if err != nil {
+		return fmt.Errorf("error reading config file: %v", err)
+	}
+	return f.applyConfig(data, "config file")
+}
+
+// applyConfig sets each flag named in a JSON or YAML document that was not
+// already given; source names the document in errors
+func (f *conversionFlags) applyConfig(data []byte, source string) error {
+	// JSON is valid YAML, so one parser handles both formats
+	var values map[string]any
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("error parsing %s: %v", source, err)
+	}
+
+	known := make(map[string]bool)
+	for _, name := range f.names {
+		known[name] = true
+	}
+	explicit := make(map[string]bool)
+	f.fs.Visit(func(fl *flag.Flag) { explicit[fl.Name] = true })
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !known[key] {
+			return fmt.Errorf("unknown setting '%s' in %s", key, source)
+		}
+		if explicit[key] {
+			continue
+		}
+		switch value := values[key].(type) {
+		case nil, map[string]any, []any:
+			return fmt.Errorf("setting '%s' in %s must be a single value", key, source)
+		default:
+			if err := f.fs.Set(key, fmt.Sprint(value)); err != nil {
+				return fmt.Errorf("invalid value for '%s' in %s: %v", key, source, err)
+			}
+		}
+	}
+	return nil
+}
+
+// applyPreset sets each processing flag not given on the command line or in
+// the config file from the selected library preset
+func (f *conversionFlags) applyPreset(preset *wav2ulaw.AudioConfig) error {
+	explicit := make(map[string]bool)
+	f.fs.Visit(func(fl *flag.Flag) { explicit[fl.Name] = true })
+
+	for name, field := range presetFields {
+		if explicit[name] {
+			continue
+		}
+		if err := f.fs.Set(name, fmt.Sprint(field(preset))); err != nil {
+			return fmt.Errorf("error applying preset: %v", err)
+		}
+	}
+	return nil
+}
+
+// beeps returns the beeps selected by -beep-at and -beep-every
+func (f *conversionFlags) beeps() ([]wav2ulaw.Beep, error) {
+	positions := strings.Split(*f.beepAt, ",")
+	if *f.beepAt == "" {
+		if *f.beepEvery == 0 {
+			return nil, nil
+		}
+		positions = []string{"0s"}
+	}
+	beeps := make([]wav2ulaw.Beep, 0, len(positions))
+	for _, position := range positions {
+		at, err := time.ParseDuration(strings.TrimSpace(position))
+		if err != nil {
+			return nil, fmt.Errorf("invalid -beep-at position '%s': %v", position, err)
+		}
+		beeps = append(beeps, wav2ulaw.Beep{
+			At:        at,
+			Duration:  *f.beepDuration,
+			Frequency: *f.beepFrequency,
+			LevelDbov: *f.beepLevel,
+			Repeat:    *f.beepEvery,
+		})
+	}
+	return beeps, nil
+}
+
+// applyASRPreset switches to asr mode and sets each asr mode flag not given
+// explicitly to the value in preset
+func (f *conversionFlags) applyASRPreset(preset *wav2ulaw.ASROptions) error {
+	if f.isSet("mode") && *f.mode != "asr" {
+		return fmt.Errorf("preset '%s' converts in asr mode, not %s", *f.preset, *f.mode)
+	}
+	if err := f.fs.Set("mode", "asr"); err != nil {
+		return err
+	}
+	for name, field := range asrPresetFields {
+		if f.isSet(name) {
+			continue
+		}
+		if err := f.fs.Set(name, fmt.Sprint(field(preset))); err != nil {
+			return fmt.Errorf("error applying preset: %v", err)
+		}
+	}
+	return nil
+}
+
+// inherit sets each processing flag given to base that f does not set
+// itself. Nothing is inherited when f chooses a preset, so that the preset
+// applies in full.
+func (f *conversionFlags) inherit(base *conversionFlags) {
+	if f.isSet("preset") {
+		return
+	}
+	known := make(map[string]bool)
+	for _, name := range f.names {
+		known[name] = true
+	}
+	base.fs.Visit(func(fl *flag.Flag) {
+		if known[fl.Name] && !f.isSet(fl.Name) {
+			f.fs.Set(fl.Name, fl.Value.String())
+		}
+	})
+}
+
+// isSet reports whether a flag was given on the command line or in the config file
+func (f *conversionFlags) isSet(name string) bool {
+	set := false
+	f.fs.Visit(func(fl *flag.Flag) {
+		if fl.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// settings returns the effective value of every processing flag, keyed by
+// flag name so the result can be written back out as a config file
+func (f *conversionFlags) settings() map[string]any {
+	settings := make(map[string]any, len(f.names))
+	for _, name := range f.names {
+		settings[name] = f.fs.Lookup(name).Value.(flag.Getter).Get()
+	}
+	return settings
+}
+
+// conversion validates the parsed flags and builds the conversion they
+// describe, reporting progress and diagnostics through logger
+func (f *conversionFlags) conversion(logger *slog.Logger) (*conversion, error) {
+	if err := f.applyConfigFile(); err != nil {
+		return nil, err
+	}
+	presetConfig, isConfigPreset := configPresets[*f.preset]
+	asrPreset, isASRPreset := asrPresets[*f.preset]
+	if *f.preset != "" && *f.preset != "telephone-fx" && !isConfigPreset && !isASRPreset {
+		return nil, fmt.Errorf("invalid preset '%s'. Must be 'telephony', 'voicemail', 'tts-narrowband', 'tts-fast', 'raw', 'telephone-fx' or 'asr-cleanup'", *f.preset)
+	}
+	var preset *wav2ulaw.AudioConfig
+	if isConfigPreset {
+		preset = presetConfig()
+		if err := f.applyPreset(preset); err != nil {
+			return nil, err
+		}
+	}
+	if isASRPreset {
+		if err := f.applyASRPreset(asrPreset()); err != nil {
+			return nil, err
+		}
+	}
+	switch *f.mode {
+	case "wav2ulaw", "ulaw2wav", "ulaw2ulaw", "asr", "wav2alaw", "alaw2wav", "ulaw2alaw", "alaw2ulaw":
+	default:
+		if *f.preset == "" {
+			return nil, fmt.Errorf("invalid mode '%s'. Must be 'wav2ulaw', 'ulaw2wav', 'ulaw2ulaw', 'asr', 'wav2alaw', 'alaw2wav', 'ulaw2alaw' or 'alaw2ulaw'", *f.mode)
+		}
+	}
+
+	ulawVariant, err := wav2ulaw.ParseUlawVariant(*f.ulawVariant)
+	if err != nil {
+		return nil, err
+	}
+	var tradeoff wav2ulaw.ResampleTradeoff
+	if err := tradeoff.UnmarshalText([]byte(*f.resampleTradeoff)); err != nil {
+		return nil, err
+	}
+
+	config := &wav2ulaw.AudioConfig{
+		LowPassCutoff:           *f.lowPass,
+		HighPassCutoff:          *f.highPass,
+		NormalizePeak:           *f.normalize,
+		TargetLoudness:          *f.targetLoudness,
+		Tempo:                   *f.tempo,
+		Speed:                   *f.speed,
+		Reverse:                 *f.reverse,
+		CompressionRatio:        *f.compressRatio,
+		CompressionThreshold:    *f.compressThreshold,
+		LevelCompensation:       *f.levelCompensation,
+		FadeInMs:                *f.fadeIn,
+		FadeOutMs:               *f.fadeOut,
+		FadeShape:               wav2ulaw.FadeShape(*f.fadeShape),
+		PadStart:                *f.padStart,
+		PadEnd:                  *f.padEnd,
+		FrameAlign:              *f.frameAlign,
+		ResamplingWindowSize:    *f.windowSize,
+		ResampleMethod:          wav2ulaw.ResampleMethod(*f.resampleMethod),
+		ResampleTradeoff:        tradeoff,
+		WindowFunction:          wav2ulaw.WindowFunction(*f.windowFunction),
+		KaiserBeta:              *f.kaiserBeta,
+		AntiAliasingCutoffRatio: *f.antiAliasingRatio,
+		AntiAliasingType:        wav2ulaw.AntiAliasingType(*f.antiAliasingType),
+		FilterOrder:             *f.filterOrder,
+		ChebyshevRipple:         *f.chebyshevRipple,
+		Concurrency:             *f.concurrency,
+		Deterministic:           *f.deterministic,
+		Lenient:                 *f.lenient,
+		SanityCheck:             *f.sanityCheck,
+		UlawVariant:             ulawVariant,
+		Logger:                  logger,
+	}
+
+	if preset != nil {
+		config.ForceMono = preset.ForceMono
+	}
+
+	if *f.autoTrim {
+		config.AutoTrim = &wav2ulaw.AutoTrim{PreRoll: *f.trimPreRoll, PostRoll: *f.trimPostRoll}
+	}
+
+	if *f.noise != "" {
+		config.Noise = &wav2ulaw.NoiseOverlay{SNR: *f.noiseSNR, Seed: *f.noiseSeed}
+		switch *f.noise {
+		case "white":
+		case "pink":
+			config.Noise.Color = wav2ulaw.NoisePink
+		default:
+			if config.Noise.Data, err = os.ReadFile(*f.noise); err != nil {
+				return nil, fmt.Errorf("error reading noise file: %v", err)
+			}
+		}
+	}
+
+	if config.Beeps, err = f.beeps(); err != nil {
+		return nil, err
+	}
+
+	if *f.warnClipping {
+		config.OnClipping = func(region wav2ulaw.ClipRegion) {
+			logger.Warn("clipping", "channel", region.Channel, "start", region.Start, "samples", region.Samples)
+		}
+	}
+
+	effect := *f.preset
+	if isConfigPreset || isASRPreset {
+		effect = ""
+	}
+	if *f.filterOrder < 2 || *f.filterOrder > 8 {
+		return nil, fmt.Errorf("invalid filter order %d. Must be between 2 and 8", *f.filterOrder)
+	}
+	if *f.preEmphasis < 0 || *f.preEmphasis >= 1 {
+		return nil, fmt.Errorf("invalid pre-emphasis %g. Must be at least 0 and below 1", *f.preEmphasis)
+	}
+	if *f.loudness > 0 {
+		return nil, fmt.Errorf("invalid loudness %g LUFS. Must be negative, or 0 to keep the level", *f.loudness)
+	}
+	if *f.samples < 0 {
+		return nil, fmt.Errorf("invalid sample count %d. Must not be negative", *f.samples)
+	}
+	if *f.bitDepth != 8 && *f.bitDepth != 16 {
+		return nil, fmt.Errorf("invalid bit depth %d. Must be 8 or 16", *f.bitDepth)
+	}
+	if *f.loop < 1 || *f.minDuration < 0 || *f.loopCrossfade < 0 {
+		return nil, fmt.Errorf("invalid loop settings: -loop must be at least 1, -min-duration and -loop-crossfade not negative")
+	}
+	if effect == "telephone-fx" && (*f.loop > 1 || *f.minDuration > 0) {
+		return nil, fmt.Errorf("-loop and -min-duration need u-law audio and do not apply to the telephone-fx preset")
+	}
+	if effect == "" && strings.Contains(*f.mode, "alaw") && (*f.loop > 1 || *f.minDuration > 0) {
+		return nil, fmt.Errorf("-loop and -min-duration need u-law audio and do not apply to A-law modes")
+	}
+
+	// Reprocessing runs only the stages asked for
+	reprocessPeak := 0.0
+	if f.isSet("normalize") {
+		reprocessPeak = *f.normalize
+	}
+
+	// Speech recognizers want 16 kHz unless told otherwise
+	sampleRate := uint32(*f.sampleRate)
+	if *f.mode == "asr" && !f.isSet("sample-rate") {
+		sampleRate = 16000
+	}
+
+	return &conversion{
+		mode:       *f.mode,
+		preset:     effect,
+		config:     config,
+		sampleRate: sampleRate,
+		samples:    *f.samples,
+		bitDepth:   *f.bitDepth,
+		dither:     *f.dither,
+		windowSize: *f.windowSize,
+		reprocess: wav2ulaw.ReprocessOptions{
+			NoiseGateDbov: *f.gate,
+			AGCTargetDbov: *f.agc,
+			AGCMaxGainDb:  *f.agcMaxGain,
+			NormalizePeak: reprocessPeak,
+			UlawVariant:   ulawVariant,
+			Logger:        logger,
+		},
+		asr: wav2ulaw.ASROptions{
+			SampleRate:      int(sampleRate),
+			PreEmphasis:     *f.preEmphasis,
+			LoudnessTarget:  *f.loudness,
+			HighShelfGainDb: *f.highShelf,
+			NoiseGateDbov:   *f.gate,
+			AGCTargetDbov:   *f.agc,
+			AGCMaxGainDb:    *f.agcMaxGain,
+			UlawVariant:     ulawVariant,
+		},
+		loop: loopSettings{
+			count:       *f.loop,
+			minDuration: *f.minDuration,
+			crossfadeMs: *f.loopCrossfade,
+		},
+		logger: logger,
+	}, nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+	"wav2ulaw"
+)
+
+// followCommand defines the flags of the "follow" subcommand and returns its implementation
+func followCommand(fs *flag.FlagSet) func(args []string) {
+	outputFile := fs.String("output", "-", "Output u-law file path (- for stdout)")
+	poll := fs.Duration("poll", 200*time.Millisecond, "How often the input is checked for new audio")
+	idle := fs.Duration("idle-timeout", 5*time.Second, "Finish once the input has not grown for this long, for writers that never fill in the WAV sizes")
+	convFlags := registerConversionFlags(fs)
+	logFlags := registerLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: wav2ulaw follow [flags] <input.wav>")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Converts a WAV file while it is still being written, like tail -f, writing")
+		fmt.Fprintln(os.Stderr, "u-law as the audio arrives. It finishes when the writer fills in the data")
+		fmt.Fprintln(os.Stderr, "size, after -idle-timeout without growth, or on Ctrl-C.")
+		fs.PrintDefaults()
+	}
+	return func(args []string) {
+		if len(args) != 1 {
+			fs.Usage()
+			os.Exit(exitUsage)
+		}
+		logger, err := logFlags.logger(os.Stderr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		job, err := convFlags.conversion(logger)
+		if err != nil {
+			logger.Error("invalid settings", "error", err)
+			os.Exit(exitUsage)
+		}
+		if *poll <= 0 || *idle <= 0 {
+			logger.Error("-poll and -idle-timeout must be positive")
+			os.Exit(exitUsage)
+		}
+
+		var out io.Writer = os.Stdout
+		if *outputFile != "-" {
+			f, err := os.Create(*outputFile)
+			if err != nil {
+				logger.Error("error creating output file", "error", err)
+				os.Exit(exitWrite)
+			}
+			defer f.Close()
+			out = f
+		}
+
+		// Ctrl-C ends the conversion with the audio so far
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		logger.Info("following", "input", args[0])
+		n, err := wav2ulaw.FollowWavFile(ctx, args[0], out, job.config, &wav2ulaw.FollowOptions{PollInterval: *poll, IdleTimeout: *idle})
+		if err != nil && !errors.Is(err, context.Canceled) {
+			logger.Error("error following input file", "input", args[0], "error", err)
+			os.Exit(conversionExitCode(err))
+		}
+		logger.Info("conversion completed", "output", *outputFile, "duration", time.Duration(n)*time.Second/8000)
+	}
+}
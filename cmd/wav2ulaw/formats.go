@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"wav2ulaw"
+)
+
+// Extensions of raw u-law files, including the names used by Asterisk
+var ulawExts = map[string]bool{".ulaw": true, ".ul": true, ".pcm": true, ".mu": true}
+
+// Extensions of raw A-law files
+var alawExts = map[string]bool{".alaw": true, ".al": true}
+
+// Telephony formats recognized by extension that cannot be converted yet
+var unsupportedExts = map[string]string{
+	".sln":  "signed linear",
+	".slin": "signed linear",
+}
+
+// inferMode picks the conversion direction from the input and output file
+// extensions, falling back to the content of an input file whose extension
+// says nothing about its format. It is only used when -mode was not given;
+// stdin and batch patterns without a recognized extension leave the mode as is.
+func (c *conversion) inferMode(inputPath, outputPath string) error {
+	if c.preset != "" {
+		return nil
+	}
+	in := strings.ToLower(filepath.Ext(inputPath))
+	if isHTTPURL(inputPath) {
+		in = strings.ToLower(urlExt(inputPath))
+	}
+	out := strings.ToLower(filepath.Ext(outputPath))
+	for _, ext := range []string{in, out} {
+		if name, ok := unsupportedExts[ext]; ok {
+			return withExitCode(exitFormat, fmt.Errorf("%s files (%s) are not supported", name, ext))
+		}
+	}
+
+	switch {
+	case alawExts[in] && alawExts[out]:
+		return withExitCode(exitFormat, fmt.Errorf("A-law files cannot be reprocessed, convert to u-law or WAV"))
+	case alawExts[in] && ulawExts[out]:
+		c.mode = "alaw2ulaw"
+	case ulawExts[in] && alawExts[out]:
+		c.mode = "ulaw2alaw"
+	case alawExts[in]:
+		c.mode = "alaw2wav"
+	case alawExts[out]:
+		c.mode = "wav2alaw"
+	case ulawExts[in] && ulawExts[out]:
+		c.mode = "ulaw2ulaw"
+	case ulawExts[in] && !ulawExts[out]:
+		c.mode = "ulaw2wav"
+	case in == ".wav" || ulawExts[out]:
+		c.mode = "wav2ulaw"
+	default:
+		return c.inferModeFromContent(inputPath)
+	}
+	return nil
+}
+
+// inferModeFromContent sniffs the start of a regular input file
+func (c *conversion) inferModeFromContent(inputPath string) error {
+	if info, err := os.Stat(inputPath); err != nil || !info.Mode().IsRegular() {
+		return nil
+	}
+	file, err := os.Open(inputPath)
+	if err != nil {
+		return withExitCode(exitInput, fmt.Errorf("error reading input file: %v", err))
+	}
+	defer file.Close()
+	head, err := io.ReadAll(io.LimitReader(file, 64*1024))
+	if err != nil {
+		return withExitCode(exitInput, fmt.Errorf("error reading input file: %v", err))
+	}
+
+	if err := c.inferModeFromData(head); err != nil {
+		return withExitCode(exitFormat, fmt.Errorf("%s: %v (use -mode to choose the conversion)", inputPath, err))
+	}
+	c.logger.Debug("detected input format", "input", inputPath, "mode", c.mode)
+	return nil
+}
+
+// inferModeFromData sets the mode from the format detected in data
+func (c *conversion) inferModeFromData(data []byte) error {
+	format, err := wav2ulaw.DetectFormat(data)
+	if err != nil {
+		return err
+	}
+	if format == wav2ulaw.FormatUlaw {
+		c.mode = "ulaw2wav"
+	} else {
+		c.mode = "wav2ulaw"
+	}
+	return nil
+}
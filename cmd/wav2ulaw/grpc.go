@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"time"
+	"wav2ulaw"
+	"wav2ulaw/api"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// converterServer implements the gRPC Converter service
+type converterServer struct {
+	api.UnimplementedConverterServer
+	// Settings used by requests that do not name a preset
+	job *conversion
+	// Largest input of a ConvertStream call and output of any call
+	maxSize int
+	// Metrics and traces of conversions, nil without -metrics and -otlp
+	instrumentation *instrumentation
+}
+
+// Convert converts the file in the request
+func (s *converterServer) Convert(ctx context.Context, req *api.ConvertRequest) (*api.ConvertResponse, error) {
+	output, direction, err := s.convert(ctx, req, req.Data)
+	if err != nil {
+		return nil, err
+	}
+	return &api.ConvertResponse{Data: output, Direction: direction}, nil
+}
+
+// ConvertStream converts a file received in chunks, replying in chunks once
+// the client has sent all of it
+func (s *converterServer) ConvertStream(stream api.Converter_ConvertStreamServer) error {
+	var settings *api.ConvertRequest
+	var input []byte
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if settings == nil {
+			settings = req
+		}
+		if len(input)+len(req.Data) > s.maxSize {
+			return status.Errorf(codes.ResourceExhausted, "input larger than %d bytes", s.maxSize)
+		}
+		input = append(input, req.Data...)
+	}
+	if settings == nil {
+		return status.Error(codes.InvalidArgument, "no input data")
+	}
+
+	output, direction, err := s.convert(stream.Context(), settings, input)
+	if err != nil {
+		return err
+	}
+	for first := true; first || len(output) > 0; first = false {
+		chunk := output[:min(len(output), api.StreamChunkSize)]
+		output = output[len(chunk):]
+		resp := &api.ConvertResponse{Data: chunk}
+		if first {
+			resp.Direction = direction
+		}
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// convert runs the conversion described by req on data, which is req.Data
+// for unary calls, returning the direction it ran in
+func (s *converterServer) convert(ctx context.Context, req *api.ConvertRequest, data []byte) ([]byte, api.Direction, error) {
+	if len(data) == 0 {
+		return nil, 0, status.Error(codes.InvalidArgument, "no input data")
+	}
+	job, err := s.requestJob(req)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch req.Direction {
+	case api.Direction_DIRECTION_WAV_TO_ULAW:
+		job.mode = "wav2ulaw"
+	case api.Direction_DIRECTION_ULAW_TO_WAV:
+		job.mode = "ulaw2wav"
+	default:
+		if err := job.inferModeFromData(data); err != nil {
+			return nil, 0, status.Errorf(codes.InvalidArgument, "cannot infer direction: %v", err)
+		}
+	}
+	if req.SampleRate != 0 {
+		job.sampleRate = req.SampleRate
+	}
+	// Loop and padding settings can make a small input grow without bound,
+	// so the output is checked before it is built
+	if size, err := job.outputSize(data); err != nil {
+		return nil, 0, status.Error(codes.InvalidArgument, err.Error())
+	} else if size > int64(s.maxSize) {
+		return nil, 0, status.Errorf(codes.ResourceExhausted, "output of %d bytes larger than %d bytes", size, s.maxSize)
+	}
+
+	output, err := s.instrumentation.convert(grpcTraceContext(ctx), "grpc", job, data)
+	if err != nil {
+		return nil, 0, status.Error(codes.InvalidArgument, err.Error())
+	}
+	direction := api.Direction_DIRECTION_WAV_TO_ULAW
+	if job.mode == "ulaw2wav" {
+		direction = api.Direction_DIRECTION_ULAW_TO_WAV
+	}
+	return output, direction, nil
+}
+
+// requestJob returns a copy of the server's settings, or of the preset req
+// names, for one call
+func (s *converterServer) requestJob(req *api.ConvertRequest) (*conversion, error) {
+	if req.Preset != "" {
+		job, err := s.job.withPreset(req.Preset)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return job, nil
+	}
+	copied := *s.job
+	return &copied, nil
+}
+
+// ConvertLive converts audio as it arrives, replying with 20 ms frames of
+// output as soon as they are complete
+func (s *converterServer) ConvertLive(stream api.Converter_ConvertLiveServer) error {
+	req, err := stream.Recv()
+	if err == io.EOF {
+		return status.Error(codes.InvalidArgument, "no input data")
+	}
+	if err != nil {
+		return err
+	}
+	job, err := s.requestJob(req)
+	if err != nil {
+		return err
+	}
+	switch req.Direction {
+	case api.Direction_DIRECTION_WAV_TO_ULAW:
+		job.mode = "wav2ulaw"
+	case api.Direction_DIRECTION_ULAW_TO_WAV:
+		job.mode = "ulaw2wav"
+	default:
+		return status.Error(codes.InvalidArgument, "live conversions need a direction")
+	}
+	direction := req.Direction
+	conv, err := newStreamConverter(job, int(req.SampleRate))
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	frame := conv.frameSize()
+	sent := false
+	// send replies with the complete frames of output and returns the rest,
+	// which is sent as well when final is set
+	send := func(output []byte, final bool) ([]byte, error) {
+		for len(output) >= frame || (final && (len(output) > 0 || !sent)) {
+			n := min(frame, len(output))
+			resp := &api.ConvertResponse{Data: output[:n]}
+			if !sent {
+				resp.Direction = direction
+				sent = true
+			}
+			if err := stream.Send(resp); err != nil {
+				return nil, err
+			}
+			output = output[n:]
+		}
+		return append([]byte(nil), output...), nil
+	}
+
+	var input, output []byte
+	for {
+		if input = append(input, req.Data...); len(input) > 0 {
+			converted, err := conv.convert(input)
+			switch {
+			case errors.Is(err, errIncompleteStreamHeader) && len(input) < maxStreamHeaderSize:
+				// The rest of the header is in the next messages
+			case err != nil:
+				return status.Error(codes.InvalidArgument, err.Error())
+			default:
+				input = input[:0]
+				if output, err = send(append(output, converted...), false); err != nil {
+					return err
+				}
+			}
+		}
+		if req, err = stream.Recv(); err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if len(input) > 0 {
+		return status.Error(codes.InvalidArgument, "incomplete WAV header")
+	}
+	_, err = send(append(output, conv.flush()...), true)
+	return err
+}
+
+// Analyze reports statistics of the file in the request
+func (s *converterServer) Analyze(ctx context.Context, req *api.AnalyzeRequest) (*api.AnalyzeResponse, error) {
+	stats, format, err := wav2ulaw.Analyze(req.Data)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	resp := &api.AnalyzeResponse{
+		Format:          format.String(),
+		DurationSeconds: stats.Duration.Seconds(),
+		Channels:        int32(stats.Channels),
+		SampleRate:      int32(stats.SampleRate),
+		BitDepth:        int32(stats.BitDepth),
+		Frames:          int64(stats.Frames),
+		Peak:            stats.Peak,
+		Rms:             stats.RMS,
+		DcOffset:        stats.DCOffset,
+		ClippedSamples:  int64(stats.ClippedSamples),
+		ClipRegions:     int32(len(stats.ClipRegions)),
+	}
+	if format == wav2ulaw.FormatUlaw {
+		// Decoded to 16-bit for analysis, but stored as 8-bit codes
+		resp.BitDepth = 8
+	}
+	return resp, nil
+}
+
+// logRPC logs every call with its duration and outcome
+func logRPC(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		if err != nil {
+			logger.Warn("request failed", "method", info.FullMethod, "duration", time.Since(start), "error", err)
+		} else {
+			logger.Info("request", "method", info.FullMethod, "duration", time.Since(start))
+		}
+		return resp, err
+	}
+}
+
+// logStream logs every streaming call with its duration and outcome
+func logStream(logger *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, stream)
+		if err != nil {
+			logger.Warn("request failed", "method", info.FullMethod, "duration", time.Since(start), "error", err)
+		} else {
+			logger.Info("request", "method", info.FullMethod, "duration", time.Since(start))
+		}
+		return err
+	}
+}
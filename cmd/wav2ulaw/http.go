@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Media types accepted and produced by the HTTP API. The first entry of
+// each list is the one sent in responses.
+var (
+	wavMediaTypes  = []string{"audio/wav", "audio/x-wav", "audio/wave", "audio/vnd.wave"}
+	ulawMediaTypes = []string{"audio/basic", "audio/pcmu", "audio/x-mulaw"}
+)
+
+// Largest resampling window a request may ask for, four times the default
+const maxRequestWindowSize = 64
+
+// httpRoute is one conversion endpoint of the HTTP API
+type httpRoute struct {
+	mode       string
+	inputTypes []string
+	// Response media types, the first is sent
+	outputTypes []string
+}
+
+// httpAPI serves conversions over HTTP
+type httpAPI struct {
+	// Flags the server was started with, the defaults for every request
+	defaults *conversionFlags
+	logger   *slog.Logger
+	maxSize  int64
+	// Metrics and traces of conversions, nil without -metrics and -otlp
+	instrumentation *instrumentation
+	// Origins besides the server's own whose pages may open streams
+	allowOrigins []string
+}
+
+// handler returns the HTTP handler serving the API routes
+func (s *httpAPI) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/convert", s.convertHandler(httpRoute{mode: "wav2ulaw", inputTypes: wavMediaTypes, outputTypes: ulawMediaTypes}))
+	mux.Handle("/convert/ulaw2wav", s.convertHandler(httpRoute{mode: "ulaw2wav", inputTypes: ulawMediaTypes, outputTypes: wavMediaTypes}))
+	mux.Handle("/stream", s.streamHandler("wav2ulaw"))
+	mux.Handle("/stream/ulaw2wav", s.streamHandler("ulaw2wav"))
+	return s.logRequests(mux)
+}
+
+// convertHandler converts the request body, a single file or a multipart
+// form with a "file" part and an optional JSON "config" part. Processing
+// settings are taken from the server's flags, then the config part, then
+// query parameters named like the flags.
+func (s *httpAPI) convertHandler(route httpRoute) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			httpError(w, http.StatusMethodNotAllowed, "use POST")
+			return
+		}
+		if !acceptsAny(r.Header.Get("Accept"), route.outputTypes) {
+			httpError(w, http.StatusNotAcceptable, fmt.Sprintf("response is %s", route.outputTypes[0]))
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, s.maxSize)
+
+		data, config, err := readConvertRequest(r, route.inputTypes)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			switch {
+			case errors.As(err, &tooLarge):
+				httpError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("request larger than %d bytes", s.maxSize))
+			case errors.Is(err, errUnsupportedMediaType):
+				httpError(w, http.StatusUnsupportedMediaType, err.Error())
+			default:
+				httpError(w, http.StatusBadRequest, err.Error())
+			}
+			return
+		}
+		if len(data) == 0 {
+			httpError(w, http.StatusBadRequest, "no input data")
+			return
+		}
+
+		job, err := s.requestConversion(r.URL.Query(), config, route.mode)
+		if err != nil {
+			httpError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		// Settings such as loop and pad-end can make a small input grow
+		// without bound, so the output is checked before it is built
+		if size, err := job.outputSize(data); err != nil {
+			httpError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		} else if size > s.maxSize {
+			httpError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("output of %d bytes larger than %d bytes", size, s.maxSize))
+			return
+		}
+		ctx := tracePropagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		output, err := s.instrumentation.convert(ctx, "http", job, data)
+		if err != nil {
+			httpError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", route.outputTypes[0])
+		w.Header().Set("Content-Length", strconv.Itoa(len(output)))
+		w.Write(output)
+	})
+}
+
+var errUnsupportedMediaType = errors.New("unsupported media type")
+
+// readConvertRequest returns the input file and the JSON config, if any,
+// carried by a request
+func readConvertRequest(r *http.Request, inputTypes []string) ([]byte, []byte, error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil && r.Header.Get("Content-Type") != "" {
+		return nil, nil, fmt.Errorf("invalid Content-Type: %v", err)
+	}
+	if mediaType != "multipart/form-data" {
+		if mediaType != "" && mediaType != "application/octet-stream" && !containsType(inputTypes, mediaType) {
+			return nil, nil, fmt.Errorf("%w %s, expected %s", errUnsupportedMediaType, mediaType, strings.Join(inputTypes, ", "))
+		}
+		data, err := io.ReadAll(r.Body)
+		return data, nil, err
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, nil, err
+	}
+	var data, config []byte
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		switch part.FormName() {
+		case "file":
+			data, err = io.ReadAll(part)
+		case "config":
+			config, err = io.ReadAll(part)
+		default:
+			err = fmt.Errorf("unexpected form field '%s'", part.FormName())
+		}
+		part.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return data, config, nil
+}
+
+// requestConversion builds the conversion for one request: the server's
+// settings, unless the request selects its own preset, overridden by the
+// JSON config and then by query parameters
+func (s *httpAPI) requestConversion(query url.Values, config []byte, mode string) (*conversion, error) {
+	fs := flag.NewFlagSet("request", flag.ContinueOnError)
+	f := registerConversionFlags(fs)
+	known := make(map[string]bool)
+	for _, name := range f.names {
+		known[name] = true
+	}
+
+	for key, values := range query {
+		if !known[key] || key == "mode" {
+			return nil, fmt.Errorf("unknown parameter '%s'", key)
+		}
+		if err := fs.Set(key, values[len(values)-1]); err != nil {
+			return nil, fmt.Errorf("invalid value for '%s': %v", key, err)
+		}
+	}
+	if config != nil {
+		if err := f.applyConfig(config, "request config"); err != nil {
+			return nil, err
+		}
+	}
+	// Clients pick generated noise only, never a file on the server
+	if *f.noise != "" && *f.noise != "white" && *f.noise != "pink" {
+		return nil, fmt.Errorf("invalid value for 'noise': requests accept white or pink only")
+	}
+	// Bound the work of one request
+	if *f.windowSize > maxRequestWindowSize {
+		return nil, fmt.Errorf("invalid value for 'window-size': requests accept at most %d", maxRequestWindowSize)
+	}
+	if *f.concurrency > runtime.NumCPU() {
+		return nil, fmt.Errorf("invalid value for 'concurrency': requests accept at most %d, or -1 for all CPUs", runtime.NumCPU())
+	}
+	f.inherit(s.defaults)
+	fs.Set("mode", mode)
+	return f.conversion(s.logger)
+}
+
+// acceptsAny reports whether an Accept header allows one of types. An empty
+// header accepts anything, and media ranges with q=0 are refused.
+func acceptsAny(accept string, types []string) bool {
+	if accept == "" {
+		return true
+	}
+	for _, entry := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(entry))
+		if err != nil || params["q"] == "0" {
+			continue
+		}
+		if mediaType == "*/*" || mediaType == "audio/*" || containsType(types, mediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsType reports whether types includes mediaType
+func containsType(types []string, mediaType string) bool {
+	for _, t := range types {
+		if t == mediaType {
+			return true
+		}
+	}
+	return false
+}
+
+// httpError writes a JSON error response
+func httpError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+// statusRecorder captures the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets http.ResponseController flush streamed responses through the recorder
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Hijack lets WebSocket upgrades through the recorder
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("connection does not support hijacking")
+	}
+	r.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
+
+// logRequests logs every request with its status and duration
+func (s *httpAPI) logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		level := slog.LevelInfo
+		if rec.status >= 400 {
+			level = slog.LevelWarn
+		}
+		s.logger.Log(r.Context(), level, "request", "method", r.Method, "path", r.URL.Path, "status", rec.status, "duration", time.Since(start))
+	})
+}
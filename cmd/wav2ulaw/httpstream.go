@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/http"
+)
+
+const (
+	// Largest read from a streamed request body, so output follows input
+	// within a few milliseconds of audio whatever the client's chunk size
+	httpStreamReadSize = 4096
+	// Largest WAV header buffered before the first audio of a stream
+	maxStreamHeaderSize = 64 << 10
+)
+
+// serveHTTPStream converts a POST body as it arrives and writes the output
+// as soon as it is produced, using chunked transfer encoding in both
+// directions on HTTP/1.1. ulaw2wav responses start with a WAV header of
+// unknown size, as ffmpeg writes to pipes. Errors before any output are
+// reported as JSON; later ones abort the response, so a client never takes
+// a cut-off stream for a complete one.
+func (s *httpAPI) serveHTTPStream(w http.ResponseWriter, r *http.Request, conv streamConverter) {
+	// Without full duplex, HTTP/1.1 servers stop reading the request body
+	// once the response starts
+	rc := http.NewResponseController(w)
+	if err := rc.EnableFullDuplex(); err != nil {
+		s.logger.Debug("full duplex not available", "error", err)
+	}
+
+	started := false
+	start := func() {
+		if decoder, ok := conv.(*ulawStreamDecoder); ok {
+			w.Header().Set("Content-Type", wavMediaTypes[0])
+			w.WriteHeader(http.StatusOK)
+			w.Write(streamWavHeader(decoder.rate))
+		} else {
+			w.Header().Set("Content-Type", ulawMediaTypes[0])
+			w.WriteHeader(http.StatusOK)
+		}
+		started = true
+	}
+	fail := func(status int, err error) {
+		s.logger.Warn("stream failed", "path", r.URL.Path, "error", err)
+		if !started {
+			httpError(w, status, err.Error())
+			return
+		}
+		panic(http.ErrAbortHandler)
+	}
+
+	var pending []byte
+	buf := make([]byte, httpStreamReadSize)
+	for {
+		n, readErr := r.Body.Read(buf)
+		if n > 0 {
+			pending = append(pending, buf[:n]...)
+			output, err := conv.convert(pending)
+			switch {
+			case errors.Is(err, errIncompleteStreamHeader) && len(pending) < maxStreamHeaderSize && readErr == nil:
+				// The rest of the header is still on its way
+				continue
+			case err != nil:
+				fail(http.StatusUnprocessableEntity, err)
+				return
+			}
+			pending = pending[:0]
+			if !started {
+				start()
+			}
+			if len(output) > 0 {
+				if _, err := w.Write(output); err != nil {
+					return
+				}
+				rc.Flush()
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			fail(http.StatusBadRequest, readErr)
+			return
+		}
+	}
+
+	if !started {
+		if len(pending) > 0 {
+			fail(http.StatusUnprocessableEntity, errors.New("incomplete WAV header"))
+			return
+		}
+		start()
+	}
+	w.Write(conv.flush())
+}
+
+// streamWavHeader returns the header of a 16-bit mono WAV file of unknown
+// length, with the RIFF and data sizes set to their largest value
+func streamWavHeader(rate int) []byte {
+	header := make([]byte, 44)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], 0xFFFFFFFF)
+	copy(header[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(header[16:], 16)
+	binary.LittleEndian.PutUint16(header[20:], 1)
+	binary.LittleEndian.PutUint16(header[22:], 1)
+	binary.LittleEndian.PutUint32(header[24:], uint32(rate))
+	binary.LittleEndian.PutUint32(header[28:], uint32(rate*2))
+	binary.LittleEndian.PutUint16(header[32:], 2)
+	binary.LittleEndian.PutUint16(header[34:], 16)
+	copy(header[36:], "data")
+	binary.LittleEndian.PutUint32(header[40:], 0xFFFFFFFF)
+	return header
+}
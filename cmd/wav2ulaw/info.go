@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"wav2ulaw"
+)
+
+const (
+	// DC offset above which a file is reported as not centered (about -40 dBFS)
+	infoDCOffsetLimit = 0.01
+	// Floor for levels reported in dBFS, so silence stays representable in JSON
+	infoMinDBFS = -120.0
+)
+
+// fileInfo is the report printed by the info subcommand for one file
+type fileInfo struct {
+	File            string    `json:"file"`
+	Format          string    `json:"format"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	Channels        int       `json:"channels"`
+	SampleRate      int       `json:"sample_rate"`
+	BitDepth        int       `json:"bit_depth"`
+	Frames          int       `json:"frames"`
+	Peak            float64   `json:"peak"`
+	PeakDBFS        float64   `json:"peak_dbfs"`
+	RMS             float64   `json:"rms"`
+	RMSDBFS         float64   `json:"rms_dbfs"`
+	DCOffset        float64   `json:"dc_offset"`
+	ClippedSamples  int       `json:"clipped_samples"`
+	ClipRegions     int       `json:"clip_regions"`
+	Talk            *talkInfo `json:"talk,omitempty"`
+	Issues          []string  `json:"issues"`
+}
+
+// talkInfo is the talk time of the channels of a two-channel WAV file, as
+// call QA scoring uses it
+type talkInfo struct {
+	LeftSpeechSeconds     float64 `json:"left_speech_seconds"`
+	RightSpeechSeconds    float64 `json:"right_speech_seconds"`
+	OverlapSeconds        float64 `json:"overlap_seconds"`
+	LongestSilenceSeconds float64 `json:"longest_silence_seconds"`
+}
+
+// infoCommand defines the flags of the "info" subcommand and returns its implementation
+func infoCommand(fs *flag.FlagSet) func(args []string) {
+	asJSON := fs.Bool("json", false, "Print the report as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: wav2ulaw info [-json] <file>... (- for stdin)")
+		fs.PrintDefaults()
+	}
+	return func(args []string) {
+		if len(args) == 0 {
+			fs.Usage()
+			os.Exit(exitUsage)
+		}
+
+		var reports []*fileInfo
+		code := 0
+		for _, path := range args {
+			info, err := inspectFile(path)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error reading '%s': %v\n", path, err)
+				code = mergeExitCode(code, exitCode(err))
+				continue
+			}
+			reports = append(reports, info)
+		}
+
+		if *asJSON {
+			if reports == nil {
+				reports = []*fileInfo{}
+			}
+			out, err := json.MarshalIndent(reports, "", "  ")
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error encoding report: %v\n", err)
+				os.Exit(1)
+			}
+			os.Stdout.Write(append(out, '\n'))
+		} else {
+			for i, info := range reports {
+				if i > 0 {
+					fmt.Println()
+				}
+				printFileInfo(info)
+			}
+		}
+
+		if code != 0 {
+			os.Exit(code)
+		}
+	}
+}
+
+// inspectFile analyzes a WAV or raw u-law file, telling them apart by content
+func inspectFile(path string) (*fileInfo, error) {
+	data, err := readInput(path)
+	if err != nil {
+		return nil, withExitCode(exitInput, err)
+	}
+
+	if _, err := wav2ulaw.DetectFormat(data); err != nil {
+		return nil, withExitCode(exitFormat, err)
+	}
+	stats, format, err := wav2ulaw.Analyze(data)
+	if err != nil {
+		return nil, withExitCode(exitDecode, err)
+	}
+
+	info := &fileInfo{
+		File:            path,
+		Format:          format.String(),
+		DurationSeconds: stats.Duration.Seconds(),
+		Channels:        stats.Channels,
+		SampleRate:      stats.SampleRate,
+		BitDepth:        stats.BitDepth,
+		Frames:          stats.Frames,
+		Peak:            stats.Peak,
+		PeakDBFS:        toDBFS(stats.Peak),
+		RMS:             stats.RMS,
+		RMSDBFS:         toDBFS(stats.RMS),
+		DCOffset:        stats.DCOffset,
+		ClippedSamples:  stats.ClippedSamples,
+		ClipRegions:     len(stats.ClipRegions),
+		Issues:          []string{},
+	}
+	if format == wav2ulaw.FormatUlaw {
+		// Decoded to 16-bit for analysis, but stored as 8-bit codes
+		info.BitDepth = 8
+		if err := wav2ulaw.ValidateUlaw(data); err != nil {
+			info.Issues = append(info.Issues, err.Error())
+		}
+	}
+	if format == wav2ulaw.FormatWAV && stats.Channels == 2 {
+		talk, err := wav2ulaw.AnalyzeTalk(data)
+		if err != nil {
+			return nil, withExitCode(exitDecode, err)
+		}
+		info.Talk = &talkInfo{
+			LeftSpeechSeconds:     talk.Speech[0].Seconds(),
+			RightSpeechSeconds:    talk.Speech[1].Seconds(),
+			OverlapSeconds:        talk.Overlap.Seconds(),
+			LongestSilenceSeconds: talk.LongestSilence.Seconds(),
+		}
+	}
+	if stats.ClippedSamples > 0 {
+		info.Issues = append(info.Issues, fmt.Sprintf("clipping: %d samples in %d regions", stats.ClippedSamples, len(stats.ClipRegions)))
+	}
+	if math.Abs(stats.DCOffset) > infoDCOffsetLimit {
+		info.Issues = append(info.Issues, fmt.Sprintf("DC offset: %.4f", stats.DCOffset))
+	}
+	return info, nil
+}
+
+// toDBFS converts a linear level to dBFS, floored at infoMinDBFS
+func toDBFS(level float64) float64 {
+	if level <= 0 {
+		return infoMinDBFS
+	}
+	return math.Max(20*math.Log10(level), infoMinDBFS)
+}
+
+// printFileInfo prints a human-readable report
+func printFileInfo(info *fileInfo) {
+	issues := "none"
+	if len(info.Issues) > 0 {
+		issues = strings.Join(info.Issues, "; ")
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(w, "File:\t%s\n", info.File)
+	fmt.Fprintf(w, "Format:\t%s\n", info.Format)
+	fmt.Fprintf(w, "Duration:\t%.3f s (%d frames)\n", info.DurationSeconds, info.Frames)
+	fmt.Fprintf(w, "Channels:\t%d\n", info.Channels)
+	fmt.Fprintf(w, "Sample rate:\t%d Hz\n", info.SampleRate)
+	fmt.Fprintf(w, "Bit depth:\t%d\n", info.BitDepth)
+	fmt.Fprintf(w, "Peak:\t%.1f dBFS (%.4f)\n", info.PeakDBFS, info.Peak)
+	fmt.Fprintf(w, "RMS:\t%.1f dBFS (%.4f)\n", info.RMSDBFS, info.RMS)
+	fmt.Fprintf(w, "DC offset:\t%.4f\n", info.DCOffset)
+	if t := info.Talk; t != nil {
+		fmt.Fprintf(w, "Talk time:\tleft %.1f s, right %.1f s, overlap %.1f s\n", t.LeftSpeechSeconds, t.RightSpeechSeconds, t.OverlapSeconds)
+		fmt.Fprintf(w, "Longest silence:\t%.1f s\n", t.LongestSilenceSeconds)
+	}
+	fmt.Fprintf(w, "Issues:\t%s\n", issues)
+	w.Flush()
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"time"
+	"wav2ulaw"
+)
+
+// ivrTestCommand defines the flags of the "ivr-test" subcommand and returns its implementation
+func ivrTestCommand(fs *flag.FlagSet) func(args []string) {
+	dest := fs.String("dest", "", "RTP address of the system under test, e.g. 10.0.0.5:4000")
+	listen := fs.String("listen", ":0", "Local UDP address the prompt is sent from and the response received on (symmetric RTP)")
+	ptime := fs.Int("ptime", 20, "Audio per packet in milliseconds")
+	jitter := fs.Duration("jitter", 0, "Largest deviation of each packet's send time from its slot, to mimic network jitter (0 = none)")
+	seed := fs.Int64("jitter-seed", 1, "Seed of -jitter; the same seed gives the same send times")
+	timeout := fs.Duration("timeout", 5*time.Second, "Stop when no packet has arrived for this long once the prompt has been sent (0 = never)")
+	duration := fs.Duration("duration", 0, "Stop this long after the prompt starts, however the response goes on (0 = until -timeout)")
+	rxFile := fs.String("rx", "", "Output WAV file of the response received")
+	txFile := fs.String("tx", "", "Output WAV file of the prompt as sent (optional)")
+	gapFill := fs.String("gap-fill", "silence", "Fill for lost response packets: silence, noise or conceal")
+	convFlags := registerConversionFlags(fs)
+	logFlags := registerLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: wav2ulaw ivr-test -dest host:port -rx response.wav [flags] <prompt> (- for stdin)")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Plays a prompt into a system under test as a PCMU RTP call and records the")
+		fmt.Fprintln(os.Stderr, "RTP it sends back to WAV. Both legs are saved on the same timeline, starting")
+		fmt.Fprintln(os.Stderr, "with the prompt. WAV prompts are converted first, u-law is sent as is.")
+		fs.PrintDefaults()
+	}
+	return func(args []string) {
+		if *dest == "" || *rxFile == "" || len(args) != 1 {
+			fs.Usage()
+			os.Exit(exitUsage)
+		}
+		logger, err := logFlags.logger(os.Stderr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		fill, ok := gapFills[*gapFill]
+		if !ok {
+			logger.Error(fmt.Sprintf("invalid -gap-fill '%s'. Must be 'silence', 'noise' or 'conceal'", *gapFill))
+			os.Exit(exitUsage)
+		}
+		ssrc := rand.Uint32()
+		packetizer, err := wav2ulaw.NewRTPPacketizer(*ptime, ssrc)
+		if err != nil {
+			logger.Error("invalid settings", "error", err)
+			os.Exit(exitUsage)
+		}
+		job, err := convFlags.conversion(logger)
+		if err != nil {
+			logger.Error("invalid settings", "error", err)
+			os.Exit(exitUsage)
+		}
+
+		prompt, err := readUlawInput(args[0], job)
+		if err != nil {
+			logger.Error("error reading input file", "input", args[0], "error", err)
+			os.Exit(exitCode(err))
+		}
+		remote, err := net.ResolveUDPAddr("udp", *dest)
+		if err != nil {
+			logger.Error("cannot reach destination", "dest", *dest, "error", err)
+			os.Exit(1)
+		}
+		conn, err := net.ListenPacket("udp", *listen)
+		if err != nil {
+			logger.Error("cannot listen", "addr", *listen, "error", err)
+			os.Exit(1)
+		}
+		// Ctrl-C ends the test and still writes the files
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt)
+		go func() {
+			<-interrupt
+			conn.Close()
+		}()
+
+		// The response is received while the prompt is still being sent
+		var mu sync.Mutex
+		var firstPacket time.Duration
+		var sent atomic.Bool
+		depacketizer := wav2ulaw.NewRTPDepacketizer()
+		received := make(chan error, 1)
+		start := time.Now()
+		go func() {
+			received <- receiveResponse(conn, *timeout, &sent, func(packet *wav2ulaw.RTPPacket) {
+				mu.Lock()
+				defer mu.Unlock()
+				depacketizer.Push(packet)
+				if depacketizer.Stats().Packets == 1 {
+					firstPacket = time.Since(start)
+					logger.Info("receiving", "ssrc", fmt.Sprintf("0x%08x", packet.SSRC), "payload-type", packet.PayloadType, "after", firstPacket.Round(time.Millisecond))
+				}
+			})
+		}()
+		if *duration > 0 {
+			time.AfterFunc(*duration, func() { conn.Close() })
+		}
+
+		logger.Info("sending prompt", "dest", remote.String(), "local", conn.LocalAddr().String(), "ssrc", fmt.Sprintf("0x%08x", ssrc), "duration", time.Duration(len(prompt))*time.Second/8000)
+		paced, err := wav2ulaw.NewPacedWriter(&rtpFrameWriter{packetizer, &packetWriter{conn, remote}}, wav2ulaw.PacingOptions{PtimeMs: *ptime, Jitter: *jitter, Seed: *seed})
+		if err != nil {
+			logger.Error("invalid settings", "error", err)
+			os.Exit(exitUsage)
+		}
+		if _, err = paced.Write(prompt); err == nil {
+			err = paced.Flush()
+		}
+		if err != nil && !errors.Is(err, net.ErrClosed) {
+			logger.Error("error sending RTP", "error", err)
+			os.Exit(1)
+		}
+		// From here the response ends after -timeout without packets
+		sent.Store(true)
+		if *timeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(*timeout))
+		}
+		if err := <-received; err != nil {
+			logger.Error("error receiving", "error", err)
+			os.Exit(1)
+		}
+		conn.Close()
+
+		if *txFile != "" {
+			tx, err := wav2ulaw.ConvertUlawBytesToWav(prompt, 8000, 16)
+			if err != nil {
+				logger.Error("error encoding WAV", "error", err)
+				os.Exit(exitDecode)
+			}
+			if err := writeOutput(*txFile, tx); err != nil {
+				logger.Error("error writing output file", "error", err)
+				os.Exit(exitWrite)
+			}
+		}
+		stats := depacketizer.Stats()
+		if stats.Packets == 0 {
+			logger.Error("no RTP audio received", "ignored", stats.Ignored)
+			os.Exit(1)
+		}
+		// Silence until the first packet puts the response on the prompt's timeline
+		lead := int(firstPacket.Seconds() * 8000)
+		rx, err := wav2ulaw.EncodeWavPCM16(append(make([]int16, lead), depacketizer.Samples(fill)...), 8000)
+		if err != nil {
+			logger.Error("error encoding WAV", "error", err)
+			os.Exit(exitDecode)
+		}
+		if err := writeOutput(*rxFile, rx); err != nil {
+			logger.Error("error writing output file", "error", err)
+			os.Exit(exitWrite)
+		}
+		logger.Info("test completed", "rx", *rxFile, "response_delay", firstPacket.Round(time.Millisecond), "packets", stats.Packets, "lost", stats.Lost, "duplicates", stats.Duplicates, "ignored", stats.Ignored)
+	}
+}
+
+// receiveResponse passes the RTP packets arriving on conn to push until conn
+// is closed or, once sent is set, no packet has arrived for timeout
+func receiveResponse(conn net.PacketConn, timeout time.Duration, sent *atomic.Bool, push func(*wav2ulaw.RTPPacket)) error {
+	buf := make([]byte, 65536)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() || errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if sent.Load() && timeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(timeout))
+		}
+		packet, err := wav2ulaw.ParseRTPPacket(buf[:n])
+		if err != nil {
+			continue
+		}
+		// Push decodes the payload, so buf can be reused
+		push(packet)
+	}
+}
+
+// rtpFrameWriter sends each u-law frame written to it as one RTP packet
+type rtpFrameWriter struct {
+	packetizer *wav2ulaw.RTPPacketizer
+	w          *packetWriter
+}
+
+func (f *rtpFrameWriter) Write(frame []byte) (int, error) {
+	for _, packet := range f.packetizer.Packetize(frame) {
+		if _, err := f.w.Write(packet); err != nil {
+			return 0, err
+		}
+	}
+	return len(frame), nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"wav2ulaw"
+)
+
+// kvsCommand defines the flags of the "kvs" subcommand and returns its implementation
+func kvsCommand(fs *flag.FlagSet) func(args []string) {
+	outputFile := fs.String("output", "", "Output file path: .wav keeps the track's PCM, anything else is converted to u-law (- for stdout)")
+	track := fs.String("track", wav2ulaw.KVSTrackFromCustomer, "Track to extract: "+wav2ulaw.KVSTrackFromCustomer+", "+wav2ulaw.KVSTrackToCustomer+", another track name, or mix for both sides")
+	convFlags := registerConversionFlags(fs)
+	logFlags := registerLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: wav2ulaw kvs [flags] -output call.ulaw <fragments.mkv> (- for stdin)")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Extracts an audio track from Kinesis Video Streams fragments, such as Amazon")
+		fmt.Fprintln(os.Stderr, "Connect contact audio saved from GetMedia, and converts it to u-law or WAV.")
+		fs.PrintDefaults()
+	}
+	return func(args []string) {
+		if *outputFile == "" || len(args) != 1 {
+			fs.Usage()
+			os.Exit(exitUsage)
+		}
+		logger, err := logFlags.logger(os.Stderr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		job, err := convFlags.conversion(logger)
+		if err != nil {
+			logger.Error("invalid settings", "error", err)
+			os.Exit(exitUsage)
+		}
+
+		data, err := readInput(args[0])
+		if err != nil {
+			logger.Error("error reading input file", "input", args[0], "error", err)
+			os.Exit(exitInput)
+		}
+		tracks, err := wav2ulaw.ReadKVSAudio(data)
+		if err != nil {
+			logger.Error("error reading KVS stream", "input", args[0], "error", err)
+			os.Exit(exitDecode)
+		}
+		for _, t := range tracks {
+			logger.Debug("track", "number", t.Number, "name", t.Name, "codec", t.CodecID, "rate", t.SampleRate, "duration", time.Duration(len(t.Samples))*time.Second/time.Duration(t.SampleRate))
+		}
+
+		var selected *wav2ulaw.KVSTrack
+		if *track == "mix" {
+			if selected, err = wav2ulaw.MixKVSTracks(tracks); err != nil {
+				logger.Error("cannot mix tracks", "error", err)
+				os.Exit(exitFormat)
+			}
+		} else if selected = wav2ulaw.KVSTrackByName(tracks, *track); selected == nil {
+			names := make([]string, len(tracks))
+			for i, t := range tracks {
+				names[i] = t.Name
+			}
+			logger.Error(fmt.Sprintf("no track '%s', the stream has %s", *track, strings.Join(names, ", ")))
+			os.Exit(exitUsage)
+		}
+
+		var output []byte
+		if strings.EqualFold(filepath.Ext(*outputFile), ".wav") {
+			output, err = selected.Wav()
+		} else {
+			output, err = wav2ulaw.ConvertPCM16ToUlaw(selected.Samples, selected.SampleRate, job.config)
+			output = job.repeat(output)
+		}
+		if err != nil {
+			logger.Error("conversion failed", "track", selected.Name, "error", err)
+			os.Exit(exitDecode)
+		}
+		if err := writeOutput(*outputFile, output); err != nil {
+			logger.Error("error writing output file", "error", err)
+			os.Exit(exitWrite)
+		}
+		logger.Info("extraction completed", "track", selected.Name, "output", *outputFile, "duration", time.Duration(len(selected.Samples))*time.Second/time.Duration(selected.SampleRate))
+	}
+}
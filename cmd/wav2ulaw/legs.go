@@ -0,0 +1,127 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"wav2ulaw"
+)
+
+// mergeLegsCommand defines the flags of the "merge-legs" subcommand and returns its implementation
+func mergeLegsCommand(fs *flag.FlagSet) func(args []string) {
+	outputFile := fs.String("output", "", "Output stereo WAV file path (- for stdout)")
+	txOffset := fs.Duration("tx-offset", 0, "Time the TX leg starts after the RX leg, e.g. 40ms or -1.5s (rounded to 125µs samples)")
+	drift := fs.Bool("drift", false, "Stretch the TX leg to end with the RX leg, correcting drift between the recording clocks")
+	maxDrift := fs.Float64("max-drift", 0.5, "Largest length difference between the legs in percent that -drift corrects")
+	ulawVariant := fs.String("ulaw-variant", "standard", "u-law bit layout of both legs: standard, or a comma-separated list of zero-trap, invert and invert-even")
+	logFlags := registerLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: wav2ulaw merge-legs [flags] -output call.wav <rx.ulaw> <tx.ulaw>")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "RX goes to the left channel and TX to the right. Either leg may be - for stdin.")
+		fs.PrintDefaults()
+	}
+	return func(args []string) {
+		if *outputFile == "" || len(args) != 2 || *maxDrift <= 0 || (args[0] == "-" && args[1] == "-") {
+			fs.Usage()
+			os.Exit(exitUsage)
+		}
+		logger, err := logFlags.logger(os.Stderr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		variant, err := wav2ulaw.ParseUlawVariant(*ulawVariant)
+		if err != nil {
+			logger.Error("invalid settings", "error", err)
+			os.Exit(exitUsage)
+		}
+
+		legs := make([][]byte, len(args))
+		for i, path := range args {
+			if legs[i], err = readInput(path); err != nil {
+				logger.Error("error reading input file", "input", path, "error", err)
+				os.Exit(exitInput)
+			}
+			if format, _ := wav2ulaw.DetectFormat(legs[i]); format == wav2ulaw.FormatWAV {
+				logger.Error("unsupported input", "input", path, "error", "input is a WAV file, expected raw u-law")
+				os.Exit(exitFormat)
+			}
+		}
+
+		options := &wav2ulaw.LegMergeOptions{
+			TXOffset:     *txOffset,
+			CorrectDrift: *drift,
+			MaxDrift:     *maxDrift / 100,
+			UlawVariant:  variant,
+		}
+		output, err := wav2ulaw.MergeUlawLegs(legs[0], legs[1], options)
+		if err != nil {
+			logger.Error("error merging legs", "error", err)
+			os.Exit(exitDecode)
+		}
+		if err := writeOutput(*outputFile, output); err != nil {
+			logger.Error("error writing output file", "error", err)
+			os.Exit(exitWrite)
+		}
+		logger.Info("legs merged", "output", *outputFile, "rx_samples", len(legs[0]), "tx_samples", len(legs[1]))
+	}
+}
+
+// splitLegsCommand defines the flags of the "split-legs" subcommand and returns its implementation
+func splitLegsCommand(fs *flag.FlagSet) func(args []string) {
+	leftFile := fs.String("left", "", "Output u-law file of the left channel, e.g. the agent (- for stdout)")
+	rightFile := fs.String("right", "", "Output u-law file of the right channel, e.g. the customer (- for stdout)")
+	convFlags := registerConversionFlags(fs)
+	logFlags := registerLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: wav2ulaw split-legs [flags] -left agent.ulaw -right customer.ulaw <call.wav>")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Each channel runs through the processing flags on its own.")
+		fs.PrintDefaults()
+	}
+	return func(args []string) {
+		if *leftFile == "" || *rightFile == "" || len(args) != 1 || (*leftFile == "-" && *rightFile == "-") {
+			fs.Usage()
+			os.Exit(exitUsage)
+		}
+		logger, err := logFlags.logger(os.Stderr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		job, err := convFlags.conversion(logger)
+		if err != nil {
+			logger.Error("invalid settings", "error", err)
+			os.Exit(exitUsage)
+		}
+
+		data, err := readInput(args[0])
+		if err != nil {
+			logger.Error("error reading input file", "input", args[0], "error", err)
+			os.Exit(exitInput)
+		}
+		if err := checkWavInput(data); err != nil {
+			logger.Error("unsupported input", "input", args[0], "error", err)
+			os.Exit(exitCode(err))
+		}
+		left, right, err := wav2ulaw.SplitStereoToUlaw(data, job.config)
+		if err != nil {
+			logger.Error("error splitting legs", "input", args[0], "error", err)
+			os.Exit(conversionExitCode(err))
+		}
+		for _, leg := range []struct {
+			path string
+			data []byte
+		}{{*leftFile, left}, {*rightFile, right}} {
+			if err := writeOutput(leg.path, leg.data); err != nil {
+				logger.Error("error writing output file", "output", leg.path, "error", err)
+				os.Exit(exitWrite)
+			}
+		}
+		logger.Info("legs split", "input", args[0], "left", *leftFile, "right", *rightFile, "samples", len(left))
+		if talk, err := wav2ulaw.AnalyzeTalk(data); err == nil {
+			logger.Info("talk time", "left_speech", talk.Speech[0], "right_speech", talk.Speech[1], "overlap", talk.Overlap, "longest_silence", talk.LongestSilence)
+		}
+	}
+}
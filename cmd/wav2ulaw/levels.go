@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+	"wav2ulaw"
+)
+
+// levelReport is the sidecar of a converted file written with -levels
+type levelReport struct {
+	// "csv" or "json"
+	format   string
+	interval time.Duration
+}
+
+// levelRow is one interval of a level sidecar. Levels are floored at
+// infoMinDBFS, so dead air stays representable in JSON.
+type levelRow struct {
+	StartSeconds    float64 `json:"start_seconds"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	PeakDBFS        float64 `json:"peak_dbfs"`
+	RMSDBFS         float64 `json:"rms_dbfs"`
+	LUFS            float64 `json:"lufs"`
+}
+
+// path returns the sidecar path of an output file
+func (r *levelReport) path(outputPath string) string {
+	return outputPath + ".levels." + r.format
+}
+
+// write measures the converted file at outputPath, a WAV file or raw u-law,
+// and writes its sidecar
+func (r *levelReport) write(outputPath string, wav bool) error {
+	data, err := readInput(outputPath)
+	if err != nil {
+		return withExitCode(exitWrite, fmt.Errorf("error reading output for the level report: %v", err))
+	}
+	format := wav2ulaw.FormatUlaw
+	if wav {
+		format = wav2ulaw.FormatWAV
+	}
+	windows, err := wav2ulaw.MeasureLevels(data, format, r.interval)
+	if err != nil {
+		return withExitCode(exitDecode, fmt.Errorf("error measuring levels: %v", err))
+	}
+	rows := make([]levelRow, len(windows))
+	for i, w := range windows {
+		rows[i] = levelRow{
+			StartSeconds:    w.Start.Seconds(),
+			DurationSeconds: w.Duration.Seconds(),
+			PeakDBFS:        toDBFS(w.Peak),
+			RMSDBFS:         toDBFS(w.RMS),
+			LUFS:            math.Max(w.Loudness, infoMinDBFS),
+		}
+	}
+
+	var buf bytes.Buffer
+	if r.format == "json" {
+		out, err := json.MarshalIndent(rows, "", "  ")
+		if err != nil {
+			return err
+		}
+		buf.Write(append(out, '\n'))
+	} else {
+		w := csv.NewWriter(&buf)
+		w.Write([]string{"start_seconds", "duration_seconds", "peak_dbfs", "rms_dbfs", "lufs"})
+		for _, row := range rows {
+			w.Write([]string{
+				strconv.FormatFloat(row.StartSeconds, 'f', -1, 64),
+				strconv.FormatFloat(row.DurationSeconds, 'f', -1, 64),
+				strconv.FormatFloat(row.PeakDBFS, 'f', 2, 64),
+				strconv.FormatFloat(row.RMSDBFS, 'f', 2, 64),
+				strconv.FormatFloat(row.LUFS, 'f', 2, 64),
+			})
+		}
+		w.Flush()
+	}
+	if err := writeOutput(r.path(outputPath), buf.Bytes()); err != nil {
+		return withExitCode(exitWrite, fmt.Errorf("error writing level report: %v", err))
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// logFlags select the verbosity, format and destination of diagnostics
+type logFlags struct {
+	verbose    *bool
+	quiet      *bool
+	format     *string
+	file       *string
+	maxSize    *int
+	maxBackups *int
+}
+
+// registerLogFlags defines -v, -q, -log-format and the -log-file flags on fs
+func registerLogFlags(fs *flag.FlagSet) *logFlags {
+	return &logFlags{
+		verbose:    fs.Bool("v", false, "Verbose: also log the detected input format, resampling and stage timings"),
+		quiet:      fs.Bool("q", false, "Quiet: only log errors"),
+		format:     fs.String("log-format", "text", "Log format: text or json"),
+		file:       fs.String("log-file", "", "Append logs to this file instead of writing them to stderr"),
+		maxSize:    fs.Int("log-max-size", 100, "Rotate -log-file when it would grow past this many MiB (0 = never)"),
+		maxBackups: fs.Int("log-max-backups", 5, "Rotated log files kept as <log-file>.1, .2 and so on"),
+	}
+}
+
+// logger builds the logger described by the flags, writing to w unless
+// -log-file names a file
+func (f *logFlags) logger(w io.Writer) (*slog.Logger, error) {
+	if *f.file != "" {
+		if *f.maxSize < 0 || *f.maxBackups < 0 {
+			return nil, fmt.Errorf("invalid log rotation: -log-max-size and -log-max-backups must not be negative")
+		}
+		file, err := openRotatingFile(*f.file, int64(*f.maxSize)<<20, *f.maxBackups)
+		if err != nil {
+			return nil, err
+		}
+		w = file
+	}
+	level := slog.LevelInfo
+	if *f.verbose {
+		level = slog.LevelDebug
+	}
+	if *f.quiet {
+		level = slog.LevelError
+	}
+	options := &slog.HandlerOptions{Level: level}
+
+	switch *f.format {
+	case "text":
+		// Timestamps only add noise when a person reads the output
+		options.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		}
+		return slog.New(slog.NewTextHandler(w, options)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, options)), nil
+	}
+	return nil, fmt.Errorf("invalid log format '%s'. Must be 'text' or 'json'", *f.format)
+}
@@ -4,84 +4,758 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"flag"
 	"fmt"
+	"io"
+	"log/slog"
 	"wav2ulaw"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
 )
 
 func main() {
+	args := os.Args[1:]
+	// Flags without a subcommand run a conversion, as before subcommands existed
+	cmd := commands[0]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") && !isFileArg(args[0]) {
+		if cmd = findCommand(args[0]); cmd == nil {
+			fmt.Fprintf(os.Stderr, "Error: Unknown command '%s'\n", args[0])
+			printCommands(os.Stderr)
+			os.Exit(exitUsage)
+		}
+		args = args[1:]
+	}
+	cmd.run(args)
+}
+
+// isFileArg reports whether a first argument that is not a command names a
+// file, as in sox-style "wav2ulaw in.wav out.ulaw effect..." invocations
+func isFileArg(arg string) bool {
+	if findCommand(arg) != nil {
+		return false
+	}
+	if strings.ContainsAny(arg, "./") {
+		return true
+	}
+	_, err := os.Stat(arg)
+	return err == nil
+}
+
+// convertCommand defines the flags of the "convert" subcommand and returns its implementation
+func convertCommand(fs *flag.FlagSet) func(args []string) {
 	// Define command line flags
-	inputFile := flag.String("input", "", "Input file path")
-	outputFile := flag.String("output", "", "Output file path")
-	mode := flag.String("mode", "wav2ulaw", "Conversion mode: wav2ulaw or ulaw2wav")
-	sampleRate := flag.Uint("sample-rate", 8000, "Sample rate for output WAV file (only for ulaw2wav mode)")
-	lowPass := flag.Float64("low-pass", 3400, "Low-pass filter cutoff frequency in Hz")
-	highPass := flag.Float64("high-pass", 300, "High-pass filter cutoff frequency in Hz")
-	normalize := flag.Float64("normalize", 0.9, "Normalize audio to this peak level (0.0 to 1.0)")
-	compressRatio := flag.Float64("compress-ratio", 2.0, "Compression ratio (1.0 means no compression)")
-	compressThreshold := flag.Float64("compress-threshold", 0.5, "Compression threshold (0.0 to 1.0)")
-	windowSize := flag.Int("window-size", 16, "Resampling window size (larger = better quality but slower)")
-	antiAliasingRatio := flag.Float64("anti-aliasing-ratio", 0.9, "Anti-aliasing filter cutoff ratio (0.0 to 1.0)")
-	antiAliasingType := flag.Int("anti-aliasing-type", int(wav2ulaw.AAButterworth), "Anti-aliasing filter type (0=Simple, 1=Butterworth, 2=Bessel, 3=Chebyshev)")
-	filterOrder := flag.Int("filter-order", 4, "Filter order for Butterworth/Bessel/Chebyshev (2-6)")
-	chebyshevRipple := flag.Float64("chebyshev-ripple", 0.5, "Ripple in dB for Chebyshev filter (0.1-3.0)")
-
-	flag.Parse()
-
-	// Validate input parameters
-	if *inputFile == "" || *outputFile == "" {
-		fmt.Println("Error: Input and output file paths are required")
-		flag.Usage()
-		os.Exit(1)
+	inputFile := fs.String("input", "", "Input file path (- for stdin)")
+	outputFile := fs.String("output", "", "Output file path (- for stdout)")
+	outputDir := fs.String("output-dir", "", "Output directory for batch conversion; -input is then a glob pattern")
+	manifest := fs.String("manifest", "", "CSV or JSON file listing the input, output and per-file settings of a batch (replaces -input and -output)")
+	report := fs.String("report", "", "In batch mode, write a summary of every file to this path (- for stdout)")
+	reportFormat := fs.String("report-format", "", "Format of -report: json or csv (default csv for a .csv path, otherwise json)")
+	outputTemplate := fs.String("output-template", "", "With -output-dir, the output path within it, using {dir}, {name}, {ext}, {rate} and {mode}, e.g. '{dir}/{name}_{rate}hz.{ext}' (default: the input path with the output extension)")
+	recursive := fs.Bool("recursive", false, "With -output-dir, walk -input (a directory, optionally with a file pattern) and mirror its structure")
+	jobs := fs.Int("jobs", 1, "Files converted in parallel in batch mode (-1 = all CPUs)")
+	debugDump := fs.String("debug-dump", "", "Write the audio after each processing stage of wav2ulaw conversions to WAV files in this directory, to find the stage that introduces an artifact")
+	dryRun := fs.Bool("dry-run", false, "Analyze inputs and report the planned stages and output size without writing anything")
+	showProgress := fs.Bool("progress", true, "Show a progress bar with ETA when stderr is a terminal")
+	force := fs.Bool("force", false, "Overwrite output files that already exist")
+	skipExisting := fs.Bool("skip-existing", false, "Skip inputs whose output file already exists, to resume an interrupted batch")
+	levels := fs.String("levels", "", "Write the peak, RMS and loudness of every -levels-interval of each output to a sidecar <output>.levels.csv or .json: csv or json")
+	levelsInterval := fs.Duration("levels-interval", time.Second, "Interval of the -levels sidecar")
+	verifyTelephony := fs.Bool("verify-telephony", false, "Check each u-law output before deployment: plausible G.711, as long as its input at 8 kHz mono, active level from -36 to -6 dBov, no full-scale samples and no digital silence gap over -verify-max-silence; a failing file exits with status 7")
+	verifyMaxSilence := fs.Duration("verify-max-silence", 2*time.Second, "Longest digital silence gap -verify-telephony accepts (0 = not checked)")
+	asJSON := fs.Bool("json", false, "Print the result of a single conversion as a JSON object on stdout: output path, sizes, audio length, sample counts, stages run and warnings, or the error")
+	checkpointInterval := fs.Duration("checkpoint-interval", 0, "Save the progress of a single WAV to u-law conversion to <output>.checkpoint after this much input, so a conversion interrupted by a crash or SIGTERM resumes when run again (0 = off); stages that need the whole signal are not applied")
+	ffmpegCompat := fs.Bool("ffmpeg-compat", false, "Use the raw formats of ffmpeg pipes: headerless u-law (-f mulaw) and 16-bit little-endian PCM (-f s16le) at -sample-rate, or WAV with unknown sizes")
+	stream := fs.Bool("stream", false, "Convert stdin to stdout as the audio arrives, in bounded memory, for live pipelines: a WAV stream to u-law, or u-law to a WAV stream in ulaw2wav mode; stages that need the whole signal are not applied")
+	registerDownloadFlags(fs)
+	convFlags := registerConversionFlags(fs)
+	logFlags := registerLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: wav2ulaw [convert] [flags]")
+		fmt.Fprintln(os.Stderr, "       wav2ulaw [convert] [flags] <input> <output> [effect [args]]...")
+		fmt.Fprintln(os.Stderr, "       wav2ulaw <command> [flags] [args]")
+		fmt.Fprintln(os.Stderr)
+		printCommands(os.Stderr)
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Flags:")
+		fs.PrintDefaults()
 	}
+	return func(args []string) {
+		// Positional files are followed by a sox-style effect chain
+		var effects []string
+		if len(args) > 0 {
+			if len(args) < 2 || *inputFile != "" || *outputFile != "" || *outputDir != "" || *manifest != "" {
+				fmt.Fprintln(os.Stderr, "Error: Input and output files are given either as flags or as the first two arguments, followed by effects")
+				fs.Usage()
+				os.Exit(exitUsage)
+			}
+			*inputFile, *outputFile, effects = args[0], args[1], args[2:]
+		}
+
+		// Validate input parameters
+		if *stream {
+			if (*inputFile != "" && *inputFile != "-") || (*outputFile != "" && *outputFile != "-") || *outputDir != "" || *manifest != "" {
+				fmt.Fprintln(os.Stderr, "Error: -stream reads stdin and writes stdout, so no files can be given")
+				fs.Usage()
+				os.Exit(exitUsage)
+			}
+			*inputFile, *outputFile = "-", "-"
+		}
+		if *manifest != "" {
+			if *inputFile != "" || *outputFile != "" || *outputDir != "" {
+				fmt.Fprintln(os.Stderr, "Error: -manifest lists the files, so -input, -output and -output-dir cannot be given")
+				fs.Usage()
+				os.Exit(exitUsage)
+			}
+		} else if *inputFile == "" || (*outputFile == "") == (*outputDir == "") {
+			fmt.Fprintln(os.Stderr, "Error: Input and either an output file path or -output-dir are required (use - for stdin/stdout)")
+			fs.Usage()
+			os.Exit(exitUsage)
+		}
+
+		// Log lines are routed through the bar so they print above it
+		bar := newProgressBar(os.Stderr, *showProgress && !*logFlags.quiet && !*dryRun)
+		logger, err := logFlags.logger(bar)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		download.logger = logger
+
+		mono, err := applyEffects(convFlags, effects, logger)
+		if err != nil {
+			logger.Error("invalid effect chain", "error", err)
+			os.Exit(exitUsage)
+		}
+		job, err := convFlags.conversion(logger)
+		if err != nil {
+			logger.Error("invalid settings", "error", err)
+			os.Exit(exitUsage)
+		}
+		job.config.ForceMono = job.config.ForceMono || mono
+		job.ffmpegCompat = *ffmpegCompat
+		if job.ffmpegCompat && job.bitDepth == 8 {
+			logger.Error("invalid settings", "error", "-bit-depth 8 writes WAV, not the 16-bit raw PCM of -ffmpeg-compat")
+			os.Exit(exitUsage)
+		}
+		if *levels != "" {
+			switch {
+			case *levels != "csv" && *levels != "json":
+				logger.Error("invalid settings", "error", fmt.Sprintf("invalid -levels '%s': use csv or json", *levels))
+				os.Exit(exitUsage)
+			case *levelsInterval <= 0:
+				logger.Error("invalid settings", "error", "-levels-interval must be positive")
+				os.Exit(exitUsage)
+			case *outputFile == "-":
+				logger.Error("invalid settings", "error", "-levels needs an output file, not stdout")
+				os.Exit(exitUsage)
+			case *ffmpegCompat:
+				logger.Error("invalid settings", "error", "-levels cannot measure the raw PCM of -ffmpeg-compat")
+				os.Exit(exitUsage)
+			}
+			job.levels = &levelReport{format: *levels, interval: *levelsInterval}
+		}
+		if *verifyTelephony {
+			switch {
+			case job.outputExt() != ".ulaw":
+				logger.Error("invalid settings", "error", "-verify-telephony checks u-law output, use wav2ulaw or ulaw2ulaw mode")
+				os.Exit(exitUsage)
+			case *outputFile == "-":
+				logger.Error("invalid settings", "error", "-verify-telephony needs an output file, not stdout")
+				os.Exit(exitUsage)
+			case *verifyMaxSilence < 0:
+				logger.Error("invalid settings", "error", "-verify-max-silence must not be negative")
+				os.Exit(exitUsage)
+			}
+			job.verify = wav2ulaw.DefaultTelephonyLimits()
+			job.verify.MaxSilenceGap = *verifyMaxSilence
+		}
+		if *debugDump != "" && !*dryRun {
+			if err := os.MkdirAll(*debugDump, 0755); err != nil {
+				logger.Error("invalid settings", "error", fmt.Sprintf("error creating debug dump directory: %v", err))
+				os.Exit(exitWrite)
+			}
+			job.debugDump = *debugDump
+		}
+		if *reportFormat == "" {
+			*reportFormat = "json"
+			if strings.EqualFold(filepath.Ext(*report), ".csv") {
+				*reportFormat = "csv"
+			}
+		}
+		if *reportFormat != "csv" && *reportFormat != "json" {
+			logger.Error("invalid settings", "error", fmt.Sprintf("invalid -report-format '%s': use csv or json", *reportFormat))
+			os.Exit(exitUsage)
+		}
+		if *asJSON {
+			switch {
+			case *manifest != "" || *outputDir != "":
+				logger.Error("invalid settings", "error", "-json reports a single conversion, use -report in batch mode")
+				os.Exit(exitUsage)
+			case *outputFile == "-":
+				logger.Error("invalid settings", "error", "-json prints to stdout, so the output must be a file")
+				os.Exit(exitUsage)
+			case *dryRun:
+				logger.Error("invalid settings", "error", "-json and -dry-run are mutually exclusive")
+				os.Exit(exitUsage)
+			}
+		}
+		if *checkpointInterval != 0 {
+			switch {
+			case *checkpointInterval < 0:
+				logger.Error("invalid settings", "error", "-checkpoint-interval must not be negative")
+				os.Exit(exitUsage)
+			case *manifest != "" || *outputDir != "":
+				logger.Error("invalid settings", "error", "-checkpoint-interval applies to a single conversion, not batches")
+				os.Exit(exitUsage)
+			case *inputFile == "-" || *outputFile == "-" || isBlobURL(*inputFile) || isBlobURL(*outputFile):
+				logger.Error("invalid settings", "error", "-checkpoint-interval needs local input and output files")
+				os.Exit(exitUsage)
+			case *dryRun || *ffmpegCompat || *levels != "" || *verifyTelephony || len(effects) > 0:
+				logger.Error("invalid settings", "error", "-checkpoint-interval cannot be combined with -dry-run, -ffmpeg-compat, -levels, -verify-telephony or effects")
+				os.Exit(exitUsage)
+			}
+		}
+		if *stream {
+			switch {
+			case *dryRun || *ffmpegCompat || *levels != "" || *verifyTelephony || *asJSON || *checkpointInterval != 0 || len(effects) > 0:
+				logger.Error("invalid settings", "error", "-stream cannot be combined with -dry-run, -ffmpeg-compat, -levels, -verify-telephony, -json, -checkpoint-interval or effects")
+				os.Exit(exitUsage)
+			case job.preset != "" || (job.mode != "wav2ulaw" && job.mode != "ulaw2wav"):
+				logger.Error("invalid settings", "error", "-stream applies to wav2ulaw and ulaw2wav conversions")
+				os.Exit(exitUsage)
+			}
+		}
+		if *force && *skipExisting {
+			logger.Error("invalid settings", "error", "-force and -skip-existing are mutually exclusive")
+			os.Exit(exitUsage)
+		}
+		overwrite := overwritePolicy{force: *force, skipExisting: *skipExisting}
+		if *jobs < 0 {
+			*jobs = runtime.NumCPU()
+		}
+		batch := batchOptions{recursive: *recursive, jobs: *jobs, dryRun: *dryRun, overwrite: overwrite, report: *report, reportFormat: *reportFormat, progress: bar}
+
+		if *manifest != "" {
+			code, err := runManifest(*manifest, convFlags, job, batch)
+			if err != nil {
+				logger.Error("manifest conversion failed", "manifest", *manifest, "error", err)
+				os.Exit(exitCode(err))
+			}
+			if code != 0 {
+				os.Exit(code)
+			}
+			return
+		}
+
+		if !convFlags.isSet("mode") {
+			if err := job.inferMode(*inputFile, *outputFile); err != nil {
+				logger.Error("invalid settings", "error", err)
+				os.Exit(exitCode(err))
+			}
+		}
+		if job.ffmpegCompat && job.preset == "" && strings.Contains(job.mode, "alaw") {
+			logger.Error("invalid settings", "error", "-ffmpeg-compat applies to u-law conversions")
+			os.Exit(exitUsage)
+		}
+		if *checkpointInterval > 0 && (job.mode != "wav2ulaw" || job.preset != "") {
+			logger.Error("invalid settings", "error", "-checkpoint-interval applies to wav2ulaw conversions")
+			os.Exit(exitUsage)
+		}
+
+		if *outputDir != "" {
+			if *outputTemplate != "" {
+				if err := job.checkOutputTemplate(*outputTemplate); err != nil {
+					logger.Error("invalid settings", "error", err)
+					os.Exit(exitUsage)
+				}
+				job.outputTemplate = *outputTemplate
+			}
+			code, err := runBatch(*inputFile, *outputDir, job, batch)
+			if err != nil {
+				logger.Error("batch conversion failed", "error", err)
+				os.Exit(exitCode(err))
+			}
+			if code != 0 {
+				os.Exit(code)
+			}
+			return
+		}
+
+		if *stream {
+			if err := job.convertStream(); err != nil {
+				logger.Error("conversion failed", "input", "stdin", "error", err)
+				os.Exit(exitCode(err))
+			}
+			return
+		}
+
+		result := &conversionResult{batchResult: batchResult{Input: *inputFile, Output: *outputFile}, Mode: job.mode}
+		fail := func(err error) {
+			logger.Error("conversion failed", "input", *inputFile, "error", err)
+			if *asJSON {
+				result.Status, result.Error, result.ExitCode = "failed", err.Error(), exitCode(err)
+				result.print()
+			}
+			os.Exit(exitCode(err))
+		}
+		if *checkpointInterval > 0 && resuming(*outputFile) {
+			logger.Info("resuming", "output", *outputFile, "checkpoint", checkpointPath(*outputFile))
+		} else if skip, err := overwrite.check(*outputFile); err != nil {
+			fail(err)
+		} else if skip {
+			logger.Info("skipped", "input", *inputFile, "output", *outputFile, "reason", "output exists")
+			if *asJSON {
+				result.Status = "skipped"
+				result.print()
+			}
+			return
+		}
+
+		if *dryRun {
+			if err := job.dryRun(*inputFile, *outputFile); err != nil {
+				logger.Error("dry run failed", "input", *inputFile, "error", err)
+				os.Exit(exitCode(err))
+			}
+			return
+		}
+
+		label := filepath.Base(*inputFile)
+		if *inputFile == "-" {
+			label = "stdin"
+		}
+		job.config.OnProgress = func(fraction float64) {
+			bar.set(label, fraction)
+		}
+
+		summarize := func() {}
+		if *asJSON {
+			job = withStageRecord(job, result)
+			job, summarize = withWarnings(job, &result.batchResult)
+		}
+
+		start := time.Now()
+		if *checkpointInterval > 0 {
+			err = job.convertResumable(*inputFile, *outputFile, *checkpointInterval)
+		} else {
+			err = job.convert(*inputFile, *outputFile)
+		}
+		bar.finish()
+		summarize()
+		result.DurationSeconds = time.Since(start).Seconds()
+		if err != nil {
+			fail(err)
+		}
+
+		logger.Info("conversion completed", "input", *inputFile, "output", *outputFile, "duration", time.Since(start))
+		if *asJSON {
+			result.Status = "converted"
+			result.measure(job)
+			result.print()
+		}
+	}
+}
+
+// conversion holds the settings shared by every file converted in one run
+type conversion struct {
+	mode       string
+	preset     string // WAV to WAV effect preset, empty for plain conversions
+	config     *wav2ulaw.AudioConfig
+	sampleRate uint32
+	// Exact length of ulaw2wav output, 0 for the length of the u-law
+	samples    int
+	// Bits per sample of ulaw2wav output, 8 for unsigned PCM with optional
+	// dither
+	bitDepth   int
+	dither     bool
+	windowSize int
+	// Output path of batch files within the output directory, empty to
+	// mirror the input path
+	outputTemplate string
+	// Directory receiving the audio after each stage, empty for none
+	debugDump string
+	// Stages of ulaw2ulaw reprocessing
+	reprocess wav2ulaw.ReprocessOptions
+	// Output of asr mode
+	asr  wav2ulaw.ASROptions
+	loop loopSettings
+	// Exchange raw PCM and u-law as ffmpeg pipes do
+	ffmpegCompat bool
+	// Sidecar with the levels of each output, nil for none
+	levels *levelReport
+	// Limits each u-law output is verified against, nil for none
+	verify *wav2ulaw.TelephonyLimits
+	logger       *slog.Logger
+}
+
+// loopSettings repeat the u-law side of a conversion to a length
+type loopSettings struct {
+	count       int
+	minDuration time.Duration
+	crossfadeMs float64
+}
+
+// enabled reports whether the settings repeat the audio at all
+func (l loopSettings) enabled() bool {
+	return l.count > 1 || l.minDuration > 0
+}
+
+// convert converts one file according to the preset or mode
+func (c *conversion) convert(inputPath, outputPath string) error {
+	if c.levels != nil {
+		measured := *c
+		measured.levels = nil
+		if err := measured.convert(inputPath, outputPath); err != nil {
+			return err
+		}
+		return c.levels.write(outputPath, c.outputExt() == ".wav")
+	}
+	if c.verify != nil {
+		checked := *c
+		checked.verify = nil
+		if err := checked.convert(inputPath, outputPath); err != nil {
+			return err
+		}
+		return c.verifyOutput(inputPath, outputPath)
+	}
+	if c.debugDump != "" {
+		return c.withStageDump(inputPath).convert(inputPath, outputPath)
+	}
+	if c.config.Deterministic {
+		return c.convertVerified(inputPath, outputPath)
+	}
+	// Stream WAV to u-law conversions so large files run in constant memory
+	if c.preset == "" && c.mode == "wav2ulaw" && (c.config.Tempo == 0 || c.config.Tempo == 1.0) && c.config.Noise == nil && c.config.AutoTrim == nil && !c.config.Reverse && c.config.TargetLoudness == 0 && c.config.OnStageOutput == nil && !c.config.SanityCheck && !c.loop.enabled() && !c.ffmpegCompat && !isBlobURL(inputPath) && !isBlobURL(outputPath) {
+		c.logger.Debug("converting", "input", inputPath, "mode", c.mode, "streaming", true)
+		return convertFileStreaming(inputPath, outputPath, c.config)
+	}
+	c.logger.Debug("converting", "input", inputPath, "mode", c.mode, "preset", c.preset, "streaming", false)
 
 	// Read input file
-	inputData, err := os.ReadFile(*inputFile)
+	inputData, err := readInput(inputPath)
+	if err != nil {
+		return withExitCode(exitInput, fmt.Errorf("error reading input file: %v", err))
+	}
+
+	outputData, err := c.convertData(inputData)
+	if err != nil {
+		return err
+	}
+
+	// Write output file
+	if err := writeOutput(outputPath, outputData); err != nil {
+		return withExitCode(exitWrite, fmt.Errorf("error writing output file: %v", err))
+	}
+	return nil
+}
+
+// convertVerified converts in memory twice, the second time sequentially,
+// and writes the output only when both runs produced the same bytes. The
+// SHA-256 of the output is logged for content-addressed storage.
+func (c *conversion) convertVerified(inputPath, outputPath string) error {
+	c.logger.Debug("converting", "input", inputPath, "mode", c.mode, "preset", c.preset, "deterministic", true)
+	inputData, err := readInput(inputPath)
+	if err != nil {
+		return withExitCode(exitInput, fmt.Errorf("error reading input file: %v", err))
+	}
+
+	outputData, err := c.convertData(inputData)
+	if err != nil {
+		return err
+	}
+	check := *c
+	config := *c.config
+	config.Concurrency = 1
+	config.OnClipping = nil
+	config.OnWarning = nil
+	config.OnProgress = nil
+	config.OnStage = nil
+	config.OnStageMetrics = nil
+	check.config = &config
+	checkData, err := check.convertData(inputData)
 	if err != nil {
-		fmt.Printf("Error reading input file: %v\n", err)
-		os.Exit(1)
+		return err
+	}
+	hash := sha256.Sum256(outputData)
+	if sha256.Sum256(checkData) != hash {
+		return fmt.Errorf("output is not deterministic: two conversions of the same input differ")
 	}
 
+	if err := writeOutput(outputPath, outputData); err != nil {
+		return withExitCode(exitWrite, fmt.Errorf("error writing output file: %v", err))
+	}
+	c.logger.Info("output hash", "output", outputPath, "sha256", hex.EncodeToString(hash[:]))
+	return nil
+}
+
+// convertData converts a whole input file held in memory according to the
+// preset or mode
+func (c *conversion) convertData(inputData []byte) ([]byte, error) {
 	var outputData []byte
+	var err error
 
-	// Process based on mode
-	if *mode == "wav2ulaw" {
-		config := &wav2ulaw.AudioConfig{
-			LowPassCutoff:          *lowPass,
-			HighPassCutoff:         *highPass,
-			NormalizePeak:          *normalize,
-			CompressionRatio:       *compressRatio,
-			CompressionThreshold:   *compressThreshold,
-			ResamplingWindowSize:   *windowSize,
-			AntiAliasingCutoffRatio: *antiAliasingRatio,
-			AntiAliasingType:       wav2ulaw.AntiAliasingType(*antiAliasingType),
-			FilterOrder:            *filterOrder,
-			ChebyshevRipple:       *chebyshevRipple,
-		}
-
-		outputData, err = wav2ulaw.ConvertWavBytesToUlaw(inputData, config)
+	// Process based on preset or mode
+	if c.preset == "" && c.mode == "ulaw2ulaw" {
+		if format, _ := wav2ulaw.DetectFormat(inputData); format == wav2ulaw.FormatWAV {
+			return nil, withExitCode(exitFormat, fmt.Errorf("input is a WAV file, expected raw u-law"))
+		}
+		outputData, err = wav2ulaw.ReprocessUlaw(inputData, &c.reprocess)
+		if err != nil {
+			return nil, withExitCode(conversionExitCode(err), fmt.Errorf("error reprocessing u-law: %v", err))
+		}
+		return c.repeat(outputData), nil
+	}
+	if c.preset == "" && c.mode == "asr" {
+		return c.convertASR(inputData)
+	}
+	if c.preset == "" && strings.Contains(c.mode, "alaw") {
+		return c.convertAlaw(inputData)
+	}
+	if c.ffmpegCompat && c.preset == "" {
+		return c.convertFFmpeg(inputData)
+	}
+	if c.preset == "telephone-fx" {
+		if err := checkWavInput(inputData); err != nil {
+			return nil, err
+		}
+		outputData, err = wav2ulaw.ApplyTelephoneEffect(inputData)
+		if err != nil {
+			return nil, withExitCode(exitDecode, fmt.Errorf("error applying telephone effect: %v", err))
+		}
+	} else if c.mode == "wav2ulaw" {
+		if err := checkWavInput(inputData); err != nil {
+			return nil, err
+		}
+		outputData, err = wav2ulaw.ConvertWavBytesToUlaw(inputData, c.config)
 		if err != nil {
-			fmt.Printf("Error converting WAV to u-law: %v\n", err)
-			os.Exit(1)
+			return nil, withExitCode(conversionExitCode(err), fmt.Errorf("error converting WAV to u-law: %v", err))
 		}
-	} else if *mode == "ulaw2wav" {
-		outputData, err = wav2ulaw.ConvertUlawBytesToWav(inputData, uint32(*sampleRate), *windowSize)
+		outputData = c.repeat(outputData)
+	} else if c.bitDepth == 8 {
+		outputData, err = wav2ulaw.ConvertUlawBytesToWavPCM8(c.standardUlaw(c.repeat(inputData)), c.sampleRate, c.windowSize, c.samples, c.dither)
 		if err != nil {
-			fmt.Printf("Error converting u-law to WAV: %v\n", err)
-			os.Exit(1)
+			return nil, withExitCode(exitDecode, fmt.Errorf("error converting u-law to WAV: %v", err))
 		}
 	} else {
-		fmt.Printf("Error: Invalid mode '%s'. Must be 'wav2ulaw' or 'ulaw2wav'\n", *mode)
-		os.Exit(1)
+		outputData, err = wav2ulaw.ConvertUlawBytesToWavLength(c.standardUlaw(c.repeat(inputData)), c.sampleRate, c.windowSize, c.samples)
+		if err != nil {
+			return nil, withExitCode(exitDecode, fmt.Errorf("error converting u-law to WAV: %v", err))
+		}
 	}
+	return outputData, nil
+}
 
-	// Write output file
-	err = os.WriteFile(*outputFile, outputData, 0644)
+// repeat loops u-law audio in the configured variant according to the loop
+// settings
+func (c *conversion) repeat(data []byte) []byte {
+	if !c.loop.enabled() {
+		return data
+	}
+	variant := c.config.UlawVariant
+	looped := wav2ulaw.LoopUlaw(wav2ulaw.ConvertUlawVariant(data, variant, 0), c.loop.count, c.loop.minDuration, c.loop.crossfadeMs, c.config.FadeShape)
+	c.logger.Debug("looped", "samples", len(data), "repeats", c.loop.count, "output_samples", len(looped))
+	return wav2ulaw.ConvertUlawVariant(looped, 0, variant)
+}
+
+// standardUlaw rewrites u-law input in the configured variant to standard
+// G.711 for decoding
+func (c *conversion) standardUlaw(data []byte) []byte {
+	if c.config.UlawVariant == 0 {
+		return data
+	}
+	return wav2ulaw.ConvertUlawVariant(data, c.config.UlawVariant, 0)
+}
+
+// checkWavInput rejects input that is not a WAV file, classifying the error
+// as an unsupported format rather than a decode failure
+func checkWavInput(data []byte) error {
+	format, err := wav2ulaw.DetectFormat(data)
 	if err != nil {
-		fmt.Printf("Error writing output file: %v\n", err)
-		os.Exit(1)
+		return withExitCode(exitFormat, fmt.Errorf("unsupported input: %v", err))
 	}
+	if format != wav2ulaw.FormatWAV {
+		return withExitCode(exitFormat, fmt.Errorf("unsupported input: expected a WAV file, got %s", format))
+	}
+	return nil
+}
 
-	fmt.Println("Conversion completed successfully")
-} 
\ No newline at end of file
+// inputExt returns the file extension selected by a bare directory in recursive mode
+func (c *conversion) inputExt() string {
+	if c.preset == "" && (c.mode == "ulaw2wav" || c.mode == "ulaw2ulaw" || c.mode == "asr" || c.mode == "ulaw2alaw") {
+		return ".ulaw"
+	}
+	if c.preset == "" && (c.mode == "alaw2wav" || c.mode == "alaw2ulaw") {
+		return ".alaw"
+	}
+	return ".wav"
+}
+
+// outputExt returns the file extension for converted files
+func (c *conversion) outputExt() string {
+	if c.preset == "" && (c.mode == "wav2ulaw" || c.mode == "ulaw2ulaw" || c.mode == "alaw2ulaw") {
+		return ".ulaw"
+	}
+	if c.preset == "" && (c.mode == "wav2alaw" || c.mode == "ulaw2alaw") {
+		return ".alaw"
+	}
+	return ".wav"
+}
+
+// convertFileStreaming converts a WAV file to u-law block by block, writing
+// the output as it is produced instead of holding the whole file in memory
+func convertFileStreaming(inputPath, outputPath string, config *wav2ulaw.AudioConfig) error {
+	var input io.ReadSeeker
+	if isHTTPURL(inputPath) {
+		file, err := downloadFile(inputPath)
+		if err != nil {
+			return withExitCode(exitInput, fmt.Errorf("error reading input file: %v", err))
+		}
+		defer os.Remove(file.Name())
+		defer file.Close()
+		input = file
+	} else if inputPath == "-" {
+		// The WAV decoder needs to seek, so piped input is buffered first
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return withExitCode(exitInput, fmt.Errorf("error reading input file: %v", err))
+		}
+		input = bytes.NewReader(data)
+	} else {
+		file, err := os.Open(inputPath)
+		if err != nil {
+			return withExitCode(exitInput, fmt.Errorf("error reading input file: %v", err))
+		}
+		defer file.Close()
+		input = file
+	}
+
+	// Check the header before creating the output
+	header := make([]byte, 12)
+	n, err := io.ReadFull(input, header)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return withExitCode(exitInput, fmt.Errorf("error reading input file: %v", err))
+	}
+	if err := checkWavInput(header[:n]); err != nil {
+		return err
+	}
+	if _, err := input.Seek(0, io.SeekStart); err != nil {
+		return withExitCode(exitInput, fmt.Errorf("error reading input file: %v", err))
+	}
+
+	output := os.Stdout
+	if outputPath != "-" {
+		var err error
+		output, err = os.Create(outputPath)
+		if err != nil {
+			return withExitCode(exitWrite, fmt.Errorf("error writing output file: %v", err))
+		}
+	}
+	// Write errors surface through the converter, so they are recorded to
+	// tell them apart from decode errors
+	tracked := &errorWriter{w: output}
+	writer := bufio.NewWriter(tracked)
+
+	err = wav2ulaw.ConvertWavStreamToUlaw(input, writer, config)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if tracked.err != nil {
+		err = withExitCode(exitWrite, fmt.Errorf("error writing output file: %v", tracked.err))
+	} else if err != nil {
+		err = withExitCode(conversionExitCode(err), fmt.Errorf("error converting WAV to u-law: %v", err))
+	}
+	if outputPath == "-" {
+		return err
+	}
+	if closeErr := output.Close(); err == nil && closeErr != nil {
+		err = withExitCode(exitWrite, fmt.Errorf("error writing output file: %v", closeErr))
+	}
+	if err != nil {
+		os.Remove(outputPath)
+	}
+	return err
+}
+
+// overwritePolicy decides what happens to output files that already exist
+type overwritePolicy struct {
+	// Replace existing outputs
+	force bool
+	// Leave existing outputs alone and skip their inputs
+	skipExisting bool
+}
+
+// check reports whether the conversion to path should be skipped, or an
+// error when it would overwrite an existing file without -force
+func (p overwritePolicy) check(path string) (bool, error) {
+	if path == "-" || p.force {
+		return false, nil
+	}
+	if isBlobURL(path) {
+		if exists, err := blobExists(path); err != nil {
+			return false, withExitCode(exitWrite, fmt.Errorf("cannot check output '%s': %v", path, err))
+		} else if !exists {
+			return false, nil
+		}
+	} else if _, err := os.Stat(path); err != nil {
+		return false, nil
+	}
+	if p.skipExisting {
+		return true, nil
+	}
+	return false, withExitCode(exitWrite, fmt.Errorf("output file '%s' exists (use -force to overwrite or -skip-existing to skip it)", path))
+}
+
+// errorWriter remembers the first error of the writer it wraps
+type errorWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (e *errorWriter) Write(p []byte) (int, error) {
+	n, err := e.w.Write(p)
+	if err != nil && e.err == nil {
+		e.err = err
+	}
+	return n, err
+}
+
+// readInput reads the whole input file, or stdin when path is "-"
+func readInput(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	if isBlobURL(path) {
+		return readBlob(path)
+	}
+	if isHTTPURL(path) {
+		return readURL(path)
+	}
+	return os.ReadFile(path)
+}
+
+// writeOutput writes data to the output file, or stdout when path is "-"
+func writeOutput(path string, data []byte) error {
+	if path == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	if isHTTPURL(path) {
+		return fmt.Errorf("HTTP(S) URLs are only supported as input")
+	}
+	if isBlobURL(path) {
+		// Objects are replaced whole, so a failed upload leaves nothing behind
+		return writeBlob(path, data)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		// A partial file would be taken for a finished one by -skip-existing
+		os.Remove(path)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// manifestEntry is one file listed in a manifest
+type manifestEntry struct {
+	Input  string `json:"input"`
+	Output string `json:"output"`
+	// Processing settings of this file, keyed by flag name
+	Options map[string]any `json:"options"`
+	// Where the entry was found, for error messages
+	source string
+}
+
+// runManifest converts the files listed in a manifest like runFiles. The
+// whole manifest is validated before any file is converted.
+func runManifest(path string, base *conversionFlags, job *conversion, opts batchOptions) (int, error) {
+	entries, err := readManifest(path)
+	if err != nil {
+		return 0, err
+	}
+	files, err := manifestFiles(entries, base, job)
+	if err != nil {
+		return 0, err
+	}
+	return runFiles(files, job, opts)
+}
+
+// manifestPath resolves a path of a manifest entry against the directory
+// of the manifest. Absolute paths, blob URLs and "-" are kept.
+func manifestPath(dir, path string) string {
+	switch {
+	case path == "-" || filepath.IsAbs(path) || isBlobURL(path):
+		return path
+	case isBlobURL(dir):
+		return joinBlobURL(dir, path)
+	}
+	return filepath.Join(dir, path)
+}
+
+// readManifest parses a CSV or JSON manifest. CSV manifests start with a
+// header naming the input and output columns, every other column being a
+// processing flag; empty cells leave the setting alone. JSON manifests are
+// an array of {"input", "output", "options"} objects. Relative paths are
+// taken from the directory of the manifest.
+func readManifest(path string) ([]manifestEntry, error) {
+	data, err := readInput(path)
+	if err != nil {
+		return nil, withExitCode(exitInput, fmt.Errorf("error reading manifest: %v", err))
+	}
+
+	var entries []manifestEntry
+	trimmed := bytes.TrimSpace(data)
+	if strings.EqualFold(filepath.Ext(path), ".json") || bytes.HasPrefix(trimmed, []byte("[")) {
+		entries, err = parseJSONManifest(data)
+	} else {
+		entries, err = parseCSVManifest(data)
+	}
+	if err != nil {
+		return nil, withExitCode(exitUsage, fmt.Errorf("invalid manifest: %v", err))
+	}
+	if len(entries) == 0 {
+		return nil, withExitCode(exitUsage, fmt.Errorf("manifest lists no files"))
+	}
+
+	dir := "."
+	if isBlobURL(path) {
+		dir = path[:strings.LastIndex(path, "/")]
+	} else if path != "-" {
+		dir = filepath.Dir(path)
+	}
+	outputs := make(map[string]string)
+	for i := range entries {
+		e := &entries[i]
+		if e.Input == "" || e.Output == "" {
+			return nil, withExitCode(exitUsage, fmt.Errorf("invalid manifest: %s: input and output are required", e.source))
+		}
+		e.Input = manifestPath(dir, e.Input)
+		e.Output = manifestPath(dir, e.Output)
+		// Two workers writing one file would interleave their output
+		if previous, ok := outputs[e.Output]; ok {
+			return nil, withExitCode(exitUsage, fmt.Errorf("invalid manifest: %s: output '%s' is also written by %s", e.source, e.Output, previous))
+		}
+		outputs[e.Output] = e.source
+	}
+	return entries, nil
+}
+
+// parseJSONManifest reads an array of manifest entries
+func parseJSONManifest(data []byte) ([]manifestEntry, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var entries []manifestEntry
+	if err := decoder.Decode(&entries); err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		entries[i].source = fmt.Sprintf("entry %d", i+1)
+	}
+	return entries, nil
+}
+
+// parseCSVManifest reads a manifest with a header row. Lines starting with
+// # are comments.
+func parseCSVManifest(data []byte) ([]manifestEntry, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("error reading header: %v", err)
+	}
+	columns := make(map[string]bool)
+	for i, name := range header {
+		header[i] = strings.TrimSpace(name)
+		columns[header[i]] = true
+	}
+	if !columns["input"] || !columns["output"] {
+		return nil, fmt.Errorf("header must name the input and output columns")
+	}
+
+	var entries []manifestEntry
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		e := manifestEntry{Options: map[string]any{}, source: fmt.Sprintf("line %d", line)}
+		for i, value := range record {
+			switch header[i] {
+			case "input":
+				e.Input = value
+			case "output":
+				e.Output = value
+			default:
+				if value != "" {
+					e.Options[header[i]] = value
+				}
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// manifestFiles builds the batch of a manifest. Each entry's options
+// override the settings of the run given by base; the direction of entries
+// that set no mode is inferred as for a single conversion.
+func manifestFiles(entries []manifestEntry, base *conversionFlags, job *conversion) ([]batchFile, error) {
+	files := make([]batchFile, 0, len(entries))
+	for _, e := range entries {
+		entryJob := job
+		if len(e.Options) > 0 || !base.isSet("mode") {
+			var err error
+			if entryJob, err = entryConversion(e, base, job); err != nil {
+				return nil, withExitCode(exitCode(err), fmt.Errorf("%s: %v", e.source, err))
+			}
+		}
+		files = append(files, batchFile{input: e.Input, output: e.Output, job: entryJob})
+	}
+	return files, nil
+}
+
+// entryConversion builds the conversion of one manifest entry
+func entryConversion(e manifestEntry, base *conversionFlags, job *conversion) (*conversion, error) {
+	fs := flag.NewFlagSet("manifest", flag.ContinueOnError)
+	f := registerConversionFlags(fs)
+	known := make(map[string]bool)
+	for _, name := range f.names {
+		known[name] = true
+	}
+
+	keys := make([]string, 0, len(e.Options))
+	for key := range e.Options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !known[key] {
+			return nil, withExitCode(exitUsage, fmt.Errorf("unknown setting '%s'", key))
+		}
+		if err := fs.Set(key, fmt.Sprint(e.Options[key])); err != nil {
+			return nil, withExitCode(exitUsage, fmt.Errorf("invalid value for '%s': %v", key, err))
+		}
+	}
+	f.inherit(base)
+
+	entryJob, err := f.conversion(job.logger)
+	if err != nil {
+		return nil, withExitCode(exitUsage, err)
+	}
+	entryJob.ffmpegCompat = job.ffmpegCompat
+	if !f.isSet("mode") {
+		if err := entryJob.inferMode(e.Input, e.Output); err != nil {
+			return nil, err
+		}
+	}
+	return entryJob, nil
+}
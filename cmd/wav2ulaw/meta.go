@@ -0,0 +1,328 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"wav2ulaw"
+)
+
+// bextField is an editable field of the bext chunk, named bext.<name>
+type bextField struct {
+	name string
+	get  func(b *wav2ulaw.Bext) string
+	set  func(b *wav2ulaw.Bext, value string) error
+}
+
+// bextFields lists the bext fields in chunk order
+var bextFields = []bextField{
+	{"description", func(b *wav2ulaw.Bext) string { return b.Description }, func(b *wav2ulaw.Bext, v string) error { return setBextText(&b.Description, v, 256) }},
+	{"originator", func(b *wav2ulaw.Bext) string { return b.Originator }, func(b *wav2ulaw.Bext, v string) error { return setBextText(&b.Originator, v, 32) }},
+	{"originator-reference", func(b *wav2ulaw.Bext) string { return b.OriginatorReference }, func(b *wav2ulaw.Bext, v string) error { return setBextText(&b.OriginatorReference, v, 32) }},
+	{"origination-date", func(b *wav2ulaw.Bext) string { return b.OriginationDate }, func(b *wav2ulaw.Bext, v string) error { return setBextText(&b.OriginationDate, v, 10) }},
+	{"origination-time", func(b *wav2ulaw.Bext) string { return b.OriginationTime }, func(b *wav2ulaw.Bext, v string) error { return setBextText(&b.OriginationTime, v, 8) }},
+	{"time-reference", func(b *wav2ulaw.Bext) string { return strconv.FormatUint(b.TimeReference, 10) }, func(b *wav2ulaw.Bext, v string) error {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("time-reference must be a sample count")
+		}
+		b.TimeReference = n
+		return nil
+	}},
+	{"coding-history", func(b *wav2ulaw.Bext) string { return b.CodingHistory }, func(b *wav2ulaw.Bext, v string) error { b.CodingHistory = v; return nil }},
+}
+
+// setBextText sets a fixed-size bext text field, rejecting values that do not fit
+func setBextText(field *string, value string, size int) error {
+	if len(value) > size {
+		return fmt.Errorf("value is longer than %d bytes", size)
+	}
+	*field = value
+	return nil
+}
+
+// metaCommand defines the flags of the "meta" subcommand and returns its implementation
+func metaCommand(fs *flag.FlagSet) func(args []string) {
+	asJSON := fs.Bool("json", false, "show: print the metadata as JSON")
+	outputFile := fs.String("output", "", "set: write the tagged file here instead of replacing the input (- for stdout)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: wav2ulaw meta show [-json] <file>...")
+		fmt.Fprintln(os.Stderr, "       wav2ulaw meta set [-output file] <file> key=value...")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Reads and edits the INFO tags and Broadcast Wave (bext) chunk of WAV files.")
+		fmt.Fprintln(os.Stderr, "Keys are INFO chunk IDs (INAM) or their names (title), and bext.<field>.")
+		fmt.Fprintln(os.Stderr, "An empty value removes the tag, and bext= removes the bext chunk.")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "INFO names: "+strings.Join(infoNames(), ", "))
+		fmt.Fprintln(os.Stderr, "bext fields: "+strings.Join(bextFieldNames(), ", "))
+		fs.PrintDefaults()
+	}
+	return func(args []string) {
+		if len(args) == 0 {
+			fs.Usage()
+			os.Exit(exitUsage)
+		}
+		// Flags may also follow the action
+		action := args[0]
+		fs.Parse(args[1:])
+		args = fs.Args()
+
+		switch action {
+		case "show":
+			if len(args) == 0 {
+				fs.Usage()
+				os.Exit(exitUsage)
+			}
+			showMetadata(args, *asJSON)
+		case "set":
+			if len(args) < 2 {
+				fs.Usage()
+				os.Exit(exitUsage)
+			}
+			output := *outputFile
+			if output == "" {
+				output = args[0]
+			}
+			if err := setMetadata(args[0], output, args[1:]); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(exitCode(err))
+			}
+		default:
+			fmt.Fprintf(os.Stderr, "Error: Unknown meta action '%s'\n", action)
+			fs.Usage()
+			os.Exit(exitUsage)
+		}
+	}
+}
+
+// fileMetadata is the JSON form of the metadata of one file
+type fileMetadata struct {
+	File string            `json:"file"`
+	Info map[string]string `json:"info"`
+	Bext map[string]string `json:"bext,omitempty"`
+}
+
+// showMetadata prints the metadata of each file
+func showMetadata(paths []string, asJSON bool) {
+	code := 0
+	var reports []fileMetadata
+	for _, path := range paths {
+		md, err := readMetadata(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading '%s': %v\n", path, err)
+			code = mergeExitCode(code, exitCode(err))
+			continue
+		}
+		report := fileMetadata{File: path, Info: md.Info}
+		if md.Bext != nil {
+			report.Bext = map[string]string{}
+			for _, f := range bextFields {
+				report.Bext[f.name] = f.get(md.Bext)
+			}
+		}
+		reports = append(reports, report)
+	}
+
+	if asJSON {
+		if reports == nil {
+			reports = []fileMetadata{}
+		}
+		out, _ := json.MarshalIndent(reports, "", "  ")
+		os.Stdout.Write(append(out, '\n'))
+	} else {
+		for i, report := range reports {
+			if len(paths) > 1 {
+				if i > 0 {
+					fmt.Println()
+				}
+				fmt.Printf("%s:\n", report.File)
+			}
+			printMetadata(report)
+		}
+	}
+	if code != 0 {
+		os.Exit(code)
+	}
+}
+
+// printMetadata prints the tags of one file, one per line
+func printMetadata(report fileMetadata) {
+	if len(report.Info) == 0 && report.Bext == nil {
+		fmt.Println("(no metadata)")
+		return
+	}
+	ids := make([]string, 0, len(report.Info))
+	for id := range report.Info {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		label := id
+		if name, ok := wav2ulaw.InfoTags[id]; ok {
+			label = fmt.Sprintf("%s (%s)", name, id)
+		}
+		fmt.Printf("%-27s %s\n", label+":", report.Info[id])
+	}
+	if report.Bext != nil {
+		for _, f := range bextFields {
+			value := report.Bext[f.name]
+			if f.name == "coding-history" {
+				value = strings.ReplaceAll(strings.TrimRight(value, "\r\n"), "\r\n", " | ")
+			}
+			fmt.Printf("%-27s %s\n", "bext."+f.name+":", value)
+		}
+	}
+}
+
+// setMetadata applies key=value assignments to the metadata of input and
+// writes the result to output, replacing the file when they are the same
+func setMetadata(input, output string, assignments []string) error {
+	data, err := readInput(input)
+	if err != nil {
+		return withExitCode(exitInput, fmt.Errorf("error reading input file: %v", err))
+	}
+	if err := checkWavInput(data); err != nil {
+		return err
+	}
+	md, err := wav2ulaw.ReadWavMetadata(data)
+	if err != nil {
+		return withExitCode(exitDecode, err)
+	}
+	for _, a := range assignments {
+		key, value, ok := strings.Cut(a, "=")
+		if !ok {
+			return withExitCode(exitUsage, fmt.Errorf("invalid assignment '%s', expected key=value", a))
+		}
+		if err := applyMetadata(md, key, value); err != nil {
+			return withExitCode(exitUsage, fmt.Errorf("%s: %v", key, err))
+		}
+	}
+
+	tagged, err := wav2ulaw.WriteWavMetadata(data, md)
+	if err != nil {
+		return withExitCode(exitDecode, err)
+	}
+	if output == input && output != "-" {
+		err = replaceFile(output, tagged)
+	} else {
+		err = writeOutput(output, tagged)
+	}
+	if err != nil {
+		return withExitCode(exitWrite, fmt.Errorf("error writing output file: %v", err))
+	}
+	return nil
+}
+
+// applyMetadata sets one INFO tag or bext field
+func applyMetadata(md *wav2ulaw.Metadata, key, value string) error {
+	if key == "bext" {
+		if value != "" {
+			return fmt.Errorf("only bext= (removing the chunk) is allowed, set bext.<field> instead")
+		}
+		md.Bext = nil
+		return nil
+	}
+	if field, ok := strings.CutPrefix(key, "bext."); ok {
+		for _, f := range bextFields {
+			if f.name == field {
+				if md.Bext == nil {
+					md.Bext = &wav2ulaw.Bext{Version: 1}
+				}
+				return f.set(md.Bext, value)
+			}
+		}
+		return fmt.Errorf("unknown bext field, expected one of %s", strings.Join(bextFieldNames(), ", "))
+	}
+
+	id := infoID(key)
+	if id == "" {
+		return fmt.Errorf("unknown key, expected an INFO ID such as INAM, one of %s, or bext.<field>", strings.Join(infoNames(), ", "))
+	}
+	if value == "" {
+		delete(md.Info, id)
+	} else {
+		md.Info[id] = value
+	}
+	return nil
+}
+
+// infoID returns the INFO chunk ID named by key, or "" if it names none.
+// Any four-character ID starting with I is accepted as is.
+func infoID(key string) string {
+	for id, name := range wav2ulaw.InfoTags {
+		if strings.EqualFold(key, name) {
+			return id
+		}
+	}
+	if len(key) == 4 && key[0] == 'I' && strings.ToUpper(key) == key {
+		return key
+	}
+	return ""
+}
+
+// infoNames returns the names of the common INFO tags, sorted
+func infoNames() []string {
+	names := make([]string, 0, len(wav2ulaw.InfoTags))
+	for _, name := range wav2ulaw.InfoTags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// bextFieldNames returns the names of the editable bext fields
+func bextFieldNames() []string {
+	names := make([]string, len(bextFields))
+	for i, f := range bextFields {
+		names[i] = f.name
+	}
+	return names
+}
+
+// readMetadata reads the metadata of a WAV file
+func readMetadata(path string) (*wav2ulaw.Metadata, error) {
+	data, err := readInput(path)
+	if err != nil {
+		return nil, withExitCode(exitInput, err)
+	}
+	if err := checkWavInput(data); err != nil {
+		return nil, err
+	}
+	md, err := wav2ulaw.ReadWavMetadata(data)
+	if err != nil {
+		return nil, withExitCode(exitDecode, err)
+	}
+	return md, nil
+}
+
+// replaceFile writes data to a temporary file next to path and renames it
+// over path, so an interrupted write does not destroy the original
+func replaceFile(path string, data []byte) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), mode)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/http"
+	"time"
+	"wav2ulaw"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// serverMetrics are the Prometheus metrics of the file conversions run by
+// "serve", labeled by the API ("grpc" or "http") that received them
+type serverMetrics struct {
+	registry     *prometheus.Registry
+	conversions  *prometheus.CounterVec
+	duration     *prometheus.HistogramVec
+	stages       *prometheus.HistogramVec
+	inputFormats *prometheus.CounterVec
+	bytes        *prometheus.CounterVec
+}
+
+// newServerMetrics registers the conversion metrics, plus the Go runtime and
+// process collectors, on a registry of their own
+func newServerMetrics() *serverMetrics {
+	m := &serverMetrics{
+		registry: prometheus.NewRegistry(),
+		conversions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "wav2ulaw_conversions_total",
+			Help: "File conversions by API, direction and status (ok or error).",
+		}, []string{"api", "direction", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "wav2ulaw_conversion_duration_seconds",
+			Help:    "Duration of file conversions by API and direction.",
+			Buckets: prometheus.ExponentialBuckets(0.001, 2, 16),
+		}, []string{"api", "direction"}),
+		stages: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "wav2ulaw_stage_duration_seconds",
+			Help:    "Duration of the processing stages of WAV to u-law conversions.",
+			Buckets: prometheus.ExponentialBuckets(0.0001, 2, 18),
+		}, []string{"stage"}),
+		inputFormats: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "wav2ulaw_input_formats_total",
+			Help: "Conversion inputs by detected format (WAV, u-law or unknown).",
+		}, []string{"format"}),
+		bytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "wav2ulaw_bytes_total",
+			Help: "Bytes read from conversion inputs and written to outputs, by API.",
+		}, []string{"api", "side"}),
+	}
+	m.registry.MustRegister(
+		m.conversions, m.duration, m.stages, m.inputFormats, m.bytes,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// handler serves the metrics in the Prometheus exposition format
+func (m *serverMetrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// observeInput records an input about to be converted
+func (m *serverMetrics) observeInput(api string, data []byte) {
+	format, _ := wav2ulaw.DetectFormat(data)
+	m.inputFormats.WithLabelValues(format.String()).Inc()
+	m.bytes.WithLabelValues(api, "input").Add(float64(len(data)))
+}
+
+// observeStage records the duration of a processing stage
+func (m *serverMetrics) observeStage(stage string, duration time.Duration) {
+	m.stages.WithLabelValues(stage).Observe(duration.Seconds())
+}
+
+// observeConversion records a finished conversion
+func (m *serverMetrics) observeConversion(api, direction string, duration time.Duration, output []byte, err error) {
+	m.duration.WithLabelValues(api, direction).Observe(duration.Seconds())
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+	m.conversions.WithLabelValues(api, direction, status).Inc()
+	m.bytes.WithLabelValues(api, "output").Add(float64(len(output)))
+}
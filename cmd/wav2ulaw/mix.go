@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"wav2ulaw"
+)
+
+// mixCommand defines the flags of the "mix" subcommand and returns its implementation
+func mixCommand(fs *flag.FlagSet) func(args []string) {
+	outputFile := fs.String("output", "", "Output u-law file path (- for stdout)")
+	gain := fs.Float64("gain", 0, "Gain of the first input in dB")
+	overlayGain := fs.Float64("overlay-gain", 0, "Gain of the overlaid second input in dB, e.g. -18 for background music")
+	offset := fs.Float64("offset", 0, "Delay before the second input starts in milliseconds")
+	convFlags := registerConversionFlags(fs)
+	logFlags := registerLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: wav2ulaw mix [flags] -output out.ulaw <file> <overlay>")
+		fs.PrintDefaults()
+	}
+	return func(args []string) {
+		if *outputFile == "" || len(args) != 2 || *offset < 0 {
+			fs.Usage()
+			os.Exit(exitUsage)
+		}
+		logger, err := logFlags.logger(os.Stderr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		job, err := convFlags.conversion(logger)
+		if err != nil {
+			logger.Error("invalid settings", "error", err)
+			os.Exit(exitUsage)
+		}
+
+		sources := make([]wav2ulaw.MixSource, len(args))
+		for i, path := range args {
+			data, err := readInput(path)
+			if err != nil {
+				logger.Error("error reading input file", "input", path, "error", err)
+				os.Exit(exitInput)
+			}
+			sources[i].Data = data
+		}
+		sources[0].GainDb = *gain
+		sources[1].GainDb = *overlayGain
+		sources[1].OffsetMs = *offset
+
+		output, err := wav2ulaw.MixToUlaw(sources[0], sources[1], job.config)
+		if err != nil {
+			logger.Error("error mixing inputs", "error", err)
+			os.Exit(conversionExitCode(err))
+		}
+		if err := writeOutput(*outputFile, output); err != nil {
+			logger.Error("error writing output file", "error", err)
+			os.Exit(exitWrite)
+		}
+		logger.Info("mix completed", "output", *outputFile, "samples", len(output))
+	}
+}
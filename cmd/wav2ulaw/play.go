@@ -0,0 +1,110 @@
+//go:build audio
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+	"wav2ulaw"
+
+	"github.com/ebitengine/oto/v3"
+	"github.com/go-audio/wav"
+)
+
+// playCommand defines the flags of the "play" subcommand and returns its implementation
+func playCommand(fs *flag.FlagSet) func(args []string) {
+	deviceRate := fs.Int("device-rate", 48000, "Sample rate u-law is upsampled to for the audio device")
+	windowSize := fs.Int("window-size", 16, "Resampling window size used when upsampling u-law")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: wav2ulaw play [flags] <file> (- for stdin)")
+		fs.PrintDefaults()
+	}
+	return func(args []string) {
+		if len(args) != 1 {
+			fs.Usage()
+			os.Exit(exitUsage)
+		}
+
+		data, err := readInput(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading input file: %v\n", err)
+			os.Exit(exitInput)
+		}
+		pcm, sampleRate, channels, err := decodeForPlayback(data, *deviceRate, *windowSize)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+
+		if err := playPCM(pcm, sampleRate, channels); err != nil {
+			fmt.Fprintf(os.Stderr, "Error playing audio: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// decodeForPlayback converts WAV or u-law input to interleaved 16-bit
+// little-endian PCM. u-law is upsampled to deviceRate, which audio devices
+// support more widely than 8 kHz.
+func decodeForPlayback(data []byte, deviceRate, windowSize int) ([]byte, int, int, error) {
+	format, err := wav2ulaw.DetectFormat(data)
+	if err != nil {
+		return nil, 0, 0, withExitCode(exitFormat, err)
+	}
+	if format == wav2ulaw.FormatUlaw {
+		if data, err = wav2ulaw.ConvertUlawBytesToWav(data, uint32(deviceRate), windowSize); err != nil {
+			return nil, 0, 0, withExitCode(exitDecode, fmt.Errorf("error decoding u-law: %v", err))
+		}
+	}
+
+	decoder := wav.NewDecoder(bytes.NewReader(data))
+	buf, err := decoder.FullPCMBuffer()
+	if err != nil {
+		return nil, 0, 0, withExitCode(exitDecode, fmt.Errorf("error reading WAV data: %v", err))
+	}
+	if buf.Format == nil || buf.Format.NumChannels < 1 {
+		return nil, 0, 0, withExitCode(exitDecode, fmt.Errorf("error reading WAV format"))
+	}
+
+	shift := 16 - buf.SourceBitDepth
+	pcm := make([]byte, 2*len(buf.Data))
+	for i, v := range buf.Data {
+		var sample int
+		switch {
+		case buf.SourceBitDepth == 8:
+			// 8-bit WAV is unsigned
+			sample = (v - 128) << 8
+		case shift >= 0:
+			sample = v << shift
+		default:
+			sample = v >> -shift
+		}
+		pcm[2*i] = byte(sample)
+		pcm[2*i+1] = byte(sample >> 8)
+	}
+	return pcm, buf.Format.SampleRate, buf.Format.NumChannels, nil
+}
+
+// playPCM plays 16-bit PCM through the default output device and waits until it ends
+func playPCM(pcm []byte, sampleRate, channels int) error {
+	ctx, ready, err := oto.NewContext(&oto.NewContextOptions{
+		SampleRate:   sampleRate,
+		ChannelCount: channels,
+		Format:       oto.FormatSignedInt16LE,
+	})
+	if err != nil {
+		return err
+	}
+	<-ready
+
+	player := ctx.NewPlayer(bytes.NewReader(pcm))
+	defer player.Close()
+	player.Play()
+	for player.IsPlaying() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	return player.Err()
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Conversions finishing sooner than this never show a bar, avoiding flicker
+	progressDelay = 500 * time.Millisecond
+	// Minimum time between redraws
+	progressInterval = 100 * time.Millisecond
+	// Width of the bar itself in characters
+	progressWidth = 30
+)
+
+// progressBar draws a single-line progress bar with an ETA on a terminal.
+// Log output written through it is printed above the bar, so the logger and
+// the bar can share stderr. When out is not a terminal the bar stays hidden
+// and writes pass straight through.
+type progressBar struct {
+	mu       sync.Mutex
+	out      *os.File
+	enabled  bool
+	start    time.Time
+	lastDraw time.Time
+	label    string
+	fraction float64
+	drawn    bool
+}
+
+// newProgressBar returns a bar drawing on out if enabled and out is a terminal
+func newProgressBar(out *os.File, enabled bool) *progressBar {
+	return &progressBar{out: out, enabled: enabled && isTerminal(out), start: time.Now()}
+}
+
+// isTerminal reports whether f is a character device such as a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Write prints p above the bar
+func (b *progressBar) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.clear()
+	n, err := b.out.Write(p)
+	if b.drawn {
+		b.draw()
+	}
+	return n, err
+}
+
+// set updates the label and completed fraction (0 to 1) and redraws
+func (b *progressBar) set(label string, fraction float64) {
+	if b == nil || !b.enabled {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.label, b.fraction = label, fraction
+
+	now := time.Now()
+	if now.Sub(b.start) < progressDelay || (now.Sub(b.lastDraw) < progressInterval && fraction < 1) {
+		return
+	}
+	b.lastDraw = now
+	b.draw()
+}
+
+// finish removes the bar from the terminal
+func (b *progressBar) finish() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.clear()
+	b.drawn = false
+}
+
+// clear erases the bar line if it is shown
+func (b *progressBar) clear() {
+	if b.drawn {
+		fmt.Fprint(b.out, "\r\x1b[K")
+	}
+}
+
+// draw renders the bar on the current line
+func (b *progressBar) draw() {
+	filled := int(b.fraction * progressWidth)
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressWidth-filled)
+
+	eta := "--:--"
+	if b.fraction > 0 {
+		elapsed := time.Since(b.start)
+		remaining := time.Duration(float64(elapsed) * (1 - b.fraction) / b.fraction)
+		eta = formatETA(remaining)
+	}
+	fmt.Fprintf(b.out, "\r%s [%s] %3.0f%% ETA %s\x1b[K", b.label, bar, 100*b.fraction, eta)
+	b.drawn = true
+}
+
+// formatETA formats a remaining duration as m:ss, or h:mm:ss when long
+func formatETA(d time.Duration) string {
+	s := int(d.Round(time.Second).Seconds())
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
@@ -0,0 +1,117 @@
+//go:build audio
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+	"wav2ulaw"
+
+	"github.com/gen2brain/malgo"
+)
+
+// recordCommand defines the flags of the "record" subcommand and returns its implementation
+func recordCommand(fs *flag.FlagSet) func(args []string) {
+	outputFile := fs.String("output", "", "Output u-law file path (- for stdout)")
+	duration := fs.Duration("duration", 10*time.Second, "Recording length (Ctrl-C stops early)")
+	captureRate := fs.Int("capture-rate", 16000, "Sample rate requested from the input device")
+	convFlags := registerConversionFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: wav2ulaw record [flags] -output out.ulaw")
+		fs.PrintDefaults()
+	}
+	return func(args []string) {
+		if *outputFile == "" {
+			fs.Usage()
+			os.Exit(exitUsage)
+		}
+		job, err := convFlags.conversion(slog.Default())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+
+		fmt.Fprintf(os.Stderr, "Recording for %v, press Ctrl-C to stop...\n", *duration)
+		samples, err := captureMono(*captureRate, *duration)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error recording audio: %v\n", err)
+			os.Exit(1)
+		}
+
+		output, err := wav2ulaw.ConvertPCM16ToUlaw(samples, *captureRate, job.config)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error converting to u-law: %v\n", err)
+			os.Exit(exitDecode)
+		}
+		if err := writeOutput(*outputFile, output); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing output file: %v\n", err)
+			os.Exit(exitWrite)
+		}
+		fmt.Fprintf(os.Stderr, "Recorded %.1f s\n", float64(len(output))/8000)
+	}
+}
+
+// captureMono records 16-bit mono samples from the default input device
+// until duration has passed or the process is interrupted
+func captureMono(sampleRate int, duration time.Duration) ([]int16, error) {
+	ctx, err := malgo.InitContext(nil, malgo.ContextConfig{}, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = ctx.Uninit()
+		ctx.Free()
+	}()
+
+	config := malgo.DefaultDeviceConfig(malgo.Capture)
+	config.Capture.Format = malgo.FormatS16
+	config.Capture.Channels = 1
+	config.SampleRate = uint32(sampleRate)
+
+	var mu sync.Mutex
+	var samples []int16
+	limit := int(duration.Seconds() * float64(sampleRate))
+	full := make(chan struct{})
+	onData := func(_, input []byte, frames uint32) {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(samples) >= limit {
+			return
+		}
+		for i := 0; i+1 < len(input) && len(samples) < limit; i += 2 {
+			samples = append(samples, int16(uint16(input[i])|uint16(input[i+1])<<8))
+		}
+		if len(samples) >= limit {
+			close(full)
+		}
+	}
+
+	device, err := malgo.InitDevice(ctx.Context, config, malgo.DeviceCallbacks{Data: onData})
+	if err != nil {
+		return nil, err
+	}
+	defer device.Uninit()
+	if err := device.Start(); err != nil {
+		return nil, err
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	select {
+	case <-full:
+	case <-interrupt:
+	}
+	if err := device.Stop(); err != nil {
+		return nil, err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	return samples, nil
+}
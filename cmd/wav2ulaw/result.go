@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"wav2ulaw"
+)
+
+// conversionResult is the object printed by -json for a single conversion:
+// the fields of a batch report entry plus the processing stages run
+type conversionResult struct {
+	batchResult
+	Mode string `json:"mode"`
+	// Samples entering the first stage and leaving the last (per channel
+	// when the channels are mixed down)
+	InputSamples  int `json:"input_samples,omitempty"`
+	OutputSamples int `json:"output_samples,omitempty"`
+	// Stages in the order they finished
+	Stages []string `json:"stages,omitempty"`
+}
+
+// withStageRecord returns job also recording the stages it runs into result
+func withStageRecord(job *conversion, result *conversionResult) *conversion {
+	var mu sync.Mutex
+	recorded := *job
+	config := *job.config
+	onStageMetrics := config.OnStageMetrics
+	config.OnStageMetrics = func(m wav2ulaw.StageMetrics) {
+		if onStageMetrics != nil {
+			onStageMetrics(m)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if len(result.Stages) == 0 {
+			result.InputSamples = m.InputSamples
+		}
+		result.Stages = append(result.Stages, m.Stage)
+		result.OutputSamples = m.OutputSamples
+	}
+	recorded.config = &config
+	return &recorded
+}
+
+// print writes the result to stdout as one line of JSON
+func (r *conversionResult) print() {
+	out, _ := json.Marshal(r)
+	os.Stdout.Write(append(out, '\n'))
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	"wav2ulaw"
+)
+
+// checkpointPath is where a resumable conversion to output saves its progress
+func checkpointPath(output string) string {
+	return output + ".checkpoint"
+}
+
+// resuming reports whether an interrupted resumable conversion to output left
+// a checkpoint, so the partial output is continued rather than refused
+func resuming(output string) bool {
+	_, err := os.Stat(checkpointPath(output))
+	return err == nil
+}
+
+// convertResumable converts a WAV file to u-law, saving a checkpoint every
+// interval of input. SIGINT and SIGTERM, as sent before a spot instance is
+// reclaimed, save a checkpoint and stop; running the same command again
+// resumes from it.
+func (c *conversion) convertResumable(inputPath, outputPath string, interval time.Duration) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	from, err := wav2ulaw.ConvertWavFileResumable(ctx, inputPath, outputPath, c.config, &wav2ulaw.CheckpointOptions{Path: checkpointPath(outputPath), Interval: interval})
+	if from > 0 {
+		c.logger.Info("resumed from checkpoint", "input", inputPath, "position", from)
+	}
+	if ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+		return withExitCode(exitFailure, fmt.Errorf("interrupted, run again to resume from %s", checkpointPath(outputPath)))
+	}
+	if err != nil {
+		return withExitCode(conversionExitCode(err), err)
+	}
+	return nil
+}
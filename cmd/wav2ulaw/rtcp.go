@@ -0,0 +1,148 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+	"wav2ulaw"
+)
+
+// rtcpSender counts the RTP packets written through it, reports them in
+// periodic RTCP sender reports and logs the receiver reports sent back
+type rtcpSender struct {
+	// Destination of the RTP packets
+	io.Writer
+	conn   net.Conn
+	ssrc   uint32
+	cname  string
+	logger *slog.Logger
+
+	mu      sync.Mutex
+	packets uint32
+	octets  uint32
+	// RTP timestamp and send time of the last packet
+	timestamp uint32
+	sentAt    time.Time
+}
+
+// newRTCPSender opens the RTCP connection to the port after the RTP port
+// of dest, as RFC 3550 pairs them
+func newRTCPSender(rtp io.Writer, dest string, ssrc uint32, logger *slog.Logger) (*rtcpSender, error) {
+	host, port, err := net.SplitHostPort(dest)
+	if err != nil {
+		return nil, err
+	}
+	number, err := strconv.Atoi(port)
+	if err != nil {
+		return nil, fmt.Errorf("RTCP needs a numeric port, got '%s'", port)
+	}
+	conn, err := net.Dial("udp", net.JoinHostPort(host, strconv.Itoa(number+1)))
+	if err != nil {
+		return nil, err
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+	return &rtcpSender{Writer: rtp, conn: conn, ssrc: ssrc, cname: "wav2ulaw@" + hostname, logger: logger}, nil
+}
+
+// Write sends an RTP packet and counts it for the sender reports
+func (s *rtcpSender) Write(packet []byte) (int, error) {
+	n, err := s.Writer.Write(packet)
+	if err != nil {
+		return n, err
+	}
+	parsed, err := wav2ulaw.ParseRTPPacket(packet)
+	if err != nil {
+		return n, nil
+	}
+	s.mu.Lock()
+	s.packets++
+	s.octets += uint32(len(parsed.Payload))
+	s.timestamp = parsed.Timestamp
+	s.sentAt = time.Now()
+	s.mu.Unlock()
+	return n, nil
+}
+
+// report returns the sender report for now, or nil before the first packet.
+// The RTP timestamp is extrapolated from the last packet at 8 kHz.
+func (s *rtcpSender) report() *wav2ulaw.RTCPSenderReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.packets == 0 {
+		return nil
+	}
+	now := time.Now()
+	return &wav2ulaw.RTCPSenderReport{
+		SSRC:         s.ssrc,
+		NTPTime:      now,
+		RTPTimestamp: s.timestamp + uint32(now.Sub(s.sentAt)*8000/time.Second),
+		PacketCount:  s.packets,
+		OctetCount:   s.octets,
+	}
+}
+
+// run sends a sender report every interval and logs the receiver reports
+// arriving until done is closed
+func (s *rtcpSender) run(interval time.Duration, done <-chan struct{}) {
+	go s.receive()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if report := s.report(); report != nil {
+				if _, err := s.conn.Write(report.Marshal(s.cname)); err != nil {
+					s.logger.Warn("error sending RTCP", "error", err)
+				}
+			}
+		}
+	}
+}
+
+// receive logs the reception reports about the stream until the connection closes
+func (s *rtcpSender) receive() {
+	buf := make([]byte, 1500)
+	for {
+		n, err := s.conn.Read(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				// Typically ICMP port unreachable: nothing listens for RTCP
+				s.logger.Debug("RTCP receive failed", "error", err)
+				continue
+			}
+			return
+		}
+		blocks, err := wav2ulaw.ParseRTCPReports(buf[:n])
+		if err != nil {
+			s.logger.Debug("invalid RTCP packet", "error", err)
+			continue
+		}
+		for _, b := range blocks {
+			if b.SSRC != s.ssrc {
+				continue
+			}
+			s.logger.Info("receiver report", "reporter", fmt.Sprintf("0x%08x", b.Reporter),
+				"loss", fmt.Sprintf("%.1f%%", b.Loss()*100), "lost", b.CumulativeLost,
+				"jitter", time.Duration(b.Jitter)*time.Second/8000, "rtt", b.RoundTrip(time.Now()))
+		}
+	}
+}
+
+// close sends a final sender report with a BYE and closes the connection
+func (s *rtcpSender) close() {
+	if report := s.report(); report != nil {
+		s.conn.Write(wav2ulaw.MarshalRTCPBye(report, s.cname))
+	}
+	s.conn.Close()
+}
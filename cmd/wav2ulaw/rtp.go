@@ -0,0 +1,167 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"time"
+	"wav2ulaw"
+)
+
+// rtpCommand defines the flags of the "rtp" subcommand and returns its implementation
+func rtpCommand(fs *flag.FlagSet) func(args []string) {
+	dest := fs.String("dest", "", "Destination address of the RTP stream, e.g. 10.0.0.5:4000")
+	ptime := fs.Int("ptime", 20, "Audio per packet in milliseconds")
+	ssrc := fs.Uint("ssrc", 0, "RTP synchronization source identifier (0 = random)")
+	payloadType := fs.Uint("payload-type", wav2ulaw.RTPPayloadTypePCMU, "RTP payload type, for SBCs mapping u-law to a dynamic type")
+	sequence := fs.Int("initial-seq", -1, "Sequence number of the first packet (-1 = random)")
+	timestamp := fs.Int64("initial-timestamp", -1, "RTP timestamp of the first packet (-1 = random)")
+	rtcpInterval := fs.Duration("rtcp-interval", 5*time.Second, "Interval of RTCP sender reports, sent to the port after -dest's (0 = no RTCP)")
+	marker := fs.String("marker", "first", "Packets with the marker bit: first, talkspurt (first with sound after silence) or never")
+	convFlags := registerConversionFlags(fs)
+	logFlags := registerLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: wav2ulaw rtp -dest host:port [flags] <file> (- for stdin)")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Sends the file as PCMU RTP packets at real-time pace.")
+		fmt.Fprintln(os.Stderr, "WAV input is converted first, u-law input is sent as is.")
+		fs.PrintDefaults()
+	}
+	return func(args []string) {
+		if *dest == "" || len(args) != 1 {
+			fs.Usage()
+			os.Exit(exitUsage)
+		}
+		logger, err := logFlags.logger(os.Stderr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		if *ssrc > 0xFFFFFFFF {
+			logger.Error("invalid settings", "error", fmt.Sprintf("SSRC %d does not fit in 32 bits", *ssrc))
+			os.Exit(exitUsage)
+		}
+		id := uint32(*ssrc)
+		if id == 0 {
+			id = rand.Uint32()
+		}
+		opts := wav2ulaw.RTPOptions{PtimeMs: *ptime, SSRC: id}
+		if err := setRTPOptions(&opts, *payloadType, *sequence, *timestamp, *marker); err != nil {
+			logger.Error("invalid settings", "error", err)
+			os.Exit(exitUsage)
+		}
+		packetizer, err := wav2ulaw.NewRTPPacketizerWithOptions(opts)
+		if err != nil {
+			logger.Error("invalid settings", "error", err)
+			os.Exit(exitUsage)
+		}
+		job, err := convFlags.conversion(logger)
+		if err != nil {
+			logger.Error("invalid settings", "error", err)
+			os.Exit(exitUsage)
+		}
+
+		data, err := readUlawInput(args[0], job)
+		if err != nil {
+			logger.Error("error reading input file", "input", args[0], "error", err)
+			os.Exit(exitCode(err))
+		}
+
+		conn, err := net.Dial("udp", *dest)
+		if err != nil {
+			logger.Error("cannot reach destination", "dest", *dest, "error", err)
+			os.Exit(1)
+		}
+		defer conn.Close()
+
+		var rtp io.Writer = conn
+		if *rtcpInterval > 0 {
+			rtcp, err := newRTCPSender(conn, *dest, id, logger)
+			if err != nil {
+				logger.Error("cannot reach RTCP destination", "dest", *dest, "error", err)
+				os.Exit(1)
+			}
+			done := make(chan struct{})
+			go rtcp.run(*rtcpInterval, done)
+			defer rtcp.close()
+			defer close(done)
+			rtp = rtcp
+		}
+
+		packets := packetizer.Packetize(data)
+		logger.Info("sending", "dest", conn.RemoteAddr().String(), "ssrc", fmt.Sprintf("0x%08x", id), "packets", len(packets), "duration", time.Duration(len(data))*time.Second/8000)
+		if err := sendPaced(rtp, packets, time.Duration(*ptime)*time.Millisecond); err != nil {
+			logger.Error("error sending RTP", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("stream completed", "packets", len(packets))
+	}
+}
+
+// setRTPOptions checks the header flags of the rtp command and sets them in opts
+func setRTPOptions(opts *wav2ulaw.RTPOptions, payloadType uint, sequence int, timestamp int64, marker string) error {
+	if payloadType > 127 {
+		return fmt.Errorf("payload type %d must be between 0 and 127", payloadType)
+	}
+	opts.PayloadType = uint8(payloadType)
+	if sequence < -1 || sequence > 0xFFFF {
+		return fmt.Errorf("initial sequence number %d does not fit in 16 bits", sequence)
+	}
+	if sequence >= 0 {
+		seq := uint16(sequence)
+		opts.Sequence = &seq
+	}
+	if timestamp < -1 || timestamp > 0xFFFFFFFF {
+		return fmt.Errorf("initial timestamp %d does not fit in 32 bits", timestamp)
+	}
+	if timestamp >= 0 {
+		ts := uint32(timestamp)
+		opts.Timestamp = &ts
+	}
+	switch marker {
+	case "first":
+		opts.Marker = wav2ulaw.RTPMarkerFirst
+	case "talkspurt":
+		opts.Marker = wav2ulaw.RTPMarkerTalkspurt
+	case "never":
+		opts.Marker = wav2ulaw.RTPMarkerNever
+	default:
+		return fmt.Errorf("unknown marker mode '%s', expected first, talkspurt or never", marker)
+	}
+	return nil
+}
+
+// readUlawInput reads a file as u-law, converting WAV input with the
+// conversion's settings
+func readUlawInput(path string, job *conversion) ([]byte, error) {
+	data, err := readInput(path)
+	if err != nil {
+		return nil, withExitCode(exitInput, err)
+	}
+	format, err := wav2ulaw.DetectFormat(data)
+	if err != nil {
+		return nil, withExitCode(exitFormat, err)
+	}
+	if format == wav2ulaw.FormatWAV {
+		if data, err = wav2ulaw.ConvertWavBytesToUlaw(data, job.config); err != nil {
+			return nil, withExitCode(exitDecode, fmt.Errorf("error converting WAV to u-law: %v", err))
+		}
+	}
+	return data, nil
+}
+
+// sendPaced writes one packet per interval. Send times are scheduled from
+// the start so timer jitter does not accumulate into drift.
+func sendPaced(w io.Writer, packets [][]byte, interval time.Duration) error {
+	start := time.Now()
+	for i, packet := range packets {
+		time.Sleep(time.Until(start.Add(time.Duration(i) * interval)))
+		if _, err := w.Write(packet); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"wav2ulaw"
+)
+
+// segmentCommand defines the flags of the "segment" subcommand and returns its implementation
+func segmentCommand(fs *flag.FlagSet) func(args []string) {
+	length := fs.Duration("length", 0, "Length of each segment, e.g. 10m")
+	onSilence := fs.Bool("on-silence", false, "Cut at the pauses between utterances instead of every -length, leaving out the silence before the first and after the last")
+	threshold := fs.Float64("silence-threshold", -40, "With -on-silence, RMS level in dBFS below which audio is silence")
+	minSilence := fs.Duration("min-silence", 500*time.Millisecond, "With -on-silence, shortest pause that ends an utterance")
+	padding := fs.Duration("padding", 200*time.Millisecond, "With -on-silence, silence kept before and after each utterance")
+	minLength := fs.Duration("min-length", 0, "With -on-silence, utterances shorter than this are dropped, e.g. 200ms for clicks (0 = keep all)")
+	outputFormat := fs.String("output-format", "wav", "Format of the segments: wav, cut without re-encoding, or ulaw, converted with the processing flags")
+	outputDir := fs.String("output-dir", "", "Directory for the segments, named <name>-001.wav and so on (default: the directory of the input; required for URLs)")
+	timecode := fs.Bool("timecode", false, "Write a bext chunk to each segment with its time reference and origination time offset by its start")
+	start := fs.String("start", "", "With -timecode, the wall-clock time at the start of the input, e.g. 2026-10-15T09:30:00 (default: the bext chunk of the input)")
+	convFlags := registerConversionFlags(fs)
+	logFlags := registerLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: wav2ulaw segment [flags] -length 10m <file.wav>")
+		fmt.Fprintln(os.Stderr, "       wav2ulaw segment [flags] -on-silence <file.wav>")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Cuts a WAV file into consecutive WAV files without re-encoding the audio, or")
+		fmt.Fprintln(os.Stderr, "into one file per utterance, optionally converted to u-law.")
+		fs.PrintDefaults()
+	}
+	return func(args []string) {
+		remote := len(args) == 1 && (isHTTPURL(args[0]) || isBlobURL(args[0]))
+		if (*length <= 0) != *onSilence || len(args) != 1 || args[0] == "-" || (remote && *outputDir == "") || (*start != "" && !*timecode) || (*timecode && *onSilence) {
+			fs.Usage()
+			os.Exit(exitUsage)
+		}
+		logger, err := logFlags.logger(os.Stderr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		if *outputFormat != "wav" && *outputFormat != "ulaw" {
+			logger.Error("invalid settings", "error", fmt.Sprintf("invalid -output-format '%s': use wav or ulaw", *outputFormat))
+			os.Exit(exitUsage)
+		}
+		job, err := convFlags.conversion(logger)
+		if err != nil {
+			logger.Error("invalid settings", "error", err)
+			os.Exit(exitUsage)
+		}
+		options := &wav2ulaw.SegmentOptions{Length: *length, Timecode: *timecode}
+		if *start != "" {
+			if options.Start, err = time.ParseInLocation("2006-01-02T15:04:05", *start, time.Local); err != nil {
+				logger.Error("invalid settings", "error", fmt.Errorf("-start must look like 2026-10-15T09:30:00"))
+				os.Exit(exitUsage)
+			}
+		}
+
+		data, err := readInput(args[0])
+		if err != nil {
+			logger.Error("error reading input file", "input", args[0], "error", err)
+			os.Exit(exitInput)
+		}
+		if err := checkWavInput(data); err != nil {
+			logger.Error("unsupported input", "input", args[0], "error", err)
+			os.Exit(exitCode(err))
+		}
+		var segments [][]byte
+		if *onSilence {
+			var utterances []wav2ulaw.Utterance
+			utterances, err = wav2ulaw.SplitWavOnSilence(data, &wav2ulaw.SilenceSplitOptions{
+				ThresholdDb: *threshold,
+				MinSilence:  *minSilence,
+				Padding:     *padding,
+				MinLength:   *minLength,
+			})
+			for i, u := range utterances {
+				logger.Info("utterance", "segment", i+1, "start", u.Start, "end", u.End)
+				segments = append(segments, u.WAV)
+			}
+		} else {
+			segments, err = wav2ulaw.SplitWavSegments(data, options)
+		}
+		if err != nil {
+			logger.Error("error segmenting input", "input", args[0], "error", err)
+			os.Exit(exitDecode)
+		}
+
+		dir := *outputDir
+		if dir == "" {
+			dir = filepath.Dir(args[0])
+		}
+		name := strings.TrimSuffix(filepath.Base(args[0]), filepath.Ext(args[0]))
+		for i, segment := range segments {
+			if *outputFormat == "ulaw" {
+				if segment, err = wav2ulaw.ConvertWavBytesToUlaw(segment, job.config); err != nil {
+					logger.Error("error converting WAV to u-law", "segment", i+1, "error", err)
+					os.Exit(conversionExitCode(err))
+				}
+			}
+			path := filepath.Join(dir, fmt.Sprintf("%s-%03d.%s", name, i+1, *outputFormat))
+			if err := writeOutput(path, segment); err != nil {
+				logger.Error("error writing output file", "output", path, "error", err)
+				os.Exit(exitWrite)
+			}
+		}
+		logger.Info("input segmented", "input", args[0], "segments", len(segments), "output_dir", dir)
+	}
+}
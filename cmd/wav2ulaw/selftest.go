@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"text/tabwriter"
+	"wav2ulaw"
+)
+
+// selftestCommand defines the flags of the "selftest" subcommand and returns its implementation
+func selftestCommand(fs *flag.FlagSet) func(args []string) {
+	verbose := fs.Bool("v", false, "List every check, not only failures")
+	return func(args []string) {
+		checks, err := wav2ulaw.SelfTest()
+		if err != nil {
+			fmt.Printf("Error running self-test: %v\n", err)
+			os.Exit(1)
+		}
+
+		failed := 0
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, c := range checks {
+			status := "PASS"
+			if !c.Passed {
+				status = "FAIL"
+				failed++
+			} else if !*verbose {
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%7.2f dB\t%s\t%s\n", status, c.GainDb, formatRange(c.MinDb, c.MaxDb), c.Name)
+		}
+		w.Flush()
+
+		fmt.Printf("%d of %d checks passed\n", len(checks)-failed, len(checks))
+		if failed > 0 {
+			os.Exit(1)
+		}
+	}
+}
+
+// formatRange formats an accepted gain range, open below for rejection checks
+func formatRange(minDb, maxDb float64) string {
+	if math.IsInf(minDb, -1) {
+		return fmt.Sprintf("expected <= %.2f dB", maxDb)
+	}
+	return fmt.Sprintf("expected %.2f to %.2f dB", minDb, maxDb)
+}
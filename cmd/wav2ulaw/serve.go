@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+	"wav2ulaw"
+	"wav2ulaw/api"
+
+	"google.golang.org/grpc"
+)
+
+// serveCommand defines the flags of the "serve" subcommand and returns its implementation
+func serveCommand(fs *flag.FlagSet) func(args []string) {
+	grpcAddr := fs.String("grpc", "", "Listen address for the gRPC API, e.g. :9090")
+	httpAddr := fs.String("http", "", "Listen address for the HTTP API, e.g. :8080")
+	maxSize := fs.Int("max-size", 64<<20, "Largest request or response in bytes; conversions whose output would be larger are refused before they run")
+	pprofAddr := fs.String("pprof", "", "Listen address for the net/http/pprof profiling endpoints, e.g. localhost:6060 (empty = disabled)")
+	metricsAddr := fs.String("metrics", "", "Listen address for Prometheus metrics at /metrics, e.g. :9100; may equal -pprof (empty = disabled)")
+	allowOrigin := fs.String("allow-origin", "", "Comma-separated origins besides the server's own whose web pages may open WebSocket streams, e.g. https://phone.example.com, or * for any")
+	otlpEndpoint := fs.String("otlp", "", "OTLP/gRPC collector receiving traces of conversions, e.g. localhost:4317 or http://collector:4317 for plaintext (empty = disabled)")
+	convFlags := registerConversionFlags(fs)
+	logFlags := registerLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: wav2ulaw serve [-grpc :9090] [-http :8080] [flags]")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Conversion flags set the defaults for requests that do not name a preset.")
+		fs.PrintDefaults()
+	}
+	return func(args []string) {
+		if (*grpcAddr == "" && *httpAddr == "") || len(args) != 0 {
+			fs.Usage()
+			os.Exit(exitUsage)
+		}
+		logger, err := logFlags.logger(os.Stderr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		job, err := convFlags.conversion(logger)
+		if err != nil {
+			logger.Error("invalid settings", "error", err)
+			os.Exit(exitUsage)
+		}
+
+		// Design the resampler banks for TTS output and the sinc table of
+		// other rates before the first request
+		wav2ulaw.PrecomputeResampler(job.config, wav2ulaw.TTSSampleRates...)
+		wav2ulaw.PrecomputeResampler(wav2ulaw.TTSFastConfig(), wav2ulaw.TTSSampleRates...)
+		wav2ulaw.PrewarmTables(job.config.ResamplingWindowSize)
+
+		instrumentation := &instrumentation{}
+		if *metricsAddr != "" {
+			instrumentation.metrics = newServerMetrics()
+		}
+		if *otlpEndpoint != "" {
+			provider, err := newTracerProvider(context.Background(), *otlpEndpoint)
+			if err != nil {
+				logger.Error("cannot set up tracing", "error", err)
+				os.Exit(exitUsage)
+			}
+			// Send the spans still buffered when the server stops
+			defer provider.Shutdown(context.Background())
+			instrumentation.tracer = provider.Tracer("wav2ulaw")
+		}
+
+		failed := make(chan error, 4)
+		var grpcServer *grpc.Server
+		if *grpcAddr != "" {
+			listener, err := net.Listen("tcp", *grpcAddr)
+			if err != nil {
+				logger.Error("cannot listen", "addr", *grpcAddr, "error", err)
+				os.Exit(1)
+			}
+			grpcServer = grpc.NewServer(
+				grpc.MaxRecvMsgSize(*maxSize),
+				grpc.MaxSendMsgSize(*maxSize),
+				grpc.UnaryInterceptor(logRPC(logger)),
+				grpc.StreamInterceptor(logStream(logger)),
+			)
+			api.RegisterConverterServer(grpcServer, &converterServer{job: job, maxSize: *maxSize, instrumentation: instrumentation})
+			logger.Info("serving gRPC", "addr", listener.Addr().String())
+			go func() {
+				if err := grpcServer.Serve(listener); err != nil {
+					failed <- fmt.Errorf("gRPC server: %v", err)
+				}
+			}()
+		}
+		var httpServer *http.Server
+		if *httpAddr != "" {
+			listener, err := net.Listen("tcp", *httpAddr)
+			if err != nil {
+				logger.Error("cannot listen", "addr", *httpAddr, "error", err)
+				os.Exit(1)
+			}
+			httpAPI := &httpAPI{defaults: convFlags, logger: logger, maxSize: int64(*maxSize), instrumentation: instrumentation}
+			for _, origin := range strings.Split(*allowOrigin, ",") {
+				if origin = strings.TrimSpace(origin); origin != "" {
+					httpAPI.allowOrigins = append(httpAPI.allowOrigins, origin)
+				}
+			}
+			httpServer = &http.Server{Handler: httpAPI.handler(), ReadHeaderTimeout: 10 * time.Second}
+			logger.Info("serving HTTP", "addr", listener.Addr().String())
+			go func() {
+				if err := httpServer.Serve(listener); err != http.ErrServerClosed {
+					failed <- fmt.Errorf("HTTP server: %v", err)
+				}
+			}()
+		}
+
+		// Admin endpoints, served together when -pprof and -metrics share an address
+		adminMuxes := make(map[string]*http.ServeMux)
+		adminMux := func(addr string) *http.ServeMux {
+			if adminMuxes[addr] == nil {
+				adminMuxes[addr] = http.NewServeMux()
+			}
+			return adminMuxes[addr]
+		}
+		if *pprofAddr != "" {
+			adminMux(*pprofAddr).Handle("/debug/pprof/", pprofHandler())
+		}
+		if instrumentation.metrics != nil {
+			adminMux(*metricsAddr).Handle("/metrics", instrumentation.metrics.handler())
+		}
+		var adminServers []*http.Server
+		for addr, mux := range adminMuxes {
+			listener, err := net.Listen("tcp", addr)
+			if err != nil {
+				logger.Error("cannot listen", "addr", addr, "error", err)
+				os.Exit(1)
+			}
+			// CPU profiles and traces run for their requested duration
+			server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+			adminServers = append(adminServers, server)
+			logger.Info("serving admin endpoints", "addr", listener.Addr().String(), "pprof", addr == *pprofAddr, "metrics", addr == *metricsAddr)
+			go func() {
+				if err := server.Serve(listener); err != http.ErrServerClosed {
+					failed <- fmt.Errorf("admin server: %v", err)
+				}
+			}()
+		}
+
+		// Finish running requests on Ctrl-C
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt)
+		select {
+		case <-interrupt:
+			logger.Info("shutting down")
+		case err := <-failed:
+			logger.Error("server failed", "error", err)
+			os.Exit(1)
+		}
+		if httpServer != nil {
+			httpServer.Shutdown(context.Background())
+		}
+		for _, server := range adminServers {
+			server.Close()
+		}
+		if grpcServer != nil {
+			grpcServer.GracefulStop()
+		}
+	}
+}
+
+// pprofHandler serves the net/http/pprof endpoints under /debug/pprof/. They
+// are registered on their own mux so the API port never exposes them.
+func pprofHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
+
+// withPreset returns a copy of c using a named preset instead of its own
+// processing settings
+func (c *conversion) withPreset(preset string) (*conversion, error) {
+	job := *c
+	if preset == "telephone-fx" {
+		job.preset = preset
+		return &job, nil
+	}
+	presetConfig, ok := configPresets[preset]
+	if !ok {
+		return nil, fmt.Errorf("invalid preset '%s'. Must be 'telephony', 'voicemail', 'tts-narrowband', 'tts-fast', 'raw' or 'telephone-fx'", preset)
+	}
+	config := presetConfig()
+	config.Logger = c.config.Logger
+	config.OnClipping = c.config.OnClipping
+	config.OnStage = c.config.OnStage
+	config.OnStageMetrics = c.config.OnStageMetrics
+	config.Limits = c.config.Limits
+	job.config = config
+	job.preset = ""
+	return &job, nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"wav2ulaw"
+)
+
+// spectrogramCommand defines the flags of the "spectrogram" subcommand and returns its implementation
+func spectrogramCommand(fs *flag.FlagSet) func(args []string) {
+	inputFile := fs.String("input", "", "Input WAV or u-law file path")
+	outputFile := fs.String("output", "", "Output PNG file path")
+	format := fs.String("format", "", "Input format: wav or ulaw (default: inferred from extension)")
+	fftSize := fs.Int("fft-size", 512, "FFT size (power of two, frequency resolution)")
+	hopSize := fs.Int("hop-size", 128, "Samples between columns (time resolution)")
+	minDb := fs.Float64("min-db", -100, "Level shown as black in dBFS, negative")
+	return func(args []string) {
+		if *inputFile == "" || *outputFile == "" {
+			fmt.Println("Error: Input and output file paths are required")
+			fs.Usage()
+			os.Exit(exitUsage)
+		}
+
+		inputData, err := os.ReadFile(*inputFile)
+		if err != nil {
+			fmt.Printf("Error reading input file: %v\n", err)
+			os.Exit(exitInput)
+		}
+
+		if *format == "" {
+			*format = "ulaw"
+			if strings.EqualFold(filepath.Ext(*inputFile), ".wav") {
+				*format = "wav"
+			}
+		}
+
+		opts := wav2ulaw.DefaultSpectrogramOptions()
+		opts.FFTSize = *fftSize
+		opts.HopSize = *hopSize
+		opts.MinDb = *minDb
+
+		var pngData []byte
+		switch *format {
+		case "wav":
+			pngData, err = wav2ulaw.WavSpectrogram(inputData, opts)
+		case "ulaw":
+			pngData, err = wav2ulaw.UlawSpectrogram(inputData, opts)
+		default:
+			fmt.Printf("Error: Invalid format '%s'. Must be 'wav' or 'ulaw'\n", *format)
+			os.Exit(exitUsage)
+		}
+		if err != nil {
+			fmt.Printf("Error rendering spectrogram: %v\n", err)
+			os.Exit(exitDecode)
+		}
+
+		if err := os.WriteFile(*outputFile, pngData, 0644); err != nil {
+			fmt.Printf("Error writing output file: %v\n", err)
+			os.Exit(exitWrite)
+		}
+
+		fmt.Println("Spectrogram written successfully")
+	}
+}
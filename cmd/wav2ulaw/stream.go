@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"wav2ulaw"
+)
+
+// convertStream converts stdin to stdout as audio arrives for -stream:
+// a WAV stream to headerless u-law, or u-law to a WAV stream of unknown
+// length. Stages that need the whole signal are not applied.
+func (c *conversion) convertStream() error {
+	if c.mode == "ulaw2wav" {
+		d, err := wav2ulaw.NewWavDecoder(&ulawVariantReader{r: os.Stdin, c: c}, int(c.sampleRate), c.windowSize)
+		if err != nil {
+			return withExitCode(exitUsage, err)
+		}
+		if _, err := io.Copy(os.Stdout, d); err != nil {
+			return withExitCode(exitWrite, fmt.Errorf("error streaming WAV: %v", err))
+		}
+		return nil
+	}
+
+	e := wav2ulaw.NewUlawEncoder(os.Stdout, c.config)
+	if _, err := io.Copy(e, os.Stdin); err != nil {
+		return withExitCode(conversionExitCode(err), fmt.Errorf("error streaming u-law: %v", err))
+	}
+	if err := e.Close(); err != nil {
+		return withExitCode(conversionExitCode(err), fmt.Errorf("error streaming u-law: %v", err))
+	}
+	return nil
+}
+
+// ulawVariantReader turns the u-law variant of -ulaw-variant read from r
+// back into standard u-law
+type ulawVariantReader struct {
+	r io.Reader
+	c *conversion
+}
+
+func (v *ulawVariantReader) Read(p []byte) (int, error) {
+	n, err := v.r.Read(p)
+	copy(p, v.c.standardUlaw(p[:n]))
+	return n, err
+}
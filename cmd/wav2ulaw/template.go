@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// templatePlaceholders are the placeholders -output-template may use
+var templatePlaceholders = map[string]bool{"dir": true, "name": true, "ext": true, "rate": true, "mode": true}
+
+// checkOutputTemplate validates an -output-template for the conversion: every
+// placeholder must be known and the result must stay inside the output
+// directory
+func (c *conversion) checkOutputTemplate(template string) error {
+	rest := template
+	for {
+		open := strings.IndexByte(rest, '{')
+		if open < 0 {
+			break
+		}
+		end := strings.IndexByte(rest[open:], '}')
+		if end < 0 {
+			return fmt.Errorf("invalid output template '%s': unclosed '{'", template)
+		}
+		name := rest[open+1 : open+end]
+		if !templatePlaceholders[name] {
+			return fmt.Errorf("invalid output template '%s': unknown placeholder {%s}, use {dir}, {name}, {ext}, {rate} or {mode}", template, name)
+		}
+		if name == "rate" && c.preset == "telephone-fx" {
+			return fmt.Errorf("invalid output template '%s': the telephone-fx preset keeps the rate of each input, so {rate} is not known", template)
+		}
+		rest = rest[open+end+1:]
+	}
+	for _, element := range strings.Split(template, "/") {
+		if element == ".." {
+			return fmt.Errorf("invalid output template '%s': outputs must stay inside the output directory", template)
+		}
+	}
+	if strings.HasSuffix(template, "/") || path.Clean("/"+template) == "/" {
+		return fmt.Errorf("invalid output template '%s': it must end in a file name", template)
+	}
+	return nil
+}
+
+// outputName returns the output path, relative to the output directory, of
+// the input at rel relative to the batch root. Without a template the input
+// path is kept with the extension of the mode; a template is expanded with
+// {dir} (the directory of rel, empty at the root), {name} (the file name
+// without extension), {ext} (the output extension without the dot), {rate}
+// (the output sample rate) and {mode}. rel and the result use slashes.
+func (c *conversion) outputName(rel string) string {
+	if c.outputTemplate == "" {
+		return strings.TrimSuffix(rel, path.Ext(rel)) + c.outputExt()
+	}
+	dir, file := path.Split(rel)
+	rate := 8000
+	if c.preset == "" && (c.mode == "ulaw2wav" || c.mode == "asr" || c.mode == "alaw2wav") {
+		rate = int(c.sampleRate)
+	}
+	mode := c.mode
+	if c.preset != "" {
+		mode = c.preset
+	}
+	expanded := strings.NewReplacer(
+		"{dir}", strings.TrimSuffix(dir, "/"),
+		"{name}", strings.TrimSuffix(file, path.Ext(file)),
+		"{ext}", strings.TrimPrefix(c.outputExt(), "."),
+		"{rate}", strconv.Itoa(rate),
+		"{mode}", mode,
+	).Replace(c.outputTemplate)
+	// An empty {dir} leaves a leading or doubled slash
+	return strings.TrimPrefix(path.Clean("/"+expanded), "/")
+}
+
+// outputPath maps an input path relative to the batch root to its output path
+func outputPath(outputDir, rel string, job *conversion) string {
+	name := job.outputName(filepath.ToSlash(rel))
+	if isBlobURL(outputDir) {
+		return joinBlobURL(outputDir, name)
+	}
+	return filepath.Join(outputDir, filepath.FromSlash(name))
+}
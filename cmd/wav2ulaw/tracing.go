@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"wav2ulaw"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+// tracePropagator reads the W3C trace context callers send with requests, so
+// conversions join the trace of the service that requested them
+var tracePropagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// newTracerProvider exports spans in batches to the OTLP/gRPC collector at
+// endpoint, a host:port or a URL (http:// for a plaintext connection).
+// OTEL_EXPORTER_OTLP_* variables set the remaining exporter options.
+func newTracerProvider(ctx context.Context, endpoint string) (*sdktrace.TracerProvider, error) {
+	option := otlptracegrpc.WithEndpoint(endpoint)
+	if strings.Contains(endpoint, "://") {
+		option = otlptracegrpc.WithEndpointURL(endpoint)
+	}
+	exporter, err := otlptracegrpc.New(ctx, option)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create OTLP exporter: %v", err)
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", "wav2ulaw")))
+	if err != nil {
+		return nil, err
+	}
+	return sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res)), nil
+}
+
+// instrumentation records the file conversions of "serve" as metrics and
+// traces. Either may be nil when its flag is not set.
+type instrumentation struct {
+	metrics *serverMetrics
+	tracer  trace.Tracer
+}
+
+// convert runs job on data like convertData. With tracing, the conversion
+// is a span under the caller's trace in ctx, with a child span per
+// processing stage.
+func (in *instrumentation) convert(ctx context.Context, api string, job *conversion, data []byte) ([]byte, error) {
+	if in == nil || (in.metrics == nil && in.tracer == nil) {
+		return job.convertData(data)
+	}
+	direction := job.mode
+	if job.preset == "telephone-fx" {
+		direction = job.preset
+	}
+	if in.metrics != nil {
+		in.metrics.observeInput(api, data)
+	}
+	var span trace.Span
+	if in.tracer != nil {
+		ctx, span = in.tracer.Start(ctx, "convert", trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+			attribute.String("wav2ulaw.api", api),
+			attribute.String("wav2ulaw.direction", direction),
+			attribute.Int("wav2ulaw.input_bytes", len(data)),
+		))
+	}
+
+	// Conversions may share their config, so hook the stages on a copy
+	observed := *job
+	config := *job.config
+	config.OnStageMetrics = func(stage wav2ulaw.StageMetrics) {
+		if in.metrics != nil {
+			in.metrics.observeStage(stage.Stage, stage.Duration)
+		}
+		if in.tracer != nil {
+			// Stages report when they finish, so their spans are started in the past
+			end := time.Now()
+			_, stageSpan := in.tracer.Start(ctx, stage.Stage, trace.WithTimestamp(end.Add(-stage.Duration)), trace.WithAttributes(
+				attribute.Int("wav2ulaw.input_samples", stage.InputSamples),
+				attribute.Int("wav2ulaw.output_samples", stage.OutputSamples),
+			))
+			stageSpan.End(trace.WithTimestamp(end))
+		}
+	}
+	observed.config = &config
+
+	start := time.Now()
+	output, err := observed.convertData(data)
+	if in.metrics != nil {
+		in.metrics.observeConversion(api, direction, time.Since(start), output, err)
+	}
+	if span != nil {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		} else {
+			span.SetAttributes(attribute.Int("wav2ulaw.output_bytes", len(output)))
+		}
+		span.End()
+	}
+	return output, err
+}
+
+// metadataCarrier adapts incoming gRPC metadata to the propagator
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	values := metadata.MD(c).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// grpcTraceContext returns ctx carrying the trace context of an incoming call
+func grpcTraceContext(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	return tracePropagator.Extract(ctx, metadataCarrier(md))
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"time"
+	"wav2ulaw"
+)
+
+// verifyOutput checks a u-law output with wav2ulaw.VerifyTelephonyOutput.
+// When the conversion keeps the length of its input, the output must also
+// last as long as the input, which catches channels or rates left over.
+func (c *conversion) verifyOutput(inputPath, outputPath string) error {
+	data, err := readInput(outputPath)
+	if err != nil {
+		return withExitCode(exitWrite, fmt.Errorf("error reading output for verification: %v", err))
+	}
+	limits := *c.verify
+	if duration, ok := c.inputDuration(inputPath); ok {
+		limits.Duration = duration
+	}
+	if err := wav2ulaw.VerifyTelephonyOutput(data, &limits); err != nil {
+		return withExitCode(exitVerify, err)
+	}
+	return nil
+}
+
+// inputDuration returns the duration of the input when the conversion keeps
+// it, reporting false when it changes the length or the input cannot be read
+// again
+func (c *conversion) inputDuration(inputPath string) (time.Duration, bool) {
+	config := c.config
+	if inputPath == "-" || c.loop.enabled() {
+		return 0, false
+	}
+	data, err := readInput(inputPath)
+	if err != nil {
+		return 0, false
+	}
+	if c.mode == "ulaw2ulaw" {
+		return wav2ulaw.UlawDuration(len(data)), true
+	}
+	if (config.Tempo != 0 && config.Tempo != 1.0) || (config.Speed != 0 && config.Speed != 1.0) ||
+		config.AutoTrim != nil || config.PadStart > 0 || config.PadEnd > 0 {
+		return 0, false
+	}
+	stats, err := wav2ulaw.AnalyzeWav(data)
+	if err != nil {
+		return 0, false
+	}
+	return stats.Duration, true
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchCommand defines the flags of the "watch" subcommand and returns its implementation
+func watchCommand(fs *flag.FlagSet) func(args []string) {
+	outputDir := fs.String("output-dir", "", "Directory for converted files (default: the watched directory)")
+	debounce := fs.Duration("debounce", 500*time.Millisecond, "Wait this long after the last write before converting a file")
+	after := fs.String("after", "none", "What to do with a source after conversion: none, move or delete")
+	moveDir := fs.String("move-dir", "", "Destination for sources with -after move (default: <dir>/converted)")
+	convFlags := registerConversionFlags(fs)
+	logFlags := registerLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: wav2ulaw watch [flags] <dir>")
+		fs.PrintDefaults()
+	}
+	return func(args []string) {
+		if len(args) != 1 {
+			fs.Usage()
+			os.Exit(exitUsage)
+		}
+		dir := args[0]
+
+		logger, err := logFlags.logger(os.Stderr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		job, err := convFlags.conversion(logger)
+		if err != nil {
+			logger.Error("invalid settings", "error", err)
+			os.Exit(exitUsage)
+		}
+		if *after != "none" && *after != "move" && *after != "delete" {
+			logger.Error(fmt.Sprintf("invalid -after '%s'. Must be 'none', 'move' or 'delete'", *after))
+			os.Exit(exitUsage)
+		}
+		if *outputDir == "" {
+			*outputDir = dir
+		}
+		if *moveDir == "" {
+			*moveDir = filepath.Join(dir, "converted")
+		}
+		if sameDir(dir, *outputDir) && job.inputExt() == job.outputExt() {
+			// Outputs would look like new inputs and be converted again
+			logger.Error("-output-dir must differ from the watched directory for WAV to WAV conversion")
+			os.Exit(exitUsage)
+		}
+
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			logger.Error("error starting watcher", "error", err)
+			os.Exit(1)
+		}
+		defer watcher.Close()
+		if err := watcher.Add(dir); err != nil {
+			logger.Error("error watching directory", "dir", dir, "error", err)
+			os.Exit(1)
+		}
+
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt)
+
+		// A file is converted once it has been quiet for the debounce period, so
+		// files still being copied in are not picked up half written
+		timers := make(map[string]*time.Timer)
+		ready := make(chan string)
+
+		logger.Info("watching", "dir", dir, "ext", job.inputExt(), "after", *after)
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Write) {
+					continue
+				}
+				if !strings.EqualFold(filepath.Ext(event.Name), job.inputExt()) {
+					continue
+				}
+				if timer, exists := timers[event.Name]; exists {
+					timer.Reset(*debounce)
+					continue
+				}
+				name := event.Name
+				timers[name] = time.AfterFunc(*debounce, func() { ready <- name })
+
+			case name := <-ready:
+				delete(timers, name)
+				if _, err := os.Stat(name); err != nil {
+					// Removed or renamed away before it settled
+					continue
+				}
+				base := strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+				output := filepath.Join(*outputDir, base+job.outputExt())
+				start := time.Now()
+				if err := convertBatchFile(job, name, output); err != nil {
+					logger.Error("conversion failed", "input", name, "error", err)
+					continue
+				}
+				logger.Info("converted", "input", name, "output", output, "duration", time.Since(start))
+				if err := afterConvert(*after, name, *moveDir); err != nil {
+					logger.Warn("post-convert action failed", "error", err)
+				}
+
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logger.Warn("watcher error", "error", err)
+
+			case <-interrupt:
+				return
+			}
+		}
+	}
+}
+
+// afterConvert moves or deletes a converted source file
+func afterConvert(action, source, moveDir string) error {
+	switch action {
+	case "move":
+		if err := os.MkdirAll(moveDir, 0755); err != nil {
+			return fmt.Errorf("error creating move directory: %v", err)
+		}
+		if err := os.Rename(source, filepath.Join(moveDir, filepath.Base(source))); err != nil {
+			return fmt.Errorf("error moving %s: %v", source, err)
+		}
+	case "delete":
+		if err := os.Remove(source); err != nil {
+			return fmt.Errorf("error deleting %s: %v", source, err)
+		}
+	}
+	return nil
+}
+
+// sameDir reports whether two paths name the same directory
+func sameDir(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
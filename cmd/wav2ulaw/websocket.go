@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"wav2ulaw"
+
+	"github.com/gorilla/websocket"
+)
+
+// Duration of the audio carried by each binary message the server sends
+const streamFrameMs = 20
+
+// checkOrigin lets browsers open streams from pages of the server's own
+// origin and of the -allow-origin list ("*" = any). Requests without an
+// Origin header do not come from a browser and are accepted.
+func (s *httpAPI) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range s.allowOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// streamConverter converts one direction of a live stream
+type streamConverter interface {
+	// convert returns the output completed by the next input message
+	convert(data []byte) ([]byte, error)
+	// flush returns the output still held back at the end of the stream
+	flush() []byte
+	// frameSize is the number of output bytes sent per message
+	frameSize() int
+}
+
+// closeError ends a stream with a WebSocket close code
+type closeError struct {
+	code    int
+	message string
+}
+
+func (e *closeError) Error() string { return e.message }
+
+// errIncompleteStreamHeader reports a stream whose first data ends inside its WAV header
+var errIncompleteStreamHeader = errors.New("WAV header must arrive in the first message")
+
+// streamHandler upgrades the request to a WebSocket converting audio as it
+// arrives. Clients send binary messages and receive the converted audio in
+// 20 ms frames; a text message "end" flushes the remaining output and
+// closes the stream. A POST instead streams the request body to the
+// response with chunked transfer encoding. Settings are query parameters as
+// for /convert, plus "rate", the sample rate of the PCM side of the stream.
+func (s *httpAPI) streamHandler(mode string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		rate := 0
+		if value := query.Get("rate"); value != "" {
+			var err error
+			if rate, err = strconv.Atoi(value); err != nil || rate <= 0 {
+				httpError(w, http.StatusBadRequest, fmt.Sprintf("invalid rate '%s'", value))
+				return
+			}
+			query.Del("rate")
+		}
+		job, err := s.requestConversion(query, nil, mode)
+		if err != nil {
+			httpError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		conv, err := newStreamConverter(job, rate)
+		if err != nil {
+			httpError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if r.Method == http.MethodPost {
+			s.serveHTTPStream(w, r, conv)
+			return
+		}
+
+		// Upgrade replies to the client itself when it fails
+		upgrader := websocket.Upgrader{CheckOrigin: s.checkOrigin}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.SetReadLimit(s.maxSize)
+
+		if err := runStream(conn, conv); err != nil {
+			s.logger.Warn("stream failed", "path", r.URL.Path, "error", err)
+			code := websocket.CloseInternalServerErr
+			var ce *closeError
+			if errors.As(err, &ce) {
+				code = ce.code
+			}
+			message := websocket.FormatCloseMessage(code, err.Error())
+			conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(time.Second))
+		}
+	})
+}
+
+// newStreamConverter returns the converter of a live stream for job. rate
+// is the sample rate of the PCM side; 0 takes it from the WAV header of
+// wav2ulaw input and from the job's output rate for ulaw2wav.
+func newStreamConverter(job *conversion, rate int) (streamConverter, error) {
+	if job.preset == "telephone-fx" {
+		return nil, fmt.Errorf("preset 'telephone-fx' cannot stream")
+	}
+	if job.mode == "wav2ulaw" {
+		return &pcmStreamEncoder{config: job.config, rate: rate, channels: 1}, nil
+	}
+	if rate == 0 {
+		rate = int(job.sampleRate)
+	}
+	decoder, err := wav2ulaw.NewChunkDecoder(rate, job.windowSize)
+	if err != nil {
+		return nil, err
+	}
+	return &ulawStreamDecoder{decoder: decoder, rate: rate}, nil
+}
+
+// runStream converts messages until the client ends or closes the stream
+func runStream(conn *websocket.Conn, conv streamConverter) error {
+	var pending []byte
+	for {
+		kind, data, err := conn.ReadMessage()
+		if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if kind == websocket.TextMessage {
+			if string(data) != "end" {
+				return &closeError{websocket.CloseUnsupportedData, fmt.Sprintf("unexpected text message '%s'", data)}
+			}
+			pending = append(pending, conv.flush()...)
+			if _, err := sendFrames(conn, pending, conv.frameSize(), true); err != nil {
+				return err
+			}
+			message := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+			return conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(time.Second))
+		}
+
+		output, err := conv.convert(data)
+		if err != nil {
+			return &closeError{websocket.CloseUnsupportedData, err.Error()}
+		}
+		if pending, err = sendFrames(conn, append(pending, output...), conv.frameSize(), false); err != nil {
+			return err
+		}
+	}
+}
+
+// sendFrames sends data in frames of size bytes and returns the incomplete
+// last frame, which is sent as well when final is set
+func sendFrames(conn *websocket.Conn, data []byte, size int, final bool) ([]byte, error) {
+	for len(data) >= size || (final && len(data) > 0) {
+		n := min(size, len(data))
+		if err := conn.WriteMessage(websocket.BinaryMessage, data[:n]); err != nil {
+			return nil, err
+		}
+		data = data[n:]
+	}
+	return append([]byte(nil), data...), nil
+}
+
+// pcmStreamEncoder converts 16-bit little-endian PCM to u-law. The first
+// message may start with a WAV header giving the format of the stream.
+type pcmStreamEncoder struct {
+	config   *wav2ulaw.AudioConfig
+	rate     int
+	channels int
+	encoder  *wav2ulaw.ChunkEncoder
+	// Bytes of a sample frame split across messages
+	partial []byte
+}
+
+func (e *pcmStreamEncoder) convert(data []byte) ([]byte, error) {
+	if e.encoder == nil {
+		if bytes.HasPrefix(data, []byte("RIFF")) {
+			rate, channels, pcm, err := parseStreamHeader(data)
+			if err != nil {
+				return nil, err
+			}
+			// An explicit rate overrides the header, like -sample-rate does for files
+			if e.rate == 0 {
+				e.rate = rate
+			}
+			e.channels = channels
+			data = pcm
+		}
+		if e.rate == 0 {
+			return nil, fmt.Errorf("no WAV header and no rate parameter")
+		}
+		encoder, err := wav2ulaw.NewChunkEncoder(e.rate, e.config)
+		if err != nil {
+			return nil, err
+		}
+		e.encoder = encoder
+	}
+
+	data = append(e.partial, data...)
+	frame := 2 * e.channels
+	n := len(data) / frame
+	samples := make([]int16, n)
+	for i := range samples {
+		// Mix channels down to mono
+		sum := 0
+		for ch := 0; ch < e.channels; ch++ {
+			sum += int(int16(binary.LittleEndian.Uint16(data[(i*e.channels+ch)*2:])))
+		}
+		samples[i] = int16(sum / e.channels)
+	}
+	e.partial = append([]byte(nil), data[n*frame:]...)
+	return e.encoder.Encode(samples), nil
+}
+
+func (e *pcmStreamEncoder) flush() []byte {
+	if e.encoder == nil {
+		return nil
+	}
+	return e.encoder.Flush()
+}
+
+func (e *pcmStreamEncoder) frameSize() int { return 8000 * streamFrameMs / 1000 }
+
+// ulawStreamDecoder converts u-law to 16-bit little-endian PCM at rate
+type ulawStreamDecoder struct {
+	decoder *wav2ulaw.ChunkDecoder
+	rate    int
+}
+
+func (d *ulawStreamDecoder) convert(data []byte) ([]byte, error) {
+	return encodePCM16LE(d.decoder.Decode(data)), nil
+}
+
+func (d *ulawStreamDecoder) flush() []byte {
+	return encodePCM16LE(d.decoder.Flush())
+}
+
+func (d *ulawStreamDecoder) frameSize() int { return d.rate * streamFrameMs / 1000 * 2 }
+
+// encodePCM16LE serializes samples as 16-bit little-endian PCM
+func encodePCM16LE(samples []int16) []byte {
+	data := make([]byte, len(samples)*2)
+	for i, sample := range samples {
+		binary.LittleEndian.PutUint16(data[i*2:], uint16(sample))
+	}
+	return data
+}
+
+// parseStreamHeader reads the WAV header at the start of a stream and
+// returns its sample rate, channel count and the PCM data following it.
+// Streams of unknown length may give any size for the data chunk.
+func parseStreamHeader(data []byte) (int, int, []byte, error) {
+	if len(data) < 12 {
+		return 0, 0, nil, errIncompleteStreamHeader
+	}
+	if string(data[8:12]) != "WAVE" {
+		return 0, 0, nil, fmt.Errorf("invalid WAV header")
+	}
+	rate, channels := 0, 0
+	for pos := 12; pos+8 <= len(data); {
+		id := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		body := data[pos+8:]
+		if id == "data" {
+			if rate == 0 {
+				return 0, 0, nil, fmt.Errorf("WAV data before format chunk")
+			}
+			return rate, channels, body, nil
+		}
+		if size > len(body) {
+			break
+		}
+		if id == "fmt " {
+			if size < 16 {
+				return 0, 0, nil, fmt.Errorf("invalid WAV format chunk")
+			}
+			format := binary.LittleEndian.Uint16(body)
+			channels = int(binary.LittleEndian.Uint16(body[2:]))
+			rate = int(binary.LittleEndian.Uint32(body[4:]))
+			bitDepth := binary.LittleEndian.Uint16(body[14:])
+			// 0xFFFE is WAVE_FORMAT_EXTENSIBLE, which browsers use for PCM as well
+			if (format != 1 && format != 0xFFFE) || bitDepth != 16 || channels < 1 || rate <= 0 {
+				return 0, 0, nil, fmt.Errorf("unsupported WAV format, streams must be 16-bit PCM")
+			}
+		}
+		// Chunks are padded to an even size
+		pos += 8 + size + size%2
+	}
+	return 0, 0, nil, errIncompleteStreamHeader
+}
@@ -0,0 +1,159 @@
+package wav2ulaw
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// OutputCodec selects how the 8 kHz output of the processing chain is
+// encoded. Every codec shares the same filtering, resampling, compression
+// and normalization; lengths such as FrameAlign count samples whatever
+// their size.
+type OutputCodec int
+
+const (
+	// CodecUlaw is G.711 u-law, the default, with AudioConfig.UlawVariant
+	CodecUlaw OutputCodec = iota
+	// CodecAlaw is G.711 A-law, as European carriers require
+	CodecAlaw
+	// CodecPCM16 is headerless 16-bit little-endian linear PCM
+	CodecPCM16
+)
+
+// outputCodecNames are the names of the codecs in String and
+// ParseOutputCodec
+var outputCodecNames = []string{
+	CodecUlaw:  "ulaw",
+	CodecAlaw:  "alaw",
+	CodecPCM16: "pcm16",
+}
+
+// String returns the name of the codec: ulaw, alaw or pcm16
+func (c OutputCodec) String() string {
+	if c < 0 || int(c) >= len(outputCodecNames) {
+		return fmt.Sprintf("OutputCodec(%d)", int(c))
+	}
+	return outputCodecNames[c]
+}
+
+// ParseOutputCodec parses the names produced by String
+func ParseOutputCodec(s string) (OutputCodec, error) {
+	for c, name := range outputCodecNames {
+		if name == s {
+			return OutputCodec(c), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown output codec '%s'", s)
+}
+
+// MarshalText returns the name of the codec, for JSON and YAML configs
+func (c OutputCodec) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText parses the names accepted by ParseOutputCodec
+func (c *OutputCodec) UnmarshalText(text []byte) error {
+	parsed, err := ParseOutputCodec(string(text))
+	if err != nil {
+		return err
+	}
+	*c = parsed
+	return nil
+}
+
+// encode compands processed samples to the codec; u-law is standard G.711
+func (c OutputCodec) encode(samples []int16) []byte {
+	switch c {
+	case CodecAlaw:
+		return encodeAlawSamples(samples)
+	case CodecPCM16:
+		data := make([]byte, 2*len(samples))
+		for i, sample := range samples {
+			binary.LittleEndian.PutUint16(data[2*i:], uint16(sample))
+		}
+		return data
+	default:
+		return encodeUlawSamples(samples)
+	}
+}
+
+// decode expands output of encode back to samples
+func (c OutputCodec) decode(data []byte) []int16 {
+	switch c {
+	case CodecAlaw:
+		return decodeAlawSamples(data)
+	case CodecPCM16:
+		samples := make([]int16, len(data)/2)
+		for i := range samples {
+			samples[i] = int16(binary.LittleEndian.Uint16(data[2*i:]))
+		}
+		return samples
+	default:
+		return decodeUlawSamples(data)
+	}
+}
+
+// encodeOutput encodes processed samples as config asks, in the u-law
+// variant for u-law output. OnStageOutput and the sanity check (nil = off)
+// see the standard encoding, before the variant is applied.
+func encodeOutput(config *AudioConfig, samples []int16, sanity *sanityWatch) ([]byte, error) {
+	data := config.OutputCodec.encode(samples)
+	dumpOutput(config, data)
+	if err := sanity.check(data, config.OutputCodec); err != nil {
+		return nil, err
+	}
+	if config.OutputCodec == CodecUlaw {
+		data = config.UlawVariant.apply(data)
+	}
+	return data, nil
+}
+
+// encodeChunk encodes a chunk of a stream through encodeOutput; without a
+// sanity check encoding cannot fail
+func encodeChunk(config *AudioConfig, samples []int16) []byte {
+	data, _ := encodeOutput(config, samples, nil)
+	return data
+}
+
+// ConvertWavBytesToAlaw converts WAV file bytes to G.711 A-law through the
+// same processing chain as ConvertWavBytesToUlaw; config.OutputCodec is
+// ignored
+func ConvertWavBytesToAlaw(wavBytes []byte, config *AudioConfig) ([]byte, error) {
+	if config == nil {
+		config = DefaultAudioConfig()
+	}
+	alaw := *config
+	alaw.OutputCodec = CodecAlaw
+	return convertWavBytes(wavBytes, &alaw, nil)
+}
+
+// ConvertAlawBytesToWav converts A-law encoded bytes to a 16-bit WAV file at
+// sampleRate, resampled with a sinc window of windowSize as
+// ConvertUlawBytesToWav does
+func ConvertAlawBytesToWav(alawBytes []byte, sampleRate uint32, windowSize int) ([]byte, error) {
+	pcm, err := resampleDecoded(decodeAlawSamples(alawBytes), sampleRate, windowSize, 0)
+	if err != nil {
+		return nil, err
+	}
+	return encodeWavPCM16(pcm, int(sampleRate))
+}
+
+// ConvertUlawToAlaw transcodes u-law to A-law code by code, through the
+// linear value of each
+func ConvertUlawToAlaw(ulaw []byte) []byte {
+	alaw := make([]byte, len(ulaw))
+	for i, code := range ulaw {
+		alaw[i] = encodeAlawSample(ulawDecodeTable[code])
+	}
+	return alaw
+}
+
+// ConvertAlawToUlaw transcodes A-law to u-law code by code, through the
+// linear value of each
+func ConvertAlawToUlaw(alaw []byte) []byte {
+	ulaw := make([]byte, len(alaw))
+	for i, code := range alaw {
+		ulaw[i] = encodeUlawSample(alawDecodeTable[code])
+	}
+	return ulaw
+}
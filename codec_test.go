@@ -0,0 +1,91 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func TestOutputCodecs(t *testing.T) {
+	wavBytes := encodeWavDepth(t, sineWave(16000, 440, 16000, 0.3), 16000, 16, 1)
+	config := DefaultAudioConfig()
+	config.OutputCodec = CodecPCM16
+	raw, err := ConvertWavBytesToUlaw(wavBytes, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pcm := make([]int16, len(raw)/2)
+	for i := range pcm {
+		pcm[i] = int16(binary.LittleEndian.Uint16(raw[2*i:]))
+	}
+
+	// Every codec encodes the output of the same processing chain
+	alaw, err := ConvertWavBytesToAlaw(wavBytes, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(alaw, encodeAlawSamples(pcm)) {
+		t.Error("A-law output is not the PCM16 output encoded to A-law")
+	}
+	ulaw, err := ConvertWavBytesToUlaw(wavBytes, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(ulaw, encodeUlawSamples(pcm)) {
+		t.Error("u-law output is not the PCM16 output encoded to u-law")
+	}
+
+	// Streaming conversions share the codec
+	config.OutputCodec = CodecAlaw
+	var streamed bytes.Buffer
+	if err := ConvertWavStreamToUlaw(bytes.NewReader(wavBytes), &streamed, config); err != nil {
+		t.Fatal(err)
+	}
+	if streamed.Len() != len(alaw) || toneLevel(decodeAlawSamples(streamed.Bytes()[800:]), 440, 8000) < 0.5 {
+		t.Errorf("streamed %d A-law bytes, want %d of the tone", streamed.Len(), len(alaw))
+	}
+
+	wav, err := ConvertAlawBytesToWav(alaw, 16000, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, _, err := decodeWavSamples(wav, &AudioConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 2*len(alaw) || toneLevel(decoded[1600:], 440, 16000) < 0.5 {
+		t.Errorf("decoded %d samples, want %d of the tone", len(decoded), 2*len(alaw))
+	}
+
+	config.OutputCodec = 7
+	if _, err := ConvertWavBytesToUlaw(wavBytes, config); !errors.As(err, new(*ConfigError)) {
+		t.Errorf("unknown codec: got %v, want a *ConfigError", err)
+	}
+}
+
+func TestTranscodeUlawAlaw(t *testing.T) {
+	samples := sineWave(800, 440, 8000, 0.5)
+	ulaw := encodeUlawSamples(samples)
+	alaw := ConvertUlawToAlaw(ulaw)
+	if level := toneLevel(decodeAlawSamples(alaw), 440, 8000); level < 0.45 || level > 0.55 {
+		t.Errorf("tone level %.2f after transcoding to A-law, want 0.5", level)
+	}
+	back := ConvertAlawToUlaw(alaw)
+	for i, s := range decodeUlawSamples(back) {
+		if d := int(s) - int(samples[i]); d > 1024 || d < -1024 {
+			t.Fatalf("sample %d is %d after a round trip, want near %d", i, s, samples[i])
+		}
+	}
+}
+
+func TestParseOutputCodec(t *testing.T) {
+	for _, codec := range []OutputCodec{CodecUlaw, CodecAlaw, CodecPCM16} {
+		if parsed, err := ParseOutputCodec(codec.String()); err != nil || parsed != codec {
+			t.Errorf("%s: parsed %v, %v", codec, parsed, err)
+		}
+	}
+	if _, err := ParseOutputCodec("gsm"); err == nil {
+		t.Error("unknown codec accepted")
+	}
+}
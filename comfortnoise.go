@@ -0,0 +1,72 @@
+package wav2ulaw
+
+import (
+	"math"
+	"math/rand"
+)
+
+const (
+	// UlawFrameSize is the number of bytes in a 20 ms u-law frame at 8 kHz
+	UlawFrameSize = 160
+	// Comfort noise level used when no background estimate is available (dBov)
+	defaultComfortNoiseDbov = -70.0
+)
+
+// ComfortNoiseGenerator produces low-level background noise in the spirit of
+// G.711 Appendix II / RFC 3389, used to fill silence so listeners do not
+// mistake a quiet line for a dropped call
+type ComfortNoiseGenerator struct {
+	rms float64
+	rng *rand.Rand
+}
+
+// NewComfortNoiseGenerator creates a generator producing noise at levelDbov
+// (RMS relative to full scale, e.g. -60). The seed makes output reproducible.
+func NewComfortNoiseGenerator(levelDbov float64, seed int64) *ComfortNoiseGenerator {
+	g := &ComfortNoiseGenerator{rng: rand.New(rand.NewSource(seed))}
+	g.SetLevel(levelDbov)
+	return g
+}
+
+// SetLevel changes the noise level in dBov
+func (g *ComfortNoiseGenerator) SetLevel(levelDbov float64) {
+	g.rms = 32767.0 * math.Pow(10, levelDbov/20.0)
+}
+
+// Samples returns n samples of 16-bit PCM comfort noise
+func (g *ComfortNoiseGenerator) Samples(n int) []int16 {
+	samples := make([]int16, n)
+	for i := range samples {
+		v := g.rng.NormFloat64() * g.rms
+		samples[i] = int16(math.Max(-32768, math.Min(32767, math.Round(v))))
+	}
+	return samples
+}
+
+// Frame returns one 20 ms frame of u-law comfort noise
+func (g *ComfortNoiseGenerator) Frame() []byte {
+	return encodeUlawSamples(g.Samples(UlawFrameSize))
+}
+
+// GenerateComfortNoise returns durationMs of u-law comfort noise at levelDbov
+func GenerateComfortNoise(levelDbov float64, durationMs int) []byte {
+	g := NewComfortNoiseGenerator(levelDbov, 1)
+	return encodeUlawSamples(g.Samples(durationMs * 8))
+}
+
+// rmsDbov returns the RMS level of samples in dBov, or -Inf for digital silence
+func rmsDbov(samples []int16) float64 {
+	if len(samples) == 0 {
+		return math.Inf(-1)
+	}
+	sum := 0.0
+	for _, sample := range samples {
+		v := float64(sample)
+		sum += v * v
+	}
+	rms := math.Sqrt(sum / float64(len(samples)))
+	if rms == 0 {
+		return math.Inf(-1)
+	}
+	return 20 * math.Log10(rms/32767.0)
+}
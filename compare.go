@@ -0,0 +1,72 @@
+package wav2ulaw
+
+import (
+	"fmt"
+	"time"
+)
+
+// Comparison contains objective differences between two renditions of the
+// same audio, such as a prompt before and after a build change
+type Comparison struct {
+	QualityMetrics
+	// Sample rate the two were compared at (Hz)
+	SampleRate int
+	// Integrated loudness of each (LUFS, -Inf when silent)
+	LoudnessA, LoudnessB float64
+	// LoudnessB minus LoudnessA (LU)
+	LoudnessDelta float64
+	// Duration of each
+	DurationA, DurationB time.Duration
+	// DurationB minus DurationA
+	DurationDelta time.Duration
+}
+
+// CompareFiles decodes two WAV or raw u-law files to mono, brings the one
+// with the higher sample rate to the rate of the other, time-aligns them and
+// compares them: CompareAudio's metrics with a as the reference, plus the
+// differences in integrated loudness and duration
+func CompareFiles(a, b []byte) (*Comparison, error) {
+	config := DefaultAudioConfig()
+	first, rateA, err := decodeMixData(a, config)
+	if err != nil {
+		return nil, fmt.Errorf("first file: %w", err)
+	}
+	defer putInt16s(first)
+	second, rateB, err := decodeMixData(b, config)
+	if err != nil {
+		return nil, fmt.Errorf("second file: %w", err)
+	}
+	defer putInt16s(second)
+
+	c := &Comparison{
+		SampleRate: min(rateA, rateB),
+		DurationA:  samplesDuration(len(first), rateA),
+		DurationB:  samplesDuration(len(second), rateB),
+	}
+	c.DurationDelta = c.DurationB - c.DurationA
+	if rateA > c.SampleRate {
+		first = release(first, resample(first, rateA, c.SampleRate, config))
+	}
+	if rateB > c.SampleRate {
+		second = release(second, resample(second, rateB, c.SampleRate, config))
+	}
+
+	metrics, err := CompareAudio(first, second, c.SampleRate)
+	if err != nil {
+		return nil, err
+	}
+	c.QualityMetrics = *metrics
+	c.LoudnessA = measureLoudness([][]float64{int16sToFloat(first)}, c.SampleRate).Integrated
+	c.LoudnessB = measureLoudness([][]float64{int16sToFloat(second)}, c.SampleRate).Integrated
+	c.LoudnessDelta = c.LoudnessB - c.LoudnessA
+	return c, nil
+}
+
+// int16sToFloat normalizes 16-bit samples to [-1, 1)
+func int16sToFloat(samples []int16) []float64 {
+	out := make([]float64, len(samples))
+	for i, s := range samples {
+		out[i] = float64(s) / 32768.0
+	}
+	return out
+}
@@ -0,0 +1,49 @@
+package wav2ulaw
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCompareFiles(t *testing.T) {
+	input := sineWave(16000, 440, 16000, 0.3)
+	for i, sample := range sineWave(len(input), 1230, 16000, 0.2) {
+		input[i] += sample
+	}
+	wavBytes, err := encodeWavPCM16(input, 16000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	same, err := CompareFiles(wavBytes, wavBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !math.IsInf(same.PSNR, 1) || same.LoudnessDelta != 0 || same.DurationDelta != 0 || same.SampleRate != 16000 {
+		t.Errorf("identical files: %+v", same)
+	}
+
+	config := DefaultAudioConfig()
+	config.NormalizePeak = 0
+	config.CompressionRatio = 1
+	config.PadEnd = 100 * time.Millisecond
+	ulaw, err := ConvertWavBytesToUlaw(wavBytes, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := CompareFiles(wavBytes, ulaw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.SampleRate != 8000 || c.DurationDelta != 100*time.Millisecond {
+		t.Errorf("compared at %d Hz with a duration delta of %v, want 8000 Hz and 100ms", c.SampleRate, c.DurationDelta)
+	}
+	if c.SegmentalSNR < 10 || math.Abs(c.LoudnessDelta) > 3 {
+		t.Errorf("u-law rendition scored a segmental SNR of %.2f dB and a loudness delta of %.2f LU", c.SegmentalSNR, c.LoudnessDelta)
+	}
+
+	if _, err := CompareFiles(wavBytes, []byte("not audio")); err == nil {
+		t.Error("invalid second file accepted")
+	}
+}
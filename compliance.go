@@ -0,0 +1,125 @@
+package wav2ulaw
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	// Frames over which VerifyTelephonyOutput measures the active level
+	// (20 ms, one RTP packet)
+	complianceFrame = 160
+	// RMS above which a frame counts as active audio (dBov)
+	complianceActiveDbov = -50.0
+)
+
+// TelephonyLimits are the bounds VerifyTelephonyOutput holds u-law output
+// to before it is deployed to a PBX
+type TelephonyLimits struct {
+	// Duration the output must last at 8000 mono samples per second,
+	// within 20 ms or 1% (0 = not checked). Output left at a higher rate
+	// or with interleaved channels is longer than its audio and fails it.
+	Duration time.Duration
+	// Range of the active level: the RMS of the 20 ms frames above
+	// -50 dBov (dBov)
+	MinLevelDbov float64
+	MaxLevelDbov float64
+	// Highest sample peak (dBov); the default rejects the largest u-law
+	// code, which audio clipped in encoding reaches
+	MaxPeakDbov float64
+	// Longest run of digital silence between two stretches of audio
+	// (0 = not checked); leading and trailing silence is not counted
+	MaxSilenceGap time.Duration
+}
+
+// DefaultTelephonyLimits returns limits for speech prompts: an active level
+// from -36 to -6 dBov, no sample at full scale and no gap of digital silence
+// over 2 seconds. The duration is not checked.
+func DefaultTelephonyLimits() *TelephonyLimits {
+	return &TelephonyLimits{
+		MinLevelDbov:  -36,
+		MaxLevelDbov:  -6,
+		MaxPeakDbov:   -0.3,
+		MaxSilenceGap: 2 * time.Second,
+	}
+}
+
+// TelephonyError lists the ways u-law output breaks its TelephonyLimits
+type TelephonyError struct {
+	Violations []string
+}
+
+func (e *TelephonyError) Error() string {
+	return "output not fit for telephony: " + strings.Join(e.Violations, "; ")
+}
+
+// VerifyTelephonyOutput checks u-law output against limits (nil =
+// DefaultTelephonyLimits) as a final gate before deployment: it must be
+// plausible standard G.711 u-law by the checks of ValidateUlaw, last the
+// expected duration at 8 kHz mono, keep its active level and peak within
+// bounds and hold no long gap of digital silence. Every violation found is
+// listed in a *TelephonyError. Empty data returns ErrEmptyInput.
+func VerifyTelephonyOutput(data []byte, limits *TelephonyLimits) error {
+	if len(data) == 0 {
+		return ErrEmptyInput
+	}
+	if limits == nil {
+		limits = DefaultTelephonyLimits()
+	}
+
+	var violations []string
+	if err := ValidateUlaw(data); err != nil {
+		violations = append(violations, err.Error())
+	}
+	if limits.Duration > 0 && !durationMatches(len(data), limits.Duration) {
+		violations = append(violations, fmt.Sprintf("lasts %v at 8 kHz mono, %v expected", UlawDuration(len(data)), limits.Duration))
+	}
+
+	samples := decodeUlawSamples(data)
+	sum, active, peak := 0.0, 0, 0.0
+	for start := 0; start < len(samples); start += complianceFrame {
+		frame := samples[start:min(start+complianceFrame, len(samples))]
+		if rmsDbov(frame) <= complianceActiveDbov {
+			continue
+		}
+		for _, s := range frame {
+			sum += float64(s) * float64(s)
+		}
+		active += len(frame)
+	}
+	for _, s := range samples {
+		peak = math.Max(peak, math.Abs(float64(s)))
+	}
+	if active == 0 {
+		violations = append(violations, "no active audio")
+	} else {
+		level := 20 * math.Log10(math.Sqrt(sum/float64(active))/32767)
+		if level < limits.MinLevelDbov || level > limits.MaxLevelDbov {
+			violations = append(violations, fmt.Sprintf("active level %.1f dBov, outside %.1f to %.1f dBov", level, limits.MinLevelDbov, limits.MaxLevelDbov))
+		}
+		if peakDbov := 20 * math.Log10(peak/32767); peakDbov > limits.MaxPeakDbov {
+			violations = append(violations, fmt.Sprintf("peak %.2f dBov, above %.2f dBov", peakDbov, limits.MaxPeakDbov))
+		}
+	}
+
+	if limits.MaxSilenceGap > 0 {
+		maxRun := int(limits.MaxSilenceGap.Seconds() * 8000)
+		lastSound := -1
+		for i, s := range samples {
+			if s >= -maxSilentLevel && s <= maxSilentLevel {
+				continue
+			}
+			if gap := i - lastSound - 1; lastSound >= 0 && gap > maxRun {
+				violations = append(violations, fmt.Sprintf("digital silence for %v at %v", UlawDuration(gap), UlawDuration(lastSound+1)))
+			}
+			lastSound = i
+		}
+	}
+
+	if len(violations) > 0 {
+		return &TelephonyError{Violations: violations}
+	}
+	return nil
+}
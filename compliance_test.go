@@ -0,0 +1,58 @@
+package wav2ulaw
+
+import (
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyTelephonyOutput(t *testing.T) {
+	prompt, err := ConvertPCM16ToUlaw(sineWave(48000, 440, 16000, 0.3), 16000, ConfigForPromptLibrary())
+	if err != nil {
+		t.Fatal(err)
+	}
+	limits := DefaultTelephonyLimits()
+	limits.Duration = 3 * time.Second
+	if err := VerifyTelephonyOutput(prompt, limits); err != nil {
+		t.Fatalf("prompt library output: %v", err)
+	}
+
+	quiet := encodeUlawSamples(sineWave(24000, 440, 8000, 0.008))
+	clipped := encodeUlawSamples(sineWave(24000, 400, 8000, 1.2))
+	gap := append(append(append([]byte(nil), prompt[:8000]...), make([]byte, 3*8000)...), prompt[8000:]...)
+	for i := 8000; i < 4*8000; i++ {
+		gap[i] = 0xFF
+	}
+	noise := make([]byte, 24000)
+	rand.New(rand.NewSource(1)).Read(noise)
+
+	for _, tc := range []struct {
+		name string
+		data []byte
+		// Substring of the violation expected
+		want string
+	}{
+		{"interleaved channels", append(append([]byte(nil), prompt...), prompt...), "expected"},
+		{"quiet", quiet, "active level"},
+		{"clipped", clipped, "peak"},
+		{"silence gap", gap, "digital silence for 3s at 1s"},
+		{"random bytes", noise, "implausible u-law"},
+		{"silence", encodeUlawSamples(make([]int16, 8000)), "no active audio"},
+	} {
+		limits.Duration = UlawDuration(len(tc.data))
+		if tc.name == "interleaved channels" {
+			limits.Duration = 3 * time.Second
+		}
+		err := VerifyTelephonyOutput(tc.data, limits)
+		var telephonyErr *TelephonyError
+		if !errors.As(err, &telephonyErr) || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: got %v, want a *TelephonyError reporting %q", tc.name, err, tc.want)
+		}
+	}
+
+	if err := VerifyTelephonyOutput(nil, nil); !errors.Is(err, ErrEmptyInput) {
+		t.Errorf("empty input: got %v, want ErrEmptyInput", err)
+	}
+}
@@ -0,0 +1,73 @@
+package wav2ulaw
+
+import (
+	"math"
+	"time"
+)
+
+// ConcatenateUlaw joins 8 kHz u-law segments into one. With crossfadeMs > 0
+// every boundary overlaps the end of one segment with the start of the next,
+// fading one out while the other fades in with the given curve, instead of a
+// hard cut that can click. The gains of the two sides always sum to one, and
+// the overlap is shortened when a segment is shorter than the crossfade.
+func ConcatenateUlaw(segments [][]byte, crossfadeMs float64, shape FadeShape) []byte {
+	total := 0
+	for _, segment := range segments {
+		total += len(segment)
+	}
+	out := make([]int16, 0, total)
+
+	crossfade := int(crossfadeMs * 8000 / 1000)
+	prevLen := 0
+	for _, segment := range segments {
+		samples := decodeUlawSamples(segment)
+		overlap := min(max(crossfade, 0), prevLen, len(samples))
+
+		tail := out[len(out)-overlap:]
+		for i := range tail {
+			t := float64(i+1) / float64(overlap+1)
+			mixed := float64(tail[i])*fadeGain(shape, 1-t) + float64(samples[i])*fadeGain(shape, t)
+			tail[i] = int16(math.Max(-32768, math.Min(32767, math.Round(mixed))))
+		}
+		out = append(out, samples[overlap:]...)
+		prevLen = len(samples)
+	}
+
+	return encodeUlawSamples(out)
+}
+
+// LoopUlaw repeats an 8 kHz u-law clip count times, or as many more times as
+// it takes to last at least minDuration, for example to build a music-on-hold
+// file from a short clip. With crossfadeMs > 0 the loop points are
+// crossfaded as by ConcatenateUlaw, each overlap shortening the repeat; the
+// crossfade is limited to half the clip.
+func LoopUlaw(clip []byte, count int, minDuration time.Duration, crossfadeMs float64, shape FadeShape) []byte {
+	if len(clip) == 0 {
+		return nil
+	}
+	count, overlap := loopPlan(len(clip), count, minDuration, crossfadeMs)
+	segments := make([][]byte, count)
+	for i := range segments {
+		segments[i] = clip
+	}
+	return ConcatenateUlaw(segments, float64(overlap)*1000/8000, shape)
+}
+
+// LoopedLength returns the number of samples LoopUlaw produces from a clip
+// of n samples
+func LoopedLength(n, count int, minDuration time.Duration, crossfadeMs float64) int {
+	if n == 0 {
+		return 0
+	}
+	count, overlap := loopPlan(n, count, minDuration, crossfadeMs)
+	return count*(n-overlap) + overlap
+}
+
+// loopPlan returns the number of copies of an n-sample clip a loop needs and
+// the overlap in samples at each loop point
+func loopPlan(n, count int, minDuration time.Duration, crossfadeMs float64) (int, int) {
+	overlap := min(max(int(crossfadeMs*8000/1000), 0), n/2)
+	step := n - overlap
+	target := int(math.Ceil(minDuration.Seconds() * 8000))
+	return max(count, 1, (target-overlap+step-1)/step), overlap
+}
@@ -0,0 +1,65 @@
+package wav2ulaw
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConcatenateUlawCrossfade(t *testing.T) {
+	a := encodeUlawSamples(sineWave(8000, 440, 8000, 0.5))
+	b := encodeUlawSamples(sineWave(4000, 660, 8000, 0.5))
+
+	if got := ConcatenateUlaw([][]byte{a, b}, 0, FadeCosine); len(got) != len(a)+len(b) {
+		t.Fatalf("hard cut length %d, want %d", len(got), len(a)+len(b))
+	}
+
+	// 20 ms at 8 kHz overlap
+	joined := ConcatenateUlaw([][]byte{a, b}, 20, FadeCosine)
+	if want := len(a) + len(b) - 160; len(joined) != want {
+		t.Fatalf("crossfaded length %d, want %d", len(joined), want)
+	}
+
+	// The overlap blends both tones: the first tone dominates its start, the
+	// second its end
+	region := decodeUlawSamples(joined[len(a)-160 : len(a)])
+	first, second := region[:40], region[120:]
+	if toneLevel(first, 440, 8000) < toneLevel(first, 660, 8000) {
+		t.Error("start of the crossfade should be dominated by the first segment")
+	}
+	if toneLevel(second, 660, 8000) < toneLevel(second, 440, 8000) {
+		t.Error("end of the crossfade should be dominated by the second segment")
+	}
+
+	// A crossfade longer than a segment is limited to the segment
+	short := encodeUlawSamples(sineWave(80, 660, 8000, 0.5))
+	if got := ConcatenateUlaw([][]byte{a, short}, 20, FadeLinear); len(got) != len(a) {
+		t.Fatalf("short segment join length %d, want %d", len(got), len(a))
+	}
+}
+
+func TestLoopUlaw(t *testing.T) {
+	clip := encodeUlawSamples(sineWave(4000, 440, 8000, 0.5))
+
+	if got := LoopUlaw(clip, 3, 0, 0, FadeCosine); len(got) != 3*len(clip) || string(got[len(clip):2*len(clip)]) != string(clip) {
+		t.Fatalf("plain loop length %d, want %d copies of the clip", len(got), 3)
+	}
+
+	// 1.6 s needs four 0.5 s copies, each loop point overlapping by 10 ms
+	got := LoopUlaw(clip, 1, 1600*time.Millisecond, 10, FadeCosine)
+	if want := 4*len(clip) - 3*80; len(got) != want {
+		t.Fatalf("crossfaded loop length %d, want %d", len(got), want)
+	}
+	if n := LoopedLength(len(clip), 1, 1600*time.Millisecond, 10); n != len(got) {
+		t.Errorf("LoopedLength %d, want %d", n, len(got))
+	}
+
+	// An exact fit needs no extra copy
+	if got := LoopUlaw(clip, 0, time.Second, 0, FadeCosine); len(got) != 2*len(clip) {
+		t.Fatalf("exact loop length %d, want %d", len(got), 2*len(clip))
+	}
+
+	// A crossfade longer than the clip is limited to half of it
+	if got := LoopUlaw(clip, 2, 0, 1000, FadeLinear); len(got) != 2*len(clip)-len(clip)/2 {
+		t.Fatalf("long crossfade loop length %d, want %d", len(got), 2*len(clip)-len(clip)/2)
+	}
+}
@@ -0,0 +1,373 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Highest input rate assumed when validating a loaded config that leaves
+// InputSampleRate to the WAV header, so only cutoffs no input could use fail
+const jsonMaxSampleRate = 384000
+
+// Names of the enumerated settings in JSON, indexed by value; filters use
+// selfTestFilterNames
+var (
+	resampleMethodNames = []string{"sinc", "fft"}
+	tradeoffNames       = []string{"balanced", "fast", "best"}
+	windowFunctionNames = []string{"blackman", "kaiser", "hann", "hamming", "blackman-harris"}
+	fadeShapeNames      = []string{"linear", "cosine"}
+	noiseColorNames     = []string{"white", "pink"}
+)
+
+// enumName returns the name of value v of kind
+func enumName(names []string, v int, kind string) ([]byte, error) {
+	if v < 0 || v >= len(names) {
+		return nil, fmt.Errorf("invalid %s %d", kind, v)
+	}
+	return []byte(names[v]), nil
+}
+
+// enumValue returns the value named text of kind
+func enumValue(names []string, text []byte, kind string) (int, error) {
+	for i, name := range names {
+		if name == string(text) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown %s '%s'", kind, text)
+}
+
+// MarshalText returns the filter's name, e.g. "butterworth"
+func (t AntiAliasingType) MarshalText() ([]byte, error) {
+	return enumName(selfTestFilterNames, int(t), "anti-aliasing type")
+}
+
+// UnmarshalText parses a name produced by MarshalText
+func (t *AntiAliasingType) UnmarshalText(text []byte) error {
+	v, err := enumValue(selfTestFilterNames, text, "anti-aliasing type")
+	*t = AntiAliasingType(v)
+	return err
+}
+
+// MarshalText returns the method's name, "sinc" or "fft"
+func (m ResampleMethod) MarshalText() ([]byte, error) {
+	return enumName(resampleMethodNames, int(m), "resample method")
+}
+
+// UnmarshalText parses a name produced by MarshalText
+func (m *ResampleMethod) UnmarshalText(text []byte) error {
+	v, err := enumValue(resampleMethodNames, text, "resample method")
+	*m = ResampleMethod(v)
+	return err
+}
+
+// MarshalText returns the tradeoff's name, e.g. "fast"
+func (t ResampleTradeoff) MarshalText() ([]byte, error) {
+	return enumName(tradeoffNames, int(t), "resample tradeoff")
+}
+
+// UnmarshalText parses a name produced by MarshalText
+func (t *ResampleTradeoff) UnmarshalText(text []byte) error {
+	v, err := enumValue(tradeoffNames, text, "resample tradeoff")
+	*t = ResampleTradeoff(v)
+	return err
+}
+
+// MarshalText returns the window's name, e.g. "kaiser"
+func (w WindowFunction) MarshalText() ([]byte, error) {
+	return enumName(windowFunctionNames, int(w), "window function")
+}
+
+// UnmarshalText parses a name produced by MarshalText
+func (w *WindowFunction) UnmarshalText(text []byte) error {
+	v, err := enumValue(windowFunctionNames, text, "window function")
+	*w = WindowFunction(v)
+	return err
+}
+
+// MarshalText returns the shape's name, "linear" or "cosine"
+func (s FadeShape) MarshalText() ([]byte, error) {
+	return enumName(fadeShapeNames, int(s), "fade shape")
+}
+
+// UnmarshalText parses a name produced by MarshalText
+func (s *FadeShape) UnmarshalText(text []byte) error {
+	v, err := enumValue(fadeShapeNames, text, "fade shape")
+	*s = FadeShape(v)
+	return err
+}
+
+// MarshalText returns the color's name, "white" or "pink"
+func (c NoiseColor) MarshalText() ([]byte, error) {
+	return enumName(noiseColorNames, int(c), "noise color")
+}
+
+// UnmarshalText parses a name produced by MarshalText
+func (c *NoiseColor) UnmarshalText(text []byte) error {
+	v, err := enumValue(noiseColorNames, text, "noise color")
+	*c = NoiseColor(v)
+	return err
+}
+
+// MarshalText returns the variant as String does
+func (v UlawVariant) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+// UnmarshalText parses the names accepted by ParseUlawVariant
+func (v *UlawVariant) UnmarshalText(text []byte) error {
+	parsed, err := ParseUlawVariant(string(text))
+	*v = parsed
+	return err
+}
+
+// jsonDuration is a time.Duration written in JSON as a string such as "300ms"
+type jsonDuration time.Duration
+
+func (d jsonDuration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *jsonDuration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return fmt.Errorf("invalid duration '%s'", text)
+	}
+	*d = jsonDuration(parsed)
+	return nil
+}
+
+// audioConfigJSON is the JSON form of AudioConfig. Callbacks and the logger
+// are not serialized, enumerations are written by name and durations as
+// strings.
+type audioConfigJSON struct {
+	InputSampleRate         int              `json:"inputSampleRate"`
+	ForceMono               bool             `json:"forceMono"`
+	LowPassCutoff           float64          `json:"lowPassCutoff"`
+	HighPassCutoff          float64          `json:"highPassCutoff"`
+	NormalizePeak           float64          `json:"normalizePeak"`
+	TargetLoudness          float64          `json:"targetLoudness"`
+	Tempo                   float64          `json:"tempo"`
+	Speed                   float64          `json:"speed"`
+	Reverse                 bool             `json:"reverse"`
+	CompressionRatio        float64          `json:"compressionRatio"`
+	CompressionThreshold    float64          `json:"compressionThreshold"`
+	LevelCompensation       bool             `json:"levelCompensation"`
+	FadeInMs                float64          `json:"fadeInMs"`
+	FadeOutMs               float64          `json:"fadeOutMs"`
+	FadeShape               FadeShape        `json:"fadeShape"`
+	AutoTrim                *autoTrimJSON    `json:"autoTrim,omitempty"`
+	PadStart                jsonDuration     `json:"padStart"`
+	PadEnd                  jsonDuration     `json:"padEnd"`
+	FrameAlign              int              `json:"frameAlign"`
+	Noise                   *noiseJSON       `json:"noise,omitempty"`
+	Beeps                   []beepJSON       `json:"beeps,omitempty"`
+	ResamplingWindowSize    int              `json:"resamplingWindowSize"`
+	ResampleMethod          ResampleMethod   `json:"resampleMethod"`
+	ResampleTradeoff        ResampleTradeoff `json:"resampleTradeoff"`
+	WindowFunction          WindowFunction   `json:"windowFunction"`
+	KaiserBeta              float64          `json:"kaiserBeta"`
+	AntiAliasingCutoffRatio float64          `json:"antiAliasingCutoffRatio"`
+	AntiAliasingType        AntiAliasingType `json:"antiAliasingType"`
+	FilterOrder             int              `json:"filterOrder"`
+	ChebyshevRipple         float64          `json:"chebyshevRipple"`
+	Concurrency             int              `json:"concurrency"`
+	Deterministic           bool             `json:"deterministic"`
+	RealTime                bool             `json:"realTime"`
+	UlawVariant             UlawVariant      `json:"ulawVariant"`
+	OutputCodec             OutputCodec      `json:"outputCodec"`
+	Lenient                 bool             `json:"lenient"`
+	SanityCheck             bool             `json:"sanityCheck"`
+	Limits                  *wavLimitsJSON   `json:"limits,omitempty"`
+}
+
+type autoTrimJSON struct {
+	PreRoll  jsonDuration `json:"preRoll"`
+	PostRoll jsonDuration `json:"postRoll"`
+}
+
+type noiseJSON struct {
+	SNR   float64    `json:"snr"`
+	Data  []byte     `json:"data,omitempty"`
+	Color NoiseColor `json:"color"`
+	Seed  int64      `json:"seed"`
+}
+
+type beepJSON struct {
+	At        jsonDuration `json:"at"`
+	Duration  jsonDuration `json:"duration"`
+	Frequency float64      `json:"frequency"`
+	LevelDbov float64      `json:"levelDbov"`
+	Repeat    jsonDuration `json:"repeat"`
+}
+
+type wavLimitsJSON struct {
+	MaxDataSize   int64        `json:"maxDataSize"`
+	MaxChannels   int          `json:"maxChannels"`
+	MaxSampleRate int          `json:"maxSampleRate"`
+	MaxDuration   jsonDuration `json:"maxDuration"`
+}
+
+// MarshalJSON writes the settings of c as a JSON object with lowerCamelCase
+// keys, filter, window and other enumerated settings by name (e.g.
+// "antiAliasingType": "butterworth") and durations as strings such as
+// "300ms". Callbacks and Logger are left out.
+func (c AudioConfig) MarshalJSON() ([]byte, error) {
+	j := audioConfigJSON{
+		InputSampleRate:         c.InputSampleRate,
+		ForceMono:               c.ForceMono,
+		LowPassCutoff:           c.LowPassCutoff,
+		HighPassCutoff:          c.HighPassCutoff,
+		NormalizePeak:           c.NormalizePeak,
+		TargetLoudness:          c.TargetLoudness,
+		Tempo:                   c.Tempo,
+		Speed:                   c.Speed,
+		Reverse:                 c.Reverse,
+		CompressionRatio:        c.CompressionRatio,
+		CompressionThreshold:    c.CompressionThreshold,
+		LevelCompensation:       c.LevelCompensation,
+		FadeInMs:                c.FadeInMs,
+		FadeOutMs:               c.FadeOutMs,
+		FadeShape:               c.FadeShape,
+		PadStart:                jsonDuration(c.PadStart),
+		PadEnd:                  jsonDuration(c.PadEnd),
+		FrameAlign:              c.FrameAlign,
+		ResamplingWindowSize:    c.ResamplingWindowSize,
+		ResampleMethod:          c.ResampleMethod,
+		ResampleTradeoff:        c.ResampleTradeoff,
+		WindowFunction:          c.WindowFunction,
+		KaiserBeta:              c.KaiserBeta,
+		AntiAliasingCutoffRatio: c.AntiAliasingCutoffRatio,
+		AntiAliasingType:        c.AntiAliasingType,
+		FilterOrder:             c.FilterOrder,
+		ChebyshevRipple:         c.ChebyshevRipple,
+		Concurrency:             c.Concurrency,
+		Deterministic:           c.Deterministic,
+		RealTime:                c.RealTime,
+		UlawVariant:             c.UlawVariant,
+		OutputCodec:             c.OutputCodec,
+		Lenient:                 c.Lenient,
+		SanityCheck:             c.SanityCheck,
+	}
+	if c.AutoTrim != nil {
+		j.AutoTrim = &autoTrimJSON{PreRoll: jsonDuration(c.AutoTrim.PreRoll), PostRoll: jsonDuration(c.AutoTrim.PostRoll)}
+	}
+	if c.Noise != nil {
+		j.Noise = &noiseJSON{SNR: c.Noise.SNR, Data: c.Noise.Data, Color: c.Noise.Color, Seed: c.Noise.Seed}
+	}
+	for _, b := range c.Beeps {
+		j.Beeps = append(j.Beeps, beepJSON{
+			At:        jsonDuration(b.At),
+			Duration:  jsonDuration(b.Duration),
+			Frequency: b.Frequency,
+			LevelDbov: b.LevelDbov,
+			Repeat:    jsonDuration(b.Repeat),
+		})
+	}
+	if c.Limits != nil {
+		j.Limits = &wavLimitsJSON{
+			MaxDataSize:   c.Limits.MaxDataSize,
+			MaxChannels:   c.Limits.MaxChannels,
+			MaxSampleRate: c.Limits.MaxSampleRate,
+			MaxDuration:   jsonDuration(c.Limits.MaxDuration),
+		}
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON reads settings written by MarshalJSON. Keys absent from
+// data keep their current value, so decoding into DefaultAudioConfig() or a
+// preset fills in the rest. Unknown keys, unknown names and invalid
+// durations are rejected, and the result must pass the same range checks as
+// a conversion, made against InputSampleRate when it is set; c is left
+// unchanged on error. Callbacks and Logger are kept.
+func (c *AudioConfig) UnmarshalJSON(data []byte) error {
+	current, err := c.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	var j audioConfigJSON
+	if err := json.Unmarshal(current, &j); err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&j); err != nil {
+		return fmt.Errorf("invalid audio config: %v", err)
+	}
+
+	loaded := *c
+	loaded.InputSampleRate = j.InputSampleRate
+	loaded.ForceMono = j.ForceMono
+	loaded.LowPassCutoff = j.LowPassCutoff
+	loaded.HighPassCutoff = j.HighPassCutoff
+	loaded.NormalizePeak = j.NormalizePeak
+	loaded.TargetLoudness = j.TargetLoudness
+	loaded.Tempo = j.Tempo
+	loaded.Speed = j.Speed
+	loaded.Reverse = j.Reverse
+	loaded.CompressionRatio = j.CompressionRatio
+	loaded.CompressionThreshold = j.CompressionThreshold
+	loaded.LevelCompensation = j.LevelCompensation
+	loaded.FadeInMs = j.FadeInMs
+	loaded.FadeOutMs = j.FadeOutMs
+	loaded.FadeShape = j.FadeShape
+	loaded.AutoTrim = nil
+	if j.AutoTrim != nil {
+		loaded.AutoTrim = &AutoTrim{PreRoll: time.Duration(j.AutoTrim.PreRoll), PostRoll: time.Duration(j.AutoTrim.PostRoll)}
+	}
+	loaded.PadStart = time.Duration(j.PadStart)
+	loaded.PadEnd = time.Duration(j.PadEnd)
+	loaded.FrameAlign = j.FrameAlign
+	loaded.Noise = nil
+	if j.Noise != nil {
+		loaded.Noise = &NoiseOverlay{SNR: j.Noise.SNR, Data: j.Noise.Data, Color: j.Noise.Color, Seed: j.Noise.Seed}
+	}
+	loaded.Beeps = nil
+	for _, b := range j.Beeps {
+		loaded.Beeps = append(loaded.Beeps, Beep{
+			At:        time.Duration(b.At),
+			Duration:  time.Duration(b.Duration),
+			Frequency: b.Frequency,
+			LevelDbov: b.LevelDbov,
+			Repeat:    time.Duration(b.Repeat),
+		})
+	}
+	loaded.ResamplingWindowSize = j.ResamplingWindowSize
+	loaded.ResampleMethod = j.ResampleMethod
+	loaded.ResampleTradeoff = j.ResampleTradeoff
+	loaded.WindowFunction = j.WindowFunction
+	loaded.KaiserBeta = j.KaiserBeta
+	loaded.AntiAliasingCutoffRatio = j.AntiAliasingCutoffRatio
+	loaded.AntiAliasingType = j.AntiAliasingType
+	loaded.FilterOrder = j.FilterOrder
+	loaded.ChebyshevRipple = j.ChebyshevRipple
+	loaded.Concurrency = j.Concurrency
+	loaded.Deterministic = j.Deterministic
+	loaded.RealTime = j.RealTime
+	loaded.UlawVariant = j.UlawVariant
+	loaded.OutputCodec = j.OutputCodec
+	loaded.Lenient = j.Lenient
+	loaded.SanityCheck = j.SanityCheck
+	loaded.Limits = nil
+	if j.Limits != nil {
+		loaded.Limits = &WavLimits{
+			MaxDataSize:   j.Limits.MaxDataSize,
+			MaxChannels:   j.Limits.MaxChannels,
+			MaxSampleRate: j.Limits.MaxSampleRate,
+			MaxDuration:   time.Duration(j.Limits.MaxDuration),
+		}
+	}
+
+	rate := loaded.InputSampleRate
+	if rate <= 0 {
+		rate = jsonMaxSampleRate
+	}
+	if _, err := validateConfig(&loaded, rate); err != nil {
+		return err
+	}
+	*c = loaded
+	return nil
+}
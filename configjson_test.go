@@ -0,0 +1,78 @@
+package wav2ulaw
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAudioConfigJSONRoundTrip(t *testing.T) {
+	config := DefaultAudioConfig()
+	config.AntiAliasingType = AAChebyshev
+	config.WindowFunction = WindowKaiser
+	config.ResampleMethod = ResampleFFT
+	config.FadeShape = FadeCosine
+	config.UlawVariant = UlawZeroTrap
+	config.AutoTrim = &AutoTrim{PreRoll: 50 * time.Millisecond, PostRoll: 100 * time.Millisecond}
+	config.Noise = &NoiseOverlay{SNR: 20, Color: NoisePink, Seed: 7}
+	config.Beeps = []Beep{{At: time.Second, Duration: 200 * time.Millisecond, Frequency: 1400, LevelDbov: -12}}
+	config.Limits = &WavLimits{MaxDataSize: 1 << 20, MaxDuration: time.Minute}
+
+	data, err := json.Marshal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"antiAliasingType":"chebyshev"`, `"windowFunction":"kaiser"`, `"resampleMethod":"fft"`, `"color":"pink"`, `"preRoll":"50ms"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("%s missing from %s", want, data)
+		}
+	}
+
+	loaded := &AudioConfig{}
+	if err := json.Unmarshal(data, loaded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, config) {
+		t.Errorf("round trip changed the config:\n got %+v\nwant %+v", loaded, config)
+	}
+}
+
+func TestAudioConfigJSONKeepsAbsentKeys(t *testing.T) {
+	config := DefaultAudioConfig()
+	if err := json.Unmarshal([]byte(`{"antiAliasingType": "bessel", "padStart": "1.5s"}`), config); err != nil {
+		t.Fatal(err)
+	}
+	want := DefaultAudioConfig()
+	want.AntiAliasingType = AABessel
+	want.PadStart = 1500 * time.Millisecond
+	if !reflect.DeepEqual(config, want) {
+		t.Errorf("got %+v, want %+v", config, want)
+	}
+}
+
+func TestAudioConfigJSONRejects(t *testing.T) {
+	for _, data := range []string{
+		`{"antiAliasingType": "elliptic"}`,
+		`{"filterType": "bessel"}`,
+		`{"padEnd": "soon"}`,
+		`{"ulawVariant": "inverted"}`,
+	} {
+		config := DefaultAudioConfig()
+		if err := json.Unmarshal([]byte(data), config); err == nil {
+			t.Errorf("%s: expected an error", data)
+		}
+		if !reflect.DeepEqual(config, DefaultAudioConfig()) {
+			t.Errorf("%s: config changed on error", data)
+		}
+	}
+
+	config := DefaultAudioConfig()
+	err := json.Unmarshal([]byte(`{"inputSampleRate": 16000, "highPassCutoff": 9000}`), config)
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) || configErr.Field != "HighPassCutoff" {
+		t.Errorf("got %v, want a HighPassCutoff ConfigError", err)
+	}
+}
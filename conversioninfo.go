@@ -0,0 +1,84 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"time"
+)
+
+// ConversionInfo describes what a conversion found in its input and did to
+// it, so callers need not analyze the audio again
+type ConversionInfo struct {
+	// Format of the input as declared by its header
+	SampleRate int
+	Channels   int
+	BitDepth   int
+	// Length of the input audio and of the u-law output
+	InputDuration  time.Duration
+	OutputDuration time.Duration
+	// Rate the audio was resampled from, after InputSampleRate and Speed,
+	// and the ratio of 8 kHz to it
+	ResampleRate  int
+	ResampleRatio float64
+	// Gain of the level stages, LevelCompensation, NormalizePeak or
+	// TargetLoudness, before limiting (dB, 0 without them)
+	GainDb float64
+	// Clipped regions of the input, as passed to AudioConfig.OnClipping
+	Clipping []ClipRegion
+	// Samples of the output in runs at full scale after processing
+	ClippedSamples int
+	// Talk time of each channel of two-channel input, as from AnalyzeTalk,
+	// nil for other input and for conversions streamed by ConvertWav
+	Talk *TalkStats
+}
+
+// ConvertWavBytesToUlawInfo converts WAV bytes like ConvertWavBytesToUlaw
+// and also returns a ConversionInfo describing the conversion. Finding the
+// clipped regions of the input costs a pass over it, which
+// ConvertWavBytesToUlaw only makes when AudioConfig.OnClipping is set, and
+// two-channel input is also run through the voice activity detector.
+func ConvertWavBytesToUlawInfo(wavBytes []byte, config *AudioConfig) ([]byte, *ConversionInfo, error) {
+	if config == nil {
+		config = DefaultAudioConfig()
+	}
+	header, err := inspectWav(bytes.NewReader(wavBytes), config.Limits)
+	if err != nil {
+		return nil, nil, err
+	}
+	info := newConversionInfo(header)
+	ulaw, err := convertWavBytes(wavBytes, info.recording(config), info)
+	if err != nil {
+		return nil, nil, err
+	}
+	info.OutputDuration = UlawDuration(len(ulaw))
+	if header.channels == 2 {
+		if info.Talk, err = AnalyzeTalk(wavBytes); err != nil {
+			return nil, nil, err
+		}
+	}
+	return ulaw, info, nil
+}
+
+// newConversionInfo starts the info of a conversion of the WAV file with
+// header
+func newConversionInfo(header *wavHeader) *ConversionInfo {
+	frames := header.dataSize / int64(header.channels*header.bitDepth/8)
+	return &ConversionInfo{
+		SampleRate:    header.sampleRate,
+		Channels:      header.channels,
+		BitDepth:      header.bitDepth,
+		InputDuration: time.Duration(frames) * time.Second / time.Duration(header.sampleRate),
+	}
+}
+
+// recording returns a copy of config that also records the clipped regions
+// of the input in info
+func (info *ConversionInfo) recording(config *AudioConfig) *AudioConfig {
+	recorded := *config
+	recorded.OnClipping = func(region ClipRegion) {
+		if config.OnClipping != nil {
+			config.OnClipping(region)
+		}
+		info.Clipping = append(info.Clipping, region)
+	}
+	return &recorded
+}
@@ -0,0 +1,68 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestConvertWavBytesToUlawInfo(t *testing.T) {
+	config := DefaultAudioConfig()
+	config.CompressionRatio = 1
+	input := sineWave(32000, 1000, 16000, 0.2)
+	wavBytes := encodeWavDepth(t, input, 16000, 24, 2)
+
+	ulaw, info, err := ConvertWavBytesToUlawInfo(wavBytes, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := ConvertWavBytesToUlaw(wavBytes, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(ulaw, plain) {
+		t.Error("output differs from ConvertWavBytesToUlaw")
+	}
+
+	if info.SampleRate != 16000 || info.Channels != 2 || info.BitDepth != 24 {
+		t.Errorf("input format %d Hz, %d channels, %d-bit, want 16000 Hz, 2 channels, 24-bit", info.SampleRate, info.Channels, info.BitDepth)
+	}
+	if info.InputDuration != 2*time.Second || info.OutputDuration != 2*time.Second {
+		t.Errorf("durations %v in, %v out, want 2s", info.InputDuration, info.OutputDuration)
+	}
+	if info.ResampleRate != 16000 || info.ResampleRatio != 0.5 {
+		t.Errorf("resampled from %d Hz at %g, want 16000 Hz at 0.5", info.ResampleRate, info.ResampleRatio)
+	}
+	if len(info.Clipping) != 0 || info.ClippedSamples != 0 {
+		t.Errorf("clean input reported %d clipped regions, %d clipped output samples", len(info.Clipping), info.ClippedSamples)
+	}
+
+	// Without filters, normalization alone brings the tone from 0.2 to 0.95
+	unfiltered := *config
+	unfiltered.HighPassCutoff, unfiltered.LowPassCutoff = 0, 0
+	input = sineWave(8000, 1000, 8000, 0.2)
+	ulaw, info, err = ConvertWavBytesToUlawInfo(encodeWavDepth(t, input, 8000, 16, 1), &unfiltered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	measured := 20 * math.Log10(toneLevel(decodeUlawSamples(ulaw), 1000, 8000)/toneLevel(input, 1000, 8000))
+	if math.Abs(info.GainDb-20*math.Log10(0.95/0.2)) > 0.05 || math.Abs(info.GainDb-measured) > 0.2 {
+		t.Errorf("gain %.2f dB, measured %.2f dB, want %.2f dB", info.GainDb, measured, 20*math.Log10(0.95/0.2))
+	}
+
+	// Clipped input is reported, and to OnClipping as well
+	clipped := make([]int16, 16000)
+	for i := range clipped {
+		clipped[i] = int16(max(-32768, min(32767, 1.5*32767*math.Sin(2*math.Pi*440*float64(i)/16000))))
+	}
+	reported := 0
+	config.OnClipping = func(ClipRegion) { reported++ }
+	_, info, err = ConvertWavBytesToUlawInfo(encodeWavDepth(t, clipped, 16000, 16, 1), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info.Clipping) == 0 || reported != len(info.Clipping) {
+		t.Errorf("%d clipped regions recorded, %d reported, want the same nonzero count", len(info.Clipping), reported)
+	}
+}
@@ -0,0 +1,236 @@
+package wav2ulaw
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/go-audio/audio"
+	"github.com/go-audio/wav"
+)
+
+// Length of the corpus fixtures that are not edge cases (s)
+const corpusSeconds = 0.5
+
+// CorpusFixture is one WAV input of the golden corpus, together with the
+// u-law this version of the library produces for it
+type CorpusFixture struct {
+	// File name without extension, e.g. "tone-44100-24bit-stereo"
+	Name string
+	// What the fixture exercises
+	Description string
+	// Input format
+	SampleRate, BitDepth, Channels int
+	// The input WAV file
+	Wav []byte
+	// Expected output, as converted by GoldenCorpus (nil from CorpusInputs)
+	Ulaw []byte
+}
+
+// corpusInput describes a fixture before it is rendered. signal returns the
+// value of each channel of a frame at time t (s) in -1 to 1.
+type corpusInput struct {
+	name        string
+	description string
+	sampleRate  int
+	bitDepth    int
+	channels    int
+	frames      int
+	signal      func(frame int, t float64, channel int) float64
+}
+
+// twoTone is a 440 Hz and 1 kHz pair at -12 dBFS, inside the telephone band
+func twoTone(_ int, t float64, _ int) float64 {
+	return 0.125*math.Sin(2*math.Pi*440*t) + 0.125*math.Sin(2*math.Pi*1000*t)
+}
+
+// corpusInputs lists the fixtures in a fixed order. Every signal is
+// synthesized from closed-form expressions, so the inputs are identical on
+// every platform.
+func corpusInputs() []corpusInput {
+	var inputs []corpusInput
+	for _, rate := range []int{8000, 11025, 16000, 22050, 32000, 44100, 48000, 96000} {
+		inputs = append(inputs, corpusInput{
+			name:        fmt.Sprintf("tone-%d-16bit-mono", rate),
+			description: fmt.Sprintf("440 Hz and 1 kHz tones at %d Hz", rate),
+			sampleRate:  rate, bitDepth: 16, channels: 1,
+			frames: int(corpusSeconds * float64(rate)),
+			signal: twoTone,
+		})
+	}
+	for _, depth := range []int{8, 24, 32} {
+		inputs = append(inputs, corpusInput{
+			name:        fmt.Sprintf("tone-16000-%dbit-mono", depth),
+			description: fmt.Sprintf("440 Hz and 1 kHz tones as %d-bit PCM", depth),
+			sampleRate:  16000, bitDepth: depth, channels: 1,
+			frames: int(corpusSeconds * 16000),
+			signal: twoTone,
+		})
+	}
+	// Each channel carries its own tone, so a wrong channel mix shows in
+	// the output spectrum
+	channelTone := func(_ int, t float64, channel int) float64 {
+		return 0.25 * math.Sin(2*math.Pi*float64(300+200*channel)*t)
+	}
+	inputs = append(inputs,
+		corpusInput{
+			name:        "tone-44100-16bit-stereo",
+			description: "300 Hz left and 500 Hz right, mixed to mono",
+			sampleRate:  44100, bitDepth: 16, channels: 2,
+			frames: int(corpusSeconds * 44100), signal: channelTone,
+		},
+		corpusInput{
+			name:        "tone-48000-24bit-6ch",
+			description: "a tone from 300 Hz to 1300 Hz in each of six channels, mixed to mono",
+			sampleRate:  48000, bitDepth: 24, channels: 6,
+			frames: int(corpusSeconds * 48000), signal: channelTone,
+		},
+		corpusInput{
+			name:        "sweep-48000-16bit-mono",
+			description: "logarithmic sweep from 20 Hz to 20 kHz, testing the anti-aliasing filter",
+			sampleRate:  48000, bitDepth: 16, channels: 1,
+			frames: int(corpusSeconds * 48000),
+			signal: func(_ int, t float64, _ int) float64 {
+				k := math.Log(20000.0 / 20.0)
+				return 0.5 * math.Sin(2*math.Pi*20*corpusSeconds/k*(math.Exp(t/corpusSeconds*k)-1))
+			},
+		},
+		corpusInput{
+			name:        "clipped-16000-16bit-mono",
+			description: "1 kHz sine driven into full-scale clipping",
+			sampleRate:  16000, bitDepth: 16, channels: 1,
+			frames: int(corpusSeconds * 16000),
+			signal: func(_ int, t float64, _ int) float64 {
+				return 2 * math.Sin(2*math.Pi*1000*t)
+			},
+		},
+		corpusInput{
+			name:        "dc-16000-16bit-mono",
+			description: "constant offset at a quarter of full scale, removed by the high-pass filter",
+			sampleRate:  16000, bitDepth: 16, channels: 1,
+			frames: int(corpusSeconds * 16000),
+			signal: func(int, float64, int) float64 { return 0.25 },
+		},
+		corpusInput{
+			name:        "silence-8000-16bit-mono",
+			description: "digital silence, which normalization must leave silent",
+			sampleRate:  8000, bitDepth: 16, channels: 1,
+			frames: int(corpusSeconds * 8000),
+			signal: func(int, float64, int) float64 { return 0 },
+		},
+		corpusInput{
+			name:        "impulse-44100-16bit-mono",
+			description: "a single full-scale sample followed by silence, showing the filter responses",
+			sampleRate:  44100, bitDepth: 16, channels: 1,
+			frames: int(corpusSeconds * 44100),
+			signal: func(frame int, _ float64, _ int) float64 {
+				if frame == 0 {
+					return 1
+				}
+				return 0
+			},
+		},
+		corpusInput{
+			name:        "nyquist-8000-16bit-mono",
+			description: "alternating samples at the Nyquist frequency of 8 kHz input",
+			sampleRate:  8000, bitDepth: 16, channels: 1,
+			frames: int(corpusSeconds * 8000),
+			signal: func(frame int, _ float64, _ int) float64 {
+				return 0.5 * float64(1-2*(frame%2))
+			},
+		},
+	)
+	for _, rate := range []int{8000, 48000} {
+		inputs = append(inputs, corpusInput{
+			name:        fmt.Sprintf("one-sample-%d-16bit-mono", rate),
+			description: fmt.Sprintf("a file holding a single sample at %d Hz", rate),
+			sampleRate:  rate, bitDepth: 16, channels: 1,
+			frames: 1,
+			signal: func(int, float64, int) float64 { return 0.5 },
+		})
+	}
+	inputs = append(inputs, corpusInput{
+		name:        "three-samples-22050-16bit-mono",
+		description: "a file shorter than the resampling window",
+		sampleRate:  22050, bitDepth: 16, channels: 1,
+		frames: 3,
+		signal: func(frame int, _ float64, _ int) float64 { return 0.25 * float64(frame-1) },
+	})
+	return inputs
+}
+
+// CorpusInputs synthesizes the inputs of the golden corpus, a fixed set of
+// WAV files covering common sample rates, bit depths and channel counts and
+// edge cases such as one-sample, DC-only and clipped files. The fixtures
+// have no Ulaw; the inputs are the same on every run and platform.
+func CorpusInputs() ([]CorpusFixture, error) {
+	inputs := corpusInputs()
+	fixtures := make([]CorpusFixture, 0, len(inputs))
+	for _, input := range inputs {
+		wavBytes, err := input.render()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", input.name, err)
+		}
+		fixtures = append(fixtures, CorpusFixture{
+			Name:        input.name,
+			Description: input.description,
+			SampleRate:  input.sampleRate,
+			BitDepth:    input.bitDepth,
+			Channels:    input.channels,
+			Wav:         wavBytes,
+		})
+	}
+	return fixtures, nil
+}
+
+// GoldenCorpus returns the corpus inputs converted with config, or with the
+// defaults and Deterministic set when config is nil, so the expected output
+// does not depend on the entry point or the number of CPUs. The outputs
+// change only when the processing does, so projects integrating the library
+// can check them into their tests to catch regressions.
+func GoldenCorpus(config *AudioConfig) ([]CorpusFixture, error) {
+	if config == nil {
+		config = DefaultAudioConfig()
+		config.Deterministic = true
+	}
+	fixtures, err := CorpusInputs()
+	if err != nil {
+		return nil, err
+	}
+	for i := range fixtures {
+		if fixtures[i].Ulaw, err = ConvertWavBytesToUlaw(fixtures[i].Wav, config); err != nil {
+			return nil, fmt.Errorf("%s: %v", fixtures[i].Name, err)
+		}
+	}
+	return fixtures, nil
+}
+
+// render quantizes the input's signal to its bit depth, clipping values
+// beyond full scale, and encodes it as a WAV file
+func (c corpusInput) render() ([]byte, error) {
+	buf := &audio.IntBuffer{
+		Format:         &audio.Format{NumChannels: c.channels, SampleRate: c.sampleRate},
+		Data:           make([]int, c.frames*c.channels),
+		SourceBitDepth: c.bitDepth,
+	}
+	fullScale := math.Ldexp(1, c.bitDepth-1) - 1
+	for frame := 0; frame < c.frames; frame++ {
+		t := float64(frame) / float64(c.sampleRate)
+		for ch := 0; ch < c.channels; ch++ {
+			v := int(math.Round(math.Max(-1, math.Min(1, c.signal(frame, t, ch))) * fullScale))
+			if c.bitDepth == 8 {
+				v += 128
+			}
+			buf.Data[frame*c.channels+ch] = v
+		}
+	}
+
+	out := &writeSeeker{}
+	enc := wav.NewEncoder(out, c.sampleRate, c.bitDepth, c.channels, 1)
+	if err := enc.Write(buf); err != nil {
+		return nil, fmt.Errorf("error writing WAV data: %v", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("error closing WAV encoder: %v", err)
+	}
+	return out.buf, nil
+}
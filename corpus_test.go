@@ -0,0 +1,56 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGoldenCorpus(t *testing.T) {
+	fixtures, err := GoldenCorpus(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := GoldenCorpus(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := DefaultAudioConfig()
+	config.Deterministic = true
+	names := make(map[string]bool)
+	for i, f := range fixtures {
+		if names[f.Name] {
+			t.Errorf("duplicate fixture name %s", f.Name)
+		}
+		names[f.Name] = true
+		if !bytes.Equal(f.Wav, again[i].Wav) || !bytes.Equal(f.Ulaw, again[i].Ulaw) {
+			t.Errorf("%s: differs between runs", f.Name)
+		}
+
+		stats, format, err := Analyze(f.Wav)
+		if err != nil {
+			t.Fatalf("%s: %v", f.Name, err)
+		}
+		if format != FormatWAV || stats.SampleRate != f.SampleRate || stats.BitDepth != f.BitDepth || stats.Channels != f.Channels {
+			t.Errorf("%s: decoded as %v %d Hz %d-bit %d channels", f.Name, format, stats.SampleRate, stats.BitDepth, stats.Channels)
+		}
+		if len(f.Ulaw) == 0 {
+			t.Errorf("%s: empty output", f.Name)
+		}
+
+		// The expected output holds whichever entry point converts the file
+		var streamed bytes.Buffer
+		if err := ConvertWavStreamToUlaw(bytes.NewReader(f.Wav), &streamed, config); err != nil {
+			t.Fatalf("%s: %v", f.Name, err)
+		}
+		if !bytes.Equal(streamed.Bytes(), f.Ulaw) {
+			t.Errorf("%s: streamed conversion differs from the expected output", f.Name)
+		}
+	}
+
+	for _, name := range []string{"one-sample-8000-16bit-mono", "silence-8000-16bit-mono"} {
+		if !names[name] {
+			t.Errorf("missing fixture %s", name)
+		}
+	}
+}
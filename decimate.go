@@ -0,0 +1,151 @@
+package wav2ulaw
+
+import "math"
+
+// decimationStages splits an integer decimation factor into 2:1 and 3:1
+// stages. It returns nil when the factor has other prime factors.
+func decimationStages(factor int) []int {
+	if factor < 2 {
+		return nil
+	}
+	var stages []int
+	for factor%2 == 0 {
+		stages = append(stages, 2)
+		factor /= 2
+	}
+	for factor%3 == 0 {
+		stages = append(stages, 3)
+		factor /= 3
+	}
+	if factor != 1 {
+		return nil
+	}
+	return stages
+}
+
+// designLowpassFIR designs a windowed-sinc low-pass kernel with the given
+// cutoff in cycles per sample (0.0 to 0.5) and unity gain at DC
+func designLowpassFIR(halfLength int, cutoff float64, window []float64) []float64 {
+	kernel := make([]float64, halfLength*2+1)
+	sum := 0.0
+	for n := -halfLength; n <= halfLength; n++ {
+		x := 2 * math.Pi * cutoff * float64(n)
+		v := 2 * cutoff
+		if x != 0 {
+			v = math.Sin(x) / (math.Pi * float64(n))
+		}
+		kernel[n+halfLength] = v * window[n+halfLength]
+		sum += kernel[n+halfLength]
+	}
+	for i := range kernel {
+		kernel[i] /= sum
+	}
+	return kernel
+}
+
+// convolveKernels returns the kernel equivalent to filtering with a then b
+func convolveKernels(a, b []float64) []float64 {
+	out := make([]float64, len(a)+len(b)-1)
+	for i, x := range a {
+		for j, y := range b {
+			out[i+j] += x * y
+		}
+	}
+	return out
+}
+
+// applyFIRFilter convolves samples with a centered odd-length kernel
+func applyFIRFilter(samples []int16, kernel []float64) []int16 {
+	signal := getFloat64s(len(samples))
+	for i, sample := range samples {
+		signal[i] = float64(sample)
+	}
+	filtered := decimateStage(signal, 1, kernel)
+	putFloat64s(signal)
+
+	output := getInt16s(len(filtered))
+	for i, v := range filtered {
+		output[i] = int16(math.Max(-32768, math.Min(32767, math.Round(v))))
+	}
+	putFloat64s(filtered)
+	return output
+}
+
+// designHalfbandFIR designs a 2:1 half-band kernel. Every other tap except
+// the center is exactly zero, which decimateStage skips.
+func designHalfbandFIR(halfLength int, window []float64) []float64 {
+	kernel := designLowpassFIR(halfLength, 0.25, window)
+	for n := -halfLength; n <= halfLength; n++ {
+		if n != 0 && n%2 == 0 {
+			kernel[n+halfLength] = 0
+		}
+	}
+	return kernel
+}
+
+// decimateStage low-pass filters and keeps every factor-th sample
+func decimateStage(input []float64, factor int, kernel []float64) []float64 {
+	halfLength := len(kernel) / 2
+	output := getFloat64s(resampledLength(len(input), 1, factor))
+
+	// Collect the non-zero taps once so half-band kernels cost half as much
+	var offsets []int
+	var weights []float64
+	for k, w := range kernel {
+		if w != 0 {
+			offsets = append(offsets, k-halfLength)
+			weights = append(weights, w)
+		}
+	}
+
+	for i := range output {
+		center := i * factor
+		sum := 0.0
+		if center-halfLength >= 0 && center+halfLength < len(input) {
+			for k, off := range offsets {
+				sum += input[center+off] * weights[k]
+			}
+		} else {
+			// Edges: the kernel reaches past the input, which is mirrored
+			for k, off := range offsets {
+				sum += input[reflectIndex(center+off, len(input))] * weights[k]
+			}
+		}
+		output[i] = sum
+	}
+
+	return output
+}
+
+// decimatePCM16 reduces the sample rate by an integer factor made of 2:1
+// half-band and 3:1 polyphase stages, e.g. 16 kHz (2) or 48 kHz (2, 3) to 8 kHz.
+// The window configures kernels with windowSize*2+1 taps per stage. A non-nil
+// prefilter is convolved into the first stage kernel, which runs at the input rate.
+func decimatePCM16(input []int16, stages []int, windowSize int, window []float64, prefilter []float64) []int16 {
+	signal := getFloat64s(len(input))
+	for i, sample := range input {
+		signal[i] = float64(sample)
+	}
+
+	for i, factor := range stages {
+		var kernel []float64
+		if factor == 2 {
+			kernel = designHalfbandFIR(windowSize, window)
+		} else {
+			kernel = designLowpassFIR(windowSize, 0.5/float64(factor), window)
+		}
+		if i == 0 && prefilter != nil {
+			kernel = convolveKernels(kernel, prefilter)
+		}
+		next := decimateStage(signal, factor, kernel)
+		putFloat64s(signal)
+		signal = next
+	}
+
+	output := getInt16s(len(signal))
+	for i, v := range signal {
+		output[i] = int16(math.Max(-32768, math.Min(32767, math.Round(v))))
+	}
+	putFloat64s(signal)
+	return output
+}
@@ -0,0 +1,36 @@
+package wav2ulaw
+
+// dotFloat32 returns the dot product of x and w, which must have the same
+// length. Eight independent accumulators break the add dependency chain so
+// the loop runs close to the CPU's multiply-add throughput, and the
+// up-front reslice lets the compiler drop bounds checks in the loop body.
+func dotFloat32(x, w []float32) float32 {
+	x = x[:len(w)]
+	var s0, s1, s2, s3, s4, s5, s6, s7 float32
+	i := 0
+	for ; i+8 <= len(w); i += 8 {
+		xs, ws := x[i:i+8:i+8], w[i:i+8:i+8]
+		s0 += xs[0] * ws[0]
+		s1 += xs[1] * ws[1]
+		s2 += xs[2] * ws[2]
+		s3 += xs[3] * ws[3]
+		s4 += xs[4] * ws[4]
+		s5 += xs[5] * ws[5]
+		s6 += xs[6] * ws[6]
+		s7 += xs[7] * ws[7]
+	}
+	for ; i < len(w); i++ {
+		s0 += x[i] * w[i]
+	}
+	return ((s0 + s1) + (s2 + s3)) + ((s4 + s5) + (s6 + s7))
+}
+
+// int16sToFloat32 converts samples to a pooled float32 buffer, so inner
+// loops read contiguous floats instead of converting every tap
+func int16sToFloat32(samples []int16) []float32 {
+	out := getFloat32s(len(samples))
+	for i, sample := range samples {
+		out[i] = float32(sample)
+	}
+	return out
+}
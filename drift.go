@@ -0,0 +1,182 @@
+package wav2ulaw
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	// Default maximum deviation of the drift-corrected ratio from nominal (±0.5%)
+	defaultDriftMaxDeviation = 0.005
+	// Default proportional gain applied to the normalized buffer fill error
+	defaultDriftGain = 0.002
+	// Default smoothing factor for ratio updates per chunk (0.0 to 1.0)
+	defaultDriftSmoothing = 0.05
+)
+
+// DriftResampler is a stateful sinc resampler for live streams whose source
+// clock drifts away from its nominal rate (e.g. a "8000 Hz" source that
+// really delivers 7998 samples per second). Each call to Process nudges the
+// conversion ratio based on how full the downstream buffer is, so the buffer
+// settles around its target level instead of slowly under- or overflowing.
+type DriftResampler struct {
+	// Maximum relative deviation from the nominal ratio
+	MaxDeviation float64
+	// Proportional gain applied to the normalized fill error
+	Gain float64
+	// Smoothing factor for ratio updates (smaller = slower, steadier)
+	Smoothing float64
+
+	nominalRatio float64
+	ratio        float64
+	windowSize   int
+	window       []float64
+	history      []float64
+	pos          float64
+}
+
+// NewDriftResampler creates a drift-correcting resampler converting from the
+// nominal input rate to the output rate with the given sinc window half-width
+func NewDriftResampler(inputRate, outputRate, windowSize int) *DriftResampler {
+	ratio := float64(outputRate) / float64(inputRate)
+	return &DriftResampler{
+		MaxDeviation: defaultDriftMaxDeviation,
+		Gain:         defaultDriftGain,
+		Smoothing:    defaultDriftSmoothing,
+		nominalRatio: ratio,
+		ratio:        ratio,
+		windowSize:   windowSize,
+		window:       makeWindow(WindowBlackman, windowSize*2+1, 0),
+		// Leading zeros let the first output sample use a full window
+		history: make([]float64, windowSize),
+		pos:     float64(windowSize),
+	}
+}
+
+// Ratio returns the current effective output/input ratio
+func (r *DriftResampler) Ratio() float64 {
+	return r.ratio
+}
+
+// Process resamples the next chunk of input. fill is the current level of the
+// buffer fed by the output and target is the level it should hover around,
+// both in samples. A fuller-than-target buffer lowers the ratio so fewer
+// samples are produced, an emptier one raises it.
+func (r *DriftResampler) Process(input []int16, fill, target int) []int16 {
+	if target > 0 {
+		fillError := float64(fill-target) / float64(target)
+		desired := r.nominalRatio * (1 - r.Gain*fillError)
+		lo := r.nominalRatio * (1 - r.MaxDeviation)
+		hi := r.nominalRatio * (1 + r.MaxDeviation)
+		desired = math.Max(lo, math.Min(hi, desired))
+		r.ratio += r.Smoothing * (desired - r.ratio)
+	}
+
+	for _, sample := range input {
+		r.history = append(r.history, float64(sample))
+	}
+	return r.drain(len(r.history) - r.windowSize)
+}
+
+// Flush emits the remaining buffered output, padding the tail with silence
+func (r *DriftResampler) Flush() []int16 {
+	end := len(r.history)
+	r.history = append(r.history, make([]float64, r.windowSize)...)
+	output := r.drain(end)
+	r.history = r.history[:0]
+	r.history = append(r.history, make([]float64, r.windowSize)...)
+	r.pos = float64(r.windowSize)
+	return output
+}
+
+// MarshalBinary serializes the current ratio, read position and history.
+// The state is restored with UnmarshalBinary into a resampler created with
+// the same rates and window size; the tuning fields are not included.
+func (r *DriftResampler) MarshalBinary() ([]byte, error) {
+	w := newStateWriter(stateResampler)
+	r.saveState(w)
+	return w.buf, nil
+}
+
+// UnmarshalBinary restores state serialized by MarshalBinary, failing
+// without changing the resampler when it does not match
+func (r *DriftResampler) UnmarshalBinary(data []byte) error {
+	restored := r.clone()
+	rd := newStateReader(data, stateResampler)
+	restored.loadState(rd)
+	if err := rd.finish(); err != nil {
+		return err
+	}
+	*r = *restored
+	return nil
+}
+
+// clone returns an independent copy of the resampler and its state
+func (r *DriftResampler) clone() *DriftResampler {
+	c := *r
+	c.history = append([]float64(nil), r.history...)
+	return &c
+}
+
+func (r *DriftResampler) saveState(w *stateWriter) {
+	w.int(r.windowSize)
+	w.float(r.nominalRatio)
+	w.float(r.ratio)
+	w.float(r.pos)
+	w.floats(r.history)
+}
+
+func (r *DriftResampler) loadState(rd *stateReader) {
+	rd.expect("resampler window size", r.windowSize)
+	if nominal := rd.float(); rd.err == nil && nominal != r.nominalRatio {
+		rd.err = fmt.Errorf("%w: resampler ratio is %g, want %g", ErrStateMismatch, nominal, r.nominalRatio)
+	}
+	ratio, pos, history := rd.float(), rd.float(), rd.floats()
+	if rd.err == nil {
+		r.ratio, r.pos, r.history = ratio, pos, history
+	}
+}
+
+// drain produces output samples while the read position is below limit,
+// then discards history the window can no longer reach
+func (r *DriftResampler) drain(limit int) []int16 {
+	var output []int16
+	step := 1.0 / r.ratio
+
+	for r.pos < float64(limit) {
+		idx := int(r.pos)
+		sum := 0.0
+		weightSum := 0.0
+		for j := -r.windowSize; j <= r.windowSize; j++ {
+			inputIdx := idx + j
+			if inputIdx < 0 || inputIdx >= len(r.history) {
+				continue
+			}
+			x := math.Pi * (r.pos - float64(inputIdx))
+			sinc := 1.0
+			if x != 0 {
+				sinc = math.Sin(x) / x
+			}
+			weight := r.window[j+r.windowSize] * sinc
+			sum += r.history[inputIdx] * weight
+			weightSum += weight
+		}
+		if weightSum > 0 {
+			sum /= weightSum
+		}
+		output = append(output, int16(math.Max(-32768, math.Min(32767, math.Round(sum)))))
+		r.pos += step
+	}
+
+	// Keep only the samples still inside the window of the next output
+	drop := int(r.pos) - r.windowSize
+	if drop > 0 {
+		if drop > len(r.history) {
+			drop = len(r.history)
+		}
+		r.history = append(r.history[:0], r.history[drop:]...)
+		r.pos -= float64(drop)
+	}
+
+	return output
+}
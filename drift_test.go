@@ -0,0 +1,30 @@
+package wav2ulaw
+
+import "testing"
+
+func TestDriftResamplerTracksBufferFill(t *testing.T) {
+	chunk := sineWave(160, 440, 8000, 0.5)
+
+	nominal := NewDriftResampler(8000, 8000, 16)
+	full := NewDriftResampler(8000, 8000, 16)
+
+	var nominalOut, fullOut int
+	for i := 0; i < 500; i++ {
+		nominalOut += len(nominal.Process(chunk, 800, 800))
+		// Downstream buffer is persistently 50% above target
+		fullOut += len(full.Process(chunk, 1200, 800))
+	}
+
+	if full.Ratio() >= nominal.Ratio() {
+		t.Fatalf("expected ratio below nominal, got %f vs %f", full.Ratio(), nominal.Ratio())
+	}
+	if full.Ratio() < 1-full.MaxDeviation {
+		t.Fatalf("ratio %f exceeds maximum deviation", full.Ratio())
+	}
+	if fullOut >= nominalOut {
+		t.Errorf("expected fewer output samples with a full buffer: %d vs %d", fullOut, nominalOut)
+	}
+	if diff := 500*160 - nominalOut; diff < 0 || diff > 16 {
+		t.Errorf("nominal ratio should emit all input minus window latency, got %d of %d", nominalOut, 500*160)
+	}
+}
@@ -0,0 +1,164 @@
+package wav2ulaw
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// Goertzel block length at 8 kHz (205 samples gives ~39 Hz resolution)
+	dtmfBlockSize8k = 205
+	// Share of block energy that must fall on the detected row+column tones
+	dtmfMinToneRatio = 0.5
+	// Maximum level difference between row and column tones (dB)
+	dtmfMaxTwistDb = 8.0
+	// Strongest tone must exceed the others in its group by this much (dB)
+	dtmfMinGroupMarginDb = 6.0
+	// Blocks quieter than this are ignored (dBFS power)
+	dtmfMinLevelDb = -45.0
+	// Consecutive detections required before a digit is reported
+	dtmfMinBlocks = 2
+)
+
+var (
+	dtmfRowFreqs = [4]float64{697, 770, 852, 941}
+	dtmfColFreqs = [4]float64{1209, 1336, 1477, 1633}
+	dtmfKeys     = [4][4]rune{
+		{'1', '2', '3', 'A'},
+		{'4', '5', '6', 'B'},
+		{'7', '8', '9', 'C'},
+		{'*', '0', '#', 'D'},
+	}
+)
+
+// DTMFDigit is a detected key press
+type DTMFDigit struct {
+	// Key: 0-9, *, # or A-D
+	Digit rune
+	// Offset of the tone from the start of the audio
+	Start time.Duration
+	// Length of the tone
+	Duration time.Duration
+}
+
+// DetectDTMFWav scans WAV file bytes for DTMF key presses
+func DetectDTMFWav(wavBytes []byte) ([]DTMFDigit, error) {
+	decoded, err := decodeWavFloat(wavBytes)
+	if err != nil {
+		return nil, err
+	}
+	return detectDTMF(decoded.monoMix(), decoded.sampleRate), nil
+}
+
+// DetectDTMFUlaw scans 8 kHz u-law bytes for DTMF key presses
+func DetectDTMFUlaw(ulawBytes []byte) []DTMFDigit {
+	return DetectDTMF(decodeUlawSamples(ulawBytes), 8000)
+}
+
+// DetectDTMF scans 16-bit PCM samples for DTMF key presses using the Goertzel algorithm
+func DetectDTMF(samples []int16, sampleRate int) []DTMFDigit {
+	data := make([]float64, len(samples))
+	for i, sample := range samples {
+		data[i] = float64(sample) / 32768.0
+	}
+	return detectDTMF(data, sampleRate)
+}
+
+// detectDTMF runs half-overlapping Goertzel blocks over normalized samples and
+// merges consecutive blocks with the same key into digits
+func detectDTMF(samples []float64, sampleRate int) []DTMFDigit {
+	blockSize := dtmfBlockSize8k * sampleRate / 8000
+	if blockSize < 1 {
+		return nil
+	}
+	hop := blockSize / 2
+	toDuration := func(n int) time.Duration {
+		return time.Duration(float64(n) / float64(sampleRate) * float64(time.Second))
+	}
+
+	var digits []DTMFDigit
+	current := rune(0)
+	runStart, runBlocks := 0, 0
+
+	flush := func(end int) {
+		if current != 0 && runBlocks >= dtmfMinBlocks {
+			digits = append(digits, DTMFDigit{
+				Digit:    current,
+				Start:    toDuration(runStart),
+				Duration: toDuration(end - runStart),
+			})
+		}
+	}
+
+	for start := 0; start+blockSize <= len(samples); start += hop {
+		key := dtmfBlockKey(samples[start:start+blockSize], float64(sampleRate))
+		if key == current {
+			if key != 0 {
+				runBlocks++
+			}
+			continue
+		}
+		flush(start - hop + blockSize)
+		current = key
+		runStart = start
+		runBlocks = 1
+	}
+	flush(len(samples))
+
+	return digits
+}
+
+// dtmfBlockKey returns the key whose tone pair dominates the block, or 0
+func dtmfBlockKey(block []float64, sampleRate float64) rune {
+	energy := 0.0
+	for _, v := range block {
+		energy += v * v
+	}
+	n := float64(len(block))
+	if energy/n < math.Pow(10, dtmfMinLevelDb/10) {
+		return 0
+	}
+
+	row, rowPower, rowOthers := strongestTone(block, dtmfRowFreqs, sampleRate)
+	col, colPower, colOthers := strongestTone(block, dtmfColFreqs, sampleRate)
+
+	// A pure tone of amplitude A gives Goertzel power (A*N/2)^2 and block energy N*A^2/2
+	if (rowPower+colPower)/(energy*n/2) < dtmfMinToneRatio {
+		return 0
+	}
+	if math.Abs(10*math.Log10(rowPower/colPower)) > dtmfMaxTwistDb {
+		return 0
+	}
+	margin := math.Pow(10, dtmfMinGroupMarginDb/10)
+	if rowPower < rowOthers*margin || colPower < colOthers*margin {
+		return 0
+	}
+	return dtmfKeys[row][col]
+}
+
+// strongestTone returns the index and power of the strongest frequency in the
+// group, plus the power of the runner-up
+func strongestTone(block []float64, freqs [4]float64, sampleRate float64) (int, float64, float64) {
+	best, bestPower, second := 0, 0.0, 0.0
+	for i, f := range freqs {
+		p := goertzelPower(block, f, sampleRate)
+		if p > bestPower {
+			second = bestPower
+			best, bestPower = i, p
+		} else if p > second {
+			second = p
+		}
+	}
+	return best, bestPower, second
+}
+
+// goertzelPower returns the squared magnitude of the DFT of block at freq
+func goertzelPower(block []float64, freq, sampleRate float64) float64 {
+	coeff := 2 * math.Cos(2*math.Pi*freq/sampleRate)
+	var s1, s2 float64
+	for _, v := range block {
+		s := v + coeff*s1 - s2
+		s2, s1 = s1, s
+	}
+	return s1*s1 + s2*s2 - coeff*s1*s2
+}
@@ -0,0 +1,50 @@
+package wav2ulaw
+
+import (
+	"testing"
+	"time"
+)
+
+// dtmfTone synthesizes the tone pair for a key
+func dtmfTone(key rune, n int) []int16 {
+	for r, row := range dtmfKeys {
+		for c, k := range row {
+			if k == key {
+				samples := sineWave(n, dtmfRowFreqs[r], 8000, 0.25)
+				for i, v := range sineWave(n, dtmfColFreqs[c], 8000, 0.25) {
+					samples[i] += v
+				}
+				return samples
+			}
+		}
+	}
+	return nil
+}
+
+func TestDetectDTMF(t *testing.T) {
+	var samples []int16
+	for _, key := range "15#" {
+		samples = append(samples, make([]int16, 800)...) // 100 ms gap
+		samples = append(samples, dtmfTone(key, 800)...) // 100 ms tone
+	}
+	samples = append(samples, make([]int16, 800)...)
+
+	digits := DetectDTMFUlaw(encodeUlawSamples(samples))
+	if len(digits) != 3 {
+		t.Fatalf("expected 3 digits, got %+v", digits)
+	}
+	for i, want := range "15#" {
+		d := digits[i]
+		if d.Digit != want {
+			t.Errorf("digit %d: expected %c, got %c", i, want, d.Digit)
+		}
+		wantStart := time.Duration(100+i*200) * time.Millisecond
+		if d.Start < wantStart-30*time.Millisecond || d.Start > wantStart+30*time.Millisecond {
+			t.Errorf("digit %d: expected start near %v, got %v", i, wantStart, d.Start)
+		}
+	}
+
+	if digits := DetectDTMF(sineWave(8000, 1000, 8000, 0.5), 8000); len(digits) != 0 {
+		t.Errorf("single tone should not be detected as DTMF: %+v", digits)
+	}
+}
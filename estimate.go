@@ -0,0 +1,90 @@
+package wav2ulaw
+
+import (
+	"io"
+	"math"
+	"time"
+)
+
+// wavOutputHeaderSize is the size of the header of the WAV files the library writes
+const wavOutputHeaderSize = 44
+
+// Estimate predicts the result of a conversion without decoding the audio
+type Estimate struct {
+	// Duration of the input audio
+	InputDuration time.Duration
+	// Samples the output will hold (per channel)
+	OutputSamples int
+	// Size of the output in bytes, including any header
+	OutputBytes int64
+	// Duration of the output audio
+	OutputDuration time.Duration
+}
+
+// EstimateWav predicts the u-law output of converting the WAV file in r with
+// config (nil for DefaultAudioConfig). Only the chunk headers are read, not
+// the audio, so uploads can be checked against quotas before they are
+// accepted; the header is validated against config.Limits like a
+// conversion would. The length accounts for resampling, speed, tempo,
+// padding and frame alignment and matches ConvertWavBytesToUlaw, except that tempo changes on
+// inputs shorter than about 50 ms leave their length unchanged. AutoTrim
+// depends on the audio, so with it the length is an upper bound. r is left
+// at the start of the file.
+func EstimateWav(r io.ReadSeeker, config *AudioConfig) (*Estimate, error) {
+	if config == nil {
+		config = DefaultAudioConfig()
+	}
+	header, err := inspectWav(r, config.Limits)
+	if err != nil {
+		return nil, err
+	}
+	inputRate := config.InputSampleRate
+	if inputRate == 0 {
+		inputRate = header.sampleRate
+	}
+
+	frames := int(header.dataSize / int64(header.channels*header.bitDepth/8))
+	// Channels are processed interleaved unless they are mixed down first
+	samples := frames
+	if !config.ForceMono || header.channels == 1 {
+		samples *= header.channels
+	}
+	output := resampledLength(samples, 8000, speedRate(inputRate, config.Speed))
+	if config.Tempo > 0 && config.Tempo != 1.0 {
+		output = int(math.Round(float64(output) / config.Tempo))
+	}
+	output += padLength(config.PadStart, 8000) + padLength(config.PadEnd, 8000)
+	output += framePadding(output, config.FrameAlign)
+
+	return &Estimate{
+		InputDuration:  samplesDuration(frames, inputRate),
+		OutputSamples:  output,
+		OutputBytes:    int64(output),
+		OutputDuration: samplesDuration(output, 8000),
+	}, nil
+}
+
+// EstimateUlaw predicts the WAV output of ConvertUlawBytesToWav for n bytes
+// of u-law converted to sampleRate
+func EstimateUlaw(n int, sampleRate uint32) *Estimate {
+	output := resampledLength(n, int(sampleRate), 8000)
+	return &Estimate{
+		InputDuration:  UlawDuration(n),
+		OutputSamples:  output,
+		OutputBytes:    wavOutputHeaderSize + 2*int64(output),
+		OutputDuration: samplesDuration(output, int(sampleRate)),
+	}
+}
+
+// UlawDuration returns the duration of n bytes of 8 kHz u-law
+func UlawDuration(n int) time.Duration {
+	return samplesDuration(n, 8000)
+}
+
+// samplesDuration returns the duration of n samples at sampleRate
+func samplesDuration(n, sampleRate int) time.Duration {
+	if sampleRate <= 0 {
+		return 0
+	}
+	return time.Duration(n) * time.Second / time.Duration(sampleRate)
+}
@@ -0,0 +1,64 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestEstimateWavMatchesConversion(t *testing.T) {
+	cases := []struct {
+		rate, depth, channels, frames int
+		mono                          bool
+		tempo                         float64
+	}{
+		{8000, 16, 1, 8000, true, 1},
+		{16000, 16, 1, 16001, true, 1},
+		{44100, 24, 2, 44100, true, 1},
+		{22050, 8, 1, 12345, true, 1},
+		{48000, 16, 2, 4800, false, 1},
+		{11025, 32, 1, 11025, true, 1.25},
+	}
+	for _, tc := range cases {
+		wavBytes := encodeWavDepth(t, sineWave(tc.frames, 440, float64(tc.rate), 0.5), tc.rate, tc.depth, tc.channels)
+		config := DefaultAudioConfig()
+		config.ForceMono = tc.mono
+		config.Tempo = tc.tempo
+		config.PadStart = 250 * time.Millisecond
+
+		estimate, err := EstimateWav(bytes.NewReader(wavBytes), config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ulaw, err := ConvertWavBytesToUlaw(wavBytes, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if estimate.OutputSamples != len(ulaw) || estimate.OutputBytes != int64(len(ulaw)) {
+			t.Errorf("%+v: estimated %d samples, %d bytes, converted %d", tc, estimate.OutputSamples, estimate.OutputBytes, len(ulaw))
+		}
+		if want := time.Duration(tc.frames) * time.Second / time.Duration(tc.rate); estimate.InputDuration != want {
+			t.Errorf("%+v: input duration %v, want %v", tc, estimate.InputDuration, want)
+		}
+	}
+
+	if _, err := EstimateWav(bytes.NewReader([]byte("RIFF")), nil); err == nil {
+		t.Error("invalid WAV accepted")
+	}
+}
+
+func TestEstimateUlaw(t *testing.T) {
+	ulaw := encodeUlawSamples(sineWave(8001, 440, 8000, 0.5))
+	if got := UlawDuration(len(ulaw)); got != time.Second+125*time.Microsecond {
+		t.Errorf("duration %v, want 1.000125s", got)
+	}
+	for _, rate := range []uint32{8000, 16000, 44100} {
+		wavBytes, err := ConvertUlawBytesToWav(ulaw, rate, 16)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if estimate := EstimateUlaw(len(ulaw), rate); estimate.OutputBytes != int64(len(wavBytes)) {
+			t.Errorf("%d Hz: estimated %d bytes, converted %d", rate, estimate.OutputBytes, len(wavBytes))
+		}
+	}
+}
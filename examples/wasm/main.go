@@ -0,0 +1,41 @@
+//go:build js && wasm
+
+// Command wasm exposes the converter to JavaScript as a global
+// wav2ulaw(Uint8Array) function returning the u-law bytes as a Uint8Array.
+//
+// Build with TinyGo or the standard toolchain:
+//
+//	tinygo build -o wav2ulaw.wasm -target wasm ./examples/wasm
+//	GOOS=js GOARCH=wasm go build -o wav2ulaw.wasm ./examples/wasm
+package main
+
+import (
+	"syscall/js"
+	"wav2ulaw"
+)
+
+// convert is the JavaScript entry point: wav2ulaw(wavBytes) -> ulawBytes
+func convert(this js.Value, args []js.Value) any {
+	if len(args) != 1 {
+		return js.Global().Get("Error").New("wav2ulaw expects one Uint8Array argument")
+	}
+
+	wavBytes := make([]byte, args[0].Get("length").Int())
+	js.CopyBytesToGo(wavBytes, args[0])
+
+	ulawBytes, err := wav2ulaw.ConvertWavBytesToUlaw(wavBytes, wav2ulaw.DefaultAudioConfig())
+	if err != nil {
+		return js.Global().Get("Error").New(err.Error())
+	}
+
+	result := js.Global().Get("Uint8Array").New(len(ulawBytes))
+	js.CopyBytesToJS(result, ulawBytes)
+	return result
+}
+
+func main() {
+	js.Global().Set("wav2ulaw", js.FuncOf(convert))
+
+	// Keep the module alive so the exported function stays callable
+	select {}
+}
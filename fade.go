@@ -0,0 +1,81 @@
+package wav2ulaw
+
+import (
+	"math"
+	"time"
+)
+
+// FadeShape defines the gain curve used by fade-in and fade-out stages
+type FadeShape int
+
+const (
+	FadeLinear FadeShape = iota // Linear gain ramp
+	FadeCosine                  // Raised-cosine ramp (smoother start and end)
+)
+
+// fadeGain returns the gain at position t (0.0 to 1.0) along a fade-in
+func fadeGain(shape FadeShape, t float64) float64 {
+	switch shape {
+	case FadeCosine:
+		return 0.5 - 0.5*math.Cos(math.Pi*t)
+	default: // FadeLinear
+		return t
+	}
+}
+
+// applyFades ramps the first fadeInMs in from silence and the last fadeOutMs
+// out to silence, modifying samples in place
+func applyFades(samples []int16, sampleRate int, fadeInMs, fadeOutMs float64, shape FadeShape) []int16 {
+	return applyFadesAt(samples, 0, len(samples), sampleRate, fadeInMs, fadeOutMs, shape)
+}
+
+// applyFadesAt applies the fades of a signal of total samples to the block
+// starting at offset within it, so a signal processed block by block gets
+// exactly the ramps applyFades would give the whole signal
+func applyFadesAt(block []int16, offset, total, sampleRate int, fadeInMs, fadeOutMs float64, shape FadeShape) []int16 {
+	fadeIn := min(int(fadeInMs*float64(sampleRate)/1000.0), total)
+	fadeOut := min(int(fadeOutMs*float64(sampleRate)/1000.0), total)
+
+	for i := max(offset, 0); i < min(fadeIn, offset+len(block)); i++ {
+		gain := fadeGain(shape, float64(i)/float64(fadeIn))
+		block[i-offset] = int16(math.Round(float64(block[i-offset]) * gain))
+	}
+	for i := 0; i < fadeOut; i++ {
+		idx := total - 1 - i - offset
+		if idx < 0 {
+			break
+		}
+		if idx >= len(block) {
+			continue
+		}
+		gain := fadeGain(shape, float64(i)/float64(fadeOut))
+		block[idx] = int16(math.Round(float64(block[idx]) * gain))
+	}
+
+	return block
+}
+
+// padLength returns the number of samples lasting d at sampleRate
+func padLength(d time.Duration, sampleRate int) int {
+	return max(0, int(math.Round(d.Seconds()*float64(sampleRate))))
+}
+
+// framePadding returns the samples to append to n samples to make them a
+// multiple of frame
+func framePadding(n, frame int) int {
+	if frame <= 1 {
+		return 0
+	}
+	return (frame - n%frame) % frame
+}
+
+// padSilence returns samples between start and end of digital silence in a
+// pooled buffer
+func padSilence(samples []int16, sampleRate int, start, end time.Duration) []int16 {
+	head, tail := padLength(start, sampleRate), padLength(end, sampleRate)
+	out := getInt16s(head + len(samples) + tail)
+	clear(out[:head])
+	copy(out[head:], samples)
+	clear(out[head+len(samples):])
+	return out
+}
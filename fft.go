@@ -0,0 +1,207 @@
+package wav2ulaw
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+const (
+	// Input samples processed per FFT resampling block (rounded to the rate ratio)
+	fftBlockSize = 8192
+	// Overlap added on each side of a block to hide circular convolution edges
+	fftBlockOverlap = 1024
+	// Largest reduced ratio denominator for which blocks stay exact
+	fftMaxRatioDenominator = 4096
+)
+
+// fft computes the discrete Fourier transform of x in place.
+// The inverse transform is not scaled, callers divide by len(x) themselves.
+// Power-of-two lengths use an iterative radix-2 transform, any other
+// length falls back to Bluestein's algorithm.
+func fft(x []complex128, inverse bool) {
+	n := len(x)
+	if n <= 1 {
+		return
+	}
+	if n&(n-1) == 0 {
+		fftRadix2(x, inverse)
+		return
+	}
+	fftBluestein(x, inverse)
+}
+
+// fftRadix2 is an iterative Cooley-Tukey transform for power-of-two lengths
+func fftRadix2(x []complex128, inverse bool) {
+	n := len(x)
+
+	// Bit-reversal permutation
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+
+	sign := -1.0
+	if inverse {
+		sign = 1.0
+	}
+
+	// Twiddle factors for the full length, smaller stages use a stride
+	twiddles := make([]complex128, n/2)
+	for k := range twiddles {
+		angle := sign * 2.0 * math.Pi * float64(k) / float64(n)
+		twiddles[k] = complex(math.Cos(angle), math.Sin(angle))
+	}
+
+	for size := 2; size <= n; size <<= 1 {
+		half := size / 2
+		stride := n / size
+		for start := 0; start < n; start += size {
+			for k := 0; k < half; k++ {
+				u := x[start+k]
+				v := x[start+k+half] * twiddles[k*stride]
+				x[start+k] = u + v
+				x[start+k+half] = u - v
+			}
+		}
+	}
+}
+
+// fftBluestein computes an arbitrary-length transform as a chirp convolution
+func fftBluestein(x []complex128, inverse bool) {
+	n := len(x)
+	m := 1
+	for m < 2*n-1 {
+		m <<= 1
+	}
+
+	sign := -1.0
+	if inverse {
+		sign = 1.0
+	}
+
+	// Chirp w[k] = exp(sign * i*pi*k^2/n), k^2 reduced mod 2n to keep angles small
+	chirp := make([]complex128, n)
+	for k := 0; k < n; k++ {
+		kk := (k * k) % (2 * n)
+		angle := sign * math.Pi * float64(kk) / float64(n)
+		chirp[k] = complex(math.Cos(angle), math.Sin(angle))
+	}
+
+	a := make([]complex128, m)
+	b := make([]complex128, m)
+	for k := 0; k < n; k++ {
+		a[k] = x[k] * chirp[k]
+	}
+	b[0] = cmplx.Conj(chirp[0])
+	for k := 1; k < n; k++ {
+		b[k] = cmplx.Conj(chirp[k])
+		b[m-k] = b[k]
+	}
+
+	fftRadix2(a, false)
+	fftRadix2(b, false)
+	for i := range a {
+		a[i] *= b[i]
+	}
+	fftRadix2(a, true)
+
+	scale := complex(1.0/float64(m), 0)
+	for k := 0; k < n; k++ {
+		x[k] = a[k] * scale * chirp[k]
+	}
+}
+
+// fftResampleBlock resamples a block to outLen samples by truncating or
+// zero-padding its spectrum, which acts as an ideal brick-wall low-pass
+func fftResampleBlock(block []float64, outLen int) []float64 {
+	n := len(block)
+	spectrum := make([]complex128, n)
+	for i, v := range block {
+		spectrum[i] = complex(v, 0)
+	}
+	fft(spectrum, false)
+
+	out := make([]complex128, outLen)
+	// Keep bins strictly below the lower Nyquist frequency, drop the Nyquist bin itself
+	keep := (min(n, outLen) - 1) / 2
+	out[0] = spectrum[0]
+	for k := 1; k <= keep; k++ {
+		out[k] = spectrum[k]
+		out[outLen-k] = spectrum[n-k]
+	}
+	fft(out, true)
+
+	result := make([]float64, outLen)
+	scale := 1.0 / float64(n)
+	for i, v := range out {
+		result[i] = real(v) * scale
+	}
+	return result
+}
+
+// gcd returns the greatest common divisor of a and b
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// resamplePCM16FFT resamples 16-bit PCM audio in the frequency domain.
+// The signal is processed in overlapping blocks whose lengths are exact
+// multiples of the reduced rate ratio, so block outputs line up sample
+// exactly and can be stitched without interpolation.
+func resamplePCM16FFT(input []int16, inputRate, outputRate int) []int16 {
+	ratio := float64(outputRate) / float64(inputRate)
+	g := gcd(inputRate, outputRate)
+	up := outputRate / g
+	down := inputRate / g
+	outputLen := resampledLength(len(input), up, down)
+	output := make([]int16, outputLen)
+	if outputLen == 0 {
+		return output
+	}
+
+	// Block and overlap lengths must be multiples of the ratio denominator.
+	// Awkward ratios are handled as a single block covering the whole signal.
+	blockLen := len(input)
+	overlap := 0
+	if down <= fftMaxRatioDenominator {
+		blockLen = down * ((fftBlockSize + down - 1) / down)
+		overlap = down * ((fftBlockOverlap + down - 1) / down)
+	}
+
+	segment := make([]float64, blockLen+2*overlap)
+	for start := 0; start < len(input); start += blockLen {
+		// Gather block with overlap, mirroring the signal past its edges
+		for i := range segment {
+			segment[i] = float64(input[reflectIndex(start-overlap+i, len(input))])
+		}
+
+		segLen := len(segment)
+		segOutLen := int(math.Round(float64(segLen) * ratio))
+		if overlap == 0 {
+			segOutLen = outputLen
+		}
+		resampled := fftResampleBlock(segment[:segLen], segOutLen)
+
+		// Discard the overlap and place the block at its exact output offset
+		outStart := start * up / down
+		skip := overlap * up / down
+		for i := skip; i < len(resampled); i++ {
+			o := outStart + i - skip
+			if o >= outputLen || o >= outStart+resampledLength(blockLen, up, down) {
+				break
+			}
+			output[o] = int16(math.Max(-32768, math.Min(32767, math.Round(resampled[i]))))
+		}
+	}
+
+	return output
+}
@@ -0,0 +1,44 @@
+package wav2ulaw
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+func TestFFTMatchesDFT(t *testing.T) {
+	for _, n := range []int{8, 12, 441} {
+		x := make([]complex128, n)
+		for i := range x {
+			x[i] = complex(math.Sin(float64(i)*0.7)+0.3*float64(i%5), 0)
+		}
+
+		got := make([]complex128, n)
+		copy(got, x)
+		fft(got, false)
+
+		for k := 0; k < n; k++ {
+			var want complex128
+			for j := 0; j < n; j++ {
+				want += x[j] * cmplx.Exp(complex(0, -2*math.Pi*float64(j*k)/float64(n)))
+			}
+			if cmplx.Abs(got[k]-want) > 1e-6*float64(n) {
+				t.Fatalf("n=%d bin %d: got %v, want %v", n, k, got[k], want)
+			}
+		}
+	}
+}
+
+func TestFFTResampleRemovesAliases(t *testing.T) {
+	// 5 kHz is above the 4 kHz Nyquist frequency of the output
+	input := sineWave(44100, 5000, 44100, 0.5)
+	output := resamplePCM16FFT(input, 44100, 8000)
+
+	if len(output) != 8000 {
+		t.Fatalf("expected 8000 samples, got %d", len(output))
+	}
+	// A 5 kHz tone would alias to 3 kHz without proper band-limiting
+	if level := toneLevel(output, 3000, 8000); level > 0.001 {
+		t.Errorf("alias at 3 kHz not suppressed: level %.4f", level)
+	}
+}
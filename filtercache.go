@@ -0,0 +1,70 @@
+package wav2ulaw
+
+import "sync"
+
+// filterKey identifies one IIR filter design
+type filterKey struct {
+	kind       AntiAliasingType
+	sampleRate float64
+	cutoff     float64
+	ripple     float64
+	order      int
+}
+
+// filterCoefficients holds the normalized coefficients (a0 = 1) of one
+// second-order IIR section; first-order sections leave b2 and a2 zero
+type filterCoefficients struct {
+	b0, b1, b2 float64
+	a1, a2     float64
+}
+
+// Filter designs kept cached when SetTableCacheLimit sets no lower limit.
+// Keys hold the input rate and cutoffs, which servers take from each
+// request, so the cache is always bounded.
+const maxFilterDesigns = 256
+
+var (
+	// Cache of designed filters, shared by every conversion with the same settings
+	filterCache      = make(map[filterKey][]filterCoefficients)
+	filterCacheMutex sync.RWMutex
+	// Keys of the cached filters, oldest first, guarded by filterCacheMutex
+	filterOrder []filterKey
+)
+
+// cachedFilterSections returns the cascaded sections for key, calling design
+// the first time a key is seen. Servers converting many files with
+// identical settings then design each filter only once. The sections must
+// not be modified.
+func cachedFilterSections(key filterKey, design func() []filterCoefficients) []filterCoefficients {
+	filterCacheMutex.RLock()
+	c, exists := filterCache[key]
+	filterCacheMutex.RUnlock()
+
+	if exists {
+		return c
+	}
+
+	filterCacheMutex.Lock()
+	defer filterCacheMutex.Unlock()
+
+	// Check again after taking the write lock
+	if c, exists = filterCache[key]; exists {
+		return c
+	}
+
+	designed := design()
+	filterCache[key] = designed
+	filterOrder = evictFilterDesigns(append(filterOrder, key))
+	return designed
+}
+
+// evictFilterDesigns drops the oldest filter designs beyond the table cache
+// limit or maxFilterDesigns, whichever is lower, and returns the remaining
+// order. The caller holds filterCacheMutex for writing.
+func evictFilterDesigns(order []filterKey) []filterKey {
+	limit := int(tableCacheLimit.Load())
+	if limit == 0 || limit > maxFilterDesigns {
+		limit = maxFilterDesigns
+	}
+	return evictBeyond(filterCache, order, limit)
+}
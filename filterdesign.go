@@ -0,0 +1,130 @@
+package wav2ulaw
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+const (
+	// Range of FilterOrder; orders outside it are clamped
+	minFilterOrder = 2
+	maxFilterOrder = 8
+)
+
+// besselPoles lists the analog Bessel prototype poles of orders 1 to 8,
+// normalized to -3 dB at 1 rad/s: one pole of each complex-conjugate pair,
+// then the real pole of odd orders
+var besselPoles = [][]complex128{
+	{-1},
+	{complex(-1.101601330592, 0.636009824757)},
+	{complex(-1.047409161009, 0.999264436281), -1.322675799910},
+	{complex(-0.995208764350, 1.257105739455), complex(-1.370067830551, 0.410249717494)},
+	{complex(-0.957676548563, 1.471124320730), complex(-1.380877325860, 0.717909587627), -1.502316271447},
+	{complex(-0.930656522947, 1.661863268943), complex(-1.381858097597, 0.971471890712), complex(-1.571490403616, 0.320896374223)},
+	{complex(-0.909867780623, 1.836451353036), complex(-1.378903216795, 1.191566777801), complex(-1.612038766226, 0.589244506931), -1.684368179273},
+	{complex(-0.892869718847, 1.998325843641), complex(-1.373841217637, 1.388356575878), complex(-1.636939418127, 0.822795625140), complex(-1.757408400402, 0.272867575102)},
+}
+
+// clampFilterOrder limits an IIR filter order to the supported range
+func clampFilterOrder(order int) int {
+	return max(minFilterOrder, min(maxFilterOrder, order))
+}
+
+// butterworthSections designs a Butterworth low-pass filter of the given
+// order as a cascade of sections, -3 dB at cutoffFreq
+func butterworthSections(sampleRate, cutoffFreq float64, order int) []filterCoefficients {
+	order = clampFilterOrder(order)
+	poles := make([]complex128, 0, (order+1)/2)
+	for k := 0; k < order/2; k++ {
+		theta := math.Pi * float64(2*k+1) / float64(2*order)
+		poles = append(poles, complex(-math.Sin(theta), math.Cos(theta)))
+	}
+	if order%2 == 1 {
+		poles = append(poles, -1)
+	}
+	return bilinearSections(poles, sampleRate, cutoffFreq, 1)
+}
+
+// besselSections designs a Bessel low-pass filter of the given order, whose
+// nearly constant group delay keeps transients intact, -3 dB at cutoffFreq
+func besselSections(sampleRate, cutoffFreq float64, order int) []filterCoefficients {
+	return bilinearSections(besselPoles[clampFilterOrder(order)-1], sampleRate, cutoffFreq, 1)
+}
+
+// chebyshevSections designs a Chebyshev Type I low-pass filter of the given
+// order with rippleDb of passband ripple. cutoffFreq is the edge of the
+// ripple band, where the response last falls to -rippleDb.
+func chebyshevSections(sampleRate, cutoffFreq, rippleDb float64, order int) []filterCoefficients {
+	order = clampFilterOrder(order)
+	epsilon := math.Sqrt(math.Pow(10, rippleDb/10) - 1)
+	v0 := math.Asinh(1/epsilon) / float64(order)
+	poles := make([]complex128, 0, (order+1)/2)
+	for k := 0; k < order/2; k++ {
+		theta := math.Pi * float64(2*k+1) / float64(2*order)
+		poles = append(poles, complex(-math.Sinh(v0)*math.Sin(theta), math.Cosh(v0)*math.Cos(theta)))
+	}
+	gain := 1.0
+	if order%2 == 1 {
+		poles = append(poles, complex(-math.Sinh(v0), 0))
+	} else {
+		// Even orders start at the bottom of the ripple
+		gain = 1 / math.Sqrt(1+epsilon*epsilon)
+	}
+	return bilinearSections(poles, sampleRate, cutoffFreq, gain)
+}
+
+// bilinearSections maps analog prototype poles (normalized to 1 rad/s) to
+// digital sections with the bilinear transform, prewarped so the cutoff
+// lands at cutoffFreq. Complex poles stand for their conjugate pair and
+// become second-order sections, real poles first-order ones. gain scales
+// the first section.
+func bilinearSections(poles []complex128, sampleRate, cutoffFreq, gain float64) []filterCoefficients {
+	k := math.Tan(math.Pi * cutoffFreq / sampleRate)
+	sections := make([]filterCoefficients, 0, len(poles))
+	for _, p := range poles {
+		var c filterCoefficients
+		if imag(p) == 0 {
+			// sigma / (s + sigma)
+			sigma := -real(p) * k
+			d := 1 + sigma
+			c = filterCoefficients{b0: sigma / d, b1: sigma / d, a1: (sigma - 1) / d}
+		} else {
+			// w0^2 / (s^2 + a*s + w0^2)
+			w0sq := cmplx.Abs(p) * cmplx.Abs(p) * k * k
+			a := -2 * real(p) * k
+			d := 1 + a + w0sq
+			c = filterCoefficients{
+				b0: w0sq / d, b1: 2 * w0sq / d, b2: w0sq / d,
+				a1: 2 * (w0sq - 1) / d, a2: (1 - a + w0sq) / d,
+			}
+		}
+		sections = append(sections, c)
+	}
+	sections[0].b0 *= gain
+	sections[0].b1 *= gain
+	sections[0].b2 *= gain
+	return sections
+}
+
+// applySections runs a cascade of sections over samples in place, keeping
+// full precision between sections
+func applySections(samples []int16, sections []filterCoefficients) []int16 {
+	stages := newSectionStages(sections)
+	for i := range samples {
+		y := float64(samples[i]) / 32767.0 // Normalize to [-1, 1]
+		for j := range stages {
+			y = stages[j].process(y)
+		}
+		samples[i] = int16(math.Max(-32768, math.Min(32767, y*32767.0)))
+	}
+	return samples
+}
+
+// newSectionStages returns stateful filters running the sections
+func newSectionStages(sections []filterCoefficients) []biquad {
+	stages := make([]biquad, len(sections))
+	for i, c := range sections {
+		stages[i] = biquad{b0: c.b0, b1: c.b1, b2: c.b2, a1: c.a1, a2: c.a2}
+	}
+	return stages
+}
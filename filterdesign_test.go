@@ -0,0 +1,108 @@
+package wav2ulaw
+
+import (
+	"math"
+	"testing"
+)
+
+// sectionsGain returns the designed gain (dB) of cascaded sections at freq
+func sectionsGain(sections []filterCoefficients, freq, sampleRate float64) float64 {
+	w := 2 * math.Pi * freq / sampleRate
+	gain := 1.0
+	for _, c := range sections {
+		gain *= transferGain([]float64{c.b0, c.b1, c.b2}, []float64{c.a1, c.a2}, w)
+	}
+	return 20 * math.Log10(gain)
+}
+
+func TestFilterOrderRolloff(t *testing.T) {
+	const rate, cutoff, ripple = 96000, 1000, 0.5
+	designs := map[string]func(order int) []filterCoefficients{
+		"butterworth": func(order int) []filterCoefficients { return butterworthSections(rate, cutoff, order) },
+		"bessel":      func(order int) []filterCoefficients { return besselSections(rate, cutoff, order) },
+		"chebyshev":   func(order int) []filterCoefficients { return chebyshevSections(rate, cutoff, ripple, order) },
+	}
+	for name, design := range designs {
+		for order := 2; order <= 8; order++ {
+			sections := design(order)
+			if want := (order + 1) / 2; len(sections) != want {
+				t.Errorf("%s order %d: %d sections, want %d", name, order, len(sections), want)
+			}
+
+			// Far in the stopband every order adds 6 dB per octave
+			slope := sectionsGain(sections, 8*cutoff, rate) - sectionsGain(sections, 4*cutoff, rate)
+			if want := -6.0 * float64(order); math.Abs(slope-want) > 2.5 {
+				t.Errorf("%s order %d: rolloff %.1f dB/octave, want %.0f", name, order, slope, want)
+			}
+
+			edge, want := sectionsGain(sections, cutoff, rate), -3.01
+			if name == "chebyshev" {
+				want = -ripple
+			}
+			if math.Abs(edge-want) > 0.05 {
+				t.Errorf("%s order %d: %.2f dB at the cutoff, want %.2f", name, order, edge, want)
+			}
+			dc := sectionsGain(sections, 0, rate)
+			if name == "chebyshev" && order%2 == 0 {
+				dc += ripple
+			}
+			if math.Abs(dc) > 0.01 {
+				t.Errorf("%s order %d: DC gain off by %.3f dB", name, order, dc)
+			}
+		}
+	}
+}
+
+func TestFilterOrderClamped(t *testing.T) {
+	if got, want := len(butterworthSections(48000, 3000, 0)), 1; got != want {
+		t.Errorf("order 0: %d sections, want %d", got, want)
+	}
+	if got, want := len(butterworthSections(48000, 3000, 20)), 4; got != want {
+		t.Errorf("order 20: %d sections, want %d", got, want)
+	}
+}
+
+func TestAntiAliasingFilterHonorsOrder(t *testing.T) {
+	const rate = 48000
+	config := DefaultAudioConfig()
+	config.AntiAliasingCutoffRatio = 0.5
+	cutoff := 4000 * config.AntiAliasingCutoffRatio
+	for _, aa := range []AntiAliasingType{AAButterworth, AABessel, AAChebyshev} {
+		config.AntiAliasingType = aa
+		for order := 2; order <= 8; order++ {
+			config.FilterOrder = order
+			samples := applyAntiAliasingFilter(sineWave(rate, 2*cutoff, rate, 0.9), rate, 8000, config)
+			// Skip the transient of the filter start
+			level := 20 * math.Log10(toneLevel(samples[rate/4:], 2*cutoff, rate)/0.9)
+
+			var sections []filterCoefficients
+			switch aa {
+			case AAButterworth:
+				sections = butterworthSections(rate, cutoff, order)
+			case AABessel:
+				sections = besselSections(rate, cutoff, order)
+			case AAChebyshev:
+				sections = chebyshevSections(rate, cutoff, config.ChebyshevRipple, order)
+			}
+			if want := sectionsGain(sections, 2*cutoff, rate); math.Abs(level-want) > 1 {
+				t.Errorf("type %d order %d: measured %.1f dB an octave above the cutoff, designed %.1f", aa, order, level, want)
+			}
+		}
+	}
+}
+
+func TestFilterCacheBounded(t *testing.T) {
+	ClearTableCaches()
+	defer ClearTableCaches()
+	for cutoff := 1; cutoff <= maxFilterDesigns+10; cutoff++ {
+		applyButterworthFilter(make([]int16, 8), 16000, float64(cutoff), 4)
+	}
+	filterCacheMutex.RLock()
+	_, newest := filterCache[filterKey{kind: AAButterworth, sampleRate: 16000, cutoff: maxFilterDesigns + 10, order: 4}]
+	_, oldest := filterCache[filterKey{kind: AAButterworth, sampleRate: 16000, cutoff: 1, order: 4}]
+	count := len(filterCache)
+	filterCacheMutex.RUnlock()
+	if count != maxFilterDesigns || !newest || oldest {
+		t.Errorf("%d designs cached (newest kept %v, oldest kept %v), want the newest %d", count, newest, oldest, maxFilterDesigns)
+	}
+}
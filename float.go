@@ -0,0 +1,57 @@
+package wav2ulaw
+
+import (
+	"fmt"
+	"math"
+)
+
+// ConvertFloat32ToUlaw runs mono samples normalized to [-1, 1], as neural TTS
+// models produce, at sampleRate through the processing chain and encodes
+// them to u-law. Models overshoot full scale now and then; rather than
+// clipping the peaks, input exceeding it is scaled down as a whole until its
+// peak is at full scale, with a WarnInputOverRange warning. NaN and infinite
+// samples are taken as silence. Clipped regions of the input are reported
+// to OnClipping as for WAV input. samples is not modified.
+func ConvertFloat32ToUlaw(samples []float32, sampleRate int, config *AudioConfig) ([]byte, error) {
+	if config == nil {
+		config = DefaultAudioConfig()
+	}
+	if sampleRate <= 0 {
+		return nil, fmt.Errorf("invalid sample rate %d", sampleRate)
+	}
+	if len(samples) == 0 {
+		return nil, ErrEmptyInput
+	}
+
+	peak := 0.0
+	for _, s := range samples {
+		if v := math.Abs(float64(s)); v > peak && !math.IsInf(v, 0) {
+			peak = v
+		}
+	}
+	scale := 32767.0
+	if peak > 1 {
+		scale /= peak
+		logWarn(config, WarnInputOverRange, "", "input exceeds full scale, scaled down to avoid clipping", "peak", peak, "gain_db", -20*math.Log10(peak))
+	}
+
+	pcm := getInt16s(len(samples))
+	defer putInt16s(pcm)
+	for i, s := range samples {
+		v := float64(s)
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			v = 0
+		}
+		pcm[i] = int16(math.Max(-32768, math.Min(32767, math.Round(v*scale))))
+	}
+	if config.OnClipping != nil {
+		data := make([]float64, len(pcm))
+		for i, v := range pcm {
+			data[i] = normalizeSample(int(v), 16)
+		}
+		for _, region := range detectClipRegions(data, 1, sampleRate, 16) {
+			config.OnClipping(region)
+		}
+	}
+	return ConvertPCM16ToUlaw(pcm, sampleRate, config)
+}
@@ -0,0 +1,58 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestConvertFloat32ToUlaw(t *testing.T) {
+	pcm := sineWave(24000, 440, 24000, 0.5)
+	samples := make([]float32, len(pcm))
+	for i, s := range pcm {
+		samples[i] = float32(s) / 32767
+	}
+	want, err := ConvertPCM16ToUlaw(pcm, 24000, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ConvertFloat32ToUlaw(samples, 24000, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("float conversion differs from converting the same samples as 16-bit PCM")
+	}
+
+	// A model overshooting to 2.0 is scaled down instead of clipped, and
+	// garbage samples become silence
+	hot := make([]float32, len(samples))
+	for i, s := range samples {
+		hot[i] = 4 * s
+	}
+	hot[100], hot[200] = float32(math.NaN()), float32(math.Inf(1))
+	var warnings []Warning
+	config := DefaultAudioConfig()
+	config.NormalizePeak = 0
+	config.OnWarning = func(w Warning) { warnings = append(warnings, w) }
+	clipped := 0
+	config.OnClipping = func(region ClipRegion) { clipped += region.Samples }
+	ulaw, err := ConvertFloat32ToUlaw(hot, 24000, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || warnings[0].Code != WarnInputOverRange {
+		t.Errorf("warnings %+v, want one %s", warnings, WarnInputOverRange)
+	}
+	if clipped != 0 {
+		t.Errorf("%d clipped samples reported after scaling down", clipped)
+	}
+	if level := toneLevel(decodeUlawSamples(ulaw[2000:6000]), 440, 8000); level < 0.6 || level > 1 {
+		t.Errorf("tone level %.2f after scaling down, want near full scale", level)
+	}
+
+	if _, err := ConvertFloat32ToUlaw(nil, 24000, nil); !errors.Is(err, ErrEmptyInput) {
+		t.Errorf("empty input: got %v, want ErrEmptyInput", err)
+	}
+}
@@ -0,0 +1,225 @@
+package wav2ulaw
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+)
+
+const (
+	// How often a followed file is checked for growth when
+	// FollowOptions.PollInterval is 0
+	defaultFollowPoll = 200 * time.Millisecond
+	// Time without growth after which a followed file is taken to be
+	// complete when FollowOptions.IdleTimeout is 0
+	defaultFollowIdle = 5 * time.Second
+)
+
+// FollowOptions control FollowWavFile
+type FollowOptions struct {
+	// How often the file is checked for new audio (0 = 200 ms)
+	PollInterval time.Duration
+	// The file is complete once it has not grown for this long, for writers
+	// that never fill in the data size (0 = 5 s)
+	IdleTimeout time.Duration
+}
+
+// FollowWavFile converts a WAV file that is still being written, such as a
+// voicemail being recorded, following its growth like tail -f. The u-law is
+// written to w as audio arrives, through a ChunkEncoder, so stages that need
+// the whole signal are not applied; the channels are mixed to mono. A header
+// not yet written is waited for. The conversion ends, flushing the encoder,
+// when the writer fills in the data size and the file holds that much audio,
+// when the file has not grown for options.IdleTimeout, or when ctx is done,
+// in which case ctx's error is returned once the output is flushed. It
+// returns the number of u-law bytes written.
+func FollowWavFile(ctx context.Context, path string, w io.Writer, config *AudioConfig, options *FollowOptions) (int64, error) {
+	if config == nil {
+		config = DefaultAudioConfig()
+	}
+	if options == nil {
+		options = &FollowOptions{}
+	}
+	poll, idle := options.PollInterval, options.IdleTimeout
+	if poll == 0 {
+		poll = defaultFollowPoll
+	}
+	if idle == 0 {
+		idle = defaultFollowIdle
+	}
+	if poll < 0 || idle < 0 {
+		return 0, fmt.Errorf("invalid follow settings: durations must not be negative")
+	}
+
+	f := &wavFollower{path: path, limits: config.Limits}
+	defer f.close()
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+	wait := func() error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			return nil
+		}
+	}
+
+	// Until the header is complete there is nothing to convert
+	lastGrowth := time.Now()
+	for {
+		grew, pending, err := f.open()
+		if err != nil {
+			return 0, err
+		}
+		if pending == nil {
+			break
+		}
+		if grew {
+			lastGrowth = time.Now()
+		} else if time.Since(lastGrowth) >= idle {
+			return 0, pending
+		}
+		if err := wait(); err != nil {
+			return 0, err
+		}
+	}
+	rate := f.header.sampleRate
+	if config.InputSampleRate != 0 {
+		rate = config.InputSampleRate
+	}
+	encoder, err := NewChunkEncoder(rate, config)
+	if err != nil {
+		return 0, err
+	}
+	logDebug(config, "following WAV file", "path", path, "sample_rate", f.header.sampleRate, "channels", f.header.channels, "bit_depth", f.header.bitDepth)
+
+	var written int64
+	emit := func(ulaw []byte) error {
+		n, err := w.Write(ulaw)
+		written += int64(n)
+		return err
+	}
+	lastGrowth = time.Now()
+	for {
+		samples, done, err := f.read()
+		if err != nil {
+			return written, err
+		}
+		if len(samples) > 0 {
+			lastGrowth = time.Now()
+			if err := emit(encoder.Encode(samples)); err != nil {
+				return written, err
+			}
+		}
+		if done || time.Since(lastGrowth) >= idle {
+			break
+		}
+		if len(samples) == streamReadFrames && ctx.Err() == nil {
+			// More may be waiting already
+			continue
+		}
+		if err = wait(); err != nil {
+			if flushErr := emit(encoder.Flush()); flushErr != nil {
+				return written, flushErr
+			}
+			return written, err
+		}
+	}
+	return written, emit(encoder.Flush())
+}
+
+// wavFollower reads the audio appended to a WAV file being written
+type wavFollower struct {
+	path   string
+	limits *WavLimits
+	file   *os.File
+	// Size of the file when the header was last looked for
+	size   int64
+	header *wavHeader
+	// Offset of the next unread frame
+	pos int64
+	buf []byte
+	pcm []int
+}
+
+// open looks for a complete header, reporting whether the file grew since
+// the last attempt. pending is why the file cannot be read yet: it does not
+// exist or its header is incomplete.
+func (f *wavFollower) open() (grew bool, pending, err error) {
+	if f.file == nil {
+		file, err := os.Open(f.path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, err, nil
+		}
+		if err != nil {
+			return false, nil, err
+		}
+		f.file = file
+	}
+	size, err := f.file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return false, nil, err
+	}
+	grew = size != f.size
+	f.size = size
+	header, err := inspectWav(f.file, f.limits)
+	var wavErr *WavError
+	if errors.As(err, &wavErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return grew, err, nil
+	}
+	if err != nil {
+		return false, nil, err
+	}
+	f.header, f.pos = header, header.dataOffset
+	return grew, nil, nil
+}
+
+// read decodes up to streamReadFrames of the whole frames appended since
+// the last call, mixed to mono, and reports whether the data chunk is complete: its size has been
+// filled in and that much audio has been read
+func (f *wavFollower) read() ([]int16, bool, error) {
+	var sizeField [4]byte
+	if _, err := f.file.ReadAt(sizeField[:], f.header.dataOffset-4); err != nil {
+		return nil, false, err
+	}
+	end := int64(-1)
+	if declared := binary.LittleEndian.Uint32(sizeField[:]); declared != 0 && declared != 0xFFFFFFFF {
+		end = f.header.dataOffset + int64(declared)
+	}
+	size, err := f.file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, false, err
+	}
+	if end >= 0 {
+		size = min(size, end)
+	}
+
+	frame := int64(f.header.channels * f.header.bitDepth / 8)
+	n := min((size-f.pos)/frame, streamReadFrames) * frame
+	if n <= 0 {
+		return nil, end >= 0 && f.pos+frame > end, nil
+	}
+	if int64(cap(f.buf)) < n {
+		f.buf = make([]byte, n)
+		f.pcm = make([]int, n/int64(f.header.bitDepth/8))
+	}
+	if _, err := f.file.ReadAt(f.buf[:n], f.pos); err != nil {
+		return nil, false, err
+	}
+	f.pos += n
+	pcm := f.pcm[:decodePCM(f.pcm[:cap(f.pcm)], f.buf[:n], f.header.bitDepth)]
+	samples := make([]int16, len(pcm)/f.header.channels)
+	pcmToInt16(samples, pcm, f.header.channels, f.header.bitDepth, true)
+	return samples, end >= 0 && f.pos+frame > end, nil
+}
+
+func (f *wavFollower) close() {
+	if f.file != nil {
+		f.file.Close()
+	}
+}
@@ -0,0 +1,99 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFollowWavFile(t *testing.T) {
+	samples := sineWave(16000, 440, 16000, 0.3)
+	wavBytes := encodeWavDepth(t, samples, 16000, 16, 2)
+	header, err := inspectWav(bytes.NewReader(wavBytes), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The whole file encoded in one go, which following must match however
+	// the file grows
+	encoder, err := NewChunkEncoder(16000, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := append(encoder.Encode(samples), encoder.Flush()...)
+
+	// A streaming writer: sizes left as placeholders, the audio appended in
+	// pieces of odd length, the size filled in at the end
+	streaming := func(path string, finalize bool) {
+		f, err := os.Create(path)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer f.Close()
+		start := append([]byte(nil), wavBytes[:header.dataOffset]...)
+		binary.LittleEndian.PutUint32(start[4:], 0xFFFFFFFF)
+		binary.LittleEndian.PutUint32(start[header.dataOffset-4:], 0xFFFFFFFF)
+		// The header itself arrives in two writes
+		f.Write(start[:20])
+		time.Sleep(10 * time.Millisecond)
+		f.Write(start[20:])
+		for pos := header.dataOffset; pos < int64(len(wavBytes)); pos += 3001 {
+			f.Write(wavBytes[pos:min(pos+3001, int64(len(wavBytes)))])
+			time.Sleep(2 * time.Millisecond)
+		}
+		if finalize {
+			f.WriteAt(wavBytes[4:8], 4)
+			f.WriteAt(wavBytes[header.dataOffset-4:header.dataOffset], header.dataOffset-4)
+		}
+	}
+
+	dir := t.TempDir()
+	options := &FollowOptions{PollInterval: 5 * time.Millisecond, IdleTimeout: time.Second}
+	for _, finalize := range []bool{true, false} {
+		path := filepath.Join(dir, "growing.wav")
+		os.Remove(path)
+		go streaming(path, finalize)
+		var out bytes.Buffer
+		begin := time.Now()
+		n, err := FollowWavFile(context.Background(), path, &out, nil, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != int64(out.Len()) || !bytes.Equal(out.Bytes(), want) {
+			t.Errorf("finalize %v: %d bytes (%d reported), want the %d of a one-go conversion", finalize, out.Len(), n, len(want))
+		}
+		// A filled-in size ends the conversion without waiting for the timeout
+		if elapsed := time.Since(begin); finalize && elapsed >= options.IdleTimeout {
+			t.Errorf("finalized file followed for %v", elapsed)
+		}
+	}
+
+	// Cancelling still flushes the encoder
+	path := filepath.Join(dir, "cancelled.wav")
+	start := append([]byte(nil), wavBytes[:header.dataOffset+8000]...)
+	binary.LittleEndian.PutUint32(start[header.dataOffset-4:], 0)
+	if err := os.WriteFile(path, start, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	n, err := FollowWavFile(ctx, path, &bytes.Buffer{}, nil, options)
+	if !errors.Is(err, context.DeadlineExceeded) || n != 1000 {
+		t.Errorf("cancelled: %d bytes (%v), want 1000 and the context's error", n, err)
+	}
+
+	// A file that never gets a header is given up after the timeout
+	if err := os.WriteFile(path, []byte("RIFF"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	options.IdleTimeout = 50 * time.Millisecond
+	var wavErr *WavError
+	if _, err := FollowWavFile(context.Background(), path, &bytes.Buffer{}, nil, options); !errors.As(err, &wavErr) {
+		t.Errorf("headerless file: got %v, want a WavError", err)
+	}
+}
@@ -0,0 +1,142 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+)
+
+// InputFormat identifies how an input file is encoded
+type InputFormat int
+
+const (
+	FormatUnknown InputFormat = iota // Not recognized
+	FormatWAV                        // RIFF/WAVE container
+	FormatUlaw                       // Raw 8 kHz u-law without a header
+)
+
+const (
+	// Bytes examined by the u-law plausibility check
+	sniffBytes = 64 * 1024
+	// Shortest headerless input the plausibility check will judge
+	minSniffBytes = 160
+	// Lag-1 correlation above which decoded samples look like audio
+	plausibleCorrelation = 0.3
+)
+
+// Container formats recognized by their magic bytes but not decoded
+var foreignMagic = []struct {
+	magic []byte
+	name  string
+}{
+	{[]byte("OggS"), "Ogg"},
+	{[]byte("fLaC"), "FLAC"},
+	{[]byte("ID3"), "MP3"},
+	{[]byte("FORM"), "AIFF"},
+	{[]byte(".snd"), "Sun AU"},
+	{[]byte("RIFX"), "big-endian WAV"},
+	{[]byte("#!AMR"), "AMR"},
+}
+
+// String returns the name of the format
+func (f InputFormat) String() string {
+	switch f {
+	case FormatWAV:
+		return "WAV"
+	case FormatUlaw:
+		return "u-law"
+	}
+	return "unknown"
+}
+
+// DetectFormat determines the format of input data from its content. WAV is
+// recognized by its RIFF/WAVE header. Headerless data is accepted as u-law
+// only when it decodes to a plausible audio signal, judged by how strongly
+// neighboring samples correlate, which random bytes or other encodings do not.
+// An error explains why data that is neither was rejected.
+func DetectFormat(data []byte) (InputFormat, error) {
+	if len(data) >= 12 && bytes.HasPrefix(data, []byte("RIFF")) {
+		if string(data[8:12]) == "WAVE" {
+			return FormatWAV, nil
+		}
+		return FormatUnknown, fmt.Errorf("RIFF file of type %q is not WAV", data[8:12])
+	}
+	for _, m := range foreignMagic {
+		if bytes.HasPrefix(data, m.magic) {
+			return FormatUnknown, fmt.Errorf("%s files are not supported", m.name)
+		}
+	}
+
+	if len(data) < minSniffBytes {
+		return FormatUnknown, fmt.Errorf("input too short to detect its format (%d bytes)", len(data))
+	}
+	data = data[:min(len(data), sniffBytes)]
+
+	// u-law silence is a run of one of the two zero codes
+	if quiet := bytes.Count(data, []byte{0xFF}) + bytes.Count(data, []byte{0x7F}); quiet == len(data) {
+		return FormatUlaw, nil
+	}
+
+	ulaw := math.Abs(lagCorrelation(decodeUlawSamples(data)))
+	pcm := math.Abs(lagCorrelation(decodeInt16LE(data)))
+	switch {
+	case ulaw >= plausibleCorrelation && ulaw > pcm:
+		return FormatUlaw, nil
+	case pcm >= plausibleCorrelation && pcm > ulaw:
+		return FormatUnknown, fmt.Errorf("input looks like headerless 16-bit PCM, which is not supported")
+	}
+	return FormatUnknown, fmt.Errorf("cannot determine input format: not WAV and not plausible u-law audio")
+}
+
+// Analyze detects the format of data and reports its statistics like
+// AnalyzeWav or AnalyzeUlaw
+func Analyze(data []byte) (*Stats, InputFormat, error) {
+	format, err := DetectFormat(data)
+	if err != nil {
+		return nil, format, err
+	}
+	var stats *Stats
+	if format == FormatWAV {
+		stats, err = AnalyzeWav(data)
+	} else {
+		stats, err = AnalyzeUlaw(data)
+	}
+	return stats, format, err
+}
+
+// decodeInt16LE interprets bytes as little-endian 16-bit samples
+func decodeInt16LE(data []byte) []int16 {
+	samples := make([]int16, len(data)/2)
+	for i := range samples {
+		samples[i] = int16(uint16(data[2*i]) | uint16(data[2*i+1])<<8)
+	}
+	return samples
+}
+
+// lagCorrelation returns the normalized correlation between neighboring
+// samples. Audio sampled well above its bandwidth scores near +1 (or -1
+// for content near Nyquist), uncorrelated noise near 0.
+func lagCorrelation(samples []int16) float64 {
+	if len(samples) < 2 {
+		return 0
+	}
+	mean := 0.0
+	for _, s := range samples {
+		mean += float64(s)
+	}
+	mean /= float64(len(samples))
+
+	var num, den float64
+	prev := float64(samples[0]) - mean
+	den = prev * prev
+	for _, s := range samples[1:] {
+		d := float64(s) - mean
+		num += d * prev
+		den += d * d
+		prev = d
+	}
+	if den == 0 {
+		return 0
+	}
+	return num / den
+}
@@ -0,0 +1,48 @@
+package wav2ulaw
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestDetectFormat(t *testing.T) {
+	speech := sineWave(8000, 440, 8000, 0.5)
+	for i, sample := range sineWave(len(speech), 3100, 8000, 0.2) {
+		speech[i] += sample
+	}
+	wavBytes, err := encodeWavPCM16(speech, 8000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	random := make([]byte, 8000)
+	rand.New(rand.NewSource(1)).Read(random)
+	silence := make([]byte, 8000)
+	for i := range silence {
+		silence[i] = 0xFF
+	}
+
+	tests := []struct {
+		name   string
+		data   []byte
+		want   InputFormat
+		reject bool
+	}{
+		{"wav", wavBytes, FormatWAV, false},
+		{"ulaw", encodeUlawSamples(speech), FormatUlaw, false},
+		{"ulaw near nyquist", encodeUlawSamples(sineWave(8000, 3600, 8000, 0.5)), FormatUlaw, false},
+		{"ulaw silence", silence, FormatUlaw, false},
+		{"headerless pcm", wavBytes[44:], FormatUnknown, true},
+		{"random", random, FormatUnknown, true},
+		{"ogg", append([]byte("OggS"), random...), FormatUnknown, true},
+		{"too short", encodeUlawSamples(speech[:40]), FormatUnknown, true},
+	}
+	for _, tt := range tests {
+		got, err := DetectFormat(tt.data)
+		if (err != nil) != tt.reject {
+			t.Errorf("%s: error %v, want rejected=%v", tt.name, err, tt.reject)
+		}
+		if got != tt.want {
+			t.Errorf("%s: detected %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package wav2ulaw
+
+import "math"
+
+// GapFill defines how missing frames are filled when decoding frame sequences
+type GapFill int
+
+const (
+	GapFillSilence      GapFill = iota // Insert digital silence
+	GapFillComfortNoise                // Insert comfort noise at the estimated background level
+	GapFillConceal                     // Conceal loss by repeating and attenuating the last pitch period
+)
+
+// ConvertUlawFramesToWav decodes a sequence of u-law frames into WAV file bytes.
+// A nil entry marks a lost 20 ms frame, which is filled according to fill.
+func ConvertUlawFramesToWav(frames [][]byte, sampleRate uint32, windowSize int, fill GapFill) ([]byte, error) {
+	samples := decodeUlawFrames(frames, fill)
+
+	// Resample if needed
+	if sampleRate != 8000 {
+		window := makeWindow(WindowBlackman, windowSize*2+1, 0)
+		samples = resamplePCM16(samples, 8000, float64(sampleRate), windowSize, window)
+	}
+
+	return encodeWavPCM16(samples, int(sampleRate))
+}
+
+// decodeUlawFrames concatenates decoded frames, filling nil frames
+func decodeUlawFrames(frames [][]byte, fill GapFill) []int16 {
+	decoded := make([][]int16, len(frames))
+	for i, frame := range frames {
+		if frame != nil {
+			decoded[i] = decodeUlawSamples(frame)
+		}
+	}
+	return fillGaps(decoded, fill)
+}
+
+// fillGaps concatenates frames of 16-bit samples, replacing each nil frame
+// with a 20 ms frame generated according to fill
+func fillGaps(frames [][]int16, fill GapFill) []int16 {
+	var samples []int16
+	var noise *ComfortNoiseGenerator
+	noiseFloor := math.Inf(1)
+	plc := &concealer{}
+
+	for _, decoded := range frames {
+		if decoded != nil {
+			// The quietest received frame approximates the background level
+			if level := rmsDbov(decoded); level < noiseFloor && !math.IsInf(level, -1) {
+				noiseFloor = level
+			}
+			if fill == GapFillConceal {
+				decoded = plc.received(decoded)
+			}
+			samples = append(samples, decoded...)
+			continue
+		}
+
+		switch fill {
+		case GapFillConceal:
+			samples = append(samples, plc.conceal(UlawFrameSize)...)
+		case GapFillComfortNoise:
+			if noise == nil {
+				noise = NewComfortNoiseGenerator(defaultComfortNoiseDbov, 1)
+			}
+			if !math.IsInf(noiseFloor, 1) {
+				noise.SetLevel(noiseFloor)
+			}
+			samples = append(samples, noise.Samples(UlawFrameSize)...)
+		default: // GapFillSilence
+			samples = append(samples, make([]int16, UlawFrameSize)...)
+		}
+	}
+
+	return samples
+}
@@ -0,0 +1,67 @@
+package wav2ulaw
+
+import (
+	"math"
+	"testing"
+)
+
+func TestComfortNoiseLevel(t *testing.T) {
+	g := NewComfortNoiseGenerator(-40, 1)
+	if level := rmsDbov(g.Samples(8000)); math.Abs(level+40) > 0.5 {
+		t.Errorf("expected -40 dBov, got %.2f", level)
+	}
+	if n := len(GenerateComfortNoise(-50, 100)); n != 800 {
+		t.Errorf("expected 800 bytes for 100 ms, got %d", n)
+	}
+}
+
+func TestDecodeUlawFramesFillsGaps(t *testing.T) {
+	tone := encodeUlawSamples(sineWave(UlawFrameSize, 400, 8000, 0.01))
+	frames := [][]byte{tone, nil, tone}
+
+	silent := decodeUlawFrames(frames, GapFillSilence)
+	if len(silent) != 3*UlawFrameSize {
+		t.Fatalf("expected %d samples, got %d", 3*UlawFrameSize, len(silent))
+	}
+	if level := rmsDbov(silent[UlawFrameSize : 2*UlawFrameSize]); !math.IsInf(level, -1) {
+		t.Errorf("expected digital silence in gap, got %.2f dBov", level)
+	}
+
+	noisy := decodeUlawFrames(frames, GapFillComfortNoise)
+	gap := rmsDbov(noisy[UlawFrameSize : 2*UlawFrameSize])
+	received := rmsDbov(noisy[:UlawFrameSize])
+	if math.Abs(gap-received) > 3 {
+		t.Errorf("comfort noise at %.2f dBov, expected near background %.2f dBov", gap, received)
+	}
+}
+
+func TestFramesFromPacketsHandlesWraparound(t *testing.T) {
+	a, b, c := []byte{1}, []byte{2}, []byte{3}
+	frames := FramesFromPackets([]UlawPacket{
+		{Sequence: 65534, Payload: a},
+		{Sequence: 1, Payload: c},
+		{Sequence: 65535, Payload: b},
+		{Sequence: 65535, Payload: b},
+	})
+
+	// 65534, 65535, (0 missing), 1
+	if len(frames) != 4 || frames[0][0] != 1 || frames[1][0] != 2 || frames[2] != nil || frames[3][0] != 3 {
+		t.Fatalf("unexpected frame order: %v", frames)
+	}
+}
+
+func TestConcealmentRepeatsWaveform(t *testing.T) {
+	// 200 Hz tone has a 40-sample period at 8 kHz
+	tone := sineWave(UlawFrameSize*4, 200, 8000, 0.5)
+	var frames [][]byte
+	for i := 0; i < 4; i++ {
+		frames = append(frames, encodeUlawSamples(tone[i*UlawFrameSize:(i+1)*UlawFrameSize]))
+	}
+	frames[2] = nil
+
+	concealed := decodeUlawFrames(frames, GapFillConceal)
+	gap := concealed[2*UlawFrameSize : 3*UlawFrameSize]
+	if level := toneLevel(gap[:plcHoldSamples], 200, 8000); level < 0.4 {
+		t.Errorf("expected the tone to continue through the gap, level %.3f", level)
+	}
+}
@@ -0,0 +1,56 @@
+package wav2ulaw
+
+import (
+	"math"
+	"slices"
+)
+
+// Clone returns a deep copy of c: changes to the copy, including to its
+// AutoTrim, Noise, Beeps and Limits, leave c alone. Callbacks and the Logger
+// are shared.
+func (c *AudioConfig) Clone() *AudioConfig {
+	clone := *c
+	if c.AutoTrim != nil {
+		trim := *c.AutoTrim
+		clone.AutoTrim = &trim
+	}
+	if c.Noise != nil {
+		noise := *c.Noise
+		noise.Data = slices.Clone(noise.Data)
+		clone.Noise = &noise
+	}
+	clone.Beeps = slices.Clone(c.Beeps)
+	if c.Limits != nil {
+		limits := *c.Limits
+		clone.Limits = &limits
+	}
+	return &clone
+}
+
+// FrozenConfig is a validated snapshot of an AudioConfig that is safe to
+// share between goroutines: a server builds it once at startup and passes
+// Config() to each conversion, while the AudioConfig it came from may
+// change. Conversions never modify the config they are given, and work on
+// a copy taken when they start.
+type FrozenConfig struct {
+	config *AudioConfig
+}
+
+// Freeze validates c and returns a snapshot of it. Out-of-range settings
+// fail with a *ConfigError, or are clamped in the snapshot with a warning
+// when c.Lenient is set. Filter cutoffs depend on the Nyquist frequency of
+// each input, so they are checked against it by the conversions.
+func (c *AudioConfig) Freeze() (*FrozenConfig, error) {
+	// At an unbounded input rate only the cutoffs' own ranges apply
+	validated, err := validateConfig(c, math.MaxInt32)
+	if err != nil {
+		return nil, err
+	}
+	return &FrozenConfig{config: validated}, nil
+}
+
+// Config returns a copy of the frozen settings for one conversion; changing
+// it affects neither the snapshot nor other conversions
+func (f *FrozenConfig) Config() *AudioConfig {
+	return f.config.Clone()
+}
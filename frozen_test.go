@@ -0,0 +1,69 @@
+package wav2ulaw
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAudioConfigClone(t *testing.T) {
+	config := DefaultAudioConfig()
+	config.AutoTrim = &AutoTrim{PreRoll: time.Second}
+	config.Noise = &NoiseOverlay{SNR: 20, Data: []byte{1, 2, 3}}
+	config.Beeps = []Beep{{At: time.Second, Frequency: 1400}}
+	config.Limits = DefaultWavLimits()
+
+	clone := config.Clone()
+	if !reflect.DeepEqual(clone, config) {
+		t.Fatalf("clone %+v differs from %+v", clone, config)
+	}
+	clone.AutoTrim.PreRoll = 0
+	clone.Noise.Data[0] = 9
+	clone.Beeps[0].Frequency = 400
+	clone.Limits.MaxChannels = 1
+	if config.AutoTrim.PreRoll != time.Second || config.Noise.Data[0] != 1 || config.Beeps[0].Frequency != 1400 || config.Limits.MaxChannels == 1 {
+		t.Error("changing the clone changed the original")
+	}
+}
+
+func TestFreeze(t *testing.T) {
+	config := DefaultAudioConfig()
+	config.NormalizePeak = 2
+	if _, err := config.Freeze(); !errors.As(err, new(*ConfigError)) {
+		t.Errorf("got %v, want a *ConfigError", err)
+	}
+	config.Lenient = true
+	frozen, err := config.Freeze()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := frozen.Config().NormalizePeak; got != 1 || config.NormalizePeak != 2 {
+		t.Errorf("frozen peak %g (original %g), want 1 clamped in the snapshot only", got, config.NormalizePeak)
+	}
+
+	// Conversions share the snapshot while the original changes
+	wavBytes := encodeWavDepth(t, sineWave(16000, 440, 16000, 0.3), 16000, 16, 1)
+	want, err := ConvertWavBytesToUlaw(wavBytes, frozen.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c := frozen.Config()
+			c.LowPassCutoff = 3000 // a conversion's own change
+			if _, err := ConvertWavBytesToUlaw(wavBytes, c); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	config.NormalizePeak, config.Beeps = 0.5, []Beep{{Frequency: 1000, Duration: time.Second}}
+	wg.Wait()
+	if got, err := ConvertWavBytesToUlaw(wavBytes, frozen.Config()); err != nil || string(got) != string(want) {
+		t.Errorf("snapshot changed by its users (%v)", err)
+	}
+}
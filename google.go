@@ -0,0 +1,146 @@
+package wav2ulaw
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// GoogleEncoding is an audio encoding accepted by Google's Speech-to-Text and
+// Dialogflow streaming APIs
+type GoogleEncoding int
+
+const (
+	// GoogleLinear16 is 16-bit little-endian PCM at any rate from 8 to 48 kHz
+	GoogleLinear16 GoogleEncoding = iota
+	// GoogleMulaw is 8 kHz G.711 u-law, the format of telephony integrations
+	GoogleMulaw
+)
+
+// String returns the name of the encoding in Speech-to-Text RecognitionConfig
+func (e GoogleEncoding) String() string {
+	switch e {
+	case GoogleLinear16:
+		return "LINEAR16"
+	case GoogleMulaw:
+		return "MULAW"
+	}
+	return "ENCODING_UNSPECIFIED"
+}
+
+// DialogflowName returns the name of the encoding in Dialogflow InputAudioConfig
+func (e GoogleEncoding) DialogflowName() string {
+	switch e {
+	case GoogleLinear16:
+		return "AUDIO_ENCODING_LINEAR_16"
+	case GoogleMulaw:
+		return "AUDIO_ENCODING_MULAW"
+	}
+	return "AUDIO_ENCODING_UNSPECIFIED"
+}
+
+// GoogleAudioConfig holds the audio settings to send in the first request of
+// a StreamingRecognize or StreamingDetectIntent session
+type GoogleAudioConfig struct {
+	Encoding          GoogleEncoding
+	SampleRateHertz   int
+	AudioChannelCount int
+}
+
+// GoogleStreamer turns 8 kHz u-law, as produced by the conversion functions
+// and ChunkEncoder, into the fixed-duration audio chunks of a Google
+// streaming session. Audio that does not fill a chunk is kept for the next
+// call.
+type GoogleStreamer struct {
+	config     GoogleAudioConfig
+	chunkBytes int
+	decoder    *ChunkDecoder
+	pending    []byte
+}
+
+// NewGoogleStreamer creates a streamer sending chunkMs of audio per chunk,
+// 100 ms (the duration Google recommends) when chunkMs is 0. u-law streams
+// must be at 8000 Hz; LINEAR16 streams are resampled from 8 kHz to
+// sampleRate.
+func NewGoogleStreamer(encoding GoogleEncoding, sampleRate, chunkMs int) (*GoogleStreamer, error) {
+	if chunkMs == 0 {
+		chunkMs = 100
+	}
+	if chunkMs < 0 || chunkMs > 1000 {
+		return nil, fmt.Errorf("invalid chunk duration %d ms, must be between 1 and 1000", chunkMs)
+	}
+	s := &GoogleStreamer{config: GoogleAudioConfig{Encoding: encoding, SampleRateHertz: sampleRate, AudioChannelCount: 1}}
+	switch encoding {
+	case GoogleMulaw:
+		if sampleRate != 8000 {
+			return nil, fmt.Errorf("invalid sample rate %d for MULAW, must be 8000", sampleRate)
+		}
+		s.chunkBytes = 8 * chunkMs
+	case GoogleLinear16:
+		if sampleRate < 8000 || sampleRate > 48000 {
+			return nil, fmt.Errorf("invalid sample rate %d for LINEAR16, must be between 8000 and 48000", sampleRate)
+		}
+		decoder, err := NewChunkDecoder(sampleRate, 16)
+		if err != nil {
+			return nil, err
+		}
+		s.decoder = decoder
+		s.chunkBytes = 2 * (sampleRate * chunkMs / 1000)
+	default:
+		return nil, fmt.Errorf("invalid encoding %d", encoding)
+	}
+	return s, nil
+}
+
+// Config returns the audio settings of the stream
+func (s *GoogleStreamer) Config() GoogleAudioConfig {
+	return s.config
+}
+
+// ChunkBytes returns the size of a full chunk
+func (s *GoogleStreamer) ChunkBytes() int {
+	return s.chunkBytes
+}
+
+// Write returns the chunks completed by the next u-law audio
+func (s *GoogleStreamer) Write(ulaw []byte) [][]byte {
+	if s.decoder == nil {
+		s.pending = append(s.pending, ulaw...)
+	} else {
+		s.pending = appendInt16LE(s.pending, s.decoder.Decode(ulaw))
+	}
+	return s.chunks()
+}
+
+// Flush returns the remaining audio, the last chunk possibly short. The
+// streamer must not be used afterwards.
+func (s *GoogleStreamer) Flush() [][]byte {
+	if s.decoder != nil {
+		s.pending = appendInt16LE(s.pending, s.decoder.Flush())
+	}
+	chunks := s.chunks()
+	if len(s.pending) > 0 {
+		chunks = append(chunks, s.pending)
+		s.pending = nil
+	}
+	return chunks
+}
+
+// chunks splits the full chunks off the pending audio
+func (s *GoogleStreamer) chunks() [][]byte {
+	var chunks [][]byte
+	for len(s.pending) >= s.chunkBytes {
+		chunks = append(chunks, s.pending[:s.chunkBytes:s.chunkBytes])
+		s.pending = s.pending[s.chunkBytes:]
+	}
+	// Keep the remainder from pinning the chunks already returned
+	s.pending = append([]byte(nil), s.pending...)
+	return chunks
+}
+
+// appendInt16LE appends samples as little-endian 16-bit PCM
+func appendInt16LE(dst []byte, samples []int16) []byte {
+	for _, sample := range samples {
+		dst = binary.LittleEndian.AppendUint16(dst, uint16(sample))
+	}
+	return dst
+}
@@ -0,0 +1,43 @@
+package wav2ulaw
+
+import "testing"
+
+func TestGoogleStreamerChunks(t *testing.T) {
+	// 250 ms of 8 kHz u-law
+	ulaw := encodeUlawSamples(sineWave(2000, 440, 8000, 0.5))
+
+	mulaw, err := NewGoogleStreamer(GoogleMulaw, 8000, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := mulaw.Config(); c.Encoding.String() != "MULAW" || c.Encoding.DialogflowName() != "AUDIO_ENCODING_MULAW" || c.SampleRateHertz != 8000 {
+		t.Errorf("unexpected config %+v", c)
+	}
+	chunks := mulaw.Write(ulaw)
+	if len(chunks) != 2 || len(chunks[0]) != 800 || string(chunks[1]) != string(ulaw[800:1600]) {
+		t.Fatalf("expected two 100 ms chunks, got %d", len(chunks))
+	}
+	if rest := mulaw.Flush(); len(rest) != 1 || len(rest[0]) != 400 {
+		t.Fatalf("expected a 50 ms final chunk, got %d chunks", len(rest))
+	}
+
+	linear, err := NewGoogleStreamer(GoogleLinear16, 16000, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if linear.ChunkBytes() != 640 {
+		t.Fatalf("expected 640-byte chunks, got %d", linear.ChunkBytes())
+	}
+	total := 0
+	for _, chunk := range append(linear.Write(ulaw), linear.Flush()...) {
+		total += len(chunk)
+	}
+	// Resampled to 16 kHz, 2 bytes a sample
+	if want := 2 * 2 * len(ulaw); total < want-64 || total > want+64 {
+		t.Errorf("expected about %d bytes of LINEAR16, got %d", want, total)
+	}
+
+	if _, err := NewGoogleStreamer(GoogleMulaw, 16000, 0); err == nil {
+		t.Error("expected error for 16 kHz MULAW")
+	}
+}
@@ -0,0 +1,70 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// InterleaveUlaw combines two u-law channels into the two-channel layout of
+// G.711 RTP payloads (RFC 3551 section 4.1): a sample of a, then the sample
+// of b at the same instant, and so on. The shorter channel is followed by
+// silence to the end of the longer one, so every frame holds both.
+func InterleaveUlaw(a, b []byte) []byte {
+	frames := max(len(a), len(b))
+	interleaved := bytes.Repeat([]byte{ulawSilence}, 2*frames)
+	for i, s := range a {
+		interleaved[2*i] = s
+	}
+	for i, s := range b {
+		interleaved[2*i+1] = s
+	}
+	return interleaved
+}
+
+// DeinterleaveUlaw splits a two-channel u-law payload from InterleaveUlaw
+// back into its channels. An odd length means a frame is cut in half and
+// the channels would swap from there on, so it is an error.
+func DeinterleaveUlaw(interleaved []byte) (a, b []byte, err error) {
+	if len(interleaved)%2 != 0 {
+		return nil, nil, fmt.Errorf("two-channel u-law of %d bytes is not a whole number of frames", len(interleaved))
+	}
+	a = make([]byte, len(interleaved)/2)
+	b = make([]byte, len(interleaved)/2)
+	for i := range a {
+		a[i] = interleaved[2*i]
+		b[i] = interleaved[2*i+1]
+	}
+	return a, b, nil
+}
+
+// UlawInterleaver interleaves two u-law channels that arrive in chunks of
+// different sizes, such as the two legs of a call read from separate
+// sockets. Samples of one channel wait until the other catches up, so the
+// channels stay aligned to the sample however the chunks fall.
+type UlawInterleaver struct {
+	pending [2][]byte
+}
+
+// Write adds the next samples of each channel, either of which may be
+// empty, and returns the frames both channels now cover
+func (il *UlawInterleaver) Write(a, b []byte) []byte {
+	il.pending[0] = append(il.pending[0], a...)
+	il.pending[1] = append(il.pending[1], b...)
+	n := min(len(il.pending[0]), len(il.pending[1]))
+	interleaved := InterleaveUlaw(il.pending[0][:n], il.pending[1][:n])
+	// Keep the remainder from pinning a large input
+	il.pending[0] = append([]byte(nil), il.pending[0][n:]...)
+	il.pending[1] = append([]byte(nil), il.pending[1][n:]...)
+	return interleaved
+}
+
+// Flush returns the frames of the samples one channel is ahead by, with
+// silence on the other, or nil when the channels are level
+func (il *UlawInterleaver) Flush() []byte {
+	if len(il.pending[0]) == 0 && len(il.pending[1]) == 0 {
+		return nil
+	}
+	interleaved := InterleaveUlaw(il.pending[0], il.pending[1])
+	il.pending = [2][]byte{}
+	return interleaved
+}
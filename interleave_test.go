@@ -0,0 +1,52 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestInterleaveUlaw(t *testing.T) {
+	a := []byte{1, 2, 3}
+	b := []byte{10, 20}
+	interleaved := InterleaveUlaw(a, b)
+	if want := []byte{1, 10, 2, 20, 3, ulawSilence}; !bytes.Equal(interleaved, want) {
+		t.Fatalf("interleaved %v, want %v", interleaved, want)
+	}
+
+	gotA, gotB, err := DeinterleaveUlaw(interleaved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(gotA, a) || !bytes.Equal(gotB, []byte{10, 20, ulawSilence}) {
+		t.Errorf("split into %v and %v, want %v and %v padded with silence", gotA, gotB, a, b)
+	}
+
+	if _, _, err := DeinterleaveUlaw(interleaved[:5]); err == nil {
+		t.Error("odd length accepted")
+	}
+}
+
+func TestUlawInterleaver(t *testing.T) {
+	a := encodeUlawSamples(sineWave(800, 440, 8000, 0.5))
+	b := encodeUlawSamples(sineWave(700, 1000, 8000, 0.5))
+	want := InterleaveUlaw(a, b)
+
+	// Chunks of unrelated sizes, one channel at times running far ahead
+	var il UlawInterleaver
+	var got []byte
+	ai, bi := 0, 0
+	for _, step := range [][2]int{{160, 0}, {0, 37}, {13, 240}, {300, 1}, {0, 200}, {327, 222}} {
+		got = append(got, il.Write(a[ai:ai+step[0]], b[bi:bi+step[1]])...)
+		ai, bi = ai+step[0], bi+step[1]
+		if len(got)%2 != 0 || len(got)/2 > min(ai, bi) {
+			t.Fatalf("after %d and %d samples, %d bytes out", ai, bi, len(got))
+		}
+	}
+	got = append(got, il.Flush()...)
+	if !bytes.Equal(got, want) {
+		t.Fatal("chunked output differs from InterleaveUlaw of the whole channels")
+	}
+	if il.Flush() != nil {
+		t.Error("second Flush returned audio")
+	}
+}
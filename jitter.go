@@ -0,0 +1,229 @@
+package wav2ulaw
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	// Playout without a late frame after which the target depth shrinks by
+	// one frame, in samples (5 seconds)
+	jitterShrinkSamples = 5 * 8000
+)
+
+// JitterStats counts what a JitterBuffer received and played out
+type JitterStats struct {
+	// Frames placed in the buffer
+	Frames int
+	// Frames received more than once
+	Duplicates int
+	// Frames that arrived after their playout time and were dropped
+	Late int
+	// Samples concealed because their audio had not arrived when due
+	Concealed int
+	// Samples skipped to shrink the buffer or because it overflowed
+	Skipped int
+}
+
+// JitterBuffer turns timestamped u-law frames arriving with network jitter,
+// out of order or not at all into a steady 8 kHz PCM stream. Frames are
+// placed by their RTP timestamp and played out once the buffer holds its
+// target depth of received audio. The depth adapts: a frame arriving after its playout time
+// raises it by the frame's duration, up to the maximum, and it shrinks back
+// a frame at a time towards the minimum while frames arrive in time.
+//
+// Output is paced by the caller, e.g. one Pop per 20 ms tick. Depth and
+// TargetDepth suit the fill and target of a DriftResampler that converts
+// the output to another rate.
+type JitterBuffer struct {
+	minDepth, maxDepth int
+	depth              int
+	started            bool
+	playing            bool
+	firstTs            uint32
+	// Next sample to play, relative to the first timestamp
+	cursor int64
+	// Received frames not yet played, ordered by offset
+	frames []jitterFrame
+	// Samples still to conceal to raise the playout delay
+	hold int
+	// Samples played since the last late frame or shrink
+	sinceAdjust int
+	concealer   concealer
+	stats       JitterStats
+}
+
+// jitterFrame is a decoded frame starting offset samples after the first timestamp
+type jitterFrame struct {
+	offset  int64
+	samples []int16
+}
+
+// NewJitterBuffer creates a jitter buffer whose depth adapts between
+// minDepthMs and maxDepthMs, starting at the minimum
+func NewJitterBuffer(minDepthMs, maxDepthMs int) (*JitterBuffer, error) {
+	if minDepthMs < 0 || maxDepthMs < minDepthMs {
+		return nil, fmt.Errorf("invalid jitter buffer depth %d-%d ms", minDepthMs, maxDepthMs)
+	}
+	return &JitterBuffer{
+		minDepth: minDepthMs * 8,
+		maxDepth: maxDepthMs * 8,
+		depth:    minDepthMs * 8,
+	}, nil
+}
+
+// Push adds a frame of u-law audio whose first sample has the given RTP
+// timestamp. ulaw is not retained.
+func (b *JitterBuffer) Push(timestamp uint32, ulaw []byte) {
+	if len(ulaw) == 0 {
+		return
+	}
+	if !b.started {
+		b.started = true
+		b.firstTs = timestamp
+	}
+	// The timestamp is unwrapped around the first one, so wraparound works
+	offset := int64(int32(timestamp - b.firstTs))
+	if b.playing && offset+int64(len(ulaw)) <= b.cursor {
+		b.stats.Late++
+		grow := min(len(ulaw), b.maxDepth-b.depth)
+		b.depth += grow
+		b.hold += grow
+		b.sinceAdjust = 0
+		return
+	}
+
+	i := sort.Search(len(b.frames), func(i int) bool { return b.frames[i].offset >= offset })
+	if i < len(b.frames) && b.frames[i].offset == offset {
+		b.stats.Duplicates++
+		return
+	}
+	b.frames = append(b.frames, jitterFrame{})
+	copy(b.frames[i+1:], b.frames[i:])
+	b.frames[i] = jitterFrame{offset: offset, samples: decodeUlawSamples(ulaw)}
+	b.stats.Frames++
+
+	if b.playing {
+		// Overflow: skip the oldest audio so the delay stays bounded
+		if excess := b.Depth() - b.maxDepth; excess > 0 {
+			b.skip(excess)
+		}
+	}
+}
+
+// Pop plays out the next n samples. It always returns n samples: silence
+// until the buffer first fills to its target depth, then the received audio
+// with concealment standing in for audio that has not arrived when due.
+func (b *JitterBuffer) Pop(n int) []int16 {
+	if !b.playing {
+		received := 0
+		for _, f := range b.frames {
+			received += len(f.samples)
+		}
+		if received == 0 || received < b.depth {
+			return make([]int16, n)
+		}
+		b.playing = true
+		b.cursor = b.frames[0].offset
+	}
+	b.adjust()
+
+	out := make([]int16, 0, n)
+	for len(out) < n {
+		want := n - len(out)
+		if b.hold > 0 {
+			k := min(b.hold, want)
+			out = append(out, b.conceal(k)...)
+			b.hold -= k
+			continue
+		}
+		// Drop what the cursor has passed, e.g. frames overlapping others
+		for len(b.frames) > 0 && b.frames[0].offset+int64(len(b.frames[0].samples)) <= b.cursor {
+			b.frames = b.frames[1:]
+		}
+		if len(b.frames) > 0 && b.frames[0].offset <= b.cursor {
+			f := b.frames[0]
+			start := int(b.cursor - f.offset)
+			k := min(len(f.samples)-start, want)
+			out = append(out, b.concealer.received(append([]int16(nil), f.samples[start:start+k]...))...)
+			b.cursor += int64(k)
+			continue
+		}
+		k := want
+		if len(b.frames) > 0 {
+			k = min(k, int(b.frames[0].offset-b.cursor))
+		}
+		out = append(out, b.conceal(k)...)
+		b.cursor += int64(k)
+	}
+	b.sinceAdjust += n
+	return out
+}
+
+// Flush plays out everything still buffered and resets the buffer for a new
+// stream. The statistics are kept.
+func (b *JitterBuffer) Flush() []int16 {
+	var out []int16
+	if len(b.frames) > 0 {
+		if !b.playing {
+			b.playing = true
+			b.cursor = b.frames[0].offset
+		}
+		b.hold = 0
+		out = b.Pop(b.Depth())
+	}
+	*b = JitterBuffer{minDepth: b.minDepth, maxDepth: b.maxDepth, depth: b.minDepth, stats: b.stats}
+	return out
+}
+
+// Depth returns the number of samples between the playout position and the
+// end of the latest frame received
+func (b *JitterBuffer) Depth() int {
+	if len(b.frames) == 0 {
+		return 0
+	}
+	cursor := b.cursor
+	if !b.playing {
+		cursor = b.frames[0].offset
+	}
+	end := cursor
+	for _, f := range b.frames {
+		end = max(end, f.offset+int64(len(f.samples)))
+	}
+	return int(end - cursor)
+}
+
+// TargetDepth returns the depth, in samples, the buffer currently aims for
+func (b *JitterBuffer) TargetDepth() int {
+	return b.depth
+}
+
+// Stats returns the counts so far
+func (b *JitterBuffer) Stats() JitterStats {
+	return b.stats
+}
+
+// adjust lowers the target depth by one frame once frames have arrived in
+// time for a while, skipping a frame of audio to actually reduce the delay
+func (b *JitterBuffer) adjust() {
+	if b.sinceAdjust < jitterShrinkSamples || b.depth <= b.minDepth {
+		return
+	}
+	b.sinceAdjust = 0
+	b.depth = max(b.minDepth, b.depth-UlawFrameSize)
+	if excess := b.Depth() - b.depth; excess >= UlawFrameSize {
+		b.skip(UlawFrameSize)
+	}
+}
+
+// skip advances the playout position by n samples
+func (b *JitterBuffer) skip(n int) {
+	b.cursor += int64(n)
+	b.stats.Skipped += n
+}
+
+// conceal synthesizes n samples of missing audio
+func (b *JitterBuffer) conceal(n int) []int16 {
+	b.stats.Concealed += n
+	return b.concealer.conceal(n)
+}
@@ -0,0 +1,136 @@
+package wav2ulaw
+
+import (
+	"slices"
+	"testing"
+)
+
+// toneFrames splits a 400 Hz tone into u-law frames
+func toneFrames(n int) [][]byte {
+	tone := encodeUlawSamples(sineWave(n*UlawFrameSize, 400, 8000, 0.5))
+	var frames [][]byte
+	for i := 0; i < n; i++ {
+		frames = append(frames, tone[i*UlawFrameSize:(i+1)*UlawFrameSize])
+	}
+	return frames
+}
+
+// playout pushes frames in the given order, one per 20 ms tick, popping a
+// frame each tick, and returns everything played out
+func playout(t *testing.T, frames [][]byte, order []int) ([]int16, JitterStats) {
+	t.Helper()
+	buffer, err := NewJitterBuffer(60, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []int16
+	for _, i := range order {
+		if i >= 0 {
+			buffer.Push(uint32(4294967000+i*UlawFrameSize), frames[i])
+		}
+		out = append(out, buffer.Pop(UlawFrameSize)...)
+	}
+	out = append(out, buffer.Flush()...)
+	return out, buffer.Stats()
+}
+
+func TestJitterBufferReorders(t *testing.T) {
+	frames := toneFrames(20)
+	inOrder, _ := playout(t, frames, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19})
+	// Within the 60 ms depth, with a duplicate, across timestamp wraparound
+	reordered, stats := playout(t, frames, []int{0, 2, 1, 3, 5, 4, 4, 6, 7, 9, 8, 10, 11, 13, 12, 14, 15, 16, 19, 17, 18})
+
+	if !slices.Equal(reordered, inOrder) {
+		t.Error("reordered frames played out differently from in-order ones")
+	}
+	if stats.Duplicates != 1 || stats.Late != 0 || stats.Concealed != 0 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	// Silence while prebuffering, then every frame
+	if len(inOrder) < 20*UlawFrameSize {
+		t.Errorf("played %d samples, want at least %d", len(inOrder), 20*UlawFrameSize)
+	}
+}
+
+func TestJitterBufferConcealsLoss(t *testing.T) {
+	frames := toneFrames(20)
+	order := []int{0, 1, 2, 3, 4, 5, 6, -1, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19}
+	out, stats := playout(t, frames, order)
+	if stats.Concealed != UlawFrameSize {
+		t.Errorf("concealed %d samples, want %d", stats.Concealed, UlawFrameSize)
+	}
+	// The lost frame sits 7 frames after playout started
+	start := slices.IndexFunc(out, func(s int16) bool { return s != 0 })
+	gap := out[start+7*UlawFrameSize : start+8*UlawFrameSize]
+	if level := toneLevel(gap[:plcHoldSamples], 400, 8000); level < 0.3 {
+		t.Errorf("expected the tone to continue through the lost frame, level %.3f", level)
+	}
+}
+
+func TestJitterBufferGrowsOnLateFrames(t *testing.T) {
+	frames := toneFrames(20)
+	buffer, _ := NewJitterBuffer(20, 100)
+	var out []int16
+	for i := 0; i < 4; i++ {
+		buffer.Push(uint32(i*UlawFrameSize), frames[i])
+		out = append(out, buffer.Pop(UlawFrameSize)...)
+	}
+	// Frame 4 is held up in the network for 60 ms
+	for i := 5; i < 8; i++ {
+		buffer.Push(uint32(i*UlawFrameSize), frames[i])
+		out = append(out, buffer.Pop(UlawFrameSize)...)
+	}
+	before := buffer.TargetDepth()
+	buffer.Push(4*UlawFrameSize, frames[4])
+	stats := buffer.Stats()
+	if stats.Late != 1 {
+		t.Fatalf("got %d late frames, want 1", stats.Late)
+	}
+	if got := buffer.TargetDepth(); got != before+UlawFrameSize {
+		t.Errorf("target depth %d after a late frame, want %d", got, before+UlawFrameSize)
+	}
+
+	// The extra depth is realized by concealment, so the next frames arrive in time
+	for i := 8; i < 20; i++ {
+		buffer.Push(uint32(i*UlawFrameSize), frames[i])
+		out = append(out, buffer.Pop(UlawFrameSize)...)
+	}
+	if got := buffer.Depth(); got < buffer.TargetDepth()-UlawFrameSize {
+		t.Errorf("depth %d, want near the target %d", got, buffer.TargetDepth())
+	}
+
+	// The depth never exceeds the maximum
+	for i := 0; i < 10; i++ {
+		buffer.Push(uint32(4*UlawFrameSize), frames[4])
+		buffer.Push(uint32(100000+i*UlawFrameSize), frames[0])
+	}
+	if got := buffer.TargetDepth(); got > 800 {
+		t.Errorf("target depth %d exceeds the 100 ms maximum", got)
+	}
+	if got := buffer.Depth(); got > 800 {
+		t.Errorf("depth %d exceeds the 100 ms maximum", got)
+	}
+}
+
+func TestJitterBufferFlush(t *testing.T) {
+	tone := encodeUlawSamples(sineWave(3*UlawFrameSize, 400, 8000, 0.5))
+	frames := toneFrames(3)
+	buffer, _ := NewJitterBuffer(100, 200)
+	for i, frame := range frames {
+		buffer.Push(uint32(i*UlawFrameSize), frame)
+	}
+	if got := buffer.Pop(UlawFrameSize); slices.ContainsFunc(got, func(s int16) bool { return s != 0 }) {
+		t.Error("expected silence while prebuffering")
+	}
+	out := buffer.Flush()
+	if !slices.Equal(out, decodeUlawSamples(tone)) {
+		t.Errorf("flush returned %d samples, want the 3 buffered frames", len(out))
+	}
+	if buffer.Depth() != 0 {
+		t.Error("buffer not empty after flush")
+	}
+
+	if _, err := NewJitterBuffer(100, 50); err == nil {
+		t.Error("maximum below minimum accepted")
+	}
+}
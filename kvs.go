@@ -0,0 +1,359 @@
+package wav2ulaw
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Track names of the audio Amazon Connect streams to Kinesis Video Streams
+const (
+	KVSTrackFromCustomer = "AUDIO_FROM_CUSTOMER"
+	KVSTrackToCustomer   = "AUDIO_TO_CUSTOMER"
+)
+
+// Matroska element IDs read by ReadKVSAudio
+const (
+	mkvSegment           = 0x18538067
+	mkvInfo              = 0x1549A966
+	mkvTimecodeScale     = 0x2AD7B1
+	mkvTracks            = 0x1654AE6B
+	mkvTrackEntry        = 0xAE
+	mkvTrackNumber       = 0xD7
+	mkvName              = 0x536E
+	mkvCodecID           = 0x86
+	mkvAudio             = 0xE1
+	mkvSamplingFrequency = 0xB5
+	mkvChannels          = 0x9F
+	mkvBitDepth          = 0x6264
+	mkvCluster           = 0x1F43B675
+	mkvTimecode          = 0xE7
+	mkvSimpleBlock       = 0xA3
+	mkvBlockGroup        = 0xA0
+	mkvBlock             = 0xA1
+)
+
+// KVSTrack is one audio track of a Kinesis Video Streams recording. Samples
+// are mono 16-bit PCM at SampleRate, ready for ConvertPCM16ToUlaw; tracks
+// with more channels are mixed down.
+type KVSTrack struct {
+	Number     uint64
+	Name       string // KVSTrackFromCustomer or KVSTrackToCustomer for Amazon Connect
+	CodecID    string
+	SampleRate int
+	Channels   int
+	BitDepth   int
+	Samples    []int16
+
+	// Stream time in nanoseconds at which Samples ends, for placing blocks
+	end int64
+}
+
+// ReadKVSAudio extracts the audio tracks of Kinesis Video Streams fragments,
+// such as the Matroska stream GetMedia returns for an Amazon Connect contact.
+// Any number of fragments may follow each other. Blocks are placed by their
+// timecodes across all tracks, so gaps in a track (e.g. while the agent is not
+// yet connected) become silence and the tracks stay aligned. Only PCM tracks
+// (A_PCM/INT/LIT or A_PCM/INT/BIG) are supported.
+func ReadKVSAudio(data []byte) ([]*KVSTrack, error) {
+	r := &mkvReader{timecodeScale: 1000000, start: -1}
+	if err := r.read(data); err != nil {
+		return nil, err
+	}
+	if len(r.tracks) == 0 {
+		return nil, fmt.Errorf("no audio tracks in KVS stream")
+	}
+	return r.tracks, nil
+}
+
+// KVSTrackByName returns the track with the given name, or nil
+func KVSTrackByName(tracks []*KVSTrack, name string) *KVSTrack {
+	for _, t := range tracks {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// MixKVSTracks sums tracks of the same sample rate into one mono track named
+// "mix", for a recording of both sides of a call
+func MixKVSTracks(tracks []*KVSTrack) (*KVSTrack, error) {
+	if len(tracks) == 0 {
+		return nil, fmt.Errorf("no tracks to mix")
+	}
+	rate, n := tracks[0].SampleRate, 0
+	for _, t := range tracks {
+		if t.SampleRate != rate {
+			return nil, fmt.Errorf("cannot mix tracks of %d and %d Hz", rate, t.SampleRate)
+		}
+		n = max(n, len(t.Samples))
+	}
+	mixed := make([]int16, n)
+	for i := range mixed {
+		sum := 0
+		for _, t := range tracks {
+			if i < len(t.Samples) {
+				sum += int(t.Samples[i])
+			}
+		}
+		mixed[i] = int16(max(-32768, min(32767, sum)))
+	}
+	return &KVSTrack{Name: "mix", CodecID: "A_PCM/INT/LIT", SampleRate: rate, Channels: 1, BitDepth: 16, Samples: mixed}, nil
+}
+
+// Wav returns the track as a 16-bit mono WAV file at its sample rate
+func (t *KVSTrack) Wav() ([]byte, error) {
+	return encodeWavPCM16(t.Samples, t.SampleRate)
+}
+
+// mkvReader walks the elements of a Matroska stream. Kinesis Video Streams
+// writes segments and clusters of unknown size, so master elements are not
+// delimited by their size: their children are read as if they followed at
+// the top level.
+type mkvReader struct {
+	tracks        []*KVSTrack
+	timecodeScale int64
+	cluster       int64
+	// Time of the first block of the stream, -1 before it
+	start int64
+}
+
+// read processes a sequence of elements
+func (r *mkvReader) read(data []byte) error {
+	for pos := 0; pos < len(data); {
+		id, n, ok := readEBMLID(data[pos:])
+		if !ok {
+			return fmt.Errorf("invalid element ID at offset %d", pos)
+		}
+		size, m, known := readEBMLSize(data[pos+n:])
+		if m == 0 {
+			return fmt.Errorf("invalid element size at offset %d", pos)
+		}
+		pos += n + m
+
+		switch id {
+		case mkvSegment, mkvCluster, mkvTracks, mkvInfo, mkvBlockGroup:
+			continue
+		}
+		if !known {
+			return fmt.Errorf("element %#x of unknown size at offset %d", id, pos)
+		}
+		if size > uint64(len(data)-pos) {
+			// A fragment cut short, as when a live stream is stopped, ends the stream
+			return nil
+		}
+		payload := data[pos : pos+int(size)]
+		pos += int(size)
+
+		var err error
+		switch id {
+		case mkvTimecodeScale:
+			r.timecodeScale = int64(readEBMLUint(payload))
+		case mkvTimecode:
+			r.cluster = int64(readEBMLUint(payload))
+		case mkvTrackEntry:
+			err = r.trackEntry(payload)
+		case mkvSimpleBlock, mkvBlock:
+			err = r.block(payload)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// trackEntry registers an audio track. Fragments repeat the track list, so
+// tracks already known are kept.
+func (r *mkvReader) trackEntry(data []byte) error {
+	t := &KVSTrack{SampleRate: 8000, Channels: 1}
+	err := walkEBML(data, func(id uint32, payload []byte) error {
+		switch id {
+		case mkvTrackNumber:
+			t.Number = readEBMLUint(payload)
+		case mkvName:
+			t.Name = string(payload)
+		case mkvCodecID:
+			t.CodecID = string(payload)
+		case mkvAudio:
+			return walkEBML(payload, func(id uint32, payload []byte) error {
+				switch id {
+				case mkvSamplingFrequency:
+					t.SampleRate = int(math.Round(readEBMLFloat(payload)))
+				case mkvChannels:
+					t.Channels = int(readEBMLUint(payload))
+				case mkvBitDepth:
+					t.BitDepth = int(readEBMLUint(payload))
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if r.track(t.Number) != nil || len(t.CodecID) < 2 || t.CodecID[:2] != "A_" {
+		return nil
+	}
+	if t.CodecID != "A_PCM/INT/LIT" && t.CodecID != "A_PCM/INT/BIG" {
+		return fmt.Errorf("unsupported codec %s on track %d (%s)", t.CodecID, t.Number, t.Name)
+	}
+	if t.BitDepth == 0 {
+		t.BitDepth = 16
+	}
+	if t.BitDepth != 16 || t.Channels < 1 || t.SampleRate <= 0 {
+		return fmt.Errorf("unsupported audio on track %d (%s): %d-bit, %d channel(s), %d Hz", t.Number, t.Name, t.BitDepth, t.Channels, t.SampleRate)
+	}
+	r.tracks = append(r.tracks, t)
+	return nil
+}
+
+// track returns the audio track with the given number, or nil
+func (r *mkvReader) track(number uint64) *KVSTrack {
+	for _, t := range r.tracks {
+		if t.Number == number {
+			return t
+		}
+	}
+	return nil
+}
+
+// block appends the samples of a SimpleBlock or Block to its track
+func (r *mkvReader) block(data []byte) error {
+	number, n, _ := readEBMLSize(data)
+	if n == 0 || len(data) < n+3 {
+		return fmt.Errorf("invalid block")
+	}
+	t := r.track(number)
+	if t == nil {
+		// Not an audio track
+		return nil
+	}
+	timecode := r.cluster + int64(int16(binary.BigEndian.Uint16(data[n:])))
+	flags := data[n+2]
+	frames := data[n+3:]
+	switch (flags >> 1) & 3 {
+	case 0:
+	case 2:
+		// Fixed-size lacing: the frames are contiguous after their count
+		if len(frames) == 0 {
+			return fmt.Errorf("invalid laced block")
+		}
+		frames = frames[1:]
+	default:
+		return fmt.Errorf("unsupported lacing in block of track %d", t.Number)
+	}
+
+	// Start the block where its timecode says when it is clearly after the
+	// end of the track, tolerating timecode rounding and jitter
+	at := timecode * r.timecodeScale
+	if r.start < 0 {
+		r.start = at
+	}
+	at -= r.start
+	frameSize := 2 * t.Channels
+	if gap := at - t.end; gap > int64(20*time.Millisecond) {
+		silence := int(gap * int64(t.SampleRate) / int64(time.Second))
+		t.Samples = append(t.Samples, make([]int16, silence)...)
+	}
+	for i := 0; i+frameSize <= len(frames); i += frameSize {
+		sum := 0
+		for c := 0; c < t.Channels; c++ {
+			b := frames[i+2*c:]
+			if t.CodecID == "A_PCM/INT/BIG" {
+				sum += int(int16(binary.BigEndian.Uint16(b)))
+			} else {
+				sum += int(int16(binary.LittleEndian.Uint16(b)))
+			}
+		}
+		t.Samples = append(t.Samples, int16(sum/t.Channels))
+	}
+	t.end = int64(len(t.Samples)) * int64(time.Second) / int64(t.SampleRate)
+	return nil
+}
+
+// walkEBML calls fn for each element of a master element payload of known size
+func walkEBML(data []byte, fn func(id uint32, payload []byte) error) error {
+	for pos := 0; pos < len(data); {
+		id, n, ok := readEBMLID(data[pos:])
+		if !ok {
+			return fmt.Errorf("invalid element ID")
+		}
+		size, m, known := readEBMLSize(data[pos+n:])
+		if m == 0 || !known || size > uint64(len(data)-pos-n-m) {
+			return fmt.Errorf("invalid size of element %#x", id)
+		}
+		pos += n + m
+		if err := fn(id, data[pos:pos+int(size)]); err != nil {
+			return err
+		}
+		pos += int(size)
+	}
+	return nil
+}
+
+// readEBMLID reads an element ID, keeping its length marker as Matroska
+// specifications write IDs
+func readEBMLID(data []byte) (uint32, int, bool) {
+	if len(data) == 0 || data[0] == 0 {
+		return 0, 0, false
+	}
+	n := 1
+	for data[0]&(0x80>>(n-1)) == 0 {
+		n++
+	}
+	if n > 4 || len(data) < n {
+		return 0, 0, false
+	}
+	id := uint32(0)
+	for _, b := range data[:n] {
+		id = id<<8 | uint32(b)
+	}
+	return id, n, true
+}
+
+// readEBMLSize reads a variable-length integer such as an element size. It
+// returns its length, 0 when invalid, and false for the reserved
+// all-ones value meaning an unknown size.
+func readEBMLSize(data []byte) (uint64, int, bool) {
+	if len(data) == 0 || data[0] == 0 {
+		return 0, 0, false
+	}
+	n := 1
+	for data[0]&(0x80>>(n-1)) == 0 {
+		n++
+	}
+	if len(data) < n {
+		return 0, 0, false
+	}
+	value := uint64(data[0] & (0xFF >> n))
+	allOnes := value == uint64(0xFF>>n)
+	for _, b := range data[1:n] {
+		value = value<<8 | uint64(b)
+		allOnes = allOnes && b == 0xFF
+	}
+	return value, n, !allOnes
+}
+
+// readEBMLUint reads a big-endian unsigned integer element
+func readEBMLUint(data []byte) uint64 {
+	value := uint64(0)
+	for _, b := range data {
+		value = value<<8 | uint64(b)
+	}
+	return value
+}
+
+// readEBMLFloat reads a 4- or 8-byte float element
+func readEBMLFloat(data []byte) float64 {
+	switch len(data) {
+	case 4:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data)))
+	case 8:
+		return math.Float64frombits(binary.BigEndian.Uint64(data))
+	}
+	return 0
+}
@@ -0,0 +1,93 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// mkvElement encodes an EBML element with an 8-byte size, or an unknown size
+// when size is negative
+func mkvElement(id uint32, size int, payload ...[]byte) []byte {
+	var buf bytes.Buffer
+	idBytes := binary.BigEndian.AppendUint32(nil, id)
+	buf.Write(bytes.TrimLeft(idBytes, "\x00"))
+	body := bytes.Join(payload, nil)
+	if size < 0 {
+		buf.WriteByte(0xFF)
+	} else {
+		buf.Write(binary.BigEndian.AppendUint64(nil, uint64(len(body))|1<<56))
+	}
+	buf.Write(body)
+	return buf.Bytes()
+}
+
+func mkvTrack(number byte, name string) []byte {
+	rate := binary.BigEndian.AppendUint64(nil, math.Float64bits(8000))
+	return mkvElement(mkvTrackEntry, 0,
+		mkvElement(mkvTrackNumber, 0, []byte{number}),
+		mkvElement(mkvName, 0, []byte(name)),
+		mkvElement(mkvCodecID, 0, []byte("A_PCM/INT/LIT")),
+		mkvElement(mkvAudio, 0, mkvElement(mkvSamplingFrequency, 0, rate), mkvElement(mkvBitDepth, 0, []byte{16})))
+}
+
+// mkvBlockOf encodes a SimpleBlock of 16-bit samples at a cluster-relative timecode
+func mkvBlockOf(number byte, timecode int16, samples []int16) []byte {
+	data := []byte{0x80 | number, byte(uint16(timecode) >> 8), byte(timecode), 0x80}
+	for _, s := range samples {
+		data = binary.LittleEndian.AppendUint16(data, uint16(s))
+	}
+	return mkvElement(mkvSimpleBlock, 0, data)
+}
+
+func TestReadKVSAudio(t *testing.T) {
+	tone := sineWave(160, 440, 8000, 0.5)
+	tracks := mkvElement(mkvTracks, 0, mkvTrack(1, KVSTrackFromCustomer), mkvTrack(2, KVSTrackToCustomer))
+	var stream []byte
+	for cluster := 0; cluster < 2; cluster++ {
+		// Like KVS, each fragment is a segment and cluster of unknown size
+		blocks := [][]byte{mkvElement(mkvTimecode, 0, []byte{0, byte(cluster * 40)}), mkvBlockOf(1, 0, tone), mkvBlockOf(1, 20, tone)}
+		if cluster == 1 {
+			// The agent joins 60 ms in
+			blocks = append(blocks, mkvBlockOf(2, 20, tone))
+		}
+		stream = append(stream, mkvElement(0x1A45DFA3, 0, mkvElement(0x4282, 0, []byte("matroska")))...)
+		stream = append(stream, mkvElement(mkvSegment, -1, tracks, mkvElement(mkvCluster, -1, blocks...))...)
+	}
+
+	got, err := ReadKVSAudio(stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 tracks, got %d", len(got))
+	}
+	customer := KVSTrackByName(got, KVSTrackFromCustomer)
+	if customer == nil || customer.SampleRate != 8000 || len(customer.Samples) != 4*160 || customer.Samples[170] != tone[10] {
+		t.Fatalf("unexpected customer track %+v", customer)
+	}
+	agent := KVSTrackByName(got, KVSTrackToCustomer)
+	if agent == nil || len(agent.Samples) != 4*160 || agent.Samples[479] != 0 || agent.Samples[490] != tone[10] {
+		t.Fatalf("expected the agent track to start with 60 ms of silence")
+	}
+
+	mixed, err := MixKVSTracks(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mixed.SampleRate != 8000 || len(mixed.Samples) != 640 || mixed.Samples[490] != 2*tone[10] {
+		t.Errorf("unexpected mix (%d Hz, %d samples)", mixed.SampleRate, len(mixed.Samples))
+	}
+	if wav, err := mixed.Wav(); err != nil || len(wav) != 44+2*640 {
+		t.Errorf("unexpected WAV of the mix (%d bytes): %v", len(wav), err)
+	}
+	if _, err := ConvertPCM16ToUlaw(customer.Samples, customer.SampleRate, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// A stream stopped mid-block keeps the audio before it
+	if got, err := ReadKVSAudio(stream[:len(stream)-100]); err != nil || len(KVSTrackByName(got, KVSTrackFromCustomer).Samples) != 4*160 {
+		t.Errorf("truncated stream: %v", err)
+	}
+}
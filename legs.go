@@ -0,0 +1,89 @@
+package wav2ulaw
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// Largest relative length difference drift correction accepts when
+	// LegMergeOptions.MaxDrift is 0 (0.5%), far more than two telephony
+	// clocks drift apart but less than a leg missing a few seconds
+	defaultMaxLegDrift = 0.005
+	// Half-width of the sinc kernel stretching a drifting leg
+	legDriftWindowSize = 16
+)
+
+// LegMergeOptions control MergeUlawLegs
+type LegMergeOptions struct {
+	// Time the TX leg starts after the RX leg, negative when it starts
+	// first. It is rounded to whole 8 kHz samples (125 µs).
+	TXOffset time.Duration
+	// Stretch the TX leg so it ends together with the RX leg, undoing the
+	// drift between two recording clocks. The legs must cover the same span
+	// of the call, apart from TXOffset at the start.
+	CorrectDrift bool
+	// Largest relative length difference CorrectDrift corrects; legs that
+	// differ more are rejected as not covering the same span (0 = 0.5%)
+	MaxDrift float64
+	// Non-standard u-law bit layout of both legs (0 = standard G.711)
+	UlawVariant UlawVariant
+}
+
+// MergeUlawLegs combines the RX and TX legs of a call, each 8 kHz u-law,
+// into one 16-bit stereo WAV file with RX on the left channel and TX on the
+// right, as call QA tools expect. The leg that starts later is preceded by
+// silence, so the two stay aligned to the sample, and the shorter leg is
+// followed by silence to the end of the longer one. Two empty legs return
+// ErrEmptyInput.
+func MergeUlawLegs(rx, tx []byte, options *LegMergeOptions) ([]byte, error) {
+	if options == nil {
+		options = &LegMergeOptions{}
+	}
+	if len(rx) == 0 && len(tx) == 0 {
+		return nil, ErrEmptyInput
+	}
+	left := decodeUlawSamples(ConvertUlawVariant(rx, options.UlawVariant, 0))
+	right := decodeUlawSamples(ConvertUlawVariant(tx, options.UlawVariant, 0))
+
+	offset := int(math.Round(options.TXOffset.Seconds() * 8000))
+	if options.CorrectDrift && len(left) > 0 && len(right) > 0 {
+		var err error
+		if right, err = correctLegDrift(right, len(left)-offset, options.MaxDrift); err != nil {
+			return nil, err
+		}
+	}
+	if offset > 0 {
+		right = append(make([]int16, offset, offset+len(right)), right...)
+	} else if offset < 0 {
+		left = append(make([]int16, -offset, len(left)-offset), left...)
+	}
+
+	frames := max(len(left), len(right))
+	interleaved := make([]int16, 2*frames)
+	for i, s := range left {
+		interleaved[2*i] = s
+	}
+	for i, s := range right {
+		interleaved[2*i+1] = s
+	}
+	return encodeWavPCM16Channels(interleaved, 8000, 2)
+}
+
+// correctLegDrift resamples leg to exactly target samples, provided that
+// differs from its length by no more than maxDrift (0 = defaultMaxLegDrift)
+func correctLegDrift(leg []int16, target int, maxDrift float64) ([]int16, error) {
+	if maxDrift == 0 {
+		maxDrift = defaultMaxLegDrift
+	}
+	if target == len(leg) {
+		return leg, nil
+	}
+	drift := float64(target-len(leg)) / float64(len(leg))
+	if target <= 0 || math.Abs(drift) > maxDrift {
+		return nil, fmt.Errorf("legs differ in length by %d samples (%.2f%%), more than drift correction allows (%.2f%%)", target-len(leg), 100*drift, 100*maxDrift)
+	}
+	window := makeWindow(WindowBlackman, legDriftWindowSize*2+1, 0)
+	return resamplePCM16Length(leg, float64(len(leg)), float64(target), target, legDriftWindowSize, window), nil
+}
@@ -0,0 +1,86 @@
+package wav2ulaw
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// stereoChannels decodes a stereo WAV into its two channels
+func stereoChannels(t *testing.T, wavBytes []byte) ([]int16, []int16) {
+	t.Helper()
+	decoded, err := decodeWavFloat(wavBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.channels != 2 || decoded.sampleRate != 8000 {
+		t.Fatalf("got %d channels at %d Hz, want stereo at 8000 Hz", decoded.channels, decoded.sampleRate)
+	}
+	left := make([]int16, len(decoded.data)/2)
+	right := make([]int16, len(left))
+	for i := range left {
+		left[i] = int16(decoded.data[2*i] * 32768)
+		right[i] = int16(decoded.data[2*i+1] * 32768)
+	}
+	return left, right
+}
+
+func TestMergeUlawLegsAlignment(t *testing.T) {
+	// Impulses mark the same instant of the call on both legs
+	rx := make([]int16, 8000)
+	tx := make([]int16, 6000)
+	rx[3000] = 16000
+	tx[1000] = 16000
+
+	merged, err := MergeUlawLegs(encodeUlawSamples(rx), encodeUlawSamples(tx), &LegMergeOptions{TXOffset: 250 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	left, right := stereoChannels(t, merged)
+	if len(left) != 8000 {
+		t.Fatalf("got %d frames, want 8000", len(left))
+	}
+	if left[3000] < 15000 || right[3000] < 15000 {
+		t.Errorf("impulses at 3000: left %d, right %d", left[3000], right[3000])
+	}
+
+	// TX starting first pushes RX back instead
+	merged, err = MergeUlawLegs(encodeUlawSamples(rx), encodeUlawSamples(tx), &LegMergeOptions{TXOffset: -125 * time.Microsecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	left, right = stereoChannels(t, merged)
+	if len(left) != 8001 || left[3001] < 15000 || right[1000] < 15000 {
+		t.Errorf("negative offset: %d frames, left %d at 3001, right %d at 1000", len(left), left[3001], right[1000])
+	}
+}
+
+func TestMergeUlawLegsDriftCorrection(t *testing.T) {
+	// The TX recorder's clock runs 0.4% fast, so its leg has 320 samples
+	// more and a 1 kHz tone on it plays slightly flat
+	rx := sineWave(80000, 1000, 8000, 0.3)
+	tx := sineWave(80320, 1000*80000/80320.0, 8000, 0.3)
+
+	merged, err := MergeUlawLegs(encodeUlawSamples(rx), encodeUlawSamples(tx), &LegMergeOptions{CorrectDrift: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	left, right := stereoChannels(t, merged)
+	if len(left) != 80000 {
+		t.Fatalf("got %d frames, want 80000", len(left))
+	}
+	// Corrected, the legs stay in phase to the end of the call
+	end := 79000
+	var diff float64
+	for i := end; i < end+800; i++ {
+		diff = math.Max(diff, math.Abs(float64(left[i])-float64(right[i])))
+	}
+	if diff > 0.02*32767 {
+		t.Errorf("legs differ by up to %.0f after 10 s, want them aligned", diff)
+	}
+
+	// A leg that lost a second is not drift
+	if _, err := MergeUlawLegs(encodeUlawSamples(rx), encodeUlawSamples(tx[:72000]), &LegMergeOptions{CorrectDrift: true}); err == nil {
+		t.Error("expected an error for legs 10% apart")
+	}
+}
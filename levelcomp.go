@@ -0,0 +1,64 @@
+package wav2ulaw
+
+import "math"
+
+const (
+	// Frequency at which LevelCompensation measures the gain of the chain,
+	// the reference frequency of telephone level measurements
+	levelReferenceFreq = 1000.0
+	// Length of the reference tone run through the chain
+	levelProbeSeconds = 0.25
+	// Output at each end of the probe left out of the measurement, where
+	// filters are still settling (samples at 8 kHz)
+	levelProbeSettle = 400
+)
+
+// chainGain measures the gain at levelReferenceFreq of process, which
+// converts samples at inputRate to 8 kHz, by running a reference tone
+// through it. It returns 1 when the gain cannot be measured.
+func chainGain(inputRate int, process func(samples []int16) []int16) float64 {
+	tone := make([]int16, int(levelProbeSeconds*float64(inputRate)))
+	for i := range tone {
+		tone[i] = int16(math.Round(16384 * math.Sin(2*math.Pi*levelReferenceFreq*float64(i)/float64(inputRate))))
+	}
+	output := process(tone)
+	if len(output) <= 2*levelProbeSettle {
+		return 1
+	}
+	gain := toneAmplitude(output[levelProbeSettle:len(output)-levelProbeSettle], 8000) / 16384
+	if gain <= 0 || math.IsNaN(gain) {
+		return 1
+	}
+	return gain
+}
+
+// toneAmplitude returns the amplitude of the levelReferenceFreq component of
+// samples at sampleRate, measured over whole periods
+func toneAmplitude(samples []int16, sampleRate int) float64 {
+	period := float64(sampleRate) / levelReferenceFreq
+	n := int(math.Floor(float64(len(samples))/period) * period)
+	var re, im float64
+	for i, sample := range samples[:n] {
+		phase := 2 * math.Pi * levelReferenceFreq * float64(i) / float64(sampleRate)
+		re += float64(sample) * math.Cos(phase)
+		im += float64(sample) * math.Sin(phase)
+	}
+	return 2 * math.Hypot(re, im) / float64(n)
+}
+
+// filterChainGain measures the gain of filterAndResample for input at
+// inputRate with config
+func filterChainGain(inputRate int, config *AudioConfig) float64 {
+	probe := *config
+	probe.Logger, probe.OnStageOutput = nil, nil
+	return chainGain(inputRate, func(samples []int16) []int16 {
+		return filterAndResample(samples, inputRate, 8000, &probe)
+	})
+}
+
+// scaleSamples multiplies samples by factor in place, saturating at full scale
+func scaleSamples(samples []int16, factor float64) {
+	for i, sample := range samples {
+		samples[i] = int16(math.Max(-32768, math.Min(32767, math.Round(float64(sample)*factor))))
+	}
+}
@@ -0,0 +1,85 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestLevelCompensation(t *testing.T) {
+	type setup struct {
+		name   string
+		rate   int
+		adjust func(*AudioConfig)
+	}
+	setups := []setup{
+		{"8000 Hz band filters", 8000, func(c *AudioConfig) {}},
+		{"16000 Hz butterworth", 16000, func(c *AudioConfig) {}},
+		{"44100 Hz simple", 44100, func(c *AudioConfig) { c.AntiAliasingType = AASimple }},
+		{"48000 Hz chebyshev", 48000, func(c *AudioConfig) { c.AntiAliasingType = AAChebyshev; c.ChebyshevRipple = 1 }},
+		{"22050 Hz windowed-sinc", 22050, func(c *AudioConfig) { c.AntiAliasingType = AAWindowedSinc }},
+	}
+
+	// Level relative to the input; u-law quantizes a tone locked to the
+	// output rate coherently, so the compensation itself is checked on PCM
+	levelDb := func(samples []int16) float64 {
+		return 20 * math.Log10(toneAmplitude(samples[levelProbeSettle:len(samples)-levelProbeSettle], 8000)/16384)
+	}
+	uncompensated := 0.0
+	for _, s := range setups {
+		input := make([]int16, s.rate)
+		for i := range input {
+			input[i] = int16(math.Round(16384 * math.Sin(2*math.Pi*levelReferenceFreq*float64(i)/float64(s.rate))))
+		}
+		wavBytes, err := encodeWavPCM16(input, s.rate)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		config := DefaultAudioConfig()
+		config.NormalizePeak = 0
+		config.CompressionRatio = 1
+		s.adjust(config)
+		plain, err := ConvertWavBytesToUlaw(wavBytes, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		uncompensated = math.Max(uncompensated, math.Abs(levelDb(decodeUlawSamples(plain))))
+
+		config.LevelCompensation = true
+		var compensated []int16
+		config.OnStageOutput = func(stage string, samples []int16, sampleRate int) {
+			if stage == "level compensation" {
+				compensated = append([]int16(nil), samples...)
+			}
+		}
+		whole, err := ConvertWavBytesToUlaw(wavBytes, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if db := levelDb(compensated); math.Abs(db) > 0.1 {
+			t.Errorf("%s: compensated level %.2f dB from the input level", s.name, db)
+		}
+		config.OnStageOutput = nil
+
+		var streamed bytes.Buffer
+		if err := ConvertWavStreamToUlaw(bytes.NewReader(wavBytes), &streamed, config); err != nil {
+			t.Fatal(err)
+		}
+		encoder, err := NewChunkEncoder(s.rate, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunked := append(encoder.Encode(input), encoder.Flush()...)
+
+		want := levelDb(decodeUlawSamples(whole))
+		for name, ulaw := range map[string][]byte{"streamed": streamed.Bytes(), "chunked": chunked} {
+			if db := levelDb(decodeUlawSamples(ulaw)); math.Abs(db-want) > 0.1 {
+				t.Errorf("%s, %s: output %.2f dB, whole-file conversion %.2f dB", s.name, name, db, want)
+			}
+		}
+	}
+	if uncompensated < 0.3 {
+		t.Errorf("largest uncompensated deviation %.2f dB, expected the chains to lose level", uncompensated)
+	}
+}
@@ -0,0 +1,101 @@
+package wav2ulaw
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// LevelWindow holds the levels of one interval of audio. Peak and RMS are
+// linear and relative to full scale (0.0 to 1.0), across all channels.
+type LevelWindow struct {
+	// Start of the interval from the start of the audio
+	Start time.Duration
+	// Length of the interval; the last one may be shorter
+	Duration time.Duration
+	// Highest absolute sample value
+	Peak float64
+	// Root-mean-square level
+	RMS float64
+	// Ungated BS.1770 loudness of the interval (LUFS), -Inf when silent
+	Loudness float64
+}
+
+// MeasureLevels reports the peak, RMS and loudness of every interval (0 =
+// one second) of WAV or raw 8 kHz u-law data, as monitoring uses to find
+// dead air and hot passages in recordings. format names the data format;
+// FormatUnknown detects it with DetectFormat.
+func MeasureLevels(data []byte, format InputFormat, interval time.Duration) ([]LevelWindow, error) {
+	if format == FormatUnknown {
+		var err error
+		if format, err = DetectFormat(data); err != nil {
+			return nil, err
+		}
+	}
+	var decoded *decodedWav
+	switch format {
+	case FormatWAV:
+		var err error
+		if decoded, err = decodeWavFloat(data); err != nil {
+			return nil, err
+		}
+	case FormatUlaw:
+		samples := decodeUlawSamples(data)
+		normalized := make([]float64, len(samples))
+		for i, sample := range samples {
+			normalized[i] = float64(sample) / 32768.0
+		}
+		decoded = &decodedWav{sampleRate: 8000, channels: 1, bitDepth: 16, data: normalized}
+	default:
+		return nil, fmt.Errorf("unsupported format %v", format)
+	}
+	return measureLevels(decoded.deinterleave(), decoded.sampleRate, interval), nil
+}
+
+// measureLevels computes the levels of per-channel normalized samples in
+// consecutive intervals
+func measureLevels(channels [][]float64, sampleRate int, interval time.Duration) []LevelWindow {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	if len(channels) == 0 || sampleRate <= 0 {
+		return nil
+	}
+	frames := len(channels[0])
+	step := max(1, int(math.Round(interval.Seconds()*float64(sampleRate))))
+
+	// K-weight each channel as one continuous signal, so the filters have
+	// settled at every interval boundary
+	weighted := make([][]float64, len(channels))
+	for ch, samples := range channels {
+		shelf, highPass := kWeightingFilters(float64(sampleRate))
+		weighted[ch] = make([]float64, frames)
+		for i, v := range samples {
+			y := highPass.process(shelf.process(v))
+			weighted[ch][i] = y * y
+		}
+	}
+
+	var windows []LevelWindow
+	for start := 0; start < frames; start += step {
+		end := min(start+step, frames)
+		window := LevelWindow{
+			Start:    time.Duration(start) * time.Second / time.Duration(sampleRate),
+			Duration: time.Duration(end-start) * time.Second / time.Duration(sampleRate),
+		}
+		sumSq, power := 0.0, 0.0
+		for ch, samples := range channels {
+			channelPower := 0.0
+			for i, v := range samples[start:end] {
+				sumSq += v * v
+				window.Peak = math.Max(window.Peak, math.Abs(v))
+				channelPower += weighted[ch][start+i]
+			}
+			power += channelPower / float64(end-start)
+		}
+		window.RMS = math.Sqrt(sumSq / float64((end-start)*len(channels)))
+		window.Loudness = powerToLUFS(power)
+		windows = append(windows, window)
+	}
+	return windows
+}
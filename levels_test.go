@@ -0,0 +1,48 @@
+package wav2ulaw
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestMeasureLevels(t *testing.T) {
+	// A second of dead air, then 2.5 s of a 1 kHz tone at half scale
+	samples := append(make([]int16, 8000), sineWave(20000, 1000, 8000, 0.5)...)
+
+	windows, err := MeasureLevels(encodeUlawSamples(samples), FormatUlaw, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(windows) != 4 {
+		t.Fatalf("got %d windows, want 4", len(windows))
+	}
+	if w := windows[0]; w.Peak > 0.001 || w.Loudness > -70 {
+		t.Errorf("dead air: peak %.4f, loudness %.1f LUFS", w.Peak, w.Loudness)
+	}
+	for _, w := range windows[2:] {
+		if math.Abs(w.RMS-0.5/math.Sqrt2) > 0.01 || math.Abs(w.Peak-0.5) > 0.02 {
+			t.Errorf("at %v: RMS %.3f, peak %.3f, want %.3f and 0.5", w.Start, w.RMS, w.Peak, 0.5/math.Sqrt2)
+		}
+		// A full-scale 1 kHz sine reads -3.01 LUFS
+		if math.Abs(w.Loudness-(-3.01-6.02)) > 0.3 {
+			t.Errorf("at %v: loudness %.2f LUFS, want -9.03", w.Start, w.Loudness)
+		}
+	}
+	if last := windows[3]; last.Start != 3*time.Second || last.Duration != 500*time.Millisecond {
+		t.Errorf("last window %v+%v, want 3s+500ms", last.Start, last.Duration)
+	}
+
+	// WAV input is measured at its own rate
+	wavBytes, err := encodeWavPCM16(sineWave(16000, 1000, 16000, 0.5), 16000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	windows, err = MeasureLevels(wavBytes, FormatUnknown, 250*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(windows) != 4 || windows[1].Start != 250*time.Millisecond {
+		t.Errorf("got %d windows, second at %v", len(windows), windows[1].Start)
+	}
+}
@@ -0,0 +1,109 @@
+package wav2ulaw
+
+import (
+	"fmt"
+	"time"
+)
+
+// logDebug reports a processing detail through config.Logger, if one is set
+func logDebug(config *AudioConfig, msg string, args ...any) {
+	if config.Logger != nil {
+		config.Logger.Debug(msg, args...)
+	}
+}
+
+// StageMetrics describes one finished processing stage
+type StageMetrics struct {
+	// Name of the stage, as passed to AudioConfig.OnStage
+	Stage    string
+	Duration time.Duration
+	// Samples the stage consumed and produced, counting every channel.
+	// Stages that decode or encode count the PCM side in both.
+	InputSamples  int
+	OutputSamples int
+}
+
+// logStage reports that a processing stage finished, how long it took and
+// how many samples it consumed and produced, through config.Logger,
+// config.OnStage and config.OnStageMetrics
+func logStage(config *AudioConfig, stage string, start time.Time, inputSamples, outputSamples int) {
+	duration := time.Since(start)
+	if config.OnStage != nil {
+		config.OnStage(stage, duration)
+	}
+	if config.OnStageMetrics != nil {
+		config.OnStageMetrics(StageMetrics{Stage: stage, Duration: duration, InputSamples: inputSamples, OutputSamples: outputSamples})
+	}
+	logDebug(config, "stage finished", "stage", stage, "duration", duration, "input_samples", inputSamples, "output_samples", outputSamples)
+}
+
+// dumpStage passes the output of a processing stage to config.OnStageOutput,
+// if one is set
+func dumpStage(config *AudioConfig, stage string, samples []int16, sampleRate int) {
+	if config.OnStageOutput != nil {
+		config.OnStageOutput(stage, samples, sampleRate)
+	}
+}
+
+// dumpOutput passes output encoded with config.OutputCodec (standard u-law
+// for u-law) to config.OnStageOutput as the "encode" stage, decoded so the
+// quantization can be heard
+func dumpOutput(config *AudioConfig, output []byte) {
+	if config.OnStageOutput != nil {
+		config.OnStageOutput("encode", config.OutputCodec.decode(output), 8000)
+	}
+}
+
+// withoutStageOutput returns config without OnStageOutput, for processing
+// in blocks whose stages would be reported piecemeal
+func withoutStageOutput(config *AudioConfig) *AudioConfig {
+	if config.OnStageOutput == nil {
+		return config
+	}
+	c := *config
+	c.OnStageOutput = nil
+	return &c
+}
+
+// logResample reports the rate conversion about to run and its reduced
+// ratio, and warns when it cannot help the audio
+func logResample(config *AudioConfig, inputRate, outputRate int) {
+	if inputRate < outputRate {
+		logWarn(config, WarnUpsampled, "", "input is upsampled, which adds no bandwidth", "input_rate", inputRate, "output_rate", outputRate)
+	}
+	if config.Logger == nil {
+		return
+	}
+	if inputRate == outputRate {
+		logDebug(config, "input already at the output rate, resampling skipped", "sample_rate", inputRate)
+		return
+	}
+	up, down, _ := rationalRatio(inputRate, outputRate)
+	logDebug(config, "resampling",
+		"input_rate", inputRate,
+		"output_rate", outputRate,
+		"ratio", fmt.Sprintf("%d/%d", up, down),
+		"method", int(config.ResampleMethod),
+		"anti_aliasing", int(config.AntiAliasingType),
+		"window_size", config.ResamplingWindowSize,
+	)
+}
+
+// logWarn reports a problem processing worked around or let through to
+// config.OnWarning and config.Logger, if they are set. args are key-value
+// pairs like those of slog.
+func logWarn(config *AudioConfig, code, stage, msg string, args ...any) {
+	if config.OnWarning != nil {
+		details := make(map[string]any, len(args)/2)
+		for i := 0; i+1 < len(args); i += 2 {
+			details[fmt.Sprint(args[i])] = args[i+1]
+		}
+		config.OnWarning(Warning{Code: code, Stage: stage, Message: msg, Details: details})
+	}
+	if config.Logger != nil {
+		if stage != "" {
+			args = append([]any{"stage", stage}, args...)
+		}
+		config.Logger.Warn(msg, append(args, "code", code)...)
+	}
+}
@@ -0,0 +1,52 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLoggerWarnings(t *testing.T) {
+	var logs bytes.Buffer
+	config := DefaultAudioConfig()
+	config.Logger = slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	// Normalizing flat tops to full scale looks like clipping
+	config.HighPassCutoff, config.LowPassCutoff = 0, 0
+	config.NormalizePeak = 1
+	square := make([]int16, 8000)
+	for i := range square {
+		square[i] = 16384
+		if i/20%2 == 1 {
+			square[i] = -16384
+		}
+	}
+	if _, err := ConvertPCM16ToUlaw(square, 8000, config); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`msg="input already at the output rate, resampling skipped"`,
+		`level=WARN msg="clipping detected" stage=normalize`,
+	} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("logs lack %s:\n%s", want, logs.String())
+		}
+	}
+
+	logs.Reset()
+	if _, err := ConvertPCM16ToUlaw([]int16{32767, 32767, 32767, 0}, 8000, config); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(logs.String(), `level=WARN msg="input is clipped" clipped_samples=3`) {
+		t.Errorf("no warning about clipped input:\n%s", logs.String())
+	}
+
+	logs.Reset()
+	if _, err := ConvertPCM16ToUlaw(sineWave(6000, 440, 6000, 0.5), 6000, config); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(logs.String(), `level=WARN msg="input is upsampled, which adds no bandwidth"`) {
+		t.Errorf("no upsampling warning:\n%s", logs.String())
+	}
+}
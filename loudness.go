@@ -0,0 +1,255 @@
+package wav2ulaw
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	// BS.1770 gating block length and step (ms)
+	loudnessBlockMs = 400
+	loudnessStepMs  = 100
+	// Absolute gate threshold (LUFS)
+	loudnessAbsoluteGate = -70.0
+	// Relative gate for integrated loudness (LU below the ungated mean)
+	loudnessRelativeGate = -10.0
+	// EBU Tech 3342 loudness range: 3 s short-term blocks, -20 LU relative gate
+	loudnessRangeBlockMs = 3000
+	loudnessRangeGate    = -20.0
+	// Oversampling factor for true-peak estimation
+	truePeakOversample = 4
+)
+
+// Loudness contains ITU-R BS.1770 / EBU R128 loudness measurements.
+// Integrated is -Inf for silent or too-short input.
+type Loudness struct {
+	// Gated integrated loudness (LUFS)
+	Integrated float64
+	// Loudness range (LU)
+	Range float64
+	// Maximum inter-sample peak level (dBTP)
+	TruePeak float64
+}
+
+// MeasureLoudness measures integrated loudness, loudness range and true peak
+// of WAV file bytes following ITU-R BS.1770-4 gating. All channels are
+// weighted equally, which matches the standard for mono and stereo.
+func MeasureLoudness(wavBytes []byte) (*Loudness, error) {
+	decoded, err := decodeWavFloat(wavBytes)
+	if err != nil {
+		return nil, err
+	}
+	return measureLoudness(decoded.deinterleave(), decoded.sampleRate), nil
+}
+
+// deinterleave splits normalized interleaved samples into per-channel slices
+func (d *decodedWav) deinterleave() [][]float64 {
+	frames := len(d.data) / d.channels
+	channels := make([][]float64, d.channels)
+	for ch := range channels {
+		channels[ch] = make([]float64, frames)
+		for i := 0; i < frames; i++ {
+			channels[ch][i] = d.data[i*d.channels+ch]
+		}
+	}
+	return channels
+}
+
+// kWeightingFilters returns the BS.1770 pre-filter (high shelf) and RLB
+// high-pass stages for the given sample rate
+func kWeightingFilters(sampleRate float64) (*biquad, *biquad) {
+	// Stage 1: high shelf modelling the acoustic effect of the head
+	f0 := 1681.974450955533
+	gain := 3.999843853973347
+	q := 0.7071752369554196
+	k := math.Tan(math.Pi * f0 / sampleRate)
+	vh := math.Pow(10, gain/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/q + k*k
+	shelf := &biquad{
+		b0: (vh + vb*k/q + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/q + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+
+	// Stage 2: revised low-frequency B-curve high-pass
+	f0 = 38.13547087602444
+	q = 0.5003270373238773
+	k = math.Tan(math.Pi * f0 / sampleRate)
+	a0 = 1 + k/q + k*k
+	highPass := &biquad{
+		b0: 1,
+		b1: -2,
+		b2: 1,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+
+	return shelf, highPass
+}
+
+// measureLoudness computes loudness statistics for per-channel normalized samples
+func measureLoudness(channels [][]float64, sampleRate int) *Loudness {
+	result := &Loudness{
+		Integrated: math.Inf(-1),
+		TruePeak:   math.Inf(-1),
+	}
+	if len(channels) == 0 || sampleRate <= 0 {
+		return result
+	}
+
+	// K-weight every channel and keep squared samples for block energies
+	frames := len(channels[0])
+	weighted := make([][]float64, len(channels))
+	for ch, samples := range channels {
+		shelf, highPass := kWeightingFilters(float64(sampleRate))
+		sq := make([]float64, frames)
+		for i, v := range samples {
+			y := highPass.process(shelf.process(v))
+			sq[i] = y * y
+		}
+		weighted[ch] = sq
+	}
+
+	step := sampleRate * loudnessStepMs / 1000
+	blocks := blockLoudness(weighted, sampleRate*loudnessBlockMs/1000, step)
+	result.Integrated = gatedLoudness(blocks, loudnessRelativeGate)
+
+	shortTerm := blockLoudness(weighted, sampleRate*loudnessRangeBlockMs/1000, step)
+	result.Range = loudnessRange(shortTerm)
+
+	peak := 0.0
+	for _, samples := range channels {
+		peak = math.Max(peak, truePeak(samples))
+	}
+	if peak > 0 {
+		result.TruePeak = 20 * math.Log10(peak)
+	}
+
+	return result
+}
+
+// blockLoudness returns the loudness (LUFS) of overlapping blocks
+func blockLoudness(weighted [][]float64, blockLen, step int) []float64 {
+	if blockLen < 1 || step < 1 || len(weighted[0]) < blockLen {
+		return nil
+	}
+
+	var blocks []float64
+	for start := 0; start+blockLen <= len(weighted[0]); start += step {
+		power := 0.0
+		for _, sq := range weighted {
+			sum := 0.0
+			for _, v := range sq[start : start+blockLen] {
+				sum += v
+			}
+			power += sum / float64(blockLen)
+		}
+		blocks = append(blocks, powerToLUFS(power))
+	}
+	return blocks
+}
+
+// powerToLUFS converts mean K-weighted power to loudness
+func powerToLUFS(power float64) float64 {
+	if power <= 0 {
+		return math.Inf(-1)
+	}
+	return -0.691 + 10*math.Log10(power)
+}
+
+// lufsToPower is the inverse of powerToLUFS
+func lufsToPower(lufs float64) float64 {
+	return math.Pow(10, (lufs+0.691)/10)
+}
+
+// gatedLoudness applies the absolute gate, then a gate relative to the mean
+// of the remaining blocks, and returns the mean loudness of what survives
+func gatedLoudness(blocks []float64, relativeGate float64) float64 {
+	mean := func(threshold float64) (float64, int) {
+		sum, n := 0.0, 0
+		for _, l := range blocks {
+			if l > threshold {
+				sum += lufsToPower(l)
+				n++
+			}
+		}
+		if n == 0 {
+			return math.Inf(-1), 0
+		}
+		return powerToLUFS(sum / float64(n)), n
+	}
+
+	ungated, n := mean(loudnessAbsoluteGate)
+	if n == 0 {
+		return math.Inf(-1)
+	}
+	gated, _ := mean(math.Max(loudnessAbsoluteGate, ungated+relativeGate))
+	return gated
+}
+
+// loudnessRange computes the EBU Tech 3342 loudness range from short-term blocks
+func loudnessRange(shortTerm []float64) float64 {
+	var gated []float64
+	for _, l := range shortTerm {
+		if l > loudnessAbsoluteGate {
+			gated = append(gated, l)
+		}
+	}
+	if len(gated) == 0 {
+		return 0
+	}
+
+	sum := 0.0
+	for _, l := range gated {
+		sum += lufsToPower(l)
+	}
+	threshold := powerToLUFS(sum/float64(len(gated))) + loudnessRangeGate
+
+	var kept []float64
+	for _, l := range gated {
+		if l > threshold {
+			kept = append(kept, l)
+		}
+	}
+	if len(kept) == 0 {
+		return 0
+	}
+	sort.Float64s(kept)
+
+	percentile := func(p float64) float64 {
+		return kept[int(math.Round(p*float64(len(kept)-1)))]
+	}
+	return percentile(0.95) - percentile(0.10)
+}
+
+// truePeak estimates the highest inter-sample peak by 4x oversampling
+func truePeak(samples []float64) float64 {
+	const halfLength = 12 * truePeakOversample
+	window := makeWindow(WindowKaiser, halfLength*2+1, 8.6)
+	kernel := designLowpassFIR(halfLength, 0.5/truePeakOversample, window)
+
+	peak := 0.0
+	for _, v := range samples {
+		peak = math.Max(peak, math.Abs(v))
+	}
+
+	// Evaluate each intermediate phase of the interpolated signal
+	for i := range samples {
+		for phase := 1; phase < truePeakOversample; phase++ {
+			sum := 0.0
+			for k := -halfLength / truePeakOversample; k <= halfLength/truePeakOversample; k++ {
+				idx := i + k
+				tap := halfLength + phase - k*truePeakOversample
+				if idx < 0 || idx >= len(samples) || tap < 0 || tap >= len(kernel) {
+					continue
+				}
+				sum += samples[idx] * kernel[tap]
+			}
+			peak = math.Max(peak, math.Abs(sum*truePeakOversample))
+		}
+	}
+	return peak
+}
@@ -0,0 +1,47 @@
+package wav2ulaw
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMeasureLoudnessReferenceTone(t *testing.T) {
+	// A 1 kHz sine at -20 dBFS on one channel measures -23.0 LUFS
+	samples := sineWave(48000*5, 1000, 48000, 0.1)
+	wavBytes, err := encodeWavPCM16(samples, 48000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loudness, err := MeasureLoudness(wavBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(loudness.Integrated+23.01) > 0.1 {
+		t.Errorf("expected -23.0 LUFS, got %.2f", loudness.Integrated)
+	}
+	if math.Abs(loudness.TruePeak+20) > 0.1 {
+		t.Errorf("expected -20 dBTP, got %.2f", loudness.TruePeak)
+	}
+	if loudness.Range > 0.5 {
+		t.Errorf("expected near-zero loudness range for a steady tone, got %.2f", loudness.Range)
+	}
+}
+
+func TestMeasureLoudnessSilence(t *testing.T) {
+	loudness := measureLoudness([][]float64{make([]float64, 48000)}, 48000)
+	if !math.IsInf(loudness.Integrated, -1) {
+		t.Errorf("expected -Inf for silence, got %.2f", loudness.Integrated)
+	}
+}
+
+func TestTruePeakFindsInterSamplePeaks(t *testing.T) {
+	// fs/4 sine sampled at 45 degree phase never hits its true peak of 1.0
+	samples := make([]float64, 400)
+	for i := range samples {
+		samples[i] = math.Sin(math.Pi/2*float64(i) + math.Pi/4)
+	}
+	if peak := truePeak(samples); math.Abs(peak-1.0) > 0.02 {
+		t.Errorf("expected true peak near 1.0, got %.3f", peak)
+	}
+}
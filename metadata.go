@@ -0,0 +1,255 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Size of the fixed part of a bext chunk, before the coding history
+const bextFixedSize = 602
+
+// Metadata is the descriptive metadata of a WAV file: the tags of its RIFF
+// INFO list and its Broadcast Wave (bext) chunk
+type Metadata struct {
+	// INFO tags by chunk ID, e.g. "INAM" (title) or "ICMT" (comment)
+	Info map[string]string
+	// Broadcast Wave description, nil when the file has none
+	Bext *Bext
+}
+
+// Bext is a Broadcast Wave Format (EBU Tech 3285) bext chunk. Text fields
+// are stored in fixed-size ASCII fields and truncated to fit.
+type Bext struct {
+	Description         string // Up to 256 characters
+	Originator          string // Up to 32 characters
+	OriginatorReference string // Up to 32 characters
+	OriginationDate     string // yyyy-mm-dd
+	OriginationTime     string // hh:mm:ss
+	// Samples since midnight at the start of the file
+	TimeReference uint64
+	Version       uint16
+	UMID          [64]byte
+	// Loudness fields of version 2, in hundredths of a dB or LU
+	LoudnessValue        int16
+	LoudnessRange        int16
+	MaxTruePeakLevel     int16
+	MaxMomentaryLoudness int16
+	MaxShortTermLoudness int16
+	CodingHistory        string
+}
+
+// InfoTags names the common INFO chunk IDs
+var InfoTags = map[string]string{
+	"IART": "artist",
+	"ICMT": "comment",
+	"ICOP": "copyright",
+	"ICRD": "date",
+	"IENG": "engineer",
+	"IGNR": "genre",
+	"IKEY": "keywords",
+	"INAM": "title",
+	"IPRD": "product",
+	"ISBJ": "subject",
+	"ISFT": "software",
+	"ISRC": "source",
+	"ITCH": "technician",
+}
+
+// riffChunk is one chunk of a RIFF file with its payload, without padding
+type riffChunk struct {
+	id   string
+	data []byte
+}
+
+// parseRIFFChunks splits a WAV file into its top-level chunks. A last chunk
+// claiming more bytes than remain, as left by interrupted or streaming
+// writers, is cut to the end of the file.
+func parseRIFFChunks(data []byte) ([]riffChunk, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, fmt.Errorf("not a WAV file")
+	}
+	var chunks []riffChunk
+	for pos := 12; pos+8 <= len(data); {
+		id := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		pos += 8
+		if size > len(data)-pos {
+			size = len(data) - pos
+		}
+		chunks = append(chunks, riffChunk{id: id, data: data[pos : pos+size]})
+		pos += size + size%2
+	}
+	return chunks, nil
+}
+
+// buildRIFF assembles a WAV file from chunks, padding odd-sized ones
+func buildRIFF(chunks []riffChunk) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("RIFFxxxxWAVE")
+	for _, c := range chunks {
+		buf.WriteString(c.id)
+		binary.Write(&buf, binary.LittleEndian, uint32(len(c.data)))
+		buf.Write(c.data)
+		if len(c.data)%2 == 1 {
+			buf.WriteByte(0)
+		}
+	}
+	out := buf.Bytes()
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out
+}
+
+// ReadWavMetadata returns the INFO tags and bext chunk of a WAV file
+func ReadWavMetadata(wavBytes []byte) (*Metadata, error) {
+	chunks, err := parseRIFFChunks(wavBytes)
+	if err != nil {
+		return nil, err
+	}
+	md := &Metadata{Info: map[string]string{}}
+	for _, c := range chunks {
+		switch {
+		case c.id == "LIST" && len(c.data) >= 4 && string(c.data[:4]) == "INFO":
+			parseInfoList(c.data[4:], md.Info)
+		case c.id == "bext":
+			if md.Bext, err = parseBext(c.data); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return md, nil
+}
+
+// WriteWavMetadata returns a copy of a WAV file with its INFO list and bext
+// chunk replaced by md. Other chunks are kept in order; the bext chunk is
+// placed first and the INFO list before the audio data, where readers that
+// stop at the data chunk still find them.
+func WriteWavMetadata(wavBytes []byte, md *Metadata) ([]byte, error) {
+	chunks, err := parseRIFFChunks(wavBytes)
+	if err != nil {
+		return nil, err
+	}
+	var kept []riffChunk
+	if md.Bext != nil {
+		kept = append(kept, riffChunk{id: "bext", data: md.Bext.encode()})
+	}
+	infoWritten := false
+	for _, c := range chunks {
+		if c.id == "bext" || (c.id == "LIST" && len(c.data) >= 4 && string(c.data[:4]) == "INFO") {
+			continue
+		}
+		if c.id == "data" && !infoWritten {
+			if list := encodeInfoList(md.Info); list != nil {
+				kept = append(kept, riffChunk{id: "LIST", data: list})
+			}
+			infoWritten = true
+		}
+		kept = append(kept, c)
+	}
+	if !infoWritten {
+		if list := encodeInfoList(md.Info); list != nil {
+			kept = append(kept, riffChunk{id: "LIST", data: list})
+		}
+	}
+	return buildRIFF(kept), nil
+}
+
+// IsEmpty reports whether md holds no tags and no bext chunk
+func (md *Metadata) IsEmpty() bool {
+	return md == nil || (len(md.Info) == 0 && md.Bext == nil)
+}
+
+// parseInfoList reads the subchunks of a LIST/INFO payload into tags
+func parseInfoList(data []byte, tags map[string]string) {
+	for pos := 0; pos+8 <= len(data); {
+		id := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		pos += 8
+		if size > len(data)-pos {
+			size = len(data) - pos
+		}
+		tags[id] = fixedString(data[pos : pos+size])
+		pos += size + size%2
+	}
+}
+
+// encodeInfoList builds a LIST/INFO payload from tags in ID order, or nil
+// when there are none. Values are stored NUL-terminated.
+func encodeInfoList(tags map[string]string) []byte {
+	ids := make([]string, 0, len(tags))
+	for id, value := range tags {
+		if value != "" {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	sort.Strings(ids)
+
+	var buf bytes.Buffer
+	buf.WriteString("INFO")
+	for _, id := range ids {
+		value := append([]byte(tags[id]), 0)
+		buf.WriteString(id)
+		binary.Write(&buf, binary.LittleEndian, uint32(len(value)))
+		buf.Write(value)
+		if len(value)%2 == 1 {
+			buf.WriteByte(0)
+		}
+	}
+	return buf.Bytes()
+}
+
+// parseBext decodes a bext chunk payload
+func parseBext(data []byte) (*Bext, error) {
+	if len(data) < bextFixedSize {
+		return nil, fmt.Errorf("bext chunk too short (%d bytes)", len(data))
+	}
+	b := &Bext{
+		Description:          fixedString(data[0:256]),
+		Originator:           fixedString(data[256:288]),
+		OriginatorReference:  fixedString(data[288:320]),
+		OriginationDate:      fixedString(data[320:330]),
+		OriginationTime:      fixedString(data[330:338]),
+		TimeReference:        binary.LittleEndian.Uint64(data[338:]),
+		Version:              binary.LittleEndian.Uint16(data[346:]),
+		LoudnessValue:        int16(binary.LittleEndian.Uint16(data[412:])),
+		LoudnessRange:        int16(binary.LittleEndian.Uint16(data[414:])),
+		MaxTruePeakLevel:     int16(binary.LittleEndian.Uint16(data[416:])),
+		MaxMomentaryLoudness: int16(binary.LittleEndian.Uint16(data[418:])),
+		MaxShortTermLoudness: int16(binary.LittleEndian.Uint16(data[420:])),
+		CodingHistory:        fixedString(data[bextFixedSize:]),
+	}
+	copy(b.UMID[:], data[348:412])
+	return b, nil
+}
+
+// encode builds the bext chunk payload
+func (b *Bext) encode() []byte {
+	data := make([]byte, bextFixedSize, bextFixedSize+len(b.CodingHistory))
+	copy(data[0:256], b.Description)
+	copy(data[256:288], b.Originator)
+	copy(data[288:320], b.OriginatorReference)
+	copy(data[320:330], b.OriginationDate)
+	copy(data[330:338], b.OriginationTime)
+	binary.LittleEndian.PutUint64(data[338:], b.TimeReference)
+	binary.LittleEndian.PutUint16(data[346:], b.Version)
+	copy(data[348:412], b.UMID[:])
+	binary.LittleEndian.PutUint16(data[412:], uint16(b.LoudnessValue))
+	binary.LittleEndian.PutUint16(data[414:], uint16(b.LoudnessRange))
+	binary.LittleEndian.PutUint16(data[416:], uint16(b.MaxTruePeakLevel))
+	binary.LittleEndian.PutUint16(data[418:], uint16(b.MaxMomentaryLoudness))
+	binary.LittleEndian.PutUint16(data[420:], uint16(b.MaxShortTermLoudness))
+	return append(data, b.CodingHistory...)
+}
+
+// fixedString returns the text of a NUL-padded field
+func fixedString(data []byte) string {
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		data = data[:i]
+	}
+	return strings.TrimRight(string(data), " ")
+}
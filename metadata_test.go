@@ -0,0 +1,69 @@
+package wav2ulaw
+
+import "testing"
+
+func TestWavMetadataRoundTrip(t *testing.T) {
+	input := sineWave(8000, 440, 8000, 0.5)
+	wav, err := encodeWavPCM16(input, 8000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	md := &Metadata{
+		Info: map[string]string{"INAM": "Welcome prompt", "ICMT": "padded"},
+		Bext: &Bext{Description: "Main menu", Originator: "studio", OriginationDate: "2024-05-01", TimeReference: 1 << 33, Version: 1, CodingHistory: "A=PCM,F=8000,W=16,M=mono\r\n"},
+	}
+	tagged, err := WriteWavMetadata(wav, md)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ReadWavMetadata(tagged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Info) != 2 || got.Info["INAM"] != "Welcome prompt" || got.Info["ICMT"] != "padded" {
+		t.Errorf("unexpected INFO tags %v", got.Info)
+	}
+	if got.Bext == nil || *got.Bext != *md.Bext {
+		t.Errorf("unexpected bext %+v", got.Bext)
+	}
+
+	// The audio still decodes with the extra chunks in place
+	samples, rate, err := decodeWavSamples(tagged, DefaultAudioConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rate != 8000 || len(samples) != len(input) || samples[100] != input[100] {
+		t.Errorf("audio changed: %d samples at %d Hz", len(samples), rate)
+	}
+
+	// Replacing the metadata drops the old chunks instead of adding more
+	retagged, err := WriteWavMetadata(tagged, &Metadata{Info: map[string]string{"IART": "narrator"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ = ReadWavMetadata(retagged)
+	if len(got.Info) != 1 || got.Info["IART"] != "narrator" || got.Bext != nil {
+		t.Errorf("unexpected metadata after rewrite %+v", got)
+	}
+}
+
+func TestTelephoneEffectKeepsMetadata(t *testing.T) {
+	wav, _ := encodeWavPCM16(sineWave(16000, 1000, 16000, 0.5), 16000)
+	tagged, err := WriteWavMetadata(wav, &Metadata{Info: map[string]string{"INAM": "call"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, err := ApplyTelephoneEffect(tagged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	md, err := ReadWavMetadata(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md.Info["INAM"] != "call" {
+		t.Errorf("expected the title to be kept, got %v", md.Info)
+	}
+}
@@ -0,0 +1,148 @@
+package wav2ulaw
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// Length of a meter frame when none is given
+	defaultMeterFrame = 20 * time.Millisecond
+	// Time a peak is held before it falls
+	meterPeakHold = 1500 * time.Millisecond
+	// Rate at which a held peak falls once released (dB per second)
+	meterPeakFallDb = 20.0
+	// Time the VU value takes to reach 99% of a steady level, as IEC 60268-17
+	// specifies
+	meterVUTime = 300 * time.Millisecond
+	// Level reported for digital silence (dBFS), the range of 16-bit audio
+	meterFloorDb = -96.0
+)
+
+// MeterReading holds the levels of one frame of metered audio. Levels are in
+// dBFS, a full-scale sine peaking at 0, and never below -96 so consoles can
+// draw them directly.
+type MeterReading struct {
+	// Start of the frame from the start of the audio
+	Start time.Duration
+	// RMS level of the frame
+	Level float64
+	// Highest absolute sample of the frame
+	Peak float64
+	// Highest frame peak of the last 1.5 s, then falling at 20 dB/s
+	PeakHold float64
+	// RMS level with the ballistics of a VU meter, reaching 99% of a steady
+	// level in 300 ms
+	VU float64
+}
+
+// Meter reports the levels of audio as it streams past, one MeterReading per
+// frame, e.g. to show live levels of a conversion on an operator console.
+// Samples are given in chunks of any size; a frame is reported once it is
+// complete. A Meter is not safe for concurrent use.
+type Meter struct {
+	sampleRate int
+	frameSize  int
+	onReading  func(MeterReading)
+
+	// Samples reported so far and the partial frame after them
+	position int64
+	count    int
+	sumSq    float64
+	peak     float64
+	// Held peak in dBFS and samples it has been held for
+	held     float64
+	heldFor  int
+	vu       float64
+	vuFactor float64
+}
+
+// NewMeter creates a meter for audio at sampleRate calling onReading with
+// every frame (0 = 20 ms) of it
+func NewMeter(sampleRate int, frame time.Duration, onReading func(MeterReading)) (*Meter, error) {
+	if sampleRate <= 0 {
+		return nil, fmt.Errorf("invalid sample rate %d", sampleRate)
+	}
+	if frame == 0 {
+		frame = defaultMeterFrame
+	}
+	frameSize := int(math.Round(frame.Seconds() * float64(sampleRate)))
+	if frameSize < 1 {
+		return nil, fmt.Errorf("invalid meter frame %v", frame)
+	}
+	m := &Meter{
+		sampleRate: sampleRate,
+		frameSize:  frameSize,
+		onReading:  onReading,
+		// One-pole smoothing of the frame RMS reaching 99% in meterVUTime
+		vuFactor: 1 - math.Exp(-math.Log(100)*float64(frameSize)/(meterVUTime.Seconds()*float64(sampleRate))),
+	}
+	m.Reset()
+	return m, nil
+}
+
+// Process meters 16-bit PCM samples
+func (m *Meter) Process(samples []int16) {
+	for _, sample := range samples {
+		v := float64(sample) / 32768.0
+		m.sumSq += v * v
+		m.peak = math.Max(m.peak, math.Abs(v))
+		m.count++
+		if m.count == m.frameSize {
+			m.report()
+		}
+	}
+}
+
+// Write meters u-law audio, so a Meter created for 8 kHz can be placed
+// behind a conversion with io.MultiWriter. It never fails.
+func (m *Meter) Write(ulaw []byte) (int, error) {
+	m.Process(decodeUlawSamples(ulaw))
+	return len(ulaw), nil
+}
+
+// Reset starts metering a new stream, discarding any partial frame
+func (m *Meter) Reset() {
+	m.position, m.count, m.sumSq, m.peak = 0, 0, 0, 0
+	m.held, m.heldFor, m.vu = meterFloorDb, 0, 0
+}
+
+// report completes the current frame
+func (m *Meter) report() {
+	power := m.sumSq / float64(m.count)
+	m.vu += (math.Sqrt(power) - m.vu) * m.vuFactor
+	reading := MeterReading{
+		Start: time.Duration(m.position) * time.Second / time.Duration(m.sampleRate),
+		// A full-scale sine has an RMS of 1/sqrt(2), so power is doubled to
+		// read 0 dBFS for it
+		Level: meterDb(2 * power),
+		Peak:  meterDb(m.peak * m.peak),
+		VU:    meterDb(2 * m.vu * m.vu),
+	}
+
+	if reading.Peak >= m.held {
+		m.held, m.heldFor = reading.Peak, 0
+	} else {
+		m.heldFor += m.count
+		if release := m.heldFor - int(meterPeakHold.Seconds()*float64(m.sampleRate)); release > 0 {
+			fall := meterPeakFallDb * float64(min(release, m.count)) / float64(m.sampleRate)
+			m.held = math.Max(reading.Peak, m.held-fall)
+		}
+	}
+	reading.PeakHold = m.held
+
+	m.position += int64(m.count)
+	m.count, m.sumSq, m.peak = 0, 0, 0
+	if m.onReading != nil {
+		m.onReading(reading)
+	}
+}
+
+// meterDb converts a power relative to full scale to dBFS, floored at meterFloorDb
+func meterDb(power float64) float64 {
+	if power <= 0 {
+		return meterFloorDb
+	}
+	return math.Max(meterFloorDb, 10*math.Log10(power))
+}
@@ -0,0 +1,72 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestMeterReadings(t *testing.T) {
+	var readings []MeterReading
+	meter, err := NewMeter(8000, 0, func(r MeterReading) { readings = append(readings, r) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	tone := sineWave(8000, 1000, 8000, 0.5)
+	// Chunks that do not line up with the 20 ms frames
+	for start := 0; start < len(tone); start += 333 {
+		meter.Process(tone[start:min(start+333, len(tone))])
+	}
+	if len(readings) != 50 {
+		t.Fatalf("got %d readings, want 50", len(readings))
+	}
+	last := readings[49]
+	if last.Start != 980*time.Millisecond {
+		t.Errorf("last frame starts at %v", last.Start)
+	}
+	for name, got := range map[string]float64{"level": last.Level, "peak": last.Peak, "peak hold": last.PeakHold, "VU": last.VU} {
+		if math.Abs(got+6) > 0.5 {
+			t.Errorf("%s %.2f dBFS, want -6", name, got)
+		}
+	}
+	if readings[0].VU > -10 {
+		t.Errorf("VU %.2f dBFS after 20 ms, want it still rising", readings[0].VU)
+	}
+
+	// The peak is held through a second of silence and then falls
+	readings = nil
+	meter.Process(make([]int16, 16000))
+	if got := readings[49].PeakHold; math.Abs(got+6) > 0.5 {
+		t.Errorf("peak hold %.2f dBFS after 1 s of silence, want -6", got)
+	}
+	if got := readings[99].PeakHold; got > -15 || got < -17 {
+		t.Errorf("peak hold %.2f dBFS after 2 s of silence, want about -16", got)
+	}
+	if readings[99].Level != meterFloorDb || readings[99].VU > -60 {
+		t.Errorf("silence read as level %.2f, VU %.2f", readings[99].Level, readings[99].VU)
+	}
+}
+
+func TestMeterBehindStream(t *testing.T) {
+	wavBytes, err := encodeWavPCM16(sineWave(16000, 1000, 16000, 0.5), 16000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frames := 0
+	meter, err := NewMeter(8000, 100*time.Millisecond, func(MeterReading) { frames++ })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ConvertWavStreamToUlaw(bytes.NewReader(wavBytes), io.MultiWriter(io.Discard, meter), DefaultAudioConfig()); err != nil {
+		t.Fatal(err)
+	}
+	if frames != 10 {
+		t.Errorf("got %d readings of 100 ms, want 10", frames)
+	}
+
+	if _, err := NewMeter(0, 0, nil); err == nil {
+		t.Error("expected an error for a zero sample rate")
+	}
+}
@@ -0,0 +1,107 @@
+package wav2ulaw
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// MixSource is one input of MixToUlaw
+type MixSource struct {
+	// WAV file or raw 8 kHz u-law bytes
+	Data []byte
+	// Gain applied to the source in dB (0 = unchanged)
+	GainDb float64
+	// Delay before the source starts, in milliseconds
+	OffsetMs float64
+}
+
+// MixToUlaw overlays two sources, such as a beep or background music under a
+// spoken prompt, and converts the mix to u-law. Both are decoded to mono and
+// the second is resampled to the sample rate of the first before they are
+// summed by MixPCM16; the mix lasts as long as the longer source including
+// its offset. It then goes through the processing chain like the input of
+// ConvertPCM16ToUlaw.
+func MixToUlaw(a, b MixSource, config *AudioConfig) ([]byte, error) {
+	if config == nil {
+		config = DefaultAudioConfig()
+	}
+
+	start := time.Now()
+	first, rate, err := decodeMixData(a.Data, config)
+	if err != nil {
+		return nil, fmt.Errorf("first source: %w", err)
+	}
+	defer putInt16s(first)
+	second, secondRate, err := decodeMixData(b.Data, config)
+	if err != nil {
+		return nil, fmt.Errorf("second source: %w", err)
+	}
+	if secondRate != rate {
+		logResample(config, secondRate, rate)
+		second = release(second, resample(second, secondRate, rate, config))
+	}
+	defer putInt16s(second)
+
+	mixed := MixPCM16(
+		delaySamples(first, a.OffsetMs, rate), delaySamples(second, b.OffsetMs, rate),
+		math.Pow(10, a.GainDb/20), math.Pow(10, b.GainDb/20),
+	)
+	logStage(config, "mix", start, len(first)+len(second), len(mixed))
+	return ConvertPCM16ToUlaw(mixed, rate, config)
+}
+
+// MixPCM16 sums two mono sample sequences at the same rate, scaled by linear
+// gains. The result is as long as the longer input. When the sum would clip,
+// the whole mix is scaled down so its peak is full scale instead.
+func MixPCM16(a, b []int16, gainA, gainB float64) []int16 {
+	sum := make([]float64, max(len(a), len(b)))
+	for i, s := range a {
+		sum[i] = float64(s) * gainA
+	}
+	for i, s := range b {
+		sum[i] += float64(s) * gainB
+	}
+
+	peak := 0.0
+	for _, v := range sum {
+		peak = math.Max(peak, math.Abs(v))
+	}
+	scale := 1.0
+	if peak > 32767 {
+		scale = 32767 / peak
+	}
+
+	out := make([]int16, len(sum))
+	for i, v := range sum {
+		out[i] = int16(math.Max(-32768, math.Min(32767, math.Round(v*scale))))
+	}
+	return out
+}
+
+// decodeMixData decodes WAV or u-law data to mono samples and returns them
+// with their sample rate. The samples come from the pool.
+func decodeMixData(data []byte, config *AudioConfig) ([]int16, int, error) {
+	format, err := DetectFormat(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	if format == FormatUlaw {
+		samples := getInt16s(len(data))
+		copy(samples, decodeUlawSamples(data))
+		return samples, 8000, nil
+	}
+
+	mono := *config
+	mono.ForceMono = true
+	return decodeWavSamples(data, &mono)
+}
+
+// delaySamples returns samples preceded by offsetMs of silence
+func delaySamples(samples []int16, offsetMs float64, sampleRate int) []int16 {
+	n := int(math.Round(offsetMs * float64(sampleRate) / 1000))
+	if n <= 0 {
+		return samples
+	}
+	return append(make([]int16, n, n+len(samples)), samples...)
+}
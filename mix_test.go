@@ -0,0 +1,63 @@
+package wav2ulaw
+
+import "testing"
+
+func TestMixPCM16(t *testing.T) {
+	a := []int16{1000, 2000, 3000}
+	b := []int16{500, -500}
+	got := MixPCM16(a, b, 1, 0.5)
+	want := []int16{1250, 1750, 3000}
+	if len(got) != len(want) {
+		t.Fatalf("got %d samples, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("sample %d: got %d, want %d", i, got[i], want[i])
+		}
+	}
+
+	// A clipping sum is scaled down as a whole, keeping the ratio between samples
+	loud := MixPCM16([]int16{30000, 15000}, []int16{30000, 15000}, 1, 1)
+	if loud[0] != 32767 || loud[1] != 16384 {
+		t.Errorf("clipping mix %v, want [32767 16384]", loud)
+	}
+}
+
+func TestMixToUlaw(t *testing.T) {
+	speech, err := encodeWavPCM16(sineWave(16000, 440, 16000, 0.4), 16000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A 1 kHz beep at 8 kHz u-law, half a second long, starting 250 ms in
+	beep := encodeUlawSamples(sineWave(4000, 1000, 8000, 0.4))
+
+	config := DefaultAudioConfig()
+	config.NormalizePeak = 0
+	config.CompressionRatio = 1
+	ulaw, err := MixToUlaw(MixSource{Data: speech}, MixSource{Data: beep, GainDb: -6, OffsetMs: 250}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ulaw) != 8000 {
+		t.Fatalf("got %d bytes, want 8000", len(ulaw))
+	}
+	decoded := decodeUlawSamples(ulaw)
+	before, during := decoded[400:1600], decoded[2800:5600]
+	if level := toneLevel(before, 1000, 8000); level > 0.02 {
+		t.Errorf("beep level %.3f before its offset", level)
+	}
+	tone, beepLevel := toneLevel(during, 440, 8000), toneLevel(during, 1000, 8000)
+	if tone < 0.2 || beepLevel < 0.1 || beepLevel > tone {
+		t.Errorf("440 Hz at %.3f and beep at %.3f, want both with the beep 6 dB down", tone, beepLevel)
+	}
+
+	// Overlay longer than the first input extends the mix
+	long := encodeUlawSamples(sineWave(12000, 1000, 8000, 0.4))
+	if ulaw, err := MixToUlaw(MixSource{Data: speech}, MixSource{Data: long}, config); err != nil || len(ulaw) != 12000 {
+		t.Errorf("got %d bytes (%v), want 12000", len(ulaw), err)
+	}
+
+	if _, err := MixToUlaw(MixSource{Data: speech}, MixSource{Data: []byte("not audio")}, config); err == nil {
+		t.Error("invalid second source accepted")
+	}
+}
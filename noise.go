@@ -0,0 +1,143 @@
+package wav2ulaw
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+const (
+	// Frames this far below the loudest one are left out of the speech level (dB)
+	speechGateDb = 40.0
+	// Accepted range of NoiseOverlay.SNR (dB)
+	minNoiseSNR = -30
+	maxNoiseSNR = 100
+)
+
+// NoiseColor selects the spectrum of generated background noise
+type NoiseColor int
+
+const (
+	NoiseWhite NoiseColor = iota // Flat spectrum
+	NoisePink                    // Falling 3 dB per octave, closer to ambient noise
+)
+
+// NoiseOverlay describes background noise mixed into the input, e.g. to
+// build ASR training and test sets from clean recordings
+type NoiseOverlay struct {
+	// Ratio of the speech level to the noise level (dB)
+	SNR float64
+	// WAV or raw 8 kHz u-law noise, looped to the length of the input
+	// (nil = generated noise)
+	Data []byte
+	// Spectrum of generated noise
+	Color NoiseColor
+	// Seed of generated noise; the same seed gives the same noise
+	Seed int64
+}
+
+// addNoise mixes the configured noise into samples at sampleRate in place,
+// scaled so the speech level is config.Noise.SNR dB above the noise level.
+// The speech level is the mean power of the 20 ms frames within speechGateDb
+// of the loudest, so pauses do not lower it. Silent input is left as is.
+func addNoise(samples []int16, sampleRate int, config *AudioConfig) error {
+	overlay := config.Noise
+	speech := speechPower(samples, sampleRate)
+	if speech == 0 {
+		logDebug(config, "input is silent, no noise added")
+		return nil
+	}
+
+	noise, err := noiseSamples(overlay, len(samples), sampleRate, config)
+	if err != nil {
+		return err
+	}
+	power := 0.0
+	for _, v := range noise {
+		power += v * v
+	}
+	power /= float64(len(noise))
+	if power == 0 {
+		return nil
+	}
+
+	gain := math.Sqrt(speech / (power * math.Pow(10, overlay.SNR/10)))
+	for i, v := range noise {
+		mixed := float64(samples[i]) + v*gain
+		samples[i] = int16(math.Max(-32768, math.Min(32767, math.Round(mixed))))
+	}
+	logDebug(config, "added noise", "snr_db", overlay.SNR, "generated", overlay.Data == nil)
+	return nil
+}
+
+// speechPower returns the mean power of the frames of samples within
+// speechGateDb of the loudest frame, or of all samples when they are
+// shorter than a frame
+func speechPower(samples []int16, sampleRate int) float64 {
+	frameLen := max(1, sampleRate*analysisFrameMs/1000)
+	var powers []float64
+	loudest := 0.0
+	for start := 0; start < len(samples); start += frameLen {
+		sum := 0.0
+		frame := samples[start:min(start+frameLen, len(samples))]
+		for _, s := range frame {
+			sum += float64(s) * float64(s)
+		}
+		p := sum / float64(len(frame))
+		powers = append(powers, p)
+		loudest = math.Max(loudest, p)
+	}
+
+	gate := loudest * math.Pow(10, -speechGateDb/10)
+	sum, n := 0.0, 0
+	for _, p := range powers {
+		if p > 0 && p >= gate {
+			sum += p
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / float64(n)
+}
+
+// noiseSamples returns n samples of the overlay's noise at sampleRate
+func noiseSamples(overlay *NoiseOverlay, n, sampleRate int, config *AudioConfig) ([]float64, error) {
+	noise := make([]float64, n)
+	if overlay.Data == nil {
+		rng := rand.New(rand.NewSource(overlay.Seed))
+		// Paul Kellet's economy filter turns white noise pink
+		var b0, b1, b2 float64
+		for i := range noise {
+			white := rng.NormFloat64()
+			if overlay.Color == NoisePink {
+				b0 = 0.99765*b0 + white*0.0990460
+				b1 = 0.96300*b1 + white*0.2965164
+				b2 = 0.57000*b2 + white*1.0526913
+				white = b0 + b1 + b2 + white*0.1848
+			}
+			noise[i] = white
+		}
+		return noise, nil
+	}
+
+	// The noise file has its own rate and its clipping is not the input's
+	plain := *config
+	plain.InputSampleRate, plain.OnClipping = 0, nil
+	source, rate, err := decodeMixData(overlay.Data, &plain)
+	if err != nil {
+		return nil, fmt.Errorf("noise: %w", err)
+	}
+	if rate != sampleRate {
+		source = release(source, resample(source, rate, sampleRate, config))
+	}
+	defer putInt16s(source)
+	if len(source) == 0 {
+		return nil, fmt.Errorf("noise: %w", ErrEmptyInput)
+	}
+	for i := range noise {
+		noise[i] = float64(source[i%len(source)])
+	}
+	return noise, nil
+}
@@ -0,0 +1,67 @@
+package wav2ulaw
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestNoiseOverlaySNR(t *testing.T) {
+	// Half a second of tone between pauses, which must not lower the speech level
+	speech := make([]int16, 16000)
+	copy(speech[4000:], sineWave(8000, 440, 16000, 0.3))
+
+	for _, color := range []NoiseColor{NoiseWhite, NoisePink} {
+		for _, snr := range []float64{0, 10, 20} {
+			config := DefaultAudioConfig()
+			config.Noise = &NoiseOverlay{SNR: snr, Color: color, Seed: 7}
+			noisy := append([]int16(nil), speech...)
+			if err := addNoise(noisy, 16000, config); err != nil {
+				t.Fatal(err)
+			}
+			noisePower := 0.0
+			for i := range noisy {
+				d := float64(noisy[i]) - float64(speech[i])
+				noisePower += d * d
+			}
+			noisePower /= float64(len(noisy))
+			got := 10 * math.Log10(speechPower(speech, 16000)/noisePower)
+			if math.Abs(got-snr) > 0.5 {
+				t.Errorf("color %d: SNR %.2f dB, want %.0f", color, got, snr)
+			}
+		}
+	}
+}
+
+func TestNoiseOverlayFromFile(t *testing.T) {
+	speech := sineWave(16000, 440, 16000, 0.3)
+	// 100 ms of a 2 kHz interferer at 8 kHz, resampled and looped over the input
+	noise, err := encodeWavPCM16(sineWave(800, 2000, 8000, 0.5), 8000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := DefaultAudioConfig()
+	config.NormalizePeak = 0
+	config.CompressionRatio = 1
+	config.Noise = &NoiseOverlay{SNR: 0, Data: noise}
+	ulaw, err := ConvertPCM16ToUlaw(speech, 16000, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := decodeUlawSamples(ulaw)
+	tone, interference := toneLevel(decoded[800:7200], 440, 8000), toneLevel(decoded[800:7200], 2000, 8000)
+	if interference < 0.5*tone || interference > 2*tone {
+		t.Errorf("440 Hz at %.3f and noise at %.3f, want equal levels", tone, interference)
+	}
+
+	config.Noise = &NoiseOverlay{SNR: 0, Data: []byte("not audio")}
+	if _, err := ConvertPCM16ToUlaw(speech, 16000, config); err == nil {
+		t.Error("invalid noise file accepted")
+	}
+	config.Noise = &NoiseOverlay{SNR: math.NaN()}
+	var configErr *ConfigError
+	if _, err := ConvertPCM16ToUlaw(speech, 16000, config); !errors.As(err, &configErr) {
+		t.Errorf("NaN SNR: got %v, want a ConfigError", err)
+	}
+}
@@ -57,6 +57,7 @@ func getSincTable(windowSize int) *SincTable {
 	}
 
 	sincTableCache[windowSize] = table
+	sincTableOrder = evictOldest(sincTableCache, append(sincTableOrder, windowSize))
 	return table
 }
 
@@ -82,50 +83,62 @@ func (t *SincTable) getSincValue(x float64) float64 {
 }
 
 // Оновлена версія resamplePCM16 з використанням попередньо обчисленої таблиці
-func resamplePCM16WithTable(input []int16, inputRate, outputRate float64, windowSize int) []int16 {
+func resamplePCM16WithTable(input []int16, inputRate, outputRate float64, windowSize int, window []float64) []int16 {
 	ratio := outputRate / inputRate
-	outputLen := int(float64(len(input)) * ratio)
-	output := make([]int16, outputLen)
+	output := getInt16s(int(math.Ceil(float64(len(input)) * outputRate / inputRate)))
 
 	// Отримуємо таблицю sinc значень
 	sincTable := getSincTable(windowSize)
 
-	// Pre-calculate window coefficients
-	window := make([]float64, windowSize*2+1)
-	for i := range window {
-		// Blackman window
-		x := float64(i) / float64(len(window)-1)
-		window[i] = 0.42 - 0.5*math.Cos(2*math.Pi*x) + 0.08*math.Cos(4*math.Pi*x)
-	}
+	// Weights for one output sample are gathered into a contiguous buffer
+	// first, so the accumulation is a plain dot product over float32 input
+	taps := windowSize*2 + 1
+	weights := make([]float32, taps)
+	edge := make([]float32, taps)
+	signal := int16sToFloat32(input)
+	defer putFloat32s(signal)
+	values := sincTable.values
+	limit := float64(windowSize)
+	scale := float64(tableSize-1) / limit
 
 	for i := range output {
 		pos := float64(i) / ratio
 		idx := int(pos)
-		
-		sum := 0.0
-		weightSum := 0.0
 
-		for j := -windowSize; j <= windowSize; j++ {
-			inputIdx := idx + j
-			if inputIdx < 0 || inputIdx >= len(input) {
+		// Same lookup as getSincValue, inlined with the table scale hoisted
+		start := idx - windowSize
+		offset := pos - float64(start)
+		weightSum := 0.0
+		for k := range weights {
+			d := math.Abs(offset - float64(k))
+			if d >= limit {
+				weights[k] = 0
 				continue
 			}
-
-			// Використовуємо попередньо обчислене значення sinc
-			x := math.Pi * (pos - float64(inputIdx))
-			sinc := sincTable.getSincValue(x)
-
-			// Apply window function
-			weight := window[j+windowSize] * sinc
-			sum += float64(input[inputIdx]) * weight
+			u := d * scale
+			n := int(u)
+			frac := u - float64(n)
+			weight := window[k] * (values[n]*(1-frac) + values[n+1]*frac)
+			weights[k] = float32(weight)
 			weightSum += weight
 		}
 
+		var segment []float32
+		if start >= 0 && start+taps <= len(signal) {
+			segment = signal[start : start+taps]
+		} else {
+			// Edges: the kernel reaches past the input, which is mirrored
+			for k := range edge {
+				edge[k] = signal[reflectIndex(start+k, len(signal))]
+			}
+			segment = edge
+		}
+		sum := float64(dotFloat32(segment, weights))
 		if weightSum > 0 {
 			sum /= weightSum
 		}
-		output[i] = int16(math.Round(sum))
+		output[i] = int16(math.Max(-32768, math.Min(32767, math.Round(sum))))
 	}
 
 	return output
-} 
\ No newline at end of file
+}
@@ -0,0 +1,107 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"time"
+)
+
+// PacingOptions control a PacedWriter
+type PacingOptions struct {
+	// Audio per frame in milliseconds (0 = 20)
+	PtimeMs int
+	// Largest deviation of each frame's send time from its slot, drawn
+	// uniformly, to mimic the arrival of packets over a real network
+	// (0 = none)
+	Jitter time.Duration
+	// Seed of the jitter; the same seed gives the same send times
+	Seed int64
+}
+
+// PacedWriter writes 8 kHz u-law to an underlying writer at wall-clock
+// rate, one frame of PtimeMs per write, so a converted file can be played
+// into a live system as if it were a call. Send times are scheduled from
+// the first frame so timer jitter does not accumulate into drift, and
+// jitter moves a frame within its slot without shifting the ones after it.
+// Audio that does not fill a frame is kept for the next Write or Flush.
+type PacedWriter struct {
+	w         io.Writer
+	frameSize int
+	interval  time.Duration
+	jitter    time.Duration
+	rng       *rand.Rand
+	pending   []byte
+	start     time.Time
+	frames    int
+	// Clock, replaced by tests
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+// NewPacedWriter creates a writer pacing u-law into w with opts
+func NewPacedWriter(w io.Writer, opts PacingOptions) (*PacedWriter, error) {
+	if opts.PtimeMs == 0 {
+		opts.PtimeMs = 20
+	}
+	if opts.PtimeMs < 0 {
+		return nil, fmt.Errorf("invalid ptime %d ms, must be positive", opts.PtimeMs)
+	}
+	if opts.Jitter < 0 {
+		return nil, fmt.Errorf("invalid jitter %v, must not be negative", opts.Jitter)
+	}
+	return &PacedWriter{
+		w:         w,
+		frameSize: opts.PtimeMs * 8,
+		interval:  time.Duration(opts.PtimeMs) * time.Millisecond,
+		jitter:    opts.Jitter,
+		rng:       rand.New(rand.NewSource(opts.Seed)),
+		now:       time.Now,
+		sleep:     time.Sleep,
+	}, nil
+}
+
+// Write sends the complete frames of ulaw, each at its time, and returns
+// once the last of them has been written
+func (p *PacedWriter) Write(ulaw []byte) (int, error) {
+	p.pending = append(p.pending, ulaw...)
+	sent := 0
+	for len(p.pending)-sent >= p.frameSize {
+		if err := p.send(p.pending[sent : sent+p.frameSize]); err != nil {
+			return 0, err
+		}
+		sent += p.frameSize
+	}
+	// Keep the remainder from pinning a large input
+	p.pending = append([]byte(nil), p.pending[sent:]...)
+	return len(ulaw), nil
+}
+
+// Flush sends the buffered audio padded to a full frame with silence, so a
+// stream ends on a whole frame. It does nothing when nothing is buffered.
+func (p *PacedWriter) Flush() error {
+	if len(p.pending) == 0 {
+		return nil
+	}
+	frame := append(p.pending, bytes.Repeat([]byte{ulawSilence}, p.frameSize-len(p.pending))...)
+	p.pending = nil
+	return p.send(frame)
+}
+
+// send waits for the slot of the next frame and writes frame
+func (p *PacedWriter) send(frame []byte) error {
+	if p.frames == 0 {
+		p.start = p.now()
+	}
+	at := p.start.Add(time.Duration(p.frames) * p.interval)
+	if p.jitter > 0 {
+		at = at.Add(time.Duration((2*p.rng.Float64() - 1) * float64(p.jitter)))
+	}
+	p.frames++
+	if wait := at.Sub(p.now()); wait > 0 {
+		p.sleep(wait)
+	}
+	_, err := p.w.Write(frame)
+	return err
+}
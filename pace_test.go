@@ -0,0 +1,109 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// fakeClock advances only when slept on
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Sleep(d time.Duration) { c.now = c.now.Add(d) }
+
+// recordingWriter records the time of every write
+type recordingWriter struct {
+	clock  *fakeClock
+	frames [][]byte
+	times  []time.Duration
+}
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	w.frames = append(w.frames, append([]byte(nil), p...))
+	w.times = append(w.times, w.clock.now.Sub(time.Time{}))
+	return len(p), nil
+}
+
+func newTestPacedWriter(t *testing.T, opts PacingOptions) (*PacedWriter, *recordingWriter) {
+	t.Helper()
+	clock := &fakeClock{}
+	out := &recordingWriter{clock: clock}
+	p, err := NewPacedWriter(out, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.now, p.sleep = clock.Now, clock.Sleep
+	return p, out
+}
+
+func TestPacedWriter(t *testing.T) {
+	p, out := newTestPacedWriter(t, PacingOptions{})
+	ulaw := encodeUlawSamples(sineWave(1000, 440, 8000, 0.5))
+	// Chunks that do not line up with the frames
+	for _, chunk := range [][]byte{ulaw[:100], ulaw[100:700], ulaw[700:]} {
+		if n, err := p.Write(chunk); err != nil || n != len(chunk) {
+			t.Fatalf("wrote %d of %d bytes: %v", n, len(chunk), err)
+		}
+	}
+	if len(out.frames) != 6 {
+		t.Fatalf("%d frames before Flush, want the 6 complete ones", len(out.frames))
+	}
+	if err := p.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.frames) != 7 {
+		t.Fatalf("%d frames after Flush, want 7", len(out.frames))
+	}
+	for i, at := range out.times {
+		if want := time.Duration(i) * 20 * time.Millisecond; at != want {
+			t.Errorf("frame %d sent at %v, want %v", i, at, want)
+		}
+		if len(out.frames[i]) != 160 {
+			t.Errorf("frame %d has %d bytes, want 160", i, len(out.frames[i]))
+		}
+	}
+	padded := append(append([]byte(nil), ulaw...), bytes.Repeat([]byte{ulawSilence}, 120)...)
+	if !bytes.Equal(bytes.Join(out.frames, nil), padded) {
+		t.Error("frames differ from the input padded with silence")
+	}
+}
+
+func TestPacedWriterJitter(t *testing.T) {
+	opts := PacingOptions{PtimeMs: 10, Jitter: 3 * time.Millisecond, Seed: 7}
+	p, out := newTestPacedWriter(t, opts)
+	if _, err := p.Write(make([]byte, 80*200)); err != nil {
+		t.Fatal(err)
+	}
+	moved := 0
+	for i, at := range out.times {
+		slot := time.Duration(i) * 10 * time.Millisecond
+		if at < slot-opts.Jitter || at > slot+opts.Jitter {
+			t.Fatalf("frame %d sent at %v, more than %v from its slot %v", i, at, opts.Jitter, slot)
+		}
+		if at != slot {
+			moved++
+		}
+	}
+	if moved < 100 {
+		t.Errorf("only %d of 200 frames jittered", moved)
+	}
+
+	// The same seed gives the same send times
+	again, repeated := newTestPacedWriter(t, opts)
+	if _, err := again.Write(make([]byte, 80*200)); err != nil {
+		t.Fatal(err)
+	}
+	for i := range out.times {
+		if out.times[i] != repeated.times[i] {
+			t.Fatalf("frame %d sent at %v, then at %v with the same seed", i, out.times[i], repeated.times[i])
+		}
+	}
+
+	if _, err := NewPacedWriter(&bytes.Buffer{}, PacingOptions{Jitter: -time.Millisecond}); err == nil {
+		t.Error("negative jitter accepted")
+	}
+}
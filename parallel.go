@@ -0,0 +1,108 @@
+package wav2ulaw
+
+import (
+	"runtime"
+	"sync"
+)
+
+const (
+	// Shortest block worth handing to a separate worker (seconds of input)
+	minParallelBlockSeconds = 2
+	// Extra input processed on each side of a block so IIR filters settle
+	// and resampling windows are complete before the kept region (seconds)
+	parallelWarmupSeconds = 0.05
+)
+
+// processBlocksParallel runs the filter chain and resampler on overlapping
+// blocks of the input using up to concurrency workers, then stitches the
+// blocks together. Block boundaries are placed on multiples of the reduced
+// rate ratio so every block's output lines up exactly with the sequential
+// result. It reports false when the input is too short or the ratio too
+// awkward to split, in which case the caller should process sequentially.
+// With config.Deterministic the blocks have the fixed size of the streaming
+// path and are used even for one worker, so neither the worker count nor the
+// input length changes how the signal is cut. Completed blocks advance
+// progress up to filterProgressShare.
+func processBlocksParallel(samples []int16, inputRate, outputRate int, config *AudioConfig, progress *progressReporter) ([]int16, bool) {
+	concurrency := config.Concurrency
+	if concurrency < 0 {
+		concurrency = runtime.NumCPU()
+	}
+	if config.Deterministic {
+		concurrency = max(concurrency, 1)
+	} else if concurrency < 2 {
+		return nil, false
+	}
+	if inputRate <= 0 {
+		return nil, false
+	}
+
+	up, down, ok := rationalRatio(inputRate, outputRate)
+	if !ok {
+		return nil, false
+	}
+
+	roundUp := func(n int) int {
+		return (n + down - 1) / down * down
+	}
+
+	var blockLen int
+	if config.Deterministic {
+		blockLen = roundUp(inputRate * streamBlockSeconds)
+	} else {
+		minBlock := inputRate * minParallelBlockSeconds
+		if len(samples) < 2*minBlock {
+			return nil, false
+		}
+		blockLen = roundUp(max(minBlock, (len(samples)+concurrency-1)/concurrency))
+	}
+	warmup := roundUp(int(float64(inputRate)*parallelWarmupSeconds) + 2*config.ResamplingWindowSize)
+
+	outputLen := resampledLength(len(samples), up, down)
+	output := make([]int16, outputLen)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	blocks, finished := (len(samples)+blockLen-1)/blockLen, 0
+	jobs := make(chan int)
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for start := range jobs {
+				end := min(start+blockLen, len(samples))
+				from := max(0, start-warmup)
+				to := min(len(samples), end+warmup)
+
+				block := getInt16s(to - from)
+				copy(block, samples[from:to])
+				block = filterAndResample(block, inputRate, outputRate, config)
+
+				// Copy only the region this block is responsible for
+				outStart := start * up / down
+				outEnd := min(resampledLength(end, up, down), outputLen)
+				offset := from * up / down
+				for i := outStart; i < outEnd; i++ {
+					if j := i - offset; j < len(block) {
+						output[i] = block[j]
+					}
+				}
+				putInt16s(block)
+
+				mu.Lock()
+				finished++
+				fraction := filterProgressShare * float64(finished) / float64(blocks)
+				mu.Unlock()
+				progress.report(fraction)
+			}
+		}()
+	}
+
+	for start := 0; start < len(samples); start += blockLen {
+		jobs <- start
+	}
+	close(jobs)
+	wg.Wait()
+
+	return output, true
+}
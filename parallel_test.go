@@ -0,0 +1,29 @@
+package wav2ulaw
+
+import "testing"
+
+func TestParallelMatchesSequential(t *testing.T) {
+	const rate = 44100
+	input := sineWave(rate*10, 440, rate, 0.4)
+	for i, v := range sineWave(rate*10, 2500, rate, 0.3) {
+		input[i] += v
+	}
+
+	for _, aa := range []AntiAliasingType{AASimple, AAButterworth, AAChebyshev, AAWindowedSinc} {
+		config := DefaultAudioConfig()
+		config.AntiAliasingType = aa
+		sequential := processSamples(append([]int16(nil), input...), rate, config, nil, nil)
+
+		config.Concurrency = 4
+		parallel := processSamples(append([]int16(nil), input...), rate, config, nil, nil)
+
+		if len(parallel) != len(sequential) {
+			t.Fatalf("filter %d: length mismatch %d vs %d", aa, len(parallel), len(sequential))
+		}
+		for i := range parallel {
+			if d := int(parallel[i]) - int(sequential[i]); d < -2 || d > 2 {
+				t.Fatalf("filter %d: sample %d differs: %d vs %d", aa, i, parallel[i], sequential[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package wav2ulaw
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/go-audio/audio"
+	"github.com/go-audio/wav"
+)
+
+// Seed of the dither noise, fixed so the same input gives the same output
+const pcm8DitherSeed = 1
+
+// ConvertUlawBytesToWavPCM8 converts u-law encoded bytes to an 8-bit
+// unsigned PCM WAV file, the only format some paging and announcement
+// hardware accepts, otherwise like ConvertUlawBytesToWavLength. Samples are
+// rounded to the nearest 8-bit level. With dither, triangular noise of one
+// 8-bit step is added first, so quiet passages turn into low hiss rather
+// than distorted or vanishing; the noise is the same on every run.
+func ConvertUlawBytesToWavPCM8(ulawBytes []byte, sampleRate uint32, windowSize, samples int, dither bool) ([]byte, error) {
+	pcm, err := decodeUlawAtRate(ulawBytes, sampleRate, windowSize, samples)
+	if err != nil {
+		return nil, err
+	}
+	return encodeWavPCM8(requantizePCM8(pcm, dither), int(sampleRate))
+}
+
+// requantizePCM8 rounds 16-bit samples to 8-bit unsigned ones, centered on
+// 128, adding TPDF dither of one 8-bit step if dither is set
+func requantizePCM8(samples []int16, dither bool) []uint8 {
+	var rng *rand.Rand
+	if dither {
+		rng = rand.New(rand.NewSource(pcm8DitherSeed))
+	}
+	out := make([]uint8, len(samples))
+	for i, s := range samples {
+		v := float64(s) / 256
+		if rng != nil {
+			v += rng.Float64() - rng.Float64()
+		}
+		out[i] = uint8(int(max(-128, min(127, math.Round(v)))) + 128)
+	}
+	return out
+}
+
+// encodeWavPCM8 builds a mono 8-bit unsigned PCM WAV file from samples
+func encodeWavPCM8(samples []uint8, sampleRate int) ([]byte, error) {
+	out := &writeSeeker{}
+	enc := wav.NewEncoder(out, sampleRate, 8, 1, 1)
+	audioBuf := &audio.IntBuffer{
+		Format: &audio.Format{
+			NumChannels: 1,
+			SampleRate:  sampleRate,
+		},
+		Data:           make([]int, len(samples)),
+		SourceBitDepth: 8,
+	}
+	for i, sample := range samples {
+		audioBuf.Data[i] = int(sample)
+	}
+	if err := enc.Write(audioBuf); err != nil {
+		return nil, fmt.Errorf("error writing WAV data: %v", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("error closing WAV encoder: %v", err)
+	}
+	return out.buf, nil
+}
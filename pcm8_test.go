@@ -0,0 +1,74 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// pcm8Samples returns the 8-bit samples of a WAV file from
+// ConvertUlawBytesToWavPCM8 as 16-bit ones
+func pcm8Samples(t *testing.T, wavBytes []byte) []int16 {
+	t.Helper()
+	if format, bits := binary.LittleEndian.Uint16(wavBytes[20:]), binary.LittleEndian.Uint16(wavBytes[34:]); format != 1 || bits != 8 {
+		t.Fatalf("format %d with %d bits, want PCM with 8", format, bits)
+	}
+	samples := make([]int16, len(wavBytes)-44)
+	for i, b := range wavBytes[44:] {
+		samples[i] = int16(int(b)-128) * 256
+	}
+	return samples
+}
+
+func TestConvertUlawBytesToWavPCM8(t *testing.T) {
+	ulaw := encodeUlawSamples(sineWave(8000, 440, 8000, 0.5))
+	wavBytes, err := ConvertUlawBytesToWavPCM8(ulaw, 8000, 16, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rate := binary.LittleEndian.Uint32(wavBytes[24:]); rate != 8000 {
+		t.Errorf("sample rate %d, want 8000", rate)
+	}
+	got := pcm8Samples(t, wavBytes)
+	want := decodeUlawSamples(ulaw)
+	if len(got) != len(want) {
+		t.Fatalf("%d samples, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if d := int(got[i]) - int(want[i]); d < -128 || d > 128 {
+			t.Fatalf("sample %d is %d, want %d rounded to 8 bits", i, got[i], want[i])
+		}
+	}
+
+	// Resampling and the exact length carry over from the 16-bit conversion
+	wavBytes, err = ConvertUlawBytesToWavPCM8(ulaw, 16000, 16, 15999, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(pcm8Samples(t, wavBytes)); n != 15999 {
+		t.Errorf("%d samples at 16 kHz, want 15999", n)
+	}
+}
+
+func TestPCM8Dither(t *testing.T) {
+	// A tone below half an 8-bit step rounds away without dither
+	quiet := sineWave(8000, 500, 8000, 100.0/32768)
+	plain := requantizePCM8(quiet, false)
+	for i, v := range plain {
+		if v != 128 {
+			t.Fatalf("undithered sample %d is %d, want 128", i, v)
+		}
+	}
+
+	dithered := requantizePCM8(quiet, true)
+	decoded := make([]int16, len(dithered))
+	for i, v := range dithered {
+		decoded[i] = int16(int(v)-128) * 256
+	}
+	if level := toneLevel(decoded, 500, 8000) * 32767; level < 50 || level > 150 {
+		t.Errorf("dithered tone level %.0f, want about 100", level)
+	}
+	if !bytes.Equal(dithered, requantizePCM8(quiet, true)) {
+		t.Error("dither differs between runs")
+	}
+}
@@ -0,0 +1,168 @@
+// Package pion bridges the streaming encoder and decoder of wav2ulaw with
+// Pion WebRTC audio tracks, so WebRTC applications can send WAV files and
+// live PCM as PCMU and decode the PCMU they receive.
+package pion
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"wav2ulaw"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"github.com/pion/webrtc/v3/pkg/media"
+)
+
+const (
+	// FrameDuration is the audio in each sample written to a local track
+	FrameDuration = 20 * time.Millisecond
+	// Bytes of u-law per frame at 8 kHz
+	frameSize = 160
+	// u-law code of digital silence, used to pad the last frame
+	ulawSilence = 0xFF
+)
+
+// NewPCMUTrack creates a local 8 kHz mono PCMU track to add to a
+// PeerConnection
+func NewPCMUTrack(id, streamID string) (*webrtc.TrackLocalStaticSample, error) {
+	return webrtc.NewTrackLocalStaticSample(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypePCMU, ClockRate: 8000, Channels: 1}, id, streamID)
+}
+
+// SampleWriter is the part of a local track a Source writes to, implemented
+// by *webrtc.TrackLocalStaticSample
+type SampleWriter interface {
+	WriteSample(sample media.Sample) error
+}
+
+// Source encodes live 16-bit PCM with wav2ulaw.ChunkEncoder and writes it to
+// a track in 20 ms samples. Audio that does not fill a sample is kept for the
+// next call. Pion sends samples as they are written, so the caller paces the
+// audio, as a capture device does.
+type Source struct {
+	track   SampleWriter
+	encoder *wav2ulaw.ChunkEncoder
+	pending []byte
+}
+
+// NewSource creates a source for mono samples at inputRate, processed with
+// config (nil for the defaults)
+func NewSource(track SampleWriter, inputRate int, config *wav2ulaw.AudioConfig) (*Source, error) {
+	encoder, err := wav2ulaw.NewChunkEncoder(inputRate, config)
+	if err != nil {
+		return nil, err
+	}
+	return &Source{track: track, encoder: encoder}, nil
+}
+
+// Write encodes samples and writes the frames they complete
+func (s *Source) Write(samples []int16) error {
+	s.pending = append(s.pending, s.encoder.Encode(samples)...)
+	return s.writeFrames()
+}
+
+// Close writes the audio still held back by the encoder, padding the last
+// frame with silence. The source must not be used afterwards.
+func (s *Source) Close() error {
+	s.pending = append(s.pending, s.encoder.Flush()...)
+	if n := len(s.pending) % frameSize; n > 0 {
+		s.pending = append(s.pending, bytes.Repeat([]byte{ulawSilence}, frameSize-n)...)
+	}
+	return s.writeFrames()
+}
+
+// writeFrames writes the complete frames of the pending audio
+func (s *Source) writeFrames() error {
+	for len(s.pending) >= frameSize {
+		if err := s.track.WriteSample(media.Sample{Data: s.pending[:frameSize:frameSize], Duration: FrameDuration}); err != nil {
+			return err
+		}
+		s.pending = s.pending[frameSize:]
+	}
+	s.pending = append([]byte(nil), s.pending...)
+	return nil
+}
+
+// PlayWav converts a WAV file with config (nil for the defaults) and writes
+// it to track at real-time pace. It returns when the file has been sent or
+// ctx is done.
+func PlayWav(ctx context.Context, track SampleWriter, wavBytes []byte, config *wav2ulaw.AudioConfig) error {
+	ulaw, err := wav2ulaw.ConvertWavBytesToUlaw(wavBytes, config)
+	if err != nil {
+		return err
+	}
+	return PlayUlaw(ctx, track, ulaw)
+}
+
+// PlayUlaw writes 8 kHz u-law to track at real-time pace. Send times are
+// scheduled from the start so timer jitter does not accumulate into drift.
+func PlayUlaw(ctx context.Context, track SampleWriter, ulaw []byte) error {
+	start := time.Now()
+	for i := 0; i*frameSize < len(ulaw); i++ {
+		frame := ulaw[i*frameSize : min((i+1)*frameSize, len(ulaw))]
+		if len(frame) < frameSize {
+			frame = append(append([]byte(nil), frame...), bytes.Repeat([]byte{ulawSilence}, frameSize-len(frame))...)
+		}
+		timer := time.NewTimer(time.Until(start.Add(time.Duration(i) * FrameDuration)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := track.WriteSample(media.Sample{Data: frame, Duration: FrameDuration}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RTPReader is the part of a remote track a Sink reads from, implemented by
+// *webrtc.TrackRemote
+type RTPReader interface {
+	ReadRTP() (*rtp.Packet, interceptor.Attributes, error)
+}
+
+// codecTrack is a track that reports its negotiated codec
+type codecTrack interface {
+	Codec() webrtc.RTPCodecParameters
+}
+
+// Sink decodes the PCMU of a remote track to 16-bit PCM with
+// wav2ulaw.ChunkDecoder
+type Sink struct {
+	track   RTPReader
+	decoder *wav2ulaw.ChunkDecoder
+}
+
+// NewSink creates a sink producing mono samples at outputRate. A
+// *webrtc.TrackRemote must have negotiated PCMU.
+func NewSink(track RTPReader, outputRate int) (*Sink, error) {
+	if remote, ok := track.(codecTrack); ok {
+		if mime := remote.Codec().MimeType; !strings.EqualFold(mime, webrtc.MimeTypePCMU) {
+			return nil, fmt.Errorf("unsupported codec %s, expected %s", mime, webrtc.MimeTypePCMU)
+		}
+	}
+	decoder, err := wav2ulaw.NewChunkDecoder(outputRate, 16)
+	if err != nil {
+		return nil, err
+	}
+	return &Sink{track: track, decoder: decoder}, nil
+}
+
+// Read decodes the next packet of the track. It returns the samples the
+// packet completes, possibly none while the resampler fills, and the error
+// of the track once it ends.
+func (s *Sink) Read() ([]int16, error) {
+	packet, _, err := s.track.ReadRTP()
+	if err != nil {
+		return nil, err
+	}
+	return s.decoder.Decode(packet.Payload), nil
+}
@@ -0,0 +1,100 @@
+package pion
+
+import (
+	"context"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/pkg/media"
+)
+
+// trackBuffer records the samples written to it and replays them as RTP
+type trackBuffer struct {
+	samples []media.Sample
+}
+
+func (b *trackBuffer) WriteSample(sample media.Sample) error {
+	b.samples = append(b.samples, sample)
+	return nil
+}
+
+func (b *trackBuffer) ReadRTP() (*rtp.Packet, interceptor.Attributes, error) {
+	if len(b.samples) == 0 {
+		return nil, nil, io.EOF
+	}
+	packet := &rtp.Packet{Payload: b.samples[0].Data}
+	b.samples = b.samples[1:]
+	return packet, nil, nil
+}
+
+func TestSourceAndSink(t *testing.T) {
+	track := &trackBuffer{}
+	source, err := NewSource(track, 16000, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 250 ms of a 1 kHz tone in odd-sized chunks
+	tone := make([]int16, 4000)
+	for i := range tone {
+		tone[i] = int16(8000 * math.Sin(2*math.Pi*1000*float64(i)/16000))
+	}
+	for start := 0; start < len(tone); start += 333 {
+		if err := source.Write(tone[start:min(start+333, len(tone))]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := source.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for i, sample := range track.samples {
+		if len(sample.Data) != 160 || sample.Duration != FrameDuration {
+			t.Fatalf("sample %d: %d bytes of %v", i, len(sample.Data), sample.Duration)
+		}
+	}
+	if n := len(track.samples); n < 12 || n > 14 {
+		t.Errorf("expected about 13 frames, got %d", n)
+	}
+
+	sink, err := NewSink(track, 8000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded []int16
+	for {
+		samples, err := sink.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded = append(decoded, samples...)
+	}
+	peak := 0
+	for _, s := range decoded[800:1600] {
+		peak = max(peak, int(s), -int(s))
+	}
+	if peak < 1000 {
+		t.Errorf("expected the tone back, peak %d", peak)
+	}
+}
+
+func TestPlayUlawStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	track := &trackBuffer{}
+	if err := PlayUlaw(ctx, track, make([]byte, 8000)); err != context.Canceled {
+		t.Fatalf("expected cancellation, got %v", err)
+	}
+
+	// A partial frame is padded to a full one
+	if err := PlayUlaw(context.Background(), track, make([]byte, 200)); err != nil {
+		t.Fatal(err)
+	}
+	if len(track.samples) != 2 || track.samples[1].Data[40] != ulawSilence {
+		t.Errorf("expected two frames, the second padded, got %d", len(track.samples))
+	}
+}
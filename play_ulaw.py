@@ -20,6 +20,7 @@ def play_ulaw(ulaw_bytes, sample_rate=8000, window_size=32):
             './wav2ulaw',
             '--input', temp_ulaw_path,
             '--output', temp_wav_path,
+            '--force',  # the temporary output file already exists
             '--mode', 'ulaw2wav',
             '--sample-rate', str(sample_rate),
             '--window-size', str(window_size)
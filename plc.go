@@ -0,0 +1,146 @@
+package wav2ulaw
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	// Pitch search range for concealment, in samples at 8 kHz (66-400 Hz)
+	plcMinPitch = 20
+	plcMaxPitch = 120
+	// History kept for pitch detection and repetition
+	plcHistoryLen = 3 * plcMaxPitch
+	// Samples of full-level repetition before attenuation starts (10 ms)
+	plcHoldSamples = 80
+	// Gain lost per 10 ms of concealment after the hold period
+	plcAttenuationPer10ms = 0.2
+	// Crossfade length when real audio resumes after a gap (4 ms)
+	plcCrossfade = 32
+)
+
+// UlawPacket is a received u-law frame tagged with its RTP sequence number
+type UlawPacket struct {
+	Sequence uint16
+	Payload  []byte
+}
+
+// FramesFromPackets orders packets by sequence number, accounting for 16-bit
+// wraparound, drops duplicates and inserts nil entries for missing sequence
+// numbers so the result can be passed to ConvertUlawFramesToWav
+func FramesFromPackets(packets []UlawPacket) [][]byte {
+	if len(packets) == 0 {
+		return nil
+	}
+
+	// Unwrap sequence numbers relative to the first packet
+	type unwrapped struct {
+		index   int64
+		payload []byte
+	}
+	base := packets[0].Sequence
+	ordered := make([]unwrapped, 0, len(packets))
+	for _, p := range packets {
+		delta := int64(int16(p.Sequence - base))
+		ordered = append(ordered, unwrapped{index: delta, payload: p.Payload})
+	}
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].index < ordered[j].index })
+
+	var frames [][]byte
+	next := ordered[0].index
+	for _, p := range ordered {
+		if p.index < next {
+			continue // duplicate
+		}
+		for ; next < p.index; next++ {
+			frames = append(frames, nil)
+		}
+		frames = append(frames, p.payload)
+		next++
+	}
+	return frames
+}
+
+// concealer synthesizes replacement audio for lost frames by repeating the
+// last pitch period with gradual attenuation, similar to G.711 Appendix I
+type concealer struct {
+	history []int16
+	pitch   int
+	phase   int
+	lost    int
+}
+
+// received records decoded audio, crossfading from synthetic audio if a gap just ended
+func (c *concealer) received(samples []int16) []int16 {
+	if c.lost > 0 && len(c.history) >= plcMinPitch*2 {
+		// Continue the synthetic waveform briefly and fade it into the real signal
+		tail := c.synthesize(min(plcCrossfade, len(samples)))
+		for i := range tail {
+			t := float64(i+1) / float64(len(tail)+1)
+			samples[i] = int16(math.Round(float64(tail[i])*(1-t) + float64(samples[i])*t))
+		}
+	}
+	c.lost = 0
+	c.pitch = 0
+
+	c.history = append(c.history, samples...)
+	if len(c.history) > plcHistoryLen {
+		c.history = append(c.history[:0], c.history[len(c.history)-plcHistoryLen:]...)
+	}
+	return samples
+}
+
+// conceal produces n samples replacing a lost frame
+func (c *concealer) conceal(n int) []int16 {
+	if len(c.history) < plcMinPitch*2 {
+		c.lost += n
+		return make([]int16, n)
+	}
+	if c.pitch == 0 {
+		c.pitch = c.detectPitch()
+		c.phase = 0
+	}
+	return c.synthesize(n)
+}
+
+// synthesize repeats the last pitch period, attenuating after the hold period
+func (c *concealer) synthesize(n int) []int16 {
+	if c.pitch == 0 {
+		c.pitch = c.detectPitch()
+	}
+	out := make([]int16, n)
+	period := c.history[len(c.history)-c.pitch:]
+	for i := range out {
+		gain := 1.0
+		if c.lost > plcHoldSamples {
+			gain = math.Max(0, 1-plcAttenuationPer10ms*float64(c.lost-plcHoldSamples)/80.0)
+		}
+		out[i] = int16(math.Round(float64(period[c.phase]) * gain))
+		c.phase = (c.phase + 1) % c.pitch
+		c.lost++
+	}
+	return out
+}
+
+// detectPitch finds the period maximizing normalized autocorrelation of the history
+func (c *concealer) detectPitch() int {
+	h := c.history
+	best := plcMinPitch
+	bestScore := math.Inf(-1)
+	maxPitch := min(plcMaxPitch, len(h)/2)
+	for lag := plcMinPitch; lag <= maxPitch; lag++ {
+		dot, energy := 0.0, 0.0
+		for i := len(h) - lag; i < len(h); i++ {
+			a := float64(h[i])
+			b := float64(h[i-lag])
+			dot += a * b
+			energy += b * b
+		}
+		score := dot / math.Sqrt(energy+1)
+		if score > bestScore {
+			bestScore = score
+			best = lag
+		}
+	}
+	return best
+}
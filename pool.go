@@ -0,0 +1,66 @@
+package wav2ulaw
+
+import "sync"
+
+// Scratch buffers shared by the processing stages. Converting many files
+// with similar lengths then reuses the same few buffers instead of
+// allocating a fresh slice per stage per file.
+var (
+	int16Pool   sync.Pool
+	float32Pool sync.Pool
+	float64Pool sync.Pool
+)
+
+// getInt16s returns a slice of length n from the pool. The contents are
+// not cleared; callers must overwrite every element.
+func getInt16s(n int) []int16 {
+	if p, ok := int16Pool.Get().(*[]int16); ok && cap(*p) >= n {
+		return (*p)[:n]
+	}
+	return make([]int16, n)
+}
+
+// putInt16s returns a slice to the pool. The caller must not use it afterwards.
+func putInt16s(b []int16) {
+	if cap(b) == 0 {
+		return
+	}
+	int16Pool.Put(&b)
+}
+
+// getFloat32s returns a slice of length n from the pool without clearing it
+func getFloat32s(n int) []float32 {
+	if p, ok := float32Pool.Get().(*[]float32); ok && cap(*p) >= n {
+		return (*p)[:n]
+	}
+	return make([]float32, n)
+}
+
+// putFloat32s returns a slice to the pool
+func putFloat32s(b []float32) {
+	if cap(b) == 0 {
+		return
+	}
+	float32Pool.Put(&b)
+}
+
+// getFloat64s returns a slice of length n from the pool without clearing it
+func getFloat64s(n int) []float64 {
+	if p, ok := float64Pool.Get().(*[]float64); ok && cap(*p) >= n {
+		return (*p)[:n]
+	}
+	return make([]float64, n)
+}
+
+// putFloat64s returns a slice to the pool
+func putFloat64s(b []float64) {
+	if cap(b) == 0 {
+		return
+	}
+	float64Pool.Put(&b)
+}
+
+// sameBuffer reports whether a and b share their first element
+func sameBuffer(a, b []int16) bool {
+	return cap(a) > 0 && cap(b) > 0 && &a[:1][0] == &b[:1][0]
+}
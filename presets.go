@@ -0,0 +1,171 @@
+package wav2ulaw
+
+// TelephonyConfig returns settings for general PSTN and VoIP playback: the
+// classic 300-3400 Hz telephone band, a steep anti-aliasing filter and
+// moderate compression so quiet passages survive a noisy line.
+func TelephonyConfig() *AudioConfig {
+	config := DefaultAudioConfig()
+	config.HighPassCutoff = 300
+	config.LowPassCutoff = 3400
+	config.NormalizePeak = 0.9
+	config.CompressionRatio = 2.0
+	config.CompressionThreshold = 0.5
+	config.ResamplingWindowSize = 32
+	config.AntiAliasingType = AAButterworth
+	config.AntiAliasingCutoffRatio = 0.9
+	config.FilterOrder = 4
+	return config
+}
+
+// VoicemailConfig returns settings for recorded speech such as voicemail
+// greetings and IVR prompts recorded on handsets or desk microphones. Rumble
+// is cut, levels are evened out with heavier compression, and short fades
+// remove the clicks often left at the edges of a recording.
+func VoicemailConfig() *AudioConfig {
+	config := DefaultAudioConfig()
+	config.HighPassCutoff = 200
+	config.LowPassCutoff = 3400
+	config.NormalizePeak = 0.95
+	config.CompressionRatio = 3.0
+	config.CompressionThreshold = 0.4
+	config.FadeInMs = 10
+	config.FadeOutMs = 10
+	config.ResamplingWindowSize = 32
+	config.AntiAliasingType = AAButterworth
+	config.AntiAliasingCutoffRatio = 0.9
+	config.FilterOrder = 4
+	return config
+}
+
+// TTSNarrowbandConfig returns settings for synthesized speech. TTS output is
+// already clean and level-controlled, so processing is kept light and the
+// effort goes into a sharp, ripple-free band edge that keeps sibilants crisp.
+func TTSNarrowbandConfig() *AudioConfig {
+	config := DefaultAudioConfig()
+	config.HighPassCutoff = 100
+	config.LowPassCutoff = 3600
+	config.NormalizePeak = 0.95
+	config.CompressionRatio = 1.2
+	config.CompressionThreshold = 0.6
+	config.ResamplingWindowSize = 64
+	config.WindowFunction = WindowKaiser
+	config.KaiserBeta = 9.0
+	config.AntiAliasingType = AAWindowedSinc
+	config.AntiAliasingCutoffRatio = 0.95
+	return config
+}
+
+// TTSSampleRates are the output rates of common TTS engines, which
+// TTSFastConfig is tuned for
+var TTSSampleRates = []int{22050, 24000, 44100}
+
+// TTSFastConfig returns settings for high-volume conversion of TTS output
+// at TTSSampleRates. It sounds like TTSNarrowbandConfig, but the band edge
+// is left to the anti-aliasing kernel alone, saving a filter pass at the
+// input rate, and a shorter kernel keeps aliases below the u-law noise
+// floor at these ratios at about half the cost. Call PrecomputeResampler
+// with it at startup to design the resampler banks for these rates before
+// the first request.
+func TTSFastConfig() *AudioConfig {
+	config := TTSNarrowbandConfig()
+	config.LowPassCutoff = 0
+	config.ResamplingWindowSize = 32
+	return config
+}
+
+// RawPassthroughConfig returns settings that change the audio no more than
+// the conversion requires: no band filters, compression or normalization,
+// only the anti-aliasing needed to resample to 8 kHz.
+func RawPassthroughConfig() *AudioConfig {
+	config := DefaultAudioConfig()
+	config.HighPassCutoff = 0
+	config.LowPassCutoff = 0
+	config.NormalizePeak = 0
+	config.CompressionRatio = 1.0
+	config.AntiAliasingType = AAWindowedSinc
+	config.AntiAliasingCutoffRatio = 0.95
+	return config
+}
+
+// ASRCleanupOptions returns options tuned for the word error rate of
+// recognizers on decoded u-law call audio: line hiss between words is gated
+// away, a gentle high shelf restores the consonant energy the telephone
+// band and companding lose, and AGC evens out quiet and loud callers.
+func ASRCleanupOptions() *ASROptions {
+	return &ASROptions{
+		SampleRate:      asrSampleRate,
+		HighShelfGainDb: 4,
+		NoiseGateDbov:   -55,
+		AGCTargetDbov:   -20,
+		AGCMaxGainDb:    12,
+	}
+}
+
+// ConfigForIVRPrompts returns settings for recorded IVR and auto-attendant
+// prompts, so every prompt of a menu plays at the same level whatever it was
+// recorded at. The level comes from peak normalization alone, since
+// compression would make it depend on the input level, and the band edge
+// from the anti-aliasing kernel, as in TTSNarrowbandConfig. Targets, checked
+// by the tests on a speech-band test program: -3 ±1 dB at 300 Hz, flat
+// within 1 dB from 1000 to 3600 Hz, and -14 ±1 LUFS integrated for input
+// peaking anywhere from -30 to -1 dBFS.
+func ConfigForIVRPrompts() *AudioConfig {
+	config := TTSNarrowbandConfig()
+	config.HighPassCutoff = 300
+	config.LowPassCutoff = 0
+	config.NormalizePeak = 0.9
+	config.CompressionRatio = 1.0
+	config.FadeInMs = 5
+	config.FadeOutMs = 5
+	return config
+}
+
+// ConfigForPromptLibrary returns settings for a library of IVR prompts
+// recorded, synthesized or edited at different times, which must all play
+// at the same perceived volume. ConfigForIVRPrompts matches their peaks,
+// which leaves prompts of different crest factors up to several LU apart;
+// this preset measures the integrated loudness of each prompt after the
+// telephone band filtering instead, applies the gain that brings it to
+// TargetLoudness and limits the peaks that gain pushes past -1 dBFS.
+// Targets, checked by the tests: the band response of ConfigForIVRPrompts,
+// and -14 ±0.5 LUFS integrated after u-law encoding for input peaking
+// anywhere from -30 to -1 dBFS, whatever its crest factor.
+func ConfigForPromptLibrary() *AudioConfig {
+	config := ConfigForIVRPrompts()
+	config.NormalizePeak = 0
+	config.TargetLoudness = -14
+	return config
+}
+
+// ConfigForCallRecordingArchive returns settings for archiving call
+// recordings, where the audio must stay as it was heard: no compression,
+// normalization or fades, only DC and hum removal and the anti-aliasing the
+// conversion needs, with deterministic processing so reconverting a
+// recording reproduces the archived file. Targets, checked by the tests:
+// flat within 1 dB from 100 to 3600 Hz and integrated loudness within
+// 0.5 LU of the input's.
+func ConfigForCallRecordingArchive() *AudioConfig {
+	config := RawPassthroughConfig()
+	config.HighPassCutoff = 40
+	config.ResamplingWindowSize = 32
+	config.Deterministic = true
+	return config
+}
+
+// ConfigForMusicOnHold returns settings for music on hold. Bass below the
+// telephone band is cut before it takes up the u-law range, the level sits
+// 2 LU under ConfigForIVRPrompts so announcements stand out from the music,
+// and fades make loops click-free. Targets, checked by the tests on the
+// same test program: -3 ±1 dB at 150 Hz, flat within 1.5 dB from 300 to
+// 3600 Hz, and -16 ±1 LUFS integrated for input peaking anywhere from -30
+// to -1 dBFS.
+func ConfigForMusicOnHold() *AudioConfig {
+	config := TTSNarrowbandConfig()
+	config.HighPassCutoff = 150
+	config.LowPassCutoff = 0
+	config.NormalizePeak = 0.7
+	config.CompressionRatio = 1.0
+	config.FadeInMs = 50
+	config.FadeOutMs = 50
+	return config
+}
@@ -0,0 +1,171 @@
+package wav2ulaw
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPresetsConvertSpeechBand(t *testing.T) {
+	presets := map[string]func() *AudioConfig{
+		"telephony":      TelephonyConfig,
+		"voicemail":      VoicemailConfig,
+		"tts-narrowband": TTSNarrowbandConfig,
+		"tts-fast":       TTSFastConfig,
+		"raw":            RawPassthroughConfig,
+		"ivr-prompts":    ConfigForIVRPrompts,
+		"prompt-library": ConfigForPromptLibrary,
+		"call-archive":   ConfigForCallRecordingArchive,
+		"music-on-hold":  ConfigForMusicOnHold,
+	}
+
+	wavBytes, err := encodeWavPCM16(sineWave(16000, 1000, 16000, 0.5), 16000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, preset := range presets {
+		ulaw, err := ConvertWavBytesToUlaw(wavBytes, preset())
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(ulaw) != 8000 {
+			t.Errorf("%s: got %d output samples, want 8000", name, len(ulaw))
+		}
+		if level := toneLevel(decodeUlawSamples(ulaw), 1000, 8000); level < 0.2 {
+			t.Errorf("%s: 1 kHz tone level %.3f, want it kept in the passband", name, level)
+		}
+	}
+}
+
+func TestTTSFastConfig(t *testing.T) {
+	config := TTSFastConfig()
+	config.HighPassCutoff = 0
+	config.NormalizePeak = 0
+	config.CompressionRatio = 1
+	PrecomputeResampler(config, TTSSampleRates...)
+	for _, rate := range TTSSampleRates {
+		// The top of the telephone band passes, a tone just above 4 kHz
+		// must not fold back into it
+		if gain, err := selfTestTone(rate, 3400, 3400, config); err != nil || gain < -1 || gain > 1 {
+			t.Errorf("%d Hz: 3400 Hz gain %.2f dB (%v)", rate, gain, err)
+		}
+		if gain, err := selfTestTone(rate, 4200, 3800, config); err != nil || gain > -60 {
+			t.Errorf("%d Hz: 4200 Hz alias at %.2f dB (%v)", rate, gain, err)
+		}
+	}
+}
+
+func TestASRCleanupOptions(t *testing.T) {
+	// Quiet speech-band audio with line hiss between two bursts of tone
+	input := make([]int16, 24000)
+	copy(input, sineWave(8000, 500, 8000, 0.02))
+	copy(input[16000:], sineWave(8000, 2800, 8000, 0.02))
+	for i := 8000; i < 16000; i++ {
+		input[i] = int16(i%3 - 1)
+	}
+	ulaw := encodeUlawSamples(input)
+
+	plain, err := ConvertForASR(ulaw, FormatUlaw, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleaned, err := ConvertForASR(ulaw, FormatUlaw, ASRCleanupOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cleaned) != len(plain) {
+		t.Fatalf("got %d samples, want %d", len(cleaned), len(plain))
+	}
+
+	// AGC raises the quiet speech, the shelf more so above 2 kHz, and the
+	// gate silences the hiss
+	low := toneLevel(cleaned[4000:12000], 500, 16000) / toneLevel(plain[4000:12000], 500, 16000)
+	high := toneLevel(cleaned[36000:44000], 2800, 16000) / toneLevel(plain[36000:44000], 2800, 16000)
+	if low < 2 || high < low*1.2 {
+		t.Errorf("gains %.2f at 500 Hz and %.2f at 2800 Hz", low, high)
+	}
+	for _, s := range cleaned[24000:28000] {
+		if s != 0 {
+			t.Fatalf("hiss between the bursts not gated: sample %d", s)
+		}
+	}
+
+	if _, err := ConvertForASR(ulaw, FormatUlaw, &ASROptions{HighShelfGainDb: 20}); err == nil {
+		t.Error("expected an error for a 20 dB shelf")
+	}
+}
+
+// testProgram returns 3 s of a speech-band multi-tone with a 3 Hz syllabic
+// envelope, peaking at peak of full scale
+func testProgram(rate int, peak float64) []int16 {
+	freqs := []float64{220, 450, 700, 1100, 1600, 2300, 3100}
+	samples := make([]int16, 3*rate)
+	for i := range samples {
+		t := float64(i) / float64(rate)
+		v := 0.0
+		for k, f := range freqs {
+			v += math.Sin(2*math.Pi*f*t+float64(k)) / float64(len(freqs))
+		}
+		samples[i] = int16(peak * 32767 * (0.55 + 0.45*math.Sin(2*math.Pi*3*t)) * v)
+	}
+	return samples
+}
+
+// programLoudness returns the integrated loudness of samples
+func programLoudness(t *testing.T, samples []int16, rate int) float64 {
+	wavBytes, err := encodeWavPCM16(samples, rate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loudness, err := MeasureLoudness(wavBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loudness.Integrated
+}
+
+func TestConfigPresetTargets(t *testing.T) {
+	type band struct{ freq, min, max float64 }
+	for _, tc := range []struct {
+		name   string
+		preset func() *AudioConfig
+		bands  []band
+		// Target integrated loudness, NaN to require the input's
+		lufs float64
+	}{
+		{"ivr-prompts", ConfigForIVRPrompts, []band{{300, -4, -2}, {1000, -1, 1}, {3600, -1, 1}}, -14},
+		{"call-archive", ConfigForCallRecordingArchive, []band{{100, -1, 1}, {1000, -1, 1}, {3600, -1, 1}}, math.NaN()},
+		{"music-on-hold", ConfigForMusicOnHold, []band{{150, -4, -2}, {300, -1.5, 1.5}, {3600, -1.5, 1.5}}, -16},
+	} {
+		// Band response with the level stages off
+		config := tc.preset()
+		config.NormalizePeak = 0
+		config.CompressionRatio = 1
+		config.FadeInMs, config.FadeOutMs = 0, 0
+		for _, b := range tc.bands {
+			gain, err := selfTestTone(16000, b.freq, b.freq, config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if gain < b.min || gain > b.max {
+				t.Errorf("%s: %.0f Hz gain %.2f dB, want %.1f to %.1f", tc.name, b.freq, gain, b.min, b.max)
+			}
+		}
+
+		for _, peak := range []float64{0.0316, 0.3, 0.89} { // -30, -10.5 and -1 dBFS
+			input := testProgram(16000, peak)
+			ulaw, err := ConvertPCM16ToUlaw(input, 16000, tc.preset())
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := programLoudness(t, decodeUlawSamples(ulaw), 8000)
+			want, tolerance := tc.lufs, 1.0
+			if math.IsNaN(want) {
+				want, tolerance = programLoudness(t, input, 16000), 0.5
+			}
+			if math.Abs(got-want) > tolerance {
+				t.Errorf("%s: input peaking at %.2f comes out at %.1f LUFS, want %.1f ±%.1f", tc.name, peak, got, want, tolerance)
+			}
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package wav2ulaw
+
+import "sync"
+
+// Share of the progress range taken by filtering and resampling, which
+// dominate the cost of an in-memory conversion
+const filterProgressShare = 0.9
+
+// progressReporter forwards the completed fraction of a conversion to
+// config.OnProgress. Calls from concurrent workers are serialized and the
+// reported fraction never decreases. A nil reporter ignores reports.
+type progressReporter struct {
+	mu   sync.Mutex
+	fn   func(fraction float64)
+	last float64
+}
+
+// newProgressReporter returns a reporter for config, or nil when no callback is set
+func newProgressReporter(config *AudioConfig) *progressReporter {
+	if config.OnProgress == nil {
+		return nil
+	}
+	return &progressReporter{fn: config.OnProgress}
+}
+
+// report announces that fraction (0 to 1) of the work is done
+func (p *progressReporter) report(fraction float64) {
+	if p == nil {
+		return
+	}
+	fraction = min(max(fraction, 0), 1)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if fraction <= p.last {
+		return
+	}
+	p.last = fraction
+	p.fn(fraction)
+}
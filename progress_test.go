@@ -0,0 +1,114 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgressReachesCompletion(t *testing.T) {
+	const rate = 16000
+	wavBytes, err := encodeWavPCM16(sineWave(rate*5, 440, rate, 0.5), rate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	convert := map[string]func(config *AudioConfig) error{
+		"memory": func(config *AudioConfig) error {
+			_, err := ConvertWavBytesToUlaw(wavBytes, config)
+			return err
+		},
+		"parallel": func(config *AudioConfig) error {
+			config.Concurrency = 2
+			_, err := ConvertWavBytesToUlaw(wavBytes, config)
+			return err
+		},
+		"stream": func(config *AudioConfig) error {
+			return ConvertWavStreamToUlaw(bytes.NewReader(wavBytes), io.Discard, config)
+		},
+	}
+
+	for name, run := range convert {
+		var reports []float64
+		config := DefaultAudioConfig()
+		config.OnProgress = func(fraction float64) {
+			reports = append(reports, fraction)
+		}
+		if err := run(config); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if len(reports) < 2 {
+			t.Fatalf("%s: got %d progress reports, want several", name, len(reports))
+		}
+		for i := 1; i < len(reports); i++ {
+			if reports[i] <= reports[i-1] {
+				t.Fatalf("%s: progress went from %.3f to %.3f", name, reports[i-1], reports[i])
+			}
+		}
+		if last := reports[len(reports)-1]; last != 1 {
+			t.Errorf("%s: final progress %.3f, want 1", name, last)
+		}
+	}
+}
+
+func TestOnStageReportsEachStage(t *testing.T) {
+	const rate = 16000
+	wavBytes, err := encodeWavPCM16(sineWave(rate, 440, rate, 0.5), rate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stages []string
+	config := DefaultAudioConfig()
+	config.FadeOutMs = 50
+	config.OnStage = func(stage string, duration time.Duration) {
+		if duration < 0 {
+			t.Errorf("stage %s took %v", stage, duration)
+		}
+		stages = append(stages, stage)
+	}
+	if _, err := ConvertWavBytesToUlaw(wavBytes, config); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"decode", "filter and resample", "compression", "normalize", "fades", "encode"}
+	if strings.Join(stages, ", ") != strings.Join(want, ", ") {
+		t.Errorf("got stages %v, want %v", stages, want)
+	}
+}
+
+func TestOnStageMetricsCountsSamples(t *testing.T) {
+	const rate = 16000
+	wavBytes, err := encodeWavPCM16(sineWave(rate, 440, rate, 0.5), rate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	metrics := make(map[string]StageMetrics)
+	config := DefaultAudioConfig()
+	config.Tempo = 2
+	config.OnStageMetrics = func(m StageMetrics) {
+		metrics[m.Stage] = m
+	}
+	ulaw, err := ConvertWavBytesToUlaw(wavBytes, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m := metrics["decode"]; m.OutputSamples != rate {
+		t.Errorf("decode produced %d samples, want %d", m.OutputSamples, rate)
+	}
+	if m := metrics["filter and resample"]; m.InputSamples != rate || m.OutputSamples != 8000 {
+		t.Errorf("filter and resample turned %d samples into %d, want %d into 8000", m.InputSamples, m.OutputSamples, rate)
+	}
+	if m := metrics["tempo"]; m.InputSamples != 8000 || math.Abs(float64(m.OutputSamples)-4000) > 10 {
+		t.Errorf("tempo turned %d samples into %d, want 8000 into about 4000", m.InputSamples, m.OutputSamples)
+	}
+	if m := metrics["encode"]; m.OutputSamples != len(ulaw) {
+		t.Errorf("encode reported %d samples for %d bytes", m.OutputSamples, len(ulaw))
+	}
+}
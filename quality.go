@@ -0,0 +1,176 @@
+package wav2ulaw
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	// Largest time offset searched when aligning two signals (ms)
+	alignMaxLagMs = 50
+	// Portion of the signals used for the alignment search (ms)
+	alignWindowMs = 5000
+	// Segmental SNR frames are clamped to this range as in ITU-T P.Sup23 practice (dB)
+	segSNRMin = -10.0
+	segSNRMax = 35.0
+	// FFT size for log-spectral distortion frames
+	spectralFFTSize = 256
+)
+
+// QualityMetrics contains objective measures of how much a processed signal
+// deviates from its original. Higher PSNR and segmental SNR and lower
+// spectral distortion mean closer to the original.
+type QualityMetrics struct {
+	// Samples the processed signal was shifted by to align it (positive = delayed)
+	Lag int
+	// Number of aligned samples compared
+	Samples int
+	// Peak signal-to-noise ratio relative to 16-bit full scale (dB)
+	PSNR float64
+	// Mean per-frame SNR over non-silent 20 ms frames (dB)
+	SegmentalSNR float64
+	// RMS log-spectral distance between the signals (dB)
+	SpectralDistortion float64
+}
+
+// CompareAudio time-aligns processed against original (both at sampleRate)
+// and computes PSNR, segmental SNR and log-spectral distortion
+func CompareAudio(original, processed []int16, sampleRate int) (*QualityMetrics, error) {
+	if sampleRate <= 0 {
+		return nil, fmt.Errorf("invalid sample rate %d", sampleRate)
+	}
+	if len(original) == 0 || len(processed) == 0 {
+		return nil, fmt.Errorf("cannot compare empty signals")
+	}
+
+	lag := alignmentLag(original, processed, sampleRate*alignMaxLagMs/1000, sampleRate*alignWindowMs/1000)
+
+	// Overlapping region after shifting processed by lag
+	a, b := original, processed
+	if lag > 0 {
+		b = b[lag:]
+	} else if lag < 0 {
+		a = a[-lag:]
+	}
+	n := min(len(a), len(b))
+	if n == 0 {
+		return nil, fmt.Errorf("signals do not overlap after alignment")
+	}
+	a, b = a[:n], b[:n]
+
+	metrics := &QualityMetrics{Lag: lag, Samples: n}
+
+	noise := 0.0
+	for i := range a {
+		d := float64(a[i]) - float64(b[i])
+		noise += d * d
+	}
+	mse := noise / float64(n)
+	if mse == 0 {
+		metrics.PSNR = math.Inf(1)
+	} else {
+		metrics.PSNR = 10 * math.Log10(32767.0*32767.0/mse)
+	}
+
+	metrics.SegmentalSNR = segmentalSNR(a, b, sampleRate*analysisFrameMs/1000)
+	metrics.SpectralDistortion = spectralDistortion(a, b)
+	return metrics, nil
+}
+
+// CompareWavToUlaw decodes a WAV original and an 8 kHz u-law rendition of it,
+// brings the original to 8 kHz mono and compares the two
+func CompareWavToUlaw(wavBytes, ulawBytes []byte) (*QualityMetrics, error) {
+	config := DefaultAudioConfig()
+	original, rate, err := decodeWavSamples(wavBytes, config)
+	if err != nil {
+		return nil, err
+	}
+	if rate != 8000 {
+		original = resample(original, rate, 8000, config)
+	}
+	return CompareAudio(original, decodeUlawSamples(ulawBytes), 8000)
+}
+
+// alignmentLag finds the shift of b relative to a within ±maxLag that
+// maximizes their cross-correlation over the first window samples
+func alignmentLag(a, b []int16, maxLag, window int) int {
+	best := 0
+	bestScore := math.Inf(-1)
+	for lag := -maxLag; lag <= maxLag; lag++ {
+		score := 0.0
+		for i := 0; i < window; i++ {
+			j := i + lag
+			if i >= len(a) || j < 0 || j >= len(b) {
+				continue
+			}
+			score += float64(a[i]) * float64(b[j])
+		}
+		if score > bestScore {
+			bestScore = score
+			best = lag
+		}
+	}
+	return best
+}
+
+// segmentalSNR averages clamped per-frame SNR over frames where the original is not silent
+func segmentalSNR(a, b []int16, frameLen int) float64 {
+	if frameLen < 1 {
+		return 0
+	}
+	sum, frames := 0.0, 0
+	for start := 0; start+frameLen <= len(a); start += frameLen {
+		signal, noise := 0.0, 0.0
+		for i := start; i < start+frameLen; i++ {
+			s := float64(a[i])
+			d := s - float64(b[i])
+			signal += s * s
+			noise += d * d
+		}
+		if signal < float64(frameLen) { // below 1 LSB RMS
+			continue
+		}
+		snr := segSNRMax
+		if noise > 0 {
+			snr = math.Max(segSNRMin, math.Min(segSNRMax, 10*math.Log10(signal/noise)))
+		}
+		sum += snr
+		frames++
+	}
+	if frames == 0 {
+		return 0
+	}
+	return sum / float64(frames)
+}
+
+// spectralDistortion returns the RMS difference of the log power spectra in dB,
+// averaged over Hann-windowed frames
+func spectralDistortion(a, b []int16) float64 {
+	window := makeWindow(WindowHann, spectralFFTSize, 0)
+	fa := make([]complex128, spectralFFTSize)
+	fb := make([]complex128, spectralFFTSize)
+
+	total, frames := 0.0, 0
+	for start := 0; start+spectralFFTSize <= len(a); start += spectralFFTSize / 2 {
+		for i := range fa {
+			fa[i] = complex(float64(a[start+i])*window[i], 0)
+			fb[i] = complex(float64(b[start+i])*window[i], 0)
+		}
+		fft(fa, false)
+		fft(fb, false)
+
+		sum := 0.0
+		for k := 1; k < spectralFFTSize/2; k++ {
+			pa := real(fa[k])*real(fa[k]) + imag(fa[k])*imag(fa[k]) + 1
+			pb := real(fb[k])*real(fb[k]) + imag(fb[k])*imag(fb[k]) + 1
+			d := 10 * math.Log10(pa/pb)
+			sum += d * d
+		}
+		total += math.Sqrt(sum / float64(spectralFFTSize/2-1))
+		frames++
+	}
+	if frames == 0 {
+		return 0
+	}
+	return total / float64(frames)
+}
@@ -0,0 +1,40 @@
+package wav2ulaw
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCompareAudioAlignsAndScores(t *testing.T) {
+	original := sineWave(8000, 440, 8000, 0.5)
+	for i, sample := range sineWave(8000, 1300, 8000, 0.2) {
+		original[i] += sample
+	}
+
+	// Delay by 25 samples and run through u-law
+	delayed := append(make([]int16, 25), original...)
+	processed := decodeUlawSamples(encodeUlawSamples(delayed))
+
+	metrics, err := CompareAudio(original, processed, 8000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metrics.Lag != 25 {
+		t.Errorf("expected lag 25, got %d", metrics.Lag)
+	}
+	// u-law quantization gives roughly 38 dB SNR on loud signals
+	if metrics.SegmentalSNR < 30 || metrics.SegmentalSNR > segSNRMax {
+		t.Errorf("unexpected segmental SNR %.2f", metrics.SegmentalSNR)
+	}
+	if metrics.PSNR < 40 {
+		t.Errorf("unexpected PSNR %.2f", metrics.PSNR)
+	}
+
+	identical, err := CompareAudio(original, original, 8000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !math.IsInf(identical.PSNR, 1) || identical.SpectralDistortion != 0 {
+		t.Errorf("identical signals should score perfectly: %+v", identical)
+	}
+}
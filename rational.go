@@ -0,0 +1,238 @@
+package wav2ulaw
+
+import (
+	"math"
+	"sync"
+)
+
+const (
+	// Largest number of filter phases used by the rational resampler.
+	// Ratios that reduce to more phases fall back to per-sample interpolation.
+	maxRationalPhases = 1024
+)
+
+// rationalRatio reduces outputRate/inputRate to up/down and reports
+// whether the polyphase resampler can handle it
+func rationalRatio(inputRate, outputRate int) (up, down int, ok bool) {
+	if inputRate <= 0 || outputRate <= 0 {
+		return 0, 0, false
+	}
+	g := gcd(inputRate, outputRate)
+	up = outputRate / g
+	down = inputRate / g
+	return up, down, up <= maxRationalPhases
+}
+
+// resampledLength returns the output length for n input samples resampled
+// by up/down: every output whose position i*down/up falls within the input,
+// i.e. ceil(n*up/down), so no trailing input is dropped
+func resampledLength(n, up, down int) int {
+	return (n*up + down - 1) / down
+}
+
+// reflectIndex maps an index outside [0, n) into it by mirroring the signal
+// about its first and last samples. Kernels reaching past the edges then see
+// a continuation of the signal, not silence, so the output neither fades nor
+// clicks at the start and end.
+func reflectIndex(i, n int) int {
+	if n <= 1 {
+		return 0
+	}
+	period := 2 * (n - 1)
+	if i %= period; i < 0 {
+		i += period
+	}
+	if i >= n {
+		i = period - i
+	}
+	return i
+}
+
+// polyphaseBank is the filter bank of the rational resampler: one kernel
+// per phase, each normalized to unity gain and reaching half input samples
+// to either side of its center
+type polyphaseBank struct {
+	phases [][]float32
+	half   int
+}
+
+// polyphaseKey identifies the bank resampleSinc designs for a conversion
+type polyphaseKey struct {
+	inputRate, outputRate int
+	windowSize            int
+	window                WindowFunction
+	kaiserBeta            float64
+	// Type and cutoff ratio of the anti-aliasing kernel convolved into the
+	// bank (ratio 0 = none)
+	prefilterType  AntiAliasingType
+	prefilterRatio float64
+}
+
+var (
+	// Cache of polyphase banks, so servers converting many short files at
+	// the same rate, such as TTS prompts, design each bank only once
+	polyphaseCache      = make(map[polyphaseKey]*polyphaseBank)
+	polyphaseCacheMutex sync.RWMutex
+)
+
+// cachedPolyphaseBank returns the bank for key, calling design the first
+// time a key is seen. The bank must not be modified.
+func cachedPolyphaseBank(key polyphaseKey, design func() *polyphaseBank) *polyphaseBank {
+	polyphaseCacheMutex.RLock()
+	bank, exists := polyphaseCache[key]
+	polyphaseCacheMutex.RUnlock()
+	if exists {
+		return bank
+	}
+
+	polyphaseCacheMutex.Lock()
+	defer polyphaseCacheMutex.Unlock()
+	if bank, exists = polyphaseCache[key]; exists {
+		return bank
+	}
+	bank = design()
+	polyphaseCache[key] = bank
+	polyphaseOrder = evictOldest(polyphaseCache, append(polyphaseOrder, key))
+	return bank
+}
+
+// rationalBank returns the cached polyphase bank resampleSinc uses from
+// inputRate to outputRate, and whether the ratio has one. prefilter must be
+// nil or the anti-aliasing kernel of config for the two rates.
+func rationalBank(inputRate, outputRate int, config *AudioConfig, window, prefilter []float64) (bank *polyphaseBank, up, down int, ok bool) {
+	up, down, ok = rationalRatio(inputRate, outputRate)
+	if !ok {
+		return nil, 0, 0, false
+	}
+	key := polyphaseKey{
+		inputRate:  inputRate,
+		outputRate: outputRate,
+		windowSize: config.ResamplingWindowSize,
+		window:     config.WindowFunction,
+		kaiserBeta: config.KaiserBeta,
+	}
+	if prefilter != nil {
+		key.prefilterType = config.AntiAliasingType
+		key.prefilterRatio = config.AntiAliasingCutoffRatio
+	}
+	bank = cachedPolyphaseBank(key, func() *polyphaseBank {
+		return designPolyphaseBank(up, config.ResamplingWindowSize, window, prefilter)
+	})
+	return bank, up, down, true
+}
+
+// PrecomputeResampler designs the polyphase banks that converting input at
+// each of inputRates with config will use, so a server can pay for them at
+// startup rather than on its first requests. Banks are otherwise designed
+// on first use and cached for the life of the process. Rates converted by
+// integer decimation or per-sample interpolation have nothing to precompute.
+func PrecomputeResampler(config *AudioConfig, inputRates ...int) {
+	if config.ResampleMethod != ResampleSinc || config.ResampleTradeoff == ResampleBest {
+		return
+	}
+	config = tradeoffConfig(config)
+	window := resampleWindow(config.ResamplingWindowSize, config)
+	for _, rate := range inputRates {
+		if rate <= 0 || rate == 8000 || (rate%8000 == 0 && decimationStages(rate/8000) != nil) {
+			continue
+		}
+		var prefilter []float64
+		if config.AntiAliasingType.isFIR() && rate > 8000 {
+			prefilter = antiAliasingKernel(float64(rate), 8000, config)
+		}
+		rationalBank(rate, 8000, config, window, prefilter)
+	}
+}
+
+// resamplePCM16Rational resamples by the exact ratio up/down using a polyphase
+// filter bank. Every output sample falls on one of up fixed phases between input
+// samples, so the windowed sinc coefficients are computed once per phase instead
+// of once per output sample. The kernel is identical to resamplePCM16.
+// A non-nil prefilter (odd length, applied at the input rate) is convolved
+// into every phase, so filtering and resampling cost a single dot product.
+func resamplePCM16Rational(input []int16, up, down, windowSize int, window []float64, prefilter []float64) []int16 {
+	return resamplePolyphase(input, up, down, designPolyphaseBank(up, windowSize, window, prefilter))
+}
+
+// designPolyphaseBank computes the windowed sinc kernel of every phase of
+// an up-phase bank, with prefilter convolved in when non-nil
+func designPolyphaseBank(up, windowSize int, window []float64, prefilter []float64) *polyphaseBank {
+	taps := windowSize*2 + 1
+	bank := make([][]float64, up)
+	coefficients := make([]float64, up*taps)
+	for p := range bank {
+		frac := float64(p) / float64(up)
+		// sin(pi*(frac-j)) only alternates sign with j, so one sine per phase is enough
+		sinFrac := math.Sin(math.Pi * frac)
+		kernel := coefficients[p*taps : (p+1)*taps]
+		for j := -windowSize; j <= windowSize; j++ {
+			x := math.Pi * (frac - float64(j))
+			sinc := 1.0
+			if x != 0 {
+				sinc = sinFrac / x
+				if j%2 != 0 {
+					sinc = -sinc
+				}
+			}
+			kernel[j+windowSize] = window[j+windowSize] * sinc
+		}
+		bank[p] = kernel
+	}
+	if prefilter != nil {
+		for p := range bank {
+			bank[p] = convolveKernels(bank[p], prefilter)
+		}
+		windowSize += len(prefilter) / 2
+	}
+
+	// Every output uses the full kernel, so each phase is pre-normalized once
+	// and the hot loop is a plain float32 dot product
+	normalized := make([][]float32, up)
+	backing := make([]float32, up*len(bank[0]))
+	for p, kernel := range bank {
+		weightSum := 0.0
+		for _, w := range kernel {
+			weightSum += w
+		}
+		if weightSum <= 0 {
+			weightSum = 1
+		}
+		normalized[p] = backing[p*len(kernel) : (p+1)*len(kernel)]
+		for k, w := range kernel {
+			normalized[p][k] = float32(w / weightSum)
+		}
+	}
+	return &polyphaseBank{phases: normalized, half: windowSize}
+}
+
+// resamplePolyphase resamples input by up/down with a bank of up phases
+func resamplePolyphase(input []int16, up, down int, bank *polyphaseBank) []int16 {
+	output := getInt16s(resampledLength(len(input), up, down))
+	signal := int16sToFloat32(input)
+	defer putFloat32s(signal)
+	edge := make([]float32, 2*bank.half+1)
+
+	for i := range output {
+		// Integer position math: output i sits at input (i*down)/up + phase/up
+		n := i * down
+		idx := n / up
+		phase := n % up
+		kernel := bank.phases[phase]
+
+		start := idx - bank.half
+		var segment []float32
+		if start >= 0 && start+len(kernel) <= len(signal) {
+			segment = signal[start : start+len(kernel)]
+		} else {
+			// Edges: the kernel reaches past the input, which is mirrored
+			for k := range edge {
+				edge[k] = signal[reflectIndex(start+k, len(signal))]
+			}
+			segment = edge
+		}
+		sum := dotFloat32(segment, kernel)
+		output[i] = int16(math.Max(-32768, math.Min(32767, math.Round(float64(sum)))))
+	}
+
+	return output
+}
@@ -0,0 +1,110 @@
+package wav2ulaw
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// RealTimeLatency is the most a ChunkEncoder in real-time mode delays
+	// audio: from the first sample of a chunk to the output it completes,
+	// counting the chunk itself and the lookahead of its filters and
+	// resampler. Processing time is not included.
+	RealTimeLatency = 40 * time.Millisecond
+	// RealTimeBlock is the longest chunk a ChunkEncoder in real-time mode
+	// takes without exceeding RealTimeLatency; the rest of the budget is its
+	// lookahead.
+	RealTimeBlock = 20 * time.Millisecond
+)
+
+// ErrNotRealTime is wrapped by the errors of NewChunkEncoder for settings
+// that need more of the signal than real-time mode may wait for
+var ErrNotRealTime = errors.New("not available in real-time mode")
+
+// realTimeConfig checks config, already validated for inputRate, against
+// the real-time bounds. Stages that need the whole signal are rejected and
+// the resampling window is limited to the lookahead left after a block; in
+// lenient mode the stages are dropped and the window is clamped instead,
+// with a warning.
+func realTimeConfig(config *AudioConfig, inputRate int) (*AudioConfig, error) {
+	c := *config
+	stages := []struct {
+		name string
+		used bool
+		drop func()
+	}{
+		{"NormalizePeak", c.NormalizePeak != 0, func() { c.NormalizePeak = 0 }},
+		{"TargetLoudness", c.TargetLoudness != 0, func() { c.TargetLoudness = 0 }},
+		{"Tempo", c.Tempo != 0 && c.Tempo != 1, func() { c.Tempo = 0 }},
+		{"Reverse", c.Reverse, func() { c.Reverse = false }},
+		{"FadeOutMs", c.FadeOutMs > 0, func() { c.FadeOutMs = 0 }},
+		{"AutoTrim", c.AutoTrim != nil, func() { c.AutoTrim = nil }},
+		{"Noise", c.Noise != nil, func() { c.Noise = nil }},
+		{"ResampleMethod", c.ResampleMethod == ResampleFFT, func() { c.ResampleMethod = ResampleSinc }},
+	}
+	for _, stage := range stages {
+		if !stage.used {
+			continue
+		}
+		if !c.Lenient {
+			return nil, fmt.Errorf("%s needs the whole signal: %w", stage.name, ErrNotRealTime)
+		}
+		logWarn(&c, WarnStageDropped, "", "stage needs the whole signal, dropped in real-time mode", "field", stage.name)
+		stage.drop()
+	}
+
+	// Input samples of lookahead per unit of window size: the anti-aliasing
+	// FIR's half length and the resampler's window. The AAAuto kernel has a
+	// length of its own.
+	perWindow := 0
+	budget := (RealTimeLatency - RealTimeBlock).Seconds() * float64(inputRate)
+	if inputRate > 8000 {
+		if c.AntiAliasingType == AAAuto {
+			budget -= float64(len(autoAntiAliasingKernel(float64(inputRate), 8000)) / 2)
+		} else {
+			perWindow += int(math.Ceil(float64(inputRate) / 8000))
+		}
+	}
+	if inputRate != 8000 {
+		perWindow++
+	}
+	if perWindow > 0 {
+		v := &configValidator{config: &c}
+		size := float64(c.ResamplingWindowSize)
+		v.check("ResamplingWindowSize", &size, 1, math.Floor(budget/float64(perWindow)))
+		if v.err != nil {
+			return nil, v.err
+		}
+		c.ResamplingWindowSize = int(size)
+	}
+	return &c, nil
+}
+
+// Latency returns the delay the encoder's filters and resampler add: the
+// input they hold back before the output it completes can be produced. A
+// chunk's own duration adds to it for the chunk's first sample.
+func (e *ChunkEncoder) Latency() time.Duration {
+	lookahead := 0
+	for _, f := range e.filters {
+		if fir, ok := f.(*firState); ok {
+			lookahead += len(fir.kernel) / 2
+		}
+	}
+	if e.resampler != nil {
+		lookahead += e.resampler.windowSize
+	}
+	return time.Duration(lookahead) * time.Second / time.Duration(e.inputRate)
+}
+
+// checkBlock warns once when a chunk in real-time mode is longer than
+// RealTimeBlock, which breaks the RealTimeLatency bound
+func (e *ChunkEncoder) checkBlock(samples int) {
+	if !e.config.RealTime || e.warnedBlock || samples*int(time.Second/RealTimeBlock) <= e.inputRate {
+		return
+	}
+	e.warnedBlock = true
+	logWarn(e.config, WarnLatencyExceeded, "", "chunk longer than the real-time block, latency bound exceeded",
+		"chunk", time.Duration(samples)*time.Second/time.Duration(e.inputRate), "block", RealTimeBlock)
+}
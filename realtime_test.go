@@ -0,0 +1,63 @@
+package wav2ulaw
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRealTimeRejectsWholeSignalStages(t *testing.T) {
+	config := DefaultAudioConfig()
+	config.RealTime = true
+	if _, err := NewChunkEncoder(16000, config); !errors.Is(err, ErrNotRealTime) {
+		t.Errorf("normalization: got %v, want ErrNotRealTime", err)
+	}
+
+	config.Lenient = true
+	config.FadeOutMs = 500
+	encoder, err := NewChunkEncoder(16000, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if encoder.config.NormalizePeak != 0 || encoder.config.FadeOutMs != 0 {
+		t.Errorf("lenient mode kept the stages: %+v", encoder.config)
+	}
+	if config.NormalizePeak == 0 {
+		t.Error("the caller's config was modified")
+	}
+}
+
+func TestRealTimeLatencyBound(t *testing.T) {
+	for _, rate := range []int{8000, 16000, 44100, 48000} {
+		config := DefaultAudioConfig()
+		config.NormalizePeak = 0
+		config.ResamplingWindowSize = 400
+		config.RealTime = true
+		_, err := NewChunkEncoder(rate, config)
+		var configErr *ConfigError
+		if rate != 8000 && (!errors.As(err, &configErr) || configErr.Field != "ResamplingWindowSize") {
+			t.Errorf("%d Hz: got %v, want a ResamplingWindowSize ConfigError", rate, err)
+		}
+
+		config.Lenient = true
+		encoder, err := NewChunkEncoder(rate, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if latency := encoder.Latency(); latency > RealTimeLatency-RealTimeBlock {
+			t.Errorf("%d Hz: lookahead %v leaves no room for a block", rate, latency)
+		}
+
+		// Every 20 ms block completes all output older than the bound
+		input := sineWave(rate, 1000, float64(rate), 0.5)
+		block := rate * int(RealTimeBlock) / int(time.Second)
+		produced := 0
+		for end := block; end <= len(input); end += block {
+			produced += len(encoder.Encode(input[end-block : end]))
+			received := time.Duration(end) * time.Second / time.Duration(rate)
+			if due := int((received - RealTimeLatency + RealTimeBlock) * 8000 / time.Second); produced < due-1 {
+				t.Fatalf("%d Hz: %d samples out after %v, want %d", rate, produced, received, due)
+			}
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package wav2ulaw
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	// Amplitude of each tone of the multitone response signal, low enough
+	// that the sum of all tones stays clear of clipping and compression
+	referenceToneAmplitude = 0.03
+	// Amplitude of the 1 kHz distortion and level signal (-6 dBFS)
+	referenceLevelAmplitude = 0.5
+)
+
+// Frequencies of the multitone response signal besides its 1 kHz reference
+// tone (Hz), multiples of 5 so each completes whole cycles in the measured
+// span and the tones do not leak into each other's measurements
+var referenceFrequencies = []float64{100, 200, 300, 500, 2000, 3000, 3400, 3800}
+
+// ResponsePoint is the gain of a conversion at one frequency
+type ResponsePoint struct {
+	Frequency float64 `json:"frequency_hz"`
+	GainDb    float64 `json:"gain_db"`
+}
+
+// ReferenceMeasurements describe what a configuration does to known test
+// signals, to be stored and compared with later measurements by
+// CheckReference
+type ReferenceMeasurements struct {
+	// Rate of the test signals fed to the conversion (Hz)
+	SampleRate int `json:"sample_rate"`
+	// Gain at each frequency of a multitone signal relative to its 1 kHz
+	// tone, so level stages that scale the whole signal leave it unchanged
+	Response []ResponsePoint `json:"response"`
+	// Total harmonic distortion of a 1 kHz tone at -6 dBFS, from its second
+	// and third harmonics (%)
+	THD float64 `json:"thd_percent"`
+	// Output RMS level of the same tone (dBFS, a full-scale sine being 0)
+	LevelDbfs float64 `json:"level_dbfs"`
+}
+
+// ReferenceTolerance bounds how far measurements may move from the
+// reference before CheckReference fails them
+type ReferenceTolerance struct {
+	// Per-frequency gain (dB)
+	ResponseDb float64
+	// Total harmonic distortion (percentage points)
+	THD float64
+	// Output level (dB)
+	LevelDb float64
+}
+
+// DefaultReferenceTolerance returns tolerances that pass the u-law
+// quantization differences of harmless changes but catch a retuned filter
+// or gain stage
+func DefaultReferenceTolerance() ReferenceTolerance {
+	return ReferenceTolerance{ResponseDb: 0.5, THD: 0.5, LevelDb: 0.5}
+}
+
+// ReferenceCheck compares one measurement with its reference
+type ReferenceCheck struct {
+	// What was measured, e.g. "response 3400 Hz" or "THD"
+	Name string
+	// Measured and reference values, in the unit of the measurement
+	Measured, Reference float64
+	// Largest accepted difference
+	Tolerance float64
+	// Whether the difference is within Tolerance
+	Passed bool
+}
+
+// ReferenceReport is the outcome of CheckReference
+type ReferenceReport struct {
+	// The measurements of the configuration checked
+	Measurements *ReferenceMeasurements
+	// One check per reference measurement
+	Checks []ReferenceCheck
+	// Whether every check passed
+	Passed bool
+}
+
+// Failed returns the checks that did not pass
+func (r *ReferenceReport) Failed() []ReferenceCheck {
+	var failed []ReferenceCheck
+	for _, c := range r.Checks {
+		if !c.Passed {
+			failed = append(failed, c)
+		}
+	}
+	return failed
+}
+
+// MeasureReference converts test signals at sampleRate to u-law with config
+// and measures the frequency response, harmonic distortion and output level
+// of the result. The signals are synthesized, so the measurements depend
+// only on config, sampleRate and the processing of this library version.
+func MeasureReference(config *AudioConfig, sampleRate int) (*ReferenceMeasurements, error) {
+	if config == nil {
+		config = DefaultAudioConfig()
+	}
+	if sampleRate < 8000 {
+		return nil, fmt.Errorf("invalid sample rate %d, the test signals need at least 8000 Hz", sampleRate)
+	}
+	m := &ReferenceMeasurements{SampleRate: sampleRate}
+
+	n := int(selfTestSeconds * float64(sampleRate))
+	multitone := make([]int16, n)
+	for i := range multitone {
+		t := float64(i) / float64(sampleRate)
+		sum := math.Sin(2 * math.Pi * 1000 * t)
+		for _, freq := range referenceFrequencies {
+			sum += math.Sin(2 * math.Pi * freq * t)
+		}
+		multitone[i] = int16(math.Round(referenceToneAmplitude * 32767 * sum))
+	}
+	output, err := referenceConvert(multitone, sampleRate, config)
+	if err != nil {
+		return nil, fmt.Errorf("converting multitone: %v", err)
+	}
+	reference := math.Max(goertzelLevel(output, 1000, 8000), 1e-9)
+	for _, freq := range referenceFrequencies {
+		gain := 20 * math.Log10(math.Max(goertzelLevel(output, freq, 8000), 1e-9)/reference)
+		m.Response = append(m.Response, ResponsePoint{Frequency: freq, GainDb: gain})
+	}
+
+	tone := make([]int16, n)
+	for i := range tone {
+		tone[i] = int16(math.Round(referenceLevelAmplitude * 32767 * math.Sin(2*math.Pi*1000*float64(i)/float64(sampleRate))))
+	}
+	if output, err = referenceConvert(tone, sampleRate, config); err != nil {
+		return nil, fmt.Errorf("converting 1 kHz tone: %v", err)
+	}
+	fundamental := goertzelLevel(output, 1000, 8000)
+	// Higher harmonics lie above the 4 kHz Nyquist frequency of the output
+	h2, h3 := goertzelLevel(output, 2000, 8000), goertzelLevel(output, 3000, 8000)
+	if fundamental > 0 {
+		m.THD = 100 * math.Sqrt(h2*h2+h3*h3) / fundamental
+	}
+	var sum float64
+	for _, s := range output {
+		v := float64(s) / 32767
+		sum += v * v
+	}
+	// Relative to the RMS of a full-scale sine, 1/sqrt(2)
+	m.LevelDbfs = 20 * math.Log10(math.Max(math.Sqrt(2*sum/float64(len(output))), 1e-9))
+	return m, nil
+}
+
+// referenceConvert converts a test signal and returns the decoded 8 kHz
+// output without its edges
+func referenceConvert(samples []int16, sampleRate int, config *AudioConfig) ([]int16, error) {
+	ulaw, err := ConvertPCM16ToUlaw(samples, sampleRate, config)
+	if err != nil {
+		return nil, err
+	}
+	// Decode the configured variant back to standard G.711 first
+	return trimSelfTestMargin(decodeUlawSamples(ConvertUlawVariant(ulaw, config.UlawVariant, 0)), 8000), nil
+}
+
+// CheckReference measures config at the reference's sample rate like
+// MeasureReference and compares the result with reference within
+// tolerance, so a deployment can refuse a configuration change that alters
+// the sound beyond what was signed off. Errors are returned for conversions
+// that fail, not for measurements out of tolerance.
+func CheckReference(config *AudioConfig, reference *ReferenceMeasurements, tolerance ReferenceTolerance) (*ReferenceReport, error) {
+	measured, err := MeasureReference(config, reference.SampleRate)
+	if err != nil {
+		return nil, err
+	}
+	report := &ReferenceReport{Measurements: measured, Passed: true}
+	add := func(name string, value, ref, tol float64) {
+		passed := math.Abs(value-ref) <= tol
+		report.Checks = append(report.Checks, ReferenceCheck{Name: name, Measured: value, Reference: ref, Tolerance: tol, Passed: passed})
+		report.Passed = report.Passed && passed
+	}
+	for _, ref := range reference.Response {
+		// References from another library version may measure other frequencies
+		gain := math.NaN()
+		for _, p := range measured.Response {
+			if p.Frequency == ref.Frequency {
+				gain = p.GainDb
+			}
+		}
+		add(fmt.Sprintf("response %.0f Hz", ref.Frequency), gain, ref.GainDb, tolerance.ResponseDb)
+	}
+	add("THD", measured.THD, reference.THD, tolerance.THD)
+	add("level", measured.LevelDbfs, reference.LevelDbfs, tolerance.LevelDb)
+	return report, nil
+}
@@ -0,0 +1,77 @@
+package wav2ulaw
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestMeasureReference(t *testing.T) {
+	config := DefaultAudioConfig()
+	config.NormalizePeak = 0
+	config.CompressionRatio = 1
+	m, err := MeasureReference(config, 16000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Without level processing the measurements follow the filter designs
+	for _, p := range m.Response {
+		want := designedGain(config, 16000, p.Frequency) - designedGain(config, 16000, 1000)
+		if math.Abs(p.GainDb-want) > 1 {
+			t.Errorf("%.0f Hz: gain %.2f dB, designed %.2f dB", p.Frequency, p.GainDb, want)
+		}
+	}
+	if m.THD > 1 {
+		t.Errorf("THD %.2f%% without compression, want below 1%%", m.THD)
+	}
+	if want := designedGain(config, 16000, 1000) - 6; math.Abs(m.LevelDbfs-want) > 1 {
+		t.Errorf("level %.2f dBFS, want %.2f", m.LevelDbfs, want)
+	}
+
+	if _, err := MeasureReference(config, 4000); err == nil {
+		t.Error("expected an error for a rate below 8000 Hz")
+	}
+}
+
+func TestCheckReference(t *testing.T) {
+	reference, err := MeasureReference(DefaultAudioConfig(), 48000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := CheckReference(DefaultAudioConfig(), reference, DefaultReferenceTolerance())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Passed || len(report.Checks) != len(referenceFrequencies)+2 {
+		t.Fatalf("unchanged config: passed %v with %d checks, failed %v", report.Passed, len(report.Checks), report.Failed())
+	}
+
+	// A narrower band fails the response at the top of the band
+	narrow := DefaultAudioConfig()
+	narrow.LowPassCutoff = 2000
+	report, err = CheckReference(narrow, reference, DefaultReferenceTolerance())
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed := make(map[string]bool)
+	for _, c := range report.Failed() {
+		failed[c.Name] = true
+	}
+	if report.Passed || !failed["response 3400 Hz"] {
+		t.Errorf("low-pass at 2000 Hz: failed %v", report.Failed())
+	}
+
+	quieter := DefaultAudioConfig()
+	quieter.NormalizePeak = 0.5
+	report, err = CheckReference(quieter, reference, DefaultReferenceTolerance())
+	if err != nil {
+		t.Fatal(err)
+	}
+	levelFailed := false
+	for _, c := range report.Failed() {
+		levelFailed = levelFailed || strings.HasPrefix(c.Name, "level")
+	}
+	if !levelFailed {
+		t.Errorf("normalize 0.5: failed %v, want the level to fail", report.Failed())
+	}
+}
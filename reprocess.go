@@ -0,0 +1,180 @@
+package wav2ulaw
+
+import (
+	"log/slog"
+	"math"
+	"time"
+)
+
+const (
+	// Frames quieter than this do not steer the automatic gain control (dBov)
+	agcSpeechFloorDbov = -50.0
+	// Largest gain AGC applies when ReprocessOptions.AGCMaxGainDb is 0 (dB)
+	defaultAGCMaxGainDb = 20.0
+	// Fraction of the distance to the wanted gain AGC moves per 20 ms frame,
+	// a time constant of about 100 ms
+	agcSmoothing = 0.2
+	// Frames the noise gate stays open after the level drops, so word
+	// endings and short pauses are not cut (200 ms)
+	gateHoldFrames = 10
+	// Accepted range of the AGC target and gate threshold (dBov)
+	minReprocessDbov = -80
+)
+
+// ReprocessOptions selects the level stages ReprocessUlaw runs. Stages left
+// at zero are skipped.
+type ReprocessOptions struct {
+	// Mute 20 ms frames quieter than this, apart from a 200 ms hold after
+	// louder audio (dBov, 0 = disabled)
+	NoiseGateDbov float64
+	// Target speech level of the automatic gain control (dBov, 0 = disabled)
+	AGCTargetDbov float64
+	// Largest boost or cut AGC applies (dB, 0 = 20 dB)
+	AGCMaxGainDb float64
+	// Normalize to this peak level after the other stages (0.0 to 1.0, 0 = disabled)
+	NormalizePeak float64
+	// Non-standard u-law bit layout of both input and output (0 = standard G.711)
+	UlawVariant UlawVariant
+	// Receives debug logs of the stages and their timings (nil = silent)
+	Logger *slog.Logger
+}
+
+// ReprocessUlaw decodes 8 kHz u-law, runs the stages selected in options
+// (noise gate, AGC, then normalization) and encodes the result back to
+// u-law of the same length, e.g. to fix the levels of prompts whose
+// original WAV files are gone. Without any stage the output equals the
+// input. Empty data returns ErrEmptyInput.
+func ReprocessUlaw(data []byte, options *ReprocessOptions) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, ErrEmptyInput
+	}
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+	if options.NoiseGateDbov == 0 && options.AGCTargetDbov == 0 && options.NormalizePeak == 0 {
+		return append([]byte(nil), data...), nil
+	}
+	// The logging helpers report through an AudioConfig
+	config := &AudioConfig{Logger: options.Logger}
+
+	start := time.Now()
+	samples := decodeUlawSamples(ConvertUlawVariant(data, options.UlawVariant, 0))
+	logStage(config, "decode", start, len(samples), len(samples))
+
+	if options.NoiseGateDbov != 0 {
+		start = time.Now()
+		applyNoiseGate(samples, 8000, options.NoiseGateDbov)
+		logStage(config, "noise gate", start, len(samples), len(samples))
+	}
+	if options.AGCTargetDbov != 0 {
+		start = time.Now()
+		maxGain := options.AGCMaxGainDb
+		if maxGain == 0 {
+			maxGain = defaultAGCMaxGainDb
+		}
+		applyAGC(samples, 8000, options.AGCTargetDbov, maxGain)
+		logStage(config, "agc", start, len(samples), len(samples))
+	}
+	if options.NormalizePeak > 0 {
+		start = time.Now()
+		normalizeAudio(samples, options.NormalizePeak)
+		logStage(config, "normalize", start, len(samples), len(samples))
+	}
+
+	start = time.Now()
+	ulaw := options.UlawVariant.apply(encodeUlawSamples(samples))
+	logStage(config, "encode", start, len(ulaw), len(ulaw))
+	return ulaw, nil
+}
+
+// validate checks the options, returning a *ConfigError for the first
+// value out of range
+func (o *ReprocessOptions) validate() error {
+	v := &configValidator{config: &AudioConfig{}}
+	v.check("NoiseGateDbov", &o.NoiseGateDbov, minReprocessDbov, 0)
+	v.check("AGCTargetDbov", &o.AGCTargetDbov, minReprocessDbov, 0)
+	v.check("AGCMaxGainDb", &o.AGCMaxGainDb, 0, math.Inf(1))
+	v.check("NormalizePeak", &o.NormalizePeak, 0, 1)
+	return v.err
+}
+
+// frameLevels returns the RMS level of each 20 ms frame of samples (dBov)
+func frameLevels(samples []int16, sampleRate int) ([]float64, int) {
+	frameLen := max(1, sampleRate*analysisFrameMs/1000)
+	levels := make([]float64, 0, (len(samples)+frameLen-1)/frameLen)
+	for start := 0; start < len(samples); start += frameLen {
+		levels = append(levels, rmsDbov(samples[start:min(start+frameLen, len(samples))]))
+	}
+	return levels, frameLen
+}
+
+// applyFrameGains scales samples in place by a gain per frame, ramping
+// linearly from the gain of one frame to the next so changes do not click
+func applyFrameGains(samples []int16, frameLen int, gains []float64) {
+	previous := gains[0]
+	for f, gain := range gains {
+		frame := samples[f*frameLen : min((f+1)*frameLen, len(samples))]
+		for i, s := range frame {
+			g := previous + (gain-previous)*float64(i+1)/float64(len(frame))
+			frame[i] = int16(math.Max(-32768, math.Min(32767, math.Round(float64(s)*g))))
+		}
+		previous = gain
+	}
+}
+
+// applyNoiseGate mutes the frames of samples below thresholdDbov in place.
+// The gate opens one frame early so onsets keep their attack and holds for
+// gateHoldFrames after the level drops.
+func applyNoiseGate(samples []int16, sampleRate int, thresholdDbov float64) {
+	levels, frameLen := frameLevels(samples, sampleRate)
+	gains := make([]float64, len(levels))
+	hold := 0
+	for f, level := range levels {
+		if level >= thresholdDbov {
+			hold = gateHoldFrames + 1
+			if f > 0 {
+				gains[f-1] = 1
+			}
+		}
+		if hold > 0 {
+			gains[f] = 1
+			hold--
+		}
+	}
+	applyFrameGains(samples, frameLen, gains)
+}
+
+// applyAGC brings the speech in samples towards targetDbov in place, with a
+// gain that follows the level of the louder frames, changes by no more than
+// maxGainDb either way and never drives a frame into clipping. Quiet frames
+// hold the gain of the speech before them, so pauses are not pumped up.
+func applyAGC(samples []int16, sampleRate int, targetDbov, maxGainDb float64) {
+	speech := speechPower(samples, sampleRate)
+	if speech == 0 {
+		return
+	}
+	levels, frameLen := frameLevels(samples, sampleRate)
+	wanted := func(levelDbov float64) float64 {
+		return math.Max(-maxGainDb, math.Min(maxGainDb, targetDbov-levelDbov))
+	}
+
+	// Start from the gain for the file's overall speech level rather than
+	// ramping up from unity over the first syllable
+	gainDb := wanted(10 * math.Log10(speech/(32767.0*32767.0)))
+	gains := make([]float64, len(levels))
+	for f, level := range levels {
+		if level > agcSpeechFloorDbov {
+			gainDb += (wanted(level) - gainDb) * agcSmoothing
+		}
+		gain := math.Pow(10, gainDb/20)
+		peak := 0.0
+		for _, s := range samples[f*frameLen : min((f+1)*frameLen, len(samples))] {
+			peak = math.Max(peak, math.Abs(float64(s)))
+		}
+		if peak*gain > 32767 {
+			gain = 32767 / peak
+		}
+		gains[f] = gain
+	}
+	applyFrameGains(samples, frameLen, gains)
+}
@@ -0,0 +1,76 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestReprocessUlawAGC(t *testing.T) {
+	// A quiet first half and a loud second half end up at similar levels
+	tone := sineWave(16000, 440, 8000, 0.02)
+	for i := 8000; i < len(tone); i++ {
+		tone[i] *= 10
+	}
+	out, err := ReprocessUlaw(encodeUlawSamples(tone), &ReprocessOptions{AGCTargetDbov: -20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != len(tone) {
+		t.Fatalf("length changed from %d to %d", len(tone), len(out))
+	}
+	decoded := decodeUlawSamples(out)
+	quiet, loud := rmsDbov(decoded[2000:7000]), rmsDbov(decoded[11000:15000])
+	if math.Abs(quiet+20) > 2 || math.Abs(loud+20) > 2 {
+		t.Errorf("halves at %.1f and %.1f dBov, want about -20", quiet, loud)
+	}
+}
+
+func TestReprocessUlawNoiseGate(t *testing.T) {
+	speech := sineWave(8000, 440, 8000, 0.5)
+	hiss := GenerateComfortNoise(-60, 1000)
+	input := append(encodeUlawSamples(speech), hiss...)
+	out, err := ReprocessUlaw(input, &ReprocessOptions{NoiseGateDbov: -45})
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := decodeUlawSamples(out)
+	if level := rmsDbov(decoded[2000:6000]); level < -10 {
+		t.Errorf("speech gated down to %.1f dBov", level)
+	}
+	// Past the hold, the hiss is digital silence
+	for i, s := range decoded[8000+(gateHoldFrames+1)*160:] {
+		if s != 0 {
+			t.Fatalf("sample %d after the hold is %d, want 0", i, s)
+		}
+	}
+}
+
+func TestReprocessUlawOptions(t *testing.T) {
+	data := encodeUlawSamples(sineWave(800, 440, 8000, 0.3))
+	out, err := ReprocessUlaw(data, &ReprocessOptions{})
+	if err != nil || !bytes.Equal(out, data) {
+		t.Errorf("no stages changed the data (err %v)", err)
+	}
+
+	normalized, err := ReprocessUlaw(ConvertUlawVariant(data, 0, UlawInvertBits), &ReprocessOptions{NormalizePeak: 0.9, UlawVariant: UlawInvertBits})
+	if err != nil {
+		t.Fatal(err)
+	}
+	peak := 0.0
+	for _, s := range decodeUlawSamples(ConvertUlawVariant(normalized, UlawInvertBits, 0)) {
+		peak = math.Max(peak, math.Abs(float64(s)))
+	}
+	if math.Abs(peak/32767-0.9) > 0.05 {
+		t.Errorf("peak %.3f after normalizing to 0.9", peak/32767)
+	}
+
+	var configErr *ConfigError
+	if _, err := ReprocessUlaw(data, &ReprocessOptions{AGCTargetDbov: 6}); !errors.As(err, &configErr) || configErr.Field != "AGCTargetDbov" {
+		t.Errorf("positive AGC target: got %v, want a *ConfigError", err)
+	}
+	if _, err := ReprocessUlaw(nil, &ReprocessOptions{}); !errors.Is(err, ErrEmptyInput) {
+		t.Errorf("empty input: got %v, want ErrEmptyInput", err)
+	}
+}
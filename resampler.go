@@ -0,0 +1,163 @@
+package wav2ulaw
+
+import (
+	"fmt"
+	"math"
+)
+
+// ResampleQuality trades the speed of a Resampler for the steepness of its
+// anti-aliasing cutoff
+type ResampleQuality int
+
+const (
+	ResampleQualityLow    ResampleQuality = iota // 8 zero crossings per side, for speech on small devices
+	ResampleQualityMedium                        // 16 zero crossings per side, as the CLI uses by default
+	ResampleQualityHigh                          // 64 zero crossings per side, as DefaultAudioConfig uses
+)
+
+// zeroCrossings returns the half-width of the kernel in periods of the
+// lower of the two rates
+func (q ResampleQuality) zeroCrossings() (int, bool) {
+	switch q {
+	case ResampleQualityLow:
+		return 8, true
+	case ResampleQualityMedium:
+		return 16, true
+	case ResampleQualityHigh:
+		return 64, true
+	}
+	return 0, false
+}
+
+// Resampler converts a stream of 16-bit PCM between sample rates one chunk
+// at a time, for uses that need the resampler without the rest of the
+// processing chain. Output positions are kept as exact fractions of the
+// input rate, so they do not drift over long streams, and the input history
+// the kernel reaches back to carries over between chunks, so the output does
+// not depend on how the input is split. When downsampling, the kernel's
+// cutoff follows the output Nyquist frequency, so no separate anti-aliasing
+// filter is needed.
+type Resampler struct {
+	up, down int
+	// Kernel half-width in input samples
+	half   int
+	cutoff float64
+	window []float64
+	// Normalized kernel of each phase, built on first use. Ratios with more
+	// than maxRationalPhases phases compute each kernel into scratch instead.
+	bank    [][]float32
+	scratch []float32
+	// Input the next output still reaches back to, preceded at the start of
+	// the stream by zeros standing in for the samples before it
+	history []float32
+	// Position of the next output in history, in units of 1/up input samples
+	pos int
+}
+
+// NewResampler creates a resampler from inputRate to outputRate (Hz)
+func NewResampler(inputRate, outputRate int, quality ResampleQuality) (*Resampler, error) {
+	if inputRate <= 0 || outputRate <= 0 {
+		return nil, fmt.Errorf("invalid sample rates %d and %d", inputRate, outputRate)
+	}
+	crossings, ok := quality.zeroCrossings()
+	if !ok {
+		return nil, fmt.Errorf("invalid resample quality %d", quality)
+	}
+	g := gcd(inputRate, outputRate)
+	r := &Resampler{up: outputRate / g, down: inputRate / g, cutoff: 1}
+	if r.down > r.up {
+		// Widen the kernel so it keeps its zero crossings at the output rate
+		r.cutoff = float64(r.up) / float64(r.down)
+	}
+	r.half = int(math.Ceil(float64(crossings) / r.cutoff))
+	r.window = makeWindow(WindowBlackman, 2*r.half+1, 0)
+	if r.up <= maxRationalPhases {
+		r.bank = make([][]float32, r.up)
+	} else {
+		r.scratch = make([]float32, 2*r.half+1)
+	}
+	r.reset()
+	return r, nil
+}
+
+// Push resamples the next chunk and returns the output it completes. The
+// output lags the input by the kernel half-width, which Flush returns at
+// the end of the stream. samples is not modified.
+func (r *Resampler) Push(samples []int16) []int16 {
+	if r.up == r.down {
+		return append([]int16(nil), samples...)
+	}
+	for _, sample := range samples {
+		r.history = append(r.history, float32(sample))
+	}
+	return r.drain(len(r.history) - r.half)
+}
+
+// Flush returns the remaining output, treating the input as followed by
+// silence, and resets the resampler for a new stream. A whole stream yields
+// ceil(n*outputRate/inputRate) samples for n input samples.
+func (r *Resampler) Flush() []int16 {
+	if r.up == r.down {
+		return nil
+	}
+	end := len(r.history)
+	r.history = append(r.history, make([]float32, r.half)...)
+	output := r.drain(end)
+	r.reset()
+	return output
+}
+
+// reset starts a new stream
+func (r *Resampler) reset() {
+	r.history = append(r.history[:0], make([]float32, r.half)...)
+	r.pos = r.half * r.up
+}
+
+// drain produces every output centered before history index limit, then
+// discards the history no later output reaches
+func (r *Resampler) drain(limit int) []int16 {
+	var output []int16
+	for r.pos < limit*r.up {
+		idx, phase := r.pos/r.up, r.pos%r.up
+		sum := dotFloat32(r.history[idx-r.half:idx+r.half+1], r.kernel(phase))
+		output = append(output, int16(math.Max(-32768, math.Min(32767, math.Round(float64(sum))))))
+		r.pos += r.down
+	}
+	if drop := r.pos/r.up - r.half; drop > 0 {
+		r.history = append(r.history[:0], r.history[drop:]...)
+		r.pos -= drop * r.up
+	}
+	return output
+}
+
+// kernel returns the normalized windowed-sinc taps for outputs phase/up of
+// an input sample past the center tap
+func (r *Resampler) kernel(phase int) []float32 {
+	if r.bank != nil && r.bank[phase] != nil {
+		return r.bank[phase]
+	}
+	taps := r.scratch
+	if r.bank != nil {
+		taps = make([]float32, 2*r.half+1)
+		r.bank[phase] = taps
+	}
+	frac := float64(phase) / float64(r.up)
+	weightSum := 0.0
+	for k := -r.half; k <= r.half; k++ {
+		x := math.Pi * r.cutoff * (float64(k) - frac)
+		sinc := 1.0
+		if x != 0 {
+			sinc = math.Sin(x) / x
+		}
+		weight := r.window[k+r.half] * sinc
+		taps[k+r.half] = float32(weight)
+		weightSum += weight
+	}
+	if weightSum <= 0 {
+		weightSum = 1
+	}
+	for k := range taps {
+		taps[k] = float32(float64(taps[k]) / weightSum)
+	}
+	return taps
+}
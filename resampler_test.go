@@ -0,0 +1,81 @@
+package wav2ulaw
+
+import (
+	"slices"
+	"testing"
+)
+
+// resampleChunks runs input through r in chunks of the given sizes, cycled
+func resampleChunks(r *Resampler, input []int16, sizes []int) []int16 {
+	var output []int16
+	for i, start := 0, 0; start < len(input); i++ {
+		end := min(start+sizes[i%len(sizes)], len(input))
+		output = append(output, r.Push(input[start:end])...)
+		start = end
+	}
+	return append(output, r.Flush()...)
+}
+
+func TestResamplerChunking(t *testing.T) {
+	input := sineWave(4410, 440, 44100, 0.5)
+	for _, rates := range [][2]int{{44100, 8000}, {8000, 44100}, {48000, 8000}, {44100, 7999}, {8000, 8000}} {
+		r, err := NewResampler(rates[0], rates[1], ResampleQualityMedium)
+		if err != nil {
+			t.Fatal(err)
+		}
+		whole := resampleChunks(r, input, []int{len(input)})
+		// Flush resets, so the same resampler converts the input again
+		chunked := resampleChunks(r, input, []int{1, 7, 160, 33})
+		if !slices.Equal(whole, chunked) {
+			t.Errorf("%d to %d Hz: chunked output differs", rates[0], rates[1])
+		}
+		if want := resampledLength(len(input), rates[1], rates[0]); len(whole) != want {
+			t.Errorf("%d to %d Hz: got %d samples, want %d", rates[0], rates[1], len(whole), want)
+		}
+	}
+}
+
+func TestResamplerResponse(t *testing.T) {
+	for _, quality := range []ResampleQuality{ResampleQualityLow, ResampleQualityMedium, ResampleQualityHigh} {
+		r, err := NewResampler(48000, 8000, quality)
+		if err != nil {
+			t.Fatal(err)
+		}
+		passband := resampleChunks(r, sineWave(48000, 1000, 48000, 0.5), []int{960})
+		if level := toneLevel(passband[800:7200], 1000, 8000); level < 0.48 || level > 0.52 {
+			t.Errorf("quality %d: 1 kHz level %.3f, want 0.5", quality, level)
+		}
+		// 6 kHz aliases to 2 kHz unless the kernel removes it
+		alias := resampleChunks(r, sineWave(48000, 6000, 48000, 0.5), []int{960})
+		if level := toneLevel(alias[800:7200], 2000, 8000); level > 0.005 {
+			t.Errorf("quality %d: 6 kHz alias at level %.4f", quality, level)
+		}
+	}
+
+	// The output is aligned with the input, not delayed by the kernel
+	r, err := NewResampler(48000, 8000, ResampleQualityMedium)
+	if err != nil {
+		t.Fatal(err)
+	}
+	impulse := make([]int16, 4800)
+	impulse[2400] = 30000
+	output := resampleChunks(r, impulse, []int{480})
+	peak := 0
+	for i, sample := range output {
+		if sample > output[peak] {
+			peak = i
+		}
+	}
+	if peak != 400 {
+		t.Errorf("impulse at output sample %d, want 400", peak)
+	}
+}
+
+func TestNewResamplerInvalid(t *testing.T) {
+	if _, err := NewResampler(0, 8000, ResampleQualityMedium); err == nil {
+		t.Error("expected an error for a zero input rate")
+	}
+	if _, err := NewResampler(16000, 8000, ResampleQuality(7)); err == nil {
+		t.Error("expected an error for an unknown quality")
+	}
+}
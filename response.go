@@ -0,0 +1,86 @@
+package wav2ulaw
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/cmplx"
+)
+
+const (
+	// Impulse response length used to measure the filter chain
+	responseImpulseLen = 16384
+	// Impulse amplitude; half scale keeps IIR overshoot from clipping
+	responseImpulseLevel = 16384
+)
+
+// FrequencyResponsePoint is the filter chain response at one frequency
+type FrequencyResponsePoint struct {
+	// Frequency (Hz)
+	Frequency float64
+	// Gain relative to the input (dB)
+	MagnitudeDb float64
+	// Phase shift (degrees, -180 to 180)
+	PhaseDegrees float64
+}
+
+// FilterFrequencyResponse measures the magnitude and phase response of the
+// high-pass, low-pass and anti-aliasing filters that config applies at
+// sampleRate, evaluated at points evenly spaced frequencies from 0 Hz to
+// Nyquist. The response is measured by passing an impulse through the same
+// code used for conversion, so it reflects what the converter really does.
+func FilterFrequencyResponse(config *AudioConfig, sampleRate int, points int) ([]FrequencyResponsePoint, error) {
+	if config == nil {
+		config = DefaultAudioConfig()
+	}
+	if sampleRate <= 0 {
+		return nil, fmt.Errorf("invalid sample rate %d", sampleRate)
+	}
+	if points < 2 {
+		return nil, fmt.Errorf("at least 2 points are required, got %d", points)
+	}
+	config, err := validateConfig(config, sampleRate)
+	if err != nil {
+		return nil, err
+	}
+
+	// The simple filters pass their first sample through unchanged, so the
+	// impulse is placed one sample in and the response read from there
+	impulse := make([]int16, responseImpulseLen+1)
+	impulse[1] = responseImpulseLevel
+	filtered := applyFilterChain(impulse, sampleRate, withoutStageOutput(config))
+
+	spectrum := make([]complex128, responseImpulseLen)
+	for i := range spectrum {
+		spectrum[i] = complex(float64(filtered[i+1])/responseImpulseLevel, 0)
+	}
+	fft(spectrum, false)
+
+	response := make([]FrequencyResponsePoint, points)
+	nyquist := float64(sampleRate) / 2
+	for i := range response {
+		freq := nyquist * float64(i) / float64(points-1)
+		bin := int(math.Round(freq / nyquist * float64(responseImpulseLen/2)))
+		h := spectrum[bin]
+		response[i] = FrequencyResponsePoint{
+			Frequency:    freq,
+			MagnitudeDb:  20 * math.Log10(cmplx.Abs(h)+1e-12),
+			PhaseDegrees: cmplx.Phase(h) * 180 / math.Pi,
+		}
+	}
+	return response, nil
+}
+
+// WriteFrequencyResponseCSV writes a response as CSV with a header row,
+// suitable for plotting in a spreadsheet or gnuplot
+func WriteFrequencyResponseCSV(w io.Writer, response []FrequencyResponsePoint) error {
+	if _, err := fmt.Fprintln(w, "frequency_hz,magnitude_db,phase_degrees"); err != nil {
+		return err
+	}
+	for _, p := range response {
+		if _, err := fmt.Fprintf(w, "%.2f,%.3f,%.2f\n", p.Frequency, p.MagnitudeDb, p.PhaseDegrees); err != nil {
+			return err
+		}
+	}
+	return nil
+}
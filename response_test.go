@@ -0,0 +1,41 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestFilterFrequencyResponse(t *testing.T) {
+	config := DefaultAudioConfig()
+	config.AntiAliasingType = AAButterworth
+
+	response, err := FilterFrequencyResponse(config, 16000, 161)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response) != 161 || response[160].Frequency != 8000 {
+		t.Fatalf("unexpected frequency grid: %d points ending at %.0f Hz", len(response), response[len(response)-1].Frequency)
+	}
+
+	at := func(freq float64) float64 {
+		return response[int(freq/50)].MagnitudeDb
+	}
+	if g := at(1000); g < -3 || g > 1 {
+		t.Errorf("expected passband gain near 0 dB at 1 kHz, got %.2f", g)
+	}
+	if at(50) > at(1000)-6 {
+		t.Errorf("expected high-pass attenuation at 50 Hz, got %.2f dB", at(50))
+	}
+	if at(7000) > at(1000)-12 {
+		t.Errorf("expected low-pass attenuation at 7 kHz, got %.2f dB", at(7000))
+	}
+
+	var buf bytes.Buffer
+	if err := WriteFrequencyResponseCSV(&buf, response); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != 162 {
+		t.Errorf("expected header plus 161 rows, got %d lines", lines)
+	}
+}
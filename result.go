@@ -0,0 +1,175 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// ConversionResult is the u-law output of ConvertWav with what is known
+// about the conversion. It reads the output like an io.Reader, whole with
+// Bytes or a frame at a time with NextFrame; all three consume it from the
+// same position, so it is read once. When the settings allow streaming
+// (see ConvertWavStreamToUlaw), the output is produced as it is read and
+// never held in memory as a whole, and conversion errors are returned by
+// the reads. Close the result to stop such a conversion early.
+type ConversionResult struct {
+	output io.Reader
+	// Starts a streaming conversion on the first read, nil otherwise
+	start func()
+	once  sync.Once
+	// Closed when a started conversion returns
+	done chan struct{}
+	pipe *io.PipeReader
+
+	// Guards the fields below, which a streaming conversion fills in
+	mu       sync.Mutex
+	info     *ConversionInfo
+	final    bool
+	warnings []Warning
+}
+
+// ConvertWav converts the WAV file read from r (nil config for
+// DefaultAudioConfig) to u-law, returning a result that reads the output.
+// The header and settings are checked up front. When the settings allow
+// streaming, the conversion runs as the result is read and r must not be
+// used until the result has been read to the end or closed; otherwise r is
+// read whole and the conversion is done before ConvertWav returns.
+func ConvertWav(r io.ReadSeeker, config *AudioConfig) (*ConversionResult, error) {
+	if config == nil {
+		config = DefaultAudioConfig()
+	}
+	result := &ConversionResult{}
+	recorded := *config
+	recorded.OnWarning = func(warning Warning) {
+		if config.OnWarning != nil {
+			config.OnWarning(warning)
+		}
+		result.mu.Lock()
+		result.warnings = append(result.warnings, warning)
+		result.mu.Unlock()
+	}
+
+	if checkStreamable(config) != nil {
+		wavBytes, err := io.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("error reading WAV data: %v", err)
+		}
+		ulaw, info, err := ConvertWavBytesToUlawInfo(wavBytes, &recorded)
+		if err != nil {
+			return nil, err
+		}
+		result.output, result.info, result.final = bytes.NewReader(ulaw), info, true
+		return result, nil
+	}
+
+	header, err := inspectWav(r, config.Limits)
+	if err != nil {
+		return nil, err
+	}
+	inputRate := config.InputSampleRate
+	if inputRate == 0 {
+		inputRate = header.sampleRate
+	}
+	// Warnings are recorded when the conversion validates the settings again
+	quiet := *config
+	quiet.Logger, quiet.OnWarning = nil, nil
+	if _, err := validateConfig(&quiet, inputRate); err != nil {
+		return nil, err
+	}
+	estimate, err := EstimateWav(r, config)
+	if err != nil {
+		return nil, err
+	}
+	if header.dataSize < int64(header.channels*header.bitDepth/8) {
+		return nil, ErrEmptyInput
+	}
+	result.info = newConversionInfo(header)
+	result.info.OutputDuration = estimate.OutputDuration
+
+	pr, pw := io.Pipe()
+	result.output, result.pipe, result.done = pr, pr, make(chan struct{})
+	result.start = func() {
+		info := *result.info
+		go func() {
+			defer close(result.done)
+			err := convertWavStream(r, pw, info.recording(&recorded), &info)
+			if err == nil {
+				result.mu.Lock()
+				result.info, result.final = &info, true
+				result.mu.Unlock()
+			}
+			pw.CloseWithError(err)
+		}()
+	}
+	return result, nil
+}
+
+// Read reads the next bytes of the output, starting a streaming conversion
+// on the first call
+func (c *ConversionResult) Read(p []byte) (int, error) {
+	if c.start != nil {
+		c.once.Do(c.start)
+	}
+	return c.output.Read(p)
+}
+
+// Bytes returns the rest of the output
+func (c *ConversionResult) Bytes() ([]byte, error) {
+	return io.ReadAll(c)
+}
+
+// NextFrame returns the next n bytes of the output, fewer at its end, and
+// io.EOF once it has all been read. At 8 kHz, 160 bytes are 20 ms.
+func (c *ConversionResult) NextFrame(n int) ([]byte, error) {
+	frame := make([]byte, n)
+	k, err := io.ReadFull(c, frame)
+	if err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	return frame[:k], err
+}
+
+// Close stops a streaming conversion that has not finished and waits for
+// it to return, after which the input may be used again
+func (c *ConversionResult) Close() error {
+	if c.pipe == nil {
+		return nil
+	}
+	c.pipe.Close()
+	c.once.Do(func() { close(c.done) })
+	<-c.done
+	return nil
+}
+
+// Info describes the conversion. A streaming conversion fills in the
+// resampling, gain and clipping once its output has been read to the end;
+// before that only the input format and durations are known.
+func (c *ConversionResult) Info() ConversionInfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	info := *c.info
+	info.Clipping = append([]ClipRegion(nil), info.Clipping...)
+	if info.Talk != nil {
+		talk := *info.Talk
+		info.Talk = &talk
+	}
+	return info
+}
+
+// Complete reports whether the conversion has finished and Info is final
+func (c *ConversionResult) Complete() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.final
+}
+
+// Warnings returns the warnings the conversion has raised so far. They are
+// recorded whether or not AudioConfig.OnWarning is set, which is still
+// called with each.
+func (c *ConversionResult) Warnings() []Warning {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Warning(nil), c.warnings...)
+}
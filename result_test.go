@@ -0,0 +1,139 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestConvertWavStreamsLazily(t *testing.T) {
+	wavBytes := encodeWavDepth(t, sineWave(48000, 440, 16000, 0.3), 16000, 16, 2)
+	var want bytes.Buffer
+	if err := ConvertWavStreamToUlaw(bytes.NewReader(wavBytes), &want, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ConvertWav(bytes.NewReader(wavBytes), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer result.Close()
+	// Nothing is converted until the output is read
+	if result.Complete() {
+		t.Error("streaming result complete before it was read")
+	}
+	if info := result.Info(); info.SampleRate != 16000 || info.Channels != 2 || info.OutputDuration.Seconds() != 3 {
+		t.Errorf("header info %+v, want 16000 Hz stereo lasting 3s", info)
+	}
+
+	var got []byte
+	for {
+		frame, err := result.NextFrame(160)
+		got = append(got, frame...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(frame) != 160 && len(got) != want.Len() {
+			t.Fatalf("short frame of %d bytes before the end", len(frame))
+		}
+	}
+	if !bytes.Equal(got, want.Bytes()) {
+		t.Fatalf("got %d bytes, want the %d of ConvertWavStreamToUlaw", len(got), want.Len())
+	}
+	if !result.Complete() {
+		t.Fatal("result not complete after reading the output")
+	}
+	if info := result.Info(); info.ResampleRate != 16000 || info.GainDb <= 0 {
+		t.Errorf("final info %+v, want the resampling and normalization gain", info)
+	}
+}
+
+func TestConvertWavClose(t *testing.T) {
+	wavBytes := encodeWavDepth(t, sineWave(160000, 440, 16000, 0.3), 16000, 16, 1)
+	input := bytes.NewReader(wavBytes)
+	result, err := ConvertWav(input, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := result.NextFrame(160); err != nil {
+		t.Fatal(err)
+	}
+	result.Close()
+	if _, err := result.NextFrame(160); err == nil {
+		t.Error("read after Close succeeded")
+	}
+	// The input is free again
+	if _, err := EstimateWav(input, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Closing before the first read starts nothing
+	result, err = ConvertWav(bytes.NewReader(wavBytes), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result.Close()
+}
+
+func TestConvertWavInMemory(t *testing.T) {
+	wavBytes := encodeWavDepth(t, testProgram(16000, 0.3), 16000, 16, 1)
+	config := ConfigForPromptLibrary()
+	want, err := ConvertWavBytesToUlaw(wavBytes, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := ConvertWav(bytes.NewReader(wavBytes), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Complete() {
+		t.Error("TargetLoudness cannot stream, so the result should be complete at once")
+	}
+	got, err := result.Bytes()
+	if err != nil || !bytes.Equal(got, want) {
+		t.Fatalf("got %d bytes (%v), want the %d of ConvertWavBytesToUlaw", len(got), err, len(want))
+	}
+}
+
+func TestConvertWavWarningsAndErrors(t *testing.T) {
+	wavBytes := encodeWavDepth(t, sineWave(16000, 440, 16000, 0.5), 16000, 16, 1)
+	config := DefaultAudioConfig()
+	config.LowPassCutoff = 9000
+	config.Lenient = true
+	for _, name := range []string{"streaming", "in memory"} {
+		if name == "in memory" {
+			config.Reverse = true
+		}
+		result, err := ConvertWav(bytes.NewReader(wavBytes), config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := result.Bytes(); err != nil {
+			t.Fatal(err)
+		}
+		clamped := 0
+		for _, warning := range result.Warnings() {
+			if warning.Code == WarnParameterClamped && warning.Details["field"] == "LowPassCutoff" {
+				clamped++
+			}
+		}
+		if clamped != 1 {
+			t.Errorf("%s: warnings %+v, want the clamped LowPassCutoff once", name, result.Warnings())
+		}
+	}
+
+	config.Lenient = false
+	var configErr *ConfigError
+	if _, err := ConvertWav(bytes.NewReader(wavBytes), config); !errors.As(err, &configErr) {
+		t.Errorf("invalid settings: got %v, want a *ConfigError before any read", err)
+	}
+	var wavErr *WavError
+	if _, err := ConvertWav(strings.NewReader("RIFF\x00\x00\x00\x00WAVE"), nil); !errors.As(err, &wavErr) {
+		t.Errorf("no chunks: got %v, want a *WavError", err)
+	}
+}
@@ -0,0 +1,41 @@
+package wav2ulaw
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestReverse(t *testing.T) {
+	// A rising ramp reversed falls, however the level stages scale it
+	samples := make([]int16, 8000)
+	for i := range samples {
+		samples[i] = int16(i * 2)
+	}
+	config := DefaultAudioConfig()
+	config.HighPassCutoff = 0
+	config.LowPassCutoff = 0
+	config.CompressionRatio = 1
+	forward, err := ConvertPCM16ToUlaw(samples, 8000, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Reverse = true
+	reversed, err := ConvertPCM16ToUlaw(samples, 8000, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Reverse(forward)
+	if !slices.Equal(forward, reversed) {
+		t.Error("reversed output is not the forward output backwards")
+	}
+
+	// Fades apply to the reversed audio
+	config.FadeInMs = 100
+	faded, err := ConvertPCM16ToUlaw(samples, 8000, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first := decodeUlawSamples(faded[:1])[0]; first > 100 || first < -100 {
+		t.Errorf("first sample %d, want the fade-in to start at silence", first)
+	}
+}
@@ -0,0 +1,126 @@
+package wav2ulaw
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// isChunkID reports whether b starts with a plausible RIFF chunk ID: four
+// printable ASCII characters
+func isChunkID(b []byte) bool {
+	if len(b) < 4 {
+		return false
+	}
+	for _, c := range b[:4] {
+		if c < ' ' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// nextChunk returns the offset of the chunk after the one of size bytes at
+// pos. Odd-sized chunks are followed by a pad byte, which some writers
+// leave out; when the padded offset does not start a chunk ID and the
+// unpadded one does, the pad byte is taken to be missing.
+func nextChunk(r io.ReadSeeker, pos, size, fileSize int64) (int64, error) {
+	next := pos + 8 + size
+	if size&1 == 0 {
+		return next, nil
+	}
+	if next+1+8 > fileSize {
+		return next + 1, nil
+	}
+	var ids [5]byte
+	if _, err := r.Seek(next, io.SeekStart); err != nil {
+		return 0, err
+	}
+	if _, err := io.ReadFull(r, ids[:]); err != nil {
+		return 0, err
+	}
+	if !isChunkID(ids[1:]) && isChunkID(ids[:4]) {
+		return next, nil
+	}
+	return next + 1, nil
+}
+
+// decodePCM converts little-endian integer PCM of bitDepth to values in
+// dst, the 8-bit ones unsigned as stored and the others sign-extended, and
+// returns the number converted: len(dst) or the whole samples in data,
+// whichever is less
+func decodePCM(dst []int, data []byte, bitDepth int) int {
+	width := bitDepth / 8
+	n := min(len(dst), len(data)/width)
+	for i := 0; i < n; i++ {
+		b := data[i*width:]
+		switch width {
+		case 1:
+			dst[i] = int(b[0])
+		case 2:
+			dst[i] = int(int16(binary.LittleEndian.Uint16(b)))
+		case 3:
+			dst[i] = int(int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24) >> 8)
+		case 4:
+			dst[i] = int(int32(binary.LittleEndian.Uint32(b)))
+		}
+	}
+	return n
+}
+
+// decodeWavData decodes the whole frames in the data chunk of wavBytes
+// found by inspectWav
+func decodeWavData(wavBytes []byte, header *wavHeader) []int {
+	frame := int64(header.channels * header.bitDepth / 8)
+	data := wavBytes[header.dataOffset : header.dataOffset+header.dataSize/frame*frame]
+	samples := make([]int, len(data)/(header.bitDepth/8))
+	decodePCM(samples, data, header.bitDepth)
+	return samples
+}
+
+// wavPCM reads the whole frames in the data chunk found by inspectWav as
+// decoded samples
+type wavPCM struct {
+	r      io.ReadSeeker
+	header *wavHeader
+	// Bytes of audio and those left to read
+	size, left int64
+	buf        []byte
+}
+
+// newWavPCM prepares to read the data chunk of header from r
+func newWavPCM(r io.ReadSeeker, header *wavHeader) (*wavPCM, error) {
+	frame := int64(header.channels * header.bitDepth / 8)
+	p := &wavPCM{r: r, header: header, size: header.dataSize / frame * frame}
+	return p, p.rewind()
+}
+
+// samples returns the number of samples in the data chunk
+func (p *wavPCM) samples() int {
+	return int(p.size / int64(p.header.bitDepth/8))
+}
+
+// read decodes the next samples into dst and returns how many it decoded,
+// 0 at the end of the data
+func (p *wavPCM) read(dst []int) (int, error) {
+	width := p.header.bitDepth / 8
+	want := min(int64(len(dst)*width), p.left)
+	if int64(cap(p.buf)) < want {
+		p.buf = make([]byte, want)
+	}
+	n, err := io.ReadFull(p.r, p.buf[:want])
+	if err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	p.left -= int64(n)
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+	return decodePCM(dst, p.buf[:n], p.header.bitDepth), nil
+}
+
+// rewind returns to the start of the data
+func (p *wavPCM) rewind() error {
+	p.left = p.size
+	_, err := p.r.Seek(p.header.dataOffset, io.SeekStart)
+	return err
+}
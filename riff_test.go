@@ -0,0 +1,116 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"slices"
+	"testing"
+)
+
+// rawChunk builds a chunk, with the pad byte after an odd payload unless
+// omitted
+func rawChunk(id string, size uint32, payload []byte, pad bool) []byte {
+	chunk := append([]byte(id), binary.LittleEndian.AppendUint32(nil, size)...)
+	chunk = append(chunk, payload...)
+	if pad && len(payload)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+// riffFile builds a WAVE file declaring riffSize from chunks
+func riffFile(riffSize uint32, chunks ...[]byte) []byte {
+	file := append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, riffSize)...)
+	file = append(file, "WAVE"...)
+	for _, chunk := range chunks {
+		file = append(file, chunk...)
+	}
+	return file
+}
+
+func TestUnusualRiffLayouts(t *testing.T) {
+	// 1601 frames, so the 8-bit data chunk is odd-sized
+	samples := sineWave(1601, 440, 16000, 0.5)
+	plain16 := encodeWavDepth(t, samples, 16000, 16, 1)
+	plain8 := encodeWavDepth(t, samples, 16000, 8, 1)
+	// go-audio writes the fmt payload at 20 and the audio at 44
+	fmt16, pcm16 := plain16[20:36], plain16[44:]
+	fmt8, pcm8 := plain8[20:36], plain8[44:]
+	size := func(b []byte) uint32 { return uint32(len(b)) }
+	fmtChunk := rawChunk("fmt ", 16, fmt16, true)
+	dataChunk := rawChunk("data", size(pcm16), pcm16, true)
+	list := rawChunk("LIST", 13, []byte("INFOISFT\x01\x00\x00\x00x"), true)
+
+	for _, tc := range []struct {
+		name  string
+		plain []byte
+		file  []byte
+	}{
+		{"LIST before fmt", plain16, riffFile(0, list, fmtChunk, dataChunk)},
+		{"odd JUNK before fmt", plain16, riffFile(0, rawChunk("JUNK", 3, []byte{0, 0, 0}, true), fmtChunk, dataChunk)},
+		{"odd chunk missing its pad byte", plain16, riffFile(0, rawChunk("bext", 3, []byte{1, 2, 3}, false), fmtChunk, dataChunk)},
+		{"odd chunk between fmt and data", plain16, riffFile(0, fmtChunk, rawChunk("cue ", 5, []byte{1, 2, 3, 4, 5}, true), dataChunk)},
+		{"18-byte fmt and fact", plain16, riffFile(0, rawChunk("fmt ", 18, append(append([]byte(nil), fmt16...), 0, 0), true),
+			rawChunk("fact", 4, binary.LittleEndian.AppendUint32(nil, 1601), true), dataChunk)},
+		{"data before fmt", plain16, riffFile(0, dataChunk, fmtChunk)},
+		{"trailing metadata", plain16, riffFile(0, fmtChunk, dataChunk, list, rawChunk("id3 ", 10, []byte("ID3\x03\x00\x00\x00\x00\x00\x00"), true))},
+		{"RIFF size of 0xFFFFFFFF", plain16, riffFile(0xFFFFFFFF, fmtChunk, dataChunk)},
+		{"data size of 0", plain16, riffFile(0, fmtChunk, rawChunk("data", 0, pcm16, true))},
+		{"data size of 0xFFFFFFFF", plain16, riffFile(0, fmtChunk, rawChunk("data", 0xFFFFFFFF, pcm16, true))},
+		{"odd 8-bit data and trailing LIST", plain8, riffFile(0, rawChunk("fmt ", 16, fmt8, true), rawChunk("data", size(pcm8), pcm8, true), list)},
+		{"odd 8-bit data missing its pad byte", plain8, riffFile(0, rawChunk("fmt ", 16, fmt8, true), rawChunk("data", size(pcm8), pcm8, false), list)},
+	} {
+		want, err := ConvertWavBytesToUlaw(tc.plain, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ConvertWavBytesToUlaw(tc.file, nil)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: %d bytes differ from the plain file's %d", tc.name, len(got), len(want))
+		}
+
+		var wantStream, gotStream bytes.Buffer
+		if err := ConvertWavStreamToUlaw(bytes.NewReader(tc.plain), &wantStream, nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := ConvertWavStreamToUlaw(bytes.NewReader(tc.file), &gotStream, nil); err != nil {
+			t.Errorf("%s: streaming: %v", tc.name, err)
+		} else if !bytes.Equal(gotStream.Bytes(), wantStream.Bytes()) {
+			t.Errorf("%s: streamed output differs from the plain file's", tc.name)
+		}
+
+		if stats, err := AnalyzeWav(tc.file); err != nil {
+			t.Errorf("%s: analyze: %v", tc.name, err)
+		} else if stats.Duration != 1601*62500 {
+			t.Errorf("%s: analyzed duration %v, want 1601 frames at 16 kHz", tc.name, stats.Duration)
+		}
+		if estimate, err := EstimateWav(bytes.NewReader(tc.file), nil); err != nil {
+			t.Errorf("%s: estimate: %v", tc.name, err)
+		} else if estimate.OutputSamples != len(want) {
+			t.Errorf("%s: estimated %d samples, want %d", tc.name, estimate.OutputSamples, len(want))
+		}
+	}
+}
+
+func TestDecodePCM(t *testing.T) {
+	for _, tc := range []struct {
+		bitDepth int
+		data     []byte
+		want     []int
+	}{
+		{8, []byte{0x00, 0x80, 0xFF}, []int{0, 128, 255}},
+		{16, []byte{0x00, 0x80, 0xFF, 0x7F, 0xFF}, []int{-32768, 32767}},
+		{24, []byte{0x00, 0x00, 0x80, 0xFF, 0xFF, 0xFF}, []int{-8388608, -1}},
+		{32, []byte{0xFF, 0xFF, 0xFF, 0x7F}, []int{2147483647}},
+	} {
+		got := make([]int, 4)
+		n := decodePCM(got, tc.data, tc.bitDepth)
+		if n != len(tc.want) || !slices.Equal(got[:n], tc.want) {
+			t.Errorf("%d-bit: got %v, want %v", tc.bitDepth, got[:n], tc.want)
+		}
+	}
+}
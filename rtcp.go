@@ -0,0 +1,179 @@
+package wav2ulaw
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// RTCP packet types (RFC 3550 section 12.1)
+const (
+	rtcpTypeSR   = 200
+	rtcpTypeRR   = 201
+	rtcpTypeSDES = 202
+	rtcpTypeBye  = 203
+)
+
+// Seconds from the NTP epoch, 1900, to the Unix epoch
+const ntpUnixOffset = 2208988800
+
+// RTCPSenderReport is the sender information of an RTCP sender report
+// (RFC 3550 section 6.4.1), tying the RTP timestamps of a stream to wall
+// clock time and counting what has been sent
+type RTCPSenderReport struct {
+	SSRC uint32
+	// Wall clock time the report describes
+	NTPTime time.Time
+	// RTP timestamp of the same instant as NTPTime
+	RTPTimestamp uint32
+	// Packets and payload octets sent since the start of the stream
+	PacketCount uint32
+	OctetCount  uint32
+}
+
+// Marshal returns the report as a compound RTCP packet: the sender report,
+// without reception blocks since the sender receives nothing, followed by
+// the source description with cname that every compound packet must carry
+func (r *RTCPSenderReport) Marshal(cname string) []byte {
+	packet := make([]byte, 28)
+	packet[0] = 2 << 6
+	packet[1] = rtcpTypeSR
+	binary.BigEndian.PutUint16(packet[2:], 6)
+	binary.BigEndian.PutUint32(packet[4:], r.SSRC)
+	binary.BigEndian.PutUint64(packet[8:], ntpTime(r.NTPTime))
+	binary.BigEndian.PutUint32(packet[16:], r.RTPTimestamp)
+	binary.BigEndian.PutUint32(packet[20:], r.PacketCount)
+	binary.BigEndian.PutUint32(packet[24:], r.OctetCount)
+	return append(packet, rtcpSourceDescription(r.SSRC, cname)...)
+}
+
+// rtcpSourceDescription returns an SDES packet giving the canonical name of
+// ssrc. Names are cut to the 255 bytes an item can hold.
+func rtcpSourceDescription(ssrc uint32, cname string) []byte {
+	if len(cname) > 255 {
+		cname = cname[:255]
+	}
+	// SSRC, the CNAME item and at least one null byte ending the item list,
+	// padded to a multiple of four bytes
+	chunk := 4 + 2 + len(cname) + 1
+	chunk += (4 - chunk%4) % 4
+	packet := make([]byte, 4+chunk)
+	packet[0] = 2<<6 | 1
+	packet[1] = rtcpTypeSDES
+	binary.BigEndian.PutUint16(packet[2:], uint16(len(packet)/4-1))
+	binary.BigEndian.PutUint32(packet[4:], ssrc)
+	packet[8] = 1 // CNAME
+	packet[9] = byte(len(cname))
+	copy(packet[10:], cname)
+	return packet
+}
+
+// MarshalRTCPBye returns a compound RTCP packet announcing that ssrc has
+// left the session: a sender report of the stream's final counts, its
+// source description and a BYE packet
+func MarshalRTCPBye(report *RTCPSenderReport, cname string) []byte {
+	bye := make([]byte, 8)
+	bye[0] = 2<<6 | 1
+	bye[1] = rtcpTypeBye
+	binary.BigEndian.PutUint16(bye[2:], 1)
+	binary.BigEndian.PutUint32(bye[4:], report.SSRC)
+	return append(report.Marshal(cname), bye...)
+}
+
+// RTCPReportBlock is a receiver's report on one source, carried by RTCP
+// receiver and sender reports (RFC 3550 section 6.4.1)
+type RTCPReportBlock struct {
+	// Receiver sending the report and source it is about
+	Reporter uint32
+	SSRC     uint32
+	// Fraction of packets lost since the previous report, out of 256
+	FractionLost uint8
+	// Packets lost since the start of the stream, negative when duplicates
+	// outnumber losses
+	CumulativeLost int32
+	// Highest sequence number received, extended with the count of wraps
+	HighestSequence uint32
+	// Interarrival jitter in timestamp units
+	Jitter uint32
+	// Middle 32 bits of the NTP time of the last sender report received, and
+	// the delay since, in 1/65536 s; both are 0 before any report arrived
+	LastSR           uint32
+	DelaySinceLastSR uint32
+}
+
+// Loss returns the fraction of packets lost since the previous report
+func (b *RTCPReportBlock) Loss() float64 {
+	return float64(b.FractionLost) / 256
+}
+
+// RoundTrip returns the round-trip time to the receiver given the arrival
+// time of the report, or 0 when it has not received a sender report yet
+func (b *RTCPReportBlock) RoundTrip(arrival time.Time) time.Duration {
+	if b.LastSR == 0 {
+		return 0
+	}
+	units := uint32(ntpTime(arrival)>>16) - b.LastSR - b.DelaySinceLastSR
+	if int32(units) < 0 {
+		// Clocks or reports out of step
+		return 0
+	}
+	return time.Duration(units) * time.Second / 65536
+}
+
+// ParseRTCPReports returns the report blocks of the receiver and sender
+// reports in a compound RTCP packet. Other packet types are skipped.
+func ParseRTCPReports(data []byte) ([]RTCPReportBlock, error) {
+	var blocks []RTCPReportBlock
+	for len(data) > 0 {
+		if len(data) < 4 {
+			return nil, fmt.Errorf("RTCP packet too short: %d bytes", len(data))
+		}
+		if data[0]>>6 != 2 {
+			return nil, fmt.Errorf("unsupported RTCP version %d", data[0]>>6)
+		}
+		size := 4 * (int(binary.BigEndian.Uint16(data[2:])) + 1)
+		if size > len(data) {
+			return nil, fmt.Errorf("RTCP packet of %d bytes truncated to %d", size, len(data))
+		}
+		packet := data[:size]
+		data = data[size:]
+
+		var offset int
+		switch packet[1] {
+		case rtcpTypeRR:
+			offset = 8
+		case rtcpTypeSR:
+			offset = 28
+		default:
+			continue
+		}
+		count := int(packet[0] & 0x1F)
+		if len(packet) < offset+24*count {
+			return nil, fmt.Errorf("RTCP report too short for %d blocks", count)
+		}
+		reporter := binary.BigEndian.Uint32(packet[4:])
+		for i := 0; i < count; i++ {
+			block := packet[offset+24*i:]
+			blocks = append(blocks, RTCPReportBlock{
+				Reporter:     reporter,
+				SSRC:         binary.BigEndian.Uint32(block),
+				FractionLost: block[4],
+				// 24-bit signed, sign-extended through the shift
+				CumulativeLost:   int32(binary.BigEndian.Uint32(block[4:])<<8) >> 8,
+				HighestSequence:  binary.BigEndian.Uint32(block[8:]),
+				Jitter:           binary.BigEndian.Uint32(block[12:]),
+				LastSR:           binary.BigEndian.Uint32(block[16:]),
+				DelaySinceLastSR: binary.BigEndian.Uint32(block[20:]),
+			})
+		}
+	}
+	return blocks, nil
+}
+
+// ntpTime returns t as a 64-bit NTP timestamp, seconds since 1900 in the
+// high 32 bits and the fraction of a second in the low 32
+func ntpTime(t time.Time) uint64 {
+	seconds := uint64(t.Unix() + ntpUnixOffset)
+	fraction := uint64(t.Nanosecond()) << 32 / uint64(time.Second)
+	return seconds<<32 | fraction
+}
@@ -0,0 +1,81 @@
+package wav2ulaw
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func TestRTCPSenderReport(t *testing.T) {
+	now := time.Unix(1700000000, 500_000_000)
+	report := &RTCPSenderReport{SSRC: 0x1234, NTPTime: now, RTPTimestamp: 8000, PacketCount: 50, OctetCount: 8000}
+	packet := report.Marshal("wav2ulaw@host")
+	if len(packet)%4 != 0 {
+		t.Fatalf("compound packet of %d bytes is not 32-bit aligned", len(packet))
+	}
+	if packet[1] != rtcpTypeSR || binary.BigEndian.Uint32(packet[4:]) != 0x1234 {
+		t.Fatalf("unexpected SR header % x", packet[:8])
+	}
+	if got := binary.BigEndian.Uint32(packet[8:]); got != 1700000000+ntpUnixOffset {
+		t.Errorf("NTP seconds %d", got)
+	}
+	if got := binary.BigEndian.Uint32(packet[12:]); got != 1<<31 {
+		t.Errorf("NTP fraction %#x, want half a second", got)
+	}
+	if count := binary.BigEndian.Uint32(packet[20:]); count != 50 {
+		t.Errorf("packet count %d", count)
+	}
+	sdes := packet[28:]
+	if sdes[1] != rtcpTypeSDES || 4*(int(binary.BigEndian.Uint16(sdes[2:]))+1) != len(sdes) {
+		t.Fatalf("unexpected SDES header % x", sdes[:4])
+	}
+	if cname := string(sdes[10 : 10+sdes[9]]); cname != "wav2ulaw@host" {
+		t.Errorf("CNAME %q", cname)
+	}
+	// Sender reports without reception blocks parse to nothing
+	if blocks, err := ParseRTCPReports(packet); err != nil || len(blocks) != 0 {
+		t.Errorf("got %v, %v", blocks, err)
+	}
+
+	bye := MarshalRTCPBye(report, "wav2ulaw@host")
+	if last := bye[len(bye)-8:]; last[1] != rtcpTypeBye || binary.BigEndian.Uint32(last[4:]) != 0x1234 {
+		t.Errorf("unexpected BYE % x", last)
+	}
+}
+
+func TestParseRTCPReports(t *testing.T) {
+	sent := time.Unix(1700000000, 0)
+	lastSR := uint32(ntpTime(sent) >> 16)
+	rr := make([]byte, 32)
+	rr[0] = 2<<6 | 1
+	rr[1] = rtcpTypeRR
+	binary.BigEndian.PutUint16(rr[2:], 7)
+	binary.BigEndian.PutUint32(rr[4:], 0xBEEF)
+	block := rr[8:]
+	binary.BigEndian.PutUint32(block, 0x1234)
+	binary.BigEndian.PutUint32(block[4:], 64<<24|0xFFFFFE) // 25% lost, -2 cumulative
+	binary.BigEndian.PutUint32(block[8:], 70000)
+	binary.BigEndian.PutUint32(block[12:], 40)
+	binary.BigEndian.PutUint32(block[16:], lastSR)
+	binary.BigEndian.PutUint32(block[20:], 65536/10) // held for 100 ms
+
+	blocks, err := ParseRTCPReports(rr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 1 {
+		t.Fatalf("got %d blocks, want 1", len(blocks))
+	}
+	b := blocks[0]
+	if b.Reporter != 0xBEEF || b.SSRC != 0x1234 || b.Loss() != 0.25 || b.CumulativeLost != -2 || b.HighestSequence != 70000 || b.Jitter != 40 {
+		t.Errorf("unexpected block %+v", b)
+	}
+	rtt := b.RoundTrip(sent.Add(150 * time.Millisecond))
+	if rtt < 49*time.Millisecond || rtt > 51*time.Millisecond {
+		t.Errorf("round trip %v, want 50ms", rtt)
+	}
+
+	if _, err := ParseRTCPReports(rr[:20]); err == nil {
+		t.Error("expected an error for a truncated report")
+	}
+}
@@ -0,0 +1,348 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"slices"
+	"sort"
+)
+
+const (
+	// RTPPayloadTypePCMU is the static RTP payload type of G.711 u-law
+	RTPPayloadTypePCMU = 0
+	// RTPPayloadTypePCMA is the static RTP payload type of G.711 A-law
+	RTPPayloadTypePCMA = 8
+	// Size of the fixed RTP header without CSRCs
+	rtpHeaderSize = 12
+	// Largest payload that keeps a packet within a typical Ethernet MTU
+	maxRTPPayload = 1400
+	// u-law code of digital silence, used to pad the last packet
+	ulawSilence = 0xFF
+)
+
+// RTPMarker selects the packets an RTPPacketizer sets the marker bit on
+type RTPMarker int
+
+const (
+	// RTPMarkerFirst marks the first packet of the stream
+	RTPMarkerFirst RTPMarker = iota
+	// RTPMarkerTalkspurt marks the first packet of the stream and the first
+	// packet of every talkspurt, the first one with sound after silence, as
+	// RFC 3551 does for senders using silence suppression
+	RTPMarkerTalkspurt
+	// RTPMarkerNever leaves the marker bit clear
+	RTPMarkerNever
+)
+
+// Largest sample magnitude of a packet counted as silence between
+// talkspurts, about -50 dBFS
+const rtpSilenceLevel = 100
+
+// RTPOptions sets the header fields of the packets of an RTPPacketizer,
+// which some SBCs are strict about
+type RTPOptions struct {
+	// Audio per packet in milliseconds
+	PtimeMs int
+	// Payload type, 0 (PCMU) unless the SDP maps u-law to a dynamic type
+	PayloadType uint8
+	SSRC        uint32
+	// Sequence number and timestamp of the first packet, random when nil as
+	// RFC 3550 recommends
+	Sequence  *uint16
+	Timestamp *uint32
+	Marker    RTPMarker
+}
+
+// RTPPacketizer splits 8 kHz u-law audio into RTP packets (RFC 3550).
+// Sequence numbers and timestamps continue across calls, so one packetizer
+// serves a whole stream.
+type RTPPacketizer struct {
+	ssrc             uint32
+	payloadType      uint8
+	marker           RTPMarker
+	sequence         uint16
+	timestamp        uint32
+	samplesPerPacket int
+	started          bool
+	// Whether the last packet was silence, for RTPMarkerTalkspurt
+	silent bool
+}
+
+// NewRTPPacketizer creates a packetizer sending ptimeMs of audio per packet
+// with payload type 0 from the given synchronization source. The initial
+// sequence number and timestamp are random, as RFC 3550 recommends.
+func NewRTPPacketizer(ptimeMs int, ssrc uint32) (*RTPPacketizer, error) {
+	return NewRTPPacketizerWithOptions(RTPOptions{PtimeMs: ptimeMs, SSRC: ssrc})
+}
+
+// NewRTPPacketizerWithOptions creates a packetizer with the header fields
+// set by opts
+func NewRTPPacketizerWithOptions(opts RTPOptions) (*RTPPacketizer, error) {
+	samples := opts.PtimeMs * 8
+	if opts.PtimeMs <= 0 || samples > maxRTPPayload {
+		return nil, fmt.Errorf("invalid ptime %d ms, must be between 1 and %d", opts.PtimeMs, maxRTPPayload/8)
+	}
+	if opts.PayloadType > 127 {
+		return nil, fmt.Errorf("invalid payload type %d, must be between 0 and 127", opts.PayloadType)
+	}
+	if opts.Marker < RTPMarkerFirst || opts.Marker > RTPMarkerNever {
+		return nil, fmt.Errorf("invalid marker mode %d", opts.Marker)
+	}
+	p := &RTPPacketizer{
+		ssrc:             opts.SSRC,
+		payloadType:      opts.PayloadType,
+		marker:           opts.Marker,
+		sequence:         uint16(rand.Uint32()),
+		timestamp:        rand.Uint32(),
+		samplesPerPacket: samples,
+	}
+	if opts.Sequence != nil {
+		p.sequence = *opts.Sequence
+	}
+	if opts.Timestamp != nil {
+		p.timestamp = *opts.Timestamp
+	}
+	return p, nil
+}
+
+// SamplesPerPacket returns the number of u-law samples carried per packet
+func (p *RTPPacketizer) SamplesPerPacket() int {
+	return p.samplesPerPacket
+}
+
+// Packetize returns the RTP packets carrying ulaw. The last packet is padded
+// with silence when ulaw does not fill it.
+func (p *RTPPacketizer) Packetize(ulaw []byte) [][]byte {
+	var packets [][]byte
+	for start := 0; start < len(ulaw); start += p.samplesPerPacket {
+		payload := ulaw[start:min(start+p.samplesPerPacket, len(ulaw))]
+
+		packet := make([]byte, rtpHeaderSize, rtpHeaderSize+p.samplesPerPacket)
+		packet[0] = 2 << 6 // Version 2, no padding, extension or CSRCs
+		packet[1] = p.payloadType
+		if p.startsTalkspurt(payload) {
+			packet[1] |= 0x80
+		}
+		binary.BigEndian.PutUint16(packet[2:], p.sequence)
+		binary.BigEndian.PutUint32(packet[4:], p.timestamp)
+		binary.BigEndian.PutUint32(packet[8:], p.ssrc)
+		packet = append(packet, payload...)
+		packet = append(packet, bytes.Repeat([]byte{ulawSilence}, p.samplesPerPacket-len(payload))...)
+		packets = append(packets, packet)
+
+		p.sequence++
+		p.timestamp += uint32(p.samplesPerPacket)
+	}
+	return packets
+}
+
+// startsTalkspurt reports whether the packet carrying payload gets the
+// marker bit, which flags the start of a talkspurt
+func (p *RTPPacketizer) startsTalkspurt(payload []byte) bool {
+	first := !p.started
+	p.started = true
+	switch p.marker {
+	case RTPMarkerNever:
+		return false
+	case RTPMarkerTalkspurt:
+		wasSilent := p.silent
+		p.silent = isSilentUlaw(payload)
+		return first || (wasSilent && !p.silent)
+	}
+	return first
+}
+
+// isSilentUlaw reports whether no sample of ulaw exceeds rtpSilenceLevel
+func isSilentUlaw(ulaw []byte) bool {
+	for _, code := range ulaw {
+		if v := ulawDecodeTable[code]; v > rtpSilenceLevel || v < -rtpSilenceLevel {
+			return false
+		}
+	}
+	return true
+}
+
+// RTPPacket is the part of an RTP packet needed to reassemble audio
+type RTPPacket struct {
+	PayloadType uint8
+	Marker      bool
+	Sequence    uint16
+	Timestamp   uint32
+	SSRC        uint32
+	Payload     []byte
+}
+
+// ParseRTPPacket parses an RTP packet, skipping CSRCs, the header extension
+// and padding. Payload refers to data.
+func ParseRTPPacket(data []byte) (*RTPPacket, error) {
+	if len(data) < rtpHeaderSize {
+		return nil, fmt.Errorf("RTP packet too short: %d bytes", len(data))
+	}
+	if data[0]>>6 != 2 {
+		return nil, fmt.Errorf("unsupported RTP version %d", data[0]>>6)
+	}
+	p := &RTPPacket{
+		PayloadType: data[1] & 0x7F,
+		Marker:      data[1]&0x80 != 0,
+		Sequence:    binary.BigEndian.Uint16(data[2:]),
+		Timestamp:   binary.BigEndian.Uint32(data[4:]),
+		SSRC:        binary.BigEndian.Uint32(data[8:]),
+	}
+
+	headerSize := rtpHeaderSize + 4*int(data[0]&0x0F)
+	if len(data) < headerSize {
+		return nil, fmt.Errorf("RTP packet too short for its CSRC list")
+	}
+	payload := data[headerSize:]
+	if data[0]&0x10 != 0 {
+		if len(payload) < 4 {
+			return nil, fmt.Errorf("RTP packet too short for its header extension")
+		}
+		extension := 4 + 4*int(binary.BigEndian.Uint16(payload[2:]))
+		if len(payload) < extension {
+			return nil, fmt.Errorf("RTP packet too short for its header extension")
+		}
+		payload = payload[extension:]
+	}
+	if data[0]&0x20 != 0 && len(payload) > 0 {
+		// The last byte counts the padding, itself included
+		padding := int(payload[len(payload)-1])
+		if padding > len(payload) {
+			return nil, fmt.Errorf("invalid RTP padding")
+		}
+		payload = payload[:len(payload)-padding]
+	}
+	p.Payload = payload
+	return p, nil
+}
+
+// RTPStats counts what an RTPDepacketizer received
+type RTPStats struct {
+	// Packets whose audio was used
+	Packets int
+	// Packets received more than once
+	Duplicates int
+	// Packets missing from the sequence number range
+	Lost int
+	// Packets of other streams or payload types
+	Ignored int
+}
+
+// RTPDepacketizer reassembles G.711 RTP packets (payload types 0 and 8) into
+// ordered 8 kHz audio. Packets are placed by their timestamp, so late,
+// reordered and repeated packets end up where they belong, and audio that
+// never arrived is filled according to a GapFill mode. The first packet
+// selects the stream; packets from other SSRCs are ignored.
+type RTPDepacketizer struct {
+	started bool
+	ssrc    uint32
+	firstTs uint32
+	minSeq  int64
+	maxSeq  int64
+	// Decoded payloads keyed by timestamp relative to the first packet
+	payloads map[int64][]int16
+	stats    RTPStats
+}
+
+// NewRTPDepacketizer creates an empty depacketizer
+func NewRTPDepacketizer() *RTPDepacketizer {
+	return &RTPDepacketizer{payloads: make(map[int64][]int16)}
+}
+
+// Push adds a received packet
+func (d *RTPDepacketizer) Push(packet *RTPPacket) {
+	if packet.PayloadType != RTPPayloadTypePCMU && packet.PayloadType != RTPPayloadTypePCMA {
+		d.stats.Ignored++
+		return
+	}
+	if !d.started {
+		d.started = true
+		d.ssrc = packet.SSRC
+		d.firstTs = packet.Timestamp
+		d.minSeq, d.maxSeq = int64(packet.Sequence), int64(packet.Sequence)
+	} else if packet.SSRC != d.ssrc {
+		d.stats.Ignored++
+		return
+	}
+
+	// Unwrap the 16-bit sequence number around the highest one seen and the
+	// timestamp around the first, so wraparound and reordering both work
+	seq := d.maxSeq + int64(int16(packet.Sequence-uint16(d.maxSeq)))
+	d.minSeq = min(d.minSeq, seq)
+	d.maxSeq = max(d.maxSeq, seq)
+	offset := int64(int32(packet.Timestamp - d.firstTs))
+
+	if _, ok := d.payloads[offset]; ok {
+		d.stats.Duplicates++
+		return
+	}
+	if packet.PayloadType == RTPPayloadTypePCMA {
+		d.payloads[offset] = decodeAlawSamples(packet.Payload)
+	} else {
+		d.payloads[offset] = decodeUlawSamples(packet.Payload)
+	}
+	d.stats.Packets++
+}
+
+// Stats returns the packet counts so far
+func (d *RTPDepacketizer) Stats() RTPStats {
+	stats := d.stats
+	if d.started {
+		stats.Lost = int(d.maxSeq-d.minSeq+1) - d.stats.Packets
+	}
+	return stats
+}
+
+// Samples returns the received audio in timestamp order. Each 20 ms frame
+// that received nothing is filled according to fill, silence stands in for
+// the missing part of partially received frames.
+func (d *RTPDepacketizer) Samples(fill GapFill) []int16 {
+	if len(d.payloads) == 0 {
+		return nil
+	}
+	offsets := make([]int64, 0, len(d.payloads))
+	for offset := range d.payloads {
+		offsets = append(offsets, offset)
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+
+	start := offsets[0]
+	end := start
+	for _, offset := range offsets {
+		end = max(end, offset+int64(len(d.payloads[offset])))
+	}
+	samples := make([]int16, end-start)
+	received := make([]bool, end-start)
+	for _, offset := range offsets {
+		for i, sample := range d.payloads[offset] {
+			samples[offset-start+int64(i)] = sample
+			received[offset-start+int64(i)] = true
+		}
+	}
+
+	var frames [][]int16
+	for i := 0; i < len(samples); i += UlawFrameSize {
+		frameEnd := min(i+UlawFrameSize, len(samples))
+		if slices.Contains(received[i:frameEnd], true) {
+			frames = append(frames, samples[i:frameEnd])
+		} else {
+			frames = append(frames, nil)
+		}
+	}
+	return fillGaps(frames, fill)
+}
+
+// ConvertToWav returns the received audio as WAV bytes at sampleRate
+func (d *RTPDepacketizer) ConvertToWav(sampleRate uint32, windowSize int, fill GapFill) ([]byte, error) {
+	samples := d.Samples(fill)
+
+	// Resample if needed
+	if sampleRate != 8000 {
+		window := makeWindow(WindowBlackman, windowSize*2+1, 0)
+		samples = resamplePCM16(samples, 8000, float64(sampleRate), windowSize, window)
+	}
+
+	return encodeWavPCM16(samples, int(sampleRate))
+}
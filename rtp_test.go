@@ -0,0 +1,157 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestRTPPacketizer(t *testing.T) {
+	p, err := NewRTPPacketizer(20, 0x12345678)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Two and a half packets of audio
+	packets := p.Packetize(make([]byte, 400))
+	if len(packets) != 3 {
+		t.Fatalf("expected 3 packets, got %d", len(packets))
+	}
+
+	firstSeq := binary.BigEndian.Uint16(packets[0][2:])
+	firstTs := binary.BigEndian.Uint32(packets[0][4:])
+	for i, packet := range packets {
+		if len(packet) != 12+160 {
+			t.Fatalf("packet %d: expected 172 bytes, got %d", i, len(packet))
+		}
+		if packet[0] != 0x80 {
+			t.Errorf("packet %d: expected version 2 header byte, got %#x", i, packet[0])
+		}
+		if marker, pt := packet[1]&0x80 != 0, packet[1]&0x7F; marker != (i == 0) || pt != RTPPayloadTypePCMU {
+			t.Errorf("packet %d: marker %v, payload type %d", i, marker, pt)
+		}
+		if seq := binary.BigEndian.Uint16(packet[2:]); seq != firstSeq+uint16(i) {
+			t.Errorf("packet %d: sequence %d, expected %d", i, seq, firstSeq+uint16(i))
+		}
+		if ts := binary.BigEndian.Uint32(packet[4:]); ts != firstTs+uint32(160*i) {
+			t.Errorf("packet %d: timestamp %d, expected %d", i, ts, firstTs+uint32(160*i))
+		}
+		if ssrc := binary.BigEndian.Uint32(packet[8:]); ssrc != 0x12345678 {
+			t.Errorf("packet %d: SSRC %#x", i, ssrc)
+		}
+	}
+	if last := packets[2]; last[12+79] != 0 || last[12+80] != ulawSilence {
+		t.Error("expected the last packet to be padded with silence after 80 samples")
+	}
+
+	// Numbering continues on the next call
+	next := p.Packetize(make([]byte, 160))
+	if seq := binary.BigEndian.Uint16(next[0][2:]); seq != firstSeq+3 {
+		t.Errorf("expected sequence %d to continue, got %d", firstSeq+3, seq)
+	}
+
+	if _, err := NewRTPPacketizer(0, 1); err == nil {
+		t.Error("expected error for zero ptime")
+	}
+}
+
+func TestRTPPacketizerOptions(t *testing.T) {
+	seq, ts := uint16(65535), uint32(5000)
+	p, err := NewRTPPacketizerWithOptions(RTPOptions{PtimeMs: 10, PayloadType: 96, SSRC: 9, Sequence: &seq, Timestamp: &ts, Marker: RTPMarkerTalkspurt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Silence, two packets of sound, silence and sound again
+	var input []byte
+	for _, code := range []byte{ulawSilence, 0x10, 0x10, ulawSilence, 0x10} {
+		input = append(input, bytes.Repeat([]byte{code}, 80)...)
+	}
+	packets := p.Packetize(input)
+	for i, want := range []bool{true, true, false, false, true} {
+		packet, err := ParseRTPPacket(packets[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if packet.Marker != want {
+			t.Errorf("packet %d: marker %v, want %v", i, packet.Marker, want)
+		}
+		if packet.PayloadType != 96 || packet.Sequence != seq+uint16(i) || packet.Timestamp != ts+uint32(80*i) || len(packet.Payload) != 80 {
+			t.Errorf("packet %d: payload type %d, sequence %d, timestamp %d, %d bytes", i, packet.PayloadType, packet.Sequence, packet.Timestamp, len(packet.Payload))
+		}
+	}
+
+	p, _ = NewRTPPacketizerWithOptions(RTPOptions{PtimeMs: 20, Marker: RTPMarkerNever})
+	if packet, _ := ParseRTPPacket(p.Packetize(input[:160])[0]); packet.Marker {
+		t.Error("expected no marker bit with RTPMarkerNever")
+	}
+	if _, err := NewRTPPacketizerWithOptions(RTPOptions{PtimeMs: 20, PayloadType: 128}); err == nil {
+		t.Error("expected error for payload type 128")
+	}
+}
+
+func TestRTPDepacketizerReordersAndFillsLoss(t *testing.T) {
+	p, _ := NewRTPPacketizer(20, 42)
+	// Both counters wrap within the stream
+	p.sequence = 65534
+	p.timestamp = 0xFFFFFF00
+	input := encodeUlawSamples(sineWave(8000, 440, 8000, 0.5))
+	packets := p.Packetize(input)
+
+	d := NewRTPDepacketizer()
+	for i := len(packets) - 1; i >= 0; i-- {
+		// Packet 10 is lost
+		if i == 10 {
+			continue
+		}
+		packet, err := ParseRTPPacket(packets[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		d.Push(packet)
+		if i == 20 {
+			d.Push(packet)
+		}
+	}
+	// Another stream on the same port
+	other, _ := NewRTPPacketizer(20, 7)
+	packet, _ := ParseRTPPacket(other.Packetize(input[:160])[0])
+	d.Push(packet)
+
+	stats := d.Stats()
+	if stats.Packets != 49 || stats.Duplicates != 1 || stats.Lost != 1 || stats.Ignored != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	output := d.Samples(GapFillSilence)
+	if len(output) != 8000 {
+		t.Fatalf("expected 8000 samples, got %d", len(output))
+	}
+	expected := decodeUlawSamples(input)
+	for i := range output {
+		want := expected[i]
+		if i >= 1600 && i < 1760 {
+			want = 0
+		}
+		if output[i] != want {
+			t.Fatalf("sample %d: got %d, want %d", i, output[i], want)
+		}
+	}
+}
+
+func TestParseRTPPacket(t *testing.T) {
+	// CSRC count 1, extension of one word and 2 bytes of padding
+	data := []byte{0xB1, 0x08, 0, 1, 0, 0, 0, 160, 0, 0, 0, 9, 1, 2, 3, 4, 0xBE, 0xDE, 0, 1, 5, 6, 7, 8, 0xD5, 0xD5, 0, 2}
+	packet, err := ParseRTPPacket(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if packet.PayloadType != RTPPayloadTypePCMA || packet.Sequence != 1 || packet.Timestamp != 160 || packet.SSRC != 9 {
+		t.Errorf("unexpected header %+v", packet)
+	}
+	if !bytes.Equal(packet.Payload, []byte{0xD5, 0xD5}) {
+		t.Errorf("unexpected payload %x", packet.Payload)
+	}
+
+	if _, err := ParseRTPPacket(data[:14]); err == nil {
+		t.Error("expected error for truncated CSRC list")
+	}
+}
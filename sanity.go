@@ -0,0 +1,130 @@
+package wav2ulaw
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	// Longest run of one audible level the sanity check accepts; real
+	// audio never holds a level this long
+	sanityMaxStuck = 100 * time.Millisecond
+	// Output lengths the sanity check accepts, relative to the expected one
+	sanityMinLength = 0.5
+	sanityMaxLength = 2.0
+	// 16-bit input peak above which the input is audible (about -60 dBFS)
+	sanityAudiblePeak = 33
+)
+
+// SanityError lists the signs of a broken conversion AudioConfig.SanityCheck
+// found in its output
+type SanityError struct {
+	Problems []string
+}
+
+func (e *SanityError) Error() string {
+	return "output failed the sanity check: " + strings.Join(e.Problems, "; ")
+}
+
+// audible reports whether 16-bit input holds more than near-silence
+func audible(samples []int16) bool {
+	for _, s := range samples {
+		if s > sanityAudiblePeak || s < -sanityAudiblePeak {
+			return true
+		}
+	}
+	return false
+}
+
+// expectedUlawLength is the number of 8 kHz samples a conversion of n input
+// samples at inputRate should produce with config, or 0 when AutoTrim makes
+// it unpredictable
+func expectedUlawLength(n, inputRate int, config *AudioConfig) int {
+	if config.AutoTrim != nil {
+		return 0
+	}
+	seconds := float64(n) / float64(speedRate(inputRate, config.Speed))
+	if config.Tempo > 0 {
+		seconds /= config.Tempo
+	}
+	seconds += (config.PadStart + config.PadEnd).Seconds()
+	return int(seconds * 8000)
+}
+
+// sanityWatch holds what AudioConfig.SanityCheck needs to know about the
+// input of a conversion, taken before processing
+type sanityWatch struct {
+	audible  bool
+	expected int
+}
+
+// newSanityWatch inspects the input samples at inputRate, returning nil
+// when config does not ask for the check
+func newSanityWatch(config *AudioConfig, samples []int16, inputRate int) *sanityWatch {
+	if !config.SanityCheck {
+		return nil
+	}
+	return &sanityWatch{audible: audible(samples), expected: expectedUlawLength(len(samples), inputRate, config)}
+}
+
+// check runs the sanity check on output encoded with codec, standard u-law
+// for u-law
+func (w *sanityWatch) check(output []byte, codec OutputCodec) error {
+	if w == nil {
+		return nil
+	}
+	return sanityCheck(codec.decode(output), w.audible, w.expected)
+}
+
+// sanityCheck inspects decoded 8 kHz output for signs of a broken
+// conversion: silence although the input was audible, one level held for
+// sanityMaxStuck (a DC signal, or samples that were NaN before they became
+// integers), a level that is mostly DC offset, or a length far from the
+// expected one (0 = not checked). It returns a *SanityError listing them.
+func sanityCheck(samples []int16, inputAudible bool, expected int) error {
+	var problems []string
+
+	silent := true
+	sum, sumSquares := 0.0, 0.0
+	for _, s := range samples {
+		silent = silent && s >= -maxSilentLevel && s <= maxSilentLevel
+		sum += float64(s)
+		sumSquares += float64(s) * float64(s)
+	}
+	if silent && inputAudible {
+		problems = append(problems, "output is silent although the input is not")
+	}
+
+	maxRun := int(sanityMaxStuck.Seconds() * 8000)
+	for i := 0; i < len(samples); {
+		j := i + 1
+		for j < len(samples) && samples[j] == samples[i] {
+			j++
+		}
+		if s := samples[i]; j-i >= maxRun && (s < -maxSilentLevel || s > maxSilentLevel) {
+			problems = append(problems, fmt.Sprintf("output stuck at level %d for %v at %v (DC or samples derived from NaN)", s, UlawDuration(j-i), UlawDuration(i)))
+			break
+		}
+		i = j
+	}
+
+	if n := float64(len(samples)); n > 0 && !silent {
+		mean, rms := sum/n, math.Sqrt(sumSquares/n)
+		if math.Abs(mean) > 0.5*rms {
+			problems = append(problems, fmt.Sprintf("output is mostly DC offset (mean %.0f, RMS %.0f)", mean, rms))
+		}
+	}
+
+	if expected > 0 {
+		if ratio := float64(len(samples)) / float64(expected); ratio < sanityMinLength || ratio > sanityMaxLength {
+			problems = append(problems, fmt.Sprintf("output lasts %v, %v expected", UlawDuration(len(samples)), UlawDuration(expected)))
+		}
+	}
+
+	if len(problems) > 0 {
+		return &SanityError{Problems: problems}
+	}
+	return nil
+}
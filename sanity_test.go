@@ -0,0 +1,50 @@
+package wav2ulaw
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSanityCheck(t *testing.T) {
+	convert := func(samples []int16, rate int, change func(*AudioConfig)) error {
+		config := DefaultAudioConfig()
+		config.SanityCheck = true
+		if change != nil {
+			change(config)
+		}
+		wavBytes := encodeWavDepth(t, samples, rate, 16, 1)
+		_, err := ConvertWavBytesToUlaw(wavBytes, config)
+		return err
+	}
+	problem := func(err error, want string) {
+		t.Helper()
+		var sanityErr *SanityError
+		if !errors.As(err, &sanityErr) || !strings.Contains(err.Error(), want) {
+			t.Errorf("got %v, want a *SanityError about %q", err, want)
+		}
+	}
+
+	if err := convert(sineWave(16000, 440, 16000, 0.3), 16000, nil); err != nil {
+		t.Errorf("tone: %v", err)
+	}
+
+	dc := make([]int16, 16000)
+	for i := range dc {
+		dc[i] = 8000
+	}
+	problem(convert(dc, 16000, func(c *AudioConfig) { c.HighPassCutoff, c.NormalizePeak = 0, 0 }), "stuck")
+
+	ulaw := decodeUlawSamples(encodeUlawSamples(sineWave(8000, 440, 8000, 0.3)))
+	if err := sanityCheck(ulaw, true, 8000); err != nil {
+		t.Errorf("expected length: %v", err)
+	}
+	problem(sanityCheck(ulaw, true, 20000), "expected")
+	problem(sanityCheck(ulaw, true, 3000), "expected")
+
+	silence := make([]int16, 8000)
+	problem(sanityCheck(silence, true, 8000), "silent")
+	if err := sanityCheck(silence, false, 8000); err != nil {
+		t.Errorf("silent input: %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+package wav2ulaw
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Layout of the bext origination date and time fields
+const (
+	bextDateLayout = "2006-01-02"
+	bextTimeLayout = "15:04:05"
+)
+
+// SegmentOptions control SplitWavSegments
+type SegmentOptions struct {
+	// Length of each segment; the last one holds what is left
+	Length time.Duration
+	// Write a bext chunk to every segment whose time reference, and
+	// origination date and time when set, are offset by the start of the
+	// segment, so tools can place the segments back on a timeline
+	Timecode bool
+	// bext chunk of the first segment when Timecode is set. nil uses the
+	// bext chunk of the input, or an empty one starting at midnight.
+	Origin *Bext
+	// Wall-clock time at the start of the input. When set, it replaces the
+	// origination date, time and time reference of the origin.
+	Start time.Time
+}
+
+// SplitWavSegments cuts a WAV file into consecutive WAV files of
+// options.Length each, without decoding the audio. Every segment keeps the
+// format and the metadata of the input, with the bext chunk replaced when
+// options.Timecode is set. A file without audio returns ErrEmptyInput.
+func SplitWavSegments(wavBytes []byte, options *SegmentOptions) ([][]byte, error) {
+	if options == nil || options.Length <= 0 {
+		return nil, fmt.Errorf("segment length must be positive")
+	}
+	cut, err := newWavCutter(wavBytes)
+	if err != nil {
+		return nil, err
+	}
+	sampleRate := cut.sampleRate
+	frames := int(math.Round(options.Length.Seconds() * float64(sampleRate)))
+	if frames < 1 {
+		return nil, fmt.Errorf("segment length %v is shorter than one sample at %d Hz", options.Length, sampleRate)
+	}
+
+	origin := cut.md.Bext
+	if options.Origin != nil {
+		origin = options.Origin
+	}
+	if !options.Timecode {
+		origin = nil
+	} else if origin == nil {
+		origin = &Bext{}
+	}
+	if origin != nil && !options.Start.IsZero() {
+		stamped := *origin
+		midnight := time.Date(options.Start.Year(), options.Start.Month(), options.Start.Day(), 0, 0, 0, 0, options.Start.Location())
+		stamped.OriginationDate = options.Start.Format(bextDateLayout)
+		stamped.OriginationTime = options.Start.Format(bextTimeLayout)
+		stamped.TimeReference = uint64(math.Round(options.Start.Sub(midnight).Seconds() * float64(sampleRate)))
+		origin = &stamped
+	}
+
+	var segments [][]byte
+	for start := 0; start < cut.frames; start += frames {
+		bext := cut.md.Bext
+		if origin != nil {
+			bext = offsetBext(origin, uint64(start), sampleRate)
+		}
+		segment, err := cut.piece(start, min(start+frames, cut.frames), bext)
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, segment)
+	}
+	return segments, nil
+}
+
+// wavCutter cuts the audio of a WAV file into files of their own without
+// decoding it, each keeping the other chunks of the file
+type wavCutter struct {
+	chunks     []riffChunk
+	dataIndex  int
+	data       []byte
+	sampleRate int
+	blockAlign int
+	// Whole frames of the data chunk
+	frames int
+	md     *Metadata
+}
+
+// newWavCutter parses wavBytes for cutting. A file without audio returns
+// ErrEmptyInput.
+func newWavCutter(wavBytes []byte) (*wavCutter, error) {
+	chunks, err := parseRIFFChunks(wavBytes)
+	if err != nil {
+		return nil, err
+	}
+	cut := &wavCutter{chunks: chunks, dataIndex: -1}
+	for i, c := range chunks {
+		switch {
+		case c.id == "fmt " && len(c.data) >= 16:
+			cut.sampleRate = int(binary.LittleEndian.Uint32(c.data[4:]))
+			cut.blockAlign = int(binary.LittleEndian.Uint16(c.data[12:]))
+		case c.id == "data" && cut.dataIndex < 0:
+			cut.dataIndex = i
+		}
+	}
+	if cut.sampleRate <= 0 || cut.blockAlign <= 0 {
+		return nil, fmt.Errorf("invalid or missing fmt chunk")
+	}
+	if cut.dataIndex < 0 {
+		return nil, fmt.Errorf("missing data chunk")
+	}
+	cut.data = chunks[cut.dataIndex].data
+	cut.frames = len(cut.data) / cut.blockAlign
+	if cut.frames == 0 {
+		return nil, ErrEmptyInput
+	}
+	if cut.md, err = ReadWavMetadata(wavBytes); err != nil {
+		return nil, err
+	}
+	return cut, nil
+}
+
+// piece returns a WAV file of frames start to end with bext as its bext
+// chunk, nil for none
+func (c *wavCutter) piece(start, end int, bext *Bext) ([]byte, error) {
+	chunks := make([]riffChunk, len(c.chunks))
+	copy(chunks, c.chunks)
+	chunks[c.dataIndex].data = c.data[start*c.blockAlign : end*c.blockAlign]
+	return WriteWavMetadata(buildRIFF(chunks), &Metadata{Info: c.md.Info, Bext: bext})
+}
+
+// offsetBext returns a copy of origin describing audio that starts frames
+// samples later. The origination date and time advance with it, in whole
+// seconds, when both parse.
+func offsetBext(origin *Bext, frames uint64, sampleRate int) *Bext {
+	b := *origin
+	b.TimeReference += frames
+	originated, err := time.Parse(bextDateLayout+" "+bextTimeLayout, origin.OriginationDate+" "+origin.OriginationTime)
+	if err != nil {
+		return &b
+	}
+	originated = originated.Add(time.Duration(frames) * time.Second / time.Duration(sampleRate))
+	b.OriginationDate = originated.Format(bextDateLayout)
+	b.OriginationTime = originated.Format(bextTimeLayout)
+	return &b
+}
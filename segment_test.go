@@ -0,0 +1,95 @@
+package wav2ulaw
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSplitWavSegments(t *testing.T) {
+	samples := sineWave(20000, 440, 8000, 0.5)
+	wavBytes, err := encodeWavPCM16(samples, 8000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wavBytes, err = WriteWavMetadata(wavBytes, &Metadata{
+		Info: map[string]string{"INAM": "call"},
+		Bext: &Bext{OriginationDate: "2026-10-14", OriginationTime: "23:59:59", TimeReference: 8000 * 86399},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	segments, err := SplitWavSegments(wavBytes, &SegmentOptions{Length: time.Second, Timecode: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segments) != 3 {
+		t.Fatalf("got %d segments, want 3", len(segments))
+	}
+	var joined []int16
+	for i, segment := range segments {
+		decoded, err := decodeWavFloat(segment)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range decoded.data {
+			joined = append(joined, int16(s*32768))
+		}
+		md, err := ReadWavMetadata(segment)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if md.Info["INAM"] != "call" {
+			t.Errorf("segment %d lost its INFO tags: %v", i, md.Info)
+		}
+		if md.Bext == nil {
+			t.Fatalf("segment %d has no bext chunk", i)
+		}
+		if want := uint64(8000*86399 + 8000*i); md.Bext.TimeReference != want {
+			t.Errorf("segment %d: time reference %d, want %d", i, md.Bext.TimeReference, want)
+		}
+		// The origination time runs past midnight into the next day
+		if i == 2 && (md.Bext.OriginationDate != "2026-10-15" || md.Bext.OriginationTime != "00:00:01") {
+			t.Errorf("segment 2 originated %s %s, want 2026-10-15 00:00:01", md.Bext.OriginationDate, md.Bext.OriginationTime)
+		}
+	}
+	if !slices.Equal(joined, samples) {
+		t.Error("segments do not join back into the input")
+	}
+
+	// An explicit origin replaces the bext chunk of the input
+	segments, err = SplitWavSegments(wavBytes, &SegmentOptions{Length: 2 * time.Second, Timecode: true, Origin: &Bext{Originator: "pbx", TimeReference: 100}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	md, err := ReadWavMetadata(segments[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segments) != 2 || md.Bext.TimeReference != 16100 || md.Bext.Originator != "pbx" || md.Bext.OriginationTime != "" {
+		t.Errorf("got %d segments, second bext %+v", len(segments), md.Bext)
+	}
+
+	// A start time sets the origin from the wall clock
+	start := time.Date(2026, 10, 15, 9, 30, 0, 500*int(time.Millisecond), time.UTC)
+	segments, err = SplitWavSegments(wavBytes, &SegmentOptions{Length: 2 * time.Second, Timecode: true, Start: start})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md, err = ReadWavMetadata(segments[1]); err != nil {
+		t.Fatal(err)
+	}
+	if want := uint64(8000*(9*3600+30*60) + 4000 + 16000); md.Bext.TimeReference != want || md.Bext.OriginationTime != "09:30:02" {
+		t.Errorf("second segment from %v: time reference %d at %s, want %d at 09:30:02", start, md.Bext.TimeReference, md.Bext.OriginationTime, want)
+	}
+
+	empty, err := encodeWavPCM16(nil, 8000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SplitWavSegments(empty, &SegmentOptions{Length: time.Second}); !errors.Is(err, ErrEmptyInput) {
+		t.Errorf("empty file: got %v, want ErrEmptyInput", err)
+	}
+}
@@ -0,0 +1,333 @@
+package wav2ulaw
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+)
+
+const (
+	// Length of each synthesized test signal (s)
+	selfTestSeconds = 1.0
+	// Test signal amplitude, low enough that u-law quantization noise and
+	// filter overshoot do not disturb the measurements
+	selfTestAmplitude = 0.25
+	// Output trimmed from both ends before measuring, so filter transients
+	// and resampler edges are excluded (s)
+	selfTestMargin = 0.1
+	// Accepted deviation from the designed response (dB), covering u-law
+	// quantization and resampler passband ripple
+	selfTestToleranceDb = 1.0
+	// Largest accepted upsampling image level (dB)
+	selfTestImageMaxDb = -40.0
+	// Rejection checks accept any level below this, since deeper designed
+	// attenuation is lost in u-law quantization noise (dB)
+	selfTestFloorDb = -80.0
+)
+
+// Input sample rates exercised by the downsampling checks
+var selfTestRates = []int{11025, 16000, 22050, 44100, 48000}
+
+// SelfTestCheck is the outcome of one self-test measurement
+type SelfTestCheck struct {
+	// What was measured, e.g. "48000 Hz sinc/butterworth: passband 1000 Hz"
+	Name string
+	// Measured gain relative to the input signal (dB)
+	GainDb float64
+	// Accepted gain range (dB), MinDb is -Inf for rejection checks
+	MinDb, MaxDb float64
+	// Whether GainDb is within the accepted range
+	Passed bool
+}
+
+// Names of the anti-aliasing filter types in check names
+var selfTestFilterNames = []string{"simple", "butterworth", "bessel", "chebyshev", "windowed-sinc", "auto"}
+
+// SelfTest synthesizes tones and sweeps, converts them with every
+// anti-aliasing filter type and resampling method from common input rates
+// to 8 kHz u-law, and upsamples u-law back to common output rates. The
+// measured passband gain, sweep level and high-pass and low-pass responses
+// must match the responses the filters are designed to have, within
+// selfTestToleranceDb, and aliases and upsampling images must be attenuated
+// at least as much as designed. A build that miscomputes any stage, or a
+// filter design that is unstable at some rate, shows up as failed checks.
+// Level processing such as normalization and compression is disabled for
+// the measurements.
+func SelfTest() ([]SelfTestCheck, error) {
+	var checks []SelfTestCheck
+	add := func(name string, gain, minDb, maxDb float64) {
+		checks = append(checks, SelfTestCheck{
+			Name:   name,
+			GainDb: gain,
+			MinDb:  minDb,
+			MaxDb:  maxDb,
+			Passed: gain >= minDb && gain <= maxDb,
+		})
+	}
+
+	for _, rate := range selfTestRates {
+		for _, method := range []ResampleMethod{ResampleSinc, ResampleFFT} {
+			for aa, aaName := range selfTestFilterNames {
+				config := selfTestConfig()
+				config.ResampleMethod = method
+				config.AntiAliasingType = AntiAliasingType(aa)
+				prefix := fmt.Sprintf("%d Hz %s/%s", rate, resampleMethodName(method), aaName)
+
+				gain, err := selfTestTone(rate, 1000, 1000, config)
+				if err != nil {
+					return nil, err
+				}
+				expected := designedGain(config, rate, 1000)
+				add(prefix+": passband 1000 Hz", gain, expected-selfTestToleranceDb, expected+selfTestToleranceDb)
+
+				gain, err = selfTestSweep(rate, 300, 3000, config)
+				if err != nil {
+					return nil, err
+				}
+				expected = designedSweepGain(config, rate, 300, 3000)
+				add(prefix+": sweep 300-3000 Hz", gain, expected-selfTestToleranceDb, expected+selfTestToleranceDb)
+
+				// A tone between 4 kHz and the input Nyquist frequency folds
+				// back into the telephone band unless it is filtered out; the
+				// resampler may only attenuate it further
+				freq := math.Round((4000 + float64(rate)/2) / 2)
+				alias := foldFrequency(freq, 8000)
+				gain, err = selfTestTone(rate, freq, alias, config)
+				if err != nil {
+					return nil, err
+				}
+				expected = designedGain(config, rate, freq)
+				limit := math.Max(expected, selfTestFloorDb) + selfTestToleranceDb
+				add(fmt.Sprintf("%s: alias %.0f Hz -> %.0f Hz", prefix, freq, alias), gain, math.Inf(-1), limit)
+			}
+		}
+	}
+
+	// Band filters at 8 kHz, where no resampling takes place
+	config := selfTestConfig()
+	config.HighPassCutoff = 300
+	config.LowPassCutoff = 3000
+	for _, freq := range []float64{60, 1000, 3900} {
+		gain, err := selfTestTone(8000, freq, freq, config)
+		if err != nil {
+			return nil, err
+		}
+		expected := designedGain(config, 8000, freq)
+		add(fmt.Sprintf("8000 Hz high-pass 300 Hz/low-pass 3000 Hz: %.0f Hz", freq), gain, expected-selfTestToleranceDb, expected+selfTestToleranceDb)
+	}
+
+	// u-law to WAV upsampling
+	for _, rate := range []int{16000, 44100, 48000} {
+		gain, err := selfTestUpsample(rate, 1000, 1000)
+		if err != nil {
+			return nil, err
+		}
+		add(fmt.Sprintf("u-law to %d Hz: passband 1000 Hz", rate), gain, -selfTestToleranceDb, selfTestToleranceDb)
+
+		// Upsampling mirrors the band around 4 kHz unless the
+		// interpolation filter removes the image
+		gain, err = selfTestUpsample(rate, 1000, 7000)
+		if err != nil {
+			return nil, err
+		}
+		add(fmt.Sprintf("u-law to %d Hz: image 7000 Hz", rate), gain, math.Inf(-1), selfTestImageMaxDb)
+	}
+
+	return checks, nil
+}
+
+// selfTestConfig returns the default configuration with the band filters
+// and level processing disabled, leaving the anti-aliasing filter and the
+// resampler to be measured
+func selfTestConfig() *AudioConfig {
+	config := DefaultAudioConfig()
+	config.HighPassCutoff = 0
+	config.LowPassCutoff = 0
+	config.NormalizePeak = 0
+	config.CompressionRatio = 1.0
+	return config
+}
+
+// designedGain returns the gain (dB) at freq of the band and anti-aliasing
+// filters config applies at sampleRate before resampling to 8 kHz, computed
+// from the filter designs rather than by running them
+func designedGain(config *AudioConfig, sampleRate int, freq float64) float64 {
+	rate := float64(sampleRate)
+	w := 2 * math.Pi * freq / rate
+	gain := 1.0
+	if config.HighPassCutoff > 0 {
+		// y[i] = alpha * (y[i-1] + x[i] - x[i-1])
+		rc := 1.0 / (2.0 * math.Pi * config.HighPassCutoff)
+		alpha := rc / (rc + 1/rate)
+		gain *= transferGain([]float64{alpha, -alpha}, []float64{-alpha}, w)
+	}
+	if config.LowPassCutoff > 0 {
+		gain *= onePoleLowPassGain(rate, config.LowPassCutoff, w)
+	}
+	if sampleRate <= 8000 {
+		return 20 * math.Log10(gain)
+	}
+
+	cutoff := 4000 * config.AntiAliasingCutoffRatio
+	var sections []filterCoefficients
+	switch config.AntiAliasingType {
+	case AAButterworth:
+		sections = butterworthSections(rate, cutoff, config.FilterOrder)
+	case AABessel:
+		sections = besselSections(rate, cutoff, config.FilterOrder)
+	case AAChebyshev:
+		sections = chebyshevSections(rate, cutoff, config.ChebyshevRipple, config.FilterOrder)
+	case AAWindowedSinc, AAAuto:
+		gain *= transferGain(antiAliasingKernel(rate, 8000, config), nil, w)
+		return 20 * math.Log10(gain)
+	default: // AASimple
+		gain *= onePoleLowPassGain(rate, cutoff, w)
+		return 20 * math.Log10(gain)
+	}
+	for _, c := range sections {
+		gain *= transferGain([]float64{c.b0, c.b1, c.b2}, []float64{c.a1, c.a2}, w)
+	}
+	return 20 * math.Log10(gain)
+}
+
+// designedSweepGain returns the designed level (dB) of a linear sweep from
+// f0 to f1, the average power gain over the swept band
+func designedSweepGain(config *AudioConfig, sampleRate int, f0, f1 float64) float64 {
+	const points = 200
+	var sum float64
+	for i := 0; i < points; i++ {
+		freq := f0 + (f1-f0)*(float64(i)+0.5)/points
+		sum += math.Pow(10, designedGain(config, sampleRate, freq)/10)
+	}
+	return 10 * math.Log10(sum/points)
+}
+
+// onePoleLowPassGain returns the magnitude response of the simple low-pass
+// filter, y[i] = y[i-1] + alpha * (x[i] - y[i-1])
+func onePoleLowPassGain(sampleRate, cutoffFreq, w float64) float64 {
+	rc := 1.0 / (2.0 * math.Pi * cutoffFreq)
+	dt := 1.0 / sampleRate
+	alpha := dt / (rc + dt)
+	return transferGain([]float64{alpha}, []float64{alpha - 1}, w)
+}
+
+// transferGain returns the magnitude of b(z)/a(z) at angular frequency w,
+// where a holds the feedback coefficients after the implied leading 1
+func transferGain(b, a []float64, w float64) float64 {
+	eval := func(coeffs []float64, lead complex128) complex128 {
+		sum := lead
+		for k, c := range coeffs {
+			sum += complex(c, 0) * cmplx.Exp(complex(0, -w*float64(k)))
+		}
+		return sum
+	}
+	num := eval(b, 0)
+	den := 1 + eval(a, 0)*cmplx.Exp(complex(0, -w))
+	return cmplx.Abs(num) / cmplx.Abs(den)
+}
+
+// resampleMethodName returns the short name of a resampling method
+func resampleMethodName(method ResampleMethod) string {
+	if method == ResampleFFT {
+		return "fft"
+	}
+	return "sinc"
+}
+
+// foldFrequency returns the frequency at which a tone appears after
+// sampling at sampleRate without filtering
+func foldFrequency(freq, sampleRate float64) float64 {
+	f := math.Mod(freq, sampleRate)
+	if f > sampleRate/2 {
+		f = sampleRate - f
+	}
+	return f
+}
+
+// selfTestTone converts a tone at freq sampled at rate and returns the
+// level found at measureFreq in the 8 kHz output, relative to the input
+func selfTestTone(rate int, freq, measureFreq float64, config *AudioConfig) (float64, error) {
+	n := int(selfTestSeconds * float64(rate))
+	samples := make([]int16, n)
+	for i := range samples {
+		samples[i] = int16(math.Round(selfTestAmplitude * 32767 * math.Sin(2*math.Pi*freq*float64(i)/float64(rate))))
+	}
+	ulaw, err := ConvertPCM16ToUlaw(samples, rate, config)
+	if err != nil {
+		return 0, fmt.Errorf("converting %.0f Hz tone at %d Hz: %v", freq, rate, err)
+	}
+	output := trimSelfTestMargin(decodeUlawSamples(ulaw), 8000)
+	return levelDb(goertzelLevel(output, measureFreq, 8000)), nil
+}
+
+// selfTestSweep converts a linear sweep from f0 to f1 sampled at rate and
+// returns the RMS level of the 8 kHz output relative to the input
+func selfTestSweep(rate int, f0, f1 float64, config *AudioConfig) (float64, error) {
+	n := int(selfTestSeconds * float64(rate))
+	samples := make([]int16, n)
+	for i := range samples {
+		t := float64(i) / float64(rate)
+		phase := 2 * math.Pi * (f0*t + (f1-f0)*t*t/(2*selfTestSeconds))
+		samples[i] = int16(math.Round(selfTestAmplitude * 32767 * math.Sin(phase)))
+	}
+	ulaw, err := ConvertPCM16ToUlaw(samples, rate, config)
+	if err != nil {
+		return 0, fmt.Errorf("converting sweep at %d Hz: %v", rate, err)
+	}
+	output := trimSelfTestMargin(decodeUlawSamples(ulaw), 8000)
+	var sum float64
+	for _, s := range output {
+		v := float64(s) / 32767
+		sum += v * v
+	}
+	// A sine of amplitude A has an RMS of A/sqrt(2)
+	rms := math.Sqrt(sum / float64(len(output)))
+	return levelDb(rms * math.Sqrt2), nil
+}
+
+// selfTestUpsample encodes a u-law tone at freq, converts it to WAV at rate
+// and returns the level found at measureFreq relative to the input
+func selfTestUpsample(rate int, freq, measureFreq float64) (float64, error) {
+	n := int(selfTestSeconds * 8000)
+	ulaw := make([]byte, n)
+	for i := range ulaw {
+		ulaw[i] = encodeUlawSample(int16(math.Round(selfTestAmplitude * 32767 * math.Sin(2*math.Pi*freq*float64(i)/8000))))
+	}
+	wav, err := ConvertUlawBytesToWav(ulaw, uint32(rate), 16)
+	if err != nil {
+		return 0, fmt.Errorf("upsampling u-law to %d Hz: %v", rate, err)
+	}
+	output, _, err := decodeWavSamples(wav, &AudioConfig{})
+	if err != nil {
+		return 0, fmt.Errorf("decoding upsampled WAV: %v", err)
+	}
+	output = trimSelfTestMargin(output, rate)
+	return levelDb(goertzelLevel(output, measureFreq, float64(rate))), nil
+}
+
+// trimSelfTestMargin drops selfTestMargin seconds from both ends
+func trimSelfTestMargin(samples []int16, rate int) []int16 {
+	margin := int(selfTestMargin * float64(rate))
+	if len(samples) <= 2*margin {
+		return samples
+	}
+	return samples[margin : len(samples)-margin]
+}
+
+// goertzelLevel measures the amplitude of a single frequency, normalized to
+// full scale
+func goertzelLevel(samples []int16, freq, sampleRate float64) float64 {
+	coeff := 2 * math.Cos(2*math.Pi*freq/sampleRate)
+	var s1, s2 float64
+	for _, sample := range samples {
+		s := float64(sample)/32767.0 + coeff*s1 - s2
+		s2, s1 = s1, s
+	}
+	power := s1*s1 + s2*s2 - coeff*s1*s2
+	return 2 * math.Sqrt(math.Max(power, 0)) / float64(len(samples))
+}
+
+// levelDb returns the level of an amplitude relative to selfTestAmplitude
+func levelDb(amplitude float64) float64 {
+	return 20 * math.Log10(math.Max(amplitude, 1e-9)/selfTestAmplitude)
+}
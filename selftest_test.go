@@ -0,0 +1,33 @@
+package wav2ulaw
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSelfTest(t *testing.T) {
+	checks, err := SelfTest()
+	if err != nil {
+		t.Fatalf("SelfTest failed: %v", err)
+	}
+	if len(checks) == 0 {
+		t.Fatal("SelfTest returned no checks")
+	}
+	for _, c := range checks {
+		if !c.Passed {
+			t.Errorf("%s: %.2f dB, expected %.2f to %.2f dB", c.Name, c.GainDb, c.MinDb, c.MaxDb)
+		}
+	}
+}
+
+func TestDesignedGain(t *testing.T) {
+	config := selfTestConfig()
+	config.HighPassCutoff = 300
+	for _, freq := range []float64{100, 300, 1000} {
+		samples := applyHighPassFilter(sineWave(8000, freq, 8000, 0.5), 8000, 300)
+		measured := 20 * math.Log10(toneLevel(samples[4000:], freq, 8000)/0.5)
+		if designed := designedGain(config, 8000, freq); math.Abs(designed-measured) > 0.2 {
+			t.Errorf("high-pass gain at %.0f Hz: designed %.2f dB, measured %.2f dB", freq, designed, measured)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package wav2ulaw
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	// Analysis frame length for energy-based statistics (ms)
+	analysisFrameMs = 20
+	// Fraction of quietest frames assumed to contain only background noise
+	noiseFrameFraction = 0.1
+	// Frames this far above the noise floor count as speech (dB)
+	speechMarginDb = 10.0
+	// Power floor used for digital silence (one LSB at 16 bits)
+	minFramePower = 1.0 / (32768.0 * 32768.0)
+)
+
+// monoMix averages interleaved normalized samples into a single channel
+func (d *decodedWav) monoMix() []float64 {
+	if d.channels == 1 {
+		return d.data
+	}
+	mono := make([]float64, len(d.data)/d.channels)
+	for i := range mono {
+		sum := 0.0
+		for ch := 0; ch < d.channels; ch++ {
+			sum += d.data[i*d.channels+ch]
+		}
+		mono[i] = sum / float64(d.channels)
+	}
+	return mono
+}
+
+// framePowers returns the mean power of consecutive analysis frames
+func framePowers(samples []float64, sampleRate int) []float64 {
+	frameLen := sampleRate * analysisFrameMs / 1000
+	if frameLen < 1 {
+		return nil
+	}
+
+	powers := make([]float64, 0, len(samples)/frameLen)
+	for start := 0; start+frameLen <= len(samples); start += frameLen {
+		sum := 0.0
+		for _, v := range samples[start : start+frameLen] {
+			sum += v * v
+		}
+		powers = append(powers, math.Max(sum/float64(frameLen), minFramePower))
+	}
+	return powers
+}
+
+// estimateSNR estimates the signal-to-noise ratio in dB by treating the
+// quietest frames as the noise floor and frames well above it as speech.
+// It returns 0 when no frame stands out from the noise.
+func estimateSNR(samples []float64, sampleRate int) float64 {
+	powers := framePowers(samples, sampleRate)
+	if len(powers) == 0 {
+		return 0
+	}
+
+	sorted := append([]float64(nil), powers...)
+	sort.Float64s(sorted)
+
+	noiseFrames := max(1, int(float64(len(sorted))*noiseFrameFraction))
+	noise := 0.0
+	for _, p := range sorted[:noiseFrames] {
+		noise += p
+	}
+	noise /= float64(noiseFrames)
+
+	threshold := noise * math.Pow(10, speechMarginDb/10)
+	speech, speechFrames := 0.0, 0
+	for _, p := range powers {
+		if p > threshold {
+			speech += p
+			speechFrames++
+		}
+	}
+	if speechFrames == 0 {
+		return 0
+	}
+	speech /= float64(speechFrames)
+
+	return 10 * math.Log10(speech/noise)
+}
@@ -0,0 +1,134 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+)
+
+// Largest spectrogram rendered, in pixels (128 MiB of RGBA)
+const maxSpectrogramPixels = 1 << 25
+
+// SpectrogramOptions configures spectrogram rendering
+type SpectrogramOptions struct {
+	// FFT size in samples (power of two, determines frequency resolution)
+	FFTSize int
+	// Hop between consecutive frames in samples (determines image width)
+	HopSize int
+	// Level mapped to black (dBFS, negative); louder bins get brighter
+	// colors up to 0 dBFS
+	MinDb float64
+	// Window applied to each frame
+	Window WindowFunction
+}
+
+// DefaultSpectrogramOptions returns options suited for telephone-band speech
+func DefaultSpectrogramOptions() *SpectrogramOptions {
+	return &SpectrogramOptions{
+		FFTSize: 512,  // 15.6 Hz bins at 8 kHz
+		HopSize: 128,  // 16 ms per column at 8 kHz
+		MinDb:   -100, // Below the u-law noise floor
+		Window:  WindowHann,
+	}
+}
+
+// WavSpectrogram renders a PNG spectrogram of WAV file bytes (channels are mixed)
+func WavSpectrogram(wavBytes []byte, opts *SpectrogramOptions) ([]byte, error) {
+	decoded, err := decodeWavFloat(wavBytes)
+	if err != nil {
+		return nil, err
+	}
+	return renderSpectrogramPNG(decoded.monoMix(), opts)
+}
+
+// UlawSpectrogram renders a PNG spectrogram of 8 kHz u-law bytes
+func UlawSpectrogram(ulawBytes []byte, opts *SpectrogramOptions) ([]byte, error) {
+	samples := decodeUlawSamples(ulawBytes)
+	data := make([]float64, len(samples))
+	for i, sample := range samples {
+		data[i] = float64(sample) / 32768.0
+	}
+	return renderSpectrogramPNG(data, opts)
+}
+
+// renderSpectrogramPNG computes a short-time Fourier transform and encodes it
+// as an image with time on the x axis and frequency increasing upwards
+func renderSpectrogramPNG(samples []float64, opts *SpectrogramOptions) ([]byte, error) {
+	if opts == nil {
+		opts = DefaultSpectrogramOptions()
+	}
+	if opts.FFTSize < 16 || opts.FFTSize&(opts.FFTSize-1) != 0 {
+		return nil, fmt.Errorf("FFT size must be a power of two >= 16, got %d", opts.FFTSize)
+	}
+	if opts.HopSize < 1 {
+		return nil, fmt.Errorf("hop size must be positive, got %d", opts.HopSize)
+	}
+	if !(opts.MinDb < 0) {
+		return nil, fmt.Errorf("minimum level must be negative, got %g dBFS", opts.MinDb)
+	}
+	if len(samples) < opts.FFTSize {
+		return nil, fmt.Errorf("audio too short for FFT size %d", opts.FFTSize)
+	}
+
+	columns := (len(samples)-opts.FFTSize)/opts.HopSize + 1
+	bins := opts.FFTSize / 2
+	if columns > maxSpectrogramPixels/bins {
+		return nil, fmt.Errorf("spectrogram of %d x %d pixels exceeds the limit of %d, use a larger hop size", columns, bins, maxSpectrogramPixels)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, columns, bins))
+
+	window := makeWindow(opts.Window, opts.FFTSize, 8.6)
+	windowSum := 0.0
+	for _, w := range window {
+		windowSum += w
+	}
+
+	frame := make([]complex128, opts.FFTSize)
+	for x := 0; x < columns; x++ {
+		start := x * opts.HopSize
+		for i := range frame {
+			frame[i] = complex(samples[start+i]*window[i], 0)
+		}
+		fft(frame, false)
+
+		for bin := 0; bin < bins; bin++ {
+			// Scale so a full-scale sine reads 0 dBFS
+			magnitude := 2 * math.Hypot(real(frame[bin]), imag(frame[bin])) / windowSum
+			db := 20 * math.Log10(magnitude+1e-12)
+			t := (db - opts.MinDb) / -opts.MinDb
+			img.Set(x, bins-1-bin, heatColor(t))
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("error encoding PNG: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// heatColor maps t in [0, 1] to a black-blue-red-yellow-white color scale
+func heatColor(t float64) color.RGBA {
+	t = math.Max(0, math.Min(1, t))
+	stops := []color.RGBA{
+		{0, 0, 0, 255},
+		{0, 0, 160, 255},
+		{200, 0, 60, 255},
+		{255, 200, 0, 255},
+		{255, 255, 255, 255},
+	}
+	pos := t * float64(len(stops)-1)
+	i := int(pos)
+	if i >= len(stops)-1 {
+		return stops[len(stops)-1]
+	}
+	frac := pos - float64(i)
+	lerp := func(a, b uint8) uint8 {
+		return uint8(math.Round(float64(a) + (float64(b)-float64(a))*frac))
+	}
+	a, b := stops[i], stops[i+1]
+	return color.RGBA{lerp(a.R, b.R), lerp(a.G, b.G), lerp(a.B, b.B), 255}
+}
@@ -0,0 +1,53 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+)
+
+func TestSpectrogram(t *testing.T) {
+	// A full-scale 1 kHz tone at 8 kHz sits exactly on bin 64 of 512
+	wavBytes, err := encodeWavPCM16(sineWave(8000, 1000, 8000, 1), 8000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := WavSpectrogram(wavBytes, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := img.Bounds().Size(); size.X != (8000-512)/128+1 || size.Y != 256 {
+		t.Fatalf("image of %v, want 59x256", size)
+	}
+	r, g, b, _ := img.At(30, 255-64).RGBA()
+	if r>>8 < 250 || g>>8 < 250 || b>>8 < 250 {
+		t.Errorf("tone bin colored %d,%d,%d, want white", r>>8, g>>8, b>>8)
+	}
+	if r, g, b, _ := img.At(30, 255-200).RGBA(); r>>8+g>>8+b>>8 > 100 {
+		t.Errorf("bin far from the tone colored %d,%d,%d, want nearly black", r>>8, g>>8, b>>8)
+	}
+
+	if data, err := UlawSpectrogram(make([]byte, 1024), &SpectrogramOptions{FFTSize: 256, HopSize: 256, MinDb: -80}); err != nil {
+		t.Error(err)
+	} else if img, err := png.Decode(bytes.NewReader(data)); err != nil || img.Bounds().Dx() != 4 || img.Bounds().Dy() != 128 {
+		t.Errorf("u-law spectrogram: %v, %v", img.Bounds(), err)
+	}
+
+	ulaw := make([]byte, 24000)
+	for name, opts := range map[string]*SpectrogramOptions{
+		"FFT size not a power of two": {FFTSize: 500, HopSize: 128, MinDb: -100},
+		"zero hop size":               {FFTSize: 512, MinDb: -100},
+		"zero minimum level":          {FFTSize: 512, HopSize: 128},
+		"positive minimum level":      {FFTSize: 512, HopSize: 128, MinDb: 20},
+		"image too large":             {FFTSize: 4096, HopSize: 1, MinDb: -100},
+		"audio shorter than the FFT":  {FFTSize: 32768, HopSize: 128, MinDb: -100},
+	} {
+		if _, err := UlawSpectrogram(ulaw, opts); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
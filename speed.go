@@ -0,0 +1,20 @@
+package wav2ulaw
+
+import "math"
+
+// Accepted range of AudioConfig.Speed
+const (
+	minSpeed = 0.5
+	maxSpeed = 2.0
+)
+
+// speedRate returns the rate input at sampleRate is processed as to play it
+// speed times as fast. Resampling from that rate to the output rate changes
+// speed and pitch together at no extra cost, and the filters designed for it
+// act on the sped-up signal. Speed 0 or 1 leaves the rate unchanged.
+func speedRate(sampleRate int, speed float64) int {
+	if speed <= 0 || speed == 1.0 {
+		return sampleRate
+	}
+	return int(math.Round(float64(sampleRate) * speed))
+}
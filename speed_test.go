@@ -0,0 +1,54 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSpeed(t *testing.T) {
+	wavBytes, err := encodeWavPCM16(sineWave(16000, 600, 16000, 0.5), 16000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		speed   float64
+		samples int
+		tone    float64
+	}{
+		{2.0, 4000, 1200},
+		{0.5, 16000, 300},
+	} {
+		config := DefaultAudioConfig()
+		config.HighPassCutoff = 0
+		config.Speed = tc.speed
+		ulaw, err := ConvertWavBytesToUlaw(wavBytes, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ulaw) != tc.samples {
+			t.Errorf("speed %g: got %d samples, want %d", tc.speed, len(ulaw), tc.samples)
+		}
+		// The pitch moves with the speed
+		samples := decodeUlawSamples(ulaw)
+		middle := samples[len(samples)/4 : 3*len(samples)/4]
+		if level := toneLevel(middle, tc.tone, 8000); level < 0.5 {
+			t.Errorf("speed %g: %.0f Hz level %.3f, want the tone shifted there", tc.speed, tc.tone, level)
+		}
+
+		// Streaming gives the same length
+		var streamed bytes.Buffer
+		if err := ConvertWavStreamToUlaw(bytes.NewReader(wavBytes), &streamed, config); err != nil {
+			t.Fatal(err)
+		}
+		if streamed.Len() != tc.samples {
+			t.Errorf("speed %g: streamed %d samples, want %d", tc.speed, streamed.Len(), tc.samples)
+		}
+	}
+
+	config := DefaultAudioConfig()
+	config.Speed = 3
+	if _, err := ConvertWavBytesToUlaw(wavBytes, config); err == nil {
+		t.Error("expected an error for speed 3")
+	}
+}
@@ -0,0 +1,47 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// SplitStereoToUlaw converts each channel of a stereo WAV file, such as a
+// call recording with the agent on one channel and the customer on the
+// other, to its own u-law stream, the inverse of MergeUlawLegs. The file is
+// decoded once and each channel then runs through the processing chain on
+// its own, so level stages such as normalization follow the level of each
+// leg rather than the louder one. Callbacks in config see the left channel
+// first, then the right.
+func SplitStereoToUlaw(wavBytes []byte, config *AudioConfig) (left, right []byte, err error) {
+	if config == nil {
+		config = DefaultAudioConfig()
+	}
+	header, err := inspectWav(bytes.NewReader(wavBytes), config.Limits)
+	if err != nil {
+		return nil, nil, err
+	}
+	if header.channels != 2 {
+		return nil, nil, fmt.Errorf("expected a stereo WAV file, got %d channel(s)", header.channels)
+	}
+
+	interleaved := *config
+	interleaved.ForceMono = false
+	samples, sampleRate, err := decodeWavSamples(wavBytes, &interleaved)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer putInt16s(samples)
+	legs := [2][]int16{make([]int16, len(samples)/2), make([]int16, len(samples)/2)}
+	for i := range legs[0] {
+		legs[0][i] = samples[2*i]
+		legs[1][i] = samples[2*i+1]
+	}
+
+	if left, err = ConvertPCM16ToUlaw(legs[0], sampleRate, config); err != nil {
+		return nil, nil, fmt.Errorf("left channel: %w", err)
+	}
+	if right, err = ConvertPCM16ToUlaw(legs[1], sampleRate, config); err != nil {
+		return nil, nil, fmt.Errorf("right channel: %w", err)
+	}
+	return left, right, nil
+}
@@ -0,0 +1,64 @@
+package wav2ulaw
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSplitStereoToUlaw(t *testing.T) {
+	// A loud agent on the left and a quiet customer on the right
+	agent := sineWave(16000, 1000, 16000, 0.8)
+	customer := sineWave(16000, 1500, 16000, 0.05)
+	interleaved := make([]int16, 2*len(agent))
+	for i := range agent {
+		interleaved[2*i] = agent[i]
+		interleaved[2*i+1] = customer[i]
+	}
+	wavBytes, err := encodeWavPCM16Channels(interleaved, 16000, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := DefaultAudioConfig()
+	config.CompressionRatio = 1
+	left, right, err := SplitStereoToUlaw(wavBytes, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != 8000 || len(right) != 8000 {
+		t.Fatalf("got %d and %d samples, want 8000 each", len(left), len(right))
+	}
+
+	// Each leg is normalized on its own and keeps only its own tone
+	for _, leg := range []struct {
+		name        string
+		ulaw        []byte
+		tone, other float64
+	}{
+		{"left", left, 1000, 1500},
+		{"right", right, 1500, 1000},
+	} {
+		samples := decodeUlawSamples(leg.ulaw)[1000:7000]
+		if level := toneLevel(samples, leg.tone, 8000); level < 0.8 {
+			t.Errorf("%s: %.0f Hz level %.3f, want it normalized towards %.2f", leg.name, leg.tone, level, config.NormalizePeak)
+		}
+		if level := toneLevel(samples, leg.other, 8000); level > 0.01 {
+			t.Errorf("%s: %.0f Hz from the other leg at %.3f", leg.name, leg.other, level)
+		}
+	}
+
+	mono, err := encodeWavPCM16(agent, 16000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := SplitStereoToUlaw(mono, config); err == nil {
+		t.Error("expected an error for a mono file")
+	}
+	empty, err := encodeWavPCM16Channels(nil, 16000, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := SplitStereoToUlaw(empty, config); !errors.Is(err, ErrEmptyInput) {
+		t.Errorf("empty file: got %v, want ErrEmptyInput", err)
+	}
+}
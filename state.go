@@ -0,0 +1,170 @@
+package wav2ulaw
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+const (
+	// Version of the serialized state layout, bumped on incompatible changes
+	stateVersion = 1
+	// Kinds of serialized state
+	stateEncoder    = 'E'
+	stateDecoder    = 'D'
+	stateResampler  = 'R'
+	stateCheckpoint = 'C'
+)
+
+// stateMagic starts every serialized state
+var stateMagic = []byte("W2UL")
+
+// ErrStateMismatch is wrapped by the UnmarshalBinary errors of ChunkEncoder,
+// ChunkDecoder and DriftResampler for state saved by one with a different
+// configuration
+var ErrStateMismatch = errors.New("state does not match")
+
+// stateWriter appends DSP state in a little-endian binary layout
+type stateWriter struct {
+	buf []byte
+}
+
+// newStateWriter starts a state of the given kind
+func newStateWriter(kind byte) *stateWriter {
+	w := &stateWriter{buf: append([]byte(nil), stateMagic...)}
+	w.buf = append(w.buf, stateVersion, kind)
+	return w
+}
+
+func (w *stateWriter) byte(v byte) { w.buf = append(w.buf, v) }
+
+func (w *stateWriter) bool(v bool) {
+	if v {
+		w.byte(1)
+	} else {
+		w.byte(0)
+	}
+}
+
+func (w *stateWriter) int(v int) { w.buf = binary.LittleEndian.AppendUint32(w.buf, uint32(v)) }
+
+func (w *stateWriter) int64(v int64) { w.buf = binary.LittleEndian.AppendUint64(w.buf, uint64(v)) }
+
+func (w *stateWriter) bytes(v []byte) {
+	w.int(len(v))
+	w.buf = append(w.buf, v...)
+}
+
+func (w *stateWriter) float(v float64) {
+	w.buf = binary.LittleEndian.AppendUint64(w.buf, math.Float64bits(v))
+}
+
+func (w *stateWriter) floats(v []float64) {
+	w.int(len(v))
+	for _, f := range v {
+		w.float(f)
+	}
+}
+
+// stateReader reads state written by stateWriter. The first error sticks,
+// later reads return zero values, so callers check err once at the end.
+type stateReader struct {
+	data []byte
+	err  error
+}
+
+// newStateReader checks the header of a state of the given kind
+func newStateReader(data []byte, kind byte) *stateReader {
+	r := &stateReader{data: data}
+	header := r.take(len(stateMagic) + 2)
+	switch {
+	case r.err != nil:
+	case string(header[:len(stateMagic)]) != string(stateMagic):
+		r.err = fmt.Errorf("not a wav2ulaw DSP state")
+	case header[len(stateMagic)] != stateVersion:
+		r.err = fmt.Errorf("unsupported state version %d", header[len(stateMagic)])
+	case header[len(stateMagic)+1] != kind:
+		r.err = fmt.Errorf("%w: state of kind %q, want %q", ErrStateMismatch, header[len(stateMagic)+1], kind)
+	}
+	return r
+}
+
+// take returns the next n bytes
+func (r *stateReader) take(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n > len(r.data) {
+		r.err = fmt.Errorf("truncated state")
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *stateReader) byte() byte {
+	if b := r.take(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *stateReader) bool() bool { return r.byte() != 0 }
+
+func (r *stateReader) int() int {
+	if b := r.take(4); b != nil {
+		return int(binary.LittleEndian.Uint32(b))
+	}
+	return 0
+}
+
+func (r *stateReader) int64() int64 {
+	if b := r.take(8); b != nil {
+		return int64(binary.LittleEndian.Uint64(b))
+	}
+	return 0
+}
+
+func (r *stateReader) bytes() []byte {
+	return r.take(r.int())
+}
+
+func (r *stateReader) float() float64 {
+	if b := r.take(8); b != nil {
+		return math.Float64frombits(binary.LittleEndian.Uint64(b))
+	}
+	return 0
+}
+
+func (r *stateReader) floats() []float64 {
+	n := r.int()
+	if r.err == nil && n > len(r.data)/8 {
+		r.err = fmt.Errorf("truncated state")
+	}
+	if r.err != nil {
+		return nil
+	}
+	v := make([]float64, n)
+	for i := range v {
+		v[i] = r.float()
+	}
+	return v
+}
+
+// expect checks that the next value written by the writer's int was want,
+// naming what in the error
+func (r *stateReader) expect(what string, want int) {
+	if got := r.int(); r.err == nil && got != want {
+		r.err = fmt.Errorf("%w: %s is %d, want %d", ErrStateMismatch, what, got, want)
+	}
+}
+
+// finish reports the first error, or trailing data
+func (r *stateReader) finish() error {
+	if r.err == nil && len(r.data) > 0 {
+		r.err = fmt.Errorf("%d bytes of trailing state data", len(r.data))
+	}
+	return r.err
+}
@@ -0,0 +1,99 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestChunkEncoderStateMigration(t *testing.T) {
+	input := sineWave(48000, 1000, 48000, 0.5)
+	for _, aa := range []AntiAliasingType{AASimple, AAChebyshev, AAWindowedSinc} {
+		config := DefaultAudioConfig()
+		config.AntiAliasingType = aa
+
+		whole, err := NewChunkEncoder(48000, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := append(whole.Encode(input), whole.Flush()...)
+
+		// Encode the first part, then move the stream to a new encoder
+		first, _ := NewChunkEncoder(48000, config)
+		output := first.Encode(input[:17000])
+		state, err := first.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, _ := NewChunkEncoder(48000, config)
+		if err := second.UnmarshalBinary(state); err != nil {
+			t.Fatalf("type %d: %v", aa, err)
+		}
+		output = append(output, second.Encode(input[17000:])...)
+		output = append(output, second.Flush()...)
+
+		if !bytes.Equal(output, expected) {
+			t.Errorf("type %d: migrated stream differs from an uninterrupted one", aa)
+		}
+	}
+}
+
+func TestChunkDecoderStateMigration(t *testing.T) {
+	ulaw := encodeUlawSamples(sineWave(8000, 440, 8000, 0.5))
+	whole, err := NewChunkDecoder(16000, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := append(whole.Decode(ulaw), whole.Flush()...)
+
+	first, _ := NewChunkDecoder(16000, 16)
+	output := first.Decode(ulaw[:3001])
+	state, err := first.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _ := NewChunkDecoder(16000, 16)
+	if err := second.UnmarshalBinary(state); err != nil {
+		t.Fatal(err)
+	}
+	output = append(output, second.Decode(ulaw[3001:])...)
+	output = append(output, second.Flush()...)
+
+	if !slices.Equal(output, expected) {
+		t.Error("migrated stream differs from an uninterrupted one")
+	}
+}
+
+func TestStateMismatchRejected(t *testing.T) {
+	encoder, _ := NewChunkEncoder(48000, nil)
+	encoder.Encode(sineWave(4800, 1000, 48000, 0.5))
+	state, _ := encoder.MarshalBinary()
+
+	config := DefaultAudioConfig()
+	config.AntiAliasingType = AAButterworth
+	other, _ := NewChunkEncoder(48000, config)
+	before, _ := other.MarshalBinary()
+	if err := other.UnmarshalBinary(state); !errors.Is(err, ErrStateMismatch) {
+		t.Errorf("different filter chain: got %v, want ErrStateMismatch", err)
+	}
+	if after, _ := other.MarshalBinary(); !bytes.Equal(after, before) {
+		t.Error("failed restore changed the encoder")
+	}
+
+	differentRate, _ := NewChunkEncoder(44100, nil)
+	if err := differentRate.UnmarshalBinary(state); !errors.Is(err, ErrStateMismatch) {
+		t.Errorf("different rate: got %v, want ErrStateMismatch", err)
+	}
+	decoder, _ := NewChunkDecoder(16000, 16)
+	if err := decoder.UnmarshalBinary(state); !errors.Is(err, ErrStateMismatch) {
+		t.Errorf("encoder state in a decoder: got %v, want ErrStateMismatch", err)
+	}
+
+	same, _ := NewChunkEncoder(48000, nil)
+	for _, n := range []int{0, 5, len(state) / 2, len(state) - 1} {
+		if err := same.UnmarshalBinary(state[:n]); err == nil {
+			t.Errorf("state truncated to %d bytes was accepted", n)
+		}
+	}
+}
@@ -0,0 +1,377 @@
+package wav2ulaw
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"time"
+)
+
+const (
+	// Input processed per block when streaming a file (seconds)
+	streamBlockSeconds = 1
+	// Frames decoded from the WAV stream per read
+	streamReadFrames = 4096
+)
+
+// ConvertWavStreamToUlaw converts a WAV stream to u-law and writes the result
+// to w, holding only a few blocks of audio in memory regardless of the input
+// length. Blocks overlap like the parallel path so filters settle and the
+// output closely matches ConvertWavBytesToUlaw. Peak normalization needs the
+// level of the processed signal, so when it is enabled the input is read
+// twice. Tempo changes, noise overlay, AutoTrim, Reverse and TargetLoudness
+// need the whole signal and are rejected, OnStageOutput is not called, and input without
+// samples returns ErrEmptyInput.
+func ConvertWavStreamToUlaw(r io.ReadSeeker, w io.Writer, config *AudioConfig) error {
+	if config == nil {
+		config = DefaultAudioConfig()
+	}
+	return convertWavStream(r, w, config, nil)
+}
+
+// checkStreamable rejects the settings that need the whole signal
+func checkStreamable(config *AudioConfig) error {
+	if config.Tempo > 0 && config.Tempo != 1.0 {
+		return fmt.Errorf("tempo change is not supported when streaming")
+	}
+	if config.Noise != nil {
+		return fmt.Errorf("noise overlay is not supported when streaming")
+	}
+	if config.AutoTrim != nil {
+		return fmt.Errorf("automatic trimming is not supported when streaming")
+	}
+	if config.Reverse {
+		return fmt.Errorf("reversing is not supported when streaming")
+	}
+	if config.TargetLoudness != 0 {
+		return fmt.Errorf("loudness targeting is not supported when streaming")
+	}
+	return nil
+}
+
+// convertWavStream implements ConvertWavStreamToUlaw, recording what the
+// processing did in info unless it is nil
+func convertWavStream(r io.ReadSeeker, w io.Writer, config *AudioConfig, info *ConversionInfo) error {
+	if err := checkStreamable(config); err != nil {
+		return err
+	}
+	stream, err := newWavStream(r, config)
+	if err != nil {
+		return err
+	}
+	if stream.samples == 0 {
+		return ErrEmptyInput
+	}
+	config = stream.config
+	if info != nil {
+		info.ResampleRate = stream.inputRate
+		info.ResampleRatio = 8000 / float64(stream.inputRate)
+		info.GainDb += 20 * math.Log10(stream.levelScale)
+	}
+
+	progress := newProgressReporter(config)
+	scale := 1.0
+	if config.NormalizePeak > 0 {
+		// First pass only measures the peak of the processed signal and
+		// accounts for the first half of the progress range
+		start := time.Now()
+		maxAbs := 0.0
+		err := stream.run(true, progress, 0, 0.5, func(block []int16, offset int) error {
+			for _, sample := range block {
+				maxAbs = math.Max(maxAbs, math.Abs(float64(sample)))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if maxAbs > 0 {
+			scale = (config.NormalizePeak * 32767.0) / maxAbs
+		} else {
+			logWarn(config, WarnSilentInput, "normalize", "normalization skipped: silent input")
+		}
+		if info != nil {
+			info.GainDb += 20 * math.Log10(scale)
+		}
+		logStage(config, "peak scan", start, stream.samples, stream.outputLen)
+		if err := stream.rewind(); err != nil {
+			return err
+		}
+	}
+
+	progressFrom := 0.0
+	if config.NormalizePeak > 0 {
+		progressFrom = 0.5
+	}
+	// Beep positions count from the start of the output, padding included
+	head := padLength(config.PadStart, 8000)
+	writeSilence := func(d time.Duration, offset int) error {
+		if n := padLength(d, 8000); n > 0 {
+			silence := make([]int16, n)
+			mixBeepsAt(silence, offset, config.Beeps)
+			if _, err := w.Write(encodeChunk(config, silence)); err != nil {
+				return fmt.Errorf("error writing u-law data: %v", err)
+			}
+		}
+		return nil
+	}
+	if err := writeSilence(config.PadStart, 0); err != nil {
+		return err
+	}
+	start := time.Now()
+	err = stream.run(config.NormalizePeak <= 0, progress, progressFrom, 1, func(block []int16, offset int) error {
+		if config.NormalizePeak > 0 {
+			for i, sample := range block {
+				block[i] = int16(math.Round(float64(sample) * scale))
+			}
+		}
+		if config.FadeInMs > 0 || config.FadeOutMs > 0 {
+			applyFadesAt(block, offset, stream.outputLen, 8000, config.FadeInMs, config.FadeOutMs, config.FadeShape)
+		}
+		mixBeepsAt(block, head+offset, config.Beeps)
+		if info != nil {
+			info.ClippedSamples += clippedSamples(block)
+		}
+		if _, err := w.Write(encodeChunk(config, block)); err != nil {
+			return fmt.Errorf("error writing u-law data: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	logStage(config, "stream conversion", start, stream.samples, stream.outputLen)
+	if err := writeSilence(config.PadEnd, head+stream.outputLen); err != nil {
+		return err
+	}
+
+	// Frame alignment is silence after the beeps, so none mix into it
+	n := head + stream.outputLen + padLength(config.PadEnd, 8000)
+	if pad := framePadding(n, config.FrameAlign); pad > 0 {
+		start = time.Now()
+		if _, err := w.Write(encodeChunk(config, make([]int16, pad))); err != nil {
+			return fmt.Errorf("error writing u-law data: %v", err)
+		}
+		logStage(config, "frame align", start, n, n+pad)
+	}
+	return nil
+}
+
+// wavStream decodes a WAV stream block by block and runs the sample-rate
+// dependent part of the processing chain on each block
+type wavStream struct {
+	pcm       *wavPCM
+	config    *AudioConfig
+	channels  int
+	bitDepth  int
+	mono      bool
+	inputRate int
+	clipRate  int
+	up        int
+	down      int
+	// Set when the rate ratio is too awkward to split into aligned blocks
+	wholeBlock bool
+	// Input samples after channel handling and the resulting output length
+	samples   int
+	outputLen int
+	buf       []int
+	detector  *clipDetector
+	// Factor undoing the gain of the filters and resampler (LevelCompensation)
+	levelScale float64
+	// Set once the input has been checked for audio decoded from u-law
+	checkedUlaw bool
+}
+
+// newWavStream validates the WAV header and prepares block processing
+func newWavStream(r io.ReadSeeker, config *AudioConfig) (*wavStream, error) {
+	header, err := inspectWav(r, config.Limits)
+	if err != nil {
+		return nil, err
+	}
+	pcm, err := newWavPCM(r, header)
+	if err != nil {
+		return nil, fmt.Errorf("error reading WAV data: %v", err)
+	}
+
+	s := &wavStream{
+		pcm:       pcm,
+		config:    config,
+		channels:  header.channels,
+		bitDepth:  header.bitDepth,
+		mono:      config.ForceMono && header.channels > 1,
+		inputRate: config.InputSampleRate,
+		clipRate:  header.sampleRate,
+	}
+	if s.inputRate == 0 {
+		s.inputRate = header.sampleRate
+	}
+	if s.config, err = validateConfig(config, s.inputRate); err != nil {
+		return nil, err
+	}
+	s.inputRate = speedRate(s.inputRate, s.config.Speed)
+	// Blocks would report their stages piecemeal
+	s.config = withoutStageOutput(s.config)
+	config = s.config
+
+	// The declared length overstates truncated files
+	s.samples = pcm.samples()
+	if s.mono {
+		s.samples /= s.channels
+	}
+	s.buf = make([]int, streamReadFrames*s.channels)
+
+	up, down, ok := rationalRatio(s.inputRate, 8000)
+	if !ok {
+		// Awkward ratios are processed as a single block covering the whole signal
+		up, down = 8000, s.inputRate
+		s.wholeBlock = true
+	}
+	s.up, s.down = up, down
+	s.outputLen = resampledLength(s.samples, up, down)
+	s.levelScale = 1
+	if config.LevelCompensation {
+		s.levelScale = 1 / filterChainGain(s.inputRate, config)
+	}
+
+	frames := s.samples
+	if !s.mono {
+		frames /= s.channels
+	}
+	logDebug(config, "decoded WAV header",
+		"sample_rate", header.sampleRate,
+		"channels", s.channels,
+		"bit_depth", s.bitDepth,
+		"frames", frames,
+		"mono", s.mono,
+		"whole_block", s.wholeBlock,
+	)
+	logResample(config, s.inputRate, 8000)
+	return s, nil
+}
+
+// rewind seeks back to the start of the PCM data for another pass
+func (s *wavStream) rewind() error {
+	if err := s.pcm.rewind(); err != nil {
+		return fmt.Errorf("error rewinding WAV data: %v", err)
+	}
+	return nil
+}
+
+// read decodes the next chunk and appends it to window, reporting false at the end of the data
+func (s *wavStream) read(window []int16) ([]int16, bool, error) {
+	n, err := s.pcm.read(s.buf)
+	if err != nil {
+		return window, false, fmt.Errorf("error reading WAV data: %v", err)
+	}
+	if n == 0 {
+		return window, false, nil
+	}
+	data := s.buf[:n]
+
+	if s.detector != nil {
+		normalized := getFloat64s(len(data))
+		for i, v := range data {
+			normalized[i] = normalizeSample(v, s.bitDepth)
+		}
+		for _, region := range s.detector.feed(normalized) {
+			s.config.OnClipping(region)
+		}
+		putFloat64s(normalized)
+	}
+
+	count := n
+	if s.mono {
+		count /= s.channels
+	}
+	start := len(window)
+	window = slices.Grow(window, count)[:start+count]
+	pcmToInt16(window[start:], data, s.channels, s.bitDepth, s.mono)
+	if !s.checkedUlaw {
+		// The start of the input stands for all of it
+		s.checkedUlaw = true
+		checkAlreadyUlaw(s.config, window[start:], s.clipRate)
+	}
+	return window, true, nil
+}
+
+// run processes the stream block by block and calls emit with each block of
+// 8 kHz output and its offset in the output. The block is only valid during
+// the call. Clipped regions are reported when detectClips is set, and
+// progress advances from progressFrom to progressTo as blocks complete.
+func (s *wavStream) run(detectClips bool, progress *progressReporter, progressFrom, progressTo float64, emit func(block []int16, offset int) error) error {
+	s.detector = nil
+	if detectClips && s.config.OnClipping != nil {
+		s.detector = newClipDetector(s.channels, s.clipRate, s.bitDepth)
+	}
+
+	roundUp := func(n int) int {
+		return (n + s.down - 1) / s.down * s.down
+	}
+	blockLen := roundUp(s.inputRate * streamBlockSeconds)
+	warmup := roundUp(int(float64(s.inputRate)*parallelWarmupSeconds) + 2*s.config.ResamplingWindowSize)
+	if s.wholeBlock {
+		blockLen, warmup = max(s.samples, 1), 0
+	}
+
+	// Decoded input starting at sample windowStart
+	var window []int16
+	windowStart := 0
+	more := true
+	for start := 0; start < s.samples; start += blockLen {
+		end := min(start+blockLen, s.samples)
+		from := max(0, start-warmup)
+		to := min(s.samples, end+warmup)
+
+		var err error
+		for more && windowStart+len(window) < to {
+			if window, more, err = s.read(window); err != nil {
+				return err
+			}
+		}
+
+		// Drop input no longer needed by any later block
+		if drop := from - windowStart; drop > 0 {
+			window = window[:copy(window, window[drop:])]
+			windowStart = from
+		}
+		to = min(to, windowStart+len(window))
+
+		block := getInt16s(to - from)
+		copy(block, window[:to-from])
+		block = filterAndResample(block, s.inputRate, 8000, s.config)
+		if s.levelScale != 1 {
+			scaleSamples(block, s.levelScale)
+		}
+		if s.config.CompressionRatio > 1.0 {
+			block = applyCompression(block, s.config.CompressionRatio, s.config.CompressionThreshold)
+		}
+
+		// Keep only the region this block is responsible for
+		outStart := start * s.up / s.down
+		outEnd := min(resampledLength(end, s.up, s.down), s.outputLen)
+		offset := from * s.up / s.down
+		out := getInt16s(outEnd - outStart)
+		for i := range out {
+			out[i] = 0
+			if j := outStart + i - offset; j < len(block) {
+				out[i] = block[j]
+			}
+		}
+		putInt16s(block)
+
+		err = emit(out, outStart)
+		putInt16s(out)
+		if err != nil {
+			return err
+		}
+		progress.report(progressFrom + (progressTo-progressFrom)*float64(end)/float64(s.samples))
+	}
+
+	if s.detector != nil {
+		for _, region := range s.detector.flush() {
+			s.config.OnClipping(region)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestStreamMatchesInMemory(t *testing.T) {
+	const rate = 44100
+	input := sineWave(rate*3+123, 440, rate, 0.5)
+	for i, sample := range sineWave(len(input), 1800, rate, 0.3) {
+		input[i] += sample
+	}
+	wavBytes, err := encodeWavPCM16(input, rate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := DefaultAudioConfig()
+	config.FadeInMs = 50
+	config.FadeOutMs = 50
+	expected, err := ConvertWavBytesToUlaw(wavBytes, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := ConvertWavStreamToUlaw(bytes.NewReader(wavBytes), &out, config); err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() != len(expected) {
+		t.Fatalf("streamed %d bytes, want %d", out.Len(), len(expected))
+	}
+
+	streamed := decodeUlawSamples(out.Bytes())
+	reference := decodeUlawSamples(expected)
+	for i := range reference {
+		if diff := int(streamed[i]) - int(reference[i]); diff > 256 || diff < -256 {
+			t.Fatalf("sample %d: streamed %d, want %d", i, streamed[i], reference[i])
+		}
+	}
+}
+
+func TestDeterministicOutputIsIdentical(t *testing.T) {
+	const rate = 44100
+	input := sineWave(rate*5+77, 440, rate, 0.5)
+	for i, sample := range sineWave(len(input), 3000, rate, 0.3) {
+		input[i] += sample
+	}
+	wavBytes, err := encodeWavPCM16(input, rate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := DefaultAudioConfig()
+	config.FadeInMs = 50
+	// A slowly settling filter makes block boundaries show in the output
+	config.HighPassCutoff = 5
+	config.Deterministic = true
+	var streamed bytes.Buffer
+	if err := ConvertWavStreamToUlaw(bytes.NewReader(wavBytes), &streamed, config); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every worker count gives the streamed bytes
+	for _, concurrency := range []int{0, 1, 3, 8, -1} {
+		config.Concurrency = concurrency
+		output, err := ConvertWavBytesToUlaw(wavBytes, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(output, streamed.Bytes()) {
+			t.Errorf("concurrency %d: output differs from the streamed conversion", concurrency)
+		}
+	}
+}
+
+func TestPaddingIsDigitalSilence(t *testing.T) {
+	wavBytes, err := encodeWavPCM16(sineWave(16000, 440, 16000, 0.5), 16000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := DefaultAudioConfig()
+	config.PadStart = 250 * time.Millisecond
+	config.PadEnd = 100 * time.Millisecond
+	config.UlawVariant = UlawInvertEvenBits
+
+	ulaw, err := ConvertWavBytesToUlaw(wavBytes, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ulaw) != 2000+8000+800 {
+		t.Fatalf("got %d bytes, want %d", len(ulaw), 2000+8000+800)
+	}
+	silence := UlawInvertEvenBits.apply([]byte{0xFF})[0]
+	for i, b := range ulaw {
+		if padding := i < 2000 || i >= 10000; padding && b != silence {
+			t.Fatalf("byte %d of the padding is %#x, want %#x", i, b, silence)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := ConvertWavStreamToUlaw(bytes.NewReader(wavBytes), &out, config); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes()[:2000], ulaw[:2000]) || !bytes.Equal(out.Bytes()[out.Len()-800:], ulaw[len(ulaw)-800:]) || out.Len() != len(ulaw) {
+		t.Error("streamed padding differs from the in-memory conversion")
+	}
+
+	config.PadStart = -time.Second
+	var configErr *ConfigError
+	if _, err := ConvertWavBytesToUlaw(wavBytes, config); !errors.As(err, &configErr) {
+		t.Errorf("negative padding: got %v, want a ConfigError", err)
+	}
+}
+
+func TestFrameAlign(t *testing.T) {
+	// 8001 samples at 8 kHz and 800 of padding, 159 short of 56 frames
+	wavBytes, err := encodeWavPCM16(sineWave(16001, 440, 16000, 0.5), 16000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := DefaultAudioConfig()
+	config.PadEnd = 100 * time.Millisecond
+	config.FrameAlign = 160
+
+	ulaw, err := ConvertWavBytesToUlaw(wavBytes, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ulaw) != 56*160 {
+		t.Fatalf("got %d bytes, want %d", len(ulaw), 56*160)
+	}
+	for i, b := range ulaw[8001:] {
+		if b != 0xFF {
+			t.Fatalf("byte %d of the padding is %#x, want 0xff", 8001+i, b)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := ConvertWavStreamToUlaw(bytes.NewReader(wavBytes), &out, config); err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() != len(ulaw) || !bytes.Equal(out.Bytes()[8001:], ulaw[8001:]) {
+		t.Errorf("streamed %d bytes, want %d ending in the same padding", out.Len(), len(ulaw))
+	}
+	estimate, err := EstimateWav(bytes.NewReader(wavBytes), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if estimate.OutputBytes != int64(len(ulaw)) {
+		t.Errorf("estimated %d bytes, want %d", estimate.OutputBytes, len(ulaw))
+	}
+
+	var stages []string
+	config.OnStageMetrics = func(m StageMetrics) { stages = append(stages, m.Stage) }
+	results := (&BatchConverter{Config: config}).Run(context.Background(), []BatchJob{
+		{Name: "unaligned", Input: bytes.NewReader(wavBytes), Output: io.Discard},
+		{Name: "aligned", Input: bytes.NewReader(ulawWav(t, ulaw)), Output: io.Discard},
+	})
+	if err := BatchError(results); err != nil {
+		t.Fatal(err)
+	}
+	if results[0].PaddingBytes != 159 || results[1].PaddingBytes != 0 {
+		t.Errorf("padding %d and %d bytes, want 159 and 0", results[0].PaddingBytes, results[1].PaddingBytes)
+	}
+	if !slices.Contains(stages, "frame align") {
+		t.Errorf("stages %v, want the frame align stage reported to the caller too", stages)
+	}
+}
+
+// ulawWav returns u-law decoded to a WAV file at 8 kHz
+func ulawWav(t *testing.T, ulaw []byte) []byte {
+	wavBytes, err := encodeWavPCM16(decodeUlawSamples(ulaw), 8000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wavBytes
+}
@@ -0,0 +1,249 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Largest WAV header a UlawEncoder buffers while waiting for the data chunk
+const maxStreamHeader = 64 << 10
+
+// UlawEncoder converts a live WAV stream to u-law as it is written, for
+// pipelines that receive audio in small chunks such as 20 ms websocket
+// frames. Writes may split the header, frames and samples anywhere; each
+// writes the u-law the input completes to the underlying writer, through a
+// ChunkEncoder, so memory stays bounded and filter and resampler state
+// carry over between writes. The channels are mixed to mono. A stream that
+// does not start with a RIFF header is taken as raw 16-bit little-endian
+// mono PCM at config.InputSampleRate. Close flushes the audio held back by
+// the filters and resampler.
+type UlawEncoder struct {
+	w       io.Writer
+	config  *AudioConfig
+	encoder *ChunkEncoder
+	// Input held back: the header until it is complete, then a partial frame
+	pending  []byte
+	channels int
+	bitDepth int
+	// Data bytes left when the header declares the size, or -1
+	remaining int64
+	pcm       []int
+	err       error
+}
+
+// NewUlawEncoder returns an encoder writing u-law to w. The configuration is
+// checked once the sample rate of the stream is known, by the first Write.
+func NewUlawEncoder(w io.Writer, config *AudioConfig) *UlawEncoder {
+	if config == nil {
+		config = DefaultAudioConfig()
+	}
+	return &UlawEncoder{w: w, config: config, remaining: -1}
+}
+
+// Write converts p, returning len(p) unless the stream is invalid or
+// writing the u-law fails. Errors are sticky.
+func (e *UlawEncoder) Write(p []byte) (int, error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+	written := len(p)
+	if e.encoder == nil {
+		e.pending = append(e.pending, p...)
+		if e.err = e.start(false); e.err != nil || e.encoder == nil {
+			return written, e.err
+		}
+		p, e.pending = e.pending, nil
+	}
+	if e.remaining >= 0 {
+		// Chunks after the audio are ignored
+		p = p[:min(int64(len(p)), e.remaining)]
+		e.remaining -= int64(len(p))
+	}
+
+	// Only whole frames are decoded, the rest waits for the next write
+	if len(e.pending) > 0 {
+		p = append(e.pending, p...)
+	}
+	frame := e.channels * e.bitDepth / 8
+	n := len(p) / frame * frame
+	if n > 0 {
+		if cap(e.pcm) < n/(e.bitDepth/8) {
+			e.pcm = make([]int, n/(e.bitDepth/8))
+		}
+		pcm := e.pcm[:decodePCM(e.pcm[:cap(e.pcm)], p[:n], e.bitDepth)]
+		samples := make([]int16, len(pcm)/e.channels)
+		pcmToInt16(samples, pcm, e.channels, e.bitDepth, true)
+		if e.err = e.emit(e.encoder.Encode(samples)); e.err != nil {
+			return 0, e.err
+		}
+	}
+	e.pending = append(e.pending[:0], p[n:]...)
+	return written, nil
+}
+
+// Close writes the u-law still held back by the filters and resampler. A
+// stream that ended within its header fails with the header's problem, and
+// one without any input with ErrEmptyInput. The encoder must not be used
+// afterwards.
+func (e *UlawEncoder) Close() error {
+	if e.err != nil {
+		return e.err
+	}
+	if e.encoder == nil {
+		if len(e.pending) == 0 {
+			return ErrEmptyInput
+		}
+		if err := e.start(true); err != nil {
+			return err
+		}
+	}
+	return e.emit(e.encoder.Flush())
+}
+
+// start looks for the end of the header in the input buffered so far,
+// creating the ChunkEncoder once it is found. Until final, a header cut
+// short by the end of the input is waited on; a malformed one fails at
+// once.
+func (e *UlawEncoder) start(final bool) error {
+	if len(e.pending) < 4 && !final {
+		return nil
+	}
+	rate := e.config.InputSampleRate
+	e.channels, e.bitDepth = 1, 16
+	if !bytes.HasPrefix(e.pending, []byte("RIFF")) {
+		if rate == 0 {
+			return &WavError{Offset: 0, Reason: "missing RIFF/WAVE header, and InputSampleRate is not set for raw PCM"}
+		}
+	} else {
+		header, err := inspectWav(bytes.NewReader(e.pending), e.config.Limits)
+		if err != nil && !final && len(e.pending) < maxStreamHeader && headerIncomplete(e.pending, e.config.Limits) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if rate == 0 {
+			rate = header.sampleRate
+		}
+		e.channels, e.bitDepth = header.channels, header.bitDepth
+		declared := binary.LittleEndian.Uint32(e.pending[header.dataOffset-4:])
+		if declared != 0 && declared != 0xFFFFFFFF {
+			e.remaining = int64(declared)
+		}
+		e.pending = e.pending[header.dataOffset:]
+	}
+	encoder, err := NewChunkEncoder(rate, e.config)
+	if err != nil {
+		return err
+	}
+	e.encoder = encoder
+	logDebug(e.config, "streaming WAV input", "sample_rate", rate, "channels", e.channels, "bit_depth", e.bitDepth)
+	return nil
+}
+
+// headerIncomplete reports whether a WAV header that failed to parse was
+// cut short by the end of data, rather than malformed: parsed as the start
+// of a longer stream, it runs out of input instead of failing
+func headerIncomplete(data []byte, limits *WavLimits) bool {
+	if len(data) < 12 {
+		return true
+	}
+	_, err := inspectWav(unboundedReader{bytes.NewReader(data)}, limits)
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+}
+
+// unboundedReader reads buffered input as the start of a stream of unknown
+// length, so reads past the buffer fail instead of the parse ending there
+type unboundedReader struct {
+	*bytes.Reader
+}
+
+func (r unboundedReader) Seek(offset int64, whence int) (int64, error) {
+	if whence == io.SeekEnd {
+		return math.MaxInt64 / 2, nil
+	}
+	return r.Reader.Seek(offset, whence)
+}
+
+func (e *UlawEncoder) emit(ulaw []byte) error {
+	if _, err := e.w.Write(ulaw); err != nil {
+		return fmt.Errorf("error writing u-law data: %v", err)
+	}
+	return nil
+}
+
+// WavDecoder reads u-law from a live stream and produces a 16-bit mono WAV
+// stream at a chosen rate, through a ChunkDecoder. Each Read decodes what
+// one read of the source returns, so output follows input closely. The
+// length is unknown, so the header declares the largest RIFF and data
+// sizes, as streaming writers do.
+type WavDecoder struct {
+	r       io.Reader
+	decoder *ChunkDecoder
+	buf     []byte
+	// Output decoded but not read yet, starting with the header
+	pending []byte
+	eof     bool
+}
+
+// NewWavDecoder returns a decoder reading u-law from r and producing samples
+// at outputRate, using a sinc window of the given half-width when resampling
+func NewWavDecoder(r io.Reader, outputRate, windowSize int) (*WavDecoder, error) {
+	decoder, err := NewChunkDecoder(outputRate, windowSize)
+	if err != nil {
+		return nil, err
+	}
+	return &WavDecoder{r: r, decoder: decoder, buf: make([]byte, streamReadFrames), pending: streamWavHeader(outputRate)}, nil
+}
+
+// Read fills p with the WAV stream, returning io.EOF once the source ended
+// and the resampler was flushed
+func (d *WavDecoder) Read(p []byte) (int, error) {
+	for len(d.pending) == 0 {
+		if d.eof {
+			return 0, io.EOF
+		}
+		n, err := d.r.Read(d.buf)
+		d.pending = appendPCM16(d.pending, d.decoder.Decode(d.buf[:n]))
+		if err == io.EOF {
+			d.eof = true
+			d.pending = appendPCM16(d.pending, d.decoder.Flush())
+		} else if err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.pending)
+	d.pending = d.pending[n:]
+	return n, nil
+}
+
+// appendPCM16 appends samples to data as 16-bit little-endian PCM
+func appendPCM16(data []byte, samples []int16) []byte {
+	for _, sample := range samples {
+		data = binary.LittleEndian.AppendUint16(data, uint16(sample))
+	}
+	return data
+}
+
+// streamWavHeader returns the header of a 16-bit mono WAV stream of unknown
+// length at rate
+func streamWavHeader(rate int) []byte {
+	header := make([]byte, 44)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], 0xFFFFFFFF)
+	copy(header[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(header[16:], 16)
+	binary.LittleEndian.PutUint16(header[20:], 1)
+	binary.LittleEndian.PutUint16(header[22:], 1)
+	binary.LittleEndian.PutUint32(header[24:], uint32(rate))
+	binary.LittleEndian.PutUint32(header[28:], uint32(rate*2))
+	binary.LittleEndian.PutUint16(header[32:], 2)
+	binary.LittleEndian.PutUint16(header[34:], 16)
+	copy(header[36:], "data")
+	binary.LittleEndian.PutUint32(header[40:], 0xFFFFFFFF)
+	return header
+}
@@ -0,0 +1,117 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestUlawEncoder(t *testing.T) {
+	samples := sineWave(16000, 440, 16000, 0.3)
+	reference, err := NewChunkEncoder(16000, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := append(reference.Encode(samples), reference.Flush()...)
+
+	// Writes of odd sizes split the header and the samples; the LIST chunk
+	// after the audio is not audio
+	wavBytes := append(encodeWavDepth(t, samples, 16000, 16, 1), "LIST\x04\x00\x00\x00INFO"...)
+	var out bytes.Buffer
+	e := NewUlawEncoder(&out, nil)
+	for data := wavBytes; len(data) > 0; {
+		n := min(len(data), 7+len(data)%161)
+		if written, err := e.Write(data[:n]); err != nil || written != n {
+			t.Fatalf("wrote %d of %d: %v", written, n, err)
+		}
+		data = data[n:]
+	}
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Errorf("streamed %d bytes differ from the %d of a ChunkEncoder", out.Len(), len(want))
+	}
+
+	// Raw PCM at InputSampleRate
+	raw := make([]byte, 0, 2*len(samples))
+	for _, s := range samples {
+		raw = binary.LittleEndian.AppendUint16(raw, uint16(s))
+	}
+	out.Reset()
+	config := DefaultAudioConfig()
+	config.InputSampleRate = 16000
+	e = NewUlawEncoder(&out, config)
+	if _, err := io.Copy(e, bytes.NewReader(raw)); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Error("raw PCM differs from the same samples in a WAV stream")
+	}
+
+	if err := NewUlawEncoder(io.Discard, nil).Close(); !errors.Is(err, ErrEmptyInput) {
+		t.Errorf("no input: got %v, want ErrEmptyInput", err)
+	}
+	e = NewUlawEncoder(io.Discard, nil)
+	e.Write(wavBytes[:30])
+	if err := e.Close(); !errors.As(err, new(*WavError)) {
+		t.Errorf("truncated header: got %v, want a *WavError", err)
+	}
+	if _, err := NewUlawEncoder(io.Discard, nil).Write(raw); !errors.As(err, new(*WavError)) {
+		t.Errorf("raw PCM without a rate: got %v, want a *WavError", err)
+	}
+
+	// Complete but malformed headers fail on the write that completes them
+	mp3 := bytes.Clone(wavBytes[:44])
+	binary.LittleEndian.PutUint16(mp3[20:], 0x55)
+	avi := bytes.Clone(wavBytes[:44])
+	copy(avi[8:], "AVI ")
+	for name, header := range map[string][]byte{"format tag 0x55": mp3, "AVI form": avi} {
+		if _, err := NewUlawEncoder(io.Discard, nil).Write(header); !errors.As(err, new(*WavError)) {
+			t.Errorf("%s: got %v, want a *WavError", name, err)
+		}
+	}
+}
+
+func TestWavDecoder(t *testing.T) {
+	ulaw := encodeUlawSamples(sineWave(8000, 440, 8000, 0.3))
+	reference, err := NewChunkDecoder(16000, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := append(reference.Decode(ulaw), reference.Flush()...)
+
+	// Chunks arrive one read at a time from a pipe
+	pr, pw := io.Pipe()
+	go func() {
+		for i := 0; i < len(ulaw); i += 160 {
+			pw.Write(ulaw[i:min(i+160, len(ulaw))])
+		}
+		pw.Close()
+	}()
+	d, err := NewWavDecoder(pr, 16000, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wav, err := io.ReadAll(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(wav) != 44+2*len(want) || string(wav[:4]) != "RIFF" || binary.LittleEndian.Uint32(wav[24:]) != 16000 {
+		t.Fatalf("got %d bytes, want a 44-byte header and %d samples at 16 kHz", len(wav), len(want))
+	}
+	for i, s := range want {
+		if got := int16(binary.LittleEndian.Uint16(wav[44+2*i:])); got != s {
+			t.Fatalf("sample %d is %d, want %d", i, got, s)
+		}
+	}
+
+	if _, err := NewWavDecoder(pr, 0, 16); err == nil {
+		t.Error("rate 0 accepted")
+	}
+}
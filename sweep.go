@@ -0,0 +1,164 @@
+package wav2ulaw
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// ConfigMatrix lists the settings SweepConfigs combines. Every combination
+// of the non-empty lists is measured; an empty list keeps Base's setting.
+type ConfigMatrix struct {
+	// Settings shared by every combination, nil for DefaultAudioConfig with
+	// normalization and compression off, so the metrics reflect filtering and
+	// resampling only
+	Base            *AudioConfig
+	FilterTypes     []AntiAliasingType
+	FilterOrders    []int
+	WindowSizes     []int
+	WindowFunctions []WindowFunction
+	ResampleMethods []ResampleMethod
+}
+
+// SweepResult is the speed and quality of one configuration of a sweep
+type SweepResult struct {
+	// Settings that differ between combinations, e.g.
+	// "sinc/butterworth/order-4/window-16/blackman"
+	Name   string
+	Config *AudioConfig
+	// Mean time to convert all inputs once
+	Elapsed time.Duration
+	// Seconds of output audio produced per second of processing
+	RealTime float64
+	// Quality against the inputs, averaged over them; see QualityMetrics
+	PSNR               float64
+	SegmentalSNR       float64
+	SpectralDistortion float64
+	// Error converting or measuring an input; the other fields are then zero
+	Err error
+}
+
+// SweepConfigs converts every input, a WAV file, with each configuration of
+// matrix, iterations times for timing, and measures the quality of the
+// u-law against the input. Results are in matrix order: filter types vary
+// slowest and resample methods fastest. Configurations that fail, such as
+// invalid combinations, report their error in the result and do not stop
+// the sweep. Use BestSweepResult to pick one.
+func SweepConfigs(inputs [][]byte, matrix ConfigMatrix, iterations int) ([]SweepResult, error) {
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("no inputs to sweep")
+	}
+	iterations = max(iterations, 1)
+	base := matrix.Base
+	if base == nil {
+		base = DefaultAudioConfig()
+		base.NormalizePeak = 0
+		base.CompressionRatio = 1.0
+	}
+
+	var results []SweepResult
+	for _, filter := range orBase(matrix.FilterTypes, base.AntiAliasingType) {
+		for _, order := range orBase(matrix.FilterOrders, base.FilterOrder) {
+			for _, size := range orBase(matrix.WindowSizes, base.ResamplingWindowSize) {
+				for _, window := range orBase(matrix.WindowFunctions, base.WindowFunction) {
+					for _, method := range orBase(matrix.ResampleMethods, base.ResampleMethod) {
+						config := *base
+						config.AntiAliasingType = filter
+						config.FilterOrder = order
+						config.ResamplingWindowSize = size
+						config.WindowFunction = window
+						config.ResampleMethod = method
+						result := sweepConfig(inputs, &config, iterations)
+						result.Name = sweepName(matrix, &config)
+						results = append(results, result)
+					}
+				}
+			}
+		}
+	}
+	return results, nil
+}
+
+// orBase returns values, or just the base setting when values is empty
+func orBase[T any](values []T, base T) []T {
+	if len(values) == 0 {
+		return []T{base}
+	}
+	return values
+}
+
+// sweepConfig measures one configuration on all inputs
+func sweepConfig(inputs [][]byte, config *AudioConfig, iterations int) SweepResult {
+	result := SweepResult{Config: config}
+	outputs := make([][]byte, len(inputs))
+	start := time.Now()
+	for i := 0; i < iterations; i++ {
+		for j, input := range inputs {
+			output, err := ConvertWavBytesToUlaw(input, config)
+			if err != nil {
+				return SweepResult{Config: config, Err: fmt.Errorf("input %d: %v", j, err)}
+			}
+			outputs[j] = output
+		}
+	}
+	result.Elapsed = time.Since(start) / time.Duration(iterations)
+
+	audio := 0
+	for j, output := range outputs {
+		metrics, err := CompareWavToUlaw(inputs[j], output)
+		if err != nil {
+			return SweepResult{Config: config, Err: fmt.Errorf("input %d: %v", j, err)}
+		}
+		result.PSNR += metrics.PSNR / float64(len(inputs))
+		result.SegmentalSNR += metrics.SegmentalSNR / float64(len(inputs))
+		result.SpectralDistortion += metrics.SpectralDistortion / float64(len(inputs))
+		audio += len(output)
+	}
+	if result.Elapsed > 0 {
+		result.RealTime = samplesDuration(audio, 8000).Seconds() / result.Elapsed.Seconds()
+	}
+	return result
+}
+
+// sweepName names config by the settings matrix varies, or by its resampler
+// and filter when it varies none
+func sweepName(matrix ConfigMatrix, config *AudioConfig) string {
+	parts := []string{resampleMethodName(config.ResampleMethod), sweepLabel(selfTestFilterNames, int(config.AntiAliasingType))}
+	if len(matrix.FilterOrders) > 0 {
+		parts = append(parts, fmt.Sprintf("order-%d", config.FilterOrder))
+	}
+	if len(matrix.WindowSizes) > 0 {
+		parts = append(parts, fmt.Sprintf("window-%d", config.ResamplingWindowSize))
+	}
+	if len(matrix.WindowFunctions) > 0 {
+		parts = append(parts, sweepLabel(windowFunctionNames, int(config.WindowFunction)))
+	}
+	return strings.Join(parts, "/")
+}
+
+// sweepLabel returns the name of setting i, or its number when unnamed
+func sweepLabel(names []string, i int) string {
+	if i < 0 || i >= len(names) {
+		return fmt.Sprint(i)
+	}
+	return names[i]
+}
+
+// BestSweepResult returns the result with the highest segmental SNR among
+// those converting at least minRealTime times faster than real time, ties
+// going to the lower spectral distortion, or nil when none qualifies
+func BestSweepResult(results []SweepResult, minRealTime float64) *SweepResult {
+	var best *SweepResult
+	for i := range results {
+		r := &results[i]
+		if r.Err != nil || r.RealTime < minRealTime || math.IsNaN(r.SegmentalSNR) {
+			continue
+		}
+		if best == nil || r.SegmentalSNR > best.SegmentalSNR ||
+			(r.SegmentalSNR == best.SegmentalSNR && r.SpectralDistortion < best.SpectralDistortion) {
+			best = r
+		}
+	}
+	return best
+}
@@ -0,0 +1,53 @@
+package wav2ulaw
+
+import "testing"
+
+func TestSweepConfigs(t *testing.T) {
+	wavBytes, err := encodeWavPCM16(sineWave(44100, 1000, 22050, 0.5), 44100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	matrix := ConfigMatrix{
+		FilterTypes:     []AntiAliasingType{AASimple, AAButterworth},
+		WindowSizes:     []int{8, 32},
+		ResampleMethods: []ResampleMethod{ResampleSinc, ResampleFFT},
+	}
+	results, err := SweepConfigs([][]byte{wavBytes}, matrix, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 8 {
+		t.Fatalf("got %d results, want 8", len(results))
+	}
+	if name := results[0].Name; name != "sinc/simple/window-8" {
+		t.Errorf("first result named %q", name)
+	}
+	if name := results[7].Name; name != "fft/butterworth/window-32" {
+		t.Errorf("last result named %q", name)
+	}
+	for _, r := range results {
+		if r.Err != nil {
+			t.Fatalf("%s: %v", r.Name, r.Err)
+		}
+		if r.RealTime <= 0 || r.SegmentalSNR <= 0 {
+			t.Errorf("%s: %.1fx real time, segmental SNR %.1f dB", r.Name, r.RealTime, r.SegmentalSNR)
+		}
+	}
+
+	best := BestSweepResult(results, 0)
+	if best == nil {
+		t.Fatal("expected a best result")
+	}
+	for _, r := range results {
+		if r.SegmentalSNR > best.SegmentalSNR {
+			t.Errorf("%s beats the best result %s", r.Name, best.Name)
+		}
+	}
+	if BestSweepResult(results, 1e12) != nil {
+		t.Error("expected no result to be fast enough")
+	}
+
+	if _, err := SweepConfigs(nil, matrix, 1); err == nil {
+		t.Error("expected an error without inputs")
+	}
+}
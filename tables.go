@@ -0,0 +1,125 @@
+package wav2ulaw
+
+import "sync/atomic"
+
+var (
+	// Entries kept in each of the sinc table, polyphase bank and filter
+	// design caches, 0 for no limit
+	tableCacheLimit atomic.Int64
+	// Keys of the cached sinc tables and polyphase banks, oldest first,
+	// guarded by the mutex of their cache
+	sincTableOrder []int
+	polyphaseOrder []polyphaseKey
+)
+
+// TableCacheStats describes the lookup tables and filter designs cached by
+// the package
+type TableCacheStats struct {
+	// Sinc interpolation tables, one per resampling window size
+	SincTables int
+	// Polyphase resampler banks, one per rate pair and window setting
+	PolyphaseBanks int
+	// IIR anti-aliasing filter designs
+	FilterDesigns int
+	// Approximate memory held by the tables and banks
+	Bytes int64
+}
+
+// PrewarmTables builds the sinc interpolation tables of the given resampling
+// window sizes (none = the default size), so a latency-sensitive service
+// pays for them at startup instead of on its first request. Use
+// PrecomputeResampler for the polyphase banks of common rates.
+func PrewarmTables(windowSizes ...int) {
+	if len(windowSizes) == 0 {
+		windowSizes = []int{DefaultAudioConfig().ResamplingWindowSize}
+	}
+	for _, size := range windowSizes {
+		if size > 0 {
+			getSincTable(size)
+		}
+	}
+}
+
+// SetTableCacheLimit bounds the number of sinc tables, of polyphase banks
+// and of filter designs kept cached (0 = no limit, the default, though
+// filter designs are always bounded), so a long-running process converting
+// many different rates and settings holds bounded memory. The oldest
+// entries are dropped first, now and as new ones are built; a dropped entry
+// is built again when next needed.
+func SetTableCacheLimit(n int) {
+	tableCacheLimit.Store(int64(max(n, 0)))
+
+	cacheMutex.Lock()
+	sincTableOrder = evictOldest(sincTableCache, sincTableOrder)
+	cacheMutex.Unlock()
+
+	polyphaseCacheMutex.Lock()
+	polyphaseOrder = evictOldest(polyphaseCache, polyphaseOrder)
+	polyphaseCacheMutex.Unlock()
+
+	filterCacheMutex.Lock()
+	filterOrder = evictFilterDesigns(filterOrder)
+	filterCacheMutex.Unlock()
+}
+
+// ClearTableCaches drops every cached sinc table, polyphase bank and filter
+// design, e.g. after a burst of unusual settings. Conversions running
+// meanwhile are not affected.
+func ClearTableCaches() {
+	cacheMutex.Lock()
+	sincTableCache = make(map[int]*SincTable)
+	sincTableOrder = nil
+	cacheMutex.Unlock()
+
+	polyphaseCacheMutex.Lock()
+	polyphaseCache = make(map[polyphaseKey]*polyphaseBank)
+	polyphaseOrder = nil
+	polyphaseCacheMutex.Unlock()
+
+	filterCacheMutex.Lock()
+	filterCache = make(map[filterKey][]filterCoefficients)
+	filterOrder = nil
+	filterCacheMutex.Unlock()
+}
+
+// TableCacheUsage returns the current contents of the caches
+func TableCacheUsage() TableCacheStats {
+	var stats TableCacheStats
+
+	cacheMutex.RLock()
+	stats.SincTables = len(sincTableCache)
+	for _, table := range sincTableCache {
+		stats.Bytes += int64(len(table.values)) * 8
+	}
+	cacheMutex.RUnlock()
+
+	polyphaseCacheMutex.RLock()
+	stats.PolyphaseBanks = len(polyphaseCache)
+	for _, bank := range polyphaseCache {
+		for _, phase := range bank.phases {
+			stats.Bytes += int64(len(phase)) * 4
+		}
+	}
+	polyphaseCacheMutex.RUnlock()
+
+	filterCacheMutex.RLock()
+	stats.FilterDesigns = len(filterCache)
+	filterCacheMutex.RUnlock()
+	return stats
+}
+
+// evictOldest drops the oldest keys of cache beyond the limit and returns
+// the remaining order. The caller holds the cache's write lock.
+func evictOldest[K comparable, V any](cache map[K]V, order []K) []K {
+	return evictBeyond(cache, order, int(tableCacheLimit.Load()))
+}
+
+// evictBeyond drops the oldest keys of cache beyond limit (0 = no limit)
+// and returns the remaining order
+func evictBeyond[K comparable, V any](cache map[K]V, order []K, limit int) []K {
+	for limit > 0 && len(order) > limit {
+		delete(cache, order[0])
+		order = order[1:]
+	}
+	return order
+}
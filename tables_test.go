@@ -0,0 +1,62 @@
+package wav2ulaw
+
+import "testing"
+
+func TestTableCacheManagement(t *testing.T) {
+	defer SetTableCacheLimit(0)
+	ClearTableCaches()
+	if stats := TableCacheUsage(); stats != (TableCacheStats{}) {
+		t.Fatalf("caches not empty after clearing: %+v", stats)
+	}
+
+	PrewarmTables(16, 32)
+	if stats := TableCacheUsage(); stats.SincTables != 2 || stats.Bytes != 2*tableSize*8 {
+		t.Errorf("after prewarming two sizes: %+v", stats)
+	}
+
+	SetTableCacheLimit(1)
+	getSincTable(64)
+	cacheMutex.RLock()
+	_, kept := sincTableCache[64]
+	count := len(sincTableCache)
+	cacheMutex.RUnlock()
+	if !kept || count != 1 {
+		t.Errorf("limit 1 kept %d tables, newest kept %v", count, kept)
+	}
+
+	for _, size := range []int{8, 16, 24} {
+		config := DefaultAudioConfig()
+		config.ResamplingWindowSize = size
+		PrecomputeResampler(config, 44100)
+	}
+	if stats := TableCacheUsage(); stats.PolyphaseBanks != 1 || stats.Bytes <= tableSize*8 {
+		t.Errorf("limit 1 after three banks: %+v", stats)
+	}
+
+	SetTableCacheLimit(0)
+	for _, cutoff := range []float64{3000, 3200, 3400} {
+		applyButterworthFilter(make([]int16, 8), 16000, cutoff, 4)
+	}
+	if stats := TableCacheUsage(); stats.FilterDesigns != 3 {
+		t.Errorf("no limit after three filter designs: %+v", stats)
+	}
+	SetTableCacheLimit(2)
+	applyBesselFilter(make([]int16, 8), 16000, 3400, 4)
+	filterCacheMutex.RLock()
+	_, kept = filterCache[filterKey{kind: AABessel, sampleRate: 16000, cutoff: 3400, order: 4}]
+	count = len(filterCache)
+	filterCacheMutex.RUnlock()
+	if !kept || count != 2 {
+		t.Errorf("limit 2 kept %d filter designs, newest kept %v", count, kept)
+	}
+
+	// Conversions rebuild what was dropped
+	ClearTableCaches()
+	wavBytes, err := encodeWavPCM16(sineWave(44100, 1000, 44100, 0.5), 44100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ulaw, err := ConvertWavBytesToUlaw(wavBytes, DefaultAudioConfig()); err != nil || len(ulaw) != 8000 {
+		t.Errorf("conversion after clearing: %d bytes, %v", len(ulaw), err)
+	}
+}
@@ -0,0 +1,64 @@
+package wav2ulaw
+
+import (
+	"fmt"
+	"time"
+)
+
+// TalkStats measures who spoke when in a two-channel call recording, such
+// as one with the agent on the left channel and the customer on the right,
+// as found by the voice activity detector of DetectSpeech
+type TalkStats struct {
+	// Length of the recording
+	Duration time.Duration
+	// Speech time of the left and right channel
+	Speech [2]time.Duration
+	// Time both channels spoke at once (double-talk)
+	Overlap time.Duration
+	// Longest stretch in which neither channel spoke (dead air)
+	LongestSilence time.Duration
+}
+
+// AnalyzeTalk measures the talk time of each channel of a two-channel WAV
+// file. Each channel is run through the voice activity detector on its own,
+// against its own noise floor, in 20 ms frames.
+func AnalyzeTalk(wavBytes []byte) (*TalkStats, error) {
+	decoded, err := decodeWavFloat(wavBytes)
+	if err != nil {
+		return nil, err
+	}
+	if decoded.channels != 2 {
+		return nil, fmt.Errorf("expected a two-channel WAV file, got %d channel(s)", decoded.channels)
+	}
+	var active [2][]bool
+	for ch := range active {
+		channel := make([]float64, len(decoded.data)/2)
+		for i := range channel {
+			channel[i] = decoded.data[2*i+ch]
+		}
+		active[ch] = detectVoiceActivity(channel, decoded.sampleRate)
+	}
+
+	frame := time.Duration(analysisFrameMs) * time.Millisecond
+	stats := &TalkStats{Duration: time.Duration(len(decoded.data)/2) * time.Second / time.Duration(decoded.sampleRate)}
+	silence := 0
+	for i := range active[0] {
+		left, right := active[0][i], active[1][i]
+		if left {
+			stats.Speech[0] += frame
+		}
+		if right {
+			stats.Speech[1] += frame
+		}
+		if left && right {
+			stats.Overlap += frame
+		}
+		if left || right {
+			silence = 0
+			continue
+		}
+		silence++
+		stats.LongestSilence = max(stats.LongestSilence, time.Duration(silence)*frame)
+	}
+	return stats, nil
+}
@@ -0,0 +1,58 @@
+package wav2ulaw
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAnalyzeTalk(t *testing.T) {
+	// Left speaks 0-2 s and 5-6 s, right 1.5-3 s, in a 6 s call
+	const rate = 8000
+	call := make([]int16, 2*6*rate)
+	speak := func(ch int, from, to float64) {
+		tone := sineWave(int((to-from)*rate), 300+200*float64(ch), rate, 0.3)
+		for i, s := range tone {
+			call[2*(int(from*rate)+i)+ch] = s
+		}
+	}
+	speak(0, 0, 2)
+	speak(0, 5, 6)
+	speak(1, 1.5, 3)
+	wavBytes, err := encodeWavPCM16Channels(call, rate, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := AnalyzeTalk(wavBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Speech runs on for the detector's hangover after each stretch
+	near := func(name string, got, want time.Duration) {
+		if d := got - want; d < -250*time.Millisecond || d > 250*time.Millisecond {
+			t.Errorf("%s %v, want about %v", name, got, want)
+		}
+	}
+	if stats.Duration != 6*time.Second {
+		t.Errorf("duration %v, want 6s", stats.Duration)
+	}
+	near("left speech", stats.Speech[0], 3*time.Second)
+	near("right speech", stats.Speech[1], 1500*time.Millisecond)
+	near("overlap", stats.Overlap, 500*time.Millisecond)
+	near("longest silence", stats.LongestSilence, 2*time.Second)
+
+	// Conversions of two-channel input report the same
+	_, info, err := ConvertWavBytesToUlawInfo(wavBytes, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Talk == nil || *info.Talk != *stats {
+		t.Errorf("conversion talk stats %+v, want %+v", info.Talk, stats)
+	}
+	if _, info, err = ConvertWavBytesToUlawInfo(encodeWavDepth(t, sineWave(8000, 440, 8000, 0.3), 8000, 16, 1), nil); err != nil || info.Talk != nil {
+		t.Errorf("mono conversion talk stats %+v (%v), want none", info.Talk, err)
+	}
+	if _, err := AnalyzeTalk(encodeWavDepth(t, sineWave(8000, 440, 8000, 0.3), 8000, 16, 1)); err == nil {
+		t.Error("mono input accepted")
+	}
+}
@@ -0,0 +1,97 @@
+package wav2ulaw
+
+import "math"
+
+const (
+	// Range of TargetLoudness accepted (LUFS); louder programs cannot stay
+	// under the limiter ceiling without audible pumping
+	minTargetLoudness = -40.0
+	maxTargetLoudness = -8.0
+	// Sample peak the limiter holds the output to: -1 dBFS, clear of the
+	// largest u-law level (32124)
+	limiterCeiling = 0.89
+	// Limiter gain changes: the lookahead over which it ducks ahead of a
+	// peak and the time constant of its recovery
+	limiterLookaheadMs = 5
+	limiterReleaseMs   = 80
+	// Measure-and-correct rounds making up for the loudness the limiter
+	// takes away, and the error (LU) at which they stop
+	loudnessPasses    = 3
+	loudnessTolerance = 0.1
+)
+
+// matchLoudness scales samples in place to an integrated loudness of target
+// LUFS and limits their peaks to limiterCeiling. The loudness is measured on
+// the signal as it will be encoded, and remeasured after limiting, so input
+// of any level and crest factor ends up at the same perceived volume. It
+// returns the gain applied before limiting (dB) and true; silence, with no
+// loudness above the absolute gate, is left as is and returns 0 and false.
+func matchLoudness(samples []int16, sampleRate int, target float64) (float64, bool) {
+	signal := make([]float64, len(samples))
+	for i, s := range samples {
+		signal[i] = float64(s) / 32768
+	}
+	loudness := measureLoudness([][]float64{signal}, sampleRate).Integrated
+	if math.IsInf(loudness, -1) {
+		return 0, false
+	}
+
+	gainDb := target - loudness
+	output := make([]float64, len(signal))
+	for pass := 0; pass < loudnessPasses; pass++ {
+		gain := math.Pow(10, gainDb/20)
+		for i, v := range signal {
+			output[i] = v * gain
+		}
+		limitPeaks(output, sampleRate)
+		loudness = measureLoudness([][]float64{output}, sampleRate).Integrated
+		if math.Abs(target-loudness) <= loudnessTolerance {
+			break
+		}
+		gainDb += target - loudness
+	}
+	for i, v := range output {
+		samples[i] = int16(math.Max(-32768, math.Min(32767, math.Round(v*32768))))
+	}
+	return gainDb, true
+}
+
+// limitPeaks holds signal under limiterCeiling in place with a lookahead
+// limiter. The gain each sample needs is held over the lookahead and
+// averaged over it, so the gain ramps down ahead of a peak and is at most
+// the needed gain at the peak itself, then recovers with the release time.
+func limitPeaks(signal []float64, sampleRate int) {
+	lookahead := max(1, sampleRate*limiterLookaheadMs/1000)
+	needed := make([]float64, len(signal))
+	for i, v := range signal {
+		needed[i] = 1
+		if a := math.Abs(v); a > limiterCeiling {
+			needed[i] = limiterCeiling / a
+		}
+	}
+
+	// held[j] is the smallest gain needed from j to the end of its lookahead
+	held := make([]float64, len(signal))
+	for j := range held {
+		held[j] = 1
+		for k := j; k < min(j+lookahead, len(signal)); k++ {
+			held[j] = math.Min(held[j], needed[k])
+		}
+	}
+
+	release := 1 - math.Exp(-1/(limiterReleaseMs/1000.0*float64(sampleRate)))
+	sum, envelope := 0.0, 1.0
+	for i := range signal {
+		sum += held[i]
+		if i >= lookahead {
+			sum -= held[i-lookahead]
+		}
+		gain := sum / float64(min(i+1, lookahead))
+		if gain < envelope {
+			envelope = gain
+		} else {
+			envelope += (gain - envelope) * release
+		}
+		signal[i] *= envelope
+	}
+}
@@ -0,0 +1,111 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+)
+
+// clickyProgram returns testProgram peaking at peak with short clicks at
+// full peak added twice a second, raising its crest factor well above the
+// plain program's
+func clickyProgram(rate int, peak float64) []int16 {
+	samples := testProgram(rate, peak/4)
+	for at := rate / 4; at < len(samples); at += rate / 2 {
+		for i := 0; i < rate/1000; i++ {
+			samples[at+i] = int16(peak * 32767 * math.Sin(2*math.Pi*float64(i)/float64(rate/1000)))
+		}
+	}
+	return samples
+}
+
+func TestLimitPeaks(t *testing.T) {
+	signal := make([]float64, 8000)
+	for i := range signal {
+		signal[i] = 0.5 * math.Sin(2*math.Pi*440*float64(i)/8000)
+	}
+	signal[4000] = -1.5
+	limitPeaks(signal, 8000)
+
+	for i, v := range signal {
+		if math.Abs(v) > limiterCeiling+1e-9 {
+			t.Fatalf("sample %d at %.3f, above the ceiling", i, v)
+		}
+	}
+	// Away from the peak the signal is untouched, and it recovers
+	for _, i := range []int{1000, 3900, 7900} {
+		want := 0.5 * math.Sin(2*math.Pi*440*float64(i)/8000)
+		if math.Abs(signal[i]-want) > 0.01 {
+			t.Errorf("sample %d: %.3f, want %.3f", i, signal[i], want)
+		}
+	}
+}
+
+func TestMatchLoudness(t *testing.T) {
+	for _, input := range []struct {
+		name    string
+		samples []int16
+	}{
+		{"quiet", testProgram(8000, 0.0316)},
+		{"loud", testProgram(8000, 0.89)},
+		{"quiet with clicks", clickyProgram(8000, 0.0316)},
+		{"loud with clicks", clickyProgram(8000, 0.89)},
+	} {
+		samples := append([]int16(nil), input.samples...)
+		matchLoudness(samples, 8000, -18)
+		if got := programLoudness(t, samples, 8000); math.Abs(got+18) > loudnessTolerance+0.05 {
+			t.Errorf("%s: %.2f LUFS, want -18", input.name, got)
+		}
+		for i, s := range samples {
+			if math.Abs(float64(s)) > limiterCeiling*32768+1 {
+				t.Fatalf("%s: sample %d at %d, above the limiter ceiling", input.name, i, s)
+			}
+		}
+	}
+
+	silence := make([]int16, 8000)
+	if gain, ok := matchLoudness(silence, 8000, -18); gain != 0 || ok {
+		t.Errorf("silence: gain %.1f dB, %v, want 0 and false", gain, ok)
+	}
+}
+
+func TestConfigForPromptLibrary(t *testing.T) {
+	// Prompts of different level and crest factor come out together; peak
+	// normalization leaves the clicky ones quieter
+	spread := func(preset func() *AudioConfig) (lo, hi float64) {
+		lo, hi = math.Inf(1), math.Inf(-1)
+		for _, input := range [][]int16{
+			testProgram(16000, 0.0316), testProgram(16000, 0.89),
+			clickyProgram(16000, 0.0316), clickyProgram(16000, 0.89),
+		} {
+			ulaw, err := ConvertPCM16ToUlaw(input, 16000, preset())
+			if err != nil {
+				t.Fatal(err)
+			}
+			loudness := programLoudness(t, decodeUlawSamples(ulaw), 8000)
+			lo, hi = math.Min(lo, loudness), math.Max(hi, loudness)
+		}
+		return lo, hi
+	}
+	if lo, hi := spread(ConfigForPromptLibrary); lo < -14.5 || hi > -13.5 {
+		t.Errorf("prompt library from %.2f to %.2f LUFS, want -14 ±0.5", lo, hi)
+	}
+	if lo, hi := spread(ConfigForIVRPrompts); hi-lo < 3 {
+		t.Errorf("IVR prompts from %.2f to %.2f LUFS, expected the clicky prompts to fall behind", lo, hi)
+	}
+
+	config := ConfigForPromptLibrary()
+	wavBytes, err := encodeWavPCM16(testProgram(16000, 0.3), 16000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ConvertWavStreamToUlaw(bytes.NewReader(wavBytes), &bytes.Buffer{}, config); err == nil {
+		t.Error("expected streaming to reject TargetLoudness")
+	}
+	config.TargetLoudness = -3
+	var configErr *ConfigError
+	if _, err := ConvertWavBytesToUlaw(wavBytes, config); !errors.As(err, &configErr) || configErr.Field != "TargetLoudness" {
+		t.Errorf("TargetLoudness -3: got %v, want a *ConfigError", err)
+	}
+}
@@ -0,0 +1,59 @@
+package wav2ulaw
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	// Band limits of the simulated telephone channel (Hz)
+	telephoneLowCutoff  = 300
+	telephoneHighCutoff = 3400
+	// Soft-clipping drive of the simulated line (1.0 = clean)
+	telephoneDrive = 2.0
+)
+
+// ApplyTelephoneEffect makes WAV audio sound as if it was played over a phone
+// line: band-limited to 300-3400 Hz, mildly distorted and compressed. Unlike
+// ConvertWavBytesToUlaw it returns a mono 16-bit WAV at the input sample rate,
+// keeping the INFO tags and bext chunk of the input.
+func ApplyTelephoneEffect(wavBytes []byte) ([]byte, error) {
+	samples, sampleRate, err := decodeWavSamples(wavBytes, DefaultAudioConfig())
+	if err != nil {
+		return nil, err
+	}
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("WAV file contains no samples")
+	}
+
+	samples = telephoneEffect(samples, float64(sampleRate))
+	output, err := encodeWavPCM16(samples, sampleRate)
+	if err != nil {
+		return nil, err
+	}
+	if md, err := ReadWavMetadata(wavBytes); err == nil && !md.IsEmpty() {
+		return WriteWavMetadata(output, md)
+	}
+	return output, nil
+}
+
+// telephoneEffect applies the telephone channel simulation to samples
+func telephoneEffect(samples []int16, sampleRate float64) []int16 {
+	// Cascade two sections of each filter for a steeper band edge
+	samples = applyHighPassFilter(samples, sampleRate, telephoneLowCutoff)
+	samples = applyHighPassFilter(samples, sampleRate, telephoneLowCutoff)
+	if sampleRate > 2*telephoneHighCutoff {
+		samples = applyButterworthFilter(samples, sampleRate, telephoneHighCutoff, 2)
+		samples = applyButterworthFilter(samples, sampleRate, telephoneHighCutoff, 2)
+	}
+
+	// Mild tanh saturation, normalized so full scale maps to full scale
+	norm := math.Tanh(telephoneDrive)
+	for i, sample := range samples {
+		x := float64(sample) / 32767.0
+		samples[i] = int16(math.Round(math.Tanh(telephoneDrive*x) / norm * 32767.0))
+	}
+
+	samples = applyCompression(samples, 4.0, 0.3)
+	return normalizeAudio(samples, 0.9)
+}
@@ -0,0 +1,103 @@
+package wav2ulaw
+
+import "math"
+
+const (
+	// WSOLA frame length in milliseconds
+	wsolaFrameMs = 30
+	// Maximum distance searched around the nominal analysis position, in milliseconds
+	wsolaSearchMs = 10
+)
+
+// timeStretch changes the tempo of the samples without changing pitch using
+// waveform-similarity overlap-add (WSOLA). tempo > 1.0 speeds speech up,
+// tempo < 1.0 slows it down. Output length is len(samples)/tempo.
+func timeStretch(samples []int16, sampleRate int, tempo float64) []int16 {
+	frameLen := sampleRate * wsolaFrameMs / 1000
+	if tempo <= 0 || tempo == 1.0 || len(samples) < frameLen*2 {
+		return samples
+	}
+
+	synthesisHop := frameLen / 2
+	analysisHop := float64(synthesisHop) * tempo
+	searchRange := sampleRate * wsolaSearchMs / 1000
+
+	outputLen := int(math.Round(float64(len(samples)) / tempo))
+	output := make([]float64, outputLen+frameLen)
+	norm := make([]float64, outputLen+frameLen)
+	window := makeWindow(WindowHann, frameLen, 0)
+
+	input := make([]float64, len(samples))
+	for i, sample := range samples {
+		input[i] = float64(sample)
+	}
+
+	prevPos := 0
+	for k := 0; k*synthesisHop < outputLen; k++ {
+		outPos := k * synthesisHop
+		pos := 0
+		if k > 0 {
+			// Natural continuation of the previously copied frame
+			natural := prevPos + synthesisHop
+			nominal := int(math.Round(float64(k) * analysisHop))
+			pos = bestOverlapPosition(input, natural, nominal, searchRange, frameLen)
+		}
+		if pos+frameLen > len(input) {
+			pos = len(input) - frameLen
+		}
+
+		for i := 0; i < frameLen; i++ {
+			output[outPos+i] += input[pos+i] * window[i]
+			norm[outPos+i] += window[i]
+		}
+		prevPos = pos
+	}
+
+	result := make([]int16, outputLen)
+	for i := range result {
+		v := output[i]
+		if norm[i] > 1e-3 {
+			v /= norm[i]
+		}
+		result[i] = int16(math.Max(-32768, math.Min(32767, math.Round(v))))
+	}
+	return result
+}
+
+// bestOverlapPosition searches around nominal for the input frame most
+// similar to the natural continuation starting at natural
+func bestOverlapPosition(input []float64, natural, nominal, searchRange, frameLen int) int {
+	maxPos := len(input) - frameLen
+	if natural > maxPos {
+		natural = maxPos
+	}
+
+	best := nominal
+	bestScore := math.Inf(-1)
+	for pos := nominal - searchRange; pos <= nominal+searchRange; pos++ {
+		if pos < 0 || pos > maxPos {
+			continue
+		}
+		// Normalized cross-correlation over the overlap region
+		dot, energy := 0.0, 0.0
+		for i := 0; i < frameLen/2; i++ {
+			a := input[natural+i]
+			b := input[pos+i]
+			dot += a * b
+			energy += b * b
+		}
+		score := dot / math.Sqrt(energy+1)
+		if score > bestScore {
+			bestScore = score
+			best = pos
+		}
+	}
+
+	if best < 0 {
+		best = 0
+	}
+	if best > maxPos {
+		best = maxPos
+	}
+	return best
+}
@@ -0,0 +1,27 @@
+package wav2ulaw
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTimeStretchPreservesPitch(t *testing.T) {
+	input := sineWave(8000, 500, 8000, 0.5)
+
+	for _, tempo := range []float64{0.9, 1.1} {
+		output := timeStretch(input, 8000, tempo)
+
+		want := int(math.Round(8000 / tempo))
+		if len(output) != want {
+			t.Fatalf("tempo %.2f: expected %d samples, got %d", tempo, want, len(output))
+		}
+		// The tone must stay at 500 Hz rather than shifting to 500*tempo
+		mid := output[800 : len(output)-800]
+		if level := toneLevel(mid, 500, 8000); level < 0.4 {
+			t.Errorf("tempo %.2f: 500 Hz level dropped to %.3f", tempo, level)
+		}
+		if level := toneLevel(mid, 500*tempo, 8000); level > 0.1 {
+			t.Errorf("tempo %.2f: pitch shifted, level at %.0f Hz is %.3f", tempo, 500*tempo, level)
+		}
+	}
+}
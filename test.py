@@ -137,6 +137,7 @@ def wav_to_ulaw(wav_bytes, input_sample_rate=0, force_mono=True,
             './wav2ulaw',
             '--input', temp_pcm_path,
             '--output', temp_ulaw_path,
+            '--force',  # the temporary output file already exists
             '--mode', 'wav2ulaw',
             '--sample-rate', str(input_sample_rate),
             '--low-pass', str(low_pass),
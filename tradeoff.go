@@ -0,0 +1,70 @@
+package wav2ulaw
+
+import "math"
+
+// ResampleTradeoff selects how the sinc resampler of a conversion weighs
+// speed against accuracy. It applies to whole-file and streamed conversions
+// with ResampleSinc; ChunkEncoder always uses its own streaming resampler.
+type ResampleTradeoff int
+
+const (
+	// Dedicated decimators for integer ratios, cached polyphase banks for
+	// small ratios and an interpolated sinc table for the rest
+	ResampleBalanced ResampleTradeoff = iota
+	// The balanced paths with kernels of half the resampling window (at
+	// least 4), up to twice as fast with a wider transition
+	// band and more aliasing
+	ResampleFast
+	// Every kernel coefficient computed exactly for its output sample, with
+	// the cutoff following the output Nyquist frequency when downsampling;
+	// 30 or more times slower than balanced. With ResampleFFT, Best makes no
+	// difference.
+	ResampleBest
+)
+
+// Smallest kernel half-width ResampleFast shortens the window to
+const fastMinWindowSize = 4
+
+// tradeoffConfig returns config as the resampling stages run it: with the
+// shortened window of ResampleFast, or config itself
+func tradeoffConfig(config *AudioConfig) *AudioConfig {
+	if config.ResampleTradeoff != ResampleFast || config.ResamplingWindowSize <= fastMinWindowSize {
+		return config
+	}
+	fast := *config
+	fast.ResamplingWindowSize = max(fastMinWindowSize, config.ResamplingWindowSize/2)
+	return &fast
+}
+
+// resampleExact resamples with a windowed sinc evaluated exactly at every
+// tap, the reference the faster paths approximate. When downsampling, the
+// sinc is widened to keep windowSize zero crossings at the output rate, so
+// it band-limits the output itself. Past the edges the signal is mirrored.
+func resampleExact(input []int16, inputRate, outputRate int, config *AudioConfig) []int16 {
+	cutoff := math.Min(1, float64(outputRate)/float64(inputRate))
+	half := int(math.Ceil(float64(config.ResamplingWindowSize) / cutoff))
+	window := resampleWindow(half, config)
+	step := float64(inputRate) / float64(outputRate)
+	output := getInt16s(int(math.Ceil(float64(len(input)) / step)))
+
+	for i := range output {
+		pos := float64(i) * step
+		idx := int(pos)
+		sum, weightSum := 0.0, 0.0
+		for k := -half; k <= half; k++ {
+			x := math.Pi * cutoff * (pos - float64(idx+k))
+			sinc := 1.0
+			if x != 0 {
+				sinc = math.Sin(x) / x
+			}
+			weight := window[k+half] * sinc
+			sum += float64(input[reflectIndex(idx+k, len(input))]) * weight
+			weightSum += weight
+		}
+		if weightSum > 0 {
+			sum /= weightSum
+		}
+		output[i] = int16(math.Max(-32768, math.Min(32767, math.Round(sum))))
+	}
+	return output
+}
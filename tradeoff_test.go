@@ -0,0 +1,111 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"testing"
+)
+
+// toneMix returns n samples at rate of equal 0.2 full-scale sines at freqs
+func toneMix(n, rate int, freqs ...float64) []int16 {
+	samples := make([]int16, n)
+	for i := range samples {
+		v := 0.0
+		for _, f := range freqs {
+			v += 0.2 * math.Sin(2*math.Pi*f*float64(i)/float64(rate))
+		}
+		samples[i] = int16(v * 32767)
+	}
+	return samples
+}
+
+// TestResampleTradeoffQuality measures the resampling stage alone, with a
+// 16-tap window, against tones generated at 8 kHz. Typical results:
+//
+//	           passband SNR (dB)        5 kHz alias (dB)
+//	           16k   44.1k  48k          16k   44.1k  48k
+//	fast       22    84     17           -17   0      -12
+//	balanced   44    85     28           -39   0      -23
+//	best       80    79     80           -75   -75    -75
+//
+// Balanced and fast leave most alias rejection to the conversion's
+// anti-aliasing filter, and the 48 kHz decimators already roll off at
+// 3 kHz. Best band-limits on its own at any rate.
+func TestResampleTradeoffQuality(t *testing.T) {
+	minSNR := map[ResampleTradeoff]float64{ResampleFast: 15, ResampleBalanced: 25, ResampleBest: 70}
+	for _, rate := range []int{11025, 16000, 22050, 44100, 48000} {
+		reference := toneMix(16000, 8000, 300, 1000, 3000)
+		for _, tradeoff := range []ResampleTradeoff{ResampleFast, ResampleBalanced, ResampleBest} {
+			config := DefaultAudioConfig()
+			config.ResamplingWindowSize = 16
+			config.ResampleTradeoff = tradeoff
+
+			output := resample(toneMix(2*rate, rate, 300, 1000, 3000), rate, 8000, config)
+			if len(output) != 16000 {
+				t.Fatalf("%d Hz, tradeoff %d: got %d samples, want 16000", rate, tradeoff, len(output))
+			}
+			signal, noise := 0.0, 0.0
+			// Skip the edges, where the kernel reaches past the input
+			for i := 400; i < 15600; i++ {
+				d := float64(output[i]) - float64(reference[i])
+				signal += float64(reference[i]) * float64(reference[i])
+				noise += d * d
+			}
+			if snr := 10 * math.Log10(signal/noise); snr < minSNR[tradeoff] {
+				t.Errorf("%d Hz, tradeoff %d: passband SNR %.1f dB, want at least %g", rate, tradeoff, snr, minSNR[tradeoff])
+			}
+
+			if tradeoff == ResampleBest {
+				alias := resample(toneMix(2*rate, rate, 5000), rate, 8000, config)
+				power := 0.0
+				for _, v := range alias[400:15600] {
+					power += float64(v) * float64(v)
+				}
+				if level := 10 * math.Log10(power/15200/(0.02*32767*32767)); level > -60 {
+					t.Errorf("%d Hz: 5 kHz tone aliased at %.1f dB, want below -60", rate, level)
+				}
+			}
+		}
+	}
+}
+
+func TestResampleTradeoffConversion(t *testing.T) {
+	wavBytes, err := encodeWavPCM16(toneMix(48000, 48000, 1000), 48000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outputs := map[ResampleTradeoff][]byte{}
+	for _, tradeoff := range []ResampleTradeoff{ResampleFast, ResampleBalanced, ResampleBest} {
+		config := DefaultAudioConfig()
+		config.ResampleTradeoff = tradeoff
+		if outputs[tradeoff], err = ConvertWavBytesToUlaw(wavBytes, config); err != nil {
+			t.Fatal(err)
+		}
+		if len(outputs[tradeoff]) != 8000 {
+			t.Errorf("tradeoff %d: got %d bytes, want 8000", tradeoff, len(outputs[tradeoff]))
+		}
+	}
+	if bytes.Equal(outputs[ResampleFast], outputs[ResampleBalanced]) || bytes.Equal(outputs[ResampleBest], outputs[ResampleBalanced]) {
+		t.Error("the tradeoff does not change the conversion")
+	}
+}
+
+// BenchmarkResampleTradeoff times the resampling stage on one second of
+// audio. Typical results with a 16-tap window: fast takes 0.55-0.8 times the
+// time of balanced, best 30-80 times.
+func BenchmarkResampleTradeoff(b *testing.B) {
+	for _, rate := range []int{16000, 44100, 48000} {
+		input := toneMix(rate, rate, 300, 1000, 3000)
+		for _, tradeoff := range []ResampleTradeoff{ResampleFast, ResampleBalanced, ResampleBest} {
+			config := DefaultAudioConfig()
+			config.ResamplingWindowSize = 16
+			config.ResampleTradeoff = tradeoff
+			b.Run(fmt.Sprintf("%d/%s", rate, tradeoffNames[tradeoff]), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					putInt16s(resample(input, rate, 8000, config))
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package wav2ulaw
+
+import (
+	"math"
+	"time"
+)
+
+// AutoTrim cuts the output down to the speech the voice activity detector
+// finds, dropping leading and trailing silence, hold tones below the
+// detection threshold and other dead air
+type AutoTrim struct {
+	// Audio kept before the first speech (0 = none)
+	PreRoll time.Duration
+	// Audio kept after the last speech, on top of the detector's own
+	// hangover (0 = none)
+	PostRoll time.Duration
+}
+
+// trimToSpeech returns samples from trim.PreRoll before the first detected
+// speech to trim.PostRoll after the last. Input without speech is returned
+// whole, so a quiet recording is not reduced to nothing. Like other stages
+// that change the length it may return a new pooled buffer.
+func trimToSpeech(samples []int16, sampleRate int, trim *AutoTrim) []int16 {
+	segments := DetectSpeech(samples, sampleRate)
+	if len(segments) == 0 {
+		return samples
+	}
+	first := segments[0].Start - trim.PreRoll
+	last := segments[len(segments)-1].End + trim.PostRoll
+	start := max(0, int(math.Round(first.Seconds()*float64(sampleRate))))
+	end := min(len(samples), int(math.Round(last.Seconds()*float64(sampleRate))))
+	if start == 0 {
+		return samples[:end]
+	}
+	out := getInt16s(end - start)
+	copy(out, samples[start:end])
+	return out
+}
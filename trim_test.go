@@ -0,0 +1,44 @@
+package wav2ulaw
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAutoTrim(t *testing.T) {
+	// Two seconds of silence, one of speech-like tone, one of silence
+	samples := make([]int16, 4*16000)
+	copy(samples[2*16000:], sineWave(16000, 440, 16000, 0.5))
+
+	config := DefaultAudioConfig()
+	config.AutoTrim = &AutoTrim{PreRoll: 100 * time.Millisecond, PostRoll: 100 * time.Millisecond}
+	ulaw, err := ConvertPCM16ToUlaw(samples, 16000, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// One second of tone, the rolls and the detector's 200 ms hangover
+	if got := UlawDuration(len(ulaw)); got < 1300*time.Millisecond || got > 1500*time.Millisecond {
+		t.Errorf("trimmed to %v, want about 1.4s", got)
+	}
+	// The pre-roll is kept before the first speech
+	decoded := decodeUlawSamples(ulaw)
+	for i, s := range decoded[:700] {
+		if s > 1000 || s < -1000 {
+			t.Fatalf("audio at sample %d, want about 800 samples of pre-roll", i)
+		}
+	}
+
+	// Nothing is detected in silence, which is kept whole
+	ulaw, err = ConvertPCM16ToUlaw(make([]int16, 16000), 16000, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ulaw) != 8000 {
+		t.Errorf("silent input trimmed to %d samples, want 8000", len(ulaw))
+	}
+
+	config.AutoTrim.PreRoll = -time.Second
+	if _, err := ConvertPCM16ToUlaw(samples, 16000, config); err == nil {
+		t.Error("expected an error for a negative pre-roll")
+	}
+}
@@ -0,0 +1,188 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// TwilioFrameSize is the u-law payload of one Twilio media message: 20 ms
+// at 8 kHz
+const TwilioFrameSize = 160
+
+// TwilioMessage is a Twilio Media Streams WebSocket message. Twilio sends
+// connected, start, media, dtmf, mark and stop events; the application sends
+// media, mark and clear events back on bidirectional streams.
+type TwilioMessage struct {
+	Event          string       `json:"event"`
+	SequenceNumber string       `json:"sequenceNumber,omitempty"`
+	StreamSid      string       `json:"streamSid,omitempty"`
+	Protocol       string       `json:"protocol,omitempty"` // connected only
+	Version        string       `json:"version,omitempty"`  // connected only
+	Start          *TwilioStart `json:"start,omitempty"`
+	Media          *TwilioMedia `json:"media,omitempty"`
+	Mark           *TwilioMark  `json:"mark,omitempty"`
+	DTMF           *TwilioDTMF  `json:"dtmf,omitempty"`
+	Stop           *TwilioStop  `json:"stop,omitempty"`
+}
+
+// TwilioStart describes the stream in a start event
+type TwilioStart struct {
+	AccountSid       string            `json:"accountSid"`
+	StreamSid        string            `json:"streamSid"`
+	CallSid          string            `json:"callSid"`
+	Tracks           []string          `json:"tracks"`
+	MediaFormat      TwilioMediaFormat `json:"mediaFormat"`
+	CustomParameters map[string]string `json:"customParameters,omitempty"`
+}
+
+// TwilioMediaFormat is the audio format of a stream, always 8 kHz mono u-law
+// ("audio/x-mulaw") as of this writing
+type TwilioMediaFormat struct {
+	Encoding   string `json:"encoding"`
+	SampleRate int    `json:"sampleRate"`
+	Channels   int    `json:"channels"`
+}
+
+// TwilioMedia carries one frame of base64 u-law. Track, Chunk and Timestamp
+// (milliseconds since the stream started) are only set by Twilio.
+type TwilioMedia struct {
+	Track     string `json:"track,omitempty"`
+	Chunk     string `json:"chunk,omitempty"`
+	Timestamp string `json:"timestamp,omitempty"`
+	Payload   string `json:"payload"`
+}
+
+// TwilioMark names a point in the outbound audio; Twilio echoes it back once
+// the audio before it has played
+type TwilioMark struct {
+	Name string `json:"name"`
+}
+
+// TwilioDTMF is a key press on the call
+type TwilioDTMF struct {
+	Track string `json:"track"`
+	Digit string `json:"digit"`
+}
+
+// TwilioStop ends a stream
+type TwilioStop struct {
+	AccountSid string `json:"accountSid"`
+	CallSid    string `json:"callSid"`
+}
+
+// ParseTwilioMessage decodes one Media Streams WebSocket message
+func ParseTwilioMessage(data []byte) (*TwilioMessage, error) {
+	var m TwilioMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid Twilio message: %v", err)
+	}
+	if m.Event == "" {
+		return nil, fmt.Errorf("invalid Twilio message: no event")
+	}
+	if m.Event == "start" && m.Start != nil {
+		f := m.Start.MediaFormat
+		if f.Encoding != "audio/x-mulaw" || f.SampleRate != 8000 || f.Channels != 1 {
+			return nil, fmt.Errorf("unsupported Twilio media format %s, %d Hz, %d channel(s)", f.Encoding, f.SampleRate, f.Channels)
+		}
+	}
+	return &m, nil
+}
+
+// Ulaw returns the u-law audio of a media message
+func (m *TwilioMessage) Ulaw() ([]byte, error) {
+	if m.Event != "media" || m.Media == nil {
+		return nil, fmt.Errorf("Twilio %s message carries no audio", m.Event)
+	}
+	ulaw, err := base64.StdEncoding.DecodeString(m.Media.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Twilio media payload: %v", err)
+	}
+	return ulaw, nil
+}
+
+// TwilioEncoder wraps 8 kHz u-law audio into media messages of one stream,
+// TwilioFrameSize bytes each. Audio that does not fill a frame is kept for
+// the next call.
+type TwilioEncoder struct {
+	streamSid string
+	track     string
+	pending   []byte
+	sequence  int
+	chunk     int
+}
+
+// NewTwilioEncoder creates an encoder for the stream with the given SID.
+// With an empty track it produces the media messages an application sends
+// to Twilio. With a track ("inbound" or "outbound") it produces them as
+// Twilio sends them, numbered and timestamped, to feed a bot under test; the
+// numbering then starts after the connected and start events.
+func NewTwilioEncoder(streamSid, track string) *TwilioEncoder {
+	return &TwilioEncoder{streamSid: streamSid, track: track, sequence: 1}
+}
+
+// Encode returns the media messages for the complete frames of ulaw
+func (e *TwilioEncoder) Encode(ulaw []byte) ([][]byte, error) {
+	e.pending = append(e.pending, ulaw...)
+	var messages [][]byte
+	for len(e.pending) >= TwilioFrameSize {
+		message, err := e.media(e.pending[:TwilioFrameSize])
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+		e.pending = e.pending[TwilioFrameSize:]
+	}
+	// Keep the remainder from pinning a large input
+	e.pending = append([]byte(nil), e.pending...)
+	return messages, nil
+}
+
+// Flush returns a media message with the buffered audio padded to a full
+// frame with silence, or nil when nothing is buffered
+func (e *TwilioEncoder) Flush() ([]byte, error) {
+	if len(e.pending) == 0 {
+		return nil, nil
+	}
+	frame := append(e.pending, bytes.Repeat([]byte{ulawSilence}, TwilioFrameSize-len(e.pending))...)
+	e.pending = nil
+	return e.media(frame)
+}
+
+// Mark returns a mark message naming the current point of the audio. Flush
+// first so the buffered audio is sent before the mark.
+func (e *TwilioEncoder) Mark(name string) ([]byte, error) {
+	return e.message(TwilioMessage{Event: "mark", Mark: &TwilioMark{Name: name}})
+}
+
+// Clear returns a clear message, which makes Twilio drop the audio it has
+// buffered for the stream, e.g. when the caller interrupts a prompt
+func (e *TwilioEncoder) Clear() ([]byte, error) {
+	e.pending = nil
+	return e.message(TwilioMessage{Event: "clear"})
+}
+
+// media builds the message carrying one frame
+func (e *TwilioEncoder) media(frame []byte) ([]byte, error) {
+	media := &TwilioMedia{Payload: base64.StdEncoding.EncodeToString(frame)}
+	if e.track != "" {
+		media.Track = e.track
+		media.Chunk = strconv.Itoa(e.chunk + 1)
+		media.Timestamp = strconv.Itoa(e.chunk * TwilioFrameSize / 8)
+		e.chunk++
+	}
+	return e.message(TwilioMessage{Event: "media", Media: media})
+}
+
+// message adds the stream SID, and in Twilio form the sequence number, and
+// encodes the message
+func (e *TwilioEncoder) message(m TwilioMessage) ([]byte, error) {
+	m.StreamSid = e.streamSid
+	if e.track != "" {
+		e.sequence++
+		m.SequenceNumber = strconv.Itoa(e.sequence)
+	}
+	return json.Marshal(m)
+}
@@ -0,0 +1,77 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTwilioRoundTrip(t *testing.T) {
+	ulaw := encodeUlawSamples(sineWave(400, 440, 8000, 0.5))
+	e := NewTwilioEncoder("MZ123", "inbound")
+	messages, err := e.Encode(ulaw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Two and a half frames: the half frame waits for more audio or Flush
+	if len(messages) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(messages))
+	}
+	last, err := e.Flush()
+	if err != nil || last == nil {
+		t.Fatalf("expected a flushed message, got %v", err)
+	}
+	messages = append(messages, last)
+
+	var got []byte
+	for i, data := range messages {
+		m, err := ParseTwilioMessage(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.Event != "media" || m.StreamSid != "MZ123" || m.Media.Track != "inbound" {
+			t.Fatalf("message %d: unexpected %s", i, data)
+		}
+		if want := []string{"1", "2", "3"}[i]; m.Media.Chunk != want {
+			t.Errorf("message %d: chunk %s, want %s", i, m.Media.Chunk, want)
+		}
+		if want := []string{"0", "20", "40"}[i]; m.Media.Timestamp != want {
+			t.Errorf("message %d: timestamp %s, want %s", i, m.Media.Timestamp, want)
+		}
+		frame, err := m.Ulaw()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(frame) != TwilioFrameSize {
+			t.Fatalf("message %d: frame of %d bytes", i, len(frame))
+		}
+		got = append(got, frame...)
+	}
+	if !bytes.Equal(got[:len(ulaw)], ulaw) || got[len(got)-1] != ulawSilence {
+		t.Error("expected the audio followed by silence padding")
+	}
+
+	// Application messages carry only the payload
+	out, _ := NewTwilioEncoder("MZ123", "").Encode(ulaw[:160])
+	if want := `{"event":"media","streamSid":"MZ123","media":{"payload":"`; !bytes.HasPrefix(out[0], []byte(want)) {
+		t.Errorf("unexpected outbound message %s", out[0])
+	}
+}
+
+func TestParseTwilioStart(t *testing.T) {
+	start := `{"event":"start","sequenceNumber":"1","start":{"accountSid":"AC1","streamSid":"MZ1","callSid":"CA1","tracks":["inbound"],"mediaFormat":{"encoding":"audio/x-mulaw","sampleRate":8000,"channels":1}},"streamSid":"MZ1"}`
+	m, err := ParseTwilioMessage([]byte(start))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Start.CallSid != "CA1" || m.StreamSid != "MZ1" {
+		t.Errorf("unexpected start %+v", m.Start)
+	}
+	if _, err := m.Ulaw(); err == nil {
+		t.Error("expected error for audio of a start message")
+	}
+
+	wideband := bytes.Replace([]byte(start), []byte(`"sampleRate":8000`), []byte(`"sampleRate":16000`), 1)
+	if _, err := ParseTwilioMessage(wideband); err == nil {
+		t.Error("expected error for a 16 kHz stream")
+	}
+}
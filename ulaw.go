@@ -0,0 +1,180 @@
+package wav2ulaw
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// Bias added to the 14-bit magnitude before segment lookup
+	ulawBias = 33
+	// Largest biased 14-bit magnitude
+	ulawClip = 0x1FFF
+)
+
+var (
+	// ulawDecodeTable maps every u-law code to its 16-bit linear value
+	ulawDecodeTable [256]int16
+	// ulawSegmentTable maps the biased magnitude >> 5 to its segment (1-8)
+	ulawSegmentTable [256]uint8
+)
+
+func init() {
+	for code := 0; code < 256; code++ {
+		v := ^uint8(code)
+		exponent := (v >> 4) & 0x07
+		mantissa := int(v & 0x0F)
+		magnitude := (((mantissa << 3) + 0x84) << exponent) - 0x84
+		if v&0x80 != 0 {
+			ulawDecodeTable[code] = int16(-magnitude)
+		} else {
+			ulawDecodeTable[code] = int16(magnitude)
+		}
+	}
+
+	for i := 1; i < 256; i++ {
+		seg := uint8(0)
+		for v := i; v > 0; v >>= 1 {
+			seg++
+		}
+		ulawSegmentTable[i] = seg
+	}
+}
+
+// encodeUlawSample compands one 16-bit sample to u-law (ITU-T G.711)
+func encodeUlawSample(sample int16) byte {
+	// Non-negative samples carry the sign bit, negatives use one's complement magnitude
+	sign := int16(0x80)
+	if sample < 0 {
+		sign = 0
+		sample = ^sample
+	}
+
+	magnitude := (sample >> 2) + ulawBias
+	if magnitude > ulawClip {
+		magnitude = ulawClip
+	}
+
+	seg := int16(ulawSegmentTable[magnitude>>5])
+	lowNibble := 0x0F - ((magnitude >> seg) & 0x0F)
+	return byte(sign | ((8 - seg) << 4) | lowNibble)
+}
+
+// encodeUlawSamples compands 16-bit PCM samples to u-law bytes
+func encodeUlawSamples(samples []int16) []byte {
+	ulaw := make([]byte, len(samples))
+	for i, sample := range samples {
+		ulaw[i] = encodeUlawSample(sample)
+	}
+	return ulaw
+}
+
+// decodeUlawSamples expands u-law bytes to 16-bit PCM samples
+func decodeUlawSamples(ulawBytes []byte) []int16 {
+	samples := make([]int16, len(ulawBytes))
+	for i, code := range ulawBytes {
+		samples[i] = ulawDecodeTable[code]
+	}
+	return samples
+}
+
+// UlawVariant selects the non-standard u-law bit layouts expected by some
+// legacy switches. Variants combine as flags; the zero value is standard
+// G.711.
+type UlawVariant uint8
+
+const (
+	// UlawZeroTrap replaces code 0x00 with 0x02, as switches using zero code
+	// suppression on T1 lines require
+	UlawZeroTrap UlawVariant = 1 << iota
+	// UlawInvertBits inverts every bit of each code
+	UlawInvertBits
+	// UlawInvertEvenBits inverts the even bits (0, 2, 4, 6) of each code
+	UlawInvertEvenBits
+)
+
+// ulawVariantNames are the names of the variant flags in String and
+// ParseUlawVariant
+var ulawVariantNames = []struct {
+	flag UlawVariant
+	name string
+}{
+	{UlawZeroTrap, "zero-trap"},
+	{UlawInvertBits, "invert"},
+	{UlawInvertEvenBits, "invert-even"},
+}
+
+// String returns the comma-separated names of the flags, or "standard"
+func (v UlawVariant) String() string {
+	var names []string
+	for _, n := range ulawVariantNames {
+		if v&n.flag != 0 {
+			names = append(names, n.name)
+		}
+	}
+	if len(names) == 0 {
+		return "standard"
+	}
+	return strings.Join(names, ",")
+}
+
+// ParseUlawVariant parses the comma-separated flag names produced by String
+func ParseUlawVariant(s string) (UlawVariant, error) {
+	var v UlawVariant
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "standard" || name == "" {
+			continue
+		}
+		known := false
+		for _, n := range ulawVariantNames {
+			if n.name == name {
+				v |= n.flag
+				known = true
+			}
+		}
+		if !known {
+			return 0, fmt.Errorf("unknown u-law variant '%s'", name)
+		}
+	}
+	return v, nil
+}
+
+// mask returns the bits the variant inverts
+func (v UlawVariant) mask() byte {
+	var mask byte
+	if v&UlawInvertBits != 0 {
+		mask ^= 0xFF
+	}
+	if v&UlawInvertEvenBits != 0 {
+		mask ^= 0x55
+	}
+	return mask
+}
+
+// apply rewrites standard u-law codes to the variant in place
+func (v UlawVariant) apply(ulaw []byte) []byte {
+	if v == 0 {
+		return ulaw
+	}
+	mask := v.mask()
+	for i, code := range ulaw {
+		if code == 0x00 && v&UlawZeroTrap != 0 {
+			code = 0x02
+		}
+		ulaw[i] = code ^ mask
+	}
+	return ulaw
+}
+
+// ConvertUlawVariant rewrites u-law codes from one variant to another, e.g.
+// to standard G.711 before decoding audio from a legacy switch. Zero trapping
+// cannot be undone, trapped codes decode to a value close to the original.
+func ConvertUlawVariant(ulaw []byte, from, to UlawVariant) []byte {
+	out := make([]byte, len(ulaw))
+	mask := from.mask()
+	for i, code := range ulaw {
+		out[i] = code ^ mask
+	}
+	return to.apply(out)
+}
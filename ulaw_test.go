@@ -0,0 +1,106 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/zaf/g711"
+	"testing"
+)
+
+func TestUlawTablesMatchG711(t *testing.T) {
+	pcmBytes := make([]byte, 2)
+	for v := -32768; v <= 32767; v++ {
+		binary.LittleEndian.PutUint16(pcmBytes, uint16(int16(v)))
+		want := g711.EncodeUlaw(pcmBytes)[0]
+		if got := encodeUlawSample(int16(v)); got != want {
+			t.Fatalf("encode %d: got %#x, want %#x", v, got, want)
+		}
+	}
+
+	for code := 0; code < 256; code++ {
+		if got, want := ulawDecodeTable[code], g711.DecodeUlawFrame(uint8(code)); got != want {
+			t.Fatalf("decode %#x: got %d, want %d", code, got, want)
+		}
+	}
+}
+
+func TestAlawTableMatchesG711(t *testing.T) {
+	for v := -32768; v <= 32767; v++ {
+		if got, want := encodeAlawSample(int16(v)), g711.EncodeAlawFrame(int16(v)); got != want {
+			t.Fatalf("encode %d: got %#x, want %#x", v, got, want)
+		}
+	}
+
+	for code := 0; code < 256; code++ {
+		if got, want := alawDecodeTable[code], g711.DecodeAlawFrame(uint8(code)); got != want {
+			t.Fatalf("decode %#x: got %d, want %d", code, got, want)
+		}
+	}
+}
+
+func BenchmarkEncodeUlawSamples(b *testing.B) {
+	samples := sineWave(8000, 440, 8000, 0.8)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		encodeUlawSamples(samples)
+	}
+}
+
+func TestUlawVariants(t *testing.T) {
+	samples := []int16{0, 1000, -1000, 32767, -32768}
+	standard := encodeUlawSamples(samples)
+	if standard[4] != 0x00 {
+		t.Fatalf("full-scale negative encodes to %#x, want 0x00", standard[4])
+	}
+
+	cases := map[UlawVariant][]byte{
+		UlawZeroTrap:                      {0xFF, 0xCE, 0x4E, 0x80, 0x02},
+		UlawInvertBits:                    {0x00, 0x31, 0xB1, 0x7F, 0xFF},
+		UlawInvertEvenBits:                {0xAA, 0x9B, 0x1B, 0xD5, 0x55},
+		UlawInvertBits | UlawZeroTrap:     {0x00, 0x31, 0xB1, 0x7F, 0xFD},
+		UlawInvertEvenBits | UlawZeroTrap: {0xAA, 0x9B, 0x1B, 0xD5, 0x57},
+	}
+	for variant, want := range cases {
+		got := ConvertUlawVariant(standard, 0, variant)
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: got % x, want % x", variant, got, want)
+		}
+		back := ConvertUlawVariant(got, variant, 0)
+		if variant&UlawZeroTrap == 0 && !bytes.Equal(back, standard) {
+			t.Errorf("%s: converting back gave % x, want % x", variant, back, standard)
+		}
+
+		parsed, err := ParseUlawVariant(variant.String())
+		if err != nil || parsed != variant {
+			t.Errorf("%s: parsed back as %s, %v", variant, parsed, err)
+		}
+	}
+	if _, err := ParseUlawVariant("invert,bogus"); err == nil {
+		t.Error("unknown variant name was accepted")
+	}
+}
+
+func TestConversionAppliesUlawVariant(t *testing.T) {
+	input := sineWave(8000, 440, 16000, 0.9)
+	want, err := ConvertPCM16ToUlaw(input, 16000, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := DefaultAudioConfig()
+	config.UlawVariant = UlawInvertBits
+	got, err := ConvertPCM16ToUlaw(input, 16000, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, ConvertUlawVariant(want, 0, UlawInvertBits)) {
+		t.Error("conversion output is not in the configured variant")
+	}
+
+	standardEncoder, _ := NewChunkEncoder(16000, nil)
+	wantStreamed := append(standardEncoder.Encode(input), standardEncoder.Flush()...)
+	encoder, _ := NewChunkEncoder(16000, config)
+	streamed := append(encoder.Encode(input), encoder.Flush()...)
+	if !bytes.Equal(streamed, ConvertUlawVariant(wantStreamed, 0, UlawInvertBits)) {
+		t.Error("chunk encoder output is not in the configured variant")
+	}
+}
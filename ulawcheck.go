@@ -0,0 +1,103 @@
+package wav2ulaw
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// Longest run of a silent code accepted in u-law audio (10 s)
+	maxSilenceRun = 10 * 8000
+	// Largest magnitude of a code counted as silent (0xFF, 0x7F, 0xFE, 0x7E)
+	maxSilentLevel = 8
+	// Longest run of any other single code accepted, a constant offset no
+	// audio holds for more than a few milliseconds (20 ms)
+	maxConstantRun = 160
+	// Largest share of codes from the loudest segment, near full scale,
+	// before the data counts as clipped or not u-law at all
+	maxLoudShare = 0.25
+	// Allowed difference between the u-law length and a claimed duration,
+	// whichever of the two is larger
+	durationToleranceMs       = 20
+	durationToleranceFraction = 0.01
+)
+
+// UlawError reports u-law data that does not look like audio, such as a file
+// mislabeled as u-law that would play as static
+type UlawError struct {
+	// Byte offset of the problem, -1 when it concerns the data as a whole
+	Offset int64
+	Reason string
+}
+
+func (e *UlawError) Error() string {
+	if e.Offset < 0 {
+		return fmt.Sprintf("implausible u-law: %s", e.Reason)
+	}
+	return fmt.Sprintf("implausible u-law at byte %d: %s", e.Offset, e.Reason)
+}
+
+// ValidateUlaw checks that data is plausible 8 kHz u-law audio by its
+// statistics, returning a *UlawError otherwise: digital silence (0xFF and
+// the codes next to it) may not run for more than 10 seconds nor any other
+// code for more than 20 ms, no more than a quarter of the codes may come from
+// the loudest segment, and the decoded signal must correlate between
+// neighboring samples as audio does, which random bytes and other encodings
+// do not. Data without audio beyond silence passes the last check. Empty
+// data returns ErrEmptyInput.
+func ValidateUlaw(data []byte) error {
+	if len(data) == 0 {
+		return ErrEmptyInput
+	}
+
+	loud := 0
+	start := 0
+	for i := range data {
+		if data[i]&0x70 == 0 {
+			loud++
+		}
+		if i+1 < len(data) && data[i+1] == data[i] {
+			continue
+		}
+		limit := maxConstantRun
+		if v := ulawDecodeTable[data[i]]; v >= -maxSilentLevel && v <= maxSilentLevel {
+			limit = maxSilenceRun
+		}
+		if run := i + 1 - start; run > limit {
+			return &UlawError{Offset: int64(start), Reason: fmt.Sprintf("code %#02x repeats for %v", data[i], UlawDuration(run))}
+		}
+		start = i + 1
+	}
+
+	if share := float64(loud) / float64(len(data)); share > maxLoudShare {
+		return &UlawError{Offset: -1, Reason: fmt.Sprintf("%.0f%% of the samples are near full scale", share*100)}
+	}
+	if len(data) >= minSniffBytes {
+		samples := decodeUlawSamples(data)
+		if correlation := math.Abs(lagCorrelation(samples)); correlation < plausibleCorrelation && rmsDbov(samples) > defaultComfortNoiseDbov {
+			return &UlawError{Offset: -1, Reason: fmt.Sprintf("neighboring samples do not correlate like audio (%.2f)", correlation)}
+		}
+	}
+	return nil
+}
+
+// ValidateUlawDuration checks data like ValidateUlaw and also that its
+// length matches the claimed duration, within 20 ms or 1%, whichever is larger
+func ValidateUlawDuration(data []byte, claimed time.Duration) error {
+	if err := ValidateUlaw(data); err != nil {
+		return err
+	}
+	if !durationMatches(len(data), claimed) {
+		return &UlawError{Offset: -1, Reason: fmt.Sprintf("%d bytes last %v, but %v was claimed", len(data), UlawDuration(len(data)), claimed)}
+	}
+	return nil
+}
+
+// durationMatches reports whether n u-law bytes last the claimed duration,
+// within 20 ms or 1%, whichever is larger
+func durationMatches(n int, claimed time.Duration) bool {
+	tolerance := max(durationToleranceMs*time.Millisecond, time.Duration(float64(claimed)*durationToleranceFraction))
+	diff := UlawDuration(n) - claimed
+	return diff <= tolerance && diff >= -tolerance
+}
@@ -0,0 +1,56 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestValidateUlaw(t *testing.T) {
+	speech := encodeUlawSamples(sineWave(8000, 440, 8000, 0.5))
+	if err := ValidateUlaw(speech); err != nil {
+		t.Errorf("tone rejected: %v", err)
+	}
+	withPause := append(append(append([]byte(nil), speech...), bytes.Repeat([]byte{0xFF}, 8000*5)...), speech...)
+	if err := ValidateUlaw(withPause); err != nil {
+		t.Errorf("5 s pause rejected: %v", err)
+	}
+
+	random := make([]byte, 8000)
+	rand.New(rand.NewSource(1)).Read(random)
+	pcm, err := encodeWavPCM16(sineWave(8000, 440, 8000, 0.5), 8000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string][]byte{
+		"random bytes":  random,
+		"16-bit PCM":    pcm[44:],
+		"long silence":  append(append([]byte(nil), speech...), bytes.Repeat([]byte{0xFF}, 8000*11)...),
+		"constant":      append(append([]byte(nil), speech...), bytes.Repeat([]byte{0x30}, 400)...),
+		"full scale":    encodeUlawSamples(sineWave(8000, 440, 8000, 8)),
+		"A-law silence": bytes.Repeat([]byte{0xD5}, 1600),
+		"zero-filled":   make([]byte, 1600),
+	}
+	for name, data := range cases {
+		var ulawErr *UlawError
+		if err := ValidateUlaw(data); !errors.As(err, &ulawErr) {
+			t.Errorf("%s: got %v, want a UlawError", name, err)
+		}
+	}
+	if err := ValidateUlaw(nil); !errors.Is(err, ErrEmptyInput) {
+		t.Errorf("empty input: got %v, want ErrEmptyInput", err)
+	}
+}
+
+func TestValidateUlawDuration(t *testing.T) {
+	speech := encodeUlawSamples(sineWave(8000, 440, 8000, 0.5))
+	if err := ValidateUlawDuration(speech, time.Second+10*time.Millisecond); err != nil {
+		t.Errorf("length within tolerance rejected: %v", err)
+	}
+	var ulawErr *UlawError
+	if err := ValidateUlawDuration(speech, 2*time.Second); !errors.As(err, &ulawErr) {
+		t.Errorf("half the claimed duration: got %v, want a UlawError", err)
+	}
+}
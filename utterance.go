@@ -0,0 +1,133 @@
+package wav2ulaw
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// Level below which audio is silence when SilenceSplitOptions.ThresholdDb
+	// is 0 (dBFS)
+	defaultSilenceThresholdDb = -40.0
+	// Silence that ends an utterance when SilenceSplitOptions.MinSilence is 0
+	defaultMinSilence = 500 * time.Millisecond
+)
+
+// SilenceSplitOptions control SplitWavOnSilence
+type SilenceSplitOptions struct {
+	// RMS level of a 20 ms frame below which it is silence (dBFS, 0 = -40)
+	ThresholdDb float64
+	// Shortest silence that ends an utterance; shorter pauses, such as
+	// between words, stay inside it (0 = 500 ms)
+	MinSilence time.Duration
+	// Silence kept before and after each utterance, so words are not
+	// clipped at the cut; it is at most half the silence between two
+	// utterances, so they do not overlap
+	Padding time.Duration
+	// Utterances shorter than this without padding, such as clicks or
+	// coughs, are dropped (0 = keep all)
+	MinLength time.Duration
+}
+
+// Utterance is one piece of a recording cut by SplitWavOnSilence
+type Utterance struct {
+	// Span of the recording it covers, padding included
+	Start time.Duration
+	End   time.Duration
+	// WAV file of the span, in the format and with the metadata of the
+	// recording
+	WAV []byte
+}
+
+// SplitWavOnSilence cuts a long recording into utterances at the pauses
+// between them, e.g. to feed a transcription service pieces it accepts. A
+// pause is a run of at least options.MinSilence of 20 ms frames below
+// options.ThresholdDb, the channels mixed together. The utterances are cut
+// from the recording without decoding it again; silence before the first
+// and after the last is left out. A recording without audio returns
+// ErrEmptyInput, and one without sound no utterances.
+func SplitWavOnSilence(wavBytes []byte, options *SilenceSplitOptions) ([]Utterance, error) {
+	if options == nil {
+		options = &SilenceSplitOptions{}
+	}
+	threshold, minSilence := options.ThresholdDb, options.MinSilence
+	if threshold == 0 {
+		threshold = defaultSilenceThresholdDb
+	}
+	if minSilence == 0 {
+		minSilence = defaultMinSilence
+	}
+	if threshold > 0 || minSilence < 0 || options.Padding < 0 || options.MinLength < 0 {
+		return nil, fmt.Errorf("invalid silence split settings: the threshold must be negative, durations not negative")
+	}
+	cut, err := newWavCutter(wavBytes)
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := decodeWavFloat(wavBytes)
+	if err != nil {
+		return nil, err
+	}
+	mono := make([]float64, len(decoded.data)/decoded.channels)
+	for i := range mono {
+		sum := 0.0
+		for ch := 0; ch < decoded.channels; ch++ {
+			sum += decoded.data[i*decoded.channels+ch]
+		}
+		mono[i] = sum / float64(decoded.channels)
+	}
+
+	// Sound runs in frames, joined across pauses shorter than minSilence
+	frameLen := cut.sampleRate * analysisFrameMs / 1000
+	gapFrames := int(math.Ceil(minSilence.Seconds() * 1000 / analysisFrameMs))
+	var spans [][2]int
+	for i, power := range framePowers(mono, cut.sampleRate) {
+		if 10*math.Log10(power) < threshold {
+			continue
+		}
+		if n := len(spans); n > 0 && i-spans[n-1][1] < gapFrames {
+			spans[n-1][1] = i + 1
+		} else {
+			spans = append(spans, [2]int{i, i + 1})
+		}
+	}
+
+	frame := func(d time.Duration) int {
+		return int(math.Round(d.Seconds() * float64(cut.sampleRate)))
+	}
+	var kept [][2]int
+	for _, span := range spans {
+		start, end := span[0]*frameLen, span[1]*frameLen
+		if span[1]*frameLen+frameLen > len(mono) {
+			// The sound reaches the samples after the last whole frame
+			end = cut.frames
+		}
+		if end-start >= frame(options.MinLength) {
+			kept = append(kept, [2]int{start, min(end, cut.frames)})
+		}
+	}
+
+	pad := frame(options.Padding)
+	utterances := make([]Utterance, 0, len(kept))
+	for i, span := range kept {
+		start, end := span[0]-pad, span[1]+pad
+		if i > 0 {
+			start = max(start, (kept[i-1][1]+span[0])/2)
+		}
+		if i < len(kept)-1 {
+			end = min(end, (span[1]+kept[i+1][0])/2)
+		}
+		start, end = max(start, 0), min(end, cut.frames)
+		piece, err := cut.piece(start, end, cut.md.Bext)
+		if err != nil {
+			return nil, err
+		}
+		utterances = append(utterances, Utterance{
+			Start: time.Duration(start) * time.Second / time.Duration(cut.sampleRate),
+			End:   time.Duration(end) * time.Second / time.Duration(cut.sampleRate),
+			WAV:   piece,
+		})
+	}
+	return utterances, nil
+}
@@ -0,0 +1,70 @@
+package wav2ulaw
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSplitWavOnSilence(t *testing.T) {
+	// Three utterances of 1 s, 0.3 s and 0.5 s; the second is followed by a
+	// pause too short to end it, and a click at 3.5 s
+	var samples []int16
+	silence := func(ms int) { samples = append(samples, make([]int16, ms*8)...) }
+	tone := func(ms int) { samples = append(samples, sineWave(ms*8, 440, 8000, 0.3)...) }
+	silence(500)
+	tone(1000)
+	silence(800)
+	tone(300)
+	silence(200)
+	tone(500)
+	silence(1000)
+	tone(20)
+	silence(1000)
+	wavBytes := encodeWavDepth(t, samples, 8000, 16, 2)
+
+	utterances, err := SplitWavOnSilence(wavBytes, &SilenceSplitOptions{Padding: 100 * time.Millisecond, MinLength: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][2]time.Duration{
+		{400 * time.Millisecond, 1600 * time.Millisecond},
+		{2200 * time.Millisecond, 3400 * time.Millisecond},
+	}
+	if len(utterances) != len(want) {
+		t.Fatalf("%d utterances, want %d", len(utterances), len(want))
+	}
+	for i, u := range utterances {
+		// Edges fall on 20 ms frames
+		if d := u.Start - want[i][0]; d < -20*time.Millisecond || d > 20*time.Millisecond {
+			t.Errorf("utterance %d starts at %v, want %v", i, u.Start, want[i][0])
+		}
+		if d := u.End - want[i][1]; d < -20*time.Millisecond || d > 20*time.Millisecond {
+			t.Errorf("utterance %d ends at %v, want %v", i, u.End, want[i][1])
+		}
+		decoded, err := decodeWavFloat(u.WAV)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded.channels != 2 || time.Duration(len(decoded.data)/2)*time.Second/8000 != u.End-u.Start {
+			t.Errorf("utterance %d: %d channels of %d samples, want stereo covering %v", i, decoded.channels, len(decoded.data), u.End-u.Start)
+		}
+	}
+
+	// Without MinLength the click is an utterance of its own
+	utterances, err = SplitWavOnSilence(wavBytes, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(utterances) != 3 {
+		t.Errorf("%d utterances without a minimum length, want 3", len(utterances))
+	}
+
+	// Silence holds no utterances, and a file without audio is an error
+	if utterances, err := SplitWavOnSilence(encodeWavDepth(t, make([]int16, 8000), 8000, 16, 1), nil); err != nil || len(utterances) != 0 {
+		t.Errorf("silence: %d utterances (%v), want none", len(utterances), err)
+	}
+	if _, err := SplitWavOnSilence(encodeWavDepth(t, nil, 8000, 16, 1), nil); !errors.Is(err, ErrEmptyInput) {
+		t.Errorf("empty file: got %v, want ErrEmptyInput", err)
+	}
+}
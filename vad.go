@@ -0,0 +1,87 @@
+package wav2ulaw
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	// Frames must be this far above the noise floor to count as speech (dB)
+	vadMarginDb = 9.0
+	// Frames quieter than this are never speech (dBFS power)
+	vadAbsoluteFloorDb = -55.0
+	// Frames kept as speech after energy drops, bridging short pauses
+	vadHangoverFrames = 10
+)
+
+// SpeechSegment is a span of audio classified as speech by the voice
+// activity detector
+type SpeechSegment struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// detectVoiceActivity classifies consecutive 20 ms frames as speech or not
+// using an energy threshold relative to the estimated noise floor, with a
+// hangover so brief pauses between words stay inside one segment
+func detectVoiceActivity(samples []float64, sampleRate int) []bool {
+	powers := framePowers(samples, sampleRate)
+	if len(powers) == 0 {
+		return nil
+	}
+
+	sorted := append([]float64(nil), powers...)
+	sort.Float64s(sorted)
+	noiseFloor := sorted[int(float64(len(sorted)-1)*noiseFrameFraction)]
+
+	threshold := math.Max(
+		noiseFloor*math.Pow(10, vadMarginDb/10),
+		math.Pow(10, vadAbsoluteFloorDb/10),
+	)
+
+	active := make([]bool, len(powers))
+	hangover := 0
+	for i, p := range powers {
+		if p > threshold {
+			active[i] = true
+			hangover = vadHangoverFrames
+		} else if hangover > 0 {
+			active[i] = true
+			hangover--
+		}
+	}
+	return active
+}
+
+// speechSegments merges consecutive active frames into time spans
+func speechSegments(active []bool) []SpeechSegment {
+	frame := time.Duration(analysisFrameMs) * time.Millisecond
+	var segments []SpeechSegment
+	start := -1
+	for i := 0; i <= len(active); i++ {
+		if i < len(active) && active[i] {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			segments = append(segments, SpeechSegment{
+				Start: time.Duration(start) * frame,
+				End:   time.Duration(i) * frame,
+			})
+			start = -1
+		}
+	}
+	return segments
+}
+
+// DetectSpeech returns the speech segments found in 16-bit PCM samples
+func DetectSpeech(samples []int16, sampleRate int) []SpeechSegment {
+	data := make([]float64, len(samples))
+	for i, sample := range samples {
+		data[i] = float64(sample) / 32768.0
+	}
+	return speechSegments(detectVoiceActivity(data, sampleRate))
+}
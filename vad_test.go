@@ -0,0 +1,41 @@
+package wav2ulaw
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDetectSpeechAndTalkTime(t *testing.T) {
+	// 1 s silence, 2 s tone, 1 s silence over a low noise floor
+	samples := NewComfortNoiseGenerator(-65, 1).Samples(8000 * 4)
+	tone := sineWave(16000, 300, 8000, 0.3)
+	for i, sample := range tone {
+		samples[8000+i] += sample
+	}
+
+	segments := DetectSpeech(samples, 8000)
+	if len(segments) != 1 {
+		t.Fatalf("expected 1 segment, got %+v", segments)
+	}
+	if segments[0].Start != time.Second {
+		t.Errorf("expected speech to start at 1s, got %v", segments[0].Start)
+	}
+	// Hangover extends the segment by up to 200 ms
+	if end := segments[0].End; end < 3*time.Second || end > 3*time.Second+220*time.Millisecond {
+		t.Errorf("unexpected speech end %v", end)
+	}
+
+	stats, err := AnalyzeUlaw(encodeUlawSamples(samples))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Duration != 4*time.Second {
+		t.Fatalf("expected 4s, got %v", stats.Duration)
+	}
+	if stats.SilencePercent < 44 || stats.SilencePercent > 50 {
+		t.Errorf("expected about 45-50%% silence, got %.1f%%", stats.SilencePercent)
+	}
+	if stats.SpeechDuration+stats.SilenceDuration != stats.Duration {
+		t.Errorf("speech and silence should add up to the duration")
+	}
+}
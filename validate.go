@@ -0,0 +1,135 @@
+package wav2ulaw
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// Smallest Chebyshev ripple accepted; the design divides by the ripple
+	minChebyshevRipple = 0.01
+	// Smallest anti-aliasing cutoff ratio accepted; a zero cutoff designs
+	// an all-zero kernel that normalizes to NaN
+	minAntiAliasingRatio = 0.01
+	// Largest Kaiser beta accepted, well before the window overflows
+	maxKaiserBeta = 100
+)
+
+// ConfigError reports an AudioConfig parameter outside the range the
+// processing chain can work with
+type ConfigError struct {
+	// Name of the AudioConfig field
+	Field string
+	Value float64
+	// Accepted range, Max being +Inf when unbounded
+	Min, Max float64
+}
+
+func (e *ConfigError) Error() string {
+	if math.IsInf(e.Max, 1) {
+		return fmt.Sprintf("invalid %s %g: must be at least %g", e.Field, e.Value, e.Min)
+	}
+	return fmt.Sprintf("invalid %s %g: must be between %g and %g", e.Field, e.Value, e.Min, e.Max)
+}
+
+// configValidator checks the parameters of one configuration
+type configValidator struct {
+	config *AudioConfig
+	err    error
+}
+
+// validateConfig checks the parameters of config for audio at inputRate,
+// returning a *ConfigError for the first one out of range. With
+// config.Lenient set, out-of-range values are instead clamped to the nearest
+// accepted value, with a warning through config.Logger. The conversion works
+// on the returned copy, so the caller may change config while it runs.
+func validateConfig(config *AudioConfig, inputRate int) (*AudioConfig, error) {
+	v := &configValidator{config: config.Clone()}
+	c := v.config
+	if c.Speed != 0 {
+		v.check("Speed", &c.Speed, minSpeed, maxSpeed)
+	}
+	// Filters run on the signal at its new speed
+	nyquist := float64(speedRate(inputRate, c.Speed)) / 2
+
+	v.check("HighPassCutoff", &c.HighPassCutoff, 0, nyquist)
+	v.check("LowPassCutoff", &c.LowPassCutoff, 0, nyquist)
+	v.check("AntiAliasingCutoffRatio", &c.AntiAliasingCutoffRatio, minAntiAliasingRatio, 1)
+	v.checkInt("ResamplingWindowSize", &c.ResamplingWindowSize, 1)
+	if c.AntiAliasingType == AAChebyshev {
+		v.check("ChebyshevRipple", &c.ChebyshevRipple, minChebyshevRipple, math.Inf(1))
+	}
+	if c.WindowFunction == WindowKaiser {
+		v.check("KaiserBeta", &c.KaiserBeta, 0, maxKaiserBeta)
+	}
+	v.check("NormalizePeak", &c.NormalizePeak, -1, 1)
+	if c.TargetLoudness != 0 {
+		v.check("TargetLoudness", &c.TargetLoudness, minTargetLoudness, maxTargetLoudness)
+	}
+	if c.CompressionRatio > 1.0 {
+		v.check("CompressionThreshold", &c.CompressionThreshold, 0, 1)
+	}
+	if c.AutoTrim != nil {
+		v.checkDuration("AutoTrim.PreRoll", &c.AutoTrim.PreRoll)
+		v.checkDuration("AutoTrim.PostRoll", &c.AutoTrim.PostRoll)
+	}
+	v.checkDuration("PadStart", &c.PadStart)
+	v.checkDuration("PadEnd", &c.PadEnd)
+	v.checkInt("FrameAlign", &c.FrameAlign, 0)
+	if c.Noise != nil {
+		v.check("Noise.SNR", &c.Noise.SNR, minNoiseSNR, maxNoiseSNR)
+	}
+	for i := range c.Beeps {
+		beep := &c.Beeps[i]
+		field := fmt.Sprintf("Beeps[%d].", i)
+		v.checkDuration(field+"At", &beep.At)
+		v.checkDuration(field+"Duration", &beep.Duration)
+		v.checkDuration(field+"Repeat", &beep.Repeat)
+		v.check(field+"Frequency", &beep.Frequency, minBeepFrequency, maxBeepFrequency)
+		v.check(field+"LevelDbov", &beep.LevelDbov, minReprocessDbov, maxBeepDbov)
+	}
+	if v.err == nil && (c.OutputCodec < CodecUlaw || c.OutputCodec > CodecPCM16) {
+		// No codec is nearer than another, so not even Lenient clamps it
+		v.err = &ConfigError{Field: "OutputCodec", Value: float64(c.OutputCodec), Min: float64(CodecUlaw), Max: float64(CodecPCM16)}
+	}
+	if v.err != nil {
+		return nil, v.err
+	}
+	return c, nil
+}
+
+// check verifies that *value lies within [lo, hi], clamping it in lenient mode
+func (v *configValidator) check(field string, value *float64, lo, hi float64) {
+	// NaN fails both comparisons and is rejected too
+	if v.err != nil || (*value >= lo && *value <= hi) {
+		return
+	}
+	if !v.config.Lenient {
+		v.err = &ConfigError{Field: field, Value: *value, Min: lo, Max: hi}
+		return
+	}
+	clamped := lo
+	if *value > hi {
+		clamped = hi
+	}
+	logWarn(v.config, WarnParameterClamped, "", "parameter out of range, clamped", "field", field, "value", *value, "clamped", clamped)
+	*value = clamped
+}
+
+// checkInt verifies that *value is at least lo, clamping it in lenient mode
+func (v *configValidator) checkInt(field string, value *int, lo int) {
+	f := float64(*value)
+	v.check(field, &f, float64(lo), math.Inf(1))
+	*value = int(f)
+}
+
+// checkDuration verifies that *value is not negative, clamping it in lenient
+// mode. The range is reported in seconds.
+func (v *configValidator) checkDuration(field string, value *time.Duration) {
+	f := value.Seconds()
+	v.check(field, &f, 0, math.Inf(1))
+	if f == 0 {
+		*value = 0
+	}
+}
@@ -0,0 +1,81 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestOutOfRangeParametersReturnConfigError(t *testing.T) {
+	wavBytes, err := encodeWavPCM16(sineWave(16000, 440, 16000, 0.5), 16000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]func(c *AudioConfig){
+		"ripple": func(c *AudioConfig) {
+			c.AntiAliasingType = AAChebyshev
+			c.ChebyshevRipple = 0
+		},
+		"negative cutoff":   func(c *AudioConfig) { c.HighPassCutoff = -100 },
+		"above nyquist":     func(c *AudioConfig) { c.LowPassCutoff = 9000 },
+		"window size":       func(c *AudioConfig) { c.ResamplingWindowSize = 0 },
+		"anti-aliasing":     func(c *AudioConfig) { c.AntiAliasingCutoffRatio = 0 },
+		"normalize":         func(c *AudioConfig) { c.NormalizePeak = 1.5 },
+		"NaN cutoff":        func(c *AudioConfig) { c.LowPassCutoff = math.NaN() },
+		"kaiser beta":       func(c *AudioConfig) { c.WindowFunction, c.KaiserBeta = WindowKaiser, -1 },
+		"compress negative": func(c *AudioConfig) { c.CompressionThreshold = -0.5 },
+	}
+	for name, mutate := range cases {
+		config := DefaultAudioConfig()
+		mutate(config)
+		var configErr *ConfigError
+		if _, err := ConvertWavBytesToUlaw(wavBytes, config); !errors.As(err, &configErr) {
+			t.Errorf("%s: got %v, want a ConfigError", name, err)
+		}
+		if err := ConvertWavStreamToUlaw(bytes.NewReader(wavBytes), &bytes.Buffer{}, config); !errors.As(err, &configErr) {
+			t.Errorf("%s: streaming: got %v, want a ConfigError", name, err)
+		}
+		if _, err := NewChunkEncoder(16000, config); !errors.As(err, &configErr) {
+			t.Errorf("%s: chunk encoder: got %v, want a ConfigError", name, err)
+		}
+	}
+}
+
+func TestLenientClampsParameters(t *testing.T) {
+	samples := sineWave(16000, 440, 16000, 0.5)
+	config := DefaultAudioConfig()
+	config.AntiAliasingType = AAChebyshev
+	config.ChebyshevRipple = 0
+	config.HighPassCutoff = -100
+	config.LowPassCutoff = 9000
+	config.ResamplingWindowSize = 0
+	config.Lenient = true
+
+	ulaw, err := ConvertPCM16ToUlaw(samples, 16000, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ulaw) != 8000 {
+		t.Fatalf("got %d bytes, want 8000", len(ulaw))
+	}
+	decoded := decodeUlawSamples(ulaw)
+	if level := toneLevel(decoded[800:7200], 440, 8000); level < 0.3 {
+		t.Errorf("440 Hz level %.2f after clamping, want the tone preserved", level)
+	}
+	// The caller's configuration is left as it was
+	if config.ChebyshevRipple != 0 || config.ResamplingWindowSize != 0 {
+		t.Error("lenient validation modified the caller's config")
+	}
+}
+
+func TestConfigErrorMessage(t *testing.T) {
+	err := &ConfigError{Field: "LowPassCutoff", Value: 9000, Min: 0, Max: 8000}
+	if got, want := err.Error(), "invalid LowPassCutoff 9000: must be between 0 and 8000"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	err = &ConfigError{Field: "ResamplingWindowSize", Value: 0, Min: 1, Max: math.Inf(1)}
+	if got, want := err.Error(), "invalid ResamplingWindowSize 0: must be at least 1"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,64 @@
+package wav2ulaw
+
+// Codes of the warnings passed to AudioConfig.OnWarning
+const (
+	// A parameter out of range was clamped (Lenient)
+	WarnParameterClamped = "parameter-clamped"
+	// The input holds clipped samples
+	WarnInputClipped = "input-clipped"
+	// Float input exceeded full scale and was scaled down
+	WarnInputOverRange = "input-over-range"
+	// A processing stage clipped samples that were not clipped before
+	WarnClipping = "clipping"
+	// The input is below 8 kHz and upsampled, which adds no bandwidth
+	WarnUpsampled = "upsampled"
+	// The input is silent, so normalization or loudness matching was skipped
+	WarnSilentInput = "silent-input"
+	// The input is 8 kHz audio decoded from u-law, which is encoded again
+	WarnAlreadyUlaw = "already-ulaw"
+	// A stage that needs the whole signal was dropped in real-time mode
+	WarnStageDropped = "stage-dropped"
+	// A real-time chunk was longer than RealTimeBlock
+	WarnLatencyExceeded = "latency-exceeded"
+)
+
+// Warning is a condition a conversion worked around or let through instead
+// of failing, such as clipping or a skipped stage
+type Warning struct {
+	// One of the Warn* codes, stable for dashboards and alerting
+	Code string
+	// Processing stage it arose in, empty when it concerns the input or
+	// settings as a whole
+	Stage string
+	// Description as logged through AudioConfig.Logger
+	Message string
+	// Values describing it, keyed like the attributes of the log record
+	Details map[string]any
+}
+
+// warns reports whether warnings go anywhere, so checks that cost a pass
+// over the audio can be skipped otherwise
+func warns(config *AudioConfig) bool {
+	return config.Logger != nil || config.OnWarning != nil
+}
+
+// onUlawGrid reports whether every sample is a value u-law decodes to and
+// they are not all silence, as in audio decoded from u-law at 8 kHz
+func onUlawGrid(samples []int16) bool {
+	sound := false
+	for _, s := range samples {
+		if ulawDecodeTable[encodeUlawSample(s)] != s {
+			return false
+		}
+		sound = sound || s < -maxSilentLevel || s > maxSilentLevel
+	}
+	return sound
+}
+
+// checkAlreadyUlaw warns when 8 kHz input was decoded from u-law, so the
+// conversion transcodes it a second time
+func checkAlreadyUlaw(config *AudioConfig, samples []int16, sampleRate int) {
+	if sampleRate == 8000 && warns(config) && onUlawGrid(samples) {
+		logWarn(config, WarnAlreadyUlaw, "", "input already u-law: every sample is a u-law level, so it is encoded a second time")
+	}
+}
@@ -0,0 +1,105 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"testing"
+)
+
+// collectWarnings returns a copy of config whose OnWarning appends to the
+// returned slice
+func collectWarnings(config *AudioConfig) (*AudioConfig, *[]Warning) {
+	var warnings []Warning
+	c := *config
+	c.OnWarning = func(warning Warning) { warnings = append(warnings, warning) }
+	return &c, &warnings
+}
+
+// findWarning returns the first warning with code, or nil
+func findWarning(warnings []Warning, code string) *Warning {
+	for i := range warnings {
+		if warnings[i].Code == code {
+			return &warnings[i]
+		}
+	}
+	return nil
+}
+
+func TestOnWarning(t *testing.T) {
+	// Silent input cannot be normalized, whole or streamed
+	config, warnings := collectWarnings(DefaultAudioConfig())
+	silence := make([]int16, 8000)
+	if _, err := ConvertPCM16ToUlaw(silence, 8000, config); err != nil {
+		t.Fatal(err)
+	}
+	if w := findWarning(*warnings, WarnSilentInput); w == nil || w.Stage != "normalize" {
+		t.Errorf("silent input: warnings %+v, want %s in normalize", *warnings, WarnSilentInput)
+	}
+	*warnings = nil
+	if err := ConvertWavStreamToUlaw(bytes.NewReader(encodeWavDepth(t, silence, 8000, 16, 1)), &bytes.Buffer{}, config); err != nil {
+		t.Fatal(err)
+	}
+	if w := findWarning(*warnings, WarnSilentInput); w == nil || w.Stage != "normalize" {
+		t.Errorf("streamed silent input: warnings %+v, want %s in normalize", *warnings, WarnSilentInput)
+	}
+
+	// Normalizing flat tops to full scale clips them
+	config.HighPassCutoff, config.LowPassCutoff = 0, 0
+	config.NormalizePeak = 1
+	square := make([]int16, 8000)
+	for i := range square {
+		square[i] = 16384
+		if i/20%2 == 1 {
+			square[i] = -16384
+		}
+	}
+	*warnings = nil
+	if _, err := ConvertPCM16ToUlaw(square, 8000, config); err != nil {
+		t.Fatal(err)
+	}
+	w := findWarning(*warnings, WarnClipping)
+	if w == nil || w.Stage != "normalize" || w.Details["clipped_samples"].(int) <= 0 {
+		t.Errorf("clipping: warnings %+v, want %s in normalize with the clipped samples", *warnings, WarnClipping)
+	}
+
+	// Settings clamped by Lenient name the field
+	config, warnings = collectWarnings(DefaultAudioConfig())
+	config.Lenient = true
+	config.LowPassCutoff = 9000
+	if _, err := ConvertPCM16ToUlaw(sineWave(8000, 440, 8000, 0.5), 8000, config); err != nil {
+		t.Fatal(err)
+	}
+	if w := findWarning(*warnings, WarnParameterClamped); w == nil || w.Details["field"] != "LowPassCutoff" {
+		t.Errorf("clamped setting: warnings %+v, want %s for LowPassCutoff", *warnings, WarnParameterClamped)
+	}
+}
+
+func TestAlreadyUlawWarning(t *testing.T) {
+	tone := sineWave(8000, 440, 8000, 0.5)
+	for _, tc := range []struct {
+		name    string
+		samples []int16
+		rate    int
+		want    bool
+	}{
+		{"decoded u-law", decodeUlawSamples(encodeUlawSamples(tone)), 8000, true},
+		{"linear PCM", tone, 8000, false},
+		{"decoded u-law at 16 kHz", decodeUlawSamples(encodeUlawSamples(sineWave(16000, 440, 16000, 0.5))), 16000, false},
+	} {
+		config, warnings := collectWarnings(DefaultAudioConfig())
+		if _, err := ConvertPCM16ToUlaw(tc.samples, tc.rate, config); err != nil {
+			t.Fatal(err)
+		}
+		if got := findWarning(*warnings, WarnAlreadyUlaw) != nil; got != tc.want {
+			t.Errorf("%s: warned %v, want %v", tc.name, got, tc.want)
+		}
+
+		*warnings = nil
+		wavBytes := encodeWavDepth(t, tc.samples, tc.rate, 16, 1)
+		if err := ConvertWavStreamToUlaw(bytes.NewReader(wavBytes), &bytes.Buffer{}, config); err != nil {
+			t.Fatal(err)
+		}
+		if got := findWarning(*warnings, WarnAlreadyUlaw) != nil; got != tc.want {
+			t.Errorf("%s streamed: warned %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
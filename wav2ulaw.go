@@ -5,14 +5,14 @@ package wav2ulaw
 
 import (
 	"bytes"
-	"encoding/binary"
+	"errors"
 	"fmt"
 	"github.com/go-audio/audio"
 	"github.com/go-audio/wav"
-	"github.com/zaf/g711"
-	"io"
+	"log/slog"
 	"math"
-	"os"
+	"slices"
+	"time"
 )
 
 // AntiAliasingType defines the type of anti-aliasing filter to use
@@ -23,6 +23,16 @@ const (
 	AAButterworth                      // Butterworth filter
 	AABessel                          // Bessel filter
 	AAChebyshev                       // Chebyshev Type I filter
+	AAWindowedSinc                    // Windowed-sinc FIR, folded into the resampler kernel
+	AAAuto                            // FIR designed from the rate pair to meet fixed passband and stopband targets, folded into the resampler kernel
+)
+
+// ResampleMethod defines the algorithm used for sample rate conversion
+type ResampleMethod int
+
+const (
+	ResampleSinc ResampleMethod = iota // Windowed sinc interpolation (suitable for streaming)
+	ResampleFFT                        // FFT overlap-save resampling (highest quality, offline)
 )
 
 // AudioConfig contains configuration for audio processing
@@ -37,20 +47,120 @@ type AudioConfig struct {
 	HighPassCutoff float64
 	// Normalize audio to this peak level (-1.0 to 1.0)
 	NormalizePeak float64
+	// Integrated loudness of the output (LUFS, -40 to -8, 0 = off), reached
+	// with a gain and a peak limiter in place of NormalizePeak, so every file
+	// plays at the same perceived volume
+	TargetLoudness float64
+	// Tempo change without pitch shift (1.0 or 0 = unchanged, 1.05 = 5% faster)
+	Tempo float64
+	// Playback speed change by resampling, raising or lowering the pitch with
+	// it (1.0 or 0 = unchanged, 0.5 to 2.0); much cheaper than Tempo
+	Speed float64
+	// Play the audio backwards. Fades, padding and beeps apply to the
+	// reversed audio.
+	Reverse bool
 	// Compression ratio (1.0 means no compression)
 	CompressionRatio float64
-	// Compression threshold (-1.0 to 1.0)
+	// Compression threshold (0.0 to 1.0)
 	CompressionThreshold float64
+	// Undo the gain the filters and resampler have at 1 kHz, measured with a
+	// reference tone, so the output level matches the input within 0.1 dB;
+	// for use with NormalizePeak 0
+	LevelCompensation bool
+	// Fade-in duration at the start of the output (ms, 0 = disabled)
+	FadeInMs float64
+	// Fade-out duration at the end of the output (ms, 0 = disabled)
+	FadeOutMs float64
+	// Gain curve for fade-in and fade-out
+	FadeShape FadeShape
+	// Trim the output to the detected speech (nil = keep everything)
+	AutoTrim *AutoTrim
+	// Digital silence inserted before the output (0 = none)
+	PadStart time.Duration
+	// Digital silence appended to the output (0 = none)
+	PadEnd time.Duration
+	// Pad the end of the output with digital silence to a multiple of this
+	// many bytes, e.g. 160 for 20 ms frames, so packetizers never send a
+	// short last packet (0 = off). The padding is reported as the "frame
+	// align" stage and in BatchResult.
+	FrameAlign int
+	// Background noise mixed into the input before filtering (nil = none)
+	Noise *NoiseOverlay
+	// Tones mixed into the output after padding, at positions of the output
+	Beeps []Beep
 	// Resampling window size (larger = better quality but slower)
 	ResamplingWindowSize int
+	// Resampling algorithm
+	ResampleMethod ResampleMethod
+	// Speed against accuracy of the sinc resampler (0 = ResampleBalanced)
+	ResampleTradeoff ResampleTradeoff
+	// Window applied to the resampling sinc kernel and FIR filter kernels
+	WindowFunction WindowFunction
+	// Kaiser window beta (higher = more stopband attenuation, wider transition)
+	KaiserBeta float64
 	// Anti-aliasing filter cutoff ratio (0.0 to 1.0, relative to Nyquist frequency)
 	AntiAliasingCutoffRatio float64
 	// Anti-aliasing filter type
 	AntiAliasingType AntiAliasingType
-	// Filter order for Butterworth/Bessel/Chebyshev
+	// Filter order for Butterworth/Bessel/Chebyshev, realized as cascaded
+	// sections (2-8, clamped)
 	FilterOrder int
 	// Ripple in dB for Chebyshev filter
 	ChebyshevRipple float64
+	// Workers used to filter and resample a single file (0 or 1 = sequential, -1 = all CPUs)
+	Concurrency int
+	// Process in fixed blocks laid out like ConvertWavStreamToUlaw, so the output
+	// is byte-identical whatever Concurrency, CPU count or entry point is used
+	Deterministic bool
+	// Bound the latency of a ChunkEncoder by RealTimeLatency for live
+	// conversations: stages needing the whole signal are refused and the
+	// resampling window is limited to the lookahead the bound leaves
+	RealTime bool
+	// Non-standard u-law bit layout of the output for legacy switches
+	// (0 = standard G.711)
+	UlawVariant UlawVariant
+	// Encoding of the output: u-law (the default), A-law or raw 16-bit PCM
+	// at 8 kHz
+	OutputCodec OutputCodec
+	// Clamp out-of-range parameters to the nearest accepted value, with a
+	// warning through OnWarning and Logger, instead of failing with a
+	// *ConfigError
+	Lenient bool
+	// Fail conversions whose output shows signs of a broken conversion with
+	// a *SanityError instead of returning it: silence from audible input,
+	// a level held for 100 ms (DC, or samples derived from NaN), mostly DC
+	// offset, or a length under half or over twice the expected one.
+	// Checked by the in-memory conversions; streaming ones cannot take back
+	// output already written.
+	SanityCheck bool
+	// Bounds on the sizes, channels and duration a WAV input may declare
+	// (nil = DefaultWavLimits)
+	Limits *WavLimits
+	// Called for each clipped region found in the source audio (nil = no detection)
+	OnClipping func(region ClipRegion)
+	// Called with each condition the conversion worked around or let
+	// through instead of failing: clamped settings, clipping, skipped
+	// normalization of silent input, input already u-law and the like. The
+	// same warnings are logged through Logger (nil = none).
+	OnWarning func(warning Warning)
+	// Called as processing advances with the completed fraction (0 to 1), possibly
+	// from several goroutines but never concurrently (nil = no reporting)
+	OnProgress func(fraction float64)
+	// Called when a processing stage (e.g. "decode", "normalize") finishes, with
+	// its duration (nil = no reporting)
+	OnStage func(stage string, duration time.Duration)
+	// Called with the duration and sample counts of each processing stage as
+	// it finishes, e.g. to feed a service's own telemetry (nil = no reporting)
+	OnStageMetrics func(metrics StageMetrics)
+	// Called with the audio after each processing stage (e.g. "high-pass",
+	// "resample") at that stage's sample rate, to find the stage that
+	// introduces an artifact. samples must not be modified or kept. Filtering
+	// and resampling in blocks (Concurrency, Deterministic) is reported as a
+	// single "filter and resample" stage, and streaming conversions do not
+	// call it (nil = none).
+	OnStageOutput func(stage string, samples []int16, sampleRate int)
+	// Receives debug logs of the input format, resampling and stage timings (nil = silent)
+	Logger *slog.Logger
 }
 
 // DefaultAudioConfig returns default audio configuration
@@ -61,9 +171,16 @@ func DefaultAudioConfig() *AudioConfig {
 		LowPassCutoff:         3400,    // Telephone bandwidth
 		HighPassCutoff:        200,     // Soft low-frequency cutoff
 		NormalizePeak:         0.95,    // 95% of maximum amplitude
+		Tempo:                 1.0,     // Keep original pacing
 		CompressionRatio:      1.5,     // Light compression
 		CompressionThreshold:  0.5,     // Start compression at 50% of maximum amplitude
+		FadeInMs:              0,       // No fade-in
+		FadeOutMs:             0,       // No fade-out
+		FadeShape:             FadeCosine, // Smooth ramps avoid clicks
 		ResamplingWindowSize:  64,      // Larger window for better quality
+		ResampleMethod:        ResampleSinc, // Sinc interpolation works for files and streams
+		WindowFunction:        WindowBlackman, // Good stopband attenuation
+		KaiserBeta:            8.6,     // Comparable to Blackman when Kaiser is selected
 		AntiAliasingCutoffRatio: 0.95,  // Soft anti-aliasing
 		AntiAliasingType:      AASimple, // Simple filter for stability
 		FilterOrder:           2,       // Low order for stability
@@ -71,159 +188,34 @@ func DefaultAudioConfig() *AudioConfig {
 	}
 }
 
-// applyButterworthFilter applies a Butterworth low-pass filter
+// applyButterworthFilter applies a Butterworth low-pass filter in place
 func applyButterworthFilter(samples []int16, sampleRate, cutoffFreq float64, order int) []int16 {
-	// Normalize frequency
-	wc := 2.0 * math.Pi * cutoffFreq / sampleRate
-	
-	// Create filter
-	result := make([]int16, len(samples))
-	copy(result, samples)
-	
-	// Calculate filter coefficients
-	alpha := math.Tan(wc / 2.0)
-	cosw := math.Cos(wc)
-	
-	a0 := 1.0 + alpha
-	b0 := (1.0 - cosw) / 2.0
-	b1 := 1.0 - cosw
-	b2 := (1.0 - cosw) / 2.0
-	a1 := -2.0 * cosw
-	a2 := 1.0 - alpha
-	
-	// Normalize coefficients
-	b0 /= a0
-	b1 /= a0
-	b2 /= a0
-	a1 /= a0
-	a2 /= a0
-	
-	// Apply filter
-	x1, x2 := 0.0, 0.0
-	y1, y2 := 0.0, 0.0
-	
-	for i := range result {
-		x := float64(result[i]) / 32767.0 // Normalize to [-1, 1]
-		
-		// Direct form II
-		y := b0*x + b1*x1 + b2*x2 - a1*y1 - a2*y2
-		
-		// Update delays
-		x2, x1 = x1, x
-		y2, y1 = y1, y
-		
-		// Scale back to int16
-		result[i] = int16(math.Max(-32768, math.Min(32767, y*32767.0)))
-	}
-	
-	return result
+	key := filterKey{kind: AAButterworth, sampleRate: sampleRate, cutoff: cutoffFreq, order: order}
+	sections := cachedFilterSections(key, func() []filterCoefficients {
+		return butterworthSections(sampleRate, cutoffFreq, order)
+	})
+
+	return applySections(samples, sections)
 }
 
-// applyBesselFilter applies a Bessel low-pass filter
+// applyBesselFilter applies a Bessel low-pass filter in place
 func applyBesselFilter(samples []int16, sampleRate, cutoffFreq float64, order int) []int16 {
-	// Normalize frequency
-	wc := 2.0 * math.Pi * cutoffFreq / sampleRate
-	
-	// Create filter
-	result := make([]int16, len(samples))
-	copy(result, samples)
-	
-	// Calculate filter coefficients (3rd order Bessel approximation)
-	alpha := math.Tan(wc / 2.0)
-	alphaSq := alpha * alpha
-	alphaCu := alphaSq * alpha
-	
-	a0 := 1.0 + 2.15*alpha + 3.15*alphaSq + 1.0*alphaCu
-	b0 := 1.0 * alphaCu
-	b1 := 3.0 * alphaCu
-	b2 := 3.0 * alphaCu
-	b3 := 1.0 * alphaCu
-	a1 := (-2.0 + 2.15*alpha - 3.15*alphaSq + 3.0*alphaCu) 
-	a2 := (1.0 - 2.15*alpha + 3.15*alphaSq - 3.0*alphaCu)
-	a3 := (-0.0 + 0.0*alpha - 0.0*alphaSq + 1.0*alphaCu)
-	
-	// Normalize coefficients
-	b0 /= a0
-	b1 /= a0
-	b2 /= a0
-	b3 /= a0
-	a1 /= a0
-	a2 /= a0
-	a3 /= a0
-	
-	// Apply filter
-	x1, x2, x3 := 0.0, 0.0, 0.0
-	y1, y2, y3 := 0.0, 0.0, 0.0
-	
-	for i := range result {
-		x := float64(result[i]) / 32767.0 // Normalize to [-1, 1]
-		
-		// Direct form II
-		y := b0*x + b1*x1 + b2*x2 + b3*x3 - a1*y1 - a2*y2 - a3*y3
-		
-		// Update delays
-		x3, x2, x1 = x2, x1, x
-		y3, y2, y1 = y2, y1, y
-		
-		// Scale back to int16
-		result[i] = int16(math.Max(-32768, math.Min(32767, y*32767.0)))
-	}
-	
-	return result
+	key := filterKey{kind: AABessel, sampleRate: sampleRate, cutoff: cutoffFreq, order: order}
+	sections := cachedFilterSections(key, func() []filterCoefficients {
+		return besselSections(sampleRate, cutoffFreq, order)
+	})
+
+	return applySections(samples, sections)
 }
 
-// applyChebyshevFilter applies a Chebyshev Type I low-pass filter
+// applyChebyshevFilter applies a Chebyshev Type I low-pass filter in place
 func applyChebyshevFilter(samples []int16, sampleRate, cutoffFreq, rippleDb float64, order int) []int16 {
-	// Normalize frequency
-	wc := 2.0 * math.Pi * cutoffFreq / sampleRate
-	
-	// Create filter
-	result := make([]int16, len(samples))
-	copy(result, samples)
-	
-	// Calculate filter coefficients (2nd order Chebyshev approximation)
-	epsilon := math.Sqrt(math.Pow(10, rippleDb/10) - 1)
-	v0 := math.Asinh(1/epsilon) / 2.0
-	
-	alpha := math.Tan(wc / 2.0)
-	alphaSq := alpha * alpha
-	
-	sinh := math.Sinh(v0)
-	
-	// Calculate coefficients
-	a0 := 1.0 + 2.0*alpha*sinh + alphaSq
-	b0 := alphaSq
-	b1 := 2.0 * alphaSq
-	b2 := alphaSq
-	a1 := 2.0 * (alphaSq - 1.0)
-	a2 := 1.0 - 2.0*alpha*sinh + alphaSq
-	
-	// Normalize coefficients
-	b0 /= a0
-	b1 /= a0
-	b2 /= a0
-	a1 /= a0
-	a2 /= a0
-	
-	// Apply filter
-	x1, x2 := 0.0, 0.0
-	y1, y2 := 0.0, 0.0
-	
-	for i := range result {
-		x := float64(result[i]) / 32767.0 // Normalize to [-1, 1]
-		
-		// Direct form II
-		y := b0*x + b1*x1 + b2*x2 - a1*y1 - a2*y2
-		
-		// Update delays
-		x2, x1 = x1, x
-		y2, y1 = y1, y
-		
-		// Scale back to int16
-		result[i] = int16(math.Max(-32768, math.Min(32767, y*32767.0)))
-	}
-	
-	return result
+	key := filterKey{kind: AAChebyshev, sampleRate: sampleRate, cutoff: cutoffFreq, ripple: rippleDb, order: order}
+	sections := cachedFilterSections(key, func() []filterCoefficients {
+		return chebyshevSections(sampleRate, cutoffFreq, rippleDb, order)
+	})
+
+	return applySections(samples, sections)
 }
 
 // applyAntiAliasingFilter applies the selected anti-aliasing filter
@@ -247,24 +239,98 @@ func applyAntiAliasingFilter(samples []int16, sampleRate, targetRate float64, co
 		return applyBesselFilter(samples, sampleRate, cutoffFreq, config.FilterOrder)
 	case AAChebyshev:
 		return applyChebyshevFilter(samples, sampleRate, cutoffFreq, config.ChebyshevRipple, config.FilterOrder)
+	case AAWindowedSinc, AAAuto:
+		return applyFIRFilter(samples, antiAliasingKernel(sampleRate, targetRate, config))
 	default: // AASimple
 		return applyLowPassFilter(samples, sampleRate, cutoffFreq)
 	}
 }
 
-// resamplePCM16 resamples 16-bit PCM audio to a new sample rate using windowed sinc interpolation
-func resamplePCM16(input []int16, inputRate, outputRate float64, windowSize int) []int16 {
-	ratio := outputRate / inputRate
-	outputLen := int(float64(len(input)) * ratio)
-	output := make([]int16, outputLen)
+// antiAliasingKernel designs the windowed-sinc anti-aliasing filter. The
+// kernel grows with the decimation ratio so the transition band stays a
+// similar width at the output rate.
+func antiAliasingKernel(sampleRate, targetRate float64, config *AudioConfig) []float64 {
+	if config.AntiAliasingType == AAAuto {
+		return autoAntiAliasingKernel(sampleRate, targetRate)
+	}
+	cutoff := targetRate / 2.0 * config.AntiAliasingCutoffRatio / sampleRate
+	halfLength := config.ResamplingWindowSize * int(math.Ceil(sampleRate/targetRate))
+	return designLowpassFIR(halfLength, cutoff, resampleWindow(halfLength, config))
+}
 
-	// Pre-calculate window coefficients
-	window := make([]float64, windowSize*2+1)
-	for i := range window {
-		// Blackman window
-		x := float64(i) / float64(len(window)-1)
-		window[i] = 0.42 - 0.5*math.Cos(2*math.Pi*x) + 0.08*math.Cos(4*math.Pi*x)
+// resample converts samples between sample rates using the configured method
+func resample(samples []int16, inputRate, outputRate int, config *AudioConfig) []int16 {
+	config = tradeoffConfig(config)
+	switch config.ResampleMethod {
+	case ResampleFFT:
+		return resamplePCM16FFT(samples, inputRate, outputRate)
+	default: // ResampleSinc
+		return resampleSinc(samples, inputRate, outputRate, config, nil)
 	}
+}
+
+// resampleSinc runs the windowed-sinc resampler best suited to the rate pair.
+// A non-nil prefilter is convolved into the resampler kernels where the path
+// allows it and applied separately otherwise.
+func resampleSinc(samples []int16, inputRate, outputRate int, config *AudioConfig, prefilter []float64) []int16 {
+	if config.ResampleTradeoff == ResampleBest {
+		if prefilter != nil {
+			samples = applyFIRFilter(samples, prefilter)
+			defer putInt16s(samples)
+		}
+		return resampleExact(samples, inputRate, outputRate, config)
+	}
+	window := resampleWindow(config.ResamplingWindowSize, config)
+	// Exact multiples of the output rate (16/24/48 kHz to 8 kHz) use dedicated decimators
+	if inputRate%outputRate == 0 {
+		if stages := decimationStages(inputRate / outputRate); stages != nil {
+			return decimatePCM16(samples, stages, config.ResamplingWindowSize, window, prefilter)
+		}
+	}
+	// Common rate pairs reduce to a small ratio, use the polyphase fast path
+	if bank, up, down, ok := rationalBank(inputRate, outputRate, config, window, prefilter); ok {
+		return resamplePolyphase(samples, up, down, bank)
+	}
+	if prefilter != nil {
+		samples = applyFIRFilter(samples, prefilter)
+		defer putInt16s(samples)
+	}
+	return resamplePCM16WithTable(samples, float64(inputRate), float64(outputRate), config.ResamplingWindowSize, window)
+}
+
+// filterAndResample runs the filter chain and converts to outputRate. A
+// windowed-sinc anti-aliasing filter is not run as a separate pass: its kernel
+// is combined with the sinc resampler's once up front, so every output sample
+// costs one convolution instead of filtering every input sample first.
+func filterAndResample(samples []int16, inputRate, outputRate int, config *AudioConfig) []int16 {
+	if inputRate == outputRate {
+		return applyFilterChain(samples, inputRate, config)
+	}
+	config = tradeoffConfig(config)
+	if config.AntiAliasingType.isFIR() && config.ResampleMethod == ResampleSinc && inputRate > outputRate {
+		samples = applyBandFilters(samples, inputRate, config)
+		prefilter := antiAliasingKernel(float64(inputRate), float64(outputRate), config)
+		samples = release(samples, resampleSinc(samples, inputRate, outputRate, config, prefilter))
+		dumpStage(config, "anti-aliasing and resample", samples, outputRate)
+		return samples
+	}
+	samples = applyFilterChain(samples, inputRate, config)
+	samples = release(samples, resample(samples, inputRate, outputRate, config))
+	dumpStage(config, "resample", samples, outputRate)
+	return samples
+}
+
+// resamplePCM16 resamples 16-bit PCM audio to a new sample rate using windowed sinc interpolation.
+// window must hold windowSize*2+1 coefficients.
+func resamplePCM16(input []int16, inputRate, outputRate float64, windowSize int, window []float64) []int16 {
+	n := int(math.Ceil(float64(len(input)) * outputRate / inputRate))
+	return resamplePCM16Length(input, inputRate, outputRate, n, windowSize, window)
+}
+
+// resamplePCM16Length is resamplePCM16 producing exactly n samples
+func resamplePCM16Length(input []int16, inputRate, outputRate float64, n, windowSize int, window []float64) []int16 {
+	ratio := outputRate / inputRate
+	output := getInt16s(n)
 
 	for i := range output {
 		pos := float64(i) / ratio
@@ -275,10 +341,8 @@ func resamplePCM16(input []int16, inputRate, outputRate float64, windowSize int)
 		weightSum := 0.0
 
 		for j := -windowSize; j <= windowSize; j++ {
+			// Past the edges the signal is mirrored
 			inputIdx := idx + j
-			if inputIdx < 0 || inputIdx >= len(input) {
-				continue
-			}
 
 			// Calculate sinc value
 			x := math.Pi * (pos - float64(inputIdx))
@@ -291,7 +355,7 @@ func resamplePCM16(input []int16, inputRate, outputRate float64, windowSize int)
 
 			// Apply window function
 			weight := window[j+windowSize] * sinc
-			sum += float64(input[inputIdx]) * weight
+			sum += float64(input[reflectIndex(inputIdx, len(input))]) * weight
 			weightSum += weight
 		}
 
@@ -299,21 +363,19 @@ func resamplePCM16(input []int16, inputRate, outputRate float64, windowSize int)
 		if weightSum > 0 {
 			sum /= weightSum
 		}
-		output[i] = int16(math.Round(sum))
+		output[i] = int16(math.Max(-32768, math.Min(32767, math.Round(sum))))
 	}
 
 	return output
 }
 
-// applyHighPassFilter applies a simple high-pass filter to the samples
+// applyHighPassFilter applies a simple high-pass filter to the samples in place
 func applyHighPassFilter(samples []int16, sampleRate float64, cutoffFreq float64) []int16 {
 	// Calculate RC constant for the filter
 	rc := 1.0 / (2.0 * math.Pi * cutoffFreq)
 	dt := 1.0 / sampleRate
 	alpha := rc / (rc + dt)
 
-	filtered := make([]int16, len(samples))
-	filtered[0] = samples[0]
 	var prevInput float64
 	var prevOutput float64
 
@@ -321,36 +383,49 @@ func applyHighPassFilter(samples []int16, sampleRate float64, cutoffFreq float64
 		input := float64(samples[i])
 		// High pass filter formula: y[i] = alpha * (y[i-1] + x[i] - x[i-1])
 		output := alpha * (prevOutput + input - prevInput)
-		filtered[i] = int16(math.Round(output))
+		samples[i] = int16(math.Max(-32768, math.Min(32767, math.Round(output))))
 		prevInput = input
 		prevOutput = output
 	}
 
-	return filtered
+	return samples
 }
 
-// applyLowPassFilter applies a simple low-pass filter to the samples
+// applyLowPassFilter applies a simple low-pass filter to the samples in place
 func applyLowPassFilter(samples []int16, sampleRate float64, cutoffFreq float64) []int16 {
 	// Calculate RC constant for the filter
 	rc := 1.0 / (2.0 * math.Pi * cutoffFreq)
 	dt := 1.0 / sampleRate
 	alpha := dt / (rc + dt)
 
-	filtered := make([]int16, len(samples))
-	filtered[0] = samples[0]
-
 	for i := 1; i < len(samples); i++ {
 		// Low pass filter formula: y[i] = y[i-1] + alpha * (x[i] - y[i-1])
-		float_sample := float64(filtered[i-1]) + alpha*float64(samples[i]-filtered[i-1])
-		filtered[i] = int16(math.Round(float_sample))
+		float_sample := float64(samples[i-1]) + alpha*float64(samples[i]-samples[i-1])
+		samples[i] = int16(math.Round(float_sample))
 	}
 
-	return filtered
+	return samples
 }
 
-// normalizeAudio normalizes audio to the specified peak level
+// normalizeAudio normalizes audio in place to the specified peak level
 func normalizeAudio(samples []int16, peakLevel float64) []int16 {
-	// Find current peak
+	// Silence stays silent instead of scaling by infinity
+	scale := normalizeScale(samples, peakLevel)
+	if scale == 0 {
+		return samples
+	}
+
+	// Apply normalization in place
+	for i, sample := range samples {
+		samples[i] = int16(math.Round(float64(sample) * scale))
+	}
+
+	return samples
+}
+
+// normalizeScale returns the factor bringing the peak of samples to
+// peakLevel, 0 for silence
+func normalizeScale(samples []int16, peakLevel float64) float64 {
 	maxAbs := float64(0)
 	for _, sample := range samples {
 		abs := math.Abs(float64(sample))
@@ -358,22 +433,14 @@ func normalizeAudio(samples []int16, peakLevel float64) []int16 {
 			maxAbs = abs
 		}
 	}
-
-	// Calculate scaling factor
-	scale := (peakLevel * 32767.0) / maxAbs
-
-	// Apply normalization
-	normalized := make([]int16, len(samples))
-	for i, sample := range samples {
-		normalized[i] = int16(math.Round(float64(sample) * scale))
+	if maxAbs == 0 {
+		return 0
 	}
-
-	return normalized
+	return (peakLevel * 32767.0) / maxAbs
 }
 
-// applyCompression applies dynamic range compression
+// applyCompression applies dynamic range compression in place
 func applyCompression(samples []int16, ratio, threshold float64) []int16 {
-	compressed := make([]int16, len(samples))
 	thresholdAbs := threshold * 32767.0
 
 	for i, sample := range samples {
@@ -383,150 +450,458 @@ func applyCompression(samples []int16, ratio, threshold float64) []int16 {
 		if sampleAbs > thresholdAbs {
 			// Apply compression above threshold
 			excess := sampleAbs - thresholdAbs
-			compressed[i] = int16(math.Round(math.Copysign(
+			samples[i] = int16(math.Round(math.Copysign(
 				thresholdAbs + (excess/ratio),
 				sampleFloat,
 			)))
-		} else {
-			compressed[i] = sample
 		}
 	}
 
-	return compressed
+	return samples
 }
 
-// ConvertWavBytesToUlaw converts WAV file bytes to u-law encoded bytes
+// ErrEmptyInput is returned by the WAV to u-law conversions for input without
+// any audio samples, such as a WAV file with an empty data chunk
+var ErrEmptyInput = errors.New("input has no audio samples")
+
+// ConvertWavBytesToUlaw converts WAV file bytes to u-law encoded bytes, or
+// to config.OutputCodec when another codec is set
 func ConvertWavBytesToUlaw(wavBytes []byte, config *AudioConfig) ([]byte, error) {
 	if config == nil {
 		config = DefaultAudioConfig()
 	}
+	return convertWavBytes(wavBytes, config, nil)
+}
 
-	// Create a decoder
-	reader := bytes.NewReader(wavBytes)
-	decoder := wav.NewDecoder(reader)
-	if !decoder.IsValidFile() {
-		return nil, fmt.Errorf("invalid WAV file")
+// convertWavBytes implements ConvertWavBytesToUlaw, recording what the
+// processing did in info unless it is nil
+func convertWavBytes(wavBytes []byte, config *AudioConfig, info *ConversionInfo) ([]byte, error) {
+	start := time.Now()
+	samples, inputSampleRate, err := decodeWavSamples(wavBytes, config)
+	if err != nil {
+		return nil, err
+	}
+	if len(samples) == 0 {
+		putInt16s(samples)
+		return nil, ErrEmptyInput
+	}
+	if config, err = validateConfig(config, inputSampleRate); err != nil {
+		putInt16s(samples)
+		return nil, err
+	}
+	logStage(config, "decode", start, len(samples), len(samples))
+	dumpStage(config, "decode", samples, inputSampleRate)
+	if config.Noise != nil {
+		if err := addNoise(samples, inputSampleRate, config); err != nil {
+			putInt16s(samples)
+			return nil, err
+		}
+		dumpStage(config, "noise", samples, inputSampleRate)
 	}
 
-	// Read audio format
-	format := decoder.Format()
-	if format == nil {
-		return nil, fmt.Errorf("error reading WAV format")
+	sanity := newSanityWatch(config, samples, inputSampleRate)
+	progress := newProgressReporter(config)
+	samples = processSamples(samples, inputSampleRate, config, progress, info)
+	if info != nil {
+		info.ClippedSamples = clippedSamples(samples)
 	}
 
-	// Read audio data
-	buf, err := decoder.FullPCMBuffer()
+	// Encode, the samples buffer can be reused by the next conversion
+	start = time.Now()
+	n := len(samples)
+	output, err := encodeOutput(config, samples, sanity)
+	putInt16s(samples)
 	if err != nil {
-		return nil, fmt.Errorf("error reading WAV data: %v", err)
+		return nil, err
+	}
+	logStage(config, "encode", start, n, n)
+	progress.report(1)
+	return output, nil
+}
+
+// ConvertPCM16ToUlaw runs mono 16-bit samples at sampleRate through the
+// processing chain and encodes them to u-law, or to config.OutputCodec when
+// another codec is set. samples is not modified.
+func ConvertPCM16ToUlaw(samples []int16, sampleRate int, config *AudioConfig) ([]byte, error) {
+	if config == nil {
+		config = DefaultAudioConfig()
+	}
+	if sampleRate <= 0 {
+		return nil, fmt.Errorf("invalid sample rate %d", sampleRate)
+	}
+	if len(samples) == 0 {
+		return nil, ErrEmptyInput
+	}
+	config, err := validateConfig(config, sampleRate)
+	if err != nil {
+		return nil, err
+	}
+
+	// processSamples takes ownership of its input, so work on a pooled copy
+	buf := getInt16s(len(samples))
+	copy(buf, samples)
+	if config.Noise != nil {
+		if err := addNoise(buf, sampleRate, config); err != nil {
+			putInt16s(buf)
+			return nil, err
+		}
+		dumpStage(config, "noise", buf, sampleRate)
+	}
+
+	sanity := newSanityWatch(config, buf, sampleRate)
+	progress := newProgressReporter(config)
+	buf = processSamples(buf, sampleRate, config, progress, nil)
+	start := time.Now()
+	n := len(buf)
+	output, err := encodeOutput(config, buf, sanity)
+	putInt16s(buf)
+	if err != nil {
+		return nil, err
+	}
+	logStage(config, "encode", start, n, n)
+	progress.report(1)
+	return output, nil
+}
+
+// decodeWavSamples parses WAV bytes into 16-bit PCM samples and returns them
+// together with the effective input sample rate
+func decodeWavSamples(wavBytes []byte, config *AudioConfig) ([]int16, int, error) {
+	// Find the format and audio
+	header, err := inspectWav(bytes.NewReader(wavBytes), config.Limits)
+	if err != nil {
+		return nil, 0, err
+	}
+	pcm := decodeWavData(wavBytes, header)
+
+	// Report clipped regions in the source before any processing hides them
+	if config.OnClipping != nil {
+		data := make([]float64, len(pcm))
+		for i, v := range pcm {
+			data[i] = normalizeSample(v, header.bitDepth)
+		}
+		for _, region := range detectClipRegions(data, header.channels, header.sampleRate, header.bitDepth) {
+			config.OnClipping(region)
+		}
 	}
 
 	// Get actual input sample rate
 	inputSampleRate := config.InputSampleRate
 	if inputSampleRate == 0 {
-		inputSampleRate = format.SampleRate
+		inputSampleRate = header.sampleRate
 	}
 
 	// Convert samples to int16 and handle mono conversion if needed
-	var samples []int16
-	if config.ForceMono && format.NumChannels > 1 {
-		// Average all channels to mono
-		samples = make([]int16, len(buf.Data)/format.NumChannels)
-		for i := 0; i < len(samples); i++ {
-			sum := 0
-			for ch := 0; ch < format.NumChannels; ch++ {
-				idx := i*format.NumChannels + ch
-				if idx < len(buf.Data) {
-					sum += buf.Data[idx]
-				}
-			}
-			avg := sum / format.NumChannels
-			if buf.SourceBitDepth == 8 {
-				samples[i] = int16((avg + 128) << 8)
-			} else {
-				samples[i] = int16(avg)
-			}
-		}
-	} else {
-		// Convert to int16 without channel mixing
-		samples = make([]int16, len(buf.Data))
-		for i, sample := range buf.Data {
-			if buf.SourceBitDepth == 8 {
-				samples[i] = int16((sample + 128) << 8)
-			} else {
-				samples[i] = int16(sample)
+	mono := config.ForceMono && header.channels > 1
+	n := len(pcm)
+	if mono {
+		n /= header.channels
+	}
+	samples := getInt16s(n)
+	pcmToInt16(samples, pcm, header.channels, header.bitDepth, mono)
+
+	logDebug(config, "decoded WAV",
+		"sample_rate", header.sampleRate,
+		"channels", header.channels,
+		"bit_depth", header.bitDepth,
+		"frames", len(pcm)/header.channels,
+		"mono", mono,
+	)
+	return samples, inputSampleRate, nil
+}
+
+// pcmToInt16 converts decoded PCM values to 16-bit samples in dst, scaling
+// them from bitDepth (unsigned 8-bit, signed 16, 24 or 32-bit) to 16 bits
+// with rounding. With mono set, channels are summed in 64 bits and averaged,
+// so dst holds one sample per frame, otherwise values are converted without
+// channel mixing.
+func pcmToInt16(dst []int16, data []int, channels, bitDepth int, mono bool) {
+	if !mono {
+		channels = 1
+	}
+	var offset int64
+	if bitDepth == 8 {
+		offset = 128
+	}
+	if bitDepth <= 0 {
+		bitDepth = 16
+	}
+	// Converts a channel sum to 16-bit full scale and averages it
+	scale := math.Ldexp(1, 16-bitDepth) / float64(channels)
+
+	for i := range dst {
+		var sum int64
+		for ch := 0; ch < channels; ch++ {
+			if idx := i*channels + ch; idx < len(data) {
+				sum += int64(data[idx]) - offset
 			}
 		}
+		dst[i] = int16(math.Max(-32768, math.Min(32767, math.Round(float64(sum)*scale))))
+	}
+}
+
+// applyFilterChain runs the filters that operate at the original sample rate.
+// It takes ownership of samples, which IIR filters overwrite in place.
+func applyFilterChain(samples []int16, inputSampleRate int, config *AudioConfig) []int16 {
+	samples = applyBandFilters(samples, inputSampleRate, config)
+
+	// Apply anti-aliasing filter before resampling
+	if inputSampleRate <= 8000 {
+		return samples
 	}
+	samples = release(samples, applyAntiAliasingFilter(samples, float64(inputSampleRate), 8000, config))
+	dumpStage(config, "anti-aliasing", samples, inputSampleRate)
+	return samples
+}
 
-	// Apply audio processing on original sample rate
+// applyBandFilters runs the high-pass and low-pass filters in place
+func applyBandFilters(samples []int16, inputSampleRate int, config *AudioConfig) []int16 {
 	if config.HighPassCutoff > 0 {
 		samples = applyHighPassFilter(samples, float64(inputSampleRate), config.HighPassCutoff)
+		dumpStage(config, "high-pass", samples, inputSampleRate)
 	}
 
 	if config.LowPassCutoff > 0 {
 		samples = applyLowPassFilter(samples, float64(inputSampleRate), config.LowPassCutoff)
+		dumpStage(config, "low-pass", samples, inputSampleRate)
 	}
 
-	// Apply anti-aliasing filter before resampling
-	samples = applyAntiAliasingFilter(samples, float64(inputSampleRate), 8000, config)
+	return samples
+}
+
+// release returns the previous stage's buffer to the pool when the next
+// stage produced a new one, and yields the new buffer
+func release(prev, next []int16) []int16 {
+	if !sameBuffer(prev, next) {
+		putInt16s(prev)
+	}
+	return next
+}
+
+// processSamples runs the filtering, resampling and level processing chain,
+// producing 8 kHz samples ready for encoding. It takes ownership of samples:
+// filters and level stages overwrite it in place, stages that change the
+// length return their input to the pool, so the caller must not reuse it.
+// The resampling and the gain of the level stages are recorded in info
+// unless it is nil.
+func processSamples(samples []int16, inputSampleRate int, config *AudioConfig, progress *progressReporter, info *ConversionInfo) []int16 {
+	checkAlreadyUlaw(config, samples, inputSampleRate)
+	inputSampleRate = speedRate(inputSampleRate, config.Speed)
+	if info != nil {
+		info.ResampleRate = inputSampleRate
+		info.ResampleRatio = 8000 / float64(inputSampleRate)
+	}
+	logResample(config, inputSampleRate, 8000)
+	clips := newClipWatch(config, samples)
+	start := time.Now()
+	n := len(samples)
+	if parallel, ok := processBlocksParallel(samples, inputSampleRate, 8000, withoutStageOutput(config), progress); ok {
+		samples = release(samples, parallel)
+		logStage(config, "filter and resample (parallel)", start, n, len(samples))
+		dumpStage(config, "filter and resample", samples, 8000)
+	} else {
+		// Filter and resample to 8kHz using the configured method
+		samples = filterAndResample(samples, inputSampleRate, 8000, config)
+		logStage(config, "filter and resample", start, n, len(samples))
+	}
+	clips.check("filter and resample", samples)
+	progress.report(filterProgressShare)
+
+	if config.LevelCompensation {
+		start = time.Now()
+		gain := filterChainGain(inputSampleRate, config)
+		scaleSamples(samples, 1/gain)
+		if info != nil {
+			info.GainDb -= 20 * math.Log10(gain)
+		}
+		logStage(config, "level compensation", start, len(samples), len(samples))
+		logDebug(config, "filter and resample gain compensated", "gain_db", 20*math.Log10(gain))
+		dumpStage(config, "level compensation", samples, 8000)
+	}
 
-	// Resample to 8kHz using optimized function
-	if inputSampleRate != 8000 {
-		samples = resamplePCM16WithTable(samples, float64(inputSampleRate), 8000, config.ResamplingWindowSize)
+	// Trim before the level stages, so dead air does not count towards them
+	if config.AutoTrim != nil {
+		start, n = time.Now(), len(samples)
+		samples = release(samples, trimToSpeech(samples, 8000, config.AutoTrim))
+		logStage(config, "auto-trim", start, n, len(samples))
+		dumpStage(config, "auto-trim", samples, 8000)
+	}
+
+	// Change tempo at the output rate where WSOLA is cheapest
+	if config.Tempo > 0 && config.Tempo != 1.0 {
+		start, n = time.Now(), len(samples)
+		samples = release(samples, timeStretch(samples, 8000, config.Tempo))
+		logStage(config, "tempo", start, n, len(samples))
+		clips.check("tempo", samples)
+		dumpStage(config, "tempo", samples, 8000)
+	}
+
+	if config.Reverse {
+		start = time.Now()
+		slices.Reverse(samples)
+		logStage(config, "reverse", start, len(samples), len(samples))
+		dumpStage(config, "reverse", samples, 8000)
 	}
 
 	// Apply volume processing after resampling
 	if config.CompressionRatio > 1.0 {
+		start = time.Now()
 		samples = applyCompression(samples, config.CompressionRatio, config.CompressionThreshold)
+		logStage(config, "compression", start, len(samples), len(samples))
+		clips.check("compression", samples)
+		dumpStage(config, "compression", samples, 8000)
 	}
 
-	if config.NormalizePeak > 0 {
+	if config.TargetLoudness != 0 {
+		start = time.Now()
+		gain, ok := matchLoudness(samples, 8000, config.TargetLoudness)
+		if !ok {
+			logWarn(config, WarnSilentInput, "loudness", "loudness matching skipped: silent input")
+		}
+		if info != nil {
+			info.GainDb += gain
+		}
+		logStage(config, "loudness", start, len(samples), len(samples))
+		logDebug(config, "loudness matched", "target_lufs", config.TargetLoudness, "gain_db", gain)
+		dumpStage(config, "loudness", samples, 8000)
+	} else if config.NormalizePeak > 0 {
+		start = time.Now()
+		if scale := normalizeScale(samples, config.NormalizePeak); scale == 0 {
+			logWarn(config, WarnSilentInput, "normalize", "normalization skipped: silent input")
+		} else if info != nil {
+			info.GainDb += 20 * math.Log10(scale)
+		}
 		samples = normalizeAudio(samples, config.NormalizePeak)
+		logStage(config, "normalize", start, len(samples), len(samples))
+		clips.check("normalize", samples)
+		dumpStage(config, "normalize", samples, 8000)
 	}
 
-	// Convert samples to bytes for g711
-	pcmBytes := make([]byte, len(samples)*2)
-	for i, sample := range samples {
-		binary.LittleEndian.PutUint16(pcmBytes[i*2:], uint16(sample))
+	// Fade after normalization so the ramps end exactly at silence
+	if config.FadeInMs > 0 || config.FadeOutMs > 0 {
+		start = time.Now()
+		samples = applyFades(samples, 8000, config.FadeInMs, config.FadeOutMs, config.FadeShape)
+		logStage(config, "fades", start, len(samples), len(samples))
+		dumpStage(config, "fades", samples, 8000)
 	}
 
-	// Convert to u-law
-	ulawData := g711.EncodeUlaw(pcmBytes)
-	return ulawData, nil
+	// Pad last, so no stage touches the silence
+	if config.PadStart > 0 || config.PadEnd > 0 {
+		start, n = time.Now(), len(samples)
+		samples = release(samples, padSilence(samples, 8000, config.PadStart, config.PadEnd))
+		logStage(config, "pad", start, n, len(samples))
+		dumpStage(config, "pad", samples, 8000)
+	}
+
+	// Beeps go on the padded output, whose timeline their positions refer to
+	if len(config.Beeps) > 0 {
+		start = time.Now()
+		mixBeeps(samples, config.Beeps)
+		logStage(config, "beeps", start, len(samples), len(samples))
+		clips.check("beeps", samples)
+		dumpStage(config, "beeps", samples, 8000)
+	}
+
+	// Align last, so the padding is all silence
+	if pad := framePadding(len(samples), config.FrameAlign); pad > 0 {
+		start, n = time.Now(), len(samples)
+		aligned := getInt16s(n + pad)
+		copy(aligned, samples)
+		clear(aligned[n:])
+		samples = release(samples, aligned)
+		logStage(config, "frame align", start, n, len(samples))
+		dumpStage(config, "frame align", samples, 8000)
+	}
+
+	return samples
 }
 
 // ConvertUlawBytesToWav converts u-law encoded bytes back to WAV file bytes
 func ConvertUlawBytesToWav(ulawBytes []byte, sampleRate uint32, windowSize int) ([]byte, error) {
-	// Convert u-law to PCM
-	pcmData := g711.DecodeUlaw(ulawBytes)
+	return ConvertUlawBytesToWavLength(ulawBytes, sampleRate, windowSize, 0)
+}
 
-	// Convert bytes to int16 samples
-	samples := make([]int16, len(pcmData)/2)
-	for i := 0; i < len(samples); i++ {
-		samples[i] = int16(binary.LittleEndian.Uint16(pcmData[i*2:]))
+// ConvertUlawBytesToWavLength converts u-law encoded bytes back to a WAV file
+// of exactly samples samples, or as many as the u-law duration covers when 0.
+// u-law holds whole 8 kHz samples, so a conversion outlasts its source by up
+// to one 8 kHz sample, which becomes several samples at the source rate.
+// Passing the frame count of the source WAV restores its exact duration; the
+// samples sit at the same positions as with ConvertUlawBytesToWav.
+func ConvertUlawBytesToWavLength(ulawBytes []byte, sampleRate uint32, windowSize, samples int) ([]byte, error) {
+	pcm, err := decodeUlawAtRate(ulawBytes, sampleRate, windowSize, samples)
+	if err != nil {
+		return nil, err
+	}
+	return encodeWavPCM16(pcm, int(sampleRate))
+}
+
+// ConvertUlawBytesToPCM16 decodes u-law bytes to mono 16-bit samples at
+// sampleRate, resampled from 8 kHz with a sinc window of windowSize as
+// ConvertUlawBytesToWavLength does, for analysis and playback code that
+// would otherwise parse the WAV file back. samples sets the exact length, 0
+// for as many as the u-law duration covers.
+func ConvertUlawBytesToPCM16(ulawBytes []byte, sampleRate uint32, windowSize, samples int) ([]int16, error) {
+	if sampleRate == 0 {
+		return nil, fmt.Errorf("invalid sample rate %d", sampleRate)
+	}
+	return decodeUlawAtRate(ulawBytes, sampleRate, windowSize, samples)
+}
+
+// decodeUlawAtRate expands u-law bytes to 16-bit PCM at sampleRate, samples
+// long as for ConvertUlawBytesToWavLength
+func decodeUlawAtRate(ulawBytes []byte, sampleRate uint32, windowSize, samples int) ([]int16, error) {
+	return resampleDecoded(decodeUlawSamples(ulawBytes), sampleRate, windowSize, samples)
+}
+
+// resampleDecoded brings 8 kHz samples decoded from G.711 to sampleRate,
+// samples long as for ConvertUlawBytesToWavLength
+func resampleDecoded(pcm []int16, sampleRate uint32, windowSize, samples int) ([]int16, error) {
+	if samples < 0 {
+		return nil, fmt.Errorf("invalid sample count %d", samples)
+	}
+	if samples == 0 {
+		samples = resampledLength(len(pcm), int(sampleRate), 8000)
+	} else if len(pcm) == 0 {
+		return nil, ErrEmptyInput
 	}
 
 	// Resample if needed
 	if sampleRate != 8000 {
-		samples = resamplePCM16(samples, 8000, float64(sampleRate), windowSize)
+		window := makeWindow(WindowBlackman, windowSize*2+1, 0)
+		pcm = resamplePCM16Length(pcm, 8000, float64(sampleRate), samples, windowSize, window)
+	} else if samples != len(pcm) {
+		// Past the end the signal is mirrored, as the resamplers do
+		fitted := make([]int16, samples)
+		for i := range fitted {
+			fitted[i] = pcm[reflectIndex(i, len(pcm))]
+		}
+		pcm = fitted
 	}
+	return pcm, nil
+}
 
-	// Create temporary file for WAV encoder
-	tmpFile, err := os.CreateTemp("", "wav_*.wav")
-	if err != nil {
-		return nil, fmt.Errorf("error creating temporary file: %v", err)
-	}
-	defer os.Remove(tmpFile.Name())
-	defer tmpFile.Close()
+// EncodeWavPCM16 builds a mono 16-bit PCM WAV file from samples at sampleRate
+func EncodeWavPCM16(samples []int16, sampleRate int) ([]byte, error) {
+	return encodeWavPCM16(samples, sampleRate)
+}
 
+// encodeWavPCM16 builds a mono 16-bit PCM WAV file from samples. The encoder
+// writes into memory, so the library needs no file system (e.g. under WASM).
+func encodeWavPCM16(samples []int16, sampleRate int) ([]byte, error) {
+	return encodeWavPCM16Channels(samples, sampleRate, 1)
+}
+
+// encodeWavPCM16Channels builds a 16-bit PCM WAV file of channels from
+// interleaved samples
+func encodeWavPCM16Channels(samples []int16, sampleRate, channels int) ([]byte, error) {
 	// Create WAV encoder
-	enc := wav.NewEncoder(tmpFile, int(sampleRate), 16, 1, 1)
+	out := &writeSeeker{}
+	enc := wav.NewEncoder(out, sampleRate, 16, channels, 1)
 
 	// Convert samples to PCM buffer
 	audioBuf := &audio.IntBuffer{
 		Format: &audio.Format{
-			NumChannels: 1,
-			SampleRate: int(sampleRate),
+			NumChannels: channels,
+			SampleRate: sampleRate,
 		},
 		Data:           make([]int, len(samples)),
 		SourceBitDepth: 16,
@@ -547,15 +922,5 @@ func ConvertUlawBytesToWav(ulawBytes []byte, sampleRate uint32, windowSize int)
 		return nil, fmt.Errorf("error closing WAV encoder: %v", err)
 	}
 
-	// Read the temporary file
-	if _, err := tmpFile.Seek(0, 0); err != nil {
-		return nil, fmt.Errorf("error seeking temporary file: %v", err)
-	}
-
-	wavData, err := io.ReadAll(tmpFile)
-	if err != nil {
-		return nil, fmt.Errorf("error reading temporary file: %v", err)
-	}
-
-	return wavData, nil
-} 
\ No newline at end of file
+	return out.buf, nil
+}
@@ -0,0 +1,565 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/go-audio/audio"
+	"github.com/go-audio/wav"
+)
+
+// sineWave generates n samples of a sine tone at the given amplitude (0.0 to 1.0)
+func sineWave(n int, freq, sampleRate, amplitude float64) []int16 {
+	samples := make([]int16, n)
+	for i := range samples {
+		samples[i] = int16(math.Round(amplitude * 32767.0 * math.Sin(2*math.Pi*freq*float64(i)/sampleRate)))
+	}
+	return samples
+}
+
+// toneLevel measures the amplitude of a single frequency using the Goertzel algorithm
+func toneLevel(samples []int16, freq, sampleRate float64) float64 {
+	coeff := 2 * math.Cos(2*math.Pi*freq/sampleRate)
+	var s1, s2 float64
+	for _, sample := range samples {
+		s := float64(sample)/32767.0 + coeff*s1 - s2
+		s2, s1 = s1, s
+	}
+	power := s1*s1 + s2*s2 - coeff*s1*s2
+	return 2 * math.Sqrt(math.Max(power, 0)) / float64(len(samples))
+}
+
+func TestResampleMethods(t *testing.T) {
+	input := sineWave(48000, 1000, 48000, 0.5)
+
+	for _, method := range []ResampleMethod{ResampleSinc, ResampleFFT} {
+		config := DefaultAudioConfig()
+		config.ResampleMethod = method
+		output := resample(input, 48000, 8000, config)
+
+		if len(output) != 8000 {
+			t.Fatalf("method %d: expected 8000 samples, got %d", method, len(output))
+		}
+		// Skip the edges where the interpolation window is incomplete
+		level := toneLevel(output[400:7600], 1000, 8000)
+		if math.Abs(level-0.5) > 0.02 {
+			t.Errorf("method %d: expected tone level 0.5, got %.3f", method, level)
+		}
+	}
+}
+
+func TestRationalResamplerMatchesSinc(t *testing.T) {
+	input := sineWave(4410, 440, 44100, 0.8)
+	window := makeWindow(WindowBlackman, 33, 0)
+
+	up, down, ok := rationalRatio(44100, 8000)
+	if !ok || up != 80 || down != 441 {
+		t.Fatalf("unexpected ratio %d/%d (ok=%v)", up, down, ok)
+	}
+
+	want := resamplePCM16(input, 44100, 8000, 16, window)
+	got := resamplePCM16Rational(input, up, down, 16, window, nil)
+	if len(got) != len(want) {
+		t.Fatalf("length mismatch: got %d, want %d", len(got), len(want))
+	}
+	for i := range got {
+		if d := int(got[i]) - int(want[i]); d < -1 || d > 1 {
+			t.Fatalf("sample %d: got %d, want %d", i, got[i], want[i])
+		}
+	}
+}
+
+func TestCachedPolyphaseBank(t *testing.T) {
+	input := sineWave(2205, 440, 22050, 0.8)
+	config := TTSNarrowbandConfig()
+	window := resampleWindow(config.ResamplingWindowSize, config)
+	prefilter := antiAliasingKernel(22050, 8000, config)
+	want := resamplePCM16Rational(input, 160, 441, config.ResamplingWindowSize, window, prefilter)
+
+	// The second conversion reuses the bank designed by the first
+	for i := 0; i < 2; i++ {
+		if got := resampleSinc(input, 22050, 8000, config, prefilter); !slices.Equal(got, want) {
+			t.Fatalf("conversion %d differs from a freshly designed bank", i+1)
+		}
+	}
+	// A different cutoff must not share the bank
+	config.AntiAliasingCutoffRatio = 0.8
+	if got := resampleSinc(input, 22050, 8000, config, antiAliasingKernel(22050, 8000, config)); slices.Equal(got, want) {
+		t.Error("another anti-aliasing cutoff reused the cached bank")
+	}
+}
+
+func TestResampleLengthAndEdges(t *testing.T) {
+	// An odd length leaves a partial output period, which must not be dropped
+	const n = 4411
+	input := make([]int16, n)
+	for i := range input {
+		input[i] = 10000
+	}
+
+	for _, rate := range []int{16000, 22050, 44100, 44101, 48000} {
+		for _, method := range []ResampleMethod{ResampleSinc, ResampleFFT} {
+			config := DefaultAudioConfig()
+			config.ResampleMethod = method
+			output := resample(input, rate, 8000, config)
+
+			if want := (n*8000 + rate - 1) / rate; len(output) != want {
+				t.Fatalf("%d Hz, method %d: expected %d samples, got %d", rate, method, want, len(output))
+			}
+			// Mirrored edges keep a constant signal constant up to the last sample
+			for _, i := range []int{0, 1, len(output) - 2, len(output) - 1} {
+				if d := int(output[i]) - 10000; d < -100 || d > 100 {
+					t.Errorf("%d Hz, method %d: sample %d is %d, want 10000", rate, method, i, output[i])
+				}
+			}
+		}
+	}
+
+	if got := len(resamplePCM16Rational([]int16{1, 2, 3}, 1, 2, 16, makeWindow(WindowBlackman, 33, 0), nil)); got != 2 {
+		t.Errorf("3 samples halved: expected 2 samples, got %d", got)
+	}
+}
+
+func BenchmarkResampleTable(b *testing.B) {
+	input := sineWave(44100, 440, 44100, 0.8)
+	window := makeWindow(WindowBlackman, 129, 0)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		resamplePCM16WithTable(input, 44100, 8000, 64, window)
+	}
+}
+
+func BenchmarkResampleRational(b *testing.B) {
+	input := sineWave(44100, 440, 44100, 0.8)
+	window := makeWindow(WindowBlackman, 129, 0)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		resamplePCM16Rational(input, 80, 441, 64, window, nil)
+	}
+}
+
+func TestDecimatorPathsRejectAliases(t *testing.T) {
+	for _, rate := range []int{16000, 48000} {
+		config := DefaultAudioConfig()
+		tone := sineWave(rate, 1000, float64(rate), 0.5)
+		output := resample(tone, rate, 8000, config)
+		if len(output) != 8000 {
+			t.Fatalf("%d Hz: expected 8000 samples, got %d", rate, len(output))
+		}
+		if level := toneLevel(output[400:7600], 1000, 8000); math.Abs(level-0.5) > 0.01 {
+			t.Errorf("%d Hz: expected passband level 0.5, got %.3f", rate, level)
+		}
+
+		// 5 kHz would fold to 3 kHz without the decimation low-pass
+		alias := resample(sineWave(rate, 5000, float64(rate), 0.5), rate, 8000, config)
+		if level := toneLevel(alias[400:7600], 3000, 8000); level > 0.005 {
+			t.Errorf("%d Hz: alias at 3 kHz not suppressed: level %.4f", rate, level)
+		}
+	}
+}
+
+func TestFoldedAntiAliasingMatchesSeparate(t *testing.T) {
+	config := DefaultAudioConfig()
+	config.AntiAliasingType = AAWindowedSinc
+	for _, rate := range []int{44100, 48000} {
+		input := sineWave(rate, 1000, float64(rate), 0.4)
+		for i, sample := range sineWave(rate, 5000, float64(rate), 0.4) {
+			input[i] += sample
+		}
+
+		separate := applyFilterChain(append([]int16(nil), input...), rate, config)
+		separate = resample(separate, rate, 8000, config)
+		folded := filterAndResample(append([]int16(nil), input...), rate, 8000, config)
+		if len(folded) != len(separate) {
+			t.Fatalf("%d Hz: folded length %d, want %d", rate, len(folded), len(separate))
+		}
+		for i := 200; i < len(folded)-200; i++ {
+			if diff := int(folded[i]) - int(separate[i]); diff > 2 || diff < -2 {
+				t.Fatalf("%d Hz: sample %d folded %d, separate %d", rate, i, folded[i], separate[i])
+			}
+		}
+		if level := toneLevel(folded[400:7600], 3000, 8000); level > 0.005 {
+			t.Errorf("%d Hz: alias at 3 kHz not suppressed: level %.4f", rate, level)
+		}
+	}
+}
+
+func TestFilterCoefficientsCached(t *testing.T) {
+	designs := 0
+	key := filterKey{kind: AAButterworth, sampleRate: 12345, cutoff: 3000, order: 2}
+	design := func() []filterCoefficients {
+		designs++
+		return []filterCoefficients{{b0: 1}}
+	}
+	first := cachedFilterSections(key, design)
+	second := cachedFilterSections(key, design)
+	if designs != 1 || &first[0] != &second[0] {
+		t.Errorf("designed %d times, same entry %v", designs, &first[0] == &second[0])
+	}
+}
+
+func TestTelephoneEffectBandLimits(t *testing.T) {
+	const rate = 16000
+	// Mix an in-band tone with tones below and above the telephone band
+	input := sineWave(rate, 1000, rate, 0.2)
+	for i, sample := range sineWave(rate, 100, rate, 0.2) {
+		input[i] += sample
+	}
+	for i, sample := range sineWave(rate, 6000, rate, 0.2) {
+		input[i] += sample
+	}
+
+	output := telephoneEffect(input, rate)[rate/4:]
+	mid := toneLevel(output, 1000, rate)
+	if low := toneLevel(output, 100, rate); low > mid/4 {
+		t.Errorf("100 Hz not attenuated: %.3f vs %.3f at 1 kHz", low, mid)
+	}
+	if high := toneLevel(output, 6000, rate); high > mid/10 {
+		t.Errorf("6 kHz not attenuated: %.3f vs %.3f at 1 kHz", high, mid)
+	}
+}
+
+func TestInPlaceStages(t *testing.T) {
+	input := sineWave(8000, 1000, 8000, 0.25)
+	output := normalizeAudio(applyCompression(input, 2.0, 0.5), 0.9)
+	if !sameBuffer(input, output) {
+		t.Fatal("level stages allocated a new buffer")
+	}
+	if peak := toneLevel(output, 1000, 8000); math.Abs(peak-0.9) > 0.01 {
+		t.Errorf("normalized peak %.3f, want 0.9", peak)
+	}
+}
+
+func BenchmarkConvertWavBytesToUlaw(b *testing.B) {
+	wavBytes, err := encodeWavPCM16(sineWave(44100*5, 440, 44100, 0.5), 44100)
+	if err != nil {
+		b.Fatal(err)
+	}
+	config := DefaultAudioConfig()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ConvertWavBytesToUlaw(wavBytes, config); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestConvertPCM16ToUlawMatchesWav(t *testing.T) {
+	input := sineWave(16000, 440, 16000, 0.5)
+	wavBytes, err := encodeWavPCM16(input, 16000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := ConvertWavBytesToUlaw(wavBytes, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	original := append([]int16(nil), input...)
+	got, err := ConvertPCM16ToUlaw(input, 16000, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, expected) {
+		t.Error("PCM conversion differs from converting the same samples as WAV")
+	}
+	for i := range input {
+		if input[i] != original[i] {
+			t.Fatal("input samples were modified")
+		}
+	}
+}
+
+func TestSilentInputStaysSilent(t *testing.T) {
+	wavBytes, err := encodeWavPCM16(make([]int16, 16000), 16000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, config := range map[string]*AudioConfig{
+		"default":   DefaultAudioConfig(),
+		"telephony": TelephonyConfig(),
+		"voicemail": VoicemailConfig(),
+	} {
+		got, err := ConvertWavBytesToUlaw(wavBytes, config)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		var streamed bytes.Buffer
+		if err := ConvertWavStreamToUlaw(bytes.NewReader(wavBytes), &streamed, config); err != nil {
+			t.Fatalf("%s: streaming: %v", name, err)
+		}
+		for _, output := range [][]byte{got, streamed.Bytes()} {
+			if len(output) != 8000 {
+				t.Fatalf("%s: got %d bytes, want 8000", name, len(output))
+			}
+			for i, sample := range decodeUlawSamples(output) {
+				if sample != 0 {
+					t.Fatalf("%s: sample %d is %d, want silence", name, i, sample)
+				}
+			}
+		}
+	}
+}
+
+func TestEmptyInputReturnsErrEmptyInput(t *testing.T) {
+	wavBytes, err := encodeWavPCM16(nil, 16000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ConvertWavBytesToUlaw(wavBytes, nil); !errors.Is(err, ErrEmptyInput) {
+		t.Errorf("ConvertWavBytesToUlaw: got %v, want ErrEmptyInput", err)
+	}
+	if _, err := ConvertPCM16ToUlaw(nil, 16000, nil); !errors.Is(err, ErrEmptyInput) {
+		t.Errorf("ConvertPCM16ToUlaw: got %v, want ErrEmptyInput", err)
+	}
+	var out bytes.Buffer
+	if err := ConvertWavStreamToUlaw(bytes.NewReader(wavBytes), &out, nil); !errors.Is(err, ErrEmptyInput) {
+		t.Errorf("ConvertWavStreamToUlaw: got %v, want ErrEmptyInput", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("streaming wrote %d bytes for empty input", out.Len())
+	}
+}
+
+func TestMonoMixdownPerBitDepth(t *testing.T) {
+	for _, bitDepth := range []int{8, 16, 24, 32} {
+		fullScale := int64(1) << (bitDepth - 1)
+		// Decoded 8-bit samples are unsigned around 128
+		code := func(x float64) int {
+			v := int(math.Min(math.Round(x*float64(fullScale)), float64(fullScale-1)))
+			if bitDepth == 8 {
+				v += 128
+			}
+			return v
+		}
+		for _, channels := range []int{1, 2, 6} {
+			frames := map[string]struct {
+				values []float64
+				want   int16
+			}{
+				"positive full scale": {[]float64{1, 1, 1, 1, 1, 1}, 32767},
+				"negative full scale": {[]float64{-1, -1, -1, -1, -1, -1}, -32768},
+				"half scale":          {[]float64{0.5, 0.5, 0.5, 0.5, 0.5, 0.5}, 16384},
+				"opposite channels":   {[]float64{0.5, -0.5, 0.5, -0.5, 0.5, -0.5}, 0},
+			}
+			for name, frame := range frames {
+				if name == "opposite channels" && channels == 1 {
+					continue
+				}
+				data := make([]int, channels)
+				for ch := range data {
+					data[ch] = code(frame.values[ch])
+				}
+				dst := make([]int16, 1)
+				pcmToInt16(dst, data, channels, bitDepth, true)
+				// 8-bit positive full scale is 127/128
+				tolerance := 1
+				if bitDepth == 8 {
+					tolerance = 256
+				}
+				if diff := int(dst[0]) - int(frame.want); diff < -tolerance || diff > tolerance {
+					t.Errorf("%d-bit, %d channels, %s: got %d, want %d", bitDepth, channels, name, dst[0], frame.want)
+				}
+			}
+		}
+	}
+}
+
+// encodeWavDepth writes 16-bit samples as a WAV file of another bit depth,
+// duplicated into each channel
+func encodeWavDepth(t *testing.T, samples []int16, sampleRate, bitDepth, channels int) []byte {
+	t.Helper()
+	out := &writeSeeker{}
+	enc := wav.NewEncoder(out, sampleRate, bitDepth, channels, 1)
+	buf := &audio.IntBuffer{
+		Format:         &audio.Format{NumChannels: channels, SampleRate: sampleRate},
+		Data:           make([]int, len(samples)*channels),
+		SourceBitDepth: bitDepth,
+	}
+	for i, sample := range samples {
+		for ch := 0; ch < channels; ch++ {
+			v := int(sample) << 16 >> (32 - bitDepth)
+			if bitDepth == 8 {
+				v = int(sample)>>8 + 128
+			}
+			buf.Data[i*channels+ch] = v
+		}
+	}
+	if err := enc.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return out.buf
+}
+
+func TestDecodeWavBitDepths(t *testing.T) {
+	tone := sineWave(1600, 440, 16000, 0.5)
+	for _, bitDepth := range []int{8, 16, 24, 32} {
+		for _, channels := range []int{1, 2} {
+			wavBytes := encodeWavDepth(t, tone, 16000, bitDepth, channels)
+			config := DefaultAudioConfig()
+			samples, _, err := decodeWavSamples(wavBytes, config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(samples) != len(tone) {
+				t.Fatalf("%d-bit, %d channels: got %d samples, want %d", bitDepth, channels, len(samples), len(tone))
+			}
+			tolerance := 1
+			if bitDepth == 8 {
+				tolerance = 256
+			}
+			for i := range tone {
+				if diff := int(samples[i]) - int(tone[i]); diff < -tolerance || diff > tolerance {
+					t.Fatalf("%d-bit, %d channels: sample %d is %d, want %d", bitDepth, channels, i, samples[i], tone[i])
+				}
+			}
+		}
+	}
+}
+
+func TestConvert32BitPCM(t *testing.T) {
+	tone := sineWave(16000, 440, 16000, 0.5)
+	want, err := ConvertPCM16ToUlaw(tone, 16000, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plain := encodeWavDepth(t, tone, 16000, 32, 1)
+	// WAVE_FORMAT_EXTENSIBLE header of the same audio, as field recorders write
+	fmtChunk := make([]byte, 48)
+	copy(fmtChunk, plain[12:36])
+	binary.LittleEndian.PutUint32(fmtChunk[4:], 40)
+	binary.LittleEndian.PutUint16(fmtChunk[8:], wavFormatExtensible)
+	binary.LittleEndian.PutUint16(fmtChunk[24:], 22)
+	binary.LittleEndian.PutUint16(fmtChunk[26:], 32)
+	binary.LittleEndian.PutUint16(fmtChunk[32:], wavFormatPCM)
+	copy(fmtChunk[34:], "\x00\x00\x00\x00\x10\x00\x80\x00\x00\xaa\x00\x38\x9b\x71")
+	extensible := append(append(append([]byte(nil), plain[:12]...), fmtChunk...), plain[36:]...)
+	binary.LittleEndian.PutUint32(extensible[4:], uint32(len(extensible)-8))
+
+	for name, wavBytes := range map[string][]byte{"PCM": plain, "extensible": extensible} {
+		got, err := ConvertWavBytesToUlaw(wavBytes, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: 32-bit input converts differently from the same 16-bit samples", name)
+		}
+	}
+
+	// IEEE float data would decode as integer noise
+	float := append([]byte(nil), plain...)
+	binary.LittleEndian.PutUint16(float[20:], 3)
+	var wavErr *WavError
+	if _, err := ConvertWavBytesToUlaw(float, nil); !errors.As(err, &wavErr) {
+		t.Errorf("float WAV: got %v, want a WavError", err)
+	}
+}
+
+func TestRoundTripKeepsExactDuration(t *testing.T) {
+	for _, rate := range []int{11025, 16000, 22050, 44100, 48000} {
+		for _, n := range []int{1, 999, 4410, 12345} {
+			wavBytes, err := encodeWavPCM16(sineWave(n, 440, float64(rate), 0.5), rate)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ulaw, err := ConvertWavBytesToUlaw(wavBytes, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// u-law covers the input to within one 8 kHz sample
+			if excess := float64(len(ulaw)) - float64(n)*8000/float64(rate); excess < 0 || excess >= 1 {
+				t.Errorf("%d Hz, %d samples: u-law is %d samples, %.2f past the input", rate, n, len(ulaw), excess)
+			}
+
+			restored, err := ConvertUlawBytesToWavLength(ulaw, uint32(rate), 16, n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			samples, _, err := decodeWavSamples(restored, &AudioConfig{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(samples) != n {
+				t.Errorf("%d Hz: round trip of %d samples returned %d", rate, n, len(samples))
+			}
+			// Same sample positions as the default length
+			full, err := ConvertUlawBytesToWav(ulaw, uint32(rate), 16)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fullSamples, _, err := decodeWavSamples(full, &AudioConfig{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(fullSamples) < n || !slices.Equal(samples, fullSamples[:n]) {
+				t.Errorf("%d Hz, %d samples: exact-length output differs from the default one", rate, n)
+			}
+		}
+	}
+}
+
+func TestConvertUlawBytesToPCM16MatchesWav(t *testing.T) {
+	ulaw := encodeUlawSamples(sineWave(8000, 440, 8000, 0.5))
+	for _, rate := range []uint32{8000, 16000, 44100} {
+		for _, n := range []int{0, 12345} {
+			wavBytes, err := ConvertUlawBytesToWavLength(ulaw, rate, 16, n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, _, err := decodeWavSamples(wavBytes, &AudioConfig{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := ConvertUlawBytesToPCM16(ulaw, rate, 16, n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("%d Hz, length %d: samples differ from those of the WAV file", rate, n)
+			}
+		}
+	}
+	if _, err := ConvertUlawBytesToPCM16(ulaw, 0, 16, 0); err == nil {
+		t.Error("sample rate 0 accepted")
+	}
+}
+
+func TestStageOutputs(t *testing.T) {
+	input := sineWave(16000, 440, 16000, 0.5)
+	config := DefaultAudioConfig()
+	config.FadeInMs = 20
+	var stages []string
+	var last []int16
+	config.OnStageOutput = func(stage string, samples []int16, sampleRate int) {
+		stages = append(stages, stage)
+		wantRate := 8000
+		if stage == "decode" || stage == "high-pass" || stage == "low-pass" || stage == "anti-aliasing" {
+			wantRate = 16000
+		}
+		if sampleRate != wantRate {
+			t.Errorf("stage %s reported at %d Hz, want %d", stage, sampleRate, wantRate)
+		}
+		last = append(last[:0], samples...)
+	}
+
+	ulaw, err := ConvertPCM16ToUlaw(input, 16000, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"high-pass", "low-pass", "anti-aliasing", "resample", "compression", "normalize", "fades", "encode"}
+	if !slices.Equal(stages, want) {
+		t.Errorf("stages %v, want %v", stages, want)
+	}
+	if !slices.Equal(last, decodeUlawSamples(ulaw)) {
+		t.Error("encode stage differs from the decoded output")
+	}
+}
@@ -0,0 +1,223 @@
+package wav2ulaw
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Format tags of the fmt chunk accepted by inspectWav
+const (
+	wavFormatPCM        = 1
+	wavFormatExtensible = 0xFFFE
+)
+
+// WavLimits bound what a WAV input may declare. Files beyond them are
+// rejected with a *LimitError before any audio is decoded, so services
+// ingesting untrusted files cannot be made to allocate without bound.
+// Zero fields are not checked.
+type WavLimits struct {
+	// Size of the data chunk (bytes)
+	MaxDataSize int64
+	// Number of interleaved channels
+	MaxChannels int
+	// Sample rate (Hz)
+	MaxSampleRate int
+	// Duration of the audio
+	MaxDuration time.Duration
+}
+
+// DefaultWavLimits returns limits that accept any realistic recording
+func DefaultWavLimits() *WavLimits {
+	return &WavLimits{
+		MaxDataSize:   2 << 30,
+		MaxChannels:   32,
+		MaxSampleRate: 768000,
+		MaxDuration:   24 * time.Hour,
+	}
+}
+
+// WavError reports a WAV file whose structure is invalid, such as a chunk
+// declaring more bytes than the file holds
+type WavError struct {
+	// Byte offset of the offending chunk or field
+	Offset int64
+	Reason string
+}
+
+func (e *WavError) Error() string {
+	return fmt.Sprintf("malformed WAV at offset %d: %s", e.Offset, e.Reason)
+}
+
+// LimitError reports a WAV file declaring more than its WavLimits allow
+type LimitError struct {
+	// "data size", "channels", "sample rate" or "duration"
+	Limit string
+	// Declared value and limit, in bytes, channels, Hz or seconds
+	Value, Max int64
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("WAV %s %d exceeds the limit of %d", e.Limit, e.Value, e.Max)
+}
+
+// wavHeader is the format of a WAV file checked by inspectWav
+type wavHeader struct {
+	// Format tag, the sub-format of WAVE_FORMAT_EXTENSIBLE files
+	format     int
+	channels   int
+	sampleRate int
+	bitDepth   int
+	// Offset of the audio and the bytes of it present in the file, which
+	// is less than declared when the file is truncated
+	dataOffset int64
+	dataSize   int64
+}
+
+// inspectWav walks the chunk headers of a WAV file without reading their
+// payloads, validating the format against limits (DefaultWavLimits when
+// nil). A data chunk running past the end of the file is accepted as
+// truncated; any other chunk doing so is malformed, since decoders allocate
+// what it declares. The walk tolerates the layouts writers produce in the
+// wild: the RIFF size is ignored, unknown chunks are skipped wherever they
+// sit, data may come before fmt, odd-sized chunks may lack their pad byte
+// and a data size of 0 or 0xFFFFFFFF left by a streaming writer runs to the
+// end of the file. r is left at the start of the file.
+func inspectWav(r io.ReadSeeker, limits *WavLimits) (*wavHeader, error) {
+	if limits == nil {
+		limits = DefaultWavLimits()
+	}
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	var buf [16]byte
+	if _, err := io.ReadFull(r, buf[:12]); err != nil || string(buf[0:4]) != "RIFF" || string(buf[8:12]) != "WAVE" {
+		return nil, &WavError{Offset: 0, Reason: "missing RIFF/WAVE header"}
+	}
+
+	var h *wavHeader
+	dataOffset, dataSize := int64(-1), int64(0)
+	for pos := int64(12); pos+8 <= size; {
+		if _, err := r.Seek(pos, io.SeekStart); err != nil {
+			return nil, err
+		}
+		if _, err := io.ReadFull(r, buf[:8]); err != nil {
+			return nil, err
+		}
+		id := string(buf[0:4])
+		chunkSize := int64(binary.LittleEndian.Uint32(buf[4:8]))
+		remaining := size - pos - 8
+
+		switch {
+		case id == "data":
+			if dataOffset >= 0 {
+				break
+			}
+			dataOffset, dataSize = pos+8, min(chunkSize, remaining)
+			if chunkSize == 0 && remaining >= 4 {
+				if _, err := io.ReadFull(r, buf[:4]); err != nil {
+					return nil, err
+				}
+				if !isChunkID(buf[:4]) {
+					// Size never filled in; the audio follows
+					dataSize = remaining
+				}
+			}
+			if h != nil {
+				return h.withData(r, limits, dataOffset, dataSize)
+			}
+			chunkSize = dataSize
+		case chunkSize > remaining:
+			return nil, &WavError{Offset: pos, Reason: fmt.Sprintf("%q chunk declares %d bytes but only %d remain", id, chunkSize, remaining)}
+		case id == "fmt " && h == nil:
+			if chunkSize < 16 {
+				return nil, &WavError{Offset: pos, Reason: fmt.Sprintf("fmt chunk of %d bytes is too short", chunkSize)}
+			}
+			if _, err := io.ReadFull(r, buf[:16]); err != nil {
+				return nil, err
+			}
+			h = &wavHeader{
+				format:     int(binary.LittleEndian.Uint16(buf[0:2])),
+				channels:   int(binary.LittleEndian.Uint16(buf[2:4])),
+				sampleRate: int(binary.LittleEndian.Uint32(buf[4:8])),
+				bitDepth:   int(binary.LittleEndian.Uint16(buf[14:16])),
+			}
+			if h.format == wavFormatExtensible {
+				// The sub-format GUID starts with the actual format tag
+				if chunkSize < 40 {
+					return nil, &WavError{Offset: pos, Reason: fmt.Sprintf("extensible fmt chunk of %d bytes is too short", chunkSize)}
+				}
+				if _, err := io.ReadFull(r, buf[:10]); err != nil {
+					return nil, err
+				}
+				h.format = int(binary.LittleEndian.Uint16(buf[8:10]))
+			}
+			if err := h.validate(pos); err != nil {
+				return nil, err
+			}
+			if dataOffset >= 0 {
+				return h.withData(r, limits, dataOffset, dataSize)
+			}
+		}
+		if pos, err = nextChunk(r, pos, chunkSize, size); err != nil {
+			return nil, err
+		}
+	}
+	if h == nil {
+		return nil, &WavError{Offset: 12, Reason: "no fmt chunk"}
+	}
+	return nil, &WavError{Offset: 12, Reason: "no data chunk"}
+}
+
+// withData records the data chunk in h and checks it against limits,
+// rewinding r
+func (h *wavHeader) withData(r io.Seeker, limits *WavLimits, offset, size int64) (*wavHeader, error) {
+	h.dataOffset, h.dataSize = offset, size
+	if err := h.check(limits); err != nil {
+		return nil, err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// validate rejects formats no decoder can handle, the fmt chunk being at offset
+func (h *wavHeader) validate(offset int64) error {
+	switch {
+	case h.format != wavFormatPCM:
+		return &WavError{Offset: offset, Reason: fmt.Sprintf("unsupported format tag %#x, only integer PCM is supported", h.format)}
+	case h.channels < 1:
+		return &WavError{Offset: offset, Reason: fmt.Sprintf("invalid channel count %d", h.channels)}
+	case h.sampleRate < 1:
+		return &WavError{Offset: offset, Reason: fmt.Sprintf("invalid sample rate %d", h.sampleRate)}
+	case h.bitDepth != 8 && h.bitDepth != 16 && h.bitDepth != 24 && h.bitDepth != 32:
+		return &WavError{Offset: offset, Reason: fmt.Sprintf("unsupported bit depth %d", h.bitDepth)}
+	}
+	return nil
+}
+
+// check compares the header with limits
+func (h *wavHeader) check(limits *WavLimits) error {
+	if limits.MaxDataSize > 0 && h.dataSize > limits.MaxDataSize {
+		return &LimitError{Limit: "data size", Value: h.dataSize, Max: limits.MaxDataSize}
+	}
+	if limits.MaxChannels > 0 && h.channels > limits.MaxChannels {
+		return &LimitError{Limit: "channels", Value: int64(h.channels), Max: int64(limits.MaxChannels)}
+	}
+	if limits.MaxSampleRate > 0 && h.sampleRate > limits.MaxSampleRate {
+		return &LimitError{Limit: "sample rate", Value: int64(h.sampleRate), Max: int64(limits.MaxSampleRate)}
+	}
+	if limits.MaxDuration > 0 {
+		frames := h.dataSize / int64(h.channels*h.bitDepth/8)
+		if duration := time.Duration(frames) * time.Second / time.Duration(h.sampleRate); duration > limits.MaxDuration {
+			return &LimitError{Limit: "duration", Value: int64(duration / time.Second), Max: int64(limits.MaxDuration / time.Second)}
+		}
+	}
+	return nil
+}
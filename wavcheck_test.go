@@ -0,0 +1,119 @@
+package wav2ulaw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMalformedWavReturnsWavError(t *testing.T) {
+	base, err := encodeWavPCM16(sineWave(1600, 440, 16000, 0.5), 16000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Offsets in the 44-byte header written by encodeWavPCM16
+	cases := map[string]func(b []byte) []byte{
+		"fmt size": func(b []byte) []byte {
+			binary.LittleEndian.PutUint32(b[16:], 0xFFFFFFF0)
+			return b
+		},
+		"no channels": func(b []byte) []byte {
+			binary.LittleEndian.PutUint16(b[22:], 0)
+			return b
+		},
+		"no sample rate": func(b []byte) []byte {
+			binary.LittleEndian.PutUint32(b[24:], 0)
+			return b
+		},
+		"bit depth": func(b []byte) []byte {
+			binary.LittleEndian.PutUint16(b[34:], 12)
+			return b
+		},
+		"list size": func(b []byte) []byte {
+			list := []byte("LIST\xf0\xff\xff\xffINFO")
+			return append(b[:36:36], append(list, b[36:]...)...)
+		},
+		"no data": func(b []byte) []byte {
+			return b[:36]
+		},
+	}
+	for name, mutate := range cases {
+		wavBytes := mutate(append([]byte(nil), base...))
+		var wavErr *WavError
+		if _, err := ConvertWavBytesToUlaw(wavBytes, nil); !errors.As(err, &wavErr) {
+			t.Errorf("%s: got %v, want a WavError", name, err)
+		}
+		var out bytes.Buffer
+		if err := ConvertWavStreamToUlaw(bytes.NewReader(wavBytes), &out, nil); !errors.As(err, &wavErr) {
+			t.Errorf("%s: streaming: got %v, want a WavError", name, err)
+		}
+		if _, err := AnalyzeWav(wavBytes); !errors.As(err, &wavErr) {
+			t.Errorf("%s: analysis: got %v, want a WavError", name, err)
+		}
+	}
+}
+
+func TestWavLimits(t *testing.T) {
+	wavBytes, err := encodeWavPCM16(sineWave(32000, 440, 16000, 0.5), 16000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wide := append([]byte(nil), wavBytes...)
+	binary.LittleEndian.PutUint16(wide[22:], 64)
+	huge := append([]byte(nil), wavBytes...)
+	binary.LittleEndian.PutUint32(huge[24:], 0xFFFFFFFF)
+
+	cases := []struct {
+		name   string
+		input  []byte
+		limits *WavLimits
+		limit  string
+	}{
+		{"channels", wide, nil, "channels"},
+		{"sample rate", huge, nil, "sample rate"},
+		{"duration", wavBytes, &WavLimits{MaxDuration: time.Second}, "duration"},
+		{"data size", wavBytes, &WavLimits{MaxDataSize: 1000}, "data size"},
+	}
+	for _, c := range cases {
+		config := DefaultAudioConfig()
+		config.Limits = c.limits
+		var limitErr *LimitError
+		if _, err := ConvertWavBytesToUlaw(c.input, config); !errors.As(err, &limitErr) || limitErr.Limit != c.limit {
+			t.Errorf("%s: got %v, want a %s LimitError", c.name, err, c.limit)
+		}
+		var out bytes.Buffer
+		if err := ConvertWavStreamToUlaw(bytes.NewReader(c.input), &out, config); !errors.As(err, &limitErr) || limitErr.Limit != c.limit {
+			t.Errorf("%s: streaming: got %v, want a %s LimitError", c.name, err, c.limit)
+		}
+	}
+
+	config := DefaultAudioConfig()
+	config.Limits = &WavLimits{MaxDuration: 2 * time.Second}
+	if _, err := ConvertWavBytesToUlaw(wavBytes, config); err != nil {
+		t.Errorf("input within the limits: %v", err)
+	}
+}
+
+func TestTruncatedWavConvertsAvailableData(t *testing.T) {
+	wavBytes, err := encodeWavPCM16(sineWave(16000, 440, 16000, 0.5), 16000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	truncated := wavBytes[:44+8000]
+	got, err := ConvertWavBytesToUlaw(truncated, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2000 {
+		t.Errorf("got %d bytes, want 2000 from the 4000 samples present", len(got))
+	}
+	var out bytes.Buffer
+	if err := ConvertWavStreamToUlaw(bytes.NewReader(truncated), &out, nil); err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() != len(got) {
+		t.Errorf("streamed %d bytes, want %d", out.Len(), len(got))
+	}
+}
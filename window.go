@@ -0,0 +1,65 @@
+package wav2ulaw
+
+import "math"
+
+// WindowFunction defines the window applied to sinc resampling and FIR filter kernels
+type WindowFunction int
+
+const (
+	WindowBlackman       WindowFunction = iota // Blackman window (good general-purpose stopband)
+	WindowKaiser                               // Kaiser window with configurable beta
+	WindowHann                                 // Hann window (narrow transition, moderate stopband)
+	WindowHamming                              // Hamming window (low first sidelobe)
+	WindowBlackmanHarris                       // 4-term Blackman-Harris window (highest stopband attenuation)
+)
+
+// makeWindow returns n coefficients of the selected window function.
+// It is shared by the resamplers and any windowed-sinc FIR kernel design.
+func makeWindow(fn WindowFunction, n int, kaiserBeta float64) []float64 {
+	window := make([]float64, n)
+	if n == 1 {
+		window[0] = 1.0
+		return window
+	}
+
+	for i := range window {
+		x := float64(i) / float64(n-1)
+		switch fn {
+		case WindowKaiser:
+			// Kaiser window: I0(beta * sqrt(1 - (2x-1)^2)) / I0(beta)
+			r := 2*x - 1
+			window[i] = besselI0(kaiserBeta*math.Sqrt(math.Max(0, 1-r*r))) / besselI0(kaiserBeta)
+		case WindowHann:
+			window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*x)
+		case WindowHamming:
+			window[i] = 0.54 - 0.46*math.Cos(2*math.Pi*x)
+		case WindowBlackmanHarris:
+			window[i] = 0.35875 - 0.48829*math.Cos(2*math.Pi*x) + 0.14128*math.Cos(4*math.Pi*x) - 0.01168*math.Cos(6*math.Pi*x)
+		default: // WindowBlackman
+			window[i] = 0.42 - 0.5*math.Cos(2*math.Pi*x) + 0.08*math.Cos(4*math.Pi*x)
+		}
+	}
+
+	return window
+}
+
+// resampleWindow returns the window coefficients for a sinc resampler with
+// the given half-width, using the window function selected in config
+func resampleWindow(windowSize int, config *AudioConfig) []float64 {
+	return makeWindow(config.WindowFunction, windowSize*2+1, config.KaiserBeta)
+}
+
+// besselI0 computes the zeroth-order modified Bessel function of the first kind
+func besselI0(x float64) float64 {
+	sum := 1.0
+	term := 1.0
+	halfX := x / 2.0
+	for k := 1; k < 50; k++ {
+		term *= (halfX / float64(k)) * (halfX / float64(k))
+		sum += term
+		if term < sum*1e-12 {
+			break
+		}
+	}
+	return sum
+}
@@ -0,0 +1,32 @@
+package wav2ulaw
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMakeWindowShape(t *testing.T) {
+	windows := []WindowFunction{WindowBlackman, WindowKaiser, WindowHann, WindowHamming, WindowBlackmanHarris}
+	for _, fn := range windows {
+		w := makeWindow(fn, 33, 8.6)
+		if math.Abs(w[16]-1.0) > 1e-9 {
+			t.Errorf("window %d: expected peak 1.0 at center, got %f", fn, w[16])
+		}
+		for i := 0; i < 16; i++ {
+			if math.Abs(w[i]-w[32-i]) > 1e-9 {
+				t.Errorf("window %d: not symmetric at %d", fn, i)
+			}
+			if w[i] > w[i+1]+1e-12 {
+				t.Errorf("window %d: not increasing towards center at %d", fn, i)
+			}
+		}
+	}
+}
+
+func TestKaiserBetaZeroIsRectangular(t *testing.T) {
+	for i, v := range makeWindow(WindowKaiser, 9, 0) {
+		if math.Abs(v-1.0) > 1e-12 {
+			t.Fatalf("coefficient %d: expected 1.0, got %f", i, v)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package wav2ulaw
+
+import (
+	"fmt"
+	"io"
+)
+
+// writeSeeker is an in-memory io.WriteSeeker. The WAV encoder seeks back to
+// patch chunk sizes once the data is written, which previously required a
+// temporary file.
+type writeSeeker struct {
+	buf []byte
+	pos int
+}
+
+// Write writes p at the current position, growing the buffer as needed
+func (w *writeSeeker) Write(p []byte) (int, error) {
+	if end := w.pos + len(p); end > len(w.buf) {
+		if end > cap(w.buf) {
+			grown := make([]byte, end, max(end, 2*cap(w.buf)))
+			copy(grown, w.buf)
+			w.buf = grown
+		} else {
+			w.buf = w.buf[:end]
+		}
+	}
+	n := copy(w.buf[w.pos:], p)
+	w.pos += n
+	return n, nil
+}
+
+// Seek sets the position for the next Write
+func (w *writeSeeker) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = int64(w.pos) + offset
+	case io.SeekEnd:
+		pos = int64(len(w.buf)) + offset
+	default:
+		return 0, fmt.Errorf("invalid whence %d", whence)
+	}
+	if pos < 0 {
+		return 0, fmt.Errorf("negative position %d", pos)
+	}
+	w.pos = int(pos)
+	return pos, nil
+}